@@ -0,0 +1,228 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/chapters"
+	"github.com/alesr/videoscriber/internal/pkg/nlp"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+	"github.com/alesr/videoscriber/internal/pkg/transcriber"
+	"github.com/alesr/videoscriber/internal/pkg/translate"
+)
+
+// config is the configuration of the server, from its flags and their environment variables.
+type config struct {
+	// Server.
+	port         string
+	grpcPort     string
+	workDir      string
+	logFile      string
+	serviceMode  string
+	publicURL    string
+	drainTimeout time.Duration
+	readyOpenAI  bool
+	testMode     bool
+	faults       string
+
+	// Transcription providers.
+	openAIKey              string
+	provider               string
+	localURL               string
+	localToken             string
+	localModel             string
+	endpointsFile          string
+	endpointHealthInterval time.Duration
+	routingPolicy          string
+	schedulePolicy         string
+	cacheTTL               time.Duration
+	secondPassThreshold    float64
+	secondPassMaxShare     float64
+	secondPassProvider     string
+	secondPassModel        string
+
+	// Processing.
+	maxConcurrency  int
+	fastLaneWorkers int
+	shortClip       time.Duration
+	ffmpegPath      string
+	ffmpegArgs      string
+	ffmpegBootstrap string
+	preprocess      string
+	pipelinesFile   string
+	scratchDirs     string
+	tmpTTL          time.Duration
+
+	// Subtitles.
+	layout          string
+	firstCueIndex   int
+	maxLineLength   int
+	maxLines        int
+	maxCPS          float64
+	signingKey      string
+	keepAudio       bool
+	retainMedia     string
+	retainTTL       time.Duration
+	reviewThreshold float64
+
+	// Language models and analyses.
+	minutesModel        string
+	askModel            string
+	embeddingModel      string
+	chatURL             string
+	translationProvider string
+	translationModel    string
+	deeplURL            string
+	deeplKey            string
+	chaptersProvider    string
+	chaptersModel       string
+	nlpProvider         string
+	nlpURL              string
+	nlpToken            string
+
+	// Submissions.
+	maxUploadSize int64
+	uploadRate    int64
+	uploadTTL     time.Duration
+	dedupWindow   time.Duration
+	queueDir      string
+	queueMemory   bool
+	ytdlpEnabled  bool
+	ytdlpBinary   string
+	watchDir      string
+	watchInterval time.Duration
+	watchPoll     bool
+	watchLanguage string
+
+	// Users and limits.
+	apiKeys        string
+	ssoHeader      string
+	adminUsers     string
+	wsOrigins      string
+	rateLimit      int
+	monthlyMinutes int
+	featureFlags   string
+
+	// Integrations and notifications.
+	slackSigningSecret string
+	slackBotToken      string
+	callbackSecret     string
+	emailTopic         string
+	emailAllowed       string
+	smtpAddr           string
+	smtpUser           string
+	smtpPassword       string
+	smtpFrom           string
+	notifyEmails       string
+	notifyMinFiles     int
+
+	// Housekeeping.
+	janitorInterval time.Duration
+	jobTTL          time.Duration
+	modelsAction    string
+	modelsDir       string
+}
+
+// parseFlags parses the flags of the command line into the configuration.
+func parseFlags() config {
+	var cfg config
+
+	flag.StringVar(&cfg.port, "port", "8080", "port to listen")
+	flag.StringVar(&cfg.grpcPort, "grpc-port", os.Getenv("VIDEOSCRIBER_GRPC_PORT"), "port the gRPC API listens on, not served when empty")
+	flag.StringVar(&cfg.openAIKey, "openai-key", "", "OpenAI API key")
+	flag.StringVar(&cfg.provider, "provider", transcriber.ProviderOpenAI, "default transcription provider (openai, local or auto)")
+	flag.StringVar(&cfg.localURL, "local-url", "", "base URL of a self-hosted faster-whisper/whisperX server")
+	flag.StringVar(&cfg.localToken, "local-token", "", "bearer token for the self-hosted server")
+	flag.StringVar(&cfg.localModel, "local-model", "large-v3", "model served by the self-hosted server")
+	flag.StringVar(&cfg.endpointsFile, "endpoints", "", "JSON file of additional endpoints per provider, e.g. in other regions, enables failover between them")
+	flag.DurationVar(&cfg.endpointHealthInterval, "endpoint-health-interval", 30*time.Second, "interval between the health checks of the provider endpoints")
+	flag.StringVar(&cfg.schedulePolicy, "schedule-policy", "", "JSON scheduling policy file, spreads low priority transcriptions under hourly and daily limits")
+	flag.StringVar(&cfg.routingPolicy, "routing-policy", "", "JSON routing policy file, enables the auto provider")
+	flag.IntVar(&cfg.maxConcurrency, "max-concurrency", runtime.NumCPU(), "maximum number of files processed at the same time")
+	flag.IntVar(&cfg.fastLaneWorkers, "fast-lane-workers", 0, "number of the max-concurrency workers reserved to the short clips, so that they aren't queued behind long recordings, none when 0")
+	flag.DurationVar(&cfg.shortClip, "short-clip", 2*time.Minute, "duration below which the files are short clips, taking the fast lane when their duration is probed before extraction")
+	flag.StringVar(&cfg.layout, "layout", storage.DefaultLayout, "subtitles directory layout, e.g. {lang}/{project}/{name}")
+	flag.StringVar(&cfg.slackSigningSecret, "slack-signing-secret", "", "signing secret of the Slack app, enables the Slack integration")
+	flag.StringVar(&cfg.slackBotToken, "slack-bot-token", "", "bot token of the Slack app")
+	flag.StringVar(&cfg.publicURL, "public-url", "", "public base URL of the server, used for links sent to users")
+	flag.StringVar(&cfg.signingKey, "signing-key", os.Getenv("VIDEOSCRIBER_SIGNING_KEY"), "Ed25519 private key file (PKCS #8 PEM), signs the generated subtitles and artifacts with detached .sig files, none when empty")
+	flag.StringVar(&cfg.queueDir, "queue-dir", os.Getenv("VIDEOSCRIBER_QUEUE_DIR"), "directory the jobs submitted while the subtitles can't be stored wait in for the storage to recover, outside of the subtitles directory, none when empty: the jobs are rejected")
+	flag.StringVar(&cfg.callbackSecret, "callback-secret", os.Getenv("VIDEOSCRIBER_CALLBACK_SECRET"), "secret signing the callbacks of finished jobs, enables the callback_url option")
+	flag.StringVar(&cfg.emailTopic, "email-sns-topic", "", "ARN of the SNS topic SES publishes inbound emails to, enables email-in")
+	flag.StringVar(&cfg.emailAllowed, "email-allowed-senders", "", "comma-separated addresses or @domains allowed to send emails, all when empty")
+	flag.StringVar(&cfg.smtpAddr, "smtp-addr", os.Getenv("VIDEOSCRIBER_SMTP_ADDR"), "SMTP server address (host:port) used to send emails")
+	flag.StringVar(&cfg.smtpUser, "smtp-user", os.Getenv("VIDEOSCRIBER_SMTP_USER"), "SMTP username")
+	flag.StringVar(&cfg.smtpPassword, "smtp-password", os.Getenv("VIDEOSCRIBER_SMTP_PASSWORD"), "SMTP password")
+	flag.StringVar(&cfg.smtpFrom, "smtp-from", os.Getenv("VIDEOSCRIBER_SMTP_FROM"), "sender address of the emails")
+	flag.StringVar(&cfg.notifyEmails, "notify-emails", os.Getenv("VIDEOSCRIBER_NOTIFY_EMAILS"), "comma-separated addresses emailed the summary of the finished jobs, requires the SMTP server, none when empty")
+	flag.IntVar(&cfg.notifyMinFiles, "notify-min-files", int(envInt64("VIDEOSCRIBER_NOTIFY_MIN_FILES", 1)), "minimum number of files of the jobs whose completion is emailed, e.g. to only notify long batches")
+	flag.BoolVar(&cfg.queueMemory, "queue-memory", false, "keep the jobs submitted while the subtitles can't be stored in memory when there is no -queue-dir, so that they are lost on restart, and their files in the tmp directory, where they are removed after -tmp-ttl")
+	flag.BoolVar(&cfg.ytdlpEnabled, "ytdlp", false, "fetch the audio of YouTube and Vimeo links with yt-dlp instead of downloading them")
+	flag.StringVar(&cfg.ytdlpBinary, "ytdlp-binary", "yt-dlp", "path of the yt-dlp binary")
+	flag.Int64Var(&cfg.maxUploadSize, "max-upload-size", envInt64("VIDEOSCRIBER_MAX_UPLOAD_SIZE", defaultMaxUploadSize), "maximum size in bytes of an uploaded file")
+	flag.Int64Var(&cfg.uploadRate, "upload-rate", envInt64("VIDEOSCRIBER_UPLOAD_RATE", 0), "maximum rate in bytes per second at which uploads are read from each client connection, unlimited when 0")
+	flag.StringVar(&cfg.minutesModel, "minutes-model", "gpt-4o-mini", "chat model writing the minutes of the meeting profile")
+	flag.StringVar(&cfg.askModel, "ask-model", "gpt-4o-mini", "chat model answering questions about the transcripts of a project")
+	flag.StringVar(&cfg.embeddingModel, "embedding-model", "text-embedding-3-small", "model embedding the transcripts questions are answered from")
+	flag.StringVar(&cfg.chatURL, "chat-url", "https://api.openai.com/v1", "base URL of the OpenAI compatible chat and embeddings API")
+	flag.StringVar(&cfg.translationProvider, "translation-provider", translate.ProviderChat, "provider translating the cues of subtitles to other languages (chat or deepl)")
+	flag.StringVar(&cfg.translationModel, "translation-model", "gpt-4o-mini", "chat model translating the cues of the chat provider")
+	flag.StringVar(&cfg.deeplURL, "deepl-url", "https://api-free.deepl.com", "base URL of the DeepL API, https://api.deepl.com for the pro plans")
+	flag.StringVar(&cfg.deeplKey, "deepl-key", os.Getenv("VIDEOSCRIBER_DEEPL_KEY"), "DeepL API key, required by the deepl translation provider")
+	flag.StringVar(&cfg.chaptersProvider, "chapters-provider", chapters.ProviderTopics, "provider splitting the transcripts into chapters (topics or chat)")
+	flag.StringVar(&cfg.chaptersModel, "chapters-model", "gpt-4o-mini", "chat model splitting the transcripts into chapters with the chat provider")
+	flag.StringVar(&cfg.nlpProvider, "nlp-provider", nlp.ProviderLexicon, "NLP provider of the sentiment and topic timelines (lexicon or http)")
+	flag.StringVar(&cfg.nlpURL, "nlp-url", "", "URL of the NLP service of the http provider")
+	flag.StringVar(&cfg.nlpToken, "nlp-token", "", "bearer token for the NLP service")
+	flag.StringVar(&cfg.apiKeys, "api-keys", os.Getenv("VIDEOSCRIBER_API_KEYS"), "comma-separated user:key pairs, enables authentication and per-user namespaces")
+	flag.IntVar(&cfg.rateLimit, "rate-limit", 0, "maximum requests per minute of each API key, unlimited when 0")
+	flag.IntVar(&cfg.monthlyMinutes, "monthly-minutes", 0, "monthly transcription minutes of each API key, unlimited when 0")
+	flag.DurationVar(&cfg.drainTimeout, "drain-timeout", 5*time.Minute, "how long to wait for running jobs to finish when stopping")
+	flag.BoolVar(&cfg.readyOpenAI, "ready-openai", false, "check the connectivity to OpenAI in /readyz")
+	flag.BoolVar(&cfg.keepAudio, "keep-audio", true, "deprecated, use -retain keep-none instead of false")
+	flag.StringVar(&cfg.retainMedia, "retain", os.Getenv("VIDEOSCRIBER_RETAIN"), "media kept alongside each subtitle instead of being deleted: keep-none, keep-audio (default, so that subtitles can be reprocessed) or keep-all (also the uploaded videos)")
+	flag.DurationVar(&cfg.retainTTL, "retain-ttl", 0, "time after which the media kept alongside subtitles is removed, kept as long as the subtitle when 0")
+	flag.StringVar(&cfg.ffmpegPath, "ffmpeg", os.Getenv("VIDEOSCRIBER_FFMPEG"), "path or name of the ffmpeg binary, e.g. ffmpeg4, looked for when empty")
+	flag.StringVar(&cfg.ffmpegArgs, "ffmpeg-args", os.Getenv("VIDEOSCRIBER_FFMPEG_ARGS"), "space-separated extra arguments of the audio extraction, e.g. \"-threads 2 -af loudnorm\", {sample_rate} is replaced by the sample rate")
+	flag.StringVar(&cfg.preprocess, "preprocess", os.Getenv("VIDEOSCRIBER_PREPROCESS"), "comma-separated audio filters applied by default before transcribing: normalize, denoise, trim-silence")
+	flag.StringVar(&cfg.pipelinesFile, "pipelines", os.Getenv("VIDEOSCRIBER_PIPELINES"), "JSON file of the ordered steps of the pipeline per project, \"*\" for the others, e.g. {\"*\": [\"extract\", \"normalize\", \"vad\", \"transcribe\", \"translate\", \"analytics\"]}")
+	flag.StringVar(&cfg.ffmpegBootstrap, "ffmpeg-bootstrap", os.Getenv("VIDEOSCRIBER_FFMPEG_BOOTSTRAP"), "JSON manifest file or URL of static ffmpeg builds pinned by checksum, the one of the platform is installed in the data directory when ffmpeg is not found")
+	flag.StringVar(&cfg.watchDir, "watch", os.Getenv("VIDEOSCRIBER_WATCH"), "inbox directory whose new files are transcribed, moved to its processed or failed directory afterwards, none when empty")
+	flag.DurationVar(&cfg.watchInterval, "watch-interval", 10*time.Second, "time the files of the inbox directory must stay unchanged to be transcribed, and interval of its polling when it can't be watched for changes")
+	flag.BoolVar(&cfg.watchPoll, "watch-poll", false, "poll the inbox directory every -watch-interval instead of watching it for changes, e.g. on a network share whose changes made by other hosts aren't notified")
+	flag.StringVar(&cfg.watchLanguage, "watch-language", subtitles.DefaultLanguage, "spoken language of the files of the inbox directory")
+	flag.IntVar(&cfg.firstCueIndex, "first-cue-index", 1, "number of the first cue of the subtitles, e.g. 0 for players counting from zero")
+	flag.IntVar(&cfg.maxLineLength, "max-line-length", 42, "characters per line of the cues, longer lines are wrapped, unlimited when 0")
+	flag.IntVar(&cfg.maxLines, "max-lines", 2, "lines per cue, the extra lines are split off into cues of their own, unlimited when 0")
+	flag.Float64Var(&cfg.maxCPS, "max-cps", 0, "characters per second of the cues, faster cues are extended into the following pause, unlimited when 0")
+	flag.Float64Var(&cfg.secondPassThreshold, "second-pass-threshold", 0, "confidence between 0 and 1 below which cues are transcribed again, keeping the more confident text, none when 0; the providers must support the verbose_json format")
+	flag.Float64Var(&cfg.secondPassMaxShare, "second-pass-max-share", 0.2, "share of the cues, between 0 and 1, transcribed again at most, the least confident first")
+	flag.StringVar(&cfg.secondPassProvider, "second-pass-provider", "", "provider of the second pass, the one of the first pass when empty")
+	flag.StringVar(&cfg.secondPassModel, "second-pass-model", "", "model of the second pass, e.g. a larger one, the one of the provider when empty")
+	flag.StringVar(&cfg.workDir, "dir", "", "directory of the subtitles, temporary files and data, the working directory when empty")
+	flag.StringVar(&cfg.logFile, "log-file", "", "file the logs are appended to, standard output when empty")
+	flag.StringVar(&cfg.serviceMode, "service", "", "install or uninstall the server as a service (Windows service, launchd agent on macOS), run when started by the service")
+	flag.DurationVar(&cfg.uploadTTL, "upload-ttl", 24*time.Hour, "time after which a resumable upload is removed unless resumed, never when 0")
+	flag.DurationVar(&cfg.dedupWindow, "dedup-window", 10*time.Second, "window within which a repeated submission of the same files and options is attached to the job of the first one, disabled when 0")
+	flag.StringVar(&cfg.modelsAction, "models", "", "manage the model files of the local backends and exit: list, download NAME BACKEND URL SHA256, pin NAME, unpin NAME or remove NAME")
+	flag.StringVar(&cfg.modelsDir, "models-dir", filepath.Join(dataDir, "models"), "directory of the model files of the local backends (whisper.cpp or Vosk), shared with their servers")
+	flag.StringVar(&cfg.ssoHeader, "sso-header", os.Getenv("VIDEOSCRIBER_SSO_HEADER"), "header set with the identity of the user, e.g. X-Forwarded-Email, by the SSO proxy in front of the server, which must strip it from the requests of clients, none when empty")
+	flag.StringVar(&cfg.wsOrigins, "ws-origins", os.Getenv("VIDEOSCRIBER_WS_ORIGINS"), "comma-separated origins allowed to open websockets besides the server's own, e.g. file:// or null for the desktop app")
+	flag.StringVar(&cfg.adminUsers, "admin-users", os.Getenv("VIDEOSCRIBER_ADMIN_USERS"), "comma-separated users allowed to administer the server, e.g. its model files, anyone when authentication is disabled")
+	flag.StringVar(&cfg.featureFlags, "features", os.Getenv("VIDEOSCRIBER_FEATURES"), "comma-separated experimental features to enable, or feature=bool pairs, e.g. \"ocr,clips=false\": clips (enabled by default), live, ocr")
+	flag.BoolVar(&cfg.testMode, "test-mode", false, "run for integration tests, allowing faults to be injected")
+	flag.StringVar(&cfg.faults, "faults", "", "comma-separated faults injected in test mode: slow-provider=DURATION, failing-ffmpeg, full-disk")
+	flag.DurationVar(&cfg.janitorInterval, "janitor-interval", time.Hour, "interval of the removal of orphaned tmp files, expired subtitles and old jobs")
+	flag.StringVar(&cfg.scratchDirs, "scratch-dirs", os.Getenv("VIDEOSCRIBER_SCRATCH_DIRS"), "comma-separated directories the uploads are copied to for processing before tmp, with the bytes they can take, e.g. /mnt/nvme=20000000000; the files that don't fit spill over to the next one, and to tmp after the last")
+	flag.DurationVar(&cfg.tmpTTL, "tmp-ttl", 24*time.Hour, "time after which files left in the tmp and scratch directories are removed, never when 0; transcriptions are deferred up to half of it")
+	flag.DurationVar(&cfg.jobTTL, "job-ttl", 0, "time after which finished jobs are deleted, kept forever when 0")
+	flag.DurationVar(&cfg.cacheTTL, "cache-ttl", 0, "time the responses of the transcription providers are cached, so that the same audio with the same options isn't transcribed again, disabled when 0")
+	flag.Float64Var(&cfg.reviewThreshold, "review-threshold", 0, "quality score (0-1) below which subtitles are held for review, disabled when 0")
+	flag.Parse()
+
+	return cfg
+}
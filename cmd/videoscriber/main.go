@@ -1,18 +1,31 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
-	"github.com/alesr/audiostripper"
 	"github.com/alesr/videoscriber/internal/app/web"
-	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+	"github.com/alesr/videoscriber/internal/pkg/chaos"
+	"github.com/alesr/videoscriber/internal/pkg/ffmpeg"
+	"github.com/alesr/videoscriber/internal/pkg/llm"
+	"github.com/alesr/videoscriber/internal/pkg/models"
+	"github.com/alesr/videoscriber/internal/pkg/service"
 
-	"github.com/alesr/whisperclient"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -21,81 +34,297 @@ const (
 	whisperAIModel string = "whisper-1"
 	subtitlesDir   string = "subtitles"
 	tmpDir         string = "tmp"
-)
+	dataDir        string = "data"
+	clipsDir       string = "clips"
 
-var extractCmd audiostripper.ExtractCmd = func(params *audiostripper.ExtractCmdParams) error {
-	cmd := exec.Command(
-		"ffmpeg", "-y", "-i", params.InputFile, "-vn", "-acodec", "pcm_s16le", "-ar", params.SampleRate,
-		"-ac", "2", "-b:a", "32k", params.OutputFile,
-	)
+	uploadsInterval time.Duration = 10 * time.Minute
 
-	cmd.Stderr = params.Stderr
-	return cmd.Run()
-}
+	maxDownloadSize      int64         = 1 << 30 // 1GB
+	maxModelSize         int64         = 8 << 30 // 8GB, larger than the largest Whisper models
+	defaultMaxUploadSize int64         = 1 << 30 // 1GB, overridden by -max-upload-size or VIDEOSCRIBER_MAX_UPLOAD_SIZE
+	downloadTimeout      time.Duration = time.Hour
+
+	serviceName        string = "videoscriber"
+	serviceDisplayName string = "Videoscriber"
+	serviceDescription string = "Generates subtitles of videos"
+
+	readinessTimeout     time.Duration = 5 * time.Second
+	storageProbeInterval time.Duration = 30 * time.Second
+	openAIURL            string        = "https://api.openai.com"
+	openAIModelsURL      string        = openAIURL + "/v1/models"
+)
 
 func main() {
-	// Configurations.
+	cfg := parseFlags()
 
-	port := flag.String("port", "8080", "port to listen")
-	openAIKey := flag.String("openai-key", "", "OpenAI API key")
-	flag.Parse()
+	switch cfg.serviceMode {
+	case "", "run":
+	case "install", "uninstall":
+		if err := manageService(cfg.serviceMode, cfg.workDir); err != nil {
+			fmt.Fprintf(os.Stderr, "could not %s service: %v\n", cfg.serviceMode, err)
+			os.Exit(1)
+		}
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown service action %q, expected install or uninstall\n", cfg.serviceMode)
+		os.Exit(1)
+	}
 
-	logger := makeLogger(*port)
+	// Transcribes local files with the pipeline of the server without starting it, e.g. in scripts.
+	var batch *transcribeCommand
 
-	if *openAIKey == "" {
+	if flag.Arg(0) == "transcribe" {
+		cmd, err := parseTranscribe(flag.Args()[1:])
+		if err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "could not transcribe: %v\n", err)
+			os.Exit(2)
+		}
+		batch = cmd
+	}
+
+	// Services start in the directory of the service manager, e.g. C:\Windows\System32.
+	if cfg.workDir != "" {
+		if err := os.Chdir(cfg.workDir); err != nil {
+			fmt.Fprintf(os.Stderr, "could not change to directory %q: %v\n", cfg.workDir, err)
+			os.Exit(2)
+		}
+	}
+
+	if cfg.modelsAction != "" {
+		if err := manageModels(os.Stdout, cfg.modelsAction, cfg.modelsDir, flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "could not %s models: %v\n", cfg.modelsAction, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logOutput := io.Writer(os.Stdout)
+
+	// The output of the transcribe command is for scripts.
+	if batch != nil {
+		logOutput = os.Stderr
+	}
+
+	if cfg.logFile != "" {
+		f, err := os.OpenFile(cfg.logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not open log file: %v\n", err)
+			os.Exit(2)
+		}
+		defer f.Close()
+
+		logOutput = f
+	}
+
+	logger := makeLogger(cfg.port, logOutput)
+
+	if cfg.openAIKey == "" {
 		logger.Error("OpenAI API key is required")
 		os.Exit(1)
 	}
 
-	makeDir(logger, subtitlesDir)
-	makeDir(logger, tmpDir)
+	run(logger, cfg, batch)
+}
 
-	// Extracts audio from video.
-	audioStripper := audiostripper.New(extractCmd)
-
-	// Requests subtitles from OpenAI.
-	whisperAIClient := whisperclient.New(&http.Client{}, *openAIKey, whisperAIModel)
-
-	// Coordinate audio extraction and subtitles request in concurrent manner.
-	subtitler, err := subtitles.New(
-		logger,
-		sampleRate,
-		subtitlesDir,
-		tmpDir,
-		audioStripper,
-		whisperAIClient,
-	)
+// run wires the server and serves requests until it is stopped, or transcribes the files of the transcribe command.
+func run(logger *slog.Logger, cfg config, batch *transcribeCommand) {
+	// Injects faults so that integration tests can exercise the failure paths, never in production.
+	faults, err := chaos.Parse(cfg.faults)
 	if err != nil {
-		logger.Error("Could not initialize subtitles", slog.String("error", err.Error()))
+		logger.Error("Could not parse faults", slog.String("error", err.Error()))
 		os.Exit(3)
 	}
 
+	if faults.Any() {
+		if !cfg.testMode {
+			logger.Error("Faults can only be injected in test mode")
+			os.Exit(3)
+		}
+		logger.Warn("Injecting faults", slog.String("faults", cfg.faults))
+	}
+
+	makeDir(logger, subtitlesDir)
+	makeDir(logger, tmpDir)
+	makeDir(logger, dataDir)
+	makeDir(logger, clipsDir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := openStores(ctx, logger, cfg)
+	defer s.db.Close()
+
+	ffmpegBinary := findFFmpeg(ctx, logger, cfg)
+
+	audioExtractor := ffmpeg.NewExtractor(ffmpegBinary, strings.Fields(cfg.ffmpegArgs))
+	mediaProcessor := faults.FFmpeg(audioExtractor)
+
+	// Calls the chat and embedding models.
+	llmClient := llm.New(&http.Client{Timeout: 5 * time.Minute}, cfg.chatURL, cfg.openAIKey)
+
+	p := newPipeline(ctx, logger, cfg, faults, batch, s, audioExtractor, mediaProcessor, llmClient)
+
+	if batch != nil {
+		batchCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if err := batch.run(batchCtx, p.subtitler, os.Stdout); err != nil {
+			logger.Error("Could not transcribe files", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.watchDir != "" {
+		watchInbox(ctx, logger, cfg, p.subtitler)
+	}
+
 	// Handles requests.
-	handlers := web.NewHandlers(logger, subtitler)
+	handlers := newHandlers(ctx, logger, cfg, s, p, ffmpegBinary, mediaProcessor, llmClient)
+
+	go handlers.RunQueue(ctx)
 
 	// Starts web app.
 
-	webApp := web.NewApp(logger, *port, chi.NewRouter(), handlers)
+	webApp := web.NewApp(logger, cfg.port, cfg.grpcPort, chi.NewRouter(), handlers)
 
 	if err := webApp.Run(); err != nil {
 		logger.Error("Could not start rest app", slog.String("error", err.Error()))
 	}
 
-	// Handles OS signals.
+	// Stops on OS signals, or when the Windows service manager stops the service.
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	defer signal.Stop(c)
+	if err := service.Run(serviceName, cfg.serviceMode == "run", func(stop <-chan struct{}) error {
+		<-stop
 
-	<-c
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.drainTimeout)
+		defer cancelDrain()
 
-	if err := webApp.Stop(); err != nil {
+		return webApp.Stop(drainCtx)
+	}); err != nil {
 		logger.Error("Could not stop rest app", slog.String("error", err.Error()))
 	}
 }
 
-func makeLogger(port string) *slog.Logger {
-	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+// bootstrapFFmpeg installs the ffmpeg build of the platform pinned in the manifest, unless already installed.
+func bootstrapFFmpeg(ctx context.Context, manifestLocation string) (string, error) {
+	httpCli := &http.Client{Timeout: 10 * time.Minute}
+
+	manifest, err := ffmpeg.LoadManifest(ctx, httpCli, manifestLocation)
+	if err != nil {
+		return "", err
+	}
+	return ffmpeg.Bootstrap(ctx, httpCli, manifest, filepath.Join(dataDir, "ffmpeg"))
+}
+
+// manageModels runs a model management action of the command line, with its arguments.
+func manageModels(w io.Writer, action, dir string, args []string) error {
+	store, err := models.NewStore(&http.Client{}, dir, maxModelSize)
+	if err != nil {
+		return err
+	}
+
+	expectArgs := func(n int, usage string) error {
+		if len(args) != n {
+			return fmt.Errorf("expected %s", usage)
+		}
+		return nil
+	}
+
+	switch action {
+	case "list":
+		usage, err := store.List()
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tBACKEND\tSIZE\tPINNED\tSHA256")
+
+		for _, m := range usage.Models {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%t\t%s\n", m.Name, m.Backend, m.Size, m.Pinned, m.SHA256)
+		}
+		fmt.Fprintf(tw, "TOTAL\t\t%d\t\t\n", usage.Size)
+		return tw.Flush()
+	case "download":
+		if err := expectArgs(4, "NAME BACKEND URL SHA256"); err != nil {
+			return err
+		}
+
+		m, err := store.Download(context.Background(), models.Model{Name: args[0], Backend: args[1], URL: args[2], SHA256: args[3]})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "installed %s in %s (%d bytes)\n", m.Name, m.Path, m.Size)
+		return nil
+	case "pin", "unpin":
+		if err := expectArgs(1, "NAME"); err != nil {
+			return err
+		}
+
+		_, err := store.Pin(args[0], action == "pin")
+		return err
+	case "remove":
+		if err := expectArgs(1, "NAME"); err != nil {
+			return err
+		}
+		return store.Remove(args[0])
+	default:
+		return fmt.Errorf("unknown action %q, expected list, download, pin, unpin or remove", action)
+	}
+}
+
+// manageService installs or uninstalls the server as a service running with the flags given
+// along with -service, in the directory, the working directory when empty.
+func manageService(action, dir string) error {
+	if action == "uninstall" {
+		return service.Uninstall(serviceName)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not find executable: %w", err)
+	}
+
+	if dir == "" {
+		dir = "."
+	}
+
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("could not resolve directory: %w", err)
+	}
+
+	args := []string{"-service=run", "-dir=" + dir}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "service" && f.Name != "dir" {
+			args = append(args, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+
+	logFile := filepath.Join(dir, serviceName+".log")
+
+	// The Windows service manager doesn't capture the output of services.
+	if runtime.GOOS == "windows" && flag.Lookup("log-file").Value.String() == "" {
+		args = append(args, "-log-file="+logFile)
+	}
+
+	return service.Install(service.Config{
+		Name:        serviceName,
+		DisplayName: serviceDisplayName,
+		Description: serviceDescription,
+		Executable:  exe,
+		Args:        args,
+		Dir:         dir,
+		LogFile:     logFile,
+	})
+}
+
+func makeLogger(port string, w io.Writer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
 		AddSource: true,
 		Level:     slog.LevelDebug,
 	}).WithAttrs(func() []slog.Attr {
@@ -109,6 +338,33 @@ func makeLogger(port string) *slog.Logger {
 	}()))
 }
 
+// pipelineVersions returns the versions of the server and of ffmpeg. Those that can't be told are left out.
+func pipelineVersions(ctx context.Context, logger *slog.Logger, extractor *ffmpeg.Extractor) map[string]string {
+	versions := make(map[string]string)
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		versions["videoscriber"] = info.Main.Version
+
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				versions["videoscriber"] += "+" + s.Value
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	version, err := extractor.Version(ctx)
+	if err != nil {
+		logger.Warn("Could not tell the ffmpeg version", slog.String("error", err.Error()))
+		return versions
+	}
+
+	versions["ffmpeg"] = version
+	return versions
+}
+
 func makeDir(logger *slog.Logger, path string) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		logger.Info("Creating directory", slog.String("path", path))
@@ -119,3 +375,29 @@ func makeDir(logger *slog.Logger, path string) {
 		}
 	}
 }
+
+// splitList splits a comma-separated flag value, skipping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envInt64 returns the integer value of the environment variable, or def when it is not set.
+func envInt64(key string, def int64) int64 {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid %s %q: %v\n", key, v, err)
+		os.Exit(1)
+	}
+	return n
+}
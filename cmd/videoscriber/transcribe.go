@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+)
+
+// transcribeCommand transcribes local files with the pipeline of the server, without starting it,
+// e.g. in scripts and cron jobs: videoscriber [flags] transcribe [-language pt] [-format srt] [-out DIR] FILE...
+type transcribeCommand struct {
+	files       []string // Absolute, since the server may change its working directory.
+	language    string
+	format      subtitles.Format
+	outDir      string // Absolute.
+	concurrency int
+}
+
+// parseTranscribe parses the flags and files of the transcribe subcommand.
+func parseTranscribe(args []string) (*transcribeCommand, error) {
+	fs := flag.NewFlagSet("transcribe", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: videoscriber [flags] transcribe [flags] FILE...")
+		fs.PrintDefaults()
+	}
+
+	language := fs.String("language", subtitles.DefaultLanguage, "spoken language of the files")
+	format := fs.String("format", string(subtitles.FormatSRT), "format of the subtitles: srt, vtt, txt, html, docx, tsv or json")
+	outDir := fs.String("out", ".", "directory the subtitles are written to")
+	concurrency := fs.Int("concurrency", runtime.NumCPU(), "maximum number of files transcribed at the same time")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return nil, errors.New("no file to transcribe")
+	}
+
+	if !subtitles.SupportedLanguage(*language) {
+		return nil, fmt.Errorf("unsupported language %q", *language)
+	}
+
+	subFormat, err := subtitles.ParseFormat(*format)
+	if err != nil {
+		return nil, err
+	}
+
+	if *concurrency < 1 {
+		return nil, fmt.Errorf("concurrency must be at least 1, got %d", *concurrency)
+	}
+
+	cmd := transcribeCommand{
+		language:    *language,
+		format:      subFormat,
+		concurrency: *concurrency,
+	}
+
+	if cmd.outDir, err = filepath.Abs(*outDir); err != nil {
+		return nil, fmt.Errorf("could not resolve output directory: %w", err)
+	}
+
+	for _, file := range fs.Args() {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve %q: %w", file, err)
+		}
+
+		if info, err := os.Stat(path); err != nil {
+			return nil, err
+		} else if info.IsDir() {
+			return nil, fmt.Errorf("%s is a directory", file)
+		}
+		cmd.files = append(cmd.files, path)
+	}
+	return &cmd, nil
+}
+
+// run transcribes the files, and writes the path of the subtitle of each file, or its error, to w.
+// It fails when any file failed.
+func (c *transcribeCommand) run(ctx context.Context, subtitler *subtitles.Subtitler, w io.Writer) error {
+	inputs := make([]*subtitles.Input, 0, len(c.files))
+
+	for _, path := range c.files {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("could not open file: %w", err)
+		}
+		defer f.Close()
+
+		inputs = append(inputs, &subtitles.Input{
+			FileName: filepath.Base(path),
+			Data:     f,
+			Language: c.language,
+			Format:   c.format,
+		})
+	}
+
+	results, err := subtitler.GenerateFromAudioData(ctx, inputs)
+
+	for i, res := range results {
+		if res.Err != nil {
+			fmt.Fprintf(w, "%s\tfailed: %v\n", c.files[i], res.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\n", c.files[i], filepath.Join(c.outDir, res.Subtitle))
+	}
+	return err
+}
@@ -0,0 +1,688 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/app/web"
+	"github.com/alesr/videoscriber/internal/pkg/audio"
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/auth"
+	"github.com/alesr/videoscriber/internal/pkg/callback"
+	"github.com/alesr/videoscriber/internal/pkg/chaos"
+	"github.com/alesr/videoscriber/internal/pkg/chapters"
+	"github.com/alesr/videoscriber/internal/pkg/clips"
+	"github.com/alesr/videoscriber/internal/pkg/degraded"
+	"github.com/alesr/videoscriber/internal/pkg/email"
+	"github.com/alesr/videoscriber/internal/pkg/features"
+	"github.com/alesr/videoscriber/internal/pkg/ffmpeg"
+	"github.com/alesr/videoscriber/internal/pkg/health"
+	"github.com/alesr/videoscriber/internal/pkg/ingest"
+	"github.com/alesr/videoscriber/internal/pkg/janitor"
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/alesr/videoscriber/internal/pkg/llm"
+	"github.com/alesr/videoscriber/internal/pkg/minutes"
+	"github.com/alesr/videoscriber/internal/pkg/models"
+	"github.com/alesr/videoscriber/internal/pkg/nlp"
+	"github.com/alesr/videoscriber/internal/pkg/plans"
+	"github.com/alesr/videoscriber/internal/pkg/publish"
+	"github.com/alesr/videoscriber/internal/pkg/qa"
+	"github.com/alesr/videoscriber/internal/pkg/quota"
+	"github.com/alesr/videoscriber/internal/pkg/retention"
+	"github.com/alesr/videoscriber/internal/pkg/schedule"
+	"github.com/alesr/videoscriber/internal/pkg/scratch"
+	"github.com/alesr/videoscriber/internal/pkg/signing"
+	"github.com/alesr/videoscriber/internal/pkg/slack"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/store"
+	"github.com/alesr/videoscriber/internal/pkg/styles"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+	"github.com/alesr/videoscriber/internal/pkg/trace"
+	"github.com/alesr/videoscriber/internal/pkg/transcriber"
+	"github.com/alesr/videoscriber/internal/pkg/translate"
+	"github.com/alesr/videoscriber/internal/pkg/uploads"
+	"github.com/alesr/videoscriber/internal/pkg/users"
+	"github.com/alesr/videoscriber/internal/pkg/watch"
+	"github.com/alesr/videoscriber/internal/pkg/ytdlp"
+
+	"github.com/alesr/whisperclient"
+)
+
+// stores are the persisted state of the server, and the janitor cleaning it up.
+type stores struct {
+	scratchDirs []scratch.Dir // The configured scratch directories, without tmp.
+	scratch     *scratch.Space
+	db          *store.Store
+	catalog     *store.Catalog
+	jobs        *jobs.Manager
+	audit       *audit.Log
+	retention   *retention.Manager
+	sweeper     *janitor.Janitor
+	models      *models.Store
+	features    *features.Set
+	plans       *plans.Store
+	users       *users.Store
+	styles      *styles.Store
+}
+
+// openStores opens the stores of the server and starts their background work, e.g. the janitor.
+func openStores(ctx context.Context, logger *slog.Logger, cfg config) stores {
+	var (
+		s   stores
+		err error
+	)
+
+	// The uploads are processed on the scratch directories with room for them, e.g. a small fast disk, then tmp.
+	s.scratchDirs, err = scratch.ParseDirs(cfg.scratchDirs)
+	if err != nil {
+		logger.Error("Could not parse scratch directories", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	s.scratch, err = scratch.New(logger, append(s.scratchDirs, scratch.Dir{Path: tmpDir}))
+	if err != nil {
+		logger.Error("Could not initialize scratch directories", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	// Resolves where subtitles are stored.
+	subtitleStorage, err := storage.New(subtitlesDir, cfg.layout)
+	if err != nil {
+		logger.Error("Could not initialize storage", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	// Persists the metadata of jobs and subtitles.
+	s.db, err = store.Open(filepath.Join(dataDir, "videoscriber.db"))
+	if err != nil {
+		logger.Error("Could not open database", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	s.catalog = store.NewCatalog(s.db, subtitleStorage)
+
+	if err := s.catalog.Sync(); err != nil {
+		logger.Error("Could not sync subtitle catalog", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	// Indexes the subtitles written before the full-text index or changed on disk, without delaying the start.
+	go func() {
+		n, err := s.catalog.Reindex()
+		if err != nil {
+			logger.Error("Could not index subtitles", slog.String("error", err.Error()))
+		}
+
+		if n > 0 {
+			logger.Info("Indexed subtitles", slog.Int("count", n))
+		}
+	}()
+
+	s.jobs, err = jobs.NewManager(s.db)
+	if err != nil {
+		logger.Error("Could not initialize jobs", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	// Records deletions and policy changes.
+	s.audit = audit.New(filepath.Join(dataDir, "audit.log"))
+
+	// Enforces per-project retention policies and legal holds.
+	s.retention, err = retention.New(logger, s.catalog, filepath.Join(dataDir, "retention.json"), s.audit, cfg.retainTTL)
+	if err != nil {
+		logger.Error("Could not initialize retention", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	// Removes orphaned tmp files, expired subtitles and old jobs, so that the disk doesn't fill up.
+	s.sweeper = janitor.New(logger, s.scratch.Dirs(), cfg.tmpTTL, s.retention, s.jobs, cfg.jobTTL)
+
+	if cfg.janitorInterval <= 0 {
+		logger.Error("The janitor interval must be positive", slog.Duration("interval", cfg.janitorInterval))
+		os.Exit(3)
+	}
+
+	go s.sweeper.Run(ctx, cfg.janitorInterval)
+
+	// Installs the model files of the local backends, e.g. for offline deployments.
+	s.models, err = models.NewStore(&http.Client{}, cfg.modelsDir, maxModelSize)
+	if err != nil {
+		logger.Error("Could not initialize models", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	// Gates the experimental subsystems, which admins can toggle at runtime.
+	s.features, err = features.New(cfg.featureFlags)
+	if err != nil {
+		logger.Error("Could not initialize feature flags", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	// Binds the API keys to rate plans overriding the limits of the server.
+	s.plans, err = plans.NewStore(filepath.Join(dataDir, "plans.json"))
+	if err != nil {
+		logger.Error("Could not initialize plans", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	// Records the users seen through their API key or the SSO proxy, with their role and projects.
+	s.users, err = users.NewStore(filepath.Join(dataDir, "users.json"))
+	if err != nil {
+		logger.Error("Could not initialize users", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	// Stores the named styling profiles of burned-in subtitles.
+	s.styles, err = styles.NewStore(filepath.Join(dataDir, "styles.json"))
+	if err != nil {
+		logger.Error("Could not initialize styling profiles", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+	return s
+}
+
+// findFFmpeg returns the ffmpeg binary extracting audio from video. Unless configured, ffmpeg is looked for in the PATH,
+// and next to the binary and where package managers install it, since services get a minimal PATH.
+func findFFmpeg(ctx context.Context, logger *slog.Logger, cfg config) string {
+	ffmpegBinary := cfg.ffmpegPath
+
+	var err error
+	if ffmpegBinary != "" {
+		if ffmpegBinary, err = exec.LookPath(ffmpegBinary); err != nil {
+			logger.Error("Could not find the configured ffmpeg", slog.String("ffmpeg", cfg.ffmpegPath), slog.String("error", err.Error()))
+			os.Exit(3)
+		}
+	} else if ffmpegBinary, err = ffmpeg.Find(); err != nil && cfg.ffmpegBootstrap != "" {
+		logger.Info("Could not find ffmpeg, installing the pinned build", slog.String("manifest", cfg.ffmpegBootstrap))
+
+		if ffmpegBinary, err = bootstrapFFmpeg(ctx, cfg.ffmpegBootstrap); err != nil {
+			logger.Error("Could not install ffmpeg", slog.String("error", err.Error()))
+			os.Exit(3)
+		}
+	} else if err != nil {
+		logger.Warn("Could not find ffmpeg, relying on the PATH, WAV, MP3 and AAC audio are decoded in pure Go without it", slog.String("error", err.Error()))
+		ffmpegBinary = "ffmpeg"
+	}
+	return ffmpegBinary
+}
+
+// newProviders returns the transcription providers by name: OpenAI and, when configured, our own GPUs,
+// with the failover between their endpoints, their cache and the routing between them.
+func newProviders(ctx context.Context, logger *slog.Logger, cfg config, faults chaos.Faults) map[string]transcriber.Transcriber {
+	// The requests of the files transcribed in debug mode are traced.
+	providerCli := &http.Client{Transport: trace.NewTransport(nil)}
+
+	providers := map[string]transcriber.Transcriber{
+		transcriber.ProviderOpenAI: faults.Transcriber(transcriber.NewOpenAI(
+			whisperclient.New(providerCli, cfg.openAIKey, whisperAIModel),
+			transcriber.NewLocal(providerCli, openAIURL, cfg.openAIKey, whisperAIModel),
+			whisperAIModel,
+		)),
+	}
+
+	if cfg.localURL != "" {
+		providers[transcriber.ProviderLocal] = faults.Transcriber(transcriber.NewLocal(providerCli, cfg.localURL, cfg.localToken, cfg.localModel))
+	}
+
+	// Sends the requests of a provider to its fastest healthy endpoint, failing over to the others.
+	if cfg.endpointsFile != "" {
+		endpoints, err := transcriber.LoadEndpoints(cfg.endpointsFile)
+		if err != nil {
+			logger.Error("Could not load endpoints", slog.String("error", err.Error()))
+			os.Exit(3)
+		}
+
+		for name, configs := range endpoints {
+			primary, ok := providers[name]
+			if !ok {
+				logger.Error("Endpoints configured for an unknown provider", slog.String("provider", name))
+				os.Exit(3)
+			}
+
+			model, token := cfg.localModel, cfg.localToken
+			if name == transcriber.ProviderOpenAI {
+				model, token = whisperAIModel, cfg.openAIKey
+			}
+
+			providerEndpoints := []transcriber.Endpoint{{Name: "default", Transcriber: primary}}
+
+			for _, c := range configs {
+				if c.Model == "" {
+					c.Model = model
+				}
+
+				if c.Token == "" {
+					c.Token = token
+				}
+
+				providerEndpoints = append(providerEndpoints, transcriber.Endpoint{
+					Name:        c.Name,
+					Transcriber: faults.Transcriber(transcriber.NewLocal(providerCli, c.URL, c.Token, c.Model)),
+					HealthURL:   c.HealthURL,
+				})
+			}
+
+			failover, err := transcriber.NewFailover(logger, name, &http.Client{}, providerEndpoints)
+			if err != nil {
+				logger.Error("Could not initialize failover", slog.String("error", err.Error()))
+				os.Exit(3)
+			}
+
+			go failover.Run(ctx, cfg.endpointHealthInterval)
+			providers[name] = failover
+		}
+	}
+
+	// Serves the responses of the providers from the cache when the same audio is transcribed again.
+	if cfg.cacheTTL > 0 {
+		cache, err := transcriber.NewCache(logger, filepath.Join(dataDir, "cache"), cfg.cacheTTL)
+		if err != nil {
+			logger.Error("Could not initialize transcription cache", slog.String("error", err.Error()))
+			os.Exit(3)
+		}
+
+		go cache.Run(ctx, cfg.janitorInterval)
+
+		for name, provider := range providers {
+			providers[name] = transcriber.NewCachedTranscriber(name, provider, cache)
+		}
+	}
+
+	// Picks the provider per file according to duration, language, priority and tenant.
+	if cfg.routingPolicy != "" {
+		policy, err := transcriber.LoadPolicy(cfg.routingPolicy)
+		if err != nil {
+			logger.Error("Could not load routing policy", slog.String("error", err.Error()))
+			os.Exit(3)
+		}
+
+		router, err := transcriber.NewRouter(policy, maps.Clone(providers))
+		if err != nil {
+			logger.Error("Could not initialize routing", slog.String("error", err.Error()))
+			os.Exit(3)
+		}
+		providers[transcriber.ProviderAuto] = router
+	}
+	return providers
+}
+
+// pipeline is the subtitler coordinating the audio extraction and the transcription of the files,
+// and the monitor of the storage it writes the subtitles to.
+type pipeline struct {
+	subtitler *subtitles.Subtitler
+	monitor   *degraded.Monitor
+	signer    *signing.Signer
+}
+
+// newPipeline wires the subtitler. The transcribe command writes the subtitles to its output directory,
+// without keeping the audio by default.
+func newPipeline(
+	ctx context.Context,
+	logger *slog.Logger,
+	cfg config,
+	faults chaos.Faults,
+	batch *transcribeCommand,
+	s stores,
+	extractor *ffmpeg.Extractor,
+	mediaProcessor chaos.FFmpeg,
+	llmClient *llm.Client,
+) pipeline {
+	providers := newProviders(ctx, logger, cfg, faults)
+
+	// Analyzes the sentiment and topics of transcripts.
+	analyzer, err := nlp.New(cfg.nlpProvider, &http.Client{Timeout: time.Minute}, cfg.nlpURL, cfg.nlpToken)
+	if err != nil {
+		logger.Error("Could not initialize NLP provider", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// Defers low priority transcriptions to stay under the provider limits and the daily spend cap.
+	var schedulingPolicy schedule.Policy
+
+	if cfg.schedulePolicy != "" {
+		schedulingPolicy, err = schedule.LoadPolicy(cfg.schedulePolicy)
+		if err != nil {
+			logger.Error("Could not load scheduling policy", slog.String("error", err.Error()))
+			os.Exit(3)
+		}
+	}
+
+	// Deferred files wait in the tmp directory, so they are transcribed well before the janitor removes them.
+	scheduleHorizon := schedule.DefaultHorizon
+	if cfg.tmpTTL > 0 {
+		scheduleHorizon = min(scheduleHorizon, cfg.tmpTTL/2)
+	}
+
+	// Translates the cues of subtitles to other languages.
+	translator, err := translate.New(cfg.translationProvider, llmClient, cfg.translationModel, &http.Client{Timeout: time.Minute}, cfg.deeplURL, cfg.deeplKey)
+	if err != nil {
+		logger.Error("Could not initialize translation provider", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// Splits the transcripts into chapters.
+	chapterDetector, err := chapters.New(cfg.chaptersProvider, llmClient, cfg.chaptersModel)
+	if err != nil {
+		logger.Error("Could not initialize chapters provider", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// Recorded in the pipeline of each subtitle, so that it can be reprocessed with the same configuration.
+	versions := pipelineVersions(ctx, logger, extractor)
+	versions["nlp"] = cfg.nlpProvider
+	versions["minutes_model"] = cfg.minutesModel
+	versions["translation"] = cfg.translationProvider
+	versions["chapters"] = cfg.chaptersProvider
+
+	if cfg.chaptersProvider == chapters.ProviderChat {
+		versions["chapters_model"] = cfg.chaptersModel
+	}
+
+	if cfg.translationProvider == translate.ProviderChat {
+		versions["translation_model"] = cfg.translationModel
+	}
+
+	// The extra arguments change the extracted audio.
+	if cfg.ffmpegArgs != "" {
+		versions["ffmpeg_args"] = cfg.ffmpegArgs
+	}
+
+	retain, err := subtitles.ParseRetain(cfg.retainMedia)
+	if err != nil {
+		logger.Error("Could not parse media retention", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	if !cfg.keepAudio {
+		retain = subtitles.RetainNone
+	}
+
+	filters, err := subtitles.ParseFilters(cfg.preprocess)
+	if err != nil {
+		logger.Error("Could not parse audio filters", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	var projectSteps subtitles.ProjectSteps
+	if cfg.pipelinesFile != "" {
+		if projectSteps, err = subtitles.LoadProjectSteps(cfg.pipelinesFile); err != nil {
+			logger.Error("Could not load pipelines", slog.String("error", err.Error()))
+			os.Exit(3)
+		}
+	}
+
+	// Without a key, the subtitles and artifacts are stored unsigned.
+	signer := signing.New(nil)
+	if cfg.signingKey != "" {
+		key, err := signing.LoadKey(cfg.signingKey)
+		if err != nil {
+			logger.Error("Could not load signing key", slog.String("error", err.Error()))
+			os.Exit(3)
+		}
+		signer = signing.New(key)
+	}
+
+	var (
+		subtitleWriter chaos.Storage = s.catalog
+		storageDir                   = subtitlesDir
+		concurrency                  = cfg.maxConcurrency
+		fastLane                     = cfg.fastLaneWorkers
+	)
+
+	if batch != nil {
+		subtitleWriter, err = storage.New(batch.outDir, "{name}")
+		if err != nil {
+			logger.Error("Could not initialize output directory", slog.String("error", err.Error()))
+			os.Exit(3)
+		}
+		storageDir = batch.outDir
+
+		if cfg.retainMedia == "" {
+			retain = subtitles.RetainNone
+		}
+		concurrency = batch.concurrency
+		fastLane = 0
+	}
+
+	// Detects when the subtitles can't be stored, e.g. when their directory is remounted read-only
+	// or the disk is full, so that the new jobs are rejected or queued instead of failing one by one.
+	monitor := degraded.New(logger, storageDir)
+	monitor.Probe()
+
+	go monitor.Run(ctx, storageProbeInterval)
+
+	// Coordinate audio extraction and subtitles request in concurrent manner.
+	subtitler, err := subtitles.New(subtitles.Config{
+		Logger:          logger,
+		SampleRate:      sampleRate,
+		Scratch:         s.scratch,
+		Storage:         degraded.NewStorage(signing.NewStorage(faults.Storage(subtitleWriter), signer), monitor),
+		Extractor:       audio.NewFallback(logger, mediaProcessor),
+		Providers:       providers,
+		DefaultProvider: cfg.provider,
+		Analyzer:        analyzer,
+		Minutes:         minutes.NewWriter(llmClient, cfg.minutesModel),
+		Chapters:        chapterDetector,
+		Translator:      translator,
+		Scheduler:       schedule.New(schedulingPolicy, scheduleHorizon),
+		Filters:         filters,
+		ProjectSteps:    projectSteps,
+		Versions:        versions,
+		Retain:          retain,
+		FirstCueIndex:   cfg.firstCueIndex,
+		Layout:          srt.Layout{MaxLineLength: cfg.maxLineLength, MaxLines: cfg.maxLines, MaxCPS: cfg.maxCPS},
+		SecondPass:      subtitles.SecondPass{Threshold: cfg.secondPassThreshold, MaxShare: cfg.secondPassMaxShare, Provider: cfg.secondPassProvider, Model: cfg.secondPassModel},
+		MaxConcurrency:  concurrency,
+		FastLaneWorkers: fastLane,
+		ShortClip:       cfg.shortClip,
+	})
+	if err != nil {
+		logger.Error("Could not initialize subtitles", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+	return pipeline{subtitler: subtitler, monitor: monitor, signer: signer}
+}
+
+// watchInbox transcribes the files dropped into the inbox, e.g. by a NAS or a media server.
+func watchInbox(ctx context.Context, logger *slog.Logger, cfg config, subtitler *subtitles.Subtitler) {
+	if cfg.watchInterval <= 0 {
+		logger.Error("The watch interval must be positive", slog.Duration("interval", cfg.watchInterval))
+		os.Exit(3)
+	}
+
+	if !subtitles.SupportedLanguage(cfg.watchLanguage) {
+		logger.Error("Unsupported watch language", slog.String("language", cfg.watchLanguage))
+		os.Exit(3)
+	}
+
+	watcher, err := watch.New(logger, subtitler, cfg.watchDir, cfg.watchLanguage, subtitles.FormatSRT)
+	if err != nil {
+		logger.Error("Could not initialize the inbox watcher", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	if cfg.watchPoll {
+		go watcher.RunPolling(ctx, cfg.watchInterval)
+		return
+	}
+	go watcher.Run(ctx, cfg.watchInterval)
+}
+
+// newPublishers returns the publishers of subtitles as captions of videos on the platforms, with per-project credentials.
+func newPublishers(logger *slog.Logger) map[string]publish.Publisher {
+	youtubePublisher, err := publish.NewYouTube(&http.Client{}, filepath.Join(dataDir, "youtube.json"))
+	if err != nil {
+		logger.Error("Could not initialize YouTube publishing", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	vimeoPublisher, err := publish.NewVimeo(&http.Client{}, filepath.Join(dataDir, "vimeo.json"))
+	if err != nil {
+		logger.Error("Could not initialize Vimeo publishing", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	wistiaPublisher, err := publish.NewWistia(&http.Client{}, filepath.Join(dataDir, "wistia.json"))
+	if err != nil {
+		logger.Error("Could not initialize Wistia publishing", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	webhookPublisher, err := publish.NewWebhook(&http.Client{Timeout: time.Minute}, filepath.Join(dataDir, "webhook.json"))
+	if err != nil {
+		logger.Error("Could not initialize webhook publishing", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	return map[string]publish.Publisher{
+		publish.PlatformYouTube: youtubePublisher,
+		publish.PlatformVimeo:   vimeoPublisher,
+		publish.PlatformWistia:  wistiaPublisher,
+		publish.PlatformWebhook: webhookPublisher,
+	}
+}
+
+// signedCatalog is the catalog of the handlers, signing the subtitles they write or edit, e.g. split parts
+// or edited cues, like the pipeline signs those it generates.
+type signedCatalog struct {
+	*store.Catalog
+	signed signing.Catalog
+}
+
+func (c signedCatalog) Write(owner, language, project, fileName string, data []byte) (string, error) {
+	return c.signed.Write(owner, language, project, fileName, data)
+}
+
+func (c signedCatalog) Replace(obj storage.Object, data []byte) error {
+	return c.signed.Replace(obj, data)
+}
+
+func (c signedCatalog) Revise(review store.Review, data []byte) error {
+	return c.signed.Replace(review.Object(), data)
+}
+
+// newHandlers wires the handlers of the requests.
+func newHandlers(
+	ctx context.Context,
+	logger *slog.Logger,
+	cfg config,
+	s stores,
+	p pipeline,
+	ffmpegBinary string,
+	mediaProcessor chaos.FFmpeg,
+	llmClient *llm.Client,
+) *web.Handlers {
+	// Downloads media files from URLs, and only their audio from video platforms when yt-dlp is enabled.
+	var fetcher ingest.Source = ingest.New(maxDownloadSize, downloadTimeout)
+
+	if cfg.ytdlpEnabled {
+		var err error
+		fetcher, err = ytdlp.New(cfg.ytdlpBinary, tmpDir, maxDownloadSize, downloadTimeout, fetcher)
+		if err != nil {
+			logger.Error("Could not initialize yt-dlp", slog.String("error", err.Error()))
+			os.Exit(3)
+		}
+	}
+
+	// Keeps the chunks of resumable uploads until they complete or expire.
+	uploadStore, err := uploads.NewStore(logger, filepath.Join(tmpDir, "uploads"), cfg.maxUploadSize, cfg.uploadTTL)
+	if err != nil {
+		logger.Error("Could not initialize uploads", slog.String("error", err.Error()))
+		os.Exit(3)
+	}
+
+	if cfg.uploadTTL > 0 {
+		go uploadStore.Run(ctx, min(cfg.uploadTTL, uploadsInterval))
+	}
+
+	var jobQueue *degraded.Queue
+	switch {
+	case cfg.queueDir != "":
+		if jobQueue, err = degraded.NewQueue(cfg.queueDir); err != nil {
+			logger.Error("Could not initialize queue", slog.String("error", err.Error()))
+			os.Exit(3)
+		}
+	case cfg.queueMemory:
+		jobQueue = degraded.NewMemoryQueue()
+	}
+
+	slackClient := slack.New(&http.Client{Timeout: 30 * time.Second}, slack.Config{
+		SigningSecret: cfg.slackSigningSecret,
+		BotToken:      cfg.slackBotToken,
+	})
+
+	// The callback URLs are given by users, who mustn't reach the internal services through the server.
+	callbacks := callback.New(&http.Client{Transport: ingest.PublicTransport(), Timeout: 30 * time.Second}, cfg.callbackSecret)
+
+	// Identifies the users of the API, whose subtitles are kept apart.
+	keys, err := auth.ParseKeys(cfg.apiKeys)
+	if err != nil {
+		logger.Error("Could not parse API keys", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// Checks the dependencies needed to serve requests, for readiness probes.
+	readinessChecks := []health.Check{
+		health.Command("ffmpeg", ffmpegBinary, "-version"),
+		health.Writable("tmp", tmpDir),
+		// The stored subtitles are still served while they can't be written, so the server stays ready.
+		{Name: "subtitles", Check: p.monitor.Check, Degrades: true},
+		{Name: "database", Check: s.db.Ping},
+	}
+
+	for _, dir := range s.scratchDirs {
+		readinessChecks = append(readinessChecks, health.Writable("scratch "+dir.Path, dir.Path))
+	}
+
+	if cfg.readyOpenAI {
+		readinessChecks = append(readinessChecks, health.HTTP("openai", &http.Client{}, openAIModelsURL, cfg.openAIKey))
+	}
+
+	return web.NewHandlers(web.HandlersConfig{
+		Logger:          logger,
+		Subtitler:       p.subtitler,
+		Jobs:            s.jobs,
+		Storage:         signedCatalog{Catalog: s.catalog, signed: signing.NewCatalog(s.catalog, p.signer)},
+		Retention:       s.retention,
+		Styles:          s.styles,
+		Publishers:      newPublishers(logger),
+		Auditor:         s.audit,
+		Ingest:          fetcher,
+		Slack:           slackClient,
+		Inbox:           email.NewInbox(&http.Client{Timeout: 30 * time.Second}, cfg.emailTopic, splitList(cfg.emailAllowed)),
+		Mailer:          email.NewSender(cfg.smtpAddr, cfg.smtpUser, cfg.smtpPassword, cfg.smtpFrom),
+		Uploads:         uploadStore,
+		Clips:           clips.New(logger, tmpDir, clipsDir, mediaProcessor),
+		Auth:            keys,
+		Limiter:         quota.NewLimiter(cfg.rateLimit, s.plans),
+		Quota:           quota.NewAccountant(s.db, time.Duration(cfg.monthlyMinutes)*time.Minute, s.plans),
+		Assistant:       qa.New(llmClient, s.db, cfg.askModel, cfg.embeddingModel),
+		Readiness:       health.New(readinessTimeout, readinessChecks...),
+		Models:          s.models,
+		Features:        s.features,
+		Sweeper:         s.sweeper,
+		Plans:           s.plans,
+		Users:           s.users,
+		Callbacks:       callbacks,
+		Signer:          p.signer,
+		Monitor:         p.monitor,
+		Queue:           jobQueue,
+		MaxUploadSize:   cfg.maxUploadSize,
+		UploadRate:      cfg.uploadRate,
+		PublicURL:       cfg.publicURL,
+		NotifyEmails:    splitList(cfg.notifyEmails),
+		NotifyMinFiles:  cfg.notifyMinFiles,
+		SSOHeader:       cfg.ssoHeader,
+		ReviewThreshold: cfg.reviewThreshold,
+		DedupWindow:     cfg.dedupWindow,
+		Admins:          splitList(cfg.adminUsers),
+		WSOrigins:       splitList(cfg.wsOrigins),
+	})
+}
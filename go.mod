@@ -3,7 +3,19 @@ module github.com/alesr/videoscriber
 go 1.21.0
 
 require (
-	github.com/alesr/audiostripper v0.0.0-20230828105950-de355ed9b475
 	github.com/alesr/whisperclient v0.0.0-20230822131735-ec185102ef54
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/go-chi/chi/v5 v5.0.10
+	github.com/gorilla/websocket v1.5.1
+	github.com/hajimehoshi/go-mp3 v0.3.4
+	github.com/mattn/go-sqlite3 v1.14.22
+	google.golang.org/grpc v1.65.0
+	google.golang.org/protobuf v1.34.2
+)
+
+require (
+	golang.org/x/net v0.25.0 // indirect
+	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240528184218-531527333157 // indirect
 )
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/qa"
+	"github.com/alesr/videoscriber/internal/pkg/retention"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxQuestionLength is the maximum length in bytes of a question about a project.
+const maxQuestionLength int = 2000
+
+type askRequest struct {
+	Question string `json:"question"`
+}
+
+// askProject answers a question from the transcripts of a project, citing the passages
+// and their timestamps the answer is based on.
+func (h *Handlers) askProject(w http.ResponseWriter, r *http.Request) {
+	project := chi.URLParam(r, "project")
+
+	if err := retention.ValidateProject(project); err != nil {
+		h.e(w, "Invalid project name", err, http.StatusBadRequest)
+		return
+	}
+
+	var req askRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	req.Question = strings.TrimSpace(req.Question)
+	if req.Question == "" || len(req.Question) > maxQuestionLength {
+		h.e(w, "Invalid question", nil, http.StatusBadRequest)
+		return
+	}
+
+	subs, err := h.storage.Subtitles(owner(r))
+	if err != nil {
+		h.e(w, "Failed to list subtitles", err, http.StatusInternalServerError)
+		return
+	}
+
+	var docs []qa.Document
+	for _, sub := range subs {
+		if sub.Project != project || !publishable(sub.Name) {
+			continue
+		}
+
+		data, err := h.storage.Read(sub.Object())
+		if err != nil {
+			h.e(w, "Failed to read subtitle", err, http.StatusInternalServerError)
+			return
+		}
+		docs = append(docs, qa.Document{Name: sub.Name, Path: sub.Object().Path, Data: data})
+	}
+
+	answer, err := h.assistant.Ask(r.Context(), req.Question, docs)
+	if err != nil {
+		if errors.Is(err, qa.ErrNoTranscripts) {
+			h.e(w, "No transcripts in project", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to answer question", err, http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(answer); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,92 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/users"
+)
+
+type ownerKey struct{}
+
+// authEnabled reports whether callers are identified, by their API key or by the SSO proxy.
+func (h *Handlers) authEnabled() bool {
+	return h.auth.Enabled() || h.ssoHeader != ""
+}
+
+// authenticate identifies the caller by the identity the SSO proxy in front of the server sets in the SSO header,
+// or by the API key sent as a bearer token or in the X-API-Key header, and scopes the request to the caller's
+// namespace. Without configured keys nor SSO, requests are anonymous and share the default namespace.
+func (h *Handlers) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.authEnabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var (
+			user users.User
+			err  error
+		)
+
+		if identity := r.Header.Get(h.ssoHeader); h.ssoHeader != "" && identity != "" {
+			user, err = h.users.Resolve(identity)
+		} else {
+			key := r.Header.Get("X-API-Key")
+			if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+				key = token
+			}
+
+			name, ok := h.auth.User(key)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="videoscriber"`)
+				h.e(w, "Invalid or missing API key", nil, http.StatusUnauthorized)
+				return
+			}
+			user, err = h.users.Seen(name)
+		}
+
+		if err != nil {
+			h.e(w, "Failed to record user", err, http.StatusInternalServerError)
+			return
+		}
+
+		if user.Role == users.RoleDisabled {
+			h.e(w, "The user is disabled", nil, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ownerKey{}, user)))
+	})
+}
+
+// owner returns the authenticated caller of the request, or an empty string for anonymous requests.
+func owner(r *http.Request) string {
+	user, _ := r.Context().Value(ownerKey{}).(users.User)
+	return user.Name
+}
+
+// canAccessProject reports whether the caller can submit files to the project and manage it.
+// Anonymous callers can access all of them.
+func canAccessProject(r *http.Request, project string) bool {
+	user, ok := r.Context().Value(ownerKey{}).(users.User)
+	return !ok || user.CanAccess(project)
+}
+
+// requireAdmin restricts the administration of the server, e.g. its model files, to the admin users,
+// configured or given the admin role. Without configured keys nor SSO, the anonymous caller administers the server.
+func (h *Handlers) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.isAdmin(r) {
+			h.e(w, "Only admins can administer the server", nil, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAdmin reports whether the caller administers the server, see requireAdmin.
+func (h *Handlers) isAdmin(r *http.Request) bool {
+	user, _ := r.Context().Value(ownerKey{}).(users.User)
+	return !h.authEnabled() || h.admins[user.Name] || user.Role == users.RoleAdmin
+}
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+)
+
+type backfillRequest struct {
+	Retag  bool `json:"retag"`
+	DryRun bool `json:"dry_run"`
+}
+
+type backfilledSubtitle struct {
+	subtitleMetadata
+	PreviousLanguage string `json:"previous_language"`
+	Owner            string `json:"owner"`
+	Error            string `json:"error,omitempty"`
+}
+
+type backfillResponse struct {
+	DryRun    bool                 `json:"dry_run"`
+	Updated   int                  `json:"updated"`
+	Failed    int                  `json:"failed"`
+	Subtitles []backfilledSubtitle `json:"subtitles"`
+}
+
+// backfillSubtitles records the language, duration and number of cues of the subtitles stored before the
+// catalog recorded them, so that they can be listed and searched like the new ones. The body is optional.
+func (h *Handlers) backfillSubtitles(w http.ResponseWriter, r *http.Request) {
+	var req backfillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.storage.Backfill(req.Retag, req.DryRun)
+	if err != nil {
+		h.e(w, "Failed to backfill subtitles", err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := backfillResponse{DryRun: req.DryRun, Subtitles: make([]backfilledSubtitle, 0, len(results))}
+	for _, res := range results {
+		sub := backfilledSubtitle{
+			subtitleMetadata: newSubtitleMetadata(res.Subtitle),
+			PreviousLanguage: res.PreviousLanguage,
+			Owner:            res.Subtitle.Owner,
+		}
+
+		if res.Err != nil {
+			sub.Error = res.Err.Error()
+			resp.Failed++
+		} else {
+			resp.Updated++
+		}
+		resp.Subtitles = append(resp.Subtitles, sub)
+	}
+
+	if !req.DryRun {
+		h.record(audit.Entry{
+			Action:  "subtitles.backfill",
+			Actor:   owner(r),
+			Address: r.RemoteAddr,
+			Details: map[string]string{"retag": strconv.FormatBool(req.Retag), "updated": strconv.Itoa(resp.Updated), "failed": strconv.Itoa(resp.Failed)},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/callback"
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+)
+
+// errCallbacksDisabled is returned when a request sets a callback URL while no callback secret is configured.
+var errCallbacksDisabled = errors.New("callbacks are not enabled")
+
+// validateCallback checks the callback URL of a request. Empty URLs are valid, for jobs without callback.
+func (h *Handlers) validateCallback(callbackURL string) error {
+	if callbackURL == "" {
+		return nil
+	}
+
+	if !h.callbacks.Enabled() {
+		return errCallbacksDisabled
+	}
+	return callback.ValidateURL(callbackURL)
+}
+
+// notifyCallback posts the outcome of the finished job to its callback URL.
+func (h *Handlers) notifyCallback(ctx context.Context, jobID, callbackURL string) {
+	job, err := h.jobs.Get(jobID)
+	if err != nil {
+		h.logger.Error("Could not get job", slog.String("job_id", jobID), slog.String("error", err.Error()))
+		return
+	}
+
+	p := callback.Payload{
+		JobID:      job.ID,
+		Status:     string(job.State),
+		Files:      make([]callback.File, 0, len(job.Files)),
+		FinishedAt: time.Now().UTC(),
+	}
+
+	for _, f := range job.Files {
+		file := callback.File{
+			Name:     f.Name,
+			Status:   string(f.State),
+			Error:    f.Error,
+			Subtitle: f.Subtitle,
+		}
+
+		if f.State == jobs.StateDone && f.Subtitle != "" {
+			file.DownloadURL = h.link(subtitlePath(f))
+		}
+		p.Files = append(p.Files, file)
+	}
+
+	if err := h.callbacks.Notify(ctx, callbackURL, p); err != nil {
+		h.logger.Error("Could not notify callback", slog.String("job_id", jobID), slog.String("error", err.Error()))
+	}
+}
+
+// subtitlePath returns the path of the subtitle of a done job file, scoped to its project.
+func subtitlePath(f jobs.File) string {
+	return "/subtitles/" + url.PathEscape(f.Subtitle) + "?project=" + url.QueryEscape(f.Project)
+}
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/chapters"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+	"github.com/go-chi/chi/v5"
+)
+
+// subtitleChapters returns the chapters detected for a subtitle uploaded with the chapters option, as JSON,
+// as the timestamps of a YouTube description (format=youtube), or as a WebVTT chapters track (format=vtt).
+func (h *Handlers) subtitleChapters(w http.ResponseWriter, r *http.Request) {
+	subName := chi.URLParam(r, "name")
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	if format != "json" && format != "youtube" && format != "vtt" {
+		h.e(w, "Invalid format, must be json, youtube or vtt", nil, http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.findSubtitle(r, subName)
+	if err != nil {
+		h.subtitleError(w, err)
+		return
+	}
+
+	obj, err := h.findAlongside(sub, subtitles.ChaptersName(subName))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.e(w, "The subtitle has no chapters", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to find chapters", err, http.StatusInternalServerError)
+		return
+	}
+
+	data, err := h.storage.Read(obj)
+	if err != nil {
+		h.e(w, "Failed to read chapters", err, http.StatusInternalServerError)
+		return
+	}
+
+	chs, err := chapters.Parse(data)
+	if err != nil {
+		h.e(w, "Failed to parse chapters", err, http.StatusInternalServerError)
+		return
+	}
+
+	base := strings.TrimSuffix(obj.Name, ".json")
+
+	switch format {
+	case "youtube":
+		w.Header().Set("Content-Type", subtitles.FormatText.ContentType())
+		w.Header().Set("Content-Disposition", "attachment; filename="+base+".txt")
+		w.Write(chapters.YouTube(chs))
+	case "vtt":
+		w.Header().Set("Content-Type", subtitles.FormatVTT.ContentType())
+		w.Header().Set("Content-Disposition", "attachment; filename="+base+".vtt")
+		w.Write(chapters.VTT(chs))
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
@@ -0,0 +1,210 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/alesr/videoscriber/internal/pkg/clips"
+	"github.com/alesr/videoscriber/internal/pkg/highlights"
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/styles"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxClips bounds the number of clips of a single job.
+const maxClips int = 20
+
+// createClips starts a job cutting clips of a video for social media, with the captions of the given
+// time ranges burned in, in vertical and square variants by default. The request is multipart:
+// the video as "file", its subtitle as "subtitle" (uploaded or the name of a stored one),
+// the ranges as "ranges" (a JSON array of {start, end, name} in seconds) or "highlights",
+// the number of highlights to detect in the subtitle and clip, and optionally
+// "variants" (e.g. vertical,square,original) and "style", the name of a styling profile.
+func (h *Handlers) createClips(w http.ResponseWriter, r *http.Request) {
+	if err := h.parseMultipartForm(w, r); err != nil {
+		h.multipartError(w, err)
+		return
+	}
+
+	video, header, err := r.FormFile("file")
+	if err != nil {
+		h.e(w, "No file part in request", err, http.StatusBadRequest)
+		return
+	}
+	defer video.Close()
+
+	subData, err := h.subtitleData(r)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrAmbiguous) {
+			h.subtitleError(w, err)
+			return
+		}
+		h.e(w, "Failed to read the subtitle", err, http.StatusBadRequest)
+		return
+	}
+
+	cues, _, err := parseCues(subData)
+	if err != nil {
+		h.e(w, "Failed to parse the subtitle", err, http.StatusBadRequest)
+		return
+	}
+
+	var ranges []splitRange
+
+	if count := r.FormValue("highlights"); count != "" {
+		n, err := highlightCount(count)
+		if err != nil {
+			h.e(w, "Invalid highlights", err, http.StatusBadRequest)
+			return
+		}
+
+		for i, hl := range highlights.Detect(cues, n) {
+			ranges = append(ranges, splitRange{
+				Start: hl.Start.Seconds(),
+				End:   hl.End.Seconds(),
+				Name:  fmt.Sprintf("highlight %d", i+1),
+			})
+		}
+
+		if len(ranges) == 0 {
+			h.e(w, "No highlights found in the subtitle", nil, http.StatusUnprocessableEntity)
+			return
+		}
+	} else if err := json.Unmarshal([]byte(r.FormValue("ranges")), &ranges); err != nil {
+		h.e(w, "Invalid ranges", err, http.StatusBadRequest)
+		return
+	}
+
+	if len(ranges) == 0 || len(ranges) > maxClips {
+		h.e(w, fmt.Sprintf("Between 1 and %d ranges are required", maxClips), nil, http.StatusBadRequest)
+		return
+	}
+
+	clipRanges := make([]clips.Range, 0, len(ranges))
+	names := make([]string, 0, len(ranges))
+
+	for i, rg := range ranges {
+		clipRange := clips.Range{Start: seconds(rg.Start), End: seconds(rg.End), Name: rg.Name}
+		if err := clipRange.Validate(); err != nil {
+			h.e(w, fmt.Sprintf("Invalid range %d", i+1), err, http.StatusBadRequest)
+			return
+		}
+
+		name := rg.Name
+		if name == "" {
+			name = fmt.Sprintf("clip %d", i+1)
+		}
+
+		clipRanges = append(clipRanges, clipRange)
+		names = append(names, name)
+	}
+
+	variants, err := clips.ParseVariants(r.FormValue("variants"))
+	if err != nil {
+		h.e(w, "Invalid variants", err, http.StatusBadRequest)
+		return
+	}
+
+	var forceStyle string
+	if name := r.FormValue("style"); name != "" {
+		profile, err := h.styles.Get(name)
+		if err != nil {
+			if errors.Is(err, styles.ErrNotFound) {
+				h.e(w, "Styling profile not found", err, http.StatusBadRequest)
+				return
+			}
+			h.e(w, "Failed to get styling profile", err, http.StatusInternalServerError)
+			return
+		}
+		forceStyle = profile.ForceStyle()
+	}
+
+	videoPath, err := h.clips.Prepare(header.Filename, video)
+	if err != nil {
+		h.e(w, "Failed to store the video", err, http.StatusInternalServerError)
+		return
+	}
+
+	job, err := h.jobs.Create(jobs.TypeClips, owner(r), names)
+	if err != nil {
+		os.Remove(videoPath)
+		h.e(w, "Failed to create job", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.background(func(ctx context.Context) {
+		h.renderClips(ctx, job.ID, videoPath, cues, clipRanges, variants, forceStyle)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	json.NewEncoder(w).Encode(uploadResponse{
+		Message: "Clips generation started",
+		JobID:   job.ID,
+	})
+}
+
+// renderClips renders the clips of the job one after the other, then removes the video.
+func (h *Handlers) renderClips(ctx context.Context, jobID, videoPath string, cues []srt.Cue, ranges []clips.Range, variants []clips.Variant, forceStyle string) {
+	defer os.Remove(videoPath)
+
+	setState := func(index int, state jobs.State, progress float64, err error) {
+		if err := h.jobs.SetFileState(jobID, index, state, progress, err); err != nil {
+			h.logger.Error("Could not update job", slog.String("job_id", jobID), slog.String("error", err.Error()))
+		}
+	}
+
+	for i, rg := range ranges {
+		setState(i, jobs.StateRendering, 0, nil)
+
+		outputs, err := h.clips.Render(ctx, jobID, i, videoPath, cues, rg, variants, forceStyle)
+
+		if len(outputs) > 0 {
+			if err := h.jobs.SetFileOutputs(jobID, i, outputs); err != nil {
+				h.logger.Error("Could not update job", slog.String("job_id", jobID), slog.String("error", err.Error()))
+			}
+		}
+
+		if err != nil {
+			h.logger.Error("Could not render clip", slog.String("job_id", jobID), slog.String("error", err.Error()))
+			setState(i, jobs.StateFailed, 0, err)
+			continue
+		}
+		setState(i, jobs.StateDone, 1, nil)
+	}
+}
+
+// clipFile serves a clip rendered by a job of the caller. The clips of the others are not found, as their jobs aren't.
+func (h *Handlers) clipFile(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "id")
+
+	if _, err := h.ownedJob(r, jobID); err != nil {
+		if errors.Is(err, jobs.ErrNotFound) {
+			h.e(w, "Clip not found", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to get job", err, http.StatusInternalServerError)
+		return
+	}
+
+	path, err := h.clips.Path(jobID, chi.URLParam(r, "name"))
+	if err != nil {
+		if errors.Is(err, clips.ErrNotFound) {
+			h.e(w, "Clip not found", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to find clip", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/mp4")
+	http.ServeFile(w, r, path)
+}
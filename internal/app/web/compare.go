@@ -0,0 +1,156 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/quality"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+)
+
+type referenceScores struct {
+	WER quality.Score `json:"wer"`
+	CER quality.Score `json:"cer"`
+}
+
+type compareResponse struct {
+	Providers []string                   `json:"providers"`
+	Subtitles map[string]string          `json:"subtitles"`
+	Reference map[string]referenceScores `json:"reference,omitempty"`
+	Agreement quality.Score              `json:"agreement"` // WER of the second provider against the first.
+	CueDiffs  []quality.CueDiff          `json:"cue_diffs,omitempty"`
+}
+
+// compareProviders transcribes a sample with two providers and reports how they differ,
+// scoring both against a reference transcript when one is given.
+func (h *Handlers) compareProviders(w http.ResponseWriter, r *http.Request) {
+	if err := h.parseMultipartForm(w, r); err != nil {
+		h.multipartError(w, err)
+		return
+	}
+
+	uploadedFile, header, err := r.FormFile("file")
+	if err != nil {
+		h.e(w, "No file part in request", err, http.StatusBadRequest)
+		return
+	}
+	defer uploadedFile.Close()
+
+	providers := []string{r.FormValue("provider_a"), r.FormValue("provider_b")}
+	for _, p := range providers {
+		if !h.subtitler.HasProvider(p) {
+			h.e(w, fmt.Sprintf("Unknown provider %q", p), nil, http.StatusBadRequest)
+			return
+		}
+	}
+
+	language := r.FormValue("language")
+	if language == "" {
+		language = subtitles.DefaultLanguage
+	}
+
+	if !subtitles.SupportedLanguage(language) {
+		h.e(w, fmt.Sprintf("Unsupported language %q", language), nil, http.StatusBadRequest)
+		return
+	}
+
+	reference, err := formText(r, "reference")
+	if err != nil {
+		h.e(w, "Failed to read the reference transcript", err, http.StatusBadRequest)
+		return
+	}
+
+	results, duration, err := h.subtitler.TranscribeWith(r.Context(), &subtitles.Input{
+		Data:     uploadedFile,
+		FileName: header.Filename,
+		Language: language,
+		Owner:    owner(r),
+		Tenant:   owner(r),
+	}, providers)
+	if err != nil {
+		h.e(w, "Failed to transcribe the sample", err, http.StatusInternalServerError)
+		return
+	}
+
+	// Each provider transcribed the whole sample.
+	if err := h.quota.Record(owner(r), duration*time.Duration(len(providers))); err != nil {
+		h.logger.Error("Could not record usage", slog.String("owner", owner(r)), slog.String("error", err.Error()))
+	}
+
+	cues := make([][]srt.Cue, len(providers))
+	for i, p := range providers {
+		parsed, err := srt.Parse(results[p])
+		if err != nil {
+			h.e(w, fmt.Sprintf("Failed to parse the subtitle of %s", p), err, http.StatusBadGateway)
+			return
+		}
+		cues[i] = parsed
+	}
+
+	resp := compareResponse{
+		Providers: providers,
+		Subtitles: make(map[string]string, len(providers)),
+		Agreement: quality.WER(quality.Text(cues[0]), quality.Text(cues[1])),
+	}
+
+	for _, p := range providers {
+		resp.Subtitles[p] = string(results[p])
+	}
+
+	if reference != "" {
+		resp.Reference = make(map[string]referenceScores, len(providers))
+		for i, p := range providers {
+			text := quality.Text(cues[i])
+			resp.Reference[p] = referenceScores{
+				WER: quality.WER(reference, text),
+				CER: quality.CER(reference, text),
+			}
+		}
+	} else {
+		resp.CueDiffs = quality.DiffCues(cues[0], cues[1])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// formText returns a text form field, or the content of the file uploaded under the same name.
+func formText(r *http.Request, key string) (string, error) {
+	if v := r.FormValue(key); v != "" {
+		return v, nil
+	}
+
+	data, err := formFile(r, key)
+	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+// formFile returns the content of the file uploaded under the key.
+func formFile(r *http.Request, key string) ([]byte, error) {
+	f, _, err := r.FormFile(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", key, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", key, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+)
+
+// notifyCompletion emails the summary of the finished job to the notified addresses, e.g.
+// "5 files transcribed, 1 failed", with a link to the zip of its subtitles. Jobs with fewer
+// than notifyMinFiles files are not notified, so that only the long batches are.
+func (h *Handlers) notifyCompletion(jobID string) {
+	if len(h.notifyEmails) == 0 || !h.mailer.Enabled() {
+		return
+	}
+
+	job, err := h.jobs.Get(jobID)
+	if err != nil {
+		h.logger.Error("Could not get job", slog.String("job_id", jobID), slog.String("error", err.Error()))
+		return
+	}
+
+	if len(job.Files) < h.notifyMinFiles {
+		return
+	}
+
+	subject, body := completionSummary(job, h.link("/subtitles/zip?job="+url.QueryEscape(job.ID)))
+
+	for _, to := range h.notifyEmails {
+		if err := h.mailer.Send(to, subject, body); err != nil {
+			h.logger.Error("Could not send completion email", slog.String("job_id", jobID), slog.String("to", to), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// completionSummary returns the subject and the body of the completion email of the job.
+func completionSummary(job jobs.Job, zipURL string) (string, string) {
+	var (
+		done, failed int
+		failures     strings.Builder
+	)
+
+	for _, f := range job.Files {
+		if f.State == jobs.StateDone {
+			done++
+			continue
+		}
+		failed++
+		fmt.Fprintf(&failures, "%s: %s\n", f.Name, f.Error)
+	}
+
+	subject := fmt.Sprintf("%d %s transcribed", done, plural(done, "file", "files"))
+	if failed > 0 {
+		subject += fmt.Sprintf(", %d failed", failed)
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Job %s finished: %s.\n", job.ID, subject)
+
+	if done > 0 {
+		fmt.Fprintf(&b, "\nDownload the subtitles: %s\n", zipURL)
+	}
+
+	if failed > 0 {
+		b.WriteString("\nFailed files:\n" + failures.String())
+	}
+	return subject, b.String()
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,154 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/go-chi/chi/v5"
+)
+
+// subtitleCue is a cue with its diarization label split off its text. Timestamps are in seconds.
+type subtitleCue struct {
+	Index   int     `json:"index"`
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	Text    string  `json:"text"`
+	Speaker string  `json:"speaker,omitempty"`
+}
+
+type replaceCuesRequest struct {
+	Cues []subtitleCue `json:"cues"`
+}
+
+type subtitleCuesResponse struct {
+	Name     string        `json:"name"`
+	Language string        `json:"language"`
+	Format   string        `json:"format"`
+	Cues     []subtitleCue `json:"cues"`
+}
+
+// subtitleCues returns the parsed cues of an SRT or VTT subtitle, so that clients don't parse the formats.
+// Cues without a speaker label are attributed to the speaker of the previous cue, like in the analytics.
+func (h *Handlers) subtitleCues(w http.ResponseWriter, r *http.Request) {
+	obj, err := h.findSubtitle(r, chi.URLParam(r, "name"))
+	if err != nil {
+		h.subtitleError(w, err)
+		return
+	}
+
+	if !publishable(obj.Name) {
+		h.e(w, "Only SRT and VTT subtitles have cues", nil, http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.storage.Read(obj)
+	if err != nil {
+		h.e(w, "Failed to read subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	cues, vtt, err := parseCues(data)
+	if err != nil {
+		h.e(w, "Failed to parse subtitle", err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	resp := subtitleCuesResponse{Name: obj.Name, Language: obj.Language, Format: "srt", Cues: make([]subtitleCue, 0, len(cues))}
+	if vtt {
+		resp.Format = "vtt"
+	}
+
+	var speaker string
+	for _, cue := range cues {
+		label, text := srt.SplitSpeaker(cue.Text)
+		if label != "" {
+			speaker = label
+		}
+
+		resp.Cues = append(resp.Cues, subtitleCue{
+			Index:   cue.Index,
+			Start:   cue.Start.Seconds(),
+			End:     cue.End.Seconds(),
+			Text:    text,
+			Speaker: speaker,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// replaceSubtitleCues replaces the cues of an SRT or VTT subtitle, e.g. once edited in the editor,
+// keeping its format. The cues are renumbered in order. Like in subtitleCues, the speaker of a cue
+// is only labeled when it differs from the speaker of the previous cue.
+func (h *Handlers) replaceSubtitleCues(w http.ResponseWriter, r *http.Request) {
+	var req replaceCuesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	obj, err := h.findSubtitle(r, chi.URLParam(r, "name"))
+	if err != nil {
+		h.subtitleError(w, err)
+		return
+	}
+
+	if !publishable(obj.Name) {
+		h.e(w, "Only SRT and VTT subtitles have cues", nil, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.retention.CanModify(obj.Project); err != nil {
+		h.e(w, "Subtitle is under legal hold", err, http.StatusLocked)
+		return
+	}
+
+	data, err := h.storage.Read(obj)
+	if err != nil {
+		h.e(w, "Failed to read subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	var (
+		cues    = make([]srt.Cue, 0, len(req.Cues))
+		speaker string
+	)
+
+	for i, c := range req.Cues {
+		cue := srt.Cue{Index: i + 1, Start: seconds(c.Start), End: seconds(c.End), Text: c.Text}
+
+		if cue.Start < 0 || cue.End <= cue.Start || cue.Text == "" {
+			h.e(w, fmt.Sprintf("Invalid cue %d", i+1), nil, http.StatusBadRequest)
+			return
+		}
+
+		if c.Speaker != speaker {
+			cue.Text = srt.JoinSpeaker(c.Speaker, cue.Text)
+			speaker = c.Speaker
+		}
+		cues = append(cues, cue)
+	}
+
+	if err := h.storage.Replace(obj, formatCues(cues, srt.IsVTT(data))); err != nil {
+		h.e(w, "Failed to write subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "subtitle.edit",
+		Subject: obj.Name,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{"project": obj.Project, "cues": strconv.Itoa(len(cues))},
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
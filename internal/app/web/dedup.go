@@ -0,0 +1,125 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+)
+
+// submission is a job started within the dedup window. ready is closed once the job is created,
+// or failed to be, in which case jobID is empty.
+type submission struct {
+	jobID   string
+	ready   chan struct{}
+	expires time.Time
+}
+
+// dedup attaches submissions repeated within the window, e.g. double-clicked upload buttons
+// or retried HTTP calls, to the job of the first one instead of processing the files twice.
+type dedup struct {
+	window time.Duration
+
+	mu          sync.Mutex
+	submissions map[string]*submission
+}
+
+func newDedup(window time.Duration) *dedup {
+	return &dedup{window: window, submissions: make(map[string]*submission)}
+}
+
+// claim returns the submission of the key within the window, and whether the caller submitted it first,
+// in which case it must resolve it.
+func (d *dedup) claim(key string) (*submission, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+
+	for k, s := range d.submissions {
+		if now.After(s.expires) {
+			delete(d.submissions, k)
+		}
+	}
+
+	if s, ok := d.submissions[key]; ok {
+		return s, false
+	}
+
+	s := &submission{ready: make(chan struct{}), expires: now.Add(d.window)}
+	d.submissions[key] = s
+	return s, true
+}
+
+// resolve sets the job of the claimed submission. A submission that failed is forgotten, so that it can be retried.
+func (d *dedup) resolve(key string, s *submission, jobID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s.jobID = jobID
+	close(s.ready)
+
+	if jobID == "" && d.submissions[key] == s {
+		delete(d.submissions, key)
+	}
+}
+
+// submissionKey identifies a submission by its owner, its options and the content of its files,
+// so that the same files submitted with other options are processed again.
+func submissionKey(owner string, options map[string][]string, contents []string) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n", owner)
+
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(hash, "%s=%s\n", k, strings.Join(options[k], "\x00"))
+	}
+
+	for _, c := range contents {
+		fmt.Fprintf(hash, "%s\n", c)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// submit starts the job of the submission identified by key, unless the same one was submitted within
+// the dedup window, in which case the job of the first submission is returned and duplicate is true.
+func (h *Handlers) submit(ctx context.Context, key string, start func() (jobs.Job, error)) (job jobs.Job, duplicate bool, err error) {
+	if h.dedup == nil {
+		job, err := start()
+		return job, false, err
+	}
+
+	s, first := h.dedup.claim(key)
+	if first {
+		job, err := start()
+		h.dedup.resolve(key, s, job.ID)
+		return job, false, err
+	}
+
+	// The job of the first submission may still be starting.
+	select {
+	case <-s.ready:
+	case <-ctx.Done():
+		return jobs.Job{}, false, ctx.Err()
+	}
+
+	if s.jobID != "" {
+		if job, err := h.jobs.Get(s.jobID); err == nil {
+			return job, true, nil
+		}
+	}
+
+	job, err = start()
+	return job, false, err
+}
@@ -0,0 +1,186 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/degraded"
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+)
+
+// degradedRetryAfter is the delay after which the clients rejected while the storage is degraded can retry.
+const degradedRetryAfter time.Duration = time.Minute
+
+// errStorageDegraded is returned when a job can't be started nor queued because the storage doesn't accept writes.
+var errStorageDegraded = errors.New("subtitles can't be stored for now")
+
+type storageMonitor interface {
+	Degraded() error
+	Wait(ctx context.Context) error
+}
+
+type jobQueue interface {
+	Enabled() bool
+	Dir() string
+	Push(e degraded.Entry) error
+	Entries() ([]degraded.Entry, error)
+	Remove(jobID string) error
+}
+
+// requireStorage rejects the requests writing to the storage while it doesn't accept writes,
+// with a 503 telling the clients to retry later instead of failing once the work is done.
+func (h *Handlers) requireStorage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h.monitor.Degraded(); err != nil {
+			h.storageUnavailable(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acceptJobs rejects the requests starting jobs while the storage doesn't accept writes,
+// unless the jobs can wait in the queue for the storage to recover.
+func (h *Handlers) acceptJobs(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h.monitor.Degraded(); err != nil && !h.queue.Enabled() {
+			h.storageUnavailable(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handlers) storageUnavailable(w http.ResponseWriter, err error) {
+	w.Header().Set("Retry-After", fmt.Sprintf("%d", int(degradedRetryAfter.Seconds())))
+	h.e(w, "Storage is degraded, new subtitles can't be stored for now, retry later", err, http.StatusServiceUnavailable)
+}
+
+// queueable reports whether the inputs of a job can wait in the queue while the storage is degraded.
+func (h *Handlers) queueable(inputs []*subtitles.Input) bool {
+	if !h.queue.Enabled() {
+		return false
+	}
+
+	for _, in := range inputs {
+		if len(in.Parts) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// enqueueJob moves the prepared inputs of the job to the queue, where they wait for the storage to accept writes again.
+func (h *Handlers) enqueueJob(job jobs.Job, inputs []*subtitles.Input, publications []*jobs.Publication, callbackURL string) error {
+	entry := degraded.Entry{
+		JobID:        job.ID,
+		Publications: publications,
+		CallbackURL:  callbackURL,
+		QueuedAt:     time.Now().UTC(),
+	}
+
+	for _, in := range inputs {
+		queued, err := h.subtitler.Queue(in, h.queue.Dir())
+		if err != nil {
+			for _, queued := range entry.Inputs {
+				h.subtitler.Discard(queued.Input())
+			}
+			return fmt.Errorf("could not queue input: %w", err)
+		}
+		entry.Inputs = append(entry.Inputs, queued)
+	}
+
+	if err := h.queue.Push(entry); err != nil {
+		for _, queued := range entry.Inputs {
+			h.subtitler.Discard(queued.Input())
+		}
+		return fmt.Errorf("could not queue job: %w", err)
+	}
+
+	h.logger.Warn("Job queued until the storage recovers", slog.String("job_id", job.ID))
+
+	select {
+	case h.queued <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// RunQueue starts the jobs queued while the storage was degraded once it accepts writes again,
+// including those queued before a restart, until the context is done.
+func (h *Handlers) RunQueue(ctx context.Context) {
+	if !h.queue.Enabled() {
+		return
+	}
+
+	// The jobs queued before a restart were marked as interrupted, while they still wait.
+	entries, err := h.queue.Entries()
+	if err != nil {
+		h.logger.Error("Could not read queue", slog.String("error", err.Error()))
+	}
+
+	for _, e := range entries {
+		if err := h.jobs.Requeue(e.JobID); err != nil {
+			h.logger.Error("Could not requeue job", slog.String("job_id", e.JobID), slog.String("error", err.Error()))
+		}
+	}
+
+	for {
+		if err := h.monitor.Wait(ctx); err != nil {
+			return
+		}
+
+		h.resumeQueued()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.queued:
+		}
+	}
+}
+
+func (h *Handlers) resumeQueued() {
+	entries, err := h.queue.Entries()
+	if err != nil {
+		h.logger.Error("Could not read queue", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, e := range entries {
+		// The storage failed again, the remaining jobs wait for the next recovery.
+		if h.monitor.Degraded() != nil {
+			return
+		}
+
+		// The job is removed from the queue first, so that it never runs twice.
+		if err := h.queue.Remove(e.JobID); err != nil {
+			h.logger.Error("Could not remove job from queue", slog.String("job_id", e.JobID), slog.String("error", err.Error()))
+			return
+		}
+
+		inputs := make([]*subtitles.Input, 0, len(e.Inputs))
+		for _, queued := range e.Inputs {
+			inputs = append(inputs, queued.Input())
+		}
+
+		// The job can be gone, e.g. removed by the janitor while the storage was degraded.
+		job, err := h.jobs.Get(e.JobID)
+		if err != nil {
+			h.logger.Error("Could not start queued job", slog.String("job_id", e.JobID), slog.String("error", err.Error()))
+
+			for _, in := range inputs {
+				h.subtitler.Discard(in)
+			}
+			continue
+		}
+
+		h.logger.Info("Starting queued job", slog.String("job_id", e.JobID), slog.Duration("queued_for", time.Since(e.QueuedAt)))
+		h.runJob(job, inputs, e.Publications, e.CallbackURL)
+	}
+}
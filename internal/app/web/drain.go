@@ -0,0 +1,124 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+)
+
+// cancelGrace is how long canceled background work has to record its failure once draining timed out.
+const cancelGrace time.Duration = 10 * time.Second
+
+// inflight counts the background work Drain waits for.
+type inflight struct {
+	mu   sync.Mutex
+	n    int
+	idle *sync.Cond // Signaled when the count drops to zero.
+}
+
+func newInflight() *inflight {
+	w := &inflight{}
+	w.idle = sync.NewCond(&w.mu)
+	return w
+}
+
+func (w *inflight) add(delta int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.n += delta; w.n <= 0 {
+		w.idle.Broadcast()
+	}
+}
+
+// wait blocks until no work is counted.
+func (w *inflight) wait() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for w.n > 0 {
+		w.idle.Wait()
+	}
+}
+
+// jobDeferral tracks whether all the unfinished files of a running job are deferred by the scheduler,
+// in which case the job isn't counted as inflight: Drain doesn't wait for transcriptions starting hours later.
+type jobDeferral struct {
+	work *inflight
+
+	mu       sync.Mutex
+	pending  int          // Files not done nor failed.
+	waiting  map[int]bool // Files deferred, by index.
+	deferred bool
+}
+
+func newJobDeferral(work *inflight, files int) *jobDeferral {
+	return &jobDeferral{work: work, pending: files, waiting: make(map[int]bool)}
+}
+
+// update records the event of the file at index.
+func (d *jobDeferral) update(index int, stage subtitles.Stage) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if stage == subtitles.StageScheduled {
+		d.waiting[index] = true
+	} else {
+		delete(d.waiting, index)
+	}
+
+	if stage == subtitles.StageDone || stage == subtitles.StageFailed {
+		d.pending--
+	}
+
+	deferred := d.pending > 0 && len(d.waiting) == d.pending
+	if deferred == d.deferred {
+		return
+	}
+
+	if d.deferred = deferred; deferred {
+		d.work.add(-1)
+	} else {
+		d.work.add(1)
+	}
+}
+
+// background runs fn in a goroutine that Drain waits for.
+// The context given to fn is canceled when draining times out.
+func (h *Handlers) background(fn func(ctx context.Context)) {
+	h.inflight.add(1)
+
+	go func() {
+		defer h.inflight.add(-1)
+		fn(h.ctx)
+	}()
+}
+
+// Drain waits for the background work, e.g. running jobs, to finish. When the context is done first,
+// the work is canceled and its jobs fail. Jobs still running when the server stops, e.g. those whose
+// files are all deferred by the scheduler, are marked as interrupted the next time it starts.
+func (h *Handlers) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		h.inflight.wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	h.cancel()
+
+	select {
+	case <-done:
+	case <-time.After(cancelGrace):
+	}
+	return fmt.Errorf("could not drain background work: %w", ctx.Err())
+}
@@ -0,0 +1,130 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/email"
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+)
+
+// maxSNSPayload bounds the size of SNS deliveries. SES only publishes messages up to 150KB to SNS.
+const maxSNSPayload int64 = 1 << 20
+
+// inboundEmail receives the emails sent to the configured address. Their media attachments
+// and linked videos are transcribed in one job, and the results are mailed back to the sender.
+func (h *Handlers) inboundEmail(w http.ResponseWriter, r *http.Request) {
+	if !h.inbox.Enabled() {
+		h.e(w, "Email integration is not configured", nil, http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSNSPayload))
+	if err != nil {
+		h.e(w, "Failed to read the request", err, http.StatusBadRequest)
+		return
+	}
+
+	msg, err := h.inbox.Receive(r.Context(), body)
+	if err != nil {
+		switch {
+		case errors.Is(err, email.ErrInvalidSNSMessage):
+			h.e(w, "Invalid SNS message", err, http.StatusForbidden)
+		case errors.Is(err, email.ErrSenderNotAllowed):
+			// Acknowledged, so that SNS doesn't retry the delivery.
+			h.logger.Warn("Ignoring email", slog.String("error", err.Error()))
+		default:
+			h.e(w, "Failed to process the email", err, http.StatusBadRequest)
+		}
+		return
+	}
+
+	if msg != nil {
+		h.background(func(ctx context.Context) {
+			h.emailTranscribe(ctx, msg)
+		})
+	}
+}
+
+func (h *Handlers) emailTranscribe(ctx context.Context, msg *email.Message) {
+	reply := func(body string) {
+		if !h.mailer.Enabled() {
+			return
+		}
+
+		if err := h.mailer.Send(msg.From, "Re: "+msg.Subject, body); err != nil {
+			h.logger.Error("Could not reply to email", slog.String("to", msg.From), slog.String("error", err.Error()))
+		}
+	}
+
+	var (
+		inputs   []*subtitles.Input
+		problems []string
+	)
+
+	for _, a := range msg.Attachments {
+		inputs = append(inputs, &subtitles.Input{
+			FileName: a.Name,
+			Data:     bytes.NewReader(a.Data),
+		})
+	}
+
+	for _, l := range msg.Links {
+		download, err := h.ingest.Fetch(ctx, l)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("Could not download %s: %s", l, err))
+			continue
+		}
+		defer download.Body.Close()
+
+		inputs = append(inputs, &subtitles.Input{
+			FileName: download.Name,
+			Data:     download.Body,
+		})
+	}
+
+	if len(inputs) == 0 {
+		reply(strings.Join(append(problems, "No video or audio was found in your email. Attach the files or include links to them."), "\n"))
+		return
+	}
+
+	job, err := h.startJob(inputs, nil, "")
+	if err != nil {
+		h.logger.Error("Could not start job", slog.String("from", msg.From), slog.String("error", err.Error()))
+		reply("Sorry, we could not start the transcription. Please try again later.")
+		return
+	}
+
+	job, err = h.awaitJob(job.ID, nil)
+	if err != nil {
+		h.logger.Error("Could not wait for job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+		return
+	}
+
+	var b strings.Builder
+
+	for _, p := range problems {
+		b.WriteString(p + "\n")
+	}
+
+	for _, f := range job.Files {
+		if f.Subtitle != "" {
+			fmt.Fprintf(&b, "%s: %s\n", f.Name, h.link(subtitlePath(f)))
+		} else {
+			fmt.Fprintf(&b, "%s: failed (%s)\n", f.Name, f.Error)
+		}
+	}
+
+	if job.State == jobs.StateDone {
+		reply("Your subtitles are ready:\n\n" + b.String())
+		return
+	}
+	reply("Some files could not be transcribed:\n\n" + b.String())
+}
@@ -0,0 +1,189 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// widgetScript turns the elements with a data-videoscriber attribute holding a share token
+// into a video player with captions and a transcript, where clicking a cue seeks the video:
+//
+//	<div data-videoscriber="TOKEN"></div>
+//	<script src="https://videoscriber.example.com/widget.js" async></script>
+//
+// The video is the one the subtitle was shared with, unless the element sets data-video-url.
+const widgetScript string = `(function () {
+  "use strict";
+
+  var script = document.currentScript;
+  var base = script ? new URL(script.src).origin : "";
+
+  function mount(el) {
+    var token = el.getAttribute("data-videoscriber");
+    if (!token || el.getAttribute("data-videoscriber-mounted")) return;
+    el.setAttribute("data-videoscriber-mounted", "true");
+
+    fetch(base + "/embed/" + encodeURIComponent(token))
+      .then(function (resp) {
+        if (!resp.ok) throw new Error("videoscriber: could not load " + token + ": " + resp.status);
+        return resp.json();
+      })
+      .then(function (embed) { render(el, embed); })
+      .catch(function (err) { console.error(err); });
+  }
+
+  function render(el, embed) {
+    var video = document.createElement("video");
+    video.controls = true;
+    video.src = el.getAttribute("data-video-url") || embed.video_url;
+    video.style.width = "100%";
+
+    var track = document.createElement("track");
+    track.kind = "captions";
+    track.srclang = embed.language;
+    track.label = embed.language;
+    track.default = true;
+    video.appendChild(track);
+
+    // Cross-origin tracks require the video to be CORS-enabled too,
+    // so the captions are loaded from a same-origin blob instead.
+    fetch(new URL(embed.captions_url, base || location.href))
+      .then(function (resp) { return resp.text(); })
+      .then(function (vtt) { track.src = URL.createObjectURL(new Blob([vtt], { type: "text/vtt" })); })
+      .catch(function (err) { console.error(err); });
+
+    var list = document.createElement("ol");
+    list.style.cssText = "list-style:none;margin:0;padding:0;max-height:20em;overflow-y:auto;font:inherit";
+
+    var items = embed.cues.map(function (cue) {
+      var item = document.createElement("li");
+      item.textContent = cue.text;
+      item.style.cssText = "cursor:pointer;padding:.25em .5em;border-radius:3px";
+      item.addEventListener("click", function () {
+        video.currentTime = cue.start;
+        video.play();
+      });
+      list.appendChild(item);
+      return item;
+    });
+
+    var active = -1;
+    video.addEventListener("timeupdate", function () {
+      var t = video.currentTime, current = -1;
+      for (var i = 0; i < embed.cues.length; i++) {
+        if (t >= embed.cues[i].start && t < embed.cues[i].end) { current = i; break; }
+      }
+      if (current === active) return;
+      if (active >= 0) items[active].style.background = "";
+      if (current >= 0) {
+        items[current].style.background = "#fff3b0";
+        list.scrollTop = items[current].offsetTop - list.offsetTop - list.clientHeight / 3;
+      }
+      active = current;
+    });
+
+    el.appendChild(video);
+    el.appendChild(list);
+  }
+
+  function mountAll() {
+    document.querySelectorAll("[data-videoscriber]").forEach(mount);
+  }
+
+  if (document.readyState === "loading") {
+    document.addEventListener("DOMContentLoaded", mountAll);
+  } else {
+    mountAll();
+  }
+})();
+`
+
+type embedResponse struct {
+	Name        string        `json:"name"`
+	Language    string        `json:"language"`
+	VideoURL    string        `json:"video_url,omitempty"`
+	CaptionsURL string        `json:"captions_url"`
+	Cues        []srt.JSONCue `json:"cues"`
+}
+
+// widget serves the embeddable player script.
+func (h *Handlers) widget(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write([]byte(widgetScript))
+}
+
+// embed returns the video and cues of a shared subtitle for the widget.
+// Embedding sites are on other origins, so the response allows any origin.
+func (h *Handlers) embed(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	sub, share, cues, ok := h.sharedCues(w, token)
+	if !ok {
+		return
+	}
+
+	resp := embedResponse{
+		Name:        sub.Name,
+		Language:    sub.Language,
+		VideoURL:    share.VideoURL,
+		CaptionsURL: h.link("/embed/" + token + "/captions.vtt"),
+		Cues:        make([]srt.JSONCue, 0, len(cues)),
+	}
+
+	for _, cue := range cues {
+		resp.Cues = append(resp.Cues, cue.ToJSON())
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// embedCaptions returns a shared subtitle as WebVTT, the only format of the track element.
+func (h *Handlers) embedCaptions(w http.ResponseWriter, r *http.Request) {
+	_, _, cues, ok := h.sharedCues(w, chi.URLParam(r, "token"))
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Write(srt.FormatVTT(cues))
+}
+
+// sharedCues returns the subtitle, share and cues of the token, otherwise it responds with an error.
+func (h *Handlers) sharedCues(w http.ResponseWriter, token string) (store.Subtitle, store.Share, []srt.Cue, bool) {
+	sub, share, err := h.storage.Shared(token)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.e(w, "Share not found", err, http.StatusNotFound)
+			return store.Subtitle{}, store.Share{}, nil, false
+		}
+		h.e(w, "Failed to find share", err, http.StatusInternalServerError)
+		return store.Subtitle{}, store.Share{}, nil, false
+	}
+
+	data, err := h.storage.Read(sub.Object())
+	if err != nil {
+		h.e(w, "Failed to read subtitle", err, http.StatusInternalServerError)
+		return store.Subtitle{}, store.Share{}, nil, false
+	}
+
+	cues, _, err := parseCues(data)
+	if err != nil {
+		h.e(w, "Failed to parse subtitle", err, http.StatusInternalServerError)
+		return store.Subtitle{}, store.Share{}, nil, false
+	}
+	return sub, share, cues, true
+}
@@ -0,0 +1,68 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/alesr/videoscriber/internal/pkg/quality"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+)
+
+// evaluateSubtitle scores a subtitle against a reference transcript.
+// The subtitle is either uploaded or the name of a stored one.
+func (h *Handlers) evaluateSubtitle(w http.ResponseWriter, r *http.Request) {
+	if err := h.parseMultipartForm(w, r); err != nil {
+		h.multipartError(w, err)
+		return
+	}
+
+	subData, err := h.subtitleData(r)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrAmbiguous) {
+			h.subtitleError(w, err)
+			return
+		}
+		h.e(w, "Failed to read the subtitle", err, http.StatusBadRequest)
+		return
+	}
+
+	reference, err := formText(r, "reference")
+	if err != nil {
+		h.e(w, "Failed to read the reference transcript", err, http.StatusBadRequest)
+		return
+	}
+
+	if reference == "" {
+		h.e(w, "No reference transcript in request", nil, http.StatusBadRequest)
+		return
+	}
+
+	cues, err := srt.Parse(subData)
+	if err != nil {
+		h.e(w, "Failed to parse the subtitle", err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(quality.Evaluate(cues, reference)); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// subtitleData returns the uploaded "subtitle" file or, when the field is text,
+// the content of the stored subtitle with that name.
+func (h *Handlers) subtitleData(r *http.Request) ([]byte, error) {
+	if name := r.FormValue("subtitle"); name != "" {
+		obj, err := h.findSubtitle(r, name)
+		if err != nil {
+			return nil, err
+		}
+		return h.storage.Read(obj)
+	}
+
+	return formFile(r, "subtitle")
+}
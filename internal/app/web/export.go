@@ -0,0 +1,197 @@
+package web
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/alesr/videoscriber/internal/pkg/store"
+)
+
+const (
+	exportCSV    = "csv"
+	exportNDJSON = "ndjson"
+)
+
+// exportRow is a file of a job, with the metadata of its subtitle when stored.
+type exportRow struct {
+	JobID        string    `json:"job_id"`
+	JobType      string    `json:"job_type"`
+	Owner        string    `json:"owner,omitempty"`
+	JobState     string    `json:"job_state"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Index        int       `json:"index"`
+	File         string    `json:"file"`
+	State        string    `json:"state"`
+	Error        string    `json:"error,omitempty"`
+	Subtitle     string    `json:"subtitle,omitempty"`
+	Project      string    `json:"project,omitempty"`
+	Language     string    `json:"language,omitempty"`
+	Format       string    `json:"format,omitempty"`
+	Size         int64     `json:"size,omitempty"`
+	OriginalName string    `json:"original_name,omitempty"`
+	Duration     float64   `json:"duration,omitempty"` // Seconds.
+	Status       string    `json:"status,omitempty"`
+}
+
+var exportColumns = []string{
+	"job_id", "job_type", "owner", "job_state", "created_at", "updated_at", "index", "file", "state", "error",
+	"subtitle", "project", "language", "format", "size", "original_name", "duration", "status",
+}
+
+func (row exportRow) record() []string {
+	var size, duration string
+	if row.Size > 0 {
+		size = strconv.FormatInt(row.Size, 10)
+	}
+
+	if row.Duration > 0 {
+		duration = strconv.FormatFloat(row.Duration, 'f', -1, 64)
+	}
+
+	return []string{
+		row.JobID, row.JobType, row.Owner, row.JobState, row.CreatedAt.Format(time.RFC3339), row.UpdatedAt.Format(time.RFC3339),
+		strconv.Itoa(row.Index), row.File, row.State, row.Error,
+		row.Subtitle, row.Project, row.Language, row.Format, size, row.OriginalName, duration, row.Status,
+	}
+}
+
+// exportJobs streams the files of the jobs created in a date range, one row per file, as CSV or
+// newline delimited JSON, e.g. /jobs/export?format=csv&from=2024-01-01&to=2024-02-01.
+// The range includes from and excludes to, both dates or RFC 3339 times, and is unbounded when omitted.
+// Only the jobs of the caller are exported, those of all the users for admins.
+func (h *Handlers) exportJobs(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportCSV
+	}
+
+	if format != exportCSV && format != exportNDJSON {
+		h.e(w, fmt.Sprintf("Unknown format %q, expected csv or ndjson", format), nil, http.StatusBadRequest)
+		return
+	}
+
+	from, err := parseExportTime(r.URL.Query().Get("from"))
+	if err != nil {
+		h.e(w, "Invalid from value", err, http.StatusBadRequest)
+		return
+	}
+
+	to, err := parseExportTime(r.URL.Query().Get("to"))
+	if err != nil {
+		h.e(w, "Invalid to value", err, http.StatusBadRequest)
+		return
+	}
+
+	var (
+		all      = h.isAdmin(r)
+		exported []jobs.Job
+		owners   = map[string]bool{}
+	)
+
+	for _, job := range h.jobs.List(from, to) {
+		if all || job.Owner == owner(r) {
+			exported = append(exported, job)
+			owners[job.Owner] = true
+		}
+	}
+
+	// The subtitles of the jobs are in the namespaces of their owners.
+	bySubtitle := map[string]store.Subtitle{}
+
+	for name := range owners {
+		subs, err := h.storage.Subtitles(name)
+		if err != nil {
+			h.e(w, "Failed to list subtitles", err, http.StatusInternalServerError)
+			return
+		}
+
+		for _, sub := range subs {
+			if sub.JobID != "" {
+				bySubtitle[sub.JobID+"/"+sub.Name] = sub
+			}
+		}
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename=jobs."+format)
+
+	var write func(row exportRow) error
+
+	switch format {
+	case exportCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+
+		cw := csv.NewWriter(w)
+		defer cw.Flush()
+
+		if err := cw.Write(exportColumns); err != nil {
+			return
+		}
+
+		write = func(row exportRow) error {
+			return cw.Write(row.record())
+		}
+	case exportNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+
+		enc := json.NewEncoder(w)
+		write = func(row exportRow) error {
+			return enc.Encode(row)
+		}
+	}
+
+	for _, job := range exported {
+		for i, f := range job.Files {
+			row := exportRow{
+				JobID:     job.ID,
+				JobType:   string(job.Type),
+				Owner:     job.Owner,
+				JobState:  string(job.State),
+				CreatedAt: job.CreatedAt,
+				UpdatedAt: job.UpdatedAt,
+				Index:     i,
+				File:      f.Name,
+				State:     string(f.State),
+				Error:     f.Error,
+				Subtitle:  f.Subtitle,
+			}
+
+			if sub, ok := bySubtitle[job.ID+"/"+f.Subtitle]; ok {
+				row.Project = sub.Project
+				row.Language = sub.Language
+				row.Format = sub.Format
+				row.Size = sub.Size
+				row.OriginalName = sub.OriginalName
+				row.Duration = sub.Duration.Seconds()
+				row.Status = sub.Status
+			}
+
+			// The client went away, there is no one to report the error to.
+			if err := write(row); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseExportTime parses a bound of the export range, either a date or an RFC 3339 time.
+func parseExportTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date (2006-01-02) or an RFC 3339 time: %w", err)
+	}
+	return t, nil
+}
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/features"
+	"github.com/go-chi/chi/v5"
+)
+
+// requireFeature responds not found to the requests of a subsystem disabled in the deployment,
+// as if the build didn't have it, and forbidden to the API keys whose plan excludes it.
+func (h *Handlers) requireFeature(f features.Flag) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !h.features.Enabled(f) {
+				h.e(w, fmt.Sprintf("The %s feature is disabled", f), nil, http.StatusNotFound)
+				return
+			}
+
+			if plan, ok := h.plans.PlanOf(owner(r)); ok && !plan.Allows(f) {
+				h.e(w, fmt.Sprintf("The %s feature is not included in the %s plan", f, plan.Name), nil, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type featuresResponse struct {
+	Features []features.State `json:"features"`
+}
+
+// listFeatures lists the feature flags of the deployment and their state.
+func (h *Handlers) listFeatures(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(featuresResponse{Features: h.features.List()}); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+type toggleFeatureRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// toggleFeature enables or disables the named feature until the server restarts,
+// after which the configured state applies again.
+func (h *Handlers) toggleFeature(w http.ResponseWriter, r *http.Request) {
+	var req toggleFeatureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	state, err := h.features.Toggle(features.Flag(chi.URLParam(r, "name")), req.Enabled)
+	if err != nil {
+		if errors.Is(err, features.ErrUnknown) {
+			h.e(w, "Feature not found", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to toggle feature", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "feature.toggle",
+		Subject: string(state.Name),
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{"enabled": strconv.FormatBool(state.Enabled)},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(state); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
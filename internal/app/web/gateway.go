@@ -0,0 +1,170 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+)
+
+// cuesFormat is the format of the gateway responding with the cues as JSON, instead of a subtitle file.
+const cuesFormat string = "cues"
+
+type transcribeResponse struct {
+	Language string        `json:"language"`
+	Duration float64       `json:"duration"` // Seconds.
+	Provider string        `json:"provider"`
+	Model    string        `json:"model,omitempty"`
+	Cues     []srt.JSONCue `json:"cues"`
+}
+
+// transcribe transcribes the raw audio or video of the request body and responds with its subtitle,
+// without storing anything, for the internal tools using the server as a transcription gateway.
+// The options are query parameters: language, provider, prompt, task, word_timestamps, diarize,
+// preprocess, and format, a subtitle format or "cues" for the cues as JSON. The transcription
+// goes through the same providers, cache and limits as the uploads, and counts in the usage.
+func (h *Handlers) transcribe(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if provider := query.Get("provider"); provider != "" && !h.subtitler.HasProvider(provider) {
+		h.e(w, fmt.Sprintf("Unknown provider %q", provider), nil, http.StatusBadRequest)
+		return
+	}
+
+	language := query.Get("language")
+	if language == "" {
+		language = subtitles.DefaultLanguage
+	}
+
+	if !subtitles.SupportedLanguage(language) {
+		h.e(w, fmt.Sprintf("Unsupported language %q", language), nil, http.StatusBadRequest)
+		return
+	}
+
+	cues := query.Get("format") == cuesFormat
+
+	format := subtitles.FormatSRT
+	if !cues {
+		var err error
+		if format, err = subtitles.ParseFormat(query.Get("format")); err != nil {
+			h.e(w, "Invalid format", err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	task, err := subtitles.ParseTask(query.Get("task"))
+	if err != nil {
+		h.e(w, "Invalid task", err, http.StatusBadRequest)
+		return
+	}
+
+	preprocess, err := subtitles.ParseFilters(query.Get("preprocess"))
+	if err != nil {
+		h.e(w, "Invalid preprocessing filters", err, http.StatusBadRequest)
+		return
+	}
+
+	wordTimestamps, err := queryBool(query.Get("word_timestamps"))
+	if err != nil {
+		h.e(w, "Invalid word_timestamps", err, http.StatusBadRequest)
+		return
+	}
+
+	diarize, err := queryBool(query.Get("diarize"))
+	if err != nil {
+		h.e(w, "Invalid diarize", err, http.StatusBadRequest)
+		return
+	}
+
+	name := query.Get("name")
+	if name == "" {
+		name = "audio"
+	}
+
+	in := &subtitles.Input{
+		FileName:       name,
+		Data:           http.MaxBytesReader(w, r.Body, h.maxUploadSize),
+		Language:       language,
+		Owner:          owner(r),
+		Tenant:         owner(r),
+		Provider:       query.Get("provider"),
+		WordTimestamps: wordTimestamps,
+		Diarize:        diarize,
+		Prompt:         query.Get("prompt"),
+		Task:           task,
+		Format:         format,
+		Preprocess:     preprocess,
+	}
+
+	if plan, ok := h.plans.PlanOf(in.Owner); ok {
+		in.Priority = plan.Priority
+	}
+
+	// The transcription counts as a running job of the caller while the request lasts.
+	h.running.add(in.Owner, 1)
+	defer h.running.add(in.Owner, -1)
+
+	t, err := h.subtitler.Transcribe(r.Context(), in)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.e(w, fmt.Sprintf("Files can be at most %d bytes", h.maxUploadSize), err, http.StatusRequestEntityTooLarge)
+			return
+		}
+		h.e(w, "Failed to transcribe", err, http.StatusBadGateway)
+		return
+	}
+
+	if err := h.quota.Record(in.Owner, t.Usage); err != nil {
+		h.logger.Error("Could not record usage", slog.String("owner", in.Owner), slog.String("error", err.Error()))
+	}
+
+	w.Header().Set("X-Videoscriber-Provider", t.Provider)
+	if t.Model != "" {
+		w.Header().Set("X-Videoscriber-Model", t.Model)
+	}
+
+	if !cues {
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Write(t.Subtitle)
+		return
+	}
+
+	parsed, err := srt.Parse(t.Subtitle)
+	if err != nil {
+		h.e(w, "Failed to parse the subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := transcribeResponse{
+		Language: in.OutputLanguage(),
+		Duration: t.Duration.Seconds(),
+		Provider: t.Provider,
+		Model:    t.Model,
+		Cues:     make([]srt.JSONCue, 0, len(parsed)),
+	}
+
+	for _, cue := range parsed {
+		resp.Cues = append(resp.Cues, cue.ToJSON())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// queryBool parses an optional boolean query parameter.
+func queryBool(v string) (bool, error) {
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
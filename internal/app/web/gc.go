@@ -0,0 +1,207 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/janitor"
+	"github.com/alesr/videoscriber/internal/pkg/signing"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+)
+
+// orphanGrace is the age from which files are orphans, so that the files of running jobs,
+// written one after the other, aren't.
+const orphanGrace = time.Hour
+
+type orphanFile struct {
+	Name    string    `json:"name"`
+	Owner   string    `json:"owner,omitempty"`
+	Project string    `json:"project"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Held    bool      `json:"held,omitempty"` // Under legal hold, so not removed.
+
+	obj storage.Object
+}
+
+type gcReport struct {
+	Untracked []orphanFile       `json:"untracked"` // Stored files without metadata, recorded when repaired.
+	Missing   []orphanFile       `json:"missing"`   // Metadata of removed files, marked as deleted when repaired.
+	Artifacts []orphanFile       `json:"artifacts"` // Artifacts of removed subtitles, removed when repaired.
+	Tmp       []janitor.Leftover `json:"tmp"`       // Expired files of the tmp directories, removed when repaired.
+	Bytes     int64              `json:"bytes"`     // Disk space of the artifacts and tmp files.
+	Repaired  bool               `json:"repaired"`
+}
+
+// gcReport reports the orphaned files of the storage: files the catalog doesn't record, records of
+// removed files, artifacts left behind by removed subtitles and expired tmp files.
+func (h *Handlers) gcReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.orphans(time.Now())
+	if err != nil {
+		h.e(w, "Failed to find orphaned files", err, http.StatusInternalServerError)
+		return
+	}
+	h.writeGCReport(w, report)
+}
+
+// collectGarbage repairs the orphaned files of gcReport: the catalog is synced with the storage, and the
+// orphaned artifacts and tmp files are removed, except the artifacts of the projects under legal hold.
+func (h *Handlers) collectGarbage(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+
+	report, err := h.orphans(now)
+	if err != nil {
+		h.e(w, "Failed to find orphaned files", err, http.StatusInternalServerError)
+		return
+	}
+
+	if len(report.Untracked) > 0 || len(report.Missing) > 0 {
+		if err := h.storage.Sync(); err != nil {
+			h.e(w, "Failed to sync the catalog", err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var removed int
+	for _, f := range report.Artifacts {
+		if f.Held {
+			continue
+		}
+
+		if err := h.storage.Remove(f.obj); err != nil {
+			h.e(w, "Failed to remove artifact", err, http.StatusInternalServerError)
+			return
+		}
+		removed++
+	}
+
+	tmpFiles, _, err := h.sweeper.SweepTmp(now)
+	if err != nil {
+		h.e(w, "Failed to remove tmp files", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "storage.gc",
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{
+			"untracked": strconv.Itoa(len(report.Untracked)),
+			"missing":   strconv.Itoa(len(report.Missing)),
+			"artifacts": strconv.Itoa(removed),
+			"tmp_files": strconv.Itoa(tmpFiles),
+		},
+	})
+
+	report.Repaired = true
+	h.writeGCReport(w, report)
+}
+
+// orphans finds the orphaned files at the given time.
+func (h *Handlers) orphans(now time.Time) (gcReport, error) {
+	report := gcReport{Untracked: []orphanFile{}, Missing: []orphanFile{}, Artifacts: []orphanFile{}}
+
+	orphans, err := h.storage.Orphans()
+	if err != nil {
+		return report, err
+	}
+
+	recorded, err := h.storage.List()
+	if err != nil {
+		return report, err
+	}
+
+	missing := make(map[string]bool, len(orphans.Missing))
+	for _, obj := range orphans.Missing {
+		missing[obj.Path] = true
+
+		if now.Sub(obj.ModTime) >= orphanGrace {
+			report.Missing = append(report.Missing, newOrphanFile(obj))
+		}
+	}
+
+	// The stored files, by directory, whether the catalog records them or not.
+	var stored []storage.Object
+	names := map[string]map[string]bool{}
+
+	for _, obj := range append(recorded, orphans.Untracked...) {
+		if missing[obj.Path] {
+			continue
+		}
+		stored = append(stored, obj)
+
+		dir := filepath.Dir(obj.Path)
+		if names[dir] == nil {
+			names[dir] = map[string]bool{}
+		}
+		names[dir][obj.Name] = true
+	}
+
+	for _, obj := range orphans.Untracked {
+		if now.Sub(obj.ModTime) >= orphanGrace {
+			report.Untracked = append(report.Untracked, newOrphanFile(obj))
+		}
+	}
+
+	for _, obj := range stored {
+		owners := subtitles.ArtifactOf(obj.Name)
+		if signed, ok := strings.CutSuffix(obj.Name, signing.Extension); ok {
+			owners = []string{signed}
+		}
+
+		if len(owners) == 0 || now.Sub(obj.ModTime) < orphanGrace {
+			continue
+		}
+
+		orphaned := true
+		for _, name := range owners {
+			if names[filepath.Dir(obj.Path)][name] {
+				orphaned = false
+				break
+			}
+		}
+
+		if orphaned {
+			f := newOrphanFile(obj)
+			f.Held = h.retention.CanModify(obj.Project) != nil
+
+			report.Artifacts = append(report.Artifacts, f)
+			report.Bytes += obj.Size
+		}
+	}
+
+	if report.Tmp, err = h.sweeper.Leftovers(now); err != nil {
+		return report, err
+	}
+
+	for _, f := range report.Tmp {
+		report.Bytes += f.Size
+	}
+	return report, nil
+}
+
+func newOrphanFile(obj storage.Object) orphanFile {
+	return orphanFile{
+		Name:    obj.Name,
+		Owner:   obj.Owner,
+		Project: obj.Project,
+		Size:    obj.Size,
+		ModTime: obj.ModTime,
+		obj:     obj,
+	}
+}
+
+func (h *Handlers) writeGCReport(w http.ResponseWriter, report gcReport) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
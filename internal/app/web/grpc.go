@@ -0,0 +1,384 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/app/grpc/videoscriberv1"
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcServer serves the gRPC API by forwarding its calls to the routes of the REST API, so that
+// both authenticate, limit and audit the callers the same way, and respond with the same errors.
+type grpcServer struct {
+	videoscriberv1.UnimplementedVideoscriberServer
+
+	handler http.Handler
+	jobs    jobManager
+}
+
+var protoStates = map[jobs.State]videoscriberv1.State{
+	jobs.StateQueued:       videoscriberv1.State_STATE_QUEUED,
+	jobs.StateExtracting:   videoscriberv1.State_STATE_EXTRACTING,
+	jobs.StateScheduled:    videoscriberv1.State_STATE_SCHEDULED,
+	jobs.StateTranscribing: videoscriberv1.State_STATE_TRANSCRIBING,
+	jobs.StateRendering:    videoscriberv1.State_STATE_RENDERING,
+	jobs.StateChecking:     videoscriberv1.State_STATE_CHECKING,
+	jobs.StateDone:         videoscriberv1.State_STATE_DONE,
+	jobs.StateFailed:       videoscriberv1.State_STATE_FAILED,
+}
+
+// Transcribe streams the uploaded file to the upload endpoint as it is received, and returns the job transcribing it.
+func (s *grpcServer) Transcribe(stream videoscriberv1.Videoscriber_TranscribeServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return status.Error(codes.InvalidArgument, "No options in request")
+		}
+		return err
+	}
+
+	opts := req.GetOptions()
+	if opts == nil {
+		return status.Error(codes.InvalidArgument, "The first message must carry the options")
+	}
+
+	if opts.GetFileName() == "" {
+		return status.Error(codes.InvalidArgument, "The options must name the file")
+	}
+
+	body, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	resp := &grpcResponse{header: http.Header{}}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.forward(stream.Context(), http.MethodPost, "/upload", body, form.FormDataContentType(), resp)
+
+		// Stops receiving the file when the upload was rejected before reading all of it.
+		body.Close()
+	}()
+
+	err = writeUpload(form, opts, stream)
+	pw.CloseWithError(err)
+	<-done
+
+	if err != nil && !errors.Is(err, io.ErrClosedPipe) {
+		return err
+	}
+
+	if err := resp.err(); err != nil {
+		return err
+	}
+
+	var upload uploadResponse
+	if err := json.Unmarshal(resp.body.Bytes(), &upload); err != nil {
+		return status.Errorf(codes.Internal, "could not decode upload response: %s", err)
+	}
+
+	job, err := s.jobs.Get(upload.JobID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "could not get job: %s", err)
+	}
+	return stream.SendAndClose(protoJob(job))
+}
+
+// writeUpload writes the options, then the content of the file as it is received,
+// as the multipart form of the upload endpoint.
+func writeUpload(form *multipart.Writer, opts *videoscriberv1.TranscribeOptions, stream videoscriberv1.Videoscriber_TranscribeServer) error {
+	fields := []struct{ name, value string }{
+		{"language", opts.GetLanguage()},
+		{"format", opts.GetFormat()},
+		{"project", opts.GetProject()},
+		{"provider", opts.GetProvider()},
+		{"prompt", opts.GetPrompt()},
+		{"translate_to", strings.Join(opts.GetTranslateTo(), ",")},
+		{"priority", opts.GetPriority()},
+		{"urgent", strconv.FormatBool(opts.GetUrgent())},
+		{"keep_video", strconv.FormatBool(opts.GetKeepVideo())},
+	}
+
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+
+		if err := form.WriteField(f.name, f.value); err != nil {
+			return fmt.Errorf("could not write field %q: %w", f.name, err)
+		}
+	}
+
+	file, err := form.CreateFormFile("file", opts.GetFileName())
+	if err != nil {
+		return fmt.Errorf("could not create file part: %w", err)
+	}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if req.GetOptions() != nil {
+			return status.Error(codes.InvalidArgument, "Only the first message can carry the options")
+		}
+
+		if _, err := file.Write(req.GetChunk()); err != nil {
+			return fmt.Errorf("could not write file part: %w", err)
+		}
+	}
+
+	if err := form.Close(); err != nil {
+		return fmt.Errorf("could not close form: %w", err)
+	}
+	return nil
+}
+
+func (s *grpcServer) GetJob(ctx context.Context, req *videoscriberv1.GetJobRequest) (*videoscriberv1.Job, error) {
+	resp := &grpcResponse{header: http.Header{}}
+	s.forward(ctx, http.MethodGet, "/jobs/"+url.PathEscape(req.GetId()), nil, "", resp)
+
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+
+	var job jobs.Job
+	if err := json.Unmarshal(resp.body.Bytes(), &job); err != nil {
+		return nil, status.Errorf(codes.Internal, "could not decode job: %s", err)
+	}
+	return protoJob(job), nil
+}
+
+// WatchJob streams the server-sent events of the job: the state of each of its files first and last,
+// and their changes in between.
+func (s *grpcServer) WatchJob(req *videoscriberv1.WatchJobRequest, stream videoscriberv1.Videoscriber_WatchJobServer) error {
+	resp := &grpcResponse{header: http.Header{}, send: func(event string, data []byte) error {
+		switch event {
+		case "job":
+			var job jobs.Job
+			if err := json.Unmarshal(data, &job); err != nil {
+				return fmt.Errorf("could not decode job: %w", err)
+			}
+
+			for i, f := range job.Files {
+				e := jobs.Event{JobID: job.ID, JobState: job.State, Index: i, File: f.Name, State: f.State, Progress: f.Progress, Error: f.Error}
+				if err := stream.Send(protoJobEvent(e)); err != nil {
+					return err
+				}
+			}
+			return nil
+		case "progress":
+			var e jobs.Event
+			if err := json.Unmarshal(data, &e); err != nil {
+				return fmt.Errorf("could not decode event: %w", err)
+			}
+			return stream.Send(protoJobEvent(e))
+		}
+		return nil
+	}}
+
+	s.forward(stream.Context(), http.MethodGet, "/jobs/"+url.PathEscape(req.GetId())+"/events", nil, "", resp)
+	return resp.err()
+}
+
+func (s *grpcServer) ListSubtitles(ctx context.Context, req *videoscriberv1.ListSubtitlesRequest) (*videoscriberv1.ListSubtitlesResponse, error) {
+	resp := &grpcResponse{header: http.Header{}}
+	s.forward(ctx, http.MethodGet, "/subtitles?project="+url.QueryEscape(req.GetProject()), nil, "", resp)
+
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+
+	var list listSubtitlesResponse
+	if err := json.Unmarshal(resp.body.Bytes(), &list); err != nil {
+		return nil, status.Errorf(codes.Internal, "could not decode subtitles: %s", err)
+	}
+
+	out := &videoscriberv1.ListSubtitlesResponse{Subtitles: make([]*videoscriberv1.Subtitle, 0, len(list.Items))}
+	for _, sub := range list.Items {
+		out.Subtitles = append(out.Subtitles, &videoscriberv1.Subtitle{
+			Name:         sub.Name,
+			Project:      sub.Project,
+			Language:     sub.Language,
+			Format:       sub.Format,
+			Size:         sub.Size,
+			OriginalName: sub.OriginalName,
+			Duration:     sub.Duration,
+			JobId:        sub.JobID,
+			Status:       sub.Status,
+			CreatedAt:    timestamppb.New(sub.CreatedAt),
+		})
+	}
+	return out, nil
+}
+
+// forwardedMetadata lists the metadata of a call forwarded as headers: the credentials of the caller.
+// The SSO header is never forwarded, as no SSO proxy stands in front of the gRPC port to set it.
+var forwardedMetadata = []string{"authorization", "x-api-key"}
+
+// forward serves the call as a request to the REST routes, with the API key in the metadata of the call as its headers.
+func (s *grpcServer) forward(ctx context.Context, method, target string, body io.Reader, contentType string, w *grpcResponse) {
+	r, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		w.fail(http.StatusInternalServerError, fmt.Sprintf("could not create request: %s", err))
+		return
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, key := range forwardedMetadata {
+		for _, v := range md.Get(key) {
+			r.Header.Add(key, v)
+		}
+	}
+
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+	}
+
+	s.handler.ServeHTTP(w, r)
+}
+
+// grpcResponse records the response of a forwarded call. The server-sent events of a streamed
+// response are passed to send as they are written, instead of being recorded.
+type grpcResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+	send   func(event string, data []byte) error
+}
+
+func (w *grpcResponse) Header() http.Header {
+	return w.header
+}
+
+func (w *grpcResponse) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *grpcResponse) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.body.Write(p)
+
+	if w.send == nil || w.status != http.StatusOK {
+		return len(p), nil
+	}
+
+	for {
+		end := bytes.Index(w.body.Bytes(), []byte("\n\n"))
+		if end < 0 {
+			return len(p), nil
+		}
+
+		var event, data string
+		for _, line := range strings.Split(string(w.body.Next(end+2)), "\n") {
+			if v, ok := strings.CutPrefix(line, "event: "); ok {
+				event = v
+			} else if v, ok := strings.CutPrefix(line, "data: "); ok {
+				data = v
+			}
+		}
+
+		if err := w.send(event, []byte(data)); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Flush lets the streamed responses be written: their events are sent as soon as they are complete.
+func (w *grpcResponse) Flush() {}
+
+func (w *grpcResponse) fail(status int, message string) {
+	w.WriteHeader(status)
+	w.body.WriteString(message)
+}
+
+// err returns the error of the response, with the gRPC code of its HTTP status and its message.
+func (w *grpcResponse) err() error {
+	if w.status == 0 || w.status < http.StatusBadRequest {
+		return nil
+	}
+	return status.Error(grpcCode(w.status), strings.TrimSpace(w.body.String()))
+}
+
+func grpcCode(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	}
+	return codes.Internal
+}
+
+func protoJob(job jobs.Job) *videoscriberv1.Job {
+	out := &videoscriberv1.Job{
+		Id:        job.ID,
+		Type:      string(job.Type),
+		State:     protoStates[job.State],
+		Files:     make([]*videoscriberv1.JobFile, 0, len(job.Files)),
+		CreatedAt: timestamppb.New(job.CreatedAt),
+		UpdatedAt: timestamppb.New(job.UpdatedAt),
+	}
+
+	for _, f := range job.Files {
+		out.Files = append(out.Files, &videoscriberv1.JobFile{
+			Name:     f.Name,
+			State:    protoStates[f.State],
+			Progress: f.Progress,
+			Error:    f.Error,
+			Subtitle: f.Subtitle,
+			Outputs:  f.Outputs,
+			Project:  f.Project,
+		})
+	}
+	return out
+}
+
+func protoJobEvent(e jobs.Event) *videoscriberv1.JobEvent {
+	return &videoscriberv1.JobEvent{
+		JobId:    e.JobID,
+		JobState: protoStates[e.JobState],
+		Index:    int32(e.Index),
+		File:     e.File,
+		State:    protoStates[e.State],
+		Progress: e.Progress,
+		Error:    e.Error,
+	}
+}
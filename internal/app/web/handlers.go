@@ -2,115 +2,999 @@ package web
 
 import (
 	"archive/zip"
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
-	"path/filepath"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/alesr/videoscriber/internal/pkg/analytics"
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/callback"
+	"github.com/alesr/videoscriber/internal/pkg/clips"
+	"github.com/alesr/videoscriber/internal/pkg/email"
+	"github.com/alesr/videoscriber/internal/pkg/features"
+	"github.com/alesr/videoscriber/internal/pkg/health"
+	"github.com/alesr/videoscriber/internal/pkg/ingest"
+	"github.com/alesr/videoscriber/internal/pkg/janitor"
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/alesr/videoscriber/internal/pkg/models"
+	"github.com/alesr/videoscriber/internal/pkg/plans"
+	"github.com/alesr/videoscriber/internal/pkg/publish"
+	"github.com/alesr/videoscriber/internal/pkg/qa"
+	"github.com/alesr/videoscriber/internal/pkg/quota"
+	"github.com/alesr/videoscriber/internal/pkg/retention"
+	"github.com/alesr/videoscriber/internal/pkg/signing"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/store"
+	"github.com/alesr/videoscriber/internal/pkg/styles"
 	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+	"github.com/alesr/videoscriber/internal/pkg/uploads"
+	"github.com/alesr/videoscriber/internal/pkg/users"
 	"github.com/go-chi/chi/v5"
 )
 
-const (
-	subtitlesDir string = "subtitles"
-	maxFileSize  int64  = 1 << 30 // 1GB
-)
-
 type subtitler interface {
-	GenerateFromAudioData(ctx context.Context, inputs []*subtitles.Input) error
+	GenerateFromAudioData(ctx context.Context, inputs []*subtitles.Input) (subtitles.Results, error)
+	Prepare(in *subtitles.Input) error
+	Discard(in *subtitles.Input)
+	TranscribeWith(ctx context.Context, in *subtitles.Input, providers []string) (map[string][]byte, time.Duration, error)
+	Transcribe(ctx context.Context, in *subtitles.Input) (subtitles.Transcription, error)
+	Queue(in *subtitles.Input, dir string) (subtitles.QueuedInput, error)
+	HasProvider(name string) bool
+}
+
+type jobManager interface {
+	Create(jobType jobs.Type, owner string, fileNames []string) (jobs.Job, error)
+	Get(id string) (jobs.Job, error)
+	List(from, to time.Time) []jobs.Job
+	SetFileState(id string, index int, state jobs.State, progress float64, fileErr error) error
+	Requeue(id string) error
+	SetPublication(id string, index int, p jobs.Publication) error
+	SetFileSubtitle(id string, index int, subName, project string) error
+	SetFileOutputs(id string, index int, outputs []string) error
+	SetFileSchedule(id string, index int, at time.Time) error
+	SetFileReview(id string, index int, state jobs.ReviewState) error
+	Subscribe(id string) (<-chan jobs.Event, func(), error)
+}
+
+type retentionManager interface {
+	Policy(project string) retention.Policy
+	SetPolicy(project string, p retention.Policy, actor string) error
+	CanModify(project string) error
+}
+
+type auditor interface {
+	Record(e audit.Entry) error
+}
+
+type styleStore interface {
+	List() []styles.Profile
+	Get(name string) (styles.Profile, error)
+	Put(p styles.Profile) error
+	Delete(name string) error
+}
+
+type urlFetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*ingest.Download, error)
+}
+
+type slackClient interface {
+	Enabled() bool
+	Verify(header http.Header, body []byte) error
+	PostMessage(ctx context.Context, channel, threadTS, text string) (string, error)
+}
+
+type inbox interface {
+	Enabled() bool
+	Receive(ctx context.Context, body []byte) (*email.Message, error)
+}
+
+type mailer interface {
+	Enabled() bool
+	Send(to, subject, body string) error
+}
+
+type clipGenerator interface {
+	Prepare(name string, data io.Reader) (string, error)
+	Render(ctx context.Context, jobID string, index int, videoPath string, cues []srt.Cue, r clips.Range, variants []clips.Variant, forceStyle string) ([]string, error)
+	Burn(ctx context.Context, videoPath string, cues []srt.Cue, forceStyle string) (string, error)
+	Path(jobID, name string) (string, error)
+}
+
+type uploadStore interface {
+	MaxSize() int64
+	Create(owner string, length int64, metadata map[string]string) (uploads.Upload, error)
+	Get(id string) (uploads.Upload, error)
+	List(owner string) ([]uploads.Upload, error)
+	Append(id string, offset int64, r io.Reader) (uploads.Upload, error)
+	Open(id string) (*os.File, error)
+	Finish(id, jobID string) error
+	Remove(id string) error
+}
+
+type notifier interface {
+	Enabled() bool
+	Notify(ctx context.Context, callbackURL string, p callback.Payload) error
+}
+
+type verifier interface {
+	Enabled() bool
+	PublicKey() ([]byte, error)
+	Verify(data, signature []byte) (signing.Signature, error)
+}
+
+type authenticator interface {
+	Enabled() bool
+	User(key string) (string, bool)
+}
+
+type rateLimiter interface {
+	Allow(key string) (bool, time.Duration)
+}
+
+type accountant interface {
+	Check(owner string) error
+	Record(owner string, d time.Duration) error
+	Usage(owner string) (quota.Usage, error)
+}
+
+type assistant interface {
+	Ask(ctx context.Context, question string, docs []qa.Document) (qa.Answer, error)
+}
+
+type readinessChecker interface {
+	Ready(ctx context.Context) health.Report
+}
+
+type modelStore interface {
+	List() (models.Usage, error)
+	Download(ctx context.Context, m models.Model) (models.Model, error)
+	Pin(name string, pinned bool) (models.Model, error)
+	Remove(name string) error
+}
+
+type featureFlags interface {
+	Enabled(f features.Flag) bool
+	Toggle(f features.Flag, enabled bool) (features.State, error)
+	List() []features.State
+}
+
+type sweeper interface {
+	Stats() janitor.Stats
+	Leftovers(now time.Time) ([]janitor.Leftover, error)
+	SweepTmp(now time.Time) (int, int64, error)
+}
+
+type planStore interface {
+	List() []plans.Plan
+	Get(name string) (plans.Plan, error)
+	Put(p plans.Plan) error
+	Delete(name string) error
+	Tenants() map[string]string
+	Assign(tenant, name string) (plans.Plan, error)
+	Unassign(tenant string) error
+	PlanOf(tenant string) (plans.Plan, bool)
+}
+
+type userStore interface {
+	Seen(name string) (users.User, error)
+	Resolve(identity string) (users.User, error)
+	List() []users.User
+	Get(name string) (users.User, error)
+	Set(name string, role users.Role, projects []string) (users.User, error)
+}
+
+type subtitleStore interface {
+	Write(owner, language, project, fileName string, data []byte) (string, error)
+	List() ([]storage.Object, error)
+	Find(owner, name, language, project string) (storage.Object, error)
+	Exists(owner, language, project, fileName string) (bool, error)
+	Remove(obj storage.Object) error
+	Replace(obj storage.Object, data []byte) error
+	Read(obj storage.Object) ([]byte, error)
+	Open(obj storage.Object) (io.ReadSeekCloser, error)
+	Subtitles(owner string) ([]store.Subtitle, error)
+	Search(owner, query, language, project string, limit int) ([]store.SearchResult, bool, error)
+	Backfill(retag, dryRun bool) ([]store.Backfilled, error)
+	Orphans() (store.Orphans, error)
+	Sync() error
+	Annotate(owner, language, project, fileName string, a store.Annotation) error
+	Hold(owner, language, project, fileName string, score float64, issues []string) error
+	Reviews(owner string) ([]store.Review, error)
+	FindReview(owner, name, language, project string) (store.Review, error)
+	Claim(review store.Review, reviewer string) (store.Review, error)
+	Assign(review store.Review, reviewer string) (store.Review, error)
+	AddComment(review store.Review, comment store.Comment) (store.Comment, error)
+	Comments(review store.Review) ([]store.Comment, error)
+	Share(obj storage.Object, expiresAt time.Time, videoURL string) (store.Share, error)
+	Shared(token string) (store.Subtitle, store.Share, error)
+	Unshare(token string) error
+	Revise(review store.Review, data []byte) error
+	Approve(review store.Review) error
 }
 
 type Handlers struct {
-	logger    *slog.Logger
-	subtitler subtitler
+	logger     *slog.Logger
+	subtitler  subtitler
+	jobs       jobManager
+	storage    subtitleStore
+	retention  retentionManager
+	styles     styleStore
+	publishers map[string]publish.Publisher
+	auditor    auditor
+	ingest     urlFetcher
+	slack      slackClient
+	inbox      inbox
+	mailer     mailer
+	uploads    uploadStore
+	clips      clipGenerator
+	auth       authenticator
+	limiter    rateLimiter
+	quota      accountant
+	assistant  assistant
+	readiness  readinessChecker
+	models     modelStore
+	features   featureFlags
+	sweeper    sweeper
+	plans      planStore
+	users      userStore
+	callbacks  notifier
+	signer     verifier
+	monitor    storageMonitor
+	// queue holds the jobs submitted while the storage is degraded. Disabled when not configured.
+	queue  jobQueue
+	queued chan struct{}
+	// running counts the jobs of each owner, limited by their plan.
+	running *runningJobs
+	// admins are the users allowed to administer the server.
+	admins map[string]bool
+	// maxUploadSize is the maximum size in bytes of an uploaded file.
+	maxUploadSize int64
+	// uploadRate is the maximum rate in bytes per second at which uploads are read from a connection, unlimited when 0.
+	uploadRate int64
+	publicURL  string
+	// notifyEmails are emailed the summary of the jobs of at least notifyMinFiles files once they finish.
+	notifyEmails   []string
+	notifyMinFiles int
+	// ssoHeader is the header the SSO proxy in front of the server sets with the identity of the user. Empty without SSO.
+	ssoHeader string
+	// reviewThreshold is the quality score below which subtitles are held for review. Zero disables reviews.
+	reviewThreshold float64
+	zipCache        *zipCache
+	hub             *hub
+	// wsOrigins are the origins allowed to open websockets, besides the one of the server.
+	wsOrigins map[string]bool
+	// dedup attaches repeated submissions to the job of the first one. Nil when disabled.
+	dedup *dedup
+
+	// ctx is the context of the background work, canceled when draining times out.
+	ctx      context.Context
+	cancel   context.CancelFunc
+	inflight *inflight
+}
+
+// HandlersConfig is the dependencies and the settings of the handlers.
+type HandlersConfig struct {
+	Logger     *slog.Logger
+	Subtitler  subtitler
+	Jobs       jobManager
+	Storage    subtitleStore
+	Retention  retentionManager
+	Styles     styleStore
+	Publishers map[string]publish.Publisher
+	Auditor    auditor
+	Ingest     urlFetcher
+	Slack      slackClient
+	Inbox      inbox
+	Mailer     mailer
+	Uploads    uploadStore
+	Clips      clipGenerator
+	Auth       authenticator
+	Limiter    rateLimiter
+	Quota      accountant
+	Assistant  assistant
+	Readiness  readinessChecker
+	Models     modelStore
+	Features   featureFlags
+	Sweeper    sweeper
+	Plans      planStore
+	Users      userStore
+	Callbacks  notifier
+	Signer     verifier
+	Monitor    storageMonitor
+	// Queue holds the jobs submitted while the storage is degraded, disabled when nil.
+	Queue jobQueue
+
+	// MaxUploadSize is the maximum size in bytes of an uploaded file.
+	MaxUploadSize int64
+	// UploadRate is the maximum rate in bytes per second at which uploads are read from a connection, unlimited when 0.
+	UploadRate int64
+	// PublicURL is the public base URL of the server, used for the links sent to users.
+	PublicURL string
+	// NotifyEmails are emailed the summary of the jobs of at least NotifyMinFiles files once they finish.
+	NotifyEmails   []string
+	NotifyMinFiles int
+	// SSOHeader is the header the SSO proxy in front of the server sets with the identity of the user. Empty without SSO.
+	SSOHeader string
+	// ReviewThreshold is the quality score below which subtitles are held for review. Zero disables reviews.
+	ReviewThreshold float64
+	// DedupWindow is the window within which repeated submissions are attached to the job of the first one, disabled when 0.
+	DedupWindow time.Duration
+	// Admins are the users allowed to administer the server.
+	Admins []string
+	// WSOrigins are the origins allowed to open websockets besides the one of the server, e.g. file:// for the Electron app.
+	WSOrigins []string
 }
 
-func NewHandlers(logger *slog.Logger, subtitler subtitler) *Handlers {
+func NewHandlers(cfg HandlersConfig) *Handlers {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var submissions *dedup
+	if cfg.DedupWindow > 0 {
+		submissions = newDedup(cfg.DedupWindow)
+	}
+
+	adminUsers := make(map[string]bool, len(cfg.Admins))
+	for _, user := range cfg.Admins {
+		adminUsers[user] = true
+	}
+
+	wsOrigins := make(map[string]bool, len(cfg.WSOrigins))
+	for _, origin := range cfg.WSOrigins {
+		wsOrigins[origin] = true
+	}
+
 	return &Handlers{
-		logger:    logger,
-		subtitler: subtitler,
+		logger:          cfg.Logger,
+		subtitler:       cfg.Subtitler,
+		jobs:            cfg.Jobs,
+		storage:         cfg.Storage,
+		retention:       cfg.Retention,
+		styles:          cfg.Styles,
+		publishers:      cfg.Publishers,
+		auditor:         cfg.Auditor,
+		ingest:          cfg.Ingest,
+		slack:           cfg.Slack,
+		inbox:           cfg.Inbox,
+		mailer:          cfg.Mailer,
+		uploads:         cfg.Uploads,
+		clips:           cfg.Clips,
+		auth:            cfg.Auth,
+		limiter:         cfg.Limiter,
+		quota:           cfg.Quota,
+		assistant:       cfg.Assistant,
+		readiness:       cfg.Readiness,
+		models:          cfg.Models,
+		features:        cfg.Features,
+		sweeper:         cfg.Sweeper,
+		plans:           cfg.Plans,
+		users:           cfg.Users,
+		callbacks:       cfg.Callbacks,
+		signer:          cfg.Signer,
+		monitor:         cfg.Monitor,
+		queue:           cfg.Queue,
+		queued:          make(chan struct{}, 1),
+		ssoHeader:       cfg.SSOHeader,
+		running:         newRunningJobs(),
+		inflight:        newInflight(),
+		admins:          adminUsers,
+		maxUploadSize:   cfg.MaxUploadSize,
+		uploadRate:      cfg.UploadRate,
+		publicURL:       strings.TrimSuffix(cfg.PublicURL, "/"),
+		notifyEmails:    cfg.NotifyEmails,
+		notifyMinFiles:  cfg.NotifyMinFiles,
+		reviewThreshold: cfg.ReviewThreshold,
+		zipCache:        newZipCache(),
+		hub:             newHub(cfg.Logger, cfg.Jobs),
+		wsOrigins:       wsOrigins,
+		dedup:           submissions,
+		ctx:             ctx,
+		cancel:          cancel,
 	}
 }
 
 type uploadResponse struct {
-	Filenames []string `json:"filenames"`
+	Message string `json:"message"`
+	JobID   string `json:"job_id,omitempty"`
+
+	// Duplicate is set when the request repeats a recent submission, and is attached to its job.
+	Duplicate bool `json:"duplicate,omitempty"`
+
+	// Succeeded and Failed are the outcomes of the files, when known. Files rejected when uploaded
+	// are failed, and so are those whose transcription failed when waiting for the job.
+	Succeeded []fileOutcome `json:"succeeded,omitempty"`
+	Failed    []fileOutcome `json:"failed,omitempty"`
+}
+
+type fileOutcome struct {
+	Name     string `json:"name"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Error    string `json:"error,omitempty"`
 }
 
 func (h *Handlers) createSubtitles(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseMultipartForm(maxFileSize); err != nil {
-		h.e(w, "Failed to parse the request", err, http.StatusBadRequest)
+	files, err := h.streamMultipart(r)
+
+	// The copies of the files are removed unless a job processes them.
+	var started bool
+	defer func() {
+		if !started {
+			for _, in := range files {
+				h.subtitler.Discard(in)
+			}
+		}
+	}()
+
+	if err != nil {
+		h.multipartError(w, err)
 		return
 	}
 
-	files, ok := r.MultipartForm.File["file"]
-	if !ok || len(files) == 0 {
+	if len(files) == 0 {
 		h.e(w, "No file part in request", nil, http.StatusBadRequest)
 		return
 	}
 
+	project := r.FormValue("project")
+	if project != "" {
+		if err := retention.ValidateProject(project); err != nil {
+			h.e(w, "Invalid project name", err, http.StatusBadRequest)
+			return
+		}
+
+		if !canAccessProject(r, project) {
+			h.e(w, "Access to the project is not allowed", nil, http.StatusForbidden)
+			return
+		}
+	}
+
+	anonymize, err := formBool(r, "anonymize")
+	if err != nil {
+		h.e(w, "Invalid anonymize value", err, http.StatusBadRequest)
+		return
+	}
+
+	multilingual, err := formBool(r, "multilingual")
+	if err != nil {
+		h.e(w, "Invalid multilingual value", err, http.StatusBadRequest)
+		return
+	}
+
+	provider := r.FormValue("provider")
+	if provider != "" && !h.subtitler.HasProvider(provider) {
+		h.e(w, fmt.Sprintf("Unknown provider %q", provider), nil, http.StatusBadRequest)
+		return
+	}
+
+	wordTimestamps, err := formBool(r, "word_timestamps")
+	if err != nil {
+		h.e(w, "Invalid word_timestamps value", err, http.StatusBadRequest)
+		return
+	}
+
+	diarize, err := formBool(r, "diarize")
+	if err != nil {
+		h.e(w, "Invalid diarize value", err, http.StatusBadRequest)
+		return
+	}
+
+	urgent, err := formBool(r, "urgent")
+	if err != nil {
+		h.e(w, "Invalid urgent value", err, http.StatusBadRequest)
+		return
+	}
+
+	timeline, err := formBool(r, "timeline")
+	if err != nil {
+		h.e(w, "Invalid timeline value", err, http.StatusBadRequest)
+		return
+	}
+
+	chapters, err := formBool(r, "chapters")
+	if err != nil {
+		h.e(w, "Invalid chapters value", err, http.StatusBadRequest)
+		return
+	}
+
+	keepVideo, err := formBool(r, "keep_video")
+	if err != nil {
+		h.e(w, "Invalid keep_video value", err, http.StatusBadRequest)
+		return
+	}
+
+	debug, err := formBool(r, "debug")
+	if err != nil {
+		h.e(w, "Invalid debug value", err, http.StatusBadRequest)
+		return
+	}
+
+	callbackURL := r.FormValue("callback_url")
+	if err := h.validateCallback(callbackURL); err != nil {
+		h.e(w, "Invalid callback_url value", err, http.StatusBadRequest)
+		return
+	}
+
+	profile, err := subtitles.ParseProfile(r.FormValue("profile"))
+	if err != nil {
+		h.e(w, "Invalid profile", err, http.StatusBadRequest)
+		return
+	}
+
+	format, err := subtitles.ParseFormat(r.FormValue("format"))
+	if err != nil {
+		h.e(w, "Invalid format", err, http.StatusBadRequest)
+		return
+	}
+
+	// Terminology of the domain, e.g. product names, people or jargon, improves their recognition.
+	prompt := r.FormValue("prompt")
+	if err := validatePrompt(prompt); err != nil {
+		h.e(w, "Invalid prompt", err, http.StatusBadRequest)
+		return
+	}
+
+	// Audio filters, e.g. preprocess=denoise,normalize, or preprocess=none to skip the default ones.
+	preprocess, err := subtitles.ParseFilters(r.FormValue("preprocess"))
+	if err != nil {
+		h.e(w, "Invalid preprocess value", err, http.StatusBadRequest)
+		return
+	}
+
+	if format == subtitles.FormatJSON && multilingual {
+		h.e(w, "The json format can not be combined with multilingual", nil, http.StatusBadRequest)
+		return
+	}
+
+	// Non-English speech can get English subtitles directly with task=translate.
+	task, err := subtitles.ParseTask(r.FormValue("task"))
+	if err != nil {
+		h.e(w, "Invalid task", err, http.StatusBadRequest)
+		return
+	}
+
+	if task == subtitles.TaskTranslate && multilingual {
+		h.e(w, "Translation can not be combined with multilingual", nil, http.StatusBadRequest)
+		return
+	}
+
+	// The subtitles can also be translated, e.g. translate_to=en,es writes video.en.srt and video.es.srt.
+	translations, err := subtitles.ParseLanguages(r.FormValue("translate_to"))
+	if err != nil {
+		h.e(w, "Invalid translate_to value", err, http.StatusBadRequest)
+		return
+	}
+
+	// Subtitles can be published to a video platform once done, e.g. publish=vimeo
+	// with the video of each file given as video_id, or video_id[video.mp4] for several files.
+	// Webhooks don't require a video_id.
+	platform := r.FormValue("publish")
+	if platform != "" {
+		if _, ok := h.publishers[platform]; !ok {
+			h.e(w, fmt.Sprintf("Unknown platform %q", platform), nil, http.StatusBadRequest)
+			return
+		}
+
+		if format != subtitles.FormatSRT && format != subtitles.FormatVTT {
+			h.e(w, "Only SRT and VTT subtitles can be published", nil, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Waits for the job to finish and responds with the outcome of each file.
+	wait, err := formBool(r, "wait")
+	if err != nil {
+		h.e(w, "Invalid wait value", err, http.StatusBadRequest)
+		return
+	}
+
+	language := r.FormValue("language")
+	if language == "" {
+		language = subtitles.DefaultLanguage
+	}
+
+	recording := r.FormValue("recording")
+
+	var rejected []fileOutcome
+
 	genSubtitleInput := make([]*subtitles.Input, 0, len(files))
+	publications := make([]*jobs.Publication, 0, len(files))
+
+	for _, in := range files {
+		// A per-file override takes precedence over the request language, e.g. language[video.mp4]=en.
+		fileLanguage := language
+		if v := r.FormValue("language[" + in.FileName + "]"); v != "" {
+			fileLanguage = v
+		}
 
-	for _, header := range files {
-		uploadedFile, err := header.Open()
+		if !subtitles.SupportedLanguage(fileLanguage) {
+			// The parts of a recording are transcribed together, so a part can't be left out.
+			if recording != "" {
+				h.e(w, fmt.Sprintf("Unsupported language %q for file %q", fileLanguage, in.FileName), nil, http.StatusBadRequest)
+				return
+			}
+
+			rejected = append(rejected, fileOutcome{Name: in.FileName, Error: fmt.Sprintf("unsupported language %q", fileLanguage)})
+			h.subtitler.Discard(in)
+			continue
+		}
+
+		in.Language = fileLanguage
+		in.Project = project
+		in.Owner = owner(r)
+		in.Anonymize = anonymize
+		in.Multilingual = multilingual
+		in.Provider = provider
+		in.WordTimestamps = wordTimestamps
+		in.Diarize = diarize
+		in.Prompt = prompt
+		in.Task = task
+		in.Translations = translations
+		in.Timeline = timeline
+		in.Chapters = chapters
+		in.Profile = profile
+		in.Format = format
+		in.Preprocess = preprocess
+		in.Priority = r.FormValue("priority")
+		in.Urgent = urgent
+		in.KeepVideo = keepVideo
+		in.Debug = debug
+
+		genSubtitleInput = append(genSubtitleInput, in)
+	}
+
+	if len(genSubtitleInput) == 0 {
+		h.respondUpload(w, http.StatusBadRequest, uploadResponse{Message: "No file can be transcribed", Failed: rejected})
+		return
+	}
+
+	// The same files submitted again with the same options are attached to the job of the first submission.
+	// Whether to wait doesn't change the submission.
+	options := maps.Clone(r.Form)
+	delete(options, "wait")
+
+	digests := make([]string, 0, len(genSubtitleInput))
+	for _, in := range genSubtitleInput {
+		digests = append(digests, in.FileName+":"+in.Digest())
+	}
+	key := submissionKey(owner(r), options, digests)
+
+	// The files of a recording split in several parts (e.g. chaptered camera files) can be
+	// transcribed as one recording, named after the recording field, in upload order.
+	if recording != "" {
+		splitParts, err := formBool(r, "split_parts")
 		if err != nil {
-			h.e(w, "Failed to open the uploaded file", err, http.StatusInternalServerError)
+			h.e(w, "Invalid split_parts value", err, http.StatusBadRequest)
 			return
 		}
-		defer uploadedFile.Close()
 
-		genSubtitleInput = append(genSubtitleInput, &subtitles.Input{
-			Data:     uploadedFile,
-			FileName: header.Filename,
-			Language: "pt", // hardcoded for now
-		})
+		in := subtitles.Recording(recording, genSubtitleInput)
+		in.Language, in.SplitParts = language, splitParts
+
+		files = append(files, in)
+		genSubtitleInput = []*subtitles.Input{in}
 	}
 
-	if err := h.subtitler.GenerateFromAudioData(r.Context(), genSubtitleInput); err != nil {
-		h.e(w, "Failed to generate subtitles", err, http.StatusInternalServerError)
+	if platform != "" {
+		for _, in := range genSubtitleInput {
+			videoID := r.FormValue("video_id[" + in.FileName + "]")
+			if videoID == "" && len(genSubtitleInput) == 1 {
+				videoID = r.FormValue("video_id")
+			}
+
+			if videoID == "" && platform != publish.PlatformWebhook {
+				h.e(w, fmt.Sprintf("No video_id for file %q", in.FileName), nil, http.StatusBadRequest)
+				return
+			}
+			publications = append(publications, &jobs.Publication{Platform: platform, VideoID: videoID, State: jobs.PublishPending})
+		}
+	}
+
+	job, duplicate, err := h.submit(r.Context(), key, func() (jobs.Job, error) {
+		return h.startJob(genSubtitleInput, publications, callbackURL)
+	})
+	if err != nil {
+		if errors.Is(err, errStorageDegraded) {
+			h.storageUnavailable(w, err)
+			return
+		}
+		h.e(w, "Failed to start job", err, http.StatusInternalServerError)
 		return
 	}
 
-	// Add these lines to send a JSON response back to the Electron app
-	response := map[string]string{
-		"message": "Subtitles generated successfully",
+	// The files of a duplicate are discarded, the job of the first submission processes its own.
+	started = !duplicate
+
+	resp := uploadResponse{Message: "Subtitles generation started", JobID: job.ID, Duplicate: duplicate, Failed: rejected}
+	if duplicate {
+		resp.Message = "Attached to the job of the same submission"
+	}
+
+	if !wait {
+		// Some files were rejected, the others are processed.
+		if len(rejected) > 0 {
+			h.respondUpload(w, http.StatusMultiStatus, resp)
+			return
+		}
+		h.respondUpload(w, http.StatusAccepted, resp)
+		return
+	}
+
+	job, err = h.awaitJob(job.ID, nil)
+	if err != nil {
+		h.e(w, "Failed to wait for job", err, http.StatusInternalServerError)
+		return
+	}
+
+	for _, f := range job.Files {
+		if f.State == jobs.StateDone {
+			resp.Succeeded = append(resp.Succeeded, fileOutcome{Name: f.Name, Subtitle: f.Subtitle})
+		} else {
+			resp.Failed = append(resp.Failed, fileOutcome{Name: f.Name, Error: f.Error})
+		}
+	}
+
+	switch {
+	case len(resp.Failed) == 0:
+		resp.Message = "Subtitles generated"
+		h.respondUpload(w, http.StatusOK, resp)
+	case len(resp.Succeeded) == 0:
+		resp.Message = "No subtitle could be generated"
+		h.respondUpload(w, http.StatusInternalServerError, resp)
+	default:
+		resp.Message = "Some subtitles could not be generated"
+		h.respondUpload(w, http.StatusMultiStatus, resp)
 	}
+}
 
+// respondUpload responds with the outcome of an upload.
+func (h *Handlers) respondUpload(w http.ResponseWriter, statusCode int, resp uploadResponse) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("Could not encode response", slog.String("error", err.Error()))
+	}
 }
 
-type listSubtitlesResponse struct {
-	Subtitles []string `json:"subtitles"`
+// startJob creates a job for the inputs and generates their subtitles in background.
+// When publications are given, the subtitle of each input is published once done.
+// While the storage is degraded, the job waits in the queue, when configured.
+func (h *Handlers) startJob(inputs []*subtitles.Input, publications []*jobs.Publication, callbackURL string) (jobs.Job, error) {
+	degradedErr := h.monitor.Degraded()
+	if degradedErr != nil && !h.queueable(inputs) {
+		return jobs.Job{}, fmt.Errorf("%w: %w", errStorageDegraded, degradedErr)
+	}
+
+	fileNames := make([]string, 0, len(inputs))
+
+	// The inputs of a job are all submitted by the same caller.
+	var jobOwner string
+	if len(inputs) > 0 {
+		jobOwner = inputs[0].Owner
+	}
+
+	// The inputs are usually gone once the request finishes,
+	// so they are copied to the tmp directory before processing in background.
+	for _, in := range inputs {
+		in.Tenant = in.Owner // The tenant is the caller, whose plan applies, never a form value.
+
+		if err := h.subtitler.Prepare(in); err != nil {
+			return jobs.Job{}, fmt.Errorf("could not store input file: %w", err)
+		}
+		fileNames = append(fileNames, in.FileName)
+	}
+
+	job, err := h.jobs.Create(jobs.TypeTranscription, jobOwner, fileNames)
+	if err != nil {
+		return jobs.Job{}, fmt.Errorf("could not create job: %w", err)
+	}
+
+	if degradedErr == nil {
+		h.runJob(job, inputs, publications, callbackURL)
+		return job, nil
+	}
+
+	if err := h.enqueueJob(job, inputs, publications, callbackURL); err != nil {
+		for i := range inputs {
+			if err := h.jobs.SetFileState(job.ID, i, jobs.StateFailed, 0, err); err != nil {
+				h.logger.Error("Could not update job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+			}
+		}
+		return jobs.Job{}, err
+	}
+	return job, nil
 }
 
-func (h *Handlers) listSubtitles(w http.ResponseWriter, r *http.Request) {
-	var listResp listSubtitlesResponse
+// runJob generates the subtitles of the prepared inputs of the job in background.
+func (h *Handlers) runJob(job jobs.Job, inputs []*subtitles.Input, publications []*jobs.Publication, callbackURL string) {
+	// The files of a job all belong to the same owner.
+	jobOwner := inputs[0].Owner
 
-	if err := filepath.WalkDir(subtitlesDir, func(filePath string, file os.DirEntry, err error) error {
-		if err != nil {
-			return fmt.Errorf("could not walk in the directory: %w", err)
+	plan, hasPlan := h.plans.PlanOf(jobOwner)
+
+	deferral := newJobDeferral(h.inflight, len(inputs))
+
+	for i, in := range inputs {
+		i, in := i, in
+
+		if hasPlan {
+			in.Priority = plan.CapPriority(in.Priority)
 		}
 
-		if file.IsDir() {
-			return nil
+		var publication *jobs.Publication
+		if i < len(publications) && publications[i] != nil {
+			publication = publications[i]
+
+			if err := h.jobs.SetPublication(job.ID, i, *publication); err != nil {
+				h.logger.Error("Could not update job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+			}
 		}
 
-		if filepath.Ext(file.Name()) != ".srt" {
-			return nil
+		in.Notify = func(e subtitles.Event) {
+			var held bool
+
+			deferral.update(i, e.Stage)
+
+			if e.Stage == subtitles.StageScheduled {
+				if err := h.jobs.SetFileSchedule(job.ID, i, e.ScheduledAt); err != nil {
+					h.logger.Error("Could not update job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+				}
+			}
+
+			if e.Stage == subtitles.StageDone {
+				if err := h.jobs.SetFileSubtitle(job.ID, i, e.Subtitle, storedProject(in.Project)); err != nil {
+					h.logger.Error("Could not update job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+				}
+
+				if err := h.storage.Annotate(in.Owner, in.OutputLanguage(), in.Project, e.Subtitle, store.Annotation{
+					OriginalName: in.FileName,
+					Duration:     e.Duration,
+					JobID:        job.ID,
+					Analytics:    e.Analytics,
+				}); err != nil {
+					h.logger.Error("Could not annotate subtitle", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+				}
+
+				if err := h.quota.Record(in.Owner, e.Usage); err != nil {
+					h.logger.Error("Could not record usage", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+				}
+
+				held = h.holdForReview(job.ID, i, in, e)
+			}
+
+			// Failed files can have artifacts too, e.g. the trace of their provider calls.
+			if len(e.Artifacts) > 0 {
+				if err := h.jobs.SetFileOutputs(job.ID, i, e.Artifacts); err != nil {
+					h.logger.Error("Could not update job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+				}
+			}
+
+			if err := h.jobs.SetFileState(job.ID, i, jobState(e.Stage), e.Progress, e.Err); err != nil {
+				h.logger.Error("Could not update job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+			}
+
+			if publication == nil {
+				return
+			}
+
+			switch e.Stage {
+			case subtitles.StageDone:
+				// Held subtitles are published once approved.
+				if held {
+					return
+				}
+				h.background(func(ctx context.Context) {
+					h.publishJobFile(ctx, job.ID, i, in.Owner, e.Subtitle, in.OutputLanguage(), in.Project, *publication)
+				})
+			case subtitles.StageFailed:
+				failed := *publication
+				failed.State, failed.Error = jobs.PublishFailed, "subtitle generation failed"
+
+				if err := h.jobs.SetPublication(job.ID, i, failed); err != nil {
+					h.logger.Error("Could not update job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+				}
+			}
 		}
+	}
 
-		name := filepath.Base(filePath)
+	h.running.add(jobOwner, 1)
 
-		listResp.Subtitles = append(listResp.Subtitles, name)
+	h.background(func(ctx context.Context) {
+		defer h.running.add(jobOwner, -1)
 
-		return nil
-	}); err != nil {
-		h.e(w, "Failed to compile zip file", err, http.StatusInternalServerError)
+		results, err := h.subtitler.GenerateFromAudioData(ctx, inputs)
+
+		if callbackURL != "" {
+			h.notifyCallback(ctx, job.ID, callbackURL)
+		}
+		h.notifyCompletion(job.ID)
+
+		if err == nil {
+			return
+		}
+
+		// The job records the state of each file, so only the failures are logged.
+		for _, res := range results.Failed() {
+			h.logger.Error("Failed to generate subtitle",
+				slog.String("job_id", job.ID),
+				slog.String("file", res.FileName),
+				slog.String("error", res.Err.Error()),
+			)
+		}
+	})
+}
+
+type listSubtitlesResponse struct {
+	Subtitles []string           `json:"subtitles"`
+	Items     []subtitleMetadata `json:"items"`
+}
+
+type subtitleMetadata struct {
+	Name         string    `json:"name"`
+	Project      string    `json:"project"`
+	Language     string    `json:"language"`
+	Format       string    `json:"format"`
+	Size         int64     `json:"size"`
+	OriginalName string    `json:"original_name,omitempty"`
+	Duration     float64   `json:"duration,omitempty"` // Seconds.
+	Cues         int       `json:"cues,omitempty"`
+	JobID        string    `json:"job_id,omitempty"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	Analytics *analytics.Analytics `json:"analytics,omitempty"`
+}
+
+func newSubtitleMetadata(sub store.Subtitle) subtitleMetadata {
+	return subtitleMetadata{
+		Name:         sub.Name,
+		Project:      sub.Project,
+		Language:     sub.Language,
+		Format:       sub.Format,
+		Size:         sub.Size,
+		OriginalName: sub.OriginalName,
+		Duration:     sub.Duration.Seconds(),
+		Cues:         sub.Cues,
+		JobID:        sub.JobID,
+		Status:       sub.Status,
+		CreatedAt:    sub.CreatedAt,
+		Analytics:    sub.Analytics,
+	}
+}
+
+func (h *Handlers) listSubtitles(w http.ResponseWriter, r *http.Request) {
+	lang, project := r.URL.Query().Get("lang"), r.URL.Query().Get("project")
+
+	subs, err := h.storage.Subtitles(owner(r))
+	if err != nil {
+		h.e(w, "Failed to list subtitles", err, http.StatusInternalServerError)
+		return
+	}
+
+	listResp := listSubtitlesResponse{Items: []subtitleMetadata{}}
+
+	for _, sub := range subs {
+		if !subtitles.IsSubtitle(sub.Name) {
+			continue
+		}
+
+		if lang != "" && sub.Language != lang || project != "" && sub.Project != project {
+			continue
+		}
+
+		listResp.Subtitles = append(listResp.Subtitles, sub.Name)
+		listResp.Items = append(listResp.Items, newSubtitleMetadata(sub))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -121,92 +1005,255 @@ func (h *Handlers) listSubtitles(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// findSubtitle returns the subtitle of the caller with the given name. The lang and project
+// form values, e.g. query parameters, tell apart the subtitles of the same name.
+func (h *Handlers) findSubtitle(r *http.Request, name string) (storage.Object, error) {
+	return h.storage.Find(owner(r), name, r.FormValue("lang"), r.FormValue("project"))
+}
+
+// findStored returns the subtitle of the owner stored with the given name, language and project,
+// as written by a job, where an empty language or project is the default one.
+func (h *Handlers) findStored(owner, name, language, project string) (storage.Object, error) {
+	if language == "" {
+		language = storage.UndefinedLanguage
+	}
+	return h.storage.Find(owner, name, language, storedProject(project))
+}
+
+// storedProject returns the project subtitles of the given project are stored in.
+func storedProject(project string) string {
+	if project == "" {
+		return storage.DefaultProject
+	}
+	return project
+}
+
+// findAlongside returns the named file stored alongside the subtitle, e.g. one of its artifacts.
+func (h *Handlers) findAlongside(sub storage.Object, name string) (storage.Object, error) {
+	return h.storage.Find(sub.Owner, name, sub.Language, sub.Project)
+}
+
+// subtitleError responds with the error of findSubtitle: 404 when no subtitle has the name,
+// 409 when several do, 500 otherwise.
+func (h *Handlers) subtitleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		h.e(w, "Subtitle not found", err, http.StatusNotFound)
+	case errors.Is(err, storage.ErrAmbiguous):
+		h.e(w, "Several subtitles have this name, pass their project or lang", err, http.StatusConflict)
+	default:
+		h.e(w, "Failed to find subtitle", err, http.StatusInternalServerError)
+	}
+}
+
 func (h *Handlers) subtitleFile(w http.ResponseWriter, r *http.Request) {
 	subName := chi.URLParam(r, "name")
 
-	if err := filepath.WalkDir(subtitlesDir, func(filePath string, file os.DirEntry, err error) error {
-		if err != nil {
-			return fmt.Errorf("could not walk in the directory: %w", err)
-		}
+	obj, err := h.findSubtitle(r, subName)
+	if err != nil {
+		h.subtitleError(w, err)
+		return
+	}
+
+	contentType := "application/octet-stream"
+	if format, ok := subtitles.FormatOf(obj.Name); ok {
+		contentType = format.ContentType()
+	}
 
-		if file.Name() == subName {
-			w.Header().Set("Content-Type", "application/x-subrip")
-			w.Header().Set("Content-Disposition", "attachment; filename="+subName)
-			http.ServeFile(w, r, filePath)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename="+subName)
+	h.serveObject(w, r, obj)
+}
+
+// serveObject serves the stored file, with range requests, e.g. to seek in a kept video.
+func (h *Handlers) serveObject(w http.ResponseWriter, r *http.Request, obj storage.Object) {
+	f, err := h.storage.Open(obj)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			h.e(w, "File not found", err, http.StatusNotFound)
+			return
 		}
-		return nil
-	}); err != nil {
-		h.e(w, "Failed to compile zip file", err, http.StatusInternalServerError)
+		h.e(w, "Failed to open file", err, http.StatusInternalServerError)
+		return
 	}
+	defer f.Close()
+
+	// The catalog records when the files were stored, not last edited, so no Last-Modified is sent.
+	http.ServeContent(w, r, obj.Name, time.Time{}, f)
 }
 
 func (h *Handlers) deleteSubtitle(w http.ResponseWriter, r *http.Request) {
 	subName := chi.URLParam(r, "name")
 
-	if err := filepath.WalkDir(subtitlesDir, func(filePath string, file os.DirEntry, err error) error {
-		if err != nil {
-			return fmt.Errorf("could not walk in the directory: %w", err)
-		}
+	obj, err := h.findSubtitle(r, subName)
+	if err != nil {
+		h.subtitleError(w, err)
+		return
+	}
 
-		if file.Name() == subName {
-			if err := os.Remove(filePath); err != nil {
-				return fmt.Errorf("could not remove subtitle: %w", err)
-			}
-		}
-		return nil
-	}); err != nil {
-		h.e(w, "Failed to compile zip file", err, http.StatusInternalServerError)
+	if err := h.retention.CanModify(obj.Project); err != nil {
+		h.e(w, "Subtitle is under legal hold", err, http.StatusLocked)
+		return
 	}
+
+	if err := h.storage.Remove(obj); err != nil {
+		h.e(w, "Failed to delete subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "subtitle.delete",
+		Subject: subName,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{"project": obj.Project},
+	})
 }
 
 func (h *Handlers) subtitlesZip(w http.ResponseWriter, r *http.Request) {
-	buffer := bytes.NewBuffer(nil)
+	lang, project, jobID := r.URL.Query().Get("lang"), r.URL.Query().Get("project"), r.URL.Query().Get("job")
 
-	zipWritter := zip.NewWriter(buffer)
-	defer zipWritter.Close()
+	objects, err := h.listSubtitleFiles(owner(r), lang, project)
+	if err != nil {
+		h.e(w, "Failed to list subtitles", err, http.StatusInternalServerError)
+		return
+	}
 
-	if err := filepath.WalkDir(subtitlesDir, func(filePath string, file os.DirEntry, err error) error {
+	if jobID != "" {
+		job, err := h.ownedJob(r, jobID)
 		if err != nil {
-			return fmt.Errorf("could not walk in the directory: %w", err)
+			if errors.Is(err, jobs.ErrNotFound) {
+				h.e(w, "Job not found", err, http.StatusNotFound)
+				return
+			}
+			h.e(w, "Failed to get job", err, http.StatusInternalServerError)
+			return
 		}
+		objects = jobSubtitleFiles(job, objects)
+	}
 
-		if file.IsDir() {
-			return nil
-		}
+	data, fingerprint, err := h.zipCache.get(owner(r)+"|"+lang+"|"+project+"|"+jobID, objects, h.storage.Open)
+	if err != nil {
+		h.e(w, "Failed to compile zip file", err, http.StatusInternalServerError)
+		return
+	}
+
+	etag := `"` + fingerprint + `"`
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=legendas.zip")
+
+	w.Write(data)
+}
 
-		if filepath.Ext(file.Name()) != ".srt" {
-			return nil
+// listSubtitleFiles returns the stored subtitles of the owner in any output format,
+// optionally filtered by language and project.
+func (h *Handlers) listSubtitleFiles(owner, lang, project string) ([]storage.Object, error) {
+	objects, err := h.storage.List()
+	if err != nil {
+		return nil, fmt.Errorf("could not list stored files: %w", err)
+	}
+
+	var subs []storage.Object
+	for _, obj := range objects {
+		if !subtitles.IsSubtitle(obj.Name) || obj.Owner != owner {
+			continue
 		}
 
-		name := filepath.Base(filePath)
+		if lang != "" && obj.Language != lang {
+			continue
+		}
 
-		zipEntry, err := zipWritter.Create(name)
-		if err != nil {
-			return fmt.Errorf("could not create zip entry: %w", err)
+		if project != "" && obj.Project != project {
+			continue
 		}
+		subs = append(subs, obj)
+	}
+	return subs, nil
+}
 
-		data, err := os.Open(filePath)
-		if err != nil {
-			return fmt.Errorf("could not open file: %w", err)
+// jobSubtitleFiles returns the objects produced by the files of the job, e.g. its subtitles in other formats.
+func jobSubtitleFiles(job jobs.Job, objects []storage.Object) []storage.Object {
+	names := map[string]bool{}
+	for _, f := range job.Files {
+		if f.Subtitle != "" {
+			names[f.Subtitle] = true
+		}
+		for _, output := range f.Outputs {
+			names[output] = true
 		}
+	}
 
-		if _, err := io.Copy(zipEntry, data); err != nil {
-			return fmt.Errorf("could not copy data: %w", err)
+	var subs []storage.Object
+	for _, obj := range objects {
+		if names[obj.Name] {
+			subs = append(subs, obj)
 		}
-		return nil
-	}); err != nil {
-		h.e(w, "Failed to compile zip file", err, http.StatusInternalServerError)
 	}
+	return subs
+}
 
-	if err := zipWritter.Close(); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// addZipEntry adds the stored file to the archive under its project, where its name is unique,
+// e.g. default/interview.srt.
+func addZipEntry(zipWritter *zip.Writer, obj storage.Object, open objectOpener) error {
+	zipEntry, err := zipWritter.Create(path.Join(obj.Project, obj.Name))
+	if err != nil {
+		return fmt.Errorf("could not create zip entry: %w", err)
 	}
 
-	w.Header().Set("Content-Type", "application/zip")
-	w.Header().Set("Content-Disposition", "attachment; filename=legendas.zip")
+	data, err := open(obj)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	if _, err := io.Copy(zipEntry, data); err != nil {
+		return fmt.Errorf("could not copy data: %w", err)
+	}
+	return nil
+}
+
+// maxPromptLength bounds the length in characters of a Whisper prompt,
+// which only considers its last 224 tokens anyway.
+const maxPromptLength int = 1000
 
-	w.Write(buffer.Bytes())
+// validatePrompt checks the length of a Whisper prompt.
+func validatePrompt(prompt string) error {
+	if n := utf8.RuneCountInString(prompt); n > maxPromptLength {
+		return fmt.Errorf("prompt of %d characters exceeds %d", n, maxPromptLength)
+	}
+	return nil
+}
+
+// formBool parses an optional boolean form field.
+func formBool(r *http.Request, key string) (bool, error) {
+	v := r.FormValue(key)
+	if v == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("could not parse %s: %w", key, err)
+	}
+	return b, nil
+}
+
+// link returns the public URL of the path on the server, used for links sent to users.
+func (h *Handlers) link(path string) string {
+	return h.publicURL + path
+}
+
+func (h *Handlers) record(e audit.Entry) {
+	if err := h.auditor.Record(e); err != nil {
+		h.logger.Error("Could not record audit entry", slog.String("action", e.Action), slog.String("error", err.Error()))
+	}
 }
 
 func (h *Handlers) e(w http.ResponseWriter, message string, err error, statusCode int) {
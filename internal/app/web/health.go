@@ -0,0 +1,33 @@
+package web
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// healthz reports that the server is up, for liveness probes.
+func (h *Handlers) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"ok"}` + "\n"))
+}
+
+// readyz reports whether the dependencies needed to serve requests are available, for readiness probes.
+// It responds with 503 when one of the checks fails.
+func (h *Handlers) readyz(w http.ResponseWriter, r *http.Request) {
+	report := h.readiness.Ready(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !report.Ready {
+		h.logger.Warn("Not ready", slog.Any("checks", report.Checks))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else if report.Degraded {
+		h.logger.Warn("Degraded", slog.Any("checks", report.Checks))
+	}
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
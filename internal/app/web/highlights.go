@@ -0,0 +1,97 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/alesr/videoscriber/internal/pkg/highlights"
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultHighlights is the number of highlights suggested when the count is not given.
+const defaultHighlights int = 5
+
+type highlight struct {
+	Start   float64  `json:"start"` // Seconds.
+	End     float64  `json:"end"`   // Seconds.
+	Name    string   `json:"name"`
+	Text    string   `json:"text"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+type highlightsResponse struct {
+	Highlights []highlight `json:"highlights"`
+}
+
+// subtitleHighlights suggests the segments of a stored subtitle worth turning into clips.
+// The highlights have the shape of clip ranges, so they can be sent as is to /clips.
+func (h *Handlers) subtitleHighlights(w http.ResponseWriter, r *http.Request) {
+	count, err := highlightCount(r.URL.Query().Get("count"))
+	if err != nil {
+		h.e(w, "Invalid count", err, http.StatusBadRequest)
+		return
+	}
+
+	obj, err := h.findSubtitle(r, chi.URLParam(r, "name"))
+	if err != nil {
+		h.subtitleError(w, err)
+		return
+	}
+
+	if !publishable(obj.Name) {
+		h.e(w, "Highlights can only be detected in SRT and VTT subtitles", nil, http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.storage.Read(obj)
+	if err != nil {
+		h.e(w, "Failed to read subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	cues, _, err := parseCues(data)
+	if err != nil {
+		h.e(w, "Failed to parse subtitle", err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	resp := highlightsResponse{Highlights: []highlight{}}
+
+	for i, hl := range highlights.Detect(cues, count) {
+		resp.Highlights = append(resp.Highlights, highlight{
+			Start:   hl.Start.Seconds(),
+			End:     hl.End.Seconds(),
+			Name:    fmt.Sprintf("highlight %d", i+1),
+			Text:    hl.Text,
+			Score:   hl.Score,
+			Reasons: hl.Reasons,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// highlightCount parses the number of highlights to suggest, at most maxClips.
+func highlightCount(s string) (int, error) {
+	if s == "" {
+		return defaultHighlights, nil
+	}
+
+	count, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+
+	if count < 1 || count > maxClips {
+		return 0, fmt.Errorf("count must be between 1 and %d", maxClips)
+	}
+	return count, nil
+}
@@ -0,0 +1,231 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/ingest"
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/alesr/videoscriber/internal/pkg/retention"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+)
+
+// maxTranscribeURLs bounds the number of URLs of a single request.
+const maxTranscribeURLs int = 20
+
+type transcribeURLRequest struct {
+	URLs         []string `json:"urls"`
+	Language     string   `json:"language"`
+	Project      string   `json:"project"`
+	Provider     string   `json:"provider"`
+	Format       string   `json:"format"`
+	Anonymize    bool     `json:"anonymize"`
+	Multilingual bool     `json:"multilingual"`
+	Timeline     bool     `json:"timeline"`
+	Chapters     bool     `json:"chapters"`
+	Profile      string   `json:"profile"`
+	Preprocess   string   `json:"preprocess"`
+	Prompt       string   `json:"prompt"`
+	Task         string   `json:"task"`
+	TranslateTo  []string `json:"translate_to"`
+	KeepVideo    bool     `json:"keep_video"`
+	Debug        bool     `json:"debug"`
+	CallbackURL  string   `json:"callback_url"`
+}
+
+// transcribeURL transcribes media files hosted elsewhere: the server downloads each URL
+// to the tmp directory and runs the same pipeline as uploads.
+func (h *Handlers) transcribeURL(w http.ResponseWriter, r *http.Request) {
+	var req transcribeURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	if len(req.URLs) == 0 {
+		h.e(w, "At least one URL is required", nil, http.StatusBadRequest)
+		return
+	}
+
+	if len(req.URLs) > maxTranscribeURLs {
+		h.e(w, fmt.Sprintf("At most %d URLs can be transcribed at once", maxTranscribeURLs), nil, http.StatusBadRequest)
+		return
+	}
+
+	if req.Project != "" {
+		if err := retention.ValidateProject(req.Project); err != nil {
+			h.e(w, "Invalid project name", err, http.StatusBadRequest)
+			return
+		}
+
+		if !canAccessProject(r, req.Project) {
+			h.e(w, "Access to the project is not allowed", nil, http.StatusForbidden)
+			return
+		}
+	}
+
+	if req.Provider != "" && !h.subtitler.HasProvider(req.Provider) {
+		h.e(w, fmt.Sprintf("Unknown provider %q", req.Provider), nil, http.StatusBadRequest)
+		return
+	}
+
+	format, err := subtitles.ParseFormat(req.Format)
+	if err != nil {
+		h.e(w, "Invalid format", err, http.StatusBadRequest)
+		return
+	}
+
+	profile, err := subtitles.ParseProfile(req.Profile)
+	if err != nil {
+		h.e(w, "Invalid profile", err, http.StatusBadRequest)
+		return
+	}
+
+	if err := validatePrompt(req.Prompt); err != nil {
+		h.e(w, "Invalid prompt", err, http.StatusBadRequest)
+		return
+	}
+
+	preprocess, err := subtitles.ParseFilters(req.Preprocess)
+	if err != nil {
+		h.e(w, "Invalid preprocess value", err, http.StatusBadRequest)
+		return
+	}
+
+	if format == subtitles.FormatJSON && req.Multilingual {
+		h.e(w, "The json format can not be combined with multilingual", nil, http.StatusBadRequest)
+		return
+	}
+
+	task, err := subtitles.ParseTask(req.Task)
+	if err != nil {
+		h.e(w, "Invalid task", err, http.StatusBadRequest)
+		return
+	}
+
+	if task == subtitles.TaskTranslate && req.Multilingual {
+		h.e(w, "Translation can not be combined with multilingual", nil, http.StatusBadRequest)
+		return
+	}
+
+	translations, err := subtitles.ParseLanguages(strings.Join(req.TranslateTo, ","))
+	if err != nil {
+		h.e(w, "Invalid translate_to value", err, http.StatusBadRequest)
+		return
+	}
+
+	language := req.Language
+	if language == "" {
+		language = subtitles.DefaultLanguage
+	}
+
+	if !subtitles.SupportedLanguage(language) {
+		h.e(w, fmt.Sprintf("Unsupported language %q", language), nil, http.StatusBadRequest)
+		return
+	}
+
+	// The same URLs submitted again with the same options are attached to the job of the first submission.
+	encoded, err := json.Marshal(req)
+	if err != nil {
+		h.e(w, "Failed to encode the request", err, http.StatusInternalServerError)
+		return
+	}
+
+	options := subtitles.Input{
+		Language:     language,
+		Project:      req.Project,
+		Owner:        owner(r),
+		Anonymize:    req.Anonymize,
+		Multilingual: req.Multilingual,
+		Timeline:     req.Timeline,
+		Chapters:     req.Chapters,
+		Profile:      profile,
+		Provider:     req.Provider,
+		Format:       format,
+		Preprocess:   preprocess,
+		Prompt:       req.Prompt,
+		Task:         task,
+		Translations: translations,
+		KeepVideo:    req.KeepVideo,
+		Debug:        req.Debug,
+	}
+
+	job, duplicate, err := h.submit(r.Context(), submissionKey(owner(r), nil, []string{string(encoded)}), func() (jobs.Job, error) {
+		return h.startURLJob(r.Context(), req.URLs, options, req.CallbackURL)
+	})
+
+	var failure *fetchFailure
+	if errors.As(err, &failure) {
+		h.fetchError(w, failure.url, failure.err)
+		return
+	}
+
+	if err != nil {
+		if errors.Is(err, ingest.ErrTooLarge) {
+			h.e(w, "The download exceeds the maximum size", err, http.StatusRequestEntityTooLarge)
+			return
+		}
+		h.e(w, "Failed to start job", err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := uploadResponse{Message: "Subtitles generation started", JobID: job.ID}
+	if duplicate {
+		resp.Message, resp.Duplicate = "Attached to the job of the same submission", true
+	}
+	h.respondUpload(w, http.StatusAccepted, resp)
+}
+
+// fetchFailure is the error of a URL that could not be downloaded.
+type fetchFailure struct {
+	url string
+	err error
+}
+
+func (f *fetchFailure) Error() string {
+	return fmt.Sprintf("could not fetch %q: %s", f.url, f.err)
+}
+
+func (f *fetchFailure) Unwrap() error {
+	return f.err
+}
+
+// startURLJob downloads the URLs and starts their job, with the options of the given input.
+func (h *Handlers) startURLJob(ctx context.Context, urls []string, options subtitles.Input, callbackURL string) (jobs.Job, error) {
+	inputs := make([]*subtitles.Input, 0, len(urls))
+
+	// All downloads are started before any is read, so that unreachable URLs, unsupported
+	// content types and announced sizes above the limit fail the request before downloading anything.
+	for _, videoURL := range urls {
+		download, err := h.ingest.Fetch(ctx, videoURL)
+		if err != nil {
+			return jobs.Job{}, &fetchFailure{url: videoURL, err: err}
+		}
+		defer download.Body.Close()
+
+		in := options
+		in.Data, in.FileName = download.Body, download.Name
+		inputs = append(inputs, &in)
+	}
+	return h.startJob(inputs, nil, callbackURL)
+}
+
+func (h *Handlers) fetchError(w http.ResponseWriter, videoURL string, err error) {
+	h.logger.Error("Could not fetch video", slog.String("url", videoURL), slog.String("error", err.Error()))
+
+	switch {
+	case errors.Is(err, ingest.ErrInvalidURL), errors.Is(err, ingest.ErrForbiddenAddress):
+		h.e(w, fmt.Sprintf("Invalid URL %q", videoURL), err, http.StatusBadRequest)
+	case errors.Is(err, ingest.ErrTooLarge):
+		h.e(w, fmt.Sprintf("The file at %q exceeds the maximum size", videoURL), err, http.StatusRequestEntityTooLarge)
+	case errors.Is(err, ingest.ErrUnsupportedType):
+		h.e(w, fmt.Sprintf("The file at %q is not an audio or video file", videoURL), err, http.StatusUnsupportedMediaType)
+	default:
+		h.e(w, fmt.Sprintf("Failed to download %q", videoURL), err, http.StatusBadGateway)
+	}
+}
@@ -0,0 +1,16 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// janitorStats reports the disk space reclaimed by the janitor since the server started.
+func (h *Handlers) janitorStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(h.sweeper.Stats()); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,181 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+	"github.com/go-chi/chi/v5"
+)
+
+// ownedJob returns the job when the caller created it. The jobs of the others are not found,
+// so that their progress and errors aren't disclosed.
+func (h *Handlers) ownedJob(r *http.Request, id string) (jobs.Job, error) {
+	job, err := h.jobs.Get(id)
+	if err != nil {
+		return jobs.Job{}, err
+	}
+
+	if job.Owner != owner(r) {
+		return jobs.Job{}, fmt.Errorf("%w: %s", jobs.ErrNotFound, id)
+	}
+	return job, nil
+}
+
+func (h *Handlers) getJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.ownedJob(r, chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, jobs.ErrNotFound) {
+			h.e(w, "Job not found", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to get job", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handlers) jobEvents(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.e(w, "Streaming not supported", nil, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.ownedJob(r, id); err != nil {
+		if errors.Is(err, jobs.ErrNotFound) {
+			h.e(w, "Job not found", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to get job", err, http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe, err := h.jobs.Subscribe(id)
+	if err != nil {
+		if errors.Is(err, jobs.ErrNotFound) {
+			h.e(w, "Job not found", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to subscribe to job", err, http.StatusInternalServerError)
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	// The current state comes first so clients don't miss what happened before they connected.
+	finished, err := h.sendJobSnapshot(w, id)
+	if err != nil {
+		h.logger.Error("Could not send job event", slog.String("job_id", id), slog.String("error", err.Error()))
+		return
+	}
+	flusher.Flush()
+
+	if finished {
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				// The job finished: send its final state.
+				if _, err := h.sendJobSnapshot(w, id); err != nil {
+					h.logger.Error("Could not send job event", slog.String("job_id", id), slog.String("error", err.Error()))
+				}
+				flusher.Flush()
+				return
+			}
+
+			if err := writeSSE(w, "progress", e); err != nil {
+				h.logger.Error("Could not send job event", slog.String("job_id", id), slog.String("error", err.Error()))
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// sendJobSnapshot writes the current state of the job and reports whether it finished.
+func (h *Handlers) sendJobSnapshot(w http.ResponseWriter, id string) (bool, error) {
+	job, err := h.jobs.Get(id)
+	if err != nil {
+		return false, fmt.Errorf("could not get job: %w", err)
+	}
+	return job.Finished(), writeSSE(w, "job", job)
+}
+
+// writeSSE writes a server-sent event with a JSON payload.
+func writeSSE(w io.Writer, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("could not marshal event: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return fmt.Errorf("could not write event: %w", err)
+	}
+	return nil
+}
+
+// jobState maps a processing stage of the subtitler to the state of a job file.
+func jobState(stage subtitles.Stage) jobs.State {
+	switch stage {
+	case subtitles.StageExtracting:
+		return jobs.StateExtracting
+	case subtitles.StageScheduled:
+		return jobs.StateScheduled
+	case subtitles.StageTranscribing:
+		return jobs.StateTranscribing
+	case subtitles.StageDone:
+		return jobs.StateDone
+	case subtitles.StageFailed:
+		return jobs.StateFailed
+	default:
+		return jobs.StateQueued
+	}
+}
+
+// awaitJob waits for the job to finish, calling onChange, when set, as the state of the job changes.
+func (h *Handlers) awaitJob(id string, onChange func(state jobs.State)) (jobs.Job, error) {
+	job, err := h.jobs.Get(id)
+	if err != nil {
+		return jobs.Job{}, err
+	}
+
+	events, unsubscribe, err := h.jobs.Subscribe(id)
+	if err != nil {
+		return jobs.Job{}, err
+	}
+	defer unsubscribe()
+
+	state := job.State
+	for e := range events {
+		if e.JobState != state {
+			state = e.JobState
+
+			if onChange != nil {
+				onChange(state)
+			}
+		}
+	}
+	return h.jobs.Get(id)
+}
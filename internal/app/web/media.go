@@ -0,0 +1,99 @@
+package web
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+	"github.com/go-chi/chi/v5"
+)
+
+// subtitleAudio serves the audio the subtitle was transcribed from, as extracted and preprocessed,
+// e.g. to debug a bad transcription. It is only kept unless the server retains no media.
+func (h *Handlers) subtitleAudio(w http.ResponseWriter, r *http.Request) {
+	subName := chi.URLParam(r, "name")
+
+	sub, err := h.findSubtitle(r, subName)
+	if err != nil {
+		h.subtitleError(w, err)
+		return
+	}
+
+	obj, err := h.keptMedia(sub, func(p subtitles.Pipeline) string { return p.Source })
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.e(w, "The audio of the subtitle was not kept", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to find audio", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Header().Set("Content-Disposition", "attachment; filename="+obj.Name)
+	h.serveObject(w, r, obj)
+}
+
+// subtitleVideo serves the video the subtitle was transcribed from, e.g. to play it in the editor.
+// It is only kept when the server retains all media or the upload asked to keep it.
+func (h *Handlers) subtitleVideo(w http.ResponseWriter, r *http.Request) {
+	subName := chi.URLParam(r, "name")
+
+	sub, err := h.findSubtitle(r, subName)
+	if err != nil {
+		h.subtitleError(w, err)
+		return
+	}
+
+	obj, err := h.keptVideo(sub)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.e(w, "The video of the subtitle was not kept", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to find video", err, http.StatusInternalServerError)
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(obj.Name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "inline; filename="+obj.Name)
+	h.serveObject(w, r, obj)
+}
+
+// keptVideo returns the video stored alongside the subtitle.
+func (h *Handlers) keptVideo(sub storage.Object) (storage.Object, error) {
+	return h.keptMedia(sub, func(p subtitles.Pipeline) string { return p.Video })
+}
+
+// keptMedia returns the media stored alongside the subtitle, named in its pipeline by the given field.
+// It returns storage.ErrNotFound when the subtitle has no pipeline, or the media wasn't kept or expired.
+func (h *Handlers) keptMedia(sub storage.Object, field func(p subtitles.Pipeline) string) (storage.Object, error) {
+	pipelineObj, err := h.findAlongside(sub, subtitles.PipelineName(sub.Name))
+	if err != nil {
+		return storage.Object{}, err
+	}
+
+	data, err := h.storage.Read(pipelineObj)
+	if err != nil {
+		return storage.Object{}, err
+	}
+
+	pipeline, err := subtitles.ParsePipeline(data)
+	if err != nil {
+		return storage.Object{}, err
+	}
+
+	name := field(pipeline)
+	if name == "" {
+		return storage.Object{}, storage.ErrNotFound
+	}
+	return h.findAlongside(sub, name)
+}
@@ -0,0 +1,144 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// listModels lists the model files installed for the local backends, with their disk usage.
+func (h *Handlers) listModels(w http.ResponseWriter, r *http.Request) {
+	usage, err := h.models.List()
+	if err != nil {
+		h.e(w, "Failed to list models", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(usage); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+type downloadModelRequest struct {
+	Backend string `json:"backend"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256"`
+	Pin     bool   `json:"pin"`
+}
+
+// downloadModel downloads the named model from its URL and verifies its checksum, optionally pinning it.
+// The response is sent once the model is installed.
+func (h *Handlers) downloadModel(w http.ResponseWriter, r *http.Request) {
+	var req downloadModelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	model, err := h.models.Download(r.Context(), models.Model{
+		Name:    chi.URLParam(r, "name"),
+		Backend: req.Backend,
+		URL:     req.URL,
+		SHA256:  req.SHA256,
+	})
+	if err != nil {
+		h.modelError(w, "Failed to download model", err)
+		return
+	}
+
+	if req.Pin && !model.Pinned {
+		if model, err = h.models.Pin(model.Name, true); err != nil {
+			h.modelError(w, "Failed to pin model", err)
+			return
+		}
+	}
+
+	h.record(audit.Entry{
+		Action:  "model.download",
+		Subject: model.Name,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{"backend": model.Backend, "url": model.URL, "sha256": model.SHA256},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(model); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handlers) pinModel(w http.ResponseWriter, r *http.Request) {
+	h.setModelPinned(w, r, true)
+}
+
+func (h *Handlers) unpinModel(w http.ResponseWriter, r *http.Request) {
+	h.setModelPinned(w, r, false)
+}
+
+func (h *Handlers) setModelPinned(w http.ResponseWriter, r *http.Request, pinned bool) {
+	model, err := h.models.Pin(chi.URLParam(r, "name"), pinned)
+	if err != nil {
+		h.modelError(w, "Failed to pin model", err)
+		return
+	}
+
+	action := "model.pin"
+	if !pinned {
+		action = "model.unpin"
+	}
+
+	h.record(audit.Entry{
+		Action:  action,
+		Subject: model.Name,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(model); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handlers) deleteModel(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	if err := h.models.Remove(name); err != nil {
+		h.modelError(w, "Failed to remove model", err)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "model.delete",
+		Subject: name,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) modelError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		h.e(w, "Model not found", err, http.StatusNotFound)
+	case errors.Is(err, models.ErrInvalidModel):
+		h.e(w, err.Error(), err, http.StatusBadRequest)
+	case errors.Is(err, models.ErrPinned), errors.Is(err, models.ErrDownloading):
+		h.e(w, err.Error(), err, http.StatusConflict)
+	case errors.Is(err, models.ErrChecksumMismatch):
+		h.e(w, err.Error(), err, http.StatusBadGateway)
+	default:
+		h.e(w, message, err, http.StatusInternalServerError)
+	}
+}
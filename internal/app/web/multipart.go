@@ -0,0 +1,178 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/ingest"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+)
+
+const (
+	// maxFormValuesSize bounds the total size of the non-file fields of a multipart request.
+	maxFormValuesSize int64 = 10 << 20 // 10MB
+
+	// maxFormMemory is the part of multipart requests parsed with ParseMultipartForm kept in memory,
+	// the rest is stored in temporary files.
+	maxFormMemory int64 = 32 << 20 // 32MB
+)
+
+var (
+	errFileTooLarge     = errors.New("file exceeds the maximum upload size")
+	errUnsupportedMedia = errors.New("unsupported media file")
+)
+
+// mediaExtensions are the extensions of the audio and video files that can be uploaded.
+var mediaExtensions = map[string]bool{
+	".3gp": true, ".avi": true, ".flv": true, ".m4v": true, ".mkv": true, ".mov": true, ".mp4": true,
+	".mpeg": true, ".mpg": true, ".mts": true, ".ts": true, ".webm": true, ".wmv": true,
+	".aac": true, ".flac": true, ".m4a": true, ".mp3": true, ".oga": true, ".ogg": true, ".opus": true, ".wav": true,
+}
+
+// validateMedia checks that the uploaded file is an audio or video file from its name and content type.
+func validateMedia(fileName, contentType string) error {
+	if ext := strings.ToLower(filepath.Ext(fileName)); !mediaExtensions[ext] {
+		return fmt.Errorf("%w: extension %q of %q", errUnsupportedMedia, ext, fileName)
+	}
+
+	if !ingest.IsMedia(contentType) {
+		return fmt.Errorf("%w: content type %q of %q", errUnsupportedMedia, contentType, fileName)
+	}
+	return nil
+}
+
+// parseMultipartForm parses a multipart request of at most the maximum upload size
+// and validates its media file, if any.
+func (h *Handlers) parseMultipartForm(w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+
+	if err := r.ParseMultipartForm(maxFormMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return fmt.Errorf("%w: %w", errFileTooLarge, err)
+		}
+		return err
+	}
+
+	for _, header := range r.MultipartForm.File["file"] {
+		if err := validateMedia(header.Filename, header.Header.Get("Content-Type")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// multipartError responds with the error of a multipart request: 413 for files above the
+// maximum upload size, 415 for files that are not audio or video, 400 otherwise.
+func (h *Handlers) multipartError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errFileTooLarge):
+		h.e(w, fmt.Sprintf("Files can be at most %d bytes", h.maxUploadSize), err, http.StatusRequestEntityTooLarge)
+	case errors.Is(err, errUnsupportedMedia):
+		h.e(w, "Only audio and video files are supported", err, http.StatusUnsupportedMediaType)
+	default:
+		h.e(w, "Failed to parse the request", err, http.StatusBadRequest)
+	}
+}
+
+// limitedFile fails reads once more than the remaining bytes of the upload were read.
+type limitedFile struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (f *limitedFile) Read(p []byte) (int, error) {
+	if int64(len(p)) > f.remaining+1 {
+		p = p[:f.remaining+1]
+	}
+
+	n, err := f.r.Read(p)
+	f.remaining -= int64(n)
+
+	if f.remaining < 0 {
+		return n, errFileTooLarge
+	}
+	return n, err
+}
+
+// streamMultipart reads a multipart request part by part, streaming each file of the "file" field
+// straight into its copy in the tmp directory instead of buffering the whole request first.
+// The other fields are stored in r.Form, so that they are read with r.FormValue as usual.
+// As with parseMultipartForm, the files of the request are at most the maximum upload size together.
+// It returns the prepared inputs of the files, with only their file name set, even on error,
+// so that the caller can discard them.
+func (h *Handlers) streamMultipart(r *http.Request) ([]*subtitles.Input, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("could not read multipart request: %w", err)
+	}
+
+	var (
+		inputs    []*subtitles.Input
+		values    = make(url.Values)
+		remaining = maxFormValuesSize
+		files     = h.maxUploadSize
+	)
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return inputs, fmt.Errorf("could not read part: %w", err)
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, remaining+1))
+			if err != nil {
+				return inputs, fmt.Errorf("could not read field %q: %w", part.FormName(), err)
+			}
+
+			remaining -= int64(len(value))
+			if remaining < 0 {
+				return inputs, errors.New("form fields are too large")
+			}
+
+			values.Add(part.FormName(), string(value))
+			continue
+		}
+
+		// Files of other fields are skipped.
+		if part.FormName() != "file" {
+			continue
+		}
+
+		if err := validateMedia(part.FileName(), part.Header.Get("Content-Type")); err != nil {
+			return inputs, err
+		}
+
+		data := &limitedFile{r: part, remaining: files}
+
+		in := &subtitles.Input{FileName: part.FileName(), Data: data}
+		if err := h.subtitler.Prepare(in); err != nil {
+			return inputs, fmt.Errorf("could not store file %q: %w", in.FileName, err)
+		}
+		inputs = append(inputs, in)
+
+		// Prepare read the whole file, so the next ones get what it left of the budget.
+		files = data.remaining
+	}
+
+	r.PostForm = values
+
+	// As with ParseMultipartForm, the query parameters are also form values, after the body ones.
+	r.Form = make(url.Values, len(values))
+	for key, vs := range values {
+		r.Form[key] = append([]string(nil), vs...)
+	}
+	for key, vs := range r.URL.Query() {
+		r.Form[key] = append(r.Form[key], vs...)
+	}
+	return inputs, nil
+}
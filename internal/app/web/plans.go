@@ -0,0 +1,209 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/plans"
+	"github.com/go-chi/chi/v5"
+)
+
+// runningJobs counts the jobs running for each owner, to enforce the concurrency of their plans.
+type runningJobs struct {
+	mu     sync.Mutex
+	owners map[string]int
+}
+
+func newRunningJobs() *runningJobs {
+	return &runningJobs{owners: make(map[string]int)}
+}
+
+func (j *runningJobs) add(owner string, delta int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.owners[owner] += delta; j.owners[owner] <= 0 {
+		delete(j.owners, owner)
+	}
+}
+
+func (j *runningJobs) count(owner string) int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.owners[owner]
+}
+
+// limitJobs rejects the transcription requests of API keys running as many jobs as their plan allows.
+// Jobs started by concurrent requests are counted once started, so the limit can be exceeded by those.
+func (h *Handlers) limitJobs(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if plan, ok := h.plans.PlanOf(owner(r)); ok && plan.MaxJobs > 0 && h.running.count(owner(r)) >= plan.MaxJobs {
+			h.e(w, "Too many running jobs for the plan", nil, http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handlers) listPlans(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(h.plans.List()); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handlers) getPlan(w http.ResponseWriter, r *http.Request) {
+	plan, err := h.plans.Get(chi.URLParam(r, "name"))
+	if err != nil {
+		if errors.Is(err, plans.ErrNotFound) {
+			h.e(w, "Plan not found", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to get plan", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(plan); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// putPlan creates or replaces a plan. The tenants bound to it get its new limits right away.
+func (h *Handlers) putPlan(w http.ResponseWriter, r *http.Request) {
+	var plan plans.Plan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	// The name in the path wins over the one in the body.
+	plan.Name = chi.URLParam(r, "name")
+
+	if err := h.plans.Put(plan); err != nil {
+		if errors.Is(err, plans.ErrInvalidPlan) {
+			h.e(w, err.Error(), err, http.StatusBadRequest)
+			return
+		}
+		h.e(w, "Failed to save plan", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "plan.put",
+		Subject: plan.Name,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(plan); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handlers) deletePlan(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	if err := h.plans.Delete(name); err != nil {
+		switch {
+		case errors.Is(err, plans.ErrNotFound):
+			h.e(w, "Plan not found", err, http.StatusNotFound)
+		case errors.Is(err, plans.ErrInUse):
+			h.e(w, "Tenants are bound to the plan", err, http.StatusConflict)
+		default:
+			h.e(w, "Failed to delete plan", err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "plan.delete",
+		Subject: name,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listTenants lists the tenants bound to a plan, and the name of their plan.
+func (h *Handlers) listTenants(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(h.plans.Tenants()); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+type assignPlanRequest struct {
+	Plan string `json:"plan"`
+}
+
+// assignPlan binds a tenant, the user of an API key, to a plan.
+func (h *Handlers) assignPlan(w http.ResponseWriter, r *http.Request) {
+	var req assignPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	tenant := chi.URLParam(r, "tenant")
+
+	plan, err := h.plans.Assign(tenant, req.Plan)
+	if err != nil {
+		if errors.Is(err, plans.ErrNotFound) {
+			h.e(w, "Plan not found", err, http.StatusBadRequest)
+			return
+		}
+		h.e(w, "Failed to assign plan", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "tenant.assign",
+		Subject: tenant,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{"plan": plan.Name},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(plan); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// unassignPlan unbinds a tenant from its plan, after which it has the limits configured for the server.
+func (h *Handlers) unassignPlan(w http.ResponseWriter, r *http.Request) {
+	tenant := chi.URLParam(r, "tenant")
+
+	if err := h.plans.Unassign(tenant); err != nil {
+		if errors.Is(err, plans.ErrNotFound) {
+			h.e(w, "The tenant is not bound to a plan", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to unassign plan", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "tenant.unassign",
+		Subject: tenant,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,192 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/alesr/videoscriber/internal/pkg/publish"
+	"github.com/alesr/videoscriber/internal/pkg/retention"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+	"github.com/go-chi/chi/v5"
+)
+
+// publishTimeout bounds the publication of a subtitle once its job file is done.
+const publishTimeout = 5 * time.Minute
+
+func (h *Handlers) setCredentials(w http.ResponseWriter, r *http.Request) {
+	project, platform := chi.URLParam(r, "project"), chi.URLParam(r, "platform")
+
+	if err := retention.ValidateProject(project); err != nil {
+		h.e(w, "Invalid project name", err, http.StatusBadRequest)
+		return
+	}
+
+	p, ok := h.publishers[platform]
+	if !ok {
+		h.e(w, fmt.Sprintf("Unknown platform %q", platform), nil, http.StatusNotFound)
+		return
+	}
+
+	var creds publish.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	if err := p.SetCredentials(project, creds); err != nil {
+		if errors.Is(err, publish.ErrInvalidCredentials) {
+			h.e(w, "Invalid credentials", err, http.StatusBadRequest)
+			return
+		}
+		h.e(w, "Failed to store credentials", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "credentials.update",
+		Subject: project,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{"platform": platform},
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type publishRequest struct {
+	VideoID  string `json:"video_id"`
+	Language string `json:"language"` // Defaults to the language of the subtitle.
+	Name     string `json:"name"`     // Defaults to the name of the subtitle without extension.
+}
+
+type publishResponse struct {
+	Ref string `json:"ref"`
+}
+
+// publishSubtitle uploads a stored subtitle as a caption track of a video
+// on the platform, using the credentials of the subtitle's project.
+func (h *Handlers) publishSubtitle(w http.ResponseWriter, r *http.Request) {
+	subName, platform := chi.URLParam(r, "name"), chi.URLParam(r, "platform")
+
+	if _, ok := h.publishers[platform]; !ok {
+		h.e(w, fmt.Sprintf("Unknown platform %q", platform), nil, http.StatusNotFound)
+		return
+	}
+
+	var req publishRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	if req.VideoID == "" && platform != publish.PlatformWebhook {
+		h.e(w, "video_id is required", nil, http.StatusBadRequest)
+		return
+	}
+
+	obj, err := h.findSubtitle(r, subName)
+	if err != nil {
+		h.subtitleError(w, err)
+		return
+	}
+
+	if !publishable(obj.Name) {
+		h.e(w, "Only SRT and VTT subtitles can be published", nil, http.StatusBadRequest)
+		return
+	}
+
+	ref, err := h.publish(r.Context(), platform, obj, req)
+	if err != nil {
+		if errors.Is(err, publish.ErrNoCredentials) {
+			h.e(w, fmt.Sprintf("No %s credentials configured for the project", platform), err, http.StatusPreconditionFailed)
+			return
+		}
+		h.e(w, "Failed to publish subtitle", err, http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(publishResponse{Ref: ref}); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// publishJobFile publishes the subtitle of a finished job file and records the outcome on the job.
+func (h *Handlers) publishJobFile(ctx context.Context, jobID string, index int, owner, subName, language, project string, p jobs.Publication) {
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	ref, err := func() (string, error) {
+		obj, err := h.findStored(owner, subName, language, project)
+		if err != nil {
+			return "", fmt.Errorf("could not find subtitle: %w", err)
+		}
+		return h.publish(ctx, p.Platform, obj, publishRequest{VideoID: p.VideoID, Language: language})
+	}()
+
+	p.State, p.Ref = jobs.PublishPublished, ref
+	if err != nil {
+		p.State, p.Error = jobs.PublishFailed, err.Error()
+
+		h.logger.Error("Could not publish subtitle",
+			slog.String("job_id", jobID),
+			slog.String("platform", p.Platform),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	if err := h.jobs.SetPublication(jobID, index, p); err != nil {
+		h.logger.Error("Could not update job", slog.String("job_id", jobID), slog.String("error", err.Error()))
+	}
+}
+
+func (h *Handlers) publish(ctx context.Context, platform string, obj storage.Object, req publishRequest) (string, error) {
+	data, err := h.storage.Read(obj)
+	if err != nil {
+		return "", fmt.Errorf("could not read subtitle: %w", err)
+	}
+
+	language := req.Language
+	if language == "" {
+		language = obj.Language
+	}
+
+	name := req.Name
+	if name == "" {
+		name = strings.TrimSuffix(obj.Name, filepath.Ext(obj.Name))
+	}
+
+	ref, err := h.publishers[platform].Publish(ctx, obj.Project, publish.Caption{
+		VideoID:  req.VideoID,
+		Language: language,
+		Name:     name,
+		Data:     data,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	h.record(audit.Entry{
+		Action:  "subtitle.publish",
+		Subject: obj.Name,
+		Details: map[string]string{"platform": platform, "video_id": req.VideoID, "ref": ref},
+	})
+	return ref, nil
+}
+
+// publishable reports whether the stored subtitle is in a format accepted by the platforms.
+func publishable(name string) bool {
+	f, _ := subtitles.FormatOf(name)
+	return (f == subtitles.FormatSRT || f == subtitles.FormatVTT) && subtitles.IsSubtitle(name)
+}
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/alesr/videoscriber/internal/pkg/quota"
+)
+
+type usageResponse struct {
+	Month        string   `json:"month"`
+	UsedMinutes  float64  `json:"used_minutes"`
+	QuotaMinutes *float64 `json:"quota_minutes"` // Null when unlimited.
+	Plan         string   `json:"plan,omitempty"`
+}
+
+// rateLimit limits the requests per minute of each API key. Anonymous requests share one limit.
+func (h *Handlers) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, retryAfter := h.limiter.Allow(owner(r)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			h.e(w, "Too many requests", nil, http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// enforceQuota rejects the transcription requests of API keys that used up their monthly quota.
+func (h *Handlers) enforceQuota(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h.quota.Check(owner(r)); err != nil {
+			if errors.Is(err, quota.ErrExceeded) {
+				h.e(w, "Monthly transcription quota exceeded", err, http.StatusTooManyRequests)
+				return
+			}
+			h.e(w, "Failed to check quota", err, http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// usage reports the transcription minutes used by the caller this month.
+func (h *Handlers) usage(w http.ResponseWriter, r *http.Request) {
+	u, err := h.quota.Usage(owner(r))
+	if err != nil {
+		h.e(w, "Failed to get usage", err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := usageResponse{Month: u.Month, UsedMinutes: u.Used.Minutes()}
+	if plan, ok := h.plans.PlanOf(owner(r)); ok {
+		resp.Plan = plan.Name
+	}
+	if u.Limit > 0 {
+		limit := u.Limit.Minutes()
+		resp.QuotaMinutes = &limit
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
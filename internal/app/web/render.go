@@ -0,0 +1,128 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/styles"
+)
+
+// renderVideo burns a stored subtitle into its video and returns the rendered MP4. The request is multipart:
+// the name of the subtitle as "subtitle", optionally the video as "file", and "style", the name of a styling
+// profile. Without a file, the video kept alongside the subtitle is used, when it was uploaded with keep_video
+// or the server retains all media.
+func (h *Handlers) renderVideo(w http.ResponseWriter, r *http.Request) {
+	if err := h.parseMultipartForm(w, r); err != nil {
+		h.multipartError(w, err)
+		return
+	}
+
+	subName := r.FormValue("subtitle")
+	if subName == "" {
+		h.e(w, "The name of the subtitle is required", nil, http.StatusBadRequest)
+		return
+	}
+
+	subData, err := h.subtitleData(r)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrAmbiguous) {
+			h.subtitleError(w, err)
+			return
+		}
+		h.e(w, "Failed to read the subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	cues, _, err := parseCues(subData)
+	if err != nil {
+		h.e(w, "Failed to parse the subtitle", err, http.StatusBadRequest)
+		return
+	}
+
+	var forceStyle string
+	if name := r.FormValue("style"); name != "" {
+		profile, err := h.styles.Get(name)
+		if err != nil {
+			if errors.Is(err, styles.ErrNotFound) {
+				h.e(w, "Styling profile not found", err, http.StatusBadRequest)
+				return
+			}
+			h.e(w, "Failed to get styling profile", err, http.StatusInternalServerError)
+			return
+		}
+		forceStyle = profile.ForceStyle()
+	}
+
+	var videoPath string
+
+	if video, header, err := r.FormFile("file"); err == nil {
+		defer video.Close()
+
+		if videoPath, err = h.clips.Prepare(header.Filename, video); err != nil {
+			h.e(w, "Failed to store the video", err, http.StatusInternalServerError)
+			return
+		}
+		defer os.Remove(videoPath)
+	} else if !errors.Is(err, http.ErrMissingFile) {
+		h.e(w, "Failed to read the video", err, http.StatusBadRequest)
+		return
+	} else {
+		sub, err := h.findSubtitle(r, subName)
+		if err != nil {
+			h.subtitleError(w, err)
+			return
+		}
+
+		videoObj, err := h.keptVideo(sub)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				h.e(w, "The video of the subtitle was not kept, upload it as file", err, http.StatusConflict)
+				return
+			}
+			h.e(w, "Failed to find the video", err, http.StatusInternalServerError)
+			return
+		}
+
+		video, err := h.storage.Open(videoObj)
+		if err != nil {
+			h.e(w, "Failed to open the video", err, http.StatusInternalServerError)
+			return
+		}
+		defer video.Close()
+
+		// ffmpeg reads the videos stored on disk in place, and a copy of the others, e.g. stored in memory.
+		if f, ok := video.(*os.File); ok {
+			videoPath = f.Name()
+		} else {
+			if videoPath, err = h.clips.Prepare(videoObj.Name, video); err != nil {
+				h.e(w, "Failed to store the video", err, http.StatusInternalServerError)
+				return
+			}
+			defer os.Remove(videoPath)
+		}
+	}
+
+	outputPath, err := h.clips.Burn(r.Context(), videoPath, cues, forceStyle)
+	if err != nil {
+		h.e(w, "Failed to render the video", err, http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(outputPath)
+
+	h.record(audit.Entry{
+		Action:  "subtitle.render",
+		Subject: subName,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{"style": r.FormValue("style")},
+	})
+
+	w.Header().Set("Content-Type", "video/mp4")
+	w.Header().Set("Content-Disposition", "attachment; filename="+strings.TrimSuffix(subName, path.Ext(subName))+".mp4")
+	http.ServeFile(w, r, outputPath)
+}
@@ -0,0 +1,166 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// configurationPinned reprocesses with the provider and model recorded in the pipeline of the subtitle.
+	configurationPinned = "pinned"
+
+	// configurationCurrent reprocesses with the default provider, keeping the recorded options.
+	configurationCurrent = "current"
+)
+
+type reprocessRequest struct {
+	Configuration string `json:"configuration"` // pinned (default) or current.
+}
+
+// reprocessSubtitle starts a job transcribing the stored audio of a subtitle again, either with the
+// pinned configuration recorded in its pipeline or with the current one. The subtitle is kept and
+// the result is stored under a new name, so that both can be compared.
+func (h *Handlers) reprocessSubtitle(w http.ResponseWriter, r *http.Request) {
+	subName := chi.URLParam(r, "name")
+
+	req := reprocessRequest{Configuration: configurationPinned}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.Configuration != configurationPinned && req.Configuration != configurationCurrent {
+		h.e(w, fmt.Sprintf("Unknown configuration %q, expected pinned or current", req.Configuration), nil, http.StatusBadRequest)
+		return
+	}
+
+	obj, err := h.findSubtitle(r, subName)
+	if err != nil {
+		h.subtitleError(w, err)
+		return
+	}
+
+	pipelineObj, err := h.findAlongside(obj, subtitles.PipelineName(obj.Name))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.e(w, "No pipeline recorded for the subtitle", err, http.StatusConflict)
+			return
+		}
+		h.e(w, "Failed to find pipeline", err, http.StatusInternalServerError)
+		return
+	}
+
+	data, err := h.storage.Read(pipelineObj)
+	if err != nil {
+		h.e(w, "Failed to read pipeline", err, http.StatusInternalServerError)
+		return
+	}
+
+	pipeline, err := subtitles.ParsePipeline(data)
+	if err != nil {
+		h.e(w, "Failed to parse pipeline", err, http.StatusInternalServerError)
+		return
+	}
+
+	if pipeline.Source == "" {
+		h.e(w, "The audio of the subtitle was not kept", nil, http.StatusConflict)
+		return
+	}
+
+	sourceObj, err := h.findAlongside(obj, pipeline.Source)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.e(w, "The audio of the subtitle is gone", err, http.StatusConflict)
+			return
+		}
+		h.e(w, "Failed to find audio", err, http.StatusInternalServerError)
+		return
+	}
+
+	in := &subtitles.Input{
+		FileName:       reprocessName(obj.Name, req.Configuration),
+		Language:       pipeline.Language,
+		Project:        obj.Project,
+		Owner:          owner(r),
+		Anonymize:      pipeline.Anonymize,
+		Multilingual:   pipeline.Multilingual,
+		WordTimestamps: pipeline.WordTimestamps,
+		Diarize:        pipeline.Diarize,
+		Prompt:         pipeline.Prompt,
+		Task:           pipeline.Task,
+		Translations:   pipeline.Translations,
+		Timeline:       pipeline.Timeline,
+		Chapters:       pipeline.Chapters,
+		Profile:        pipeline.Profile,
+		Format:         pipeline.Format,
+		// The source audio was preprocessed already.
+		Preprocess: []subtitles.Filter{},
+	}
+
+	if req.Configuration == configurationPinned {
+		if !h.subtitler.HasProvider(pipeline.Provider) {
+			h.e(w, fmt.Sprintf("The pinned provider %q is not configured", pipeline.Provider), nil, http.StatusConflict)
+			return
+		}
+		in.Provider, in.Model, in.Steps = pipeline.Provider, pipeline.Model, pipeline.Steps
+	}
+
+	source, err := h.storage.Open(sourceObj)
+	if err != nil {
+		h.e(w, "Failed to open audio", err, http.StatusInternalServerError)
+		return
+	}
+	defer source.Close()
+
+	in.Data, in.Size = source, sourceObj.Size
+
+	job, err := h.startJob([]*subtitles.Input{in}, nil, "")
+	if err != nil {
+		if errors.Is(err, errStorageDegraded) {
+			h.storageUnavailable(w, err)
+			return
+		}
+		h.e(w, "Failed to start job", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "subtitle.reprocess",
+		Subject: subName,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{
+			"configuration": req.Configuration,
+			"provider":      in.Provider,
+			"model":         in.Model,
+			"job_id":        job.ID,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	json.NewEncoder(w).Encode(uploadResponse{
+		Message: "Subtitle reprocessing started",
+		JobID:   job.ID,
+	})
+}
+
+// reprocessName returns the name of the file reprocessing the subtitle, e.g. talk-pinned-20240102T150405Z.wav,
+// so that the reprocessed subtitle doesn't replace the original one.
+func reprocessName(subName, configuration string) string {
+	base := strings.TrimSuffix(subName, path.Ext(subName))
+	return base + "-" + configuration + "-" + time.Now().UTC().Format("20060102T150405Z") + ".wav"
+}
@@ -0,0 +1,52 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/alesr/videoscriber/internal/pkg/retention"
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handlers) getRetention(w http.ResponseWriter, r *http.Request) {
+	project := chi.URLParam(r, "project")
+
+	if err := retention.ValidateProject(project); err != nil {
+		h.e(w, "Invalid project name", err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(h.retention.Policy(project)); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handlers) setRetention(w http.ResponseWriter, r *http.Request) {
+	project := chi.URLParam(r, "project")
+
+	var policy retention.Policy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.retention.SetPolicy(project, policy, owner(r)); err != nil {
+		if errors.Is(err, retention.ErrInvalidProject) || errors.Is(err, retention.ErrInvalidPolicy) {
+			h.e(w, "Invalid retention policy", err, http.StatusBadRequest)
+			return
+		}
+		h.e(w, "Failed to update retention policy", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(policy); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,506 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/alesr/videoscriber/internal/pkg/quality"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/store"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+	"github.com/go-chi/chi/v5"
+)
+
+type reviewMetadata struct {
+	subtitleMetadata
+	Score     float64    `json:"score"`
+	Issues    []string   `json:"issues,omitempty"`
+	Reviewer  string     `json:"reviewer,omitempty"`
+	ClaimedAt *time.Time `json:"claimed_at,omitempty"`
+}
+
+type listReviewsResponse struct {
+	Reviews []reviewMetadata `json:"reviews"`
+}
+
+type assignRequest struct {
+	Assignee string `json:"assignee"`
+}
+
+type commentRequest struct {
+	Cue  int    `json:"cue"`
+	Text string `json:"text"`
+}
+
+type reviewComment struct {
+	ID        int64     `json:"id"`
+	Cue       int       `json:"cue"`
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type listCommentsResponse struct {
+	Comments []reviewComment `json:"comments"`
+}
+
+type reviseRequest struct {
+	Cues []srt.JSONCue `json:"cues"`
+}
+
+type reviewCuesResponse struct {
+	Cues []srt.JSONCue `json:"cues"`
+}
+
+// holdForReview assesses the quality of the subtitle of a done file and, when it is below
+// the review threshold, holds it for review. It reports whether the subtitle was held.
+func (h *Handlers) holdForReview(jobID string, index int, in *subtitles.Input, e subtitles.Event) bool {
+	if h.reviewThreshold <= 0 || !publishable(e.Subtitle) {
+		return false
+	}
+
+	assessment, err := func() (quality.Assessment, error) {
+		obj, err := h.findStored(in.Owner, e.Subtitle, in.OutputLanguage(), in.Project)
+		if err != nil {
+			return quality.Assessment{}, fmt.Errorf("could not find subtitle: %w", err)
+		}
+
+		data, err := h.storage.Read(obj)
+		if err != nil {
+			return quality.Assessment{}, fmt.Errorf("could not read subtitle: %w", err)
+		}
+
+		cues, _, err := parseCues(data)
+		if err != nil {
+			return quality.Assessment{}, fmt.Errorf("could not parse subtitle: %w", err)
+		}
+		return quality.Assess(cues, e.Duration), nil
+	}()
+	if err != nil {
+		h.logger.Error("Could not assess subtitle", slog.String("job_id", jobID), slog.String("error", err.Error()))
+		return false
+	}
+
+	if assessment.Score >= h.reviewThreshold {
+		return false
+	}
+
+	if err := h.storage.Hold(in.Owner, in.OutputLanguage(), in.Project, e.Subtitle, assessment.Score, assessment.Issues); err != nil {
+		h.logger.Error("Could not hold subtitle for review", slog.String("job_id", jobID), slog.String("error", err.Error()))
+		return false
+	}
+
+	if err := h.jobs.SetFileReview(jobID, index, jobs.ReviewPending); err != nil {
+		h.logger.Error("Could not update job", slog.String("job_id", jobID), slog.String("error", err.Error()))
+	}
+
+	h.logger.Info("Subtitle held for review",
+		slog.String("job_id", jobID),
+		slog.String("subtitle", e.Subtitle),
+		slog.String("score", fmt.Sprintf("%.2f", assessment.Score)),
+	)
+	return true
+}
+
+// listReviews lists the subtitles of the caller waiting for review and the ones assigned to them, oldest first,
+// optionally only the ones assigned to a reviewer.
+func (h *Handlers) listReviews(w http.ResponseWriter, r *http.Request) {
+	reviews, err := h.storage.Reviews(owner(r))
+	if err != nil {
+		h.e(w, "Failed to list reviews", err, http.StatusInternalServerError)
+		return
+	}
+
+	reviewer := r.URL.Query().Get("reviewer")
+
+	resp := listReviewsResponse{Reviews: make([]reviewMetadata, 0, len(reviews))}
+
+	for _, review := range reviews {
+		if reviewer != "" && review.Reviewer != reviewer {
+			continue
+		}
+		resp.Reviews = append(resp.Reviews, newReviewMetadata(review))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// claimReview assigns the review of a subtitle to the caller, so that only them can edit and approve it.
+func (h *Handlers) claimReview(w http.ResponseWriter, r *http.Request) {
+	reviewer, ok := h.reviewer(w, r)
+	if !ok {
+		return
+	}
+
+	review, err := h.findReview(r)
+	if err != nil {
+		h.reviewError(w, err)
+		return
+	}
+
+	review, err = h.storage.Claim(review, reviewer)
+	if err != nil {
+		h.reviewError(w, err)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "review.claim",
+		Subject: review.Name,
+		Actor:   reviewer,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(newReviewMetadata(review)); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// assignReview assigns the review of a subtitle of the caller to a reviewer, replacing the current one if any.
+// The reviewer can then find the review, though the subtitle isn't theirs.
+func (h *Handlers) assignReview(w http.ResponseWriter, r *http.Request) {
+	var req assignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	if req.Assignee == "" {
+		h.e(w, "Assignee is required", nil, http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.findReview(r)
+	if err != nil {
+		h.reviewError(w, err)
+		return
+	}
+
+	if review.Owner != owner(r) {
+		h.e(w, "Only the owner of the subtitle can assign its review", nil, http.StatusForbidden)
+		return
+	}
+
+	review, err = h.storage.Assign(review, req.Assignee)
+	if err != nil {
+		h.reviewError(w, err)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "review.assign",
+		Subject: review.Name,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{"assignee": req.Assignee},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(newReviewMetadata(review)); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// listReviewComments returns the comments on the cues of a subtitle under review, oldest first.
+func (h *Handlers) listReviewComments(w http.ResponseWriter, r *http.Request) {
+	review, err := h.findReview(r)
+	if err != nil {
+		h.reviewError(w, err)
+		return
+	}
+
+	comments, err := h.storage.Comments(review)
+	if err != nil {
+		h.e(w, "Failed to list comments", err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := listCommentsResponse{Comments: make([]reviewComment, 0, len(comments))}
+	for _, c := range comments {
+		resp.Comments = append(resp.Comments, reviewComment(c))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// commentReview adds a comment of the caller on a cue of a subtitle under review.
+// Anyone can comment, not only the assigned reviewer.
+func (h *Handlers) commentReview(w http.ResponseWriter, r *http.Request) {
+	author, ok := h.reviewer(w, r)
+	if !ok {
+		return
+	}
+
+	var req commentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	if req.Text == "" {
+		h.e(w, "Text is required", nil, http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.findReview(r)
+	if err != nil {
+		h.reviewError(w, err)
+		return
+	}
+
+	data, err := h.storage.Read(review.Object())
+	if err != nil {
+		h.e(w, "Failed to read subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	cues, _, err := parseCues(data)
+	if err != nil {
+		h.e(w, "Failed to parse subtitle", err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if req.Cue < 1 || req.Cue > len(cues) {
+		h.e(w, fmt.Sprintf("Cue must be between 1 and %d", len(cues)), nil, http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.storage.AddComment(review, store.Comment{Cue: req.Cue, Author: author, Text: req.Text})
+	if err != nil {
+		h.e(w, "Failed to add comment", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(reviewComment(comment)); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// reviewCues returns the cues of a subtitle under review.
+func (h *Handlers) reviewCues(w http.ResponseWriter, r *http.Request) {
+	review, err := h.findReview(r)
+	if err != nil {
+		h.reviewError(w, err)
+		return
+	}
+
+	data, err := h.storage.Read(review.Object())
+	if err != nil {
+		h.e(w, "Failed to read subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	cues, _, err := parseCues(data)
+	if err != nil {
+		h.e(w, "Failed to parse subtitle", err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	resp := reviewCuesResponse{Cues: make([]srt.JSONCue, 0, len(cues))}
+	for _, cue := range cues {
+		resp.Cues = append(resp.Cues, cue.ToJSON())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// reviseReview replaces the cues of a subtitle under review. The review must be claimed by the caller.
+func (h *Handlers) reviseReview(w http.ResponseWriter, r *http.Request) {
+	var req reviseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	review, ok := h.claimedReview(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.retention.CanModify(review.Project); err != nil {
+		h.e(w, "Subtitle is under legal hold", err, http.StatusLocked)
+		return
+	}
+
+	data, err := h.storage.Read(review.Object())
+	if err != nil {
+		h.e(w, "Failed to read subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	cues := make([]srt.Cue, 0, len(req.Cues))
+	for i, c := range req.Cues {
+		cue := srt.Cue{Index: i + 1, Start: seconds(c.Start), End: seconds(c.End), Text: c.Text}
+
+		if cue.Start < 0 || cue.End <= cue.Start || cue.Text == "" {
+			h.e(w, fmt.Sprintf("Invalid cue %d", i+1), nil, http.StatusBadRequest)
+			return
+		}
+		cues = append(cues, cue)
+	}
+
+	if err := h.storage.Revise(review, formatCues(cues, srt.IsVTT(data))); err != nil {
+		h.e(w, "Failed to write subtitle", err, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// approveReview releases a subtitle from review, listing it and publishing it if its job requested so.
+// The review must be claimed by the caller.
+func (h *Handlers) approveReview(w http.ResponseWriter, r *http.Request) {
+	review, ok := h.claimedReview(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.Approve(review); err != nil {
+		h.e(w, "Failed to approve subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "review.approve",
+		Subject: review.Name,
+		Actor:   review.Reviewer,
+		Details: map[string]string{
+			"project": review.Project,
+			"job_id":  review.JobID,
+			"score":   fmt.Sprintf("%.2f", review.Score),
+		},
+	})
+
+	h.releaseJobFile(review)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reviewer returns the caller reviewing a subtitle. Reviews are claimed, revised, approved and commented by
+// authenticated callers only, so that their reviewer and the audit log name who did it.
+func (h *Handlers) reviewer(w http.ResponseWriter, r *http.Request) (string, bool) {
+	reviewer := owner(r)
+	if reviewer == "" {
+		h.e(w, "Reviewing requires an authenticated caller", nil, http.StatusUnauthorized)
+		return "", false
+	}
+	return reviewer, true
+}
+
+// findReview returns the review of the subtitle named in the request, of the caller or assigned to them.
+// The reviews of the others are not found, as their jobs aren't. The lang and project form values,
+// e.g. query parameters, tell apart the subtitles of the same name, as for findSubtitle.
+func (h *Handlers) findReview(r *http.Request) (store.Review, error) {
+	return h.storage.FindReview(owner(r), chi.URLParam(r, "name"), r.FormValue("lang"), r.FormValue("project"))
+}
+
+// claimedReview returns the review of the subtitle named in the request if it is claimed by the caller,
+// otherwise it responds with an error.
+func (h *Handlers) claimedReview(w http.ResponseWriter, r *http.Request) (store.Review, bool) {
+	reviewer, ok := h.reviewer(w, r)
+	if !ok {
+		return store.Review{}, false
+	}
+
+	review, err := h.findReview(r)
+	if err != nil {
+		h.reviewError(w, err)
+		return store.Review{}, false
+	}
+
+	if review.Reviewer != reviewer {
+		h.e(w, "The review must be claimed by the caller first", nil, http.StatusConflict)
+		return store.Review{}, false
+	}
+	return review, true
+}
+
+// releaseJobFile marks the job file of an approved subtitle as approved and runs its pending publication.
+func (h *Handlers) releaseJobFile(review store.Review) {
+	if review.JobID == "" {
+		return
+	}
+
+	job, err := h.jobs.Get(review.JobID)
+	if err != nil {
+		h.logger.Error("Could not get job", slog.String("job_id", review.JobID), slog.String("error", err.Error()))
+		return
+	}
+
+	for i, f := range job.Files {
+		if f.Subtitle != review.Name || f.Review != jobs.ReviewPending {
+			continue
+		}
+
+		if err := h.jobs.SetFileReview(job.ID, i, jobs.ReviewApproved); err != nil {
+			h.logger.Error("Could not update job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+		}
+
+		if f.Publication != nil && f.Publication.State == jobs.PublishPending {
+			language := review.Language
+			if language == storage.UndefinedLanguage {
+				language = ""
+			}
+			index, publication := i, *f.Publication
+			h.background(func(ctx context.Context) {
+				h.publishJobFile(ctx, job.ID, index, review.Owner, review.Name, language, review.Project, publication)
+			})
+		}
+	}
+}
+
+func (h *Handlers) reviewError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		h.e(w, "Review not found", err, http.StatusNotFound)
+	case errors.Is(err, storage.ErrAmbiguous):
+		h.e(w, "Several reviews have this name, pass their project or lang", err, http.StatusConflict)
+	case errors.Is(err, store.ErrClaimed):
+		h.e(w, "The review is claimed by another reviewer", err, http.StatusConflict)
+	default:
+		h.e(w, "Failed to find review", err, http.StatusInternalServerError)
+	}
+}
+
+func newReviewMetadata(review store.Review) reviewMetadata {
+	meta := reviewMetadata{
+		subtitleMetadata: newSubtitleMetadata(review.Subtitle),
+		Score:            review.Score,
+		Issues:           review.Issues,
+		Reviewer:         review.Reviewer,
+	}
+
+	if !review.ClaimedAt.IsZero() {
+		meta.ClaimedAt = &review.ClaimedAt
+	}
+	return meta
+}
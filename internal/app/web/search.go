@@ -0,0 +1,111 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+const (
+	// defaultSearchLimit is the number of subtitles returned by a search when the limit is not given.
+	defaultSearchLimit int = 20
+	maxSearchLimit     int = 100
+)
+
+type searchMatch struct {
+	srt.JSONCue
+	Score float64 `json:"score"`
+	// Snippet is the HTML text of the cue, with the matching words in <mark> elements.
+	Snippet string `json:"snippet"`
+}
+
+type searchResult struct {
+	Name         string        `json:"name"`
+	Project      string        `json:"project"`
+	Language     string        `json:"language"`
+	OriginalName string        `json:"original_name,omitempty"`
+	JobID        string        `json:"job_id,omitempty"`
+	Score        float64       `json:"score"`
+	Matches      []searchMatch `json:"matches"`
+}
+
+type searchResponse struct {
+	Query   string         `json:"query"`
+	Results []searchResult `json:"results"`
+	// Truncated is set when more subtitles match than the limit.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// search finds the cues of the stored subtitles of the caller containing all the words of q in the full-text
+// index, so that the video in which something was said can be found, with when it was said. The words match
+// regardless of their case, accents and inflections in the language of the subtitle, and the subtitles are
+// ranked by relevance. They can be filtered by lang and project like the listing. Only SRT and VTT subtitles
+// are indexed, as they have cues.
+func (h *Handlers) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		h.e(w, "Missing query", nil, http.StatusBadRequest)
+		return
+	}
+
+	limit, err := searchLimit(query.Get("limit"))
+	if err != nil {
+		h.e(w, "Invalid limit", err, http.StatusBadRequest)
+		return
+	}
+
+	results, truncated, err := h.storage.Search(owner(r), q, query.Get("lang"), query.Get("project"), limit)
+	if err != nil {
+		h.e(w, "Failed to search subtitles", err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := searchResponse{Query: query.Get("q"), Results: make([]searchResult, 0, len(results)), Truncated: truncated}
+
+	for _, res := range results {
+		matches := make([]searchMatch, 0, len(res.Matches))
+		for _, m := range res.Matches {
+			matches = append(matches, searchMatch{JSONCue: m.Cue.ToJSON(), Score: m.Score, Snippet: m.Snippet})
+		}
+
+		resp.Results = append(resp.Results, searchResult{
+			Name:         res.Subtitle.Name,
+			Project:      res.Subtitle.Project,
+			Language:     res.Subtitle.Language,
+			OriginalName: res.Subtitle.OriginalName,
+			JobID:        res.Subtitle.JobID,
+			Score:        res.Score,
+			Matches:      matches,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// searchLimit parses the number of subtitles a search returns, at most maxSearchLimit.
+func searchLimit(s string) (int, error) {
+	if s == "" {
+		return defaultSearchLimit, nil
+	}
+
+	limit, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+
+	if limit < 1 || limit > maxSearchLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxSearchLimit)
+	}
+	return limit, nil
+}
@@ -0,0 +1,245 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// sharePage renders a transcript with a timeline of its cues. It has no scripts nor external resources.
+var sharePage = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="{{.Language}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Name}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+h1 { font-size: 1.4rem; word-break: break-all; }
+.meta { color: #666; font-size: .9rem; }
+.timeline { position: relative; height: 1.5rem; margin: 1.5rem 0; background: #eee; border-radius: 4px; }
+.timeline a { position: absolute; top: 0; bottom: 0; min-width: 2px; background: #4a7bd0; }
+.timeline a:hover { background: #1d4ea3; }
+ol { list-style: none; padding: 0; }
+li { display: flex; gap: 1rem; padding: .4rem 0; border-bottom: 1px solid #f0f0f0; }
+li:target { background: #fff6d5; }
+time { color: #4a7bd0; font-variant-numeric: tabular-nums; white-space: nowrap; }
+p { margin: 0; white-space: pre-line; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<div class="meta">{{.Language}} · {{.Length}} · {{len .Cues}} cues</div>
+<nav class="timeline">
+{{- range .Cues}}
+<a href="#cue-{{.Index}}" title="{{.Start}}" style="left: {{.Left}}%; width: {{.Width}}%"></a>
+{{- end}}
+</nav>
+<ol>
+{{- range .Cues}}
+<li id="cue-{{.Index}}"><time>{{.Start}}</time><p>{{.Text}}</p></li>
+{{- end}}
+</ol>
+</body>
+</html>
+`))
+
+type sharePageData struct {
+	Name     string
+	Language string
+	Length   string
+	Cues     []shareCue
+}
+
+type shareCue struct {
+	Index int
+	Start string
+	Text  string
+	Left  string // Position of the cue on the timeline, in percent.
+	Width string
+}
+
+type shareRequest struct {
+	ExpiresIn int64  `json:"expires_in"` // Seconds. Zero means the share never expires.
+	VideoURL  string `json:"video_url"`  // Video played by the embeddable widget.
+}
+
+type shareResponse struct {
+	Token     string     `json:"token"`
+	URL       string     `json:"url"`
+	EmbedCode string     `json:"embed_code"` // Snippet embedding the player widget.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// shareSubtitle creates a read-only link to a subtitle, for clients without API access.
+func (h *Handlers) shareSubtitle(w http.ResponseWriter, r *http.Request) {
+	var req shareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	if req.ExpiresIn < 0 {
+		h.e(w, "Invalid expires_in", nil, http.StatusBadRequest)
+		return
+	}
+
+	if req.VideoURL != "" {
+		if u, err := url.Parse(req.VideoURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			h.e(w, "Invalid video_url", err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	obj, err := h.findSubtitle(r, chi.URLParam(r, "name"))
+	if err != nil {
+		h.subtitleError(w, err)
+		return
+	}
+
+	if !publishable(obj.Name) {
+		h.e(w, "Only SRT and VTT subtitles can be shared", nil, http.StatusBadRequest)
+		return
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+	}
+
+	share, err := h.storage.Share(obj, expiresAt, req.VideoURL)
+	if err != nil {
+		h.e(w, "Failed to share subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "subtitle.share",
+		Subject: obj.Name,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{"expires_in": fmt.Sprint(req.ExpiresIn)},
+	})
+
+	resp := shareResponse{
+		Token: share.Token,
+		URL:   h.link("/share/" + share.Token),
+		EmbedCode: fmt.Sprintf(`<div data-videoscriber="%s"></div><script src="%s" async></script>`,
+			share.Token, h.link("/widget.js"),
+		),
+	}
+	if !share.ExpiresAt.IsZero() {
+		resp.ExpiresAt = &share.ExpiresAt
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// revokeShare disables a read-only link.
+func (h *Handlers) revokeShare(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	// Shares of the subtitles of other users are reported as not found.
+	sub, _, err := h.storage.Shared(token)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.e(w, "Share not found", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to find share", err, http.StatusInternalServerError)
+		return
+	}
+
+	if sub.Owner != owner(r) {
+		h.e(w, "Share not found", nil, http.StatusNotFound)
+		return
+	}
+
+	if err := h.storage.Unshare(token); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.e(w, "Share not found", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to revoke share", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "subtitle.unshare",
+		Subject: token,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sharedSubtitle renders the read-only page of a shared subtitle.
+func (h *Handlers) sharedSubtitle(w http.ResponseWriter, r *http.Request) {
+	sub, _, cues, ok := h.sharedCues(w, chi.URLParam(r, "token"))
+	if !ok {
+		return
+	}
+
+	length := sub.Duration
+	if len(cues) > 0 {
+		length = max(length, cues[len(cues)-1].End)
+	}
+
+	page := sharePageData{
+		Name:     sub.Name,
+		Language: sub.Language,
+		Length:   clock(length),
+		Cues:     make([]shareCue, 0, len(cues)),
+	}
+
+	for _, cue := range cues {
+		page.Cues = append(page.Cues, shareCue{
+			Index: cue.Index,
+			Start: clock(cue.Start),
+			Text:  cue.Text,
+			Left:  percent(cue.Start, length),
+			Width: percent(cue.End-cue.Start, length),
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	if err := sharePage.Execute(w, page); err != nil {
+		h.logger.Error("Could not render share page", slog.String("error", err.Error()))
+	}
+}
+
+// clock formats the duration as h:mm:ss, or m:ss under an hour.
+func clock(d time.Duration) string {
+	s := int(d.Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+func percent(d, total time.Duration) string {
+	if total <= 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%.3f", 100*float64(d)/float64(total))
+}
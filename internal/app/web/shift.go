@@ -0,0 +1,123 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/go-chi/chi/v5"
+)
+
+type shiftRequest struct {
+	Offset string `json:"offset"` // E.g. "+1.250s" or "-500ms".
+	Drift  string `json:"drift"`  // Shift reached at the end of the last cue, growing linearly from zero.
+}
+
+type shiftResponse struct {
+	Name    string `json:"name"`
+	Cues    int    `json:"cues"`
+	Dropped int    `json:"dropped"` // Cues shifted before the start of the recording.
+}
+
+// shiftSubtitle rewrites the timestamps of an SRT or VTT subtitle, e.g. when the transcription drifts
+// against the original video. All the cues are shifted by the offset, and by the drift proportionally
+// to their time, so that the last cue is shifted by the offset plus the drift.
+func (h *Handlers) shiftSubtitle(w http.ResponseWriter, r *http.Request) {
+	var req shiftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	offset, err := parseShift(req.Offset)
+	if err != nil {
+		h.e(w, "Invalid offset, must be a duration like +1.250s", err, http.StatusBadRequest)
+		return
+	}
+
+	drift, err := parseShift(req.Drift)
+	if err != nil {
+		h.e(w, "Invalid drift, must be a duration like -2s", err, http.StatusBadRequest)
+		return
+	}
+
+	if offset == 0 && drift == 0 {
+		h.e(w, "Either offset or drift is required", nil, http.StatusBadRequest)
+		return
+	}
+
+	obj, err := h.findSubtitle(r, chi.URLParam(r, "name"))
+	if err != nil {
+		h.subtitleError(w, err)
+		return
+	}
+
+	if !publishable(obj.Name) {
+		h.e(w, "Only SRT and VTT subtitles can be shifted", nil, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.retention.CanModify(obj.Project); err != nil {
+		h.e(w, "Subtitle is under legal hold", err, http.StatusLocked)
+		return
+	}
+
+	data, err := h.storage.Read(obj)
+	if err != nil {
+		h.e(w, "Failed to read subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	cues, vtt, err := parseCues(data)
+	if err != nil {
+		h.e(w, "Failed to parse subtitle", err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	scale := 1.0
+	if drift != 0 {
+		var end time.Duration
+		for _, cue := range cues {
+			end = max(end, cue.End)
+		}
+
+		if end == 0 || drift <= -end {
+			h.e(w, "The drift must be shorter than the subtitle", nil, http.StatusBadRequest)
+			return
+		}
+		scale = 1 + float64(drift)/float64(end)
+	}
+
+	shifted := srt.Shift(cues, offset, scale)
+
+	if err := h.storage.Replace(obj, formatCues(shifted, vtt)); err != nil {
+		h.e(w, "Failed to write subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.record(audit.Entry{
+		Action:  "subtitle.shift",
+		Subject: obj.Name,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{"project": obj.Project, "offset": offset.String(), "drift": drift.String()},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+
+	resp := shiftResponse{Name: obj.Name, Cues: len(shifted), Dropped: len(cues) - len(shifted)}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseShift parses a signed duration, zero when empty.
+func parseShift(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
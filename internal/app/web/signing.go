@@ -0,0 +1,106 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/signing"
+)
+
+// maxVerifySize bounds the size of the verification requests. Only the files written in memory are signed,
+// i.e. subtitles and artifacts of a few MB, not the kept media.
+const maxVerifySize int64 = 10 << 20 // 10MB
+
+type verifyResponse struct {
+	Valid    bool      `json:"valid"`
+	Reason   string    `json:"reason,omitempty"` // Why the file doesn't match its signature.
+	Name     string    `json:"name"`
+	KeyID    string    `json:"key_id"`
+	SHA256   string    `json:"sha256"`
+	SignedAt time.Time `json:"signed_at"`
+}
+
+// signingKey serves the public key verifying the signatures of the generated files, for offline verification.
+func (h *Handlers) signingKey(w http.ResponseWriter, r *http.Request) {
+	if !h.signer.Enabled() {
+		h.e(w, "Signing is not configured", nil, http.StatusNotFound)
+		return
+	}
+
+	key, err := h.signer.PublicKey()
+	if err != nil {
+		h.e(w, "Failed to encode the public key", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.Write(key)
+}
+
+// verifySignature checks that an uploaded subtitle or artifact matches its detached signature,
+// i.e. that it wasn't altered after it was generated. It is public, for the downstream consumers
+// of the files, and responds with the verdict rather than an error when they don't match.
+func (h *Handlers) verifySignature(w http.ResponseWriter, r *http.Request) {
+	if !h.signer.Enabled() {
+		h.e(w, "Signing is not configured", nil, http.StatusNotFound)
+		return
+	}
+
+	// The route is public, so the uploads are bounded to the size of the signed files rather than of the media.
+	r.Body = http.MaxBytesReader(w, r.Body, maxVerifySize)
+
+	if err := r.ParseMultipartForm(maxVerifySize); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.e(w, fmt.Sprintf("Files can be at most %d bytes", maxVerifySize), err, http.StatusRequestEntityTooLarge)
+			return
+		}
+		h.e(w, "Failed to parse the request", err, http.StatusBadRequest)
+		return
+	}
+
+	data, err := formFile(r, "artifact")
+	if err != nil {
+		h.e(w, "Failed to read the artifact", err, http.StatusBadRequest)
+		return
+	}
+
+	signature, err := formText(r, "signature")
+	if err != nil {
+		h.e(w, "Failed to read the signature", err, http.StatusBadRequest)
+		return
+	}
+
+	if signature == "" {
+		h.e(w, "No signature in request", nil, http.StatusBadRequest)
+		return
+	}
+
+	sig, err := h.signer.Verify(data, []byte(signature))
+	if err != nil && !errors.Is(err, signing.ErrInvalidSignature) {
+		h.e(w, "Failed to parse the signature", err, http.StatusBadRequest)
+		return
+	}
+
+	resp := verifyResponse{
+		Valid:    err == nil,
+		Name:     sig.Name,
+		KeyID:    sig.KeyID,
+		SHA256:   sig.SHA256,
+		SignedAt: sig.SignedAt,
+	}
+
+	if err != nil {
+		resp.Reason = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
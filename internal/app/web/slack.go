@@ -0,0 +1,228 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+)
+
+// maxSlackPayload bounds the size of the requests sent by Slack.
+const maxSlackPayload int64 = 1 << 20
+
+// slackLink matches the links in Slack messages, formatted as <https://example.com/video.mp4|label>.
+var slackLink = regexp.MustCompile(`<(https?://[^|>]+)(?:\|[^>]*)?>`)
+
+// slackCommand handles the slash command, e.g. "/transcribe https://example.com/video.mp4 en".
+// The job is announced in the channel and progress is reported in the thread of the announcement.
+func (h *Handlers) slackCommand(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.slackRequest(w, r)
+	if !ok {
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		h.e(w, "Failed to parse the request", err, http.StatusBadRequest)
+		return
+	}
+
+	fields := strings.Fields(form.Get("text"))
+	if len(fields) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"response_type": "ephemeral",
+			"text":          "Usage: " + form.Get("command") + " <video url> [language]",
+		})
+		return
+	}
+
+	videoURL, language := strings.Trim(fields[0], "<>"), ""
+	if len(fields) > 1 {
+		language = fields[1]
+	}
+
+	h.background(func(ctx context.Context) {
+		h.slackTranscribe(ctx, form.Get("channel_id"), "", videoURL, language)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"response_type": "ephemeral",
+		"text":          "Transcription requested, follow the progress in the channel.",
+	})
+}
+
+type slackEvent struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Event     struct {
+		Type     string `json:"type"`
+		Subtype  string `json:"subtype"`
+		BotID    string `json:"bot_id"`
+		Channel  string `json:"channel"`
+		Text     string `json:"text"`
+		TS       string `json:"ts"`
+		ThreadTS string `json:"thread_ts"`
+	} `json:"event"`
+}
+
+// slackEvents handles the Events API. Videos linked in messages mentioning the bot,
+// or in channels it is a member of, are transcribed with progress reported in-thread.
+func (h *Handlers) slackEvents(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.slackRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var payload slackEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	if payload.Type == "url_verification" {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, payload.Challenge)
+		return
+	}
+
+	// Slack retries events not acknowledged in time; the job was already started by the first delivery.
+	if r.Header.Get("X-Slack-Retry-Num") != "" {
+		return
+	}
+
+	event := payload.Event
+
+	if payload.Type != "event_callback" || event.BotID != "" || event.Subtype != "" {
+		return
+	}
+
+	if event.Type != "app_mention" && event.Type != "message" {
+		return
+	}
+
+	match := slackLink.FindStringSubmatch(event.Text)
+	if match == nil {
+		return
+	}
+
+	threadTS := event.ThreadTS
+	if threadTS == "" {
+		threadTS = event.TS
+	}
+
+	h.background(func(ctx context.Context) {
+		h.slackTranscribe(ctx, event.Channel, threadTS, match[1], "")
+	})
+}
+
+// slackRequest reads and verifies a request sent by Slack.
+func (h *Handlers) slackRequest(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if !h.slack.Enabled() {
+		h.e(w, "Slack integration is not configured", nil, http.StatusNotFound)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSlackPayload))
+	if err != nil {
+		h.e(w, "Failed to read the request", err, http.StatusBadRequest)
+		return nil, false
+	}
+
+	if err := h.slack.Verify(r.Header, body); err != nil {
+		h.e(w, "Invalid request signature", err, http.StatusUnauthorized)
+		return nil, false
+	}
+	return body, true
+}
+
+// slackTranscribe fetches the video, runs a job for it and reports its progress in the thread.
+// When threadTS is empty, the job is announced in the channel and the announcement starts the thread.
+func (h *Handlers) slackTranscribe(ctx context.Context, channel, threadTS, videoURL, language string) {
+	post := func(text string) string {
+		ts, err := h.slack.PostMessage(ctx, channel, threadTS, text)
+		if err != nil {
+			h.logger.Error("Could not post Slack message", slog.String("channel", channel), slog.String("error", err.Error()))
+		}
+		return ts
+	}
+
+	if threadTS == "" {
+		threadTS = post("Transcribing " + videoURL)
+		if threadTS == "" {
+			return
+		}
+	}
+
+	if language == "" {
+		language = subtitles.DefaultLanguage
+	}
+
+	if !subtitles.SupportedLanguage(language) {
+		post(fmt.Sprintf(":warning: Unsupported language %q.", language))
+		return
+	}
+
+	download, err := h.ingest.Fetch(ctx, videoURL)
+	if err != nil {
+		h.logger.Error("Could not fetch video", slog.String("url", videoURL), slog.String("error", err.Error()))
+		post(":warning: Could not download the video: " + err.Error())
+		return
+	}
+	defer download.Body.Close()
+
+	job, err := h.startJob([]*subtitles.Input{{
+		FileName: download.Name,
+		Data:     download.Body,
+		Language: language,
+	}}, nil, "")
+	if err != nil {
+		h.logger.Error("Could not start job", slog.String("url", videoURL), slog.String("error", err.Error()))
+		post(":warning: Could not start the transcription: " + err.Error())
+		return
+	}
+
+	post(fmt.Sprintf("Job `%s` created.", job.ID))
+
+	// Only changes of the job state are reported, to keep the thread readable.
+	job, err = h.awaitJob(job.ID, func(state jobs.State) {
+		if state != jobs.StateDone && state != jobs.StateFailed {
+			post(fmt.Sprintf("Job `%s` is %s.", job.ID, state))
+		}
+	})
+	if err != nil {
+		h.logger.Error("Could not wait for job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+		return
+	}
+	post(slackSummary(job, h.link))
+}
+
+func slackSummary(job jobs.Job, link func(path string) string) string {
+	var b strings.Builder
+
+	if job.State == jobs.StateDone {
+		fmt.Fprintf(&b, ":white_check_mark: Job `%s` is done.", job.ID)
+	} else {
+		fmt.Fprintf(&b, ":x: Job `%s` failed.", job.ID)
+	}
+
+	for _, f := range job.Files {
+		if f.Subtitle != "" {
+			fmt.Fprintf(&b, "\n• <%s|%s>", link(subtitlePath(f)), f.Subtitle)
+		}
+
+		if f.Error != "" {
+			fmt.Fprintf(&b, "\n• %s: %s", f.Name, f.Error)
+		}
+	}
+	return b.String()
+}
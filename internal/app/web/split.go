@@ -0,0 +1,202 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxSplitParts bounds the number of files a subtitle can be split into.
+const maxSplitParts int = 100
+
+type splitRange struct {
+	Start float64 `json:"start"` // Seconds.
+	End   float64 `json:"end"`   // Seconds. Zero means until the end of the subtitle.
+	Name  string  `json:"name"`  // Optional name of the part, without extension.
+}
+
+type splitRequest struct {
+	Ranges   []splitRange `json:"ranges"`
+	Chapters int          `json:"chapters"` // Number of chapters to generate, split at the longest pauses.
+}
+
+type splitResponse struct {
+	Subtitles []string `json:"subtitles"`
+}
+
+// splitSubtitle splits a stored SRT or WebVTT subtitle into several files, either by time
+// ranges or by generated chapters, for distributing a long recording as episodes.
+// The timestamps of each part are rebased to the start of the part.
+func (h *Handlers) splitSubtitle(w http.ResponseWriter, r *http.Request) {
+	subName := chi.URLParam(r, "name")
+
+	var req splitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	if (len(req.Ranges) == 0) == (req.Chapters == 0) {
+		h.e(w, "Either ranges or chapters is required", nil, http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Ranges) > maxSplitParts || req.Chapters > maxSplitParts || req.Chapters < 0 {
+		h.e(w, fmt.Sprintf("A subtitle can be split in at most %d parts", maxSplitParts), nil, http.StatusBadRequest)
+		return
+	}
+
+	obj, err := h.findSubtitle(r, subName)
+	if err != nil {
+		h.subtitleError(w, err)
+		return
+	}
+
+	if !publishable(obj.Name) {
+		h.e(w, "Only SRT and VTT subtitles can be split", nil, http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.storage.Read(obj)
+	if err != nil {
+		h.e(w, "Failed to read subtitle", err, http.StatusInternalServerError)
+		return
+	}
+
+	cues, vtt, err := parseCues(data)
+	if err != nil {
+		h.e(w, "Failed to parse subtitle", err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	ranges := req.Ranges
+	if req.Chapters > 0 {
+		ranges = chapterRanges(srt.Chapters(cues, req.Chapters))
+	}
+
+	ext := filepath.Ext(obj.Name)
+	base := strings.TrimSuffix(obj.Name, ext)
+
+	type part struct {
+		name       string
+		start, end time.Duration
+	}
+
+	// Every part is checked before any is written, so that a rejected split writes nothing.
+	parts := make([]part, 0, len(ranges))
+	names := make(map[string]bool, len(ranges))
+
+	for i, rg := range ranges {
+		start, end := seconds(rg.Start), seconds(rg.End)
+		if end == 0 {
+			end = math.MaxInt64
+		}
+
+		if start < 0 || end <= start {
+			h.e(w, fmt.Sprintf("Invalid range %d", i+1), nil, http.StatusBadRequest)
+			return
+		}
+
+		name := rg.Name
+		if name == "" {
+			name = fmt.Sprintf("%s.part%d", base, i+1)
+		}
+
+		if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+			h.e(w, fmt.Sprintf("Invalid name %q", rg.Name), nil, http.StatusBadRequest)
+			return
+		}
+
+		if names[name] {
+			h.e(w, fmt.Sprintf("Duplicate name %q", name), nil, http.StatusBadRequest)
+			return
+		}
+		names[name] = true
+
+		// The parts never overwrite a stored file, e.g. the split subtitle or an artifact alongside it.
+		exists, err := h.storage.Exists(obj.Owner, obj.Language, obj.Project, name+ext)
+		if err != nil {
+			h.e(w, "Failed to check subtitle part", err, http.StatusInternalServerError)
+			return
+		}
+
+		if exists {
+			h.e(w, fmt.Sprintf("A subtitle named %q already exists", name+ext), nil, http.StatusConflict)
+			return
+		}
+
+		parts = append(parts, part{name: name + ext, start: start, end: end})
+	}
+
+	resp := splitResponse{Subtitles: []string{}}
+
+	for _, p := range parts {
+		partData := formatCues(srt.Slice(cues, p.start, p.end), vtt)
+
+		if _, err := h.storage.Write(obj.Owner, obj.Language, obj.Project, p.name, partData); err != nil {
+			h.e(w, "Failed to write subtitle part", err, http.StatusInternalServerError)
+			return
+		}
+		resp.Subtitles = append(resp.Subtitles, p.name)
+	}
+
+	h.record(audit.Entry{
+		Action:  "subtitle.split",
+		Subject: subName,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{"parts": strings.Join(resp.Subtitles, ",")},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// chapterRanges turns the chapter starts into consecutive ranges, the last one running until the end.
+func chapterRanges(starts []time.Duration) []splitRange {
+	ranges := make([]splitRange, 0, len(starts))
+
+	for i, start := range starts {
+		rg := splitRange{Start: start.Seconds()}
+		if i+1 < len(starts) {
+			rg.End = starts[i+1].Seconds()
+		}
+		ranges = append(ranges, rg)
+	}
+	return ranges
+}
+
+// parseCues parses SRT or WebVTT data and reports whether it was WebVTT.
+func parseCues(data []byte) ([]srt.Cue, bool, error) {
+	if srt.IsVTT(data) {
+		cues, err := srt.ParseVTT(data)
+		return cues, true, err
+	}
+
+	cues, err := srt.Parse(data)
+	return cues, false, err
+}
+
+func formatCues(cues []srt.Cue, vtt bool) []byte {
+	if vtt {
+		return srt.FormatVTT(cues)
+	}
+	return srt.Format(cues)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,77 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/alesr/videoscriber/internal/pkg/styles"
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handlers) listStyles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(h.styles.List()); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handlers) getStyle(w http.ResponseWriter, r *http.Request) {
+	profile, err := h.styles.Get(chi.URLParam(r, "name"))
+	if err != nil {
+		if errors.Is(err, styles.ErrNotFound) {
+			h.e(w, "Styling profile not found", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to get styling profile", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(profile); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handlers) putStyle(w http.ResponseWriter, r *http.Request) {
+	var profile styles.Profile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	// The name in the path wins over the one in the body.
+	profile.Name = chi.URLParam(r, "name")
+
+	if err := h.styles.Put(profile); err != nil {
+		if errors.Is(err, styles.ErrInvalidProfile) {
+			h.e(w, err.Error(), err, http.StatusBadRequest)
+			return
+		}
+		h.e(w, "Failed to save styling profile", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(profile); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handlers) deleteStyle(w http.ResponseWriter, r *http.Request) {
+	if err := h.styles.Delete(chi.URLParam(r, "name")); err != nil {
+		if errors.Is(err, styles.ErrNotFound) {
+			h.e(w, "Styling profile not found", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to delete styling profile", err, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,137 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/alesr/videoscriber/internal/pkg/retention"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/store"
+	"github.com/alesr/videoscriber/internal/pkg/terminology"
+	"github.com/go-chi/chi/v5"
+)
+
+type terminologyRequest struct {
+	Glossary []terminology.Term `json:"glossary"`
+}
+
+// checkTerminology starts a job checking that the subtitles of a project spell the terms of a glossary
+// consistently. The report lists the spellings of each term, and the subtitle and cue of those that differ
+// from the glossary. It is stored in the project and named in the outputs of the job.
+func (h *Handlers) checkTerminology(w http.ResponseWriter, r *http.Request) {
+	project := chi.URLParam(r, "project")
+
+	if err := retention.ValidateProject(project); err != nil {
+		h.e(w, "Invalid project name", err, http.StatusBadRequest)
+		return
+	}
+
+	var req terminologyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	if err := terminology.Validate(req.Glossary); err != nil {
+		h.e(w, "Invalid glossary", err, http.StatusBadRequest)
+		return
+	}
+
+	subs, err := h.storage.Subtitles(owner(r))
+	if err != nil {
+		h.e(w, "Failed to list subtitles", err, http.StatusInternalServerError)
+		return
+	}
+
+	var projectSubs []store.Subtitle
+	for _, sub := range subs {
+		if sub.Project == project && publishable(sub.Name) {
+			projectSubs = append(projectSubs, sub)
+		}
+	}
+
+	if len(projectSubs) == 0 {
+		h.e(w, "No subtitles in project", nil, http.StatusNotFound)
+		return
+	}
+
+	job, err := h.jobs.Create(jobs.TypeTerminology, owner(r), []string{"terminology report"})
+	if err != nil {
+		h.e(w, "Failed to create job", err, http.StatusInternalServerError)
+		return
+	}
+
+	user := owner(r)
+	h.background(func(context.Context) {
+		h.runTerminologyCheck(job.ID, user, project, projectSubs, req.Glossary)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	json.NewEncoder(w).Encode(uploadResponse{
+		Message: "Terminology check started",
+		JobID:   job.ID,
+	})
+}
+
+// runTerminologyCheck reads the subtitles, checks them against the glossary and stores the report.
+func (h *Handlers) runTerminologyCheck(jobID, owner, project string, subs []store.Subtitle, glossary []terminology.Term) {
+	setState := func(state jobs.State, progress float64, err error) {
+		if err := h.jobs.SetFileState(jobID, 0, state, progress, err); err != nil {
+			h.logger.Error("Could not update job", slog.String("job_id", jobID), slog.String("error", err.Error()))
+		}
+	}
+
+	fail := func(err error) {
+		h.logger.Error("Could not check terminology", slog.String("job_id", jobID), slog.String("error", err.Error()))
+		setState(jobs.StateFailed, 0, err)
+	}
+
+	docs := make([]terminology.Document, 0, len(subs))
+
+	for i, sub := range subs {
+		setState(jobs.StateChecking, float64(i)/float64(len(subs)), nil)
+
+		data, err := h.storage.Read(sub.Object())
+		if err != nil {
+			fail(fmt.Errorf("could not read %s: %w", sub.Name, err))
+			return
+		}
+
+		cues, _, err := parseCues(data)
+		if err != nil {
+			fail(fmt.Errorf("could not parse %s: %w", sub.Name, err))
+			return
+		}
+
+		docs = append(docs, terminology.Document{Name: sub.Name, Language: sub.Language, Cues: cues})
+	}
+
+	data, err := json.MarshalIndent(terminology.Check(docs, glossary), "", "  ")
+	if err != nil {
+		fail(fmt.Errorf("could not marshal report: %w", err))
+		return
+	}
+
+	name := terminologyReportName(jobID)
+
+	if _, err := h.storage.Write(owner, storage.UndefinedLanguage, project, name, data); err != nil {
+		fail(fmt.Errorf("could not write report: %w", err))
+		return
+	}
+
+	if err := h.jobs.SetFileOutputs(jobID, 0, []string{name}); err != nil {
+		h.logger.Error("Could not update job", slog.String("job_id", jobID), slog.String("error", err.Error()))
+	}
+	setState(jobs.StateDone, 1, nil)
+}
+
+// terminologyReportName returns the name of the report of a terminology check job.
+func terminologyReportName(jobID string) string {
+	return "terminology-" + jobID + ".json"
+}
@@ -0,0 +1,96 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type connThrottleKey struct{}
+
+// connThrottle is the read budget of a connection, shared by the uploads sent over it, e.g. the
+// concurrent streams of an HTTP/2 connection. The budget refills at the upload rate, up to a second of it.
+type connThrottle struct {
+	mu      sync.Mutex
+	tokens  float64 // Bytes that can be read without waiting, negative when overdrawn.
+	updated time.Time
+}
+
+// withConnThrottle gives each connection its own read budget, see throttleUpload.
+func withConnThrottle(ctx context.Context, _ net.Conn) context.Context {
+	return context.WithValue(ctx, connThrottleKey{}, &connThrottle{})
+}
+
+// take draws n bytes from the budget and returns how long to wait until it isn't overdrawn anymore.
+func (t *connThrottle) take(rate int64, n int) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if t.updated.IsZero() {
+		t.tokens = float64(rate)
+	} else {
+		t.tokens = min(float64(rate), t.tokens+now.Sub(t.updated).Seconds()*float64(rate))
+	}
+	t.updated = now
+
+	t.tokens -= float64(n)
+	if t.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-t.tokens / float64(rate) * float64(time.Second))
+}
+
+// throttledReader reads a request body at most at the upload rate of its connection.
+// Not reading faster fills the TCP window, which slows the client down.
+type throttledReader struct {
+	io.ReadCloser
+	ctx      context.Context
+	throttle *connThrottle
+	rate     int64 // Bytes per second.
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	// Reads are bounded to a second of the rate, so that the budget isn't overdrawn by much.
+	if int64(len(p)) > r.rate {
+		p = p[:r.rate]
+	}
+
+	n, err := r.ReadCloser.Read(p)
+
+	if wait := r.throttle.take(r.rate, n); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-r.ctx.Done():
+			return n, fmt.Errorf("could not read request body: %w", r.ctx.Err())
+		}
+	}
+	return n, err
+}
+
+// throttleUpload limits the rate at which the uploaded files are read from each connection, so that
+// a client saturating the uplink doesn't starve the other clients and the health checks.
+func (h *Handlers) throttleUpload(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.uploadRate <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Requests served without a connection of the server, e.g. by httptest, are throttled on their own.
+		throttle, ok := r.Context().Value(connThrottleKey{}).(*connThrottle)
+		if !ok {
+			throttle = &connThrottle{}
+		}
+
+		r.Body = &throttledReader{ReadCloser: r.Body, ctx: r.Context(), throttle: throttle, rate: h.uploadRate}
+		next.ServeHTTP(w, r)
+	})
+}
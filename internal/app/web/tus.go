@@ -0,0 +1,398 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/retention"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+	"github.com/alesr/videoscriber/internal/pkg/uploads"
+	"github.com/go-chi/chi/v5"
+)
+
+// Resumable uploads follow the core protocol of tus 1.0.0 with the creation, expiration and termination
+// extensions, see https://tus.io/protocols/resumable-upload. Once an upload completes, its file is transcribed
+// like a regular upload, with the options given in the Upload-Metadata header.
+const (
+	tusVersion    string = "1.0.0"
+	tusExtensions string = "creation,expiration,termination"
+
+	// jobIDHeader is set on completed uploads to the job processing them.
+	jobIDHeader string = "Videoscriber-Job-Id"
+)
+
+// tusOptions describes the supported protocol version, extensions and maximum size.
+func (h *Handlers) tusOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.uploads.MaxSize(), 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tusResumable rejects the requests of clients using another version of the protocol.
+func (h *Handlers) tusResumable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+
+		if r.Method != http.MethodOptions && r.Header.Get("Tus-Resumable") != tusVersion {
+			w.Header().Set("Tus-Version", tusVersion)
+			h.e(w, "Unsupported tus version", nil, http.StatusPreconditionFailed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// createUpload starts a resumable upload.
+func (h *Handlers) createUpload(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		h.e(w, "Invalid Upload-Length", err, http.StatusBadRequest)
+		return
+	}
+
+	if length > h.uploads.MaxSize() {
+		h.e(w, "The upload exceeds the maximum size", nil, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		h.e(w, "Invalid Upload-Metadata", err, http.StatusBadRequest)
+		return
+	}
+
+	// The options are validated now rather than once the whole file is uploaded.
+	if _, err := h.uploadInput(metadata); err != nil {
+		h.e(w, "Invalid Upload-Metadata: "+err.Error(), nil, http.StatusBadRequest)
+		return
+	}
+
+	if project := metadata["project"]; project != "" && !canAccessProject(r, project) {
+		h.e(w, "Access to the project is not allowed", nil, http.StatusForbidden)
+		return
+	}
+
+	// tus clients send the MIME type of the file as the filetype metadata.
+	if err := validateMedia(metadata["filename"], metadata["filetype"]); err != nil {
+		h.multipartError(w, err)
+		return
+	}
+
+	upload, err := h.uploads.Create(owner(r), length, metadata)
+	if err != nil {
+		h.e(w, "Failed to create upload", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", h.link("/files/"+upload.ID))
+	setUploadExpires(w, upload)
+	w.WriteHeader(http.StatusCreated)
+}
+
+type uploadState struct {
+	uploads.Upload
+	Location string `json:"location"` // URL to resume the upload from, or to abandon it with a DELETE.
+}
+
+// listUploads lists the incomplete uploads of the caller with their offset and expiry,
+// so that clients can resume or abandon stalled transfers, e.g. after a restart.
+func (h *Handlers) listUploads(w http.ResponseWriter, r *http.Request) {
+	list, err := h.uploads.List(owner(r))
+	if err != nil {
+		h.e(w, "Failed to list uploads", err, http.StatusInternalServerError)
+		return
+	}
+
+	states := make([]uploadState, 0, len(list))
+	for _, u := range list {
+		states = append(states, uploadState{Upload: u, Location: h.link("/files/" + u.ID)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := json.NewEncoder(w).Encode(struct {
+		Uploads []uploadState `json:"uploads"`
+	}{states}); err != nil {
+		h.logger.Error("Could not encode response", slog.String("error", err.Error()))
+	}
+}
+
+// setUploadExpires tells when the incomplete upload expires unless resumed.
+func setUploadExpires(w http.ResponseWriter, upload uploads.Upload) {
+	if !upload.ExpiresAt.IsZero() {
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// uploadOffset reports how many bytes of the upload were received, so that the client can resume it.
+func (h *Handlers) uploadOffset(w http.ResponseWriter, r *http.Request) {
+	upload, ok := h.ownedUpload(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+
+	if upload.JobID != "" {
+		w.Header().Set(jobIDHeader, upload.JobID)
+	}
+	setUploadExpires(w, upload)
+	w.WriteHeader(http.StatusOK)
+}
+
+// patchUpload appends a chunk to the upload, and starts transcribing the file once complete.
+func (h *Handlers) patchUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		h.e(w, "Content-Type must be application/offset+octet-stream", nil, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		h.e(w, "Invalid Upload-Offset", err, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := h.ownedUpload(w, r); !ok {
+		return
+	}
+
+	upload, err := h.uploads.Append(chi.URLParam(r, "id"), offset, r.Body)
+	if err != nil {
+		h.uploadError(w, err)
+		return
+	}
+
+	if upload.Complete() {
+		jobID, err := h.processUpload(upload)
+		if err != nil {
+			h.e(w, "Failed to start job", err, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(jobIDHeader, jobID)
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	setUploadExpires(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteUpload terminates the upload and removes its data.
+func (h *Handlers) deleteUpload(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.ownedUpload(w, r); !ok {
+		return
+	}
+
+	if err := h.uploads.Remove(chi.URLParam(r, "id")); err != nil {
+		h.uploadError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedUpload returns the upload if it belongs to the caller, otherwise it responds with an error.
+// Uploads of other users are reported as not found.
+func (h *Handlers) ownedUpload(w http.ResponseWriter, r *http.Request) (uploads.Upload, bool) {
+	upload, err := h.uploads.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		h.uploadError(w, err)
+		return uploads.Upload{}, false
+	}
+
+	if upload.Owner != owner(r) {
+		h.uploadError(w, uploads.ErrNotFound)
+		return uploads.Upload{}, false
+	}
+	return upload, true
+}
+
+// processUpload starts the job of a completed upload.
+func (h *Handlers) processUpload(upload uploads.Upload) (string, error) {
+	in, err := h.uploadInput(upload.Metadata)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := h.uploads.Open(upload.ID)
+	if err != nil {
+		return "", fmt.Errorf("could not open upload: %w", err)
+	}
+	defer f.Close()
+
+	in.Data, in.Size, in.Owner = f, upload.Length, upload.Owner
+
+	job, err := h.startJob([]*subtitles.Input{in}, nil, upload.Metadata["callback_url"])
+	if err != nil {
+		return "", err
+	}
+
+	// The file was copied by the job, so the upload data isn't needed anymore.
+	if err := h.uploads.Finish(upload.ID, job.ID); err != nil {
+		return "", fmt.Errorf("could not finish upload: %w", err)
+	}
+	return job.ID, nil
+}
+
+// uploadInput returns the input of the options of an upload, without data:
+// filename (required), language, project, format, provider, anonymize, timeline, chapters, profile, keep_video and debug.
+// The callback_url of the job is validated too.
+func (h *Handlers) uploadInput(metadata map[string]string) (*subtitles.Input, error) {
+	fileName := metadata["filename"]
+	if fileName == "" {
+		return nil, errors.New("filename is required")
+	}
+
+	language := metadata["language"]
+	if language == "" {
+		language = subtitles.DefaultLanguage
+	}
+
+	if !subtitles.SupportedLanguage(language) {
+		return nil, fmt.Errorf("unsupported language %q", language)
+	}
+
+	if project := metadata["project"]; project != "" {
+		if err := retention.ValidateProject(project); err != nil {
+			return nil, errors.New("invalid project name")
+		}
+	}
+
+	if provider := metadata["provider"]; provider != "" && !h.subtitler.HasProvider(provider) {
+		return nil, fmt.Errorf("unknown provider %q", provider)
+	}
+
+	format, err := subtitles.ParseFormat(metadata["format"])
+	if err != nil {
+		return nil, errors.New("invalid format")
+	}
+
+	var anonymize bool
+	if v := metadata["anonymize"]; v != "" {
+		if anonymize, err = strconv.ParseBool(v); err != nil {
+			return nil, errors.New("invalid anonymize value")
+		}
+	}
+
+	var timeline bool
+	if v := metadata["timeline"]; v != "" {
+		if timeline, err = strconv.ParseBool(v); err != nil {
+			return nil, errors.New("invalid timeline value")
+		}
+	}
+
+	var chapters bool
+	if v := metadata["chapters"]; v != "" {
+		if chapters, err = strconv.ParseBool(v); err != nil {
+			return nil, errors.New("invalid chapters value")
+		}
+	}
+
+	var keepVideo bool
+	if v := metadata["keep_video"]; v != "" {
+		if keepVideo, err = strconv.ParseBool(v); err != nil {
+			return nil, errors.New("invalid keep_video value")
+		}
+	}
+
+	var debug bool
+	if v := metadata["debug"]; v != "" {
+		if debug, err = strconv.ParseBool(v); err != nil {
+			return nil, errors.New("invalid debug value")
+		}
+	}
+
+	if err := h.validateCallback(metadata["callback_url"]); err != nil {
+		return nil, errors.New("invalid callback_url value")
+	}
+
+	profile, err := subtitles.ParseProfile(metadata["profile"])
+	if err != nil {
+		return nil, errors.New("invalid profile")
+	}
+
+	if err := validatePrompt(metadata["prompt"]); err != nil {
+		return nil, errors.New("invalid prompt")
+	}
+
+	preprocess, err := subtitles.ParseFilters(metadata["preprocess"])
+	if err != nil {
+		return nil, errors.New("invalid preprocess value")
+	}
+
+	task, err := subtitles.ParseTask(metadata["task"])
+	if err != nil {
+		return nil, errors.New("invalid task")
+	}
+
+	translations, err := subtitles.ParseLanguages(metadata["translate_to"])
+	if err != nil {
+		return nil, errors.New("invalid translate_to value")
+	}
+
+	return &subtitles.Input{
+		FileName:     fileName,
+		Language:     language,
+		Project:      metadata["project"],
+		Provider:     metadata["provider"],
+		Format:       format,
+		Anonymize:    anonymize,
+		Timeline:     timeline,
+		Chapters:     chapters,
+		Profile:      profile,
+		Preprocess:   preprocess,
+		Prompt:       metadata["prompt"],
+		Task:         task,
+		Translations: translations,
+		KeepVideo:    keepVideo,
+		Debug:        debug,
+	}, nil
+}
+
+func (h *Handlers) uploadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, uploads.ErrNotFound):
+		h.e(w, "Upload not found", err, http.StatusNotFound)
+	case errors.Is(err, uploads.ErrOffsetMismatch):
+		h.e(w, "Upload-Offset does not match the offset of the upload", err, http.StatusConflict)
+	case errors.Is(err, uploads.ErrBusy):
+		h.e(w, "Another chunk of the upload is being written", err, http.StatusLocked)
+	case errors.Is(err, uploads.ErrCompleted):
+		h.e(w, "The upload is completed", err, http.StatusForbidden)
+	case errors.Is(err, uploads.ErrTooLarge):
+		h.e(w, "The chunk exceeds the length of the upload", err, http.StatusRequestEntityTooLarge)
+	default:
+		h.e(w, "Failed to write upload", err, http.StatusInternalServerError)
+	}
+}
+
+// parseUploadMetadata parses the Upload-Metadata header: comma-separated keys and base64 encoded values.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode metadata %q: %w", key, err)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// uiFiles are the pages and assets of the web UI, which uploads files and manages the subtitles
+// through the REST API, so that the server is usable from a browser.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// uiAssets serves the assets of the web UI under /ui/.
+var uiAssets = http.StripPrefix("/ui/", http.FileServer(http.FS(mustSub(uiFiles, "ui"))))
+
+// ui serves the page of the web UI.
+func (h *Handlers) ui(w http.ResponseWriter, r *http.Request) {
+	h.uiPage(w, "ui/index.html")
+}
+
+// editor serves the subtitle editor of the web UI, which edits the cues of the subtitle named by the
+// name query parameter, playing its video or audio when kept.
+func (h *Handlers) editor(w http.ResponseWriter, r *http.Request) {
+	h.uiPage(w, "ui/editor.html")
+}
+
+func (h *Handlers) uiPage(w http.ResponseWriter, name string) {
+	page, err := uiFiles.ReadFile(name)
+	if err != nil {
+		h.e(w, "Failed to read the page", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// uiAsset serves a script or stylesheet of the web UI.
+func (h *Handlers) uiAsset(w http.ResponseWriter, r *http.Request) {
+	uiAssets.ServeHTTP(w, r)
+}
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,253 @@
+// The web UI of the server: uploads files with drag and drop, follows their jobs,
+// and lists the subtitles with edit, download and delete buttons. It only uses the REST API.
+(function () {
+  "use strict";
+
+  var keyInput = document.getElementById("key");
+  var form = document.getElementById("upload");
+  var drop = document.getElementById("drop");
+  var fileInput = document.getElementById("files");
+  var chosen = document.getElementById("chosen");
+  var submit = form.querySelector("button[type=submit]");
+  var status = document.getElementById("status");
+  var jobList = document.getElementById("jobs");
+  var subtitleRows = document.getElementById("subtitles");
+
+  var files = [];
+
+  keyInput.value = localStorage.getItem("videoscriber.key") || "";
+  keyInput.addEventListener("change", function () {
+    localStorage.setItem("videoscriber.key", keyInput.value);
+    loadSubtitles();
+  });
+
+  function headers() {
+    return keyInput.value ? { Authorization: "Bearer " + keyInput.value } : {};
+  }
+
+  // api sends a request to the REST API, rejecting with the plain text error of the server.
+  function api(method, path) {
+    return fetch(path, { method: method, headers: headers() }).then(function (resp) {
+      if (resp.ok) return resp;
+      return resp.text().then(function (text) {
+        throw new Error(text.trim() || resp.status + " " + resp.statusText);
+      });
+    });
+  }
+
+  function choose(list) {
+    files = Array.prototype.slice.call(list);
+    chosen.textContent = "";
+    files.forEach(function (f) {
+      var item = document.createElement("li");
+      item.textContent = f.name + " (" + size(f.size) + ")";
+      chosen.appendChild(item);
+    });
+    submit.disabled = files.length === 0;
+  }
+
+  drop.addEventListener("click", function () { fileInput.click(); });
+  drop.addEventListener("keydown", function (e) {
+    if (e.key === "Enter" || e.key === " ") fileInput.click();
+  });
+  fileInput.addEventListener("change", function () { choose(fileInput.files); });
+
+  ["dragenter", "dragover"].forEach(function (type) {
+    drop.addEventListener(type, function (e) {
+      e.preventDefault();
+      drop.classList.add("over");
+    });
+  });
+  ["dragleave", "drop"].forEach(function (type) {
+    drop.addEventListener(type, function (e) {
+      e.preventDefault();
+      drop.classList.remove("over");
+    });
+  });
+  drop.addEventListener("drop", function (e) { choose(e.dataTransfer.files); });
+
+  // The upload goes through XMLHttpRequest, since fetch doesn't report the progress of request bodies.
+  form.addEventListener("submit", function (e) {
+    e.preventDefault();
+
+    var data = new FormData();
+    files.forEach(function (f) { data.append("file", f, f.name); });
+    ["project", "language", "format"].forEach(function (name) {
+      var v = form.elements[name].value;
+      if (v) data.append(name, v);
+    });
+
+    var item = document.createElement("li");
+    var label = document.createElement("span");
+    var bar = document.createElement("progress");
+    bar.max = 1;
+    bar.value = 0;
+    label.textContent = "Uploading " + files.map(function (f) { return f.name; }).join(", ") + " ";
+    item.appendChild(label);
+    item.appendChild(bar);
+    jobList.insertBefore(item, jobList.firstChild);
+
+    submit.disabled = true;
+    status.textContent = "";
+
+    var xhr = new XMLHttpRequest();
+    xhr.open("POST", "upload");
+    var h = headers();
+    Object.keys(h).forEach(function (name) { xhr.setRequestHeader(name, h[name]); });
+
+    xhr.upload.addEventListener("progress", function (e) {
+      if (e.lengthComputable) bar.value = e.loaded / e.total;
+    });
+
+    xhr.addEventListener("load", function () {
+      submit.disabled = files.length === 0;
+
+      var resp;
+      try {
+        resp = JSON.parse(xhr.responseText);
+      } catch (err) {
+        fail(item, xhr.responseText.trim() || xhr.status + " " + xhr.statusText);
+        return;
+      }
+
+      (resp.failed || []).forEach(function (f) {
+        var rejected = document.createElement("div");
+        rejected.className = "failed";
+        rejected.textContent = f.name + ": " + f.error;
+        item.appendChild(rejected);
+      });
+
+      if (!resp.job_id) {
+        fail(item, resp.message);
+        return;
+      }
+      follow(item, resp.job_id);
+    });
+
+    xhr.addEventListener("error", function () {
+      submit.disabled = files.length === 0;
+      fail(item, "The upload failed");
+    });
+
+    xhr.send(data);
+  });
+
+  function fail(item, message) {
+    item.className = "failed";
+    item.textContent = message;
+  }
+
+  // follow polls the job until it finishes, showing the state and progress of its files.
+  function follow(item, jobID) {
+    api("GET", "jobs/" + encodeURIComponent(jobID))
+      .then(function (resp) { return resp.json(); })
+      .then(function (job) {
+        item.textContent = "";
+
+        job.files.forEach(function (f) {
+          var row = document.createElement("div");
+          row.className = f.state;
+          row.textContent = f.name + ": " + f.state + (f.error ? " (" + f.error + ")" : "") + " ";
+
+          if (f.state !== "done" && f.state !== "failed") {
+            var bar = document.createElement("progress");
+            bar.max = 1;
+            bar.value = f.progress || 0;
+            row.appendChild(bar);
+          }
+          item.appendChild(row);
+        });
+
+        if (job.state === "done" || job.state === "failed") {
+          loadSubtitles();
+          return;
+        }
+        setTimeout(function () { follow(item, jobID); }, 1000);
+      })
+      .catch(function (err) { fail(item, err.message); });
+  }
+
+  function loadSubtitles() {
+    api("GET", "subtitles")
+      .then(function (resp) { return resp.json(); })
+      .then(function (list) {
+        status.className = "";
+        status.textContent = "";
+        subtitleRows.textContent = "";
+
+        (list.items || []).forEach(function (sub) {
+          var row = document.createElement("tr");
+          [sub.name, sub.project, sub.language, size(sub.size), new Date(sub.created_at).toLocaleString()].forEach(function (text) {
+            var cell = document.createElement("td");
+            cell.textContent = text;
+            row.appendChild(cell);
+          });
+
+          var actions = document.createElement("td");
+          if (/\.(srt|vtt)$/i.test(sub.name)) {
+            actions.appendChild(button("Edit", "secondary", function () {
+              location.href = "editor?" + new URLSearchParams({ name: sub.name, project: sub.project, lang: sub.language });
+            }));
+            actions.appendChild(document.createTextNode(" "));
+          }
+          actions.appendChild(button("Download", "secondary", function () { download(sub); }));
+          actions.appendChild(document.createTextNode(" "));
+          actions.appendChild(button("Delete", "danger", function () { remove(sub); }));
+          row.appendChild(actions);
+
+          subtitleRows.appendChild(row);
+        });
+      })
+      .catch(function (err) { showError(err); });
+  }
+
+  // subtitlePath is the path of the subtitle, with the project and language telling apart those of the same name.
+  function subtitlePath(sub) {
+    return "subtitles/" + encodeURIComponent(sub.name) + "?" + new URLSearchParams({ project: sub.project, lang: sub.language });
+  }
+
+  // download fetches the subtitle with the API key, which links can't send, and saves it.
+  function download(sub) {
+    api("GET", subtitlePath(sub))
+      .then(function (resp) { return resp.blob(); })
+      .then(function (blob) {
+        var a = document.createElement("a");
+        a.href = URL.createObjectURL(blob);
+        a.download = sub.name;
+        a.click();
+        setTimeout(function () { URL.revokeObjectURL(a.href); }, 1000);
+      })
+      .catch(function (err) { showError(err); });
+  }
+
+  function remove(sub) {
+    if (!confirm("Delete " + sub.name + "?")) return;
+
+    api("DELETE", subtitlePath(sub))
+      .then(loadSubtitles)
+      .catch(function (err) { showError(err); });
+  }
+
+  function button(text, className, onClick) {
+    var b = document.createElement("button");
+    b.type = "button";
+    b.className = className;
+    b.textContent = text;
+    b.addEventListener("click", onClick);
+    return b;
+  }
+
+  function showError(err) {
+    status.className = "error";
+    status.textContent = err.message;
+  }
+
+  function size(bytes) {
+    if (bytes < 1024) return bytes + " B";
+    if (bytes < 1024 * 1024) return (bytes / 1024).toFixed(1) + " KB";
+    return (bytes / 1024 / 1024).toFixed(1) + " MB";
+  }
+
+  document.getElementById("refresh").addEventListener("click", loadSubtitles);
+  loadSubtitles();
+})();
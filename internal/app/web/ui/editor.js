@@ -0,0 +1,226 @@
+// The subtitle editor of the web UI: loads the cues of a subtitle, edits their text, speaker and timing
+// inline, and saves them back. When the video or the audio of the subtitle was kept, it plays it and
+// highlights the cue being played, seeking to a cue when its number is clicked. It only uses the REST API.
+(function () {
+  "use strict";
+
+  var keyInput = document.getElementById("key");
+  var nameLabel = document.getElementById("name");
+  var media = document.getElementById("media");
+  var player = document.getElementById("player");
+  var follow = document.getElementById("follow");
+  var saveButton = document.getElementById("save");
+  var revertButton = document.getElementById("revert");
+  var status = document.getElementById("status");
+  var cueRows = document.getElementById("cues");
+
+  var params = new URLSearchParams(location.search);
+  var name = params.get("name") || "";
+  var path = "subtitles/" + encodeURIComponent(name);
+  // The project and language tell apart the subtitles of the same name.
+  var scope = "?" + new URLSearchParams({ project: params.get("project") || "", lang: params.get("lang") || "" });
+  var rows = [];
+  var current = null;
+  var dirty = false;
+
+  keyInput.value = localStorage.getItem("videoscriber.key") || "";
+  keyInput.addEventListener("change", function () {
+    localStorage.setItem("videoscriber.key", keyInput.value);
+    load();
+  });
+
+  function headers() {
+    return keyInput.value ? { Authorization: "Bearer " + keyInput.value } : {};
+  }
+
+  // api sends a request to the REST API, rejecting with the plain text error of the server.
+  function api(method, path, body) {
+    var h = headers();
+    if (body !== undefined) h["Content-Type"] = "application/json";
+
+    return fetch(path, {
+      method: method,
+      headers: h,
+      body: body === undefined ? undefined : JSON.stringify(body)
+    }).then(function (resp) {
+      if (resp.ok) return resp;
+      return resp.text().then(function (text) {
+        throw new Error(text.trim() || resp.status + " " + resp.statusText);
+      });
+    });
+  }
+
+  function load() {
+    api("GET", path + "/cues" + scope)
+      .then(function (resp) { return resp.json(); })
+      .then(function (sub) {
+        render(sub.cues);
+        setDirty(false);
+        showStatus("", "");
+        loadMedia(["video", "audio"]);
+      })
+      .catch(function (err) { showStatus(err.message, "error"); });
+  }
+
+  function render(cues) {
+    cueRows.textContent = "";
+    rows = [];
+    current = null;
+
+    cues.forEach(function (cue, i) {
+      var row = document.createElement("tr");
+
+      var seek = document.createElement("button");
+      seek.type = "button";
+      seek.className = "link";
+      seek.textContent = i + 1;
+      seek.title = "Play from this cue";
+      seek.addEventListener("click", function () {
+        if (media.hidden) return;
+        player.currentTime = Number(start.value);
+        player.play();
+      });
+
+      var start = timeInput(cue.start);
+      var end = timeInput(cue.end);
+
+      var speaker = document.createElement("input");
+      speaker.value = cue.speaker || "";
+      speaker.size = 10;
+
+      var text = document.createElement("textarea");
+      text.value = cue.text;
+      text.rows = Math.max(1, cue.text.split("\n").length);
+
+      [seek, start, end, speaker, text].forEach(function (el) {
+        var cell = document.createElement("td");
+        cell.appendChild(el);
+        row.appendChild(cell);
+      });
+
+      cueRows.appendChild(row);
+      rows.push({ row: row, start: start, end: end, speaker: speaker, text: text });
+    });
+  }
+
+  function timeInput(seconds) {
+    var input = document.createElement("input");
+    input.type = "number";
+    input.min = 0;
+    input.step = 0.001;
+    input.className = "time";
+    input.value = seconds.toFixed(3);
+    return input;
+  }
+
+  cueRows.addEventListener("input", function () { setDirty(true); });
+
+  function setDirty(d) {
+    dirty = d;
+    saveButton.disabled = !d;
+    revertButton.disabled = !d;
+  }
+
+  function save() {
+    var cues = rows.map(function (r) {
+      return {
+        start: Number(r.start.value),
+        end: Number(r.end.value),
+        speaker: r.speaker.value.trim(),
+        text: r.text.value.trim()
+      };
+    });
+
+    saveButton.disabled = true;
+    showStatus("Saving…", "");
+
+    api("PUT", path + "/cues" + scope, { cues: cues })
+      .then(function () {
+        setDirty(false);
+        showStatus("Saved", "done");
+      })
+      .catch(function (err) {
+        saveButton.disabled = false;
+        showStatus(err.message, "error");
+      });
+  }
+
+  saveButton.addEventListener("click", save);
+  revertButton.addEventListener("click", function () {
+    if (confirm("Discard the changes?")) load();
+  });
+
+  document.addEventListener("keydown", function (e) {
+    if ((e.ctrlKey || e.metaKey) && e.key === "s") {
+      e.preventDefault();
+      if (dirty) save();
+    }
+  });
+
+  window.addEventListener("beforeunload", function (e) {
+    if (dirty) e.preventDefault();
+  });
+
+  // loadMedia plays the first kept media of the subtitle. Media elements can't send the API key,
+  // so with a key the media is fetched whole, like downloads, instead of being streamed.
+  function loadMedia(kinds) {
+    if (kinds.length === 0) {
+      media.hidden = true;
+      return;
+    }
+
+    var url = path + "/" + kinds[0] + scope;
+    var next = function () { loadMedia(kinds.slice(1)); };
+
+    if (!keyInput.value) {
+      player.onerror = next;
+      player.src = url;
+      media.hidden = false;
+      return;
+    }
+
+    api("GET", url)
+      .then(function (resp) { return resp.blob(); })
+      .then(function (blob) {
+        if (player.src.indexOf("blob:") === 0) URL.revokeObjectURL(player.src);
+        player.onerror = null;
+        player.src = URL.createObjectURL(blob);
+        media.hidden = false;
+      })
+      .catch(next);
+  }
+
+  // The cue being played is highlighted, and scrolled to while following the playback.
+  player.addEventListener("timeupdate", function () {
+    var t = player.currentTime;
+    var playing = null;
+
+    rows.forEach(function (r) {
+      if (t >= Number(r.start.value) && t < Number(r.end.value)) playing = r;
+    });
+
+    if (playing === current) return;
+    if (current) current.row.classList.remove("playing");
+    current = playing;
+
+    if (!current) return;
+    current.row.classList.add("playing");
+    if (follow.checked && !current.row.contains(document.activeElement)) {
+      current.row.scrollIntoView({ block: "nearest" });
+    }
+  });
+
+  function showStatus(message, className) {
+    status.className = className;
+    status.textContent = message;
+  }
+
+  if (!name) {
+    showStatus("No subtitle to edit, open the editor from the list of subtitles", "error");
+    return;
+  }
+
+  nameLabel.textContent = name;
+  document.title = name + " - videoscriber editor";
+  load();
+})();
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/users"
+	"github.com/go-chi/chi/v5"
+)
+
+// requireProject forbids the callers who can't access the project of the path.
+func (h *Handlers) requireProject(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !canAccessProject(r, chi.URLParam(r, "project")) {
+			h.e(w, "Access to the project is not allowed", nil, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// listUsers lists the users seen through their API key or the SSO proxy.
+func (h *Handlers) listUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(h.users.List()); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handlers) getUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.users.Get(chi.URLParam(r, "name"))
+	if err != nil {
+		if errors.Is(err, users.ErrNotFound) {
+			h.e(w, "User not found", err, http.StatusNotFound)
+			return
+		}
+		h.e(w, "Failed to get user", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
+
+type updateUserRequest struct {
+	Role     users.Role `json:"role"`
+	Projects []string   `json:"projects"` // All of them when null.
+}
+
+// updateUser sets the role of a user and the projects it can access. Users are only known once seen,
+// e.g. once they signed in through the SSO proxy.
+func (h *Handlers) updateUser(w http.ResponseWriter, r *http.Request) {
+	var req updateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.e(w, "Failed to decode the request", err, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.users.Set(chi.URLParam(r, "name"), req.Role, req.Projects)
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrNotFound):
+			h.e(w, "User not found", err, http.StatusNotFound)
+		case errors.Is(err, users.ErrInvalidUser):
+			h.e(w, err.Error(), err, http.StatusBadRequest)
+		default:
+			h.e(w, "Failed to update user", err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	projects := "*"
+	if user.Projects != nil {
+		projects = strings.Join(user.Projects, ",")
+	}
+
+	h.record(audit.Entry{
+		Action:  "user.update",
+		Subject: user.Name,
+		Actor:   owner(r),
+		Address: r.RemoteAddr,
+		Details: map[string]string{"role": string(user.Role), "projects": projects},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		h.e(w, "Failed to encode response", err, http.StatusInternalServerError)
+		return
+	}
+}
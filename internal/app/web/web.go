@@ -7,36 +7,163 @@ import (
 	"net"
 	"net/http"
 
+	"github.com/alesr/videoscriber/internal/app/grpc/videoscriberv1"
+	"github.com/alesr/videoscriber/internal/pkg/features"
 	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc"
 )
 
 // App is the web application.
 type App struct {
-	logger *slog.Logger
-	srv    *http.Server
-	port   string
+	logger   *slog.Logger
+	srv      *http.Server
+	port     string
+	handlers *Handlers
+
+	// The gRPC API is served on its own port, when set.
+	grpcSrv  *grpc.Server
+	grpcPort string
 }
 
-// NewApp creates a new web app.
-func NewApp(logger *slog.Logger, port string, router chi.Router, h *Handlers) *App {
+// NewApp creates a new web app, serving the gRPC API on the gRPC port unless empty.
+func NewApp(logger *slog.Logger, port, grpcPort string, router chi.Router, h *Handlers) *App {
 	router.Route("/", func(r chi.Router) {
-		r.Post("/upload", h.createSubtitles)
-		r.Get("/subtitles", h.listSubtitles)
-		r.Get("/subtitles/{name}", h.subtitleFile)
-		r.Get("/subtitles/zip", h.subtitlesZip)
-		r.Delete("/subtitles/{name}", h.deleteSubtitle)
+		// Public pages and integrations verifying their own requests.
+		r.Get("/", h.ui)
+		r.Get("/editor", h.editor)
+		r.Get("/ui/*", h.uiAsset)
+		r.Get("/share/{token}", h.sharedSubtitle)
+		r.Get("/embed/{token}", h.embed)
+		r.Get("/embed/{token}/captions.vtt", h.embedCaptions)
+		r.Get("/widget.js", h.widget)
+		r.Get("/healthz", h.healthz)
+		r.Get("/readyz", h.readyz)
+		r.Get("/openapi.json", h.openAPI)
+		r.Get("/docs", h.apiDocs)
+		r.Get("/signing-key", h.signingKey)
+		r.With(h.rateLimit).Post("/verify", h.verifySignature)
+		r.Post("/slack/commands", h.slackCommand)
+		r.Post("/slack/events", h.slackEvents)
+		r.Post("/email/inbound", h.inboundEmail)
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.authenticate, h.rateLimit)
+
+			r.With(h.enforceQuota, h.limitJobs, h.acceptJobs, h.throttleUpload).Post("/upload", h.createSubtitles)
+			r.With(h.enforceQuota, h.limitJobs, h.acceptJobs).Post("/transcribe-url", h.transcribeURL)
+			r.With(h.enforceQuota, h.limitJobs, h.throttleUpload).Post("/transcribe", h.transcribe)
+			r.Route("/files", func(r chi.Router) {
+				r.Use(h.tusResumable)
+				r.Options("/", h.tusOptions)
+				r.With(h.enforceQuota, h.limitJobs, h.acceptJobs).Post("/", h.createUpload)
+				r.Head("/{id}", h.uploadOffset)
+				r.With(h.throttleUpload).Patch("/{id}", h.patchUpload)
+				r.Delete("/{id}", h.deleteUpload)
+			})
+			r.Get("/uploads", h.listUploads)
+			r.With(h.enforceQuota, h.limitJobs, h.throttleUpload).Post("/compare", h.compareProviders)
+			r.Post("/evaluate", h.evaluateSubtitle)
+			r.Group(func(r chi.Router) {
+				r.Use(h.requireFeature(features.FlagClips))
+				r.Post("/clips", h.createClips)
+				r.Get("/clips/{id}/{name}", h.clipFile)
+				r.Post("/render", h.renderVideo)
+			})
+			r.Get("/subtitles", h.listSubtitles)
+			r.Get("/subtitles/{name}", h.subtitleFile)
+			r.Get("/subtitles/zip", h.subtitlesZip)
+			r.Get("/search", h.search)
+			r.Delete("/subtitles/{name}", h.deleteSubtitle)
+			r.Post("/subtitles/{name}/publish/{platform}", h.publishSubtitle)
+			r.With(h.requireStorage).Post("/subtitles/{name}/split", h.splitSubtitle)
+			r.With(h.requireStorage).Post("/subtitles/{name}/shift", h.shiftSubtitle)
+			r.Get("/subtitles/{name}/cues", h.subtitleCues)
+			r.With(h.requireStorage).Put("/subtitles/{name}/cues", h.replaceSubtitleCues)
+			r.Get("/subtitles/{name}/chapters", h.subtitleChapters)
+			r.Get("/subtitles/{name}/highlights", h.subtitleHighlights)
+			r.Get("/subtitles/{name}/audio", h.subtitleAudio)
+			r.Get("/subtitles/{name}/video", h.subtitleVideo)
+			r.Post("/subtitles/{name}/share", h.shareSubtitle)
+			r.With(h.enforceQuota, h.limitJobs, h.acceptJobs).Post("/subtitles/{name}/reprocess", h.reprocessSubtitle)
+			r.Delete("/shares/{token}", h.revokeShare)
+			r.Get("/reviews", h.listReviews)
+			r.Post("/reviews/{name}/claim", h.claimReview)
+			r.Put("/reviews/{name}/assignee", h.assignReview)
+			r.Get("/reviews/{name}/comments", h.listReviewComments)
+			r.Post("/reviews/{name}/comments", h.commentReview)
+			r.Get("/reviews/{name}/cues", h.reviewCues)
+			r.With(h.requireStorage).Put("/reviews/{name}/cues", h.reviseReview)
+			r.Post("/reviews/{name}/approve", h.approveReview)
+			r.Get("/usage", h.usage)
+			r.Get("/jobs/export", h.exportJobs)
+			r.Get("/jobs/{id}", h.getJob)
+			r.Get("/jobs/{id}/events", h.jobEvents)
+			r.Get("/ws", h.websocket)
+			r.Get("/styles", h.listStyles)
+			r.Get("/styles/{name}", h.getStyle)
+			r.Put("/styles/{name}", h.putStyle)
+			r.Delete("/styles/{name}", h.deleteStyle)
+			r.With(h.requireProject).Get("/projects/{project}/retention", h.getRetention)
+			r.With(h.requireProject).Put("/projects/{project}/retention", h.setRetention)
+			r.With(h.requireProject).Put("/projects/{project}/credentials/{platform}", h.setCredentials)
+			r.With(h.requireProject).Post("/projects/{project}/ask", h.askProject)
+			r.With(h.requireProject, h.requireStorage).Post("/projects/{project}/terminology", h.checkTerminology)
+
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(h.requireAdmin)
+				r.Get("/models", h.listModels)
+				r.Put("/models/{name}", h.downloadModel)
+				r.Delete("/models/{name}", h.deleteModel)
+				r.Put("/models/{name}/pin", h.pinModel)
+				r.Delete("/models/{name}/pin", h.unpinModel)
+				r.Get("/features", h.listFeatures)
+				r.Put("/features/{name}", h.toggleFeature)
+				r.Get("/janitor", h.janitorStats)
+				r.With(h.requireStorage).Post("/backfill", h.backfillSubtitles)
+				r.Get("/gc", h.gcReport)
+				r.With(h.requireStorage).Post("/gc", h.collectGarbage)
+				r.Get("/plans", h.listPlans)
+				r.Get("/plans/{name}", h.getPlan)
+				r.Put("/plans/{name}", h.putPlan)
+				r.Delete("/plans/{name}", h.deletePlan)
+				r.Get("/tenants", h.listTenants)
+				r.Put("/tenants/{tenant}/plan", h.assignPlan)
+				r.Delete("/tenants/{tenant}/plan", h.unassignPlan)
+				r.Get("/users", h.listUsers)
+				r.Get("/users/{name}", h.getUser)
+				r.Put("/users/{name}", h.updateUser)
+			})
+		})
 	})
 
+	grpcSrv := grpc.NewServer()
+	videoscriberv1.RegisterVideoscriberServer(grpcSrv, &grpcServer{handler: router, jobs: h.jobs})
+
 	return &App{
 		logger: logger,
 		srv: &http.Server{
-			Addr:    net.JoinHostPort("", port),
-			Handler: router,
+			Addr:        net.JoinHostPort("", port),
+			Handler:     router,
+			ConnContext: withConnThrottle,
 		},
-		port: port,
+		port:     port,
+		handlers: h,
+		grpcSrv:  grpcSrv,
+		grpcPort: grpcPort,
 	}
 }
 
+// NewTestApp creates a web app that doesn't listen on a port, for integration tests serving its Handler
+// with httptest. Stop drains its running jobs like those of a started app.
+func NewTestApp(logger *slog.Logger, h *Handlers) *App {
+	return NewApp(logger, "", "", chi.NewRouter(), h)
+}
+
+// Handler returns the handler of the routes of the web app, e.g. for httptest.NewServer.
+func (s *App) Handler() http.Handler {
+	return s.srv.Handler
+}
+
 // Run starts the web server.
 func (s *App) Run() error {
 	s.logger.Info("Starting web app")
@@ -46,15 +173,54 @@ func (s *App) Run() error {
 			s.logger.Error("Could not listen and server", slog.String("error", err.Error()))
 		}
 	}()
+
+	if s.grpcPort == "" {
+		return nil
+	}
+
+	lis, err := net.Listen("tcp", net.JoinHostPort("", s.grpcPort))
+	if err != nil {
+		return fmt.Errorf("could not listen on gRPC port: %w", err)
+	}
+
+	s.logger.Info("Starting gRPC API", slog.String("port", s.grpcPort))
+
+	go func() {
+		if err := s.grpcSrv.Serve(lis); err != nil {
+			s.logger.Error("Could not serve gRPC API", slog.String("error", err.Error()))
+		}
+	}()
 	return nil
 }
 
-// Stop stops the web server.
-func (app *App) Stop() error {
+// Stop stops the web server, then waits for the running jobs to finish until the context is done.
+func (app *App) Stop(ctx context.Context) error {
 	app.logger.Info("Stopping web app")
 
-	if err := app.srv.Shutdown(context.TODO()); err != nil {
+	// Like the HTTP streams, those of the gRPC API are cut once the context is done.
+	grpcStopped := make(chan struct{})
+	go func() {
+		app.grpcSrv.GracefulStop()
+		close(grpcStopped)
+	}()
+	defer func() {
+		select {
+		case <-grpcStopped:
+		case <-ctx.Done():
+			app.grpcSrv.Stop()
+		}
+	}()
+
+	if err := app.srv.Shutdown(ctx); err != nil {
+		// The context is done, so draining cancels the running jobs.
+		app.handlers.Drain(ctx)
 		return fmt.Errorf("could not shutdown server: %w", err)
 	}
+
+	app.logger.Info("Draining running jobs")
+
+	if err := app.handlers.Drain(ctx); err != nil {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,222 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteTimeout time.Duration = 10 * time.Second
+	wsSendBuffer   int           = 64
+)
+
+// wsRequest is a message sent by websocket clients.
+type wsRequest struct {
+	Action string `json:"action"` // "subscribe" or "unsubscribe".
+	JobID  string `json:"job_id"`
+}
+
+// wsFrame is a message pushed to websocket clients.
+type wsFrame struct {
+	Type  string      `json:"type"` // "job", "progress" or "error".
+	Job   *jobs.Job   `json:"job,omitempty"`
+	Event *jobs.Event `json:"event,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+type wsClient struct {
+	send chan wsFrame
+}
+
+// push queues the frame without blocking and reports whether it was queued.
+func (c *wsClient) push(frame wsFrame) bool {
+	select {
+	case c.send <- frame:
+		return true
+	default:
+		return false
+	}
+}
+
+// hub fans out job events to the websocket clients subscribed to them.
+// It holds a single subscription to the job manager per job, shared by all clients.
+type hub struct {
+	logger  *slog.Logger
+	jobs    jobManager
+	mu      sync.Mutex
+	clients map[string]map[*wsClient]struct{}
+}
+
+func newHub(logger *slog.Logger, jobs jobManager) *hub {
+	return &hub{
+		logger:  logger,
+		jobs:    jobs,
+		clients: make(map[string]map[*wsClient]struct{}),
+	}
+}
+
+// subscribe registers the client for the events of the job.
+func (hb *hub) subscribe(jobID string, c *wsClient) error {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+
+	if _, ok := hb.clients[jobID]; ok {
+		hb.clients[jobID][c] = struct{}{}
+		return nil
+	}
+
+	events, unsubscribe, err := hb.jobs.Subscribe(jobID)
+	if err != nil {
+		return err
+	}
+
+	hb.clients[jobID] = map[*wsClient]struct{}{c: {}}
+
+	go hb.forward(jobID, events, unsubscribe)
+	return nil
+}
+
+// unsubscribe removes the client from the job, or from all jobs when jobID is empty.
+func (hb *hub) unsubscribe(jobID string, c *wsClient) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+
+	for id, clients := range hb.clients {
+		if jobID == "" || id == jobID {
+			delete(clients, c)
+		}
+	}
+}
+
+func (hb *hub) forward(jobID string, events <-chan jobs.Event, unsubscribe func()) {
+	defer unsubscribe()
+
+	for e := range events {
+		e := e
+		hb.broadcast(jobID, wsFrame{Type: "progress", Event: &e})
+	}
+
+	// The job finished: push its final state and forget its clients.
+	job, err := hb.jobs.Get(jobID)
+	if err != nil {
+		hb.logger.Error("Could not get job", slog.String("job_id", jobID), slog.String("error", err.Error()))
+	} else {
+		hb.broadcast(jobID, wsFrame{Type: "job", Job: &job})
+	}
+
+	hb.mu.Lock()
+	delete(hb.clients, jobID)
+	hb.mu.Unlock()
+}
+
+func (hb *hub) broadcast(jobID string, frame wsFrame) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+
+	for c := range hb.clients[jobID] {
+		if !c.push(frame) {
+			hb.logger.Warn("Dropping websocket frame for slow client", slog.String("job_id", jobID))
+		}
+	}
+}
+
+// checkOrigin accepts the websockets opened by the pages of the server, and of the allowed origins,
+// e.g. file:// for the Electron app. Browsers send the credentials of the server, e.g. the cookie of
+// the SSO proxy, along websockets opened by any site, which could otherwise follow the jobs of its visitors.
+func (h *Handlers) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // Not a browser.
+	}
+
+	if h.wsOrigins[origin] {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// websocket lets clients subscribe to job IDs and pushes their progress as JSON frames.
+func (h *Handlers) websocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     h.checkOrigin,
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.logger.Error("Could not upgrade to websocket", slog.String("error", err.Error()))
+		return
+	}
+
+	client := &wsClient{send: make(chan wsFrame, wsSendBuffer)}
+
+	done := make(chan struct{})
+	defer func() {
+		h.hub.unsubscribe("", client)
+		close(done)
+		conn.Close()
+	}()
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case frame := <-client.send:
+				conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+
+				if err := conn.WriteJSON(frame); err != nil {
+					h.logger.Error("Could not write websocket frame", slog.String("error", err.Error()))
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var req wsRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				h.logger.Debug("Websocket closed", slog.String("error", err.Error()))
+			}
+			return
+		}
+
+		switch req.Action {
+		case "subscribe":
+			job, err := h.ownedJob(r, req.JobID)
+			if err != nil {
+				client.push(wsFrame{Type: "error", Error: "job not found: " + req.JobID})
+				continue
+			}
+
+			client.push(wsFrame{Type: "job", Job: &job})
+
+			if job.Finished() {
+				continue
+			}
+
+			if err := h.hub.subscribe(req.JobID, client); err != nil {
+				client.push(wsFrame{Type: "error", Error: err.Error()})
+			}
+		case "unsubscribe":
+			h.hub.unsubscribe(req.JobID, client)
+		default:
+			client.push(wsFrame{Type: "error", Error: "unknown action: " + req.Action})
+		}
+	}
+}
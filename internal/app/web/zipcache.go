@@ -0,0 +1,116 @@
+package web
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+)
+
+const (
+	// maxZipCacheEntries bounds the number of cached archives (one per lang/project filter).
+	maxZipCacheEntries int = 16
+
+	// maxZipCacheSize bounds the total size in bytes of the cached archives.
+	// Larger archives are built for each request.
+	maxZipCacheSize int = 256 << 20 // 256MB
+)
+
+// objectOpener opens a stored file, wherever it is stored.
+type objectOpener func(obj storage.Object) (io.ReadSeekCloser, error)
+
+type cachedZip struct {
+	fingerprint string
+	data        []byte
+}
+
+// zipCache keeps the last archive built for each filter. An archive is reused
+// as long as the fingerprint of the files it contains didn't change, so any write,
+// removal or modification in the storage invalidates it.
+type zipCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedZip
+	size    int // Total size of the cached archives.
+}
+
+func newZipCache() *zipCache {
+	return &zipCache{entries: make(map[string]cachedZip)}
+}
+
+// get returns the archive for the objects, reading them with open only when the cached one is stale.
+func (c *zipCache) get(key string, objects []storage.Object, open objectOpener) ([]byte, string, error) {
+	fingerprint := zipFingerprint(objects)
+
+	c.mu.Lock()
+	cached, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && cached.fingerprint == fingerprint {
+		return cached.data, fingerprint, nil
+	}
+
+	data, err := buildZip(objects, open)
+	if err != nil {
+		return nil, "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(data) > maxZipCacheSize {
+		return data, fingerprint, nil
+	}
+
+	if previous, ok := c.entries[key]; ok {
+		delete(c.entries, key)
+		c.size -= len(previous.data)
+	}
+
+	// Arbitrary archives are evicted until the new one fits.
+	for k, e := range c.entries {
+		if len(c.entries) < maxZipCacheEntries && c.size+len(data) <= maxZipCacheSize {
+			break
+		}
+		delete(c.entries, k)
+		c.size -= len(e.data)
+	}
+
+	c.size += len(data)
+	c.entries[key] = cachedZip{
+		fingerprint: fingerprint,
+		data:        data,
+	}
+	return data, fingerprint, nil
+}
+
+func buildZip(objects []storage.Object, open objectOpener) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+
+	zipWritter := zip.NewWriter(buffer)
+	defer zipWritter.Close()
+
+	for _, obj := range objects {
+		if err := addZipEntry(zipWritter, obj, open); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zipWritter.Close(); err != nil {
+		return nil, fmt.Errorf("could not close zip writer: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+func zipFingerprint(objects []storage.Object) string {
+	hash := sha256.New()
+
+	for _, obj := range objects {
+		fmt.Fprintf(hash, "%s\x00%d\x00%d\n", obj.Path, obj.Size, obj.ModTime.UnixNano())
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,156 @@
+package analytics
+
+import (
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alesr/videoscriber/internal/pkg/langid"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+const (
+	// topWords is the number of most frequent words reported.
+	topWords = 20
+
+	// minWordLength excludes short words, mostly fillers, from the most frequent ones.
+	minWordLength = 3
+)
+
+// Analytics describe how a transcript is spoken, for coaching and editorial use. Durations are in seconds.
+type Analytics struct {
+	Duration   float64 `json:"duration"`    // Of the transcribed audio.
+	SpeechTime float64 `json:"speech_time"` // Covered by cues.
+	WordCount  int     `json:"word_count"`
+
+	// SpeakingRate is in words per minute of speech.
+	SpeakingRate float64 `json:"speaking_rate"`
+
+	// LongestSilence is the longest time without cues, including before the first one and after the last one.
+	LongestSilence Silence `json:"longest_silence"`
+
+	// Words are the most frequent words that aren't stopwords, the most frequent first.
+	Words []WordCount `json:"words"`
+
+	// Speakers is the talk time of each speaker, when the transcript is diarized.
+	Speakers []Speaker `json:"speakers,omitempty"`
+}
+
+type Silence struct {
+	Start    float64 `json:"start"`
+	Duration float64 `json:"duration"`
+}
+
+type WordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+type Speaker struct {
+	Speaker   string  `json:"speaker"`
+	TalkTime  float64 `json:"talk_time"`
+	WordCount int     `json:"word_count"`
+	Share     float64 `json:"share"` // Fraction of the speech time.
+}
+
+// Compute computes the analytics of the cues of a transcript of the given duration.
+// Cues without a speaker label are attributed to the speaker of the previous cue.
+func Compute(cues []srt.Cue, duration time.Duration) Analytics {
+	var (
+		a       Analytics
+		speech  time.Duration
+		silence Silence
+		last    time.Duration // End of the previous cue.
+		speaker string
+		counts  = make(map[string]int)
+		talk    = make(map[string]*Speaker)
+		order   []string
+	)
+
+	gap := func(start, end time.Duration) {
+		if d := (end - start).Seconds(); d > silence.Duration {
+			silence = Silence{Start: start.Seconds(), Duration: d}
+		}
+	}
+
+	for _, cue := range cues {
+		if cue.Start > last {
+			gap(last, cue.Start)
+		}
+		last = max(last, cue.End)
+
+		label, text := srt.SplitSpeaker(cue.Text)
+		if label != "" {
+			speaker = label
+		}
+
+		words := tokenize(text)
+		length := max(cue.End-cue.Start, 0)
+
+		speech += length
+		a.WordCount += len(words)
+
+		for _, w := range words {
+			if utf8.RuneCountInString(w) >= minWordLength && !langid.IsStopword(w) {
+				counts[w]++
+			}
+		}
+
+		if speaker != "" {
+			s, ok := talk[speaker]
+			if !ok {
+				s = &Speaker{Speaker: speaker}
+				talk[speaker] = s
+				order = append(order, speaker)
+			}
+			s.TalkTime += length.Seconds()
+			s.WordCount += len(words)
+		}
+	}
+
+	if duration > last {
+		gap(last, duration)
+	}
+
+	a.Duration = max(duration, last).Seconds()
+	a.SpeechTime = speech.Seconds()
+	a.LongestSilence = silence
+	a.Words = frequent(counts, topWords)
+
+	if speech > 0 {
+		a.SpeakingRate = float64(a.WordCount) / speech.Minutes()
+	}
+
+	for _, name := range order {
+		s := talk[name]
+		if speech > 0 {
+			s.Share = s.TalkTime / speech.Seconds()
+		}
+		a.Speakers = append(a.Speakers, *s)
+	}
+	return a
+}
+
+// frequent returns the n most frequent words, ties in alphabetical order.
+func frequent(counts map[string]int, n int) []WordCount {
+	words := make([]WordCount, 0, len(counts))
+	for w, c := range counts {
+		words = append(words, WordCount{Word: w, Count: c})
+	}
+
+	slices.SortFunc(words, func(a, b WordCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Word, b.Word)
+	})
+	return words[:min(n, len(words))]
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
@@ -0,0 +1,87 @@
+package anonymize
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+// Redacted replaces names found in self-identifications.
+const Redacted string = "[REDACTED]"
+
+var (
+	// speakerLabelRe matches diarization labels at the beginning of a line,
+	// e.g. "SPEAKER_00:", "[SPEAKER 1]" or "Speaker 2:".
+	speakerLabelRe = regexp.MustCompile(`(?m)^(?:\[((?i:speaker)[ _]?(?:\d+|[A-Z]))\]:?|((?i:speaker)[ _]?(?:\d+|[A-Z])):)[ \t]*`)
+
+	// selfIdentificationRe matches common self-introductions followed by a capitalized name,
+	// in English and Portuguese.
+	selfIdentificationRe = regexp.MustCompile(
+		`((?i:my name is|my name's|i am|i'm|this is|meu nome é|me chamo|eu sou|aqui é|aqui fala)\s+)` +
+			`(\p{Lu}[\p{L}'-]+(?:\s+\p{Lu}[\p{L}'-]+)*)`,
+	)
+)
+
+// Anonymizer replaces speaker labels with consistent pseudonyms and redacts self-identifications.
+// A single Anonymizer must be used for all cues of a transcript so pseudonyms stay consistent.
+type Anonymizer struct {
+	pseudonyms map[string]string
+}
+
+// New returns a new anonymizer.
+func New() *Anonymizer {
+	return &Anonymizer{pseudonyms: make(map[string]string)}
+}
+
+// Cues returns an anonymized copy of the cues.
+func (a *Anonymizer) Cues(cues []srt.Cue) []srt.Cue {
+	out := make([]srt.Cue, len(cues))
+
+	for i, cue := range cues {
+		cue.Text = a.Text(cue.Text)
+		out[i] = cue
+	}
+	return out
+}
+
+// Text anonymizes a single piece of transcript text.
+func (a *Anonymizer) Text(text string) string {
+	text = speakerLabelRe.ReplaceAllStringFunc(text, func(match string) string {
+		groups := speakerLabelRe.FindStringSubmatch(match)
+		label := strings.ToUpper(groups[1] + groups[2])
+		return a.pseudonym(label) + ": "
+	})
+
+	return selfIdentificationRe.ReplaceAllString(text, "${1}"+Redacted)
+}
+
+func (a *Anonymizer) pseudonym(label string) string {
+	if p, ok := a.pseudonyms[label]; ok {
+		return p
+	}
+
+	p := "Speaker " + sequenceName(len(a.pseudonyms))
+	a.pseudonyms[label] = p
+	return p
+}
+
+// sequenceName returns A, B, ..., Z, AA, AB, ... for 0, 1, ...
+func sequenceName(n int) string {
+	name := ""
+	for n >= 0 {
+		name = fmt.Sprintf("%c", 'A'+n%26) + name
+		n = n/26 - 1
+	}
+	return name
+}
+
+// SRT anonymizes SRT data.
+func SRT(data []byte) ([]byte, error) {
+	cues, err := srt.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse subtitle: %w", err)
+	}
+	return srt.Format(New().Cues(cues)), nil
+}
@@ -0,0 +1,1011 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The syntactic elements of a raw AAC frame.
+const (
+	elementSCE = iota // Single channel.
+	elementCPE        // Channel pair.
+	elementCCE        // Coupling channel.
+	elementLFE        // Low frequency effects channel.
+	elementDSE        // Data stream.
+	elementPCE        // Program config.
+	elementFIL        // Fill.
+	elementEND
+)
+
+// The codebooks of the scalefactor bands without spectral data.
+const (
+	zeroCodebook       = 0
+	noiseCodebook      = 13
+	intensityCodebook2 = 14 // Out of phase.
+	intensityCodebook  = 15
+)
+
+const (
+	// maxBadFrames is the number of invalid frames concealed with silence before their share fails the decoding.
+	maxBadFrames = 8
+
+	// maxFrameSize bounds the size of a frame, well above the 6144 bits per channel of AAC.
+	maxFrameSize = 64 << 10
+
+	// maxChannels bounds the channels of a frame, the 48 of the AAC specification.
+	maxChannels = 48
+)
+
+// aacConfig is the configuration of an AAC-LC stream. The SBR and parametric stereo
+// extensions of HE-AAC are ignored, their core is decoded at its own sample rate.
+type aacConfig struct {
+	sampleRateIndex int
+	sampleRate      int
+	channels        int // Zero when set by a program config element.
+}
+
+// parseAudioSpecificConfig parses the configuration of the AAC stream of an MP4 file.
+func parseAudioSpecificConfig(b []byte) (aacConfig, error) {
+	r := bitReader{b: b}
+
+	objectType := func() int {
+		t := r.read(5)
+		if t == 31 {
+			t = 32 + r.read(6)
+		}
+		return t
+	}
+
+	var c aacConfig
+
+	sampleRate := func() {
+		c.sampleRateIndex = r.read(4)
+		if c.sampleRateIndex == 15 {
+			c.sampleRate = r.read(24)
+			c.sampleRateIndex = nearestSampleRateIndex(c.sampleRate)
+			return
+		}
+		if c.sampleRateIndex < len(aacSampleRates) {
+			c.sampleRate = aacSampleRates[c.sampleRateIndex]
+		}
+	}
+
+	aot := objectType()
+	sampleRate()
+	c.channels = r.read(4)
+
+	// The core of HE-AAC follows the sample rate of its extension.
+	if aot == 5 || aot == 29 {
+		r.read(4)
+		if r.read(4) == 15 {
+			r.read(24)
+		}
+		aot = objectType()
+	}
+
+	if aot != 2 {
+		return aacConfig{}, fmt.Errorf("%w: AAC object type %d", ErrUnsupported, aot)
+	}
+
+	if r.bit() {
+		return aacConfig{}, fmt.Errorf("%w: AAC frames of 960 samples", ErrUnsupported)
+	}
+
+	if r.overrun() {
+		return aacConfig{}, errors.New("truncated AAC config")
+	}
+	return c, c.validate()
+}
+
+func (c aacConfig) validate() error {
+	if c.sampleRate < 1 || c.sampleRateIndex >= len(aacSampleRates) {
+		return fmt.Errorf("invalid AAC sample rate index %d", c.sampleRateIndex)
+	}
+	return nil
+}
+
+// nearestSampleRateIndex returns the index of the tables of a sample rate without its own.
+func nearestSampleRateIndex(rate int) int {
+	for i, min := range []int{92017, 75132, 55426, 46009, 37566, 27713, 23004, 18783, 13856, 11502, 9391} {
+		if rate >= min {
+			return i
+		}
+	}
+	return 11
+}
+
+// aacDecoder decodes raw AAC-LC frames into stereo, mixing down the center and front
+// channels of surround audio.
+type aacDecoder struct {
+	config      aacConfig
+	swbLong     []int
+	swbShort    []int
+	channels    []*aacChannel // The channels of the frame, in the order of their elements.
+	filterbanks []*filterbank
+	noise       uint32
+	frames, bad int
+	silence     []float64
+	left, right []float64
+}
+
+func newAACDecoder(config aacConfig) *aacDecoder {
+	return &aacDecoder{
+		config:   config,
+		swbLong:  swbOffsetsLong[config.sampleRateIndex],
+		swbShort: swbOffsetsShort[config.sampleRateIndex],
+		noise:    1,
+		silence:  make([]float64, 1024),
+		left:     make([]float64, 1024),
+		right:    make([]float64, 1024),
+	}
+}
+
+// decode returns the 1024 frames of a raw AAC frame. Invalid frames are concealed with silence
+// until they are too many. The returned slices are reused by the next call.
+func (d *aacDecoder) decode(b []byte) ([]float64, []float64, error) {
+	d.frames++
+
+	left, right, err := d.decodeFrame(b)
+	if err != nil {
+		d.bad++
+
+		if d.bad > maxBadFrames && d.bad*10 > d.frames {
+			return nil, nil, fmt.Errorf("could not decode %d of %d AAC frames: %w", d.bad, d.frames, err)
+		}
+		return d.silence, d.silence, nil
+	}
+	return left, right, nil
+}
+
+// element is a single channel or channel pair element of a frame.
+type element struct {
+	id      int
+	channel int // The index of its first channel.
+}
+
+func (d *aacDecoder) decodeFrame(b []byte) ([]float64, []float64, error) {
+	r := &bitReader{b: b}
+
+	var (
+		elements []element
+		channel  int
+	)
+
+	for {
+		id := r.read(3)
+		if id == elementEND {
+			break
+		}
+
+		if r.overrun() {
+			return nil, nil, errors.New("truncated AAC frame")
+		}
+
+		switch id {
+		case elementSCE, elementLFE:
+			if channel+1 > maxChannels {
+				return nil, nil, fmt.Errorf("invalid AAC frame of more than %d channels", maxChannels)
+			}
+			r.read(4)
+
+			ch := d.channel(channel)
+			if err := d.readICS(r, ch, false); err != nil {
+				return nil, nil, err
+			}
+			d.dequantize(ch)
+
+			elements = append(elements, element{id: id, channel: channel})
+			channel++
+		case elementCPE:
+			if channel+2 > maxChannels {
+				return nil, nil, fmt.Errorf("invalid AAC frame of more than %d channels", maxChannels)
+			}
+			r.read(4)
+
+			left, right := d.channel(channel), d.channel(channel+1)
+			if err := d.readCPE(r, left, right); err != nil {
+				return nil, nil, err
+			}
+
+			elements = append(elements, element{id: id, channel: channel})
+			channel += 2
+		case elementDSE:
+			r.read(4)
+			align := r.bit()
+			count := r.read(8)
+			if count == 255 {
+				count += r.read(8)
+			}
+			if align {
+				r.align()
+			}
+			r.skip(8 * count)
+		case elementPCE:
+			readProgramConfig(r)
+		case elementFIL:
+			count := r.read(4)
+			if count == 15 {
+				count += r.read(8) - 1
+			}
+			r.skip(8 * count)
+		default:
+			return nil, nil, fmt.Errorf("%w: AAC coupling channels", ErrUnsupported)
+		}
+	}
+
+	if r.overrun() {
+		return nil, nil, errors.New("truncated AAC frame")
+	}
+
+	return d.mixdown(elements)
+}
+
+// mixdown mixes the front channels of the frame into stereo, the center included, and copies mono to both sides.
+func (d *aacDecoder) mixdown(elements []element) ([]float64, []float64, error) {
+	var center, front *element
+
+	for i, e := range elements {
+		if e.id == elementCPE {
+			front = &elements[i]
+			if i > 0 && elements[0].id == elementSCE {
+				center = &elements[0]
+			}
+			break
+		}
+	}
+
+	switch {
+	case front != nil:
+		left, right := d.synthesize(front.channel), d.synthesize(front.channel+1)
+		if center == nil {
+			return left, right, nil
+		}
+
+		c := d.synthesize(center.channel)
+		for i := range d.left {
+			d.left[i] = (left[i] + c[i]*math.Sqrt2/2) / (1 + math.Sqrt2/2)
+			d.right[i] = (right[i] + c[i]*math.Sqrt2/2) / (1 + math.Sqrt2/2)
+		}
+		return d.left, d.right, nil
+	case len(elements) > 0 && elements[0].id == elementSCE:
+		mono := d.synthesize(elements[0].channel)
+		return mono, mono, nil
+	default:
+		return d.silence, d.silence, nil
+	}
+}
+
+// synthesize applies the temporal noise shaping to the spectrum of the channel and returns its samples.
+func (d *aacDecoder) synthesize(channel int) []float64 {
+	for len(d.filterbanks) <= channel {
+		d.filterbanks = append(d.filterbanks, newFilterbank())
+	}
+
+	ch := d.channels[channel]
+	d.applyTNS(ch)
+	return d.filterbanks[channel].transform(ch.spec[:], ch.ics.windowSequence, ch.ics.windowShape)
+}
+
+func (d *aacDecoder) channel(i int) *aacChannel {
+	for len(d.channels) <= i {
+		d.channels = append(d.channels, &aacChannel{})
+	}
+	return d.channels[i]
+}
+
+// readProgramConfig skips a program config element, the channels are mixed down by their elements.
+func readProgramConfig(r *bitReader) {
+	r.read(10)
+
+	front, side, back, lfe, data, coupling := r.read(4), r.read(4), r.read(4), r.read(2), r.read(3), r.read(4)
+
+	for i := 0; i < 2; i++ {
+		if r.bit() {
+			r.read(4) // Mono and stereo mixdowns.
+		}
+	}
+	if r.bit() {
+		r.read(3)
+	}
+
+	r.skip(5*(front+side+back) + 4*(lfe+data) + 5*coupling)
+	r.align()
+	r.skip(8 * r.read(8))
+}
+
+// icsInfo is the window of a channel.
+type icsInfo struct {
+	windowSequence int
+	windowShape    int
+	maxSFB         int
+	groups         int
+	groupLen       [8]int
+}
+
+func (ics *icsInfo) windows() int {
+	if ics.windowSequence == eightShortSequence {
+		return 8
+	}
+	return 1
+}
+
+// aacChannel is the spectrum of a channel of a frame.
+type aacChannel struct {
+	ics          icsInfo
+	codebooks    [8][64]int // By window group and scalefactor band.
+	scalefactors [8][64]int
+	quant        [1024]int
+	spec         [1024]float64
+	tns          tnsData
+}
+
+func (d *aacDecoder) readICSInfo(r *bitReader, ics *icsInfo) error {
+	if r.bit() {
+		return errors.New("invalid AAC reserved bit")
+	}
+
+	ics.windowSequence = r.read(2)
+	ics.windowShape = r.read(1)
+
+	if ics.windowSequence == eightShortSequence {
+		ics.maxSFB = r.read(4)
+		grouping := r.read(7)
+
+		ics.groups, ics.groupLen[0] = 1, 1
+		for i := 6; i >= 0; i-- {
+			if grouping>>i&1 == 1 {
+				ics.groupLen[ics.groups-1]++
+			} else {
+				ics.groupLen[ics.groups] = 1
+				ics.groups++
+			}
+		}
+
+		if ics.maxSFB > len(d.swbShort)-1 {
+			return fmt.Errorf("invalid AAC max scalefactor band %d", ics.maxSFB)
+		}
+		return nil
+	}
+
+	ics.maxSFB = r.read(6)
+	ics.groups, ics.groupLen[0] = 1, 1
+
+	if r.bit() {
+		return fmt.Errorf("%w: AAC prediction", ErrUnsupported)
+	}
+
+	if ics.maxSFB > len(d.swbLong)-1 {
+		return fmt.Errorf("invalid AAC max scalefactor band %d", ics.maxSFB)
+	}
+	return nil
+}
+
+func (d *aacDecoder) swb(ics *icsInfo) []int {
+	if ics.windowSequence == eightShortSequence {
+		return d.swbShort
+	}
+	return d.swbLong
+}
+
+func (d *aacDecoder) readCPE(r *bitReader, left, right *aacChannel) error {
+	commonWindow := r.bit()
+
+	var msUsed [8][64]bool
+
+	if commonWindow {
+		if err := d.readICSInfo(r, &left.ics); err != nil {
+			return err
+		}
+		right.ics = left.ics
+
+		switch r.read(2) {
+		case 1:
+			for g := 0; g < left.ics.groups; g++ {
+				for sfb := 0; sfb < left.ics.maxSFB; sfb++ {
+					msUsed[g][sfb] = r.bit()
+				}
+			}
+		case 2:
+			for g := range msUsed {
+				for sfb := range msUsed[g] {
+					msUsed[g][sfb] = true
+				}
+			}
+		case 3:
+			return errors.New("invalid AAC stereo mask")
+		}
+	}
+
+	if err := d.readICS(r, left, commonWindow); err != nil {
+		return err
+	}
+
+	if err := d.readICS(r, right, commonWindow); err != nil {
+		return err
+	}
+
+	d.dequantize(left)
+	d.dequantize(right)
+
+	if commonWindow {
+		d.applyStereo(left, right, &msUsed)
+	}
+	return nil
+}
+
+// applyStereo decodes the mid/side and intensity stereo bands of a channel pair sharing their window.
+func (d *aacDecoder) applyStereo(left, right *aacChannel, msUsed *[8][64]bool) {
+	ics := &left.ics
+	swb := d.swb(ics)
+
+	window := 0
+	for g := 0; g < ics.groups; g++ {
+		for sfb := 0; sfb < ics.maxSFB; sfb++ {
+			lcb, rcb := left.codebooks[g][sfb], right.codebooks[g][sfb]
+
+			for w := window; w < window+ics.groupLen[g]; w++ {
+				for k := w*128 + swb[sfb]; k < w*128+swb[sfb+1]; k++ {
+					switch {
+					case rcb == intensityCodebook || rcb == intensityCodebook2:
+						scale := math.Pow(0.5, float64(right.scalefactors[g][sfb])/4)
+						if rcb == intensityCodebook2 {
+							scale = -scale
+						}
+						if msUsed[g][sfb] {
+							scale = -scale
+						}
+						right.spec[k] = left.spec[k] * scale
+					case msUsed[g][sfb] && lcb < noiseCodebook && rcb < noiseCodebook:
+						mid, side := left.spec[k], right.spec[k]
+						left.spec[k], right.spec[k] = mid+side, mid-side
+					}
+				}
+			}
+		}
+		window += ics.groupLen[g]
+	}
+}
+
+// readICS reads the individual channel stream of a channel, up to its quantized spectrum.
+func (d *aacDecoder) readICS(r *bitReader, ch *aacChannel, commonWindow bool) error {
+	globalGain := r.read(8)
+
+	if !commonWindow {
+		if err := d.readICSInfo(r, &ch.ics); err != nil {
+			return err
+		}
+	}
+
+	if err := d.readSections(r, ch); err != nil {
+		return err
+	}
+
+	if err := d.readScalefactors(r, ch, globalGain); err != nil {
+		return err
+	}
+
+	clear(ch.quant[:])
+
+	var pulses []pulse
+	if r.bit() {
+		if ch.ics.windowSequence == eightShortSequence {
+			return errors.New("invalid AAC pulse data in short windows")
+		}
+		pulses = readPulses(r)
+	}
+
+	ch.tns.filters = 0
+	if r.bit() {
+		if err := d.readTNS(r, ch); err != nil {
+			return err
+		}
+	}
+
+	if r.bit() {
+		return fmt.Errorf("%w: AAC gain control", ErrUnsupported)
+	}
+
+	if err := d.readSpectrum(r, ch); err != nil {
+		return err
+	}
+
+	if len(pulses) > 0 {
+		// The pulses are added to the bands with spectral data, below the max scalefactor band
+		// of the long window, itself checked against the bands of the sample rate.
+		start := pulses[0].start
+		if start >= ch.ics.maxSFB || start >= len(d.swbLong)-1 {
+			return fmt.Errorf("invalid AAC pulse band %d", start)
+		}
+
+		k, end := d.swbLong[start], d.swbLong[ch.ics.maxSFB]
+		for _, p := range pulses {
+			k += p.offset
+			if k >= end {
+				return fmt.Errorf("invalid AAC pulse offset %d", k)
+			}
+
+			if ch.quant[k] > 0 {
+				ch.quant[k] += p.amp
+			} else {
+				ch.quant[k] -= p.amp
+			}
+		}
+	}
+	return nil
+}
+
+func (d *aacDecoder) readSections(r *bitReader, ch *aacChannel) error {
+	bits, escape := 5, 31
+	if ch.ics.windowSequence == eightShortSequence {
+		bits, escape = 3, 7
+	}
+
+	for g := 0; g < ch.ics.groups; g++ {
+		for sfb := 0; sfb < ch.ics.maxSFB; {
+			cb := r.read(4)
+			if cb == 12 {
+				return errors.New("invalid AAC codebook 12")
+			}
+
+			length := 0
+			for {
+				n := r.read(bits)
+				length += n
+				if n != escape || r.overrun() {
+					break
+				}
+			}
+
+			if sfb+length > ch.ics.maxSFB {
+				return errors.New("invalid AAC section length")
+			}
+
+			for end := sfb + length; sfb < end; sfb++ {
+				ch.codebooks[g][sfb] = cb
+			}
+
+			if length == 0 && r.overrun() {
+				return errors.New("truncated AAC frame")
+			}
+		}
+	}
+	return nil
+}
+
+func (d *aacDecoder) readScalefactors(r *bitReader, ch *aacChannel, globalGain int) error {
+	var (
+		scalefactor = globalGain
+		energy      = globalGain - 90
+		position    = 0
+		firstNoise  = true
+	)
+
+	for g := 0; g < ch.ics.groups; g++ {
+		for sfb := 0; sfb < ch.ics.maxSFB; sfb++ {
+			switch ch.codebooks[g][sfb] {
+			case zeroCodebook:
+				ch.scalefactors[g][sfb] = 0
+				continue
+			case intensityCodebook, intensityCodebook2:
+				delta, err := aacScalefactorCodebook.decode(r)
+				if err != nil {
+					return err
+				}
+				position += delta - 60
+				ch.scalefactors[g][sfb] = position
+			case noiseCodebook:
+				if firstNoise {
+					firstNoise = false
+					energy += r.read(9) - 256
+				} else {
+					delta, err := aacScalefactorCodebook.decode(r)
+					if err != nil {
+						return err
+					}
+					energy += delta - 60
+				}
+				ch.scalefactors[g][sfb] = energy
+			default:
+				delta, err := aacScalefactorCodebook.decode(r)
+				if err != nil {
+					return err
+				}
+				scalefactor += delta - 60
+				if scalefactor < 0 || scalefactor > 255 {
+					return fmt.Errorf("invalid AAC scalefactor %d", scalefactor)
+				}
+				ch.scalefactors[g][sfb] = scalefactor
+			}
+		}
+	}
+	return nil
+}
+
+// pulse is an amplitude added to a spectral coefficient of a long window.
+type pulse struct {
+	start  int // The scalefactor band of the first pulse.
+	offset int
+	amp    int
+}
+
+func readPulses(r *bitReader) []pulse {
+	count := r.read(2) + 1
+	start := r.read(6)
+
+	pulses := make([]pulse, count)
+	for i := range pulses {
+		pulses[i] = pulse{start: start, offset: r.read(5), amp: r.read(4)}
+	}
+	return pulses
+}
+
+// tnsData is the temporal noise shaping of a channel: the filters of each of its windows.
+type tnsData struct {
+	filters int // Zero when not present.
+	count   [8]int
+	filter  [8][3]tnsFilter
+}
+
+type tnsFilter struct {
+	length   int // In scalefactor bands, from the top of the previous filter.
+	order    int
+	downward bool
+	lpc      [12]float64
+}
+
+func (d *aacDecoder) readTNS(r *bitReader, ch *aacChannel) error {
+	countBits, lengthBits, orderBits, maxOrder := 2, 6, 5, 12
+	if ch.ics.windowSequence == eightShortSequence {
+		countBits, lengthBits, orderBits, maxOrder = 1, 4, 3, 7
+	}
+
+	for w := 0; w < ch.ics.windows(); w++ {
+		ch.tns.count[w] = r.read(countBits)
+		if ch.tns.count[w] == 0 {
+			continue
+		}
+		ch.tns.filters += ch.tns.count[w]
+
+		resolution := r.read(1) + 3
+
+		for i := 0; i < ch.tns.count[w]; i++ {
+			f := &ch.tns.filter[w][i]
+			f.length = r.read(lengthBits)
+			f.order = r.read(orderBits)
+
+			if f.order > maxOrder {
+				return fmt.Errorf("invalid AAC TNS order %d", f.order)
+			}
+
+			if f.order == 0 {
+				continue
+			}
+
+			f.downward = r.bit()
+			bits := resolution - r.read(1)
+
+			// The quantized reflection coefficients are converted to the coefficients of the filter.
+			var coef, prev [13]float64
+			coef[0] = 1
+
+			for m := 1; m <= f.order; m++ {
+				q := r.read(bits)
+				if q >= 1<<(bits-1) {
+					q -= 1 << bits
+				}
+
+				iq := (float64(int(1)<<(resolution-1)) - 0.5) / (math.Pi / 2)
+				if q < 0 {
+					iq = (float64(int(1)<<(resolution-1)) + 0.5) / (math.Pi / 2)
+				}
+				k := math.Sin(float64(q) / iq)
+
+				prev = coef
+				for i := 1; i < m; i++ {
+					coef[i] = prev[i] + k*prev[m-i]
+				}
+				coef[m] = k
+			}
+			copy(f.lpc[:], coef[1:])
+		}
+	}
+	return nil
+}
+
+func (d *aacDecoder) applyTNS(ch *aacChannel) {
+	if ch.tns.filters == 0 {
+		return
+	}
+
+	ics := &ch.ics
+	swb := d.swb(ics)
+
+	bands, maxBands := len(swb)-1, tnsMaxBandsLong[d.config.sampleRateIndex]
+	if ics.windowSequence == eightShortSequence {
+		maxBands = tnsMaxBandsShort[d.config.sampleRateIndex]
+	}
+	maxBands = min(maxBands, ics.maxSFB)
+
+	for w := 0; w < ics.windows(); w++ {
+		bottom := bands
+
+		for i := 0; i < ch.tns.count[w]; i++ {
+			f := &ch.tns.filter[w][i]
+
+			top := bottom
+			bottom = max(0, top-f.length)
+
+			if f.order == 0 {
+				continue
+			}
+
+			start, end := swb[min(bottom, maxBands)], swb[min(top, maxBands)]
+			size := end - start
+			if size <= 0 {
+				continue
+			}
+
+			k, inc := w*128+start, 1
+			if f.downward {
+				k, inc = w*128+end-1, -1
+			}
+
+			for m := 0; m < size; m, k = m+1, k+inc {
+				for j := 1; j <= min(m, f.order); j++ {
+					ch.spec[k] -= ch.spec[k-j*inc] * f.lpc[j-1]
+				}
+			}
+		}
+	}
+}
+
+// readSpectrum reads the quantized spectral coefficients of a channel, interleaved by window in each window group.
+func (d *aacDecoder) readSpectrum(r *bitReader, ch *aacChannel) error {
+	ics := &ch.ics
+	swb := d.swb(ics)
+
+	var values [4]int
+
+	window := 0
+	for g := 0; g < ics.groups; g++ {
+		for sfb := 0; sfb < ics.maxSFB; sfb++ {
+			cb := ch.codebooks[g][sfb]
+			if cb == zeroCodebook || cb >= noiseCodebook {
+				continue
+			}
+
+			dim := 2
+			if cb < 5 {
+				dim = 4
+			}
+
+			for w := window; w < window+ics.groupLen[g]; w++ {
+				for k := w*128 + swb[sfb]; k < w*128+swb[sfb+1]; k += dim {
+					if err := readSpectralValues(r, cb, values[:dim]); err != nil {
+						return err
+					}
+					copy(ch.quant[k:], values[:dim])
+				}
+			}
+		}
+		window += ics.groupLen[g]
+	}
+	return nil
+}
+
+func readSpectralValues(r *bitReader, cb int, values []int) error {
+	if cb < 1 || cb >= len(aacSpectralCodebooks) {
+		return fmt.Errorf("invalid AAC spectral codebook %d", cb)
+	}
+
+	i, err := aacSpectralCodebooks[cb].decode(r)
+	if err != nil {
+		return err
+	}
+
+	switch cb {
+	case 1, 2:
+		values[0], values[1], values[2], values[3] = i/27-1, i/9%3-1, i/3%3-1, i%3-1
+		return nil
+	case 3, 4:
+		values[0], values[1], values[2], values[3] = i/27, i/9%3, i/3%3, i%3
+	case 5, 6:
+		values[0], values[1] = i/9-4, i%9-4
+		return nil
+	case 7, 8:
+		values[0], values[1] = i/8, i%8
+	case 9, 10:
+		values[0], values[1] = i/13, i%13
+	default:
+		values[0], values[1] = i/17, i%17
+	}
+
+	// The unsigned codebooks are followed by the signs of the values, then the escaped ones.
+	for j, v := range values {
+		if v != 0 && r.bit() {
+			values[j] = -v
+		}
+	}
+
+	if cb == 11 {
+		for j, v := range values {
+			if v != 16 && v != -16 {
+				continue
+			}
+
+			n := 4
+			for r.bit() {
+				if n++; n > 12 || r.overrun() {
+					return errors.New("invalid AAC escape")
+				}
+			}
+
+			e := 1<<n + r.read(n)
+			if v < 0 {
+				e = -e
+			}
+			values[j] = e
+		}
+	}
+	return nil
+}
+
+// dequantize scales the quantized spectrum of the channel by its scalefactors, and fills its noise bands.
+func (d *aacDecoder) dequantize(ch *aacChannel) {
+	ics := &ch.ics
+	swb := d.swb(ics)
+
+	clear(ch.spec[:])
+
+	window := 0
+	for g := 0; g < ics.groups; g++ {
+		for sfb := 0; sfb < ics.maxSFB; sfb++ {
+			cb := ch.codebooks[g][sfb]
+			if cb == zeroCodebook || cb == intensityCodebook || cb == intensityCodebook2 {
+				continue
+			}
+
+			for w := window; w < window+ics.groupLen[g]; w++ {
+				band := ch.spec[w*128+swb[sfb] : w*128+swb[sfb+1]]
+
+				if cb == noiseCodebook {
+					var energy float64
+					for k := range band {
+						d.noise = d.noise*1664525 + 1013904223
+						band[k] = float64(int32(d.noise))
+						energy += band[k] * band[k]
+					}
+
+					scale := math.Pow(2, float64(ch.scalefactors[g][sfb])/4) / math.Sqrt(energy)
+					for k := range band {
+						band[k] *= scale
+					}
+					continue
+				}
+
+				gain := math.Pow(2, float64(ch.scalefactors[g][sfb]-100)/4)
+				for k, q := range ch.quant[w*128+swb[sfb] : w*128+swb[sfb+1]] {
+					band[k] = dequantize(q) * gain
+				}
+			}
+		}
+		window += ics.groupLen[g]
+	}
+}
+
+// pow43 is the table of the quantized values to the power of 4/3, up to the largest escaped value.
+var pow43 = func() []float64 {
+	t := make([]float64, 8192)
+	for i := range t {
+		t[i] = math.Pow(float64(i), 4.0/3)
+	}
+	return t
+}()
+
+func dequantize(q int) float64 {
+	a := q
+	if a < 0 {
+		a = -a
+	}
+
+	v := math.Pow(float64(a), 4.0/3)
+	if a < len(pow43) {
+		v = pow43[a]
+	}
+
+	if q < 0 {
+		return -v
+	}
+	return v
+}
+
+// bitReader reads the bits of a frame, most significant first. Reading past its end
+// returns zeros, which is checked once a frame is read.
+type bitReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *bitReader) bit() bool {
+	i, shift := r.pos>>3, 7-r.pos&7
+	r.pos++
+	return i < len(r.b) && r.b[i]>>shift&1 == 1
+}
+
+func (r *bitReader) read(n int) int {
+	v := 0
+	for ; n > 0; n-- {
+		v <<= 1
+		if r.bit() {
+			v |= 1
+		}
+	}
+	return v
+}
+
+func (r *bitReader) skip(n int) {
+	r.pos += n
+}
+
+func (r *bitReader) align() {
+	r.pos = (r.pos + 7) &^ 7
+}
+
+func (r *bitReader) overrun() bool {
+	return r.pos > len(r.b)*8
+}
+
+// huffmanCodebook decodes the values of a Huffman code, by walking the binary tree of its codes.
+type huffmanCodebook struct {
+	codes []uint32
+	bits  []uint8
+	tree  [][2]int32 // The children of each node, leaves are the complement of their value.
+}
+
+func init() {
+	aacScalefactorCodebook.build()
+	for i := 1; i < len(aacSpectralCodebooks); i++ {
+		aacSpectralCodebooks[i].build()
+	}
+}
+
+func (c *huffmanCodebook) build() {
+	c.tree = [][2]int32{{}}
+
+	for value, code := range c.codes {
+		node := 0
+		for i := int(c.bits[value]) - 1; i >= 0; i-- {
+			b := code >> i & 1
+
+			if i == 0 {
+				c.tree[node][b] = ^int32(value)
+				break
+			}
+
+			if c.tree[node][b] == 0 {
+				c.tree = append(c.tree, [2]int32{})
+				c.tree[node][b] = int32(len(c.tree) - 1)
+			}
+			node = int(c.tree[node][b])
+		}
+	}
+}
+
+func (c *huffmanCodebook) decode(r *bitReader) (int, error) {
+	node := int32(0)
+	for {
+		b := 0
+		if r.bit() {
+			b = 1
+		}
+
+		node = c.tree[node][b]
+		switch {
+		case node < 0:
+			return int(^node), nil
+		case node == 0 || r.overrun():
+			return 0, errors.New("invalid AAC Huffman code")
+		}
+	}
+}
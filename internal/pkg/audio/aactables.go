@@ -0,0 +1,411 @@
+package audio
+
+// The Huffman codebooks of AAC, from ISO/IEC 14496-3 annex 4.A.1: the codes and their
+// lengths in bits, indexed by the value they encode.
+
+var aacScalefactorCodebook = huffmanCodebook{
+	codes: []uint32{
+		0x3ffe8, 0x3ffe6, 0x3ffe7, 0x3ffe5, 0x7fff5, 0x7fff1, 0x7ffed, 0x7fff6,
+		0x7ffee, 0x7ffef, 0x7fff0, 0x7fffc, 0x7fffd, 0x7ffff, 0x7fffe, 0x7fff7,
+		0x7fff8, 0x7fffb, 0x7fff9, 0x3ffe4, 0x7fffa, 0x3ffe3, 0x1ffef, 0x1fff0,
+		0x0fff5, 0x1ffee, 0x0fff2, 0x0fff3, 0x0fff4, 0x0fff1, 0x07ff6, 0x07ff7,
+		0x03ff9, 0x03ff5, 0x03ff7, 0x03ff3, 0x03ff6, 0x03ff2, 0x01ff7, 0x01ff5,
+		0x00ff9, 0x00ff7, 0x00ff6, 0x007f9, 0x00ff4, 0x007f8, 0x003f9, 0x003f7,
+		0x003f5, 0x001f8, 0x001f7, 0x000fa, 0x000f8, 0x000f6, 0x00079, 0x0003a,
+		0x00038, 0x0001a, 0x0000b, 0x00004, 0x00000, 0x0000a, 0x0000c, 0x0001b,
+		0x00039, 0x0003b, 0x00078, 0x0007a, 0x000f7, 0x000f9, 0x001f6, 0x001f9,
+		0x003f4, 0x003f6, 0x003f8, 0x007f5, 0x007f4, 0x007f6, 0x007f7, 0x00ff5,
+		0x00ff8, 0x01ff4, 0x01ff6, 0x01ff8, 0x03ff8, 0x03ff4, 0x0fff0, 0x07ff4,
+		0x0fff6, 0x07ff5, 0x3ffe2, 0x7ffd9, 0x7ffda, 0x7ffdb, 0x7ffdc, 0x7ffdd,
+		0x7ffde, 0x7ffd8, 0x7ffd2, 0x7ffd3, 0x7ffd4, 0x7ffd5, 0x7ffd6, 0x7fff2,
+		0x7ffdf, 0x7ffe7, 0x7ffe8, 0x7ffe9, 0x7ffea, 0x7ffeb, 0x7ffe6, 0x7ffe0,
+		0x7ffe1, 0x7ffe2, 0x7ffe3, 0x7ffe4, 0x7ffe5, 0x7ffd7, 0x7ffec, 0x7fff4,
+		0x7fff3,
+	},
+	bits: []uint8{
+		18, 18, 18, 18, 19, 19, 19, 19, 19, 19, 19, 19, 19, 19, 19, 19,
+		19, 19, 19, 18, 19, 18, 17, 17, 16, 17, 16, 16, 16, 16, 15, 15,
+		14, 14, 14, 14, 14, 14, 13, 13, 12, 12, 12, 11, 12, 11, 10, 10,
+		10, 9, 9, 8, 8, 8, 7, 6, 6, 5, 4, 3, 1, 4, 4, 5,
+		6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 10, 11, 11, 11, 11, 12,
+		12, 13, 13, 13, 14, 14, 16, 15, 16, 15, 18, 19, 19, 19, 19, 19,
+		19, 19, 19, 19, 19, 19, 19, 19, 19, 19, 19, 19, 19, 19, 19, 19,
+		19, 19, 19, 19, 19, 19, 19, 19, 19,
+	},
+}
+
+var aacSpectralCodebooks = [12]huffmanCodebook{
+	{}, // The zero codebook encodes no values.
+	{ // Codebook 1.
+		codes: []uint32{
+			0x007f8, 0x001f1, 0x007fd, 0x003f5, 0x00068, 0x003f0, 0x007f7, 0x001ec,
+			0x007f5, 0x003f1, 0x00072, 0x003f4, 0x00074, 0x00011, 0x00076, 0x001eb,
+			0x0006c, 0x003f6, 0x007fc, 0x001e1, 0x007f1, 0x001f0, 0x00061, 0x001f6,
+			0x007f2, 0x001ea, 0x007fb, 0x001f2, 0x00069, 0x001ed, 0x00077, 0x00017,
+			0x0006f, 0x001e6, 0x00064, 0x001e5, 0x00067, 0x00015, 0x00062, 0x00012,
+			0x00000, 0x00014, 0x00065, 0x00016, 0x0006d, 0x001e9, 0x00063, 0x001e4,
+			0x0006b, 0x00013, 0x00071, 0x001e3, 0x00070, 0x001f3, 0x007fe, 0x001e7,
+			0x007f3, 0x001ef, 0x00060, 0x001ee, 0x007f0, 0x001e2, 0x007fa, 0x003f3,
+			0x0006a, 0x001e8, 0x00075, 0x00010, 0x00073, 0x001f4, 0x0006e, 0x003f7,
+			0x007f6, 0x001e0, 0x007f9, 0x003f2, 0x00066, 0x001f5, 0x007ff, 0x001f7,
+			0x007f4,
+		},
+		bits: []uint8{
+			11, 9, 11, 10, 7, 10, 11, 9, 11, 10, 7, 10, 7, 5, 7, 9,
+			7, 10, 11, 9, 11, 9, 7, 9, 11, 9, 11, 9, 7, 9, 7, 5,
+			7, 9, 7, 9, 7, 5, 7, 5, 1, 5, 7, 5, 7, 9, 7, 9,
+			7, 5, 7, 9, 7, 9, 11, 9, 11, 9, 7, 9, 11, 9, 11, 10,
+			7, 9, 7, 5, 7, 9, 7, 10, 11, 9, 11, 10, 7, 9, 11, 9,
+			11,
+		},
+	},
+	{ // Codebook 2.
+		codes: []uint32{
+			0x001f3, 0x0006f, 0x001fd, 0x000eb, 0x00023, 0x000ea, 0x001f7, 0x000e8,
+			0x001fa, 0x000f2, 0x0002d, 0x00070, 0x00020, 0x00006, 0x0002b, 0x0006e,
+			0x00028, 0x000e9, 0x001f9, 0x00066, 0x000f8, 0x000e7, 0x0001b, 0x000f1,
+			0x001f4, 0x0006b, 0x001f5, 0x000ec, 0x0002a, 0x0006c, 0x0002c, 0x0000a,
+			0x00027, 0x00067, 0x0001a, 0x000f5, 0x00024, 0x00008, 0x0001f, 0x00009,
+			0x00000, 0x00007, 0x0001d, 0x0000b, 0x00030, 0x000ef, 0x0001c, 0x00064,
+			0x0001e, 0x0000c, 0x00029, 0x000f3, 0x0002f, 0x000f0, 0x001fc, 0x00071,
+			0x001f2, 0x000f4, 0x00021, 0x000e6, 0x000f7, 0x00068, 0x001f8, 0x000ee,
+			0x00022, 0x00065, 0x00031, 0x00002, 0x00026, 0x000ed, 0x00025, 0x0006a,
+			0x001fb, 0x00072, 0x001fe, 0x00069, 0x0002e, 0x000f6, 0x001ff, 0x0006d,
+			0x001f6,
+		},
+		bits: []uint8{
+			9, 7, 9, 8, 6, 8, 9, 8, 9, 8, 6, 7, 6, 5, 6, 7,
+			6, 8, 9, 7, 8, 8, 6, 8, 9, 7, 9, 8, 6, 7, 6, 5,
+			6, 7, 6, 8, 6, 5, 6, 5, 3, 5, 6, 5, 6, 8, 6, 7,
+			6, 5, 6, 8, 6, 8, 9, 7, 9, 8, 6, 8, 8, 7, 9, 8,
+			6, 7, 6, 4, 6, 8, 6, 7, 9, 7, 9, 7, 6, 8, 9, 7,
+			9,
+		},
+	},
+	{ // Codebook 3.
+		codes: []uint32{
+			0x00000, 0x00009, 0x000ef, 0x0000b, 0x00019, 0x000f0, 0x001eb, 0x001e6,
+			0x003f2, 0x0000a, 0x00035, 0x001ef, 0x00034, 0x00037, 0x001e9, 0x001ed,
+			0x001e7, 0x003f3, 0x001ee, 0x003ed, 0x01ffa, 0x001ec, 0x001f2, 0x007f9,
+			0x007f8, 0x003f8, 0x00ff8, 0x00008, 0x00038, 0x003f6, 0x00036, 0x00075,
+			0x003f1, 0x003eb, 0x003ec, 0x00ff4, 0x00018, 0x00076, 0x007f4, 0x00039,
+			0x00074, 0x003ef, 0x001f3, 0x001f4, 0x007f6, 0x001e8, 0x003ea, 0x01ffc,
+			0x000f2, 0x001f1, 0x00ffb, 0x003f5, 0x007f3, 0x00ffc, 0x000ee, 0x003f7,
+			0x07ffe, 0x001f0, 0x007f5, 0x07ffd, 0x01ffb, 0x03ffa, 0x0ffff, 0x000f1,
+			0x003f0, 0x03ffc, 0x001ea, 0x003ee, 0x03ffb, 0x00ff6, 0x00ffa, 0x07ffc,
+			0x007f2, 0x00ff5, 0x0fffe, 0x003f4, 0x007f7, 0x07ffb, 0x00ff7, 0x00ff9,
+			0x07ffa,
+		},
+		bits: []uint8{
+			1, 4, 8, 4, 5, 8, 9, 9, 10, 4, 6, 9, 6, 6, 9, 9,
+			9, 10, 9, 10, 13, 9, 9, 11, 11, 10, 12, 4, 6, 10, 6, 7,
+			10, 10, 10, 12, 5, 7, 11, 6, 7, 10, 9, 9, 11, 9, 10, 13,
+			8, 9, 12, 10, 11, 12, 8, 10, 15, 9, 11, 15, 13, 14, 16, 8,
+			10, 14, 9, 10, 14, 12, 12, 15, 11, 12, 16, 10, 11, 15, 12, 12,
+			15,
+		},
+	},
+	{ // Codebook 4.
+		codes: []uint32{
+			0x00007, 0x00016, 0x000f6, 0x00018, 0x00008, 0x000ef, 0x001ef, 0x000f3,
+			0x007f8, 0x00019, 0x00017, 0x000ed, 0x00015, 0x00001, 0x000e2, 0x000f0,
+			0x00070, 0x003f0, 0x001ee, 0x000f1, 0x007fa, 0x000ee, 0x000e4, 0x003f2,
+			0x007f6, 0x003ef, 0x007fd, 0x00005, 0x00014, 0x000f2, 0x00009, 0x00004,
+			0x000e5, 0x000f4, 0x000e8, 0x003f4, 0x00006, 0x00002, 0x000e7, 0x00003,
+			0x00000, 0x0006b, 0x000e3, 0x00069, 0x001f3, 0x000eb, 0x000e6, 0x003f6,
+			0x0006e, 0x0006a, 0x001f4, 0x003ec, 0x001f0, 0x003f9, 0x000f5, 0x000ec,
+			0x007fb, 0x000ea, 0x0006f, 0x003f7, 0x007f9, 0x003f3, 0x00fff, 0x000e9,
+			0x0006d, 0x003f8, 0x0006c, 0x00068, 0x001f5, 0x003ee, 0x001f2, 0x007f4,
+			0x007f7, 0x003f1, 0x00ffe, 0x003ed, 0x001f1, 0x007f5, 0x007fe, 0x003f5,
+			0x007fc,
+		},
+		bits: []uint8{
+			4, 5, 8, 5, 4, 8, 9, 8, 11, 5, 5, 8, 5, 4, 8, 8,
+			7, 10, 9, 8, 11, 8, 8, 10, 11, 10, 11, 4, 5, 8, 4, 4,
+			8, 8, 8, 10, 4, 4, 8, 4, 4, 7, 8, 7, 9, 8, 8, 10,
+			7, 7, 9, 10, 9, 10, 8, 8, 11, 8, 7, 10, 11, 10, 12, 8,
+			7, 10, 7, 7, 9, 10, 9, 11, 11, 10, 12, 10, 9, 11, 11, 10,
+			11,
+		},
+	},
+	{ // Codebook 5.
+		codes: []uint32{
+			0x01fff, 0x00ff7, 0x007f4, 0x007e8, 0x003f1, 0x007ee, 0x007f9, 0x00ff8,
+			0x01ffd, 0x00ffd, 0x007f1, 0x003e8, 0x001e8, 0x000f0, 0x001ec, 0x003ee,
+			0x007f2, 0x00ffa, 0x00ff4, 0x003ef, 0x001f2, 0x000e8, 0x00070, 0x000ec,
+			0x001f0, 0x003ea, 0x007f3, 0x007eb, 0x001eb, 0x000ea, 0x0001a, 0x00008,
+			0x00019, 0x000ee, 0x001ef, 0x007ed, 0x003f0, 0x000f2, 0x00073, 0x0000b,
+			0x00000, 0x0000a, 0x00071, 0x000f3, 0x007e9, 0x007ef, 0x001ee, 0x000ef,
+			0x00018, 0x00009, 0x0001b, 0x000eb, 0x001e9, 0x007ec, 0x007f6, 0x003eb,
+			0x001f3, 0x000ed, 0x00072, 0x000e9, 0x001f1, 0x003ed, 0x007f7, 0x00ff6,
+			0x007f0, 0x003e9, 0x001ed, 0x000f1, 0x001ea, 0x003ec, 0x007f8, 0x00ff9,
+			0x01ffc, 0x00ffc, 0x00ff5, 0x007ea, 0x003f3, 0x003f2, 0x007f5, 0x00ffb,
+			0x01ffe,
+		},
+		bits: []uint8{
+			13, 12, 11, 11, 10, 11, 11, 12, 13, 12, 11, 10, 9, 8, 9, 10,
+			11, 12, 12, 10, 9, 8, 7, 8, 9, 10, 11, 11, 9, 8, 5, 4,
+			5, 8, 9, 11, 10, 8, 7, 4, 1, 4, 7, 8, 11, 11, 9, 8,
+			5, 4, 5, 8, 9, 11, 11, 10, 9, 8, 7, 8, 9, 10, 11, 12,
+			11, 10, 9, 8, 9, 10, 11, 12, 13, 12, 12, 11, 10, 10, 11, 12,
+			13,
+		},
+	},
+	{ // Codebook 6.
+		codes: []uint32{
+			0x007fe, 0x003fd, 0x001f1, 0x001eb, 0x001f4, 0x001ea, 0x001f0, 0x003fc,
+			0x007fd, 0x003f6, 0x001e5, 0x000ea, 0x0006c, 0x00071, 0x00068, 0x000f0,
+			0x001e6, 0x003f7, 0x001f3, 0x000ef, 0x00032, 0x00027, 0x00028, 0x00026,
+			0x00031, 0x000eb, 0x001f7, 0x001e8, 0x0006f, 0x0002e, 0x00008, 0x00004,
+			0x00006, 0x00029, 0x0006b, 0x001ee, 0x001ef, 0x00072, 0x0002d, 0x00002,
+			0x00000, 0x00003, 0x0002f, 0x00073, 0x001fa, 0x001e7, 0x0006e, 0x0002b,
+			0x00007, 0x00001, 0x00005, 0x0002c, 0x0006d, 0x001ec, 0x001f9, 0x000ee,
+			0x00030, 0x00024, 0x0002a, 0x00025, 0x00033, 0x000ec, 0x001f2, 0x003f8,
+			0x001e4, 0x000ed, 0x0006a, 0x00070, 0x00069, 0x00074, 0x000f1, 0x003fa,
+			0x007ff, 0x003f9, 0x001f6, 0x001ed, 0x001f8, 0x001e9, 0x001f5, 0x003fb,
+			0x007fc,
+		},
+		bits: []uint8{
+			11, 10, 9, 9, 9, 9, 9, 10, 11, 10, 9, 8, 7, 7, 7, 8,
+			9, 10, 9, 8, 6, 6, 6, 6, 6, 8, 9, 9, 7, 6, 4, 4,
+			4, 6, 7, 9, 9, 7, 6, 4, 4, 4, 6, 7, 9, 9, 7, 6,
+			4, 4, 4, 6, 7, 9, 9, 8, 6, 6, 6, 6, 6, 8, 9, 10,
+			9, 8, 7, 7, 7, 7, 8, 10, 11, 10, 9, 9, 9, 9, 9, 10,
+			11,
+		},
+	},
+	{ // Codebook 7.
+		codes: []uint32{
+			0x00000, 0x00005, 0x00037, 0x00074, 0x000f2, 0x001eb, 0x003ed, 0x007f7,
+			0x00004, 0x0000c, 0x00035, 0x00071, 0x000ec, 0x000ee, 0x001ee, 0x001f5,
+			0x00036, 0x00034, 0x00072, 0x000ea, 0x000f1, 0x001e9, 0x001f3, 0x003f5,
+			0x00073, 0x00070, 0x000eb, 0x000f0, 0x001f1, 0x001f0, 0x003ec, 0x003fa,
+			0x000f3, 0x000ed, 0x001e8, 0x001ef, 0x003ef, 0x003f1, 0x003f9, 0x007fb,
+			0x001ed, 0x000ef, 0x001ea, 0x001f2, 0x003f3, 0x003f8, 0x007f9, 0x007fc,
+			0x003ee, 0x001ec, 0x001f4, 0x003f4, 0x003f7, 0x007f8, 0x00ffd, 0x00ffe,
+			0x007f6, 0x003f0, 0x003f2, 0x003f6, 0x007fa, 0x007fd, 0x00ffc, 0x00fff,
+		},
+		bits: []uint8{
+			1, 3, 6, 7, 8, 9, 10, 11, 3, 4, 6, 7, 8, 8, 9, 9,
+			6, 6, 7, 8, 8, 9, 9, 10, 7, 7, 8, 8, 9, 9, 10, 10,
+			8, 8, 9, 9, 10, 10, 10, 11, 9, 8, 9, 9, 10, 10, 11, 11,
+			10, 9, 9, 10, 10, 11, 12, 12, 11, 10, 10, 10, 11, 11, 12, 12,
+		},
+	},
+	{ // Codebook 8.
+		codes: []uint32{
+			0x0000e, 0x00005, 0x00010, 0x00030, 0x0006f, 0x000f1, 0x001fa, 0x003fe,
+			0x00003, 0x00000, 0x00004, 0x00012, 0x0002c, 0x0006a, 0x00075, 0x000f8,
+			0x0000f, 0x00002, 0x00006, 0x00014, 0x0002e, 0x00069, 0x00072, 0x000f5,
+			0x0002f, 0x00011, 0x00013, 0x0002a, 0x00032, 0x0006c, 0x000ec, 0x000fa,
+			0x00071, 0x0002b, 0x0002d, 0x00031, 0x0006d, 0x00070, 0x000f2, 0x001f9,
+			0x000ef, 0x00068, 0x00033, 0x0006b, 0x0006e, 0x000ee, 0x000f9, 0x003fc,
+			0x001f8, 0x00074, 0x00073, 0x000ed, 0x000f0, 0x000f6, 0x001f6, 0x001fd,
+			0x003fd, 0x000f3, 0x000f4, 0x000f7, 0x001f7, 0x001fb, 0x001fc, 0x003ff,
+		},
+		bits: []uint8{
+			5, 4, 5, 6, 7, 8, 9, 10, 4, 3, 4, 5, 6, 7, 7, 8,
+			5, 4, 4, 5, 6, 7, 7, 8, 6, 5, 5, 6, 6, 7, 8, 8,
+			7, 6, 6, 6, 7, 7, 8, 9, 8, 7, 6, 7, 7, 8, 8, 10,
+			9, 7, 7, 8, 8, 8, 9, 9, 10, 8, 8, 8, 9, 9, 9, 10,
+		},
+	},
+	{ // Codebook 9.
+		codes: []uint32{
+			0x00000, 0x00005, 0x00037, 0x000e7, 0x001de, 0x003ce, 0x003d9, 0x007c8,
+			0x007cd, 0x00fc8, 0x00fdd, 0x01fe4, 0x01fec, 0x00004, 0x0000c, 0x00035,
+			0x00072, 0x000ea, 0x000ed, 0x001e2, 0x003d1, 0x003d3, 0x003e0, 0x007d8,
+			0x00fcf, 0x00fd5, 0x00036, 0x00034, 0x00071, 0x000e8, 0x000ec, 0x001e1,
+			0x003cf, 0x003dd, 0x003db, 0x007d0, 0x00fc7, 0x00fd4, 0x00fe4, 0x000e6,
+			0x00070, 0x000e9, 0x001dd, 0x001e3, 0x003d2, 0x003dc, 0x007cc, 0x007ca,
+			0x007de, 0x00fd8, 0x00fea, 0x01fdb, 0x001df, 0x000eb, 0x001dc, 0x001e6,
+			0x003d5, 0x003de, 0x007cb, 0x007dd, 0x007dc, 0x00fcd, 0x00fe2, 0x00fe7,
+			0x01fe1, 0x003d0, 0x001e0, 0x001e4, 0x003d6, 0x007c5, 0x007d1, 0x007db,
+			0x00fd2, 0x007e0, 0x00fd9, 0x00feb, 0x01fe3, 0x01fe9, 0x007c4, 0x001e5,
+			0x003d7, 0x007c6, 0x007cf, 0x007da, 0x00fcb, 0x00fda, 0x00fe3, 0x00fe9,
+			0x01fe6, 0x01ff3, 0x01ff7, 0x007d3, 0x003d8, 0x003e1, 0x007d4, 0x007d9,
+			0x00fd3, 0x00fde, 0x01fdd, 0x01fd9, 0x01fe2, 0x01fea, 0x01ff1, 0x01ff6,
+			0x007d2, 0x003d4, 0x003da, 0x007c7, 0x007d7, 0x007e2, 0x00fce, 0x00fdb,
+			0x01fd8, 0x01fee, 0x03ff0, 0x01ff4, 0x03ff2, 0x007e1, 0x003df, 0x007c9,
+			0x007d6, 0x00fca, 0x00fd0, 0x00fe5, 0x00fe6, 0x01feb, 0x01fef, 0x03ff3,
+			0x03ff4, 0x03ff5, 0x00fe0, 0x007ce, 0x007d5, 0x00fc6, 0x00fd1, 0x00fe1,
+			0x01fe0, 0x01fe8, 0x01ff0, 0x03ff1, 0x03ff8, 0x03ff6, 0x07ffc, 0x00fe8,
+			0x007df, 0x00fc9, 0x00fd7, 0x00fdc, 0x01fdc, 0x01fdf, 0x01fed, 0x01ff5,
+			0x03ff9, 0x03ffb, 0x07ffd, 0x07ffe, 0x01fe7, 0x00fcc, 0x00fd6, 0x00fdf,
+			0x01fde, 0x01fda, 0x01fe5, 0x01ff2, 0x03ffa, 0x03ff7, 0x03ffc, 0x03ffd,
+			0x07fff,
+		},
+		bits: []uint8{
+			1, 3, 6, 8, 9, 10, 10, 11, 11, 12, 12, 13, 13, 3, 4, 6,
+			7, 8, 8, 9, 10, 10, 10, 11, 12, 12, 6, 6, 7, 8, 8, 9,
+			10, 10, 10, 11, 12, 12, 12, 8, 7, 8, 9, 9, 10, 10, 11, 11,
+			11, 12, 12, 13, 9, 8, 9, 9, 10, 10, 11, 11, 11, 12, 12, 12,
+			13, 10, 9, 9, 10, 11, 11, 11, 12, 11, 12, 12, 13, 13, 11, 9,
+			10, 11, 11, 11, 12, 12, 12, 12, 13, 13, 13, 11, 10, 10, 11, 11,
+			12, 12, 13, 13, 13, 13, 13, 13, 11, 10, 10, 11, 11, 11, 12, 12,
+			13, 13, 14, 13, 14, 11, 10, 11, 11, 12, 12, 12, 12, 13, 13, 14,
+			14, 14, 12, 11, 11, 12, 12, 12, 13, 13, 13, 14, 14, 14, 15, 12,
+			11, 12, 12, 12, 13, 13, 13, 13, 14, 14, 15, 15, 13, 12, 12, 12,
+			13, 13, 13, 13, 14, 14, 14, 14, 15,
+		},
+	},
+	{ // Codebook 10.
+		codes: []uint32{
+			0x00022, 0x00008, 0x0001d, 0x00026, 0x0005f, 0x000d3, 0x001cf, 0x003d0,
+			0x003d7, 0x003ed, 0x007f0, 0x007f6, 0x00ffd, 0x00007, 0x00000, 0x00001,
+			0x00009, 0x00020, 0x00054, 0x00060, 0x000d5, 0x000dc, 0x001d4, 0x003cd,
+			0x003de, 0x007e7, 0x0001c, 0x00002, 0x00006, 0x0000c, 0x0001e, 0x00028,
+			0x0005b, 0x000cd, 0x000d9, 0x001ce, 0x001dc, 0x003d9, 0x003f1, 0x00025,
+			0x0000b, 0x0000a, 0x0000d, 0x00024, 0x00057, 0x00061, 0x000cc, 0x000dd,
+			0x001cc, 0x001de, 0x003d3, 0x003e7, 0x0005d, 0x00021, 0x0001f, 0x00023,
+			0x00027, 0x00059, 0x00064, 0x000d8, 0x000df, 0x001d2, 0x001e2, 0x003dd,
+			0x003ee, 0x000d1, 0x00055, 0x00029, 0x00056, 0x00058, 0x00062, 0x000ce,
+			0x000e0, 0x000e2, 0x001da, 0x003d4, 0x003e3, 0x007eb, 0x001c9, 0x0005e,
+			0x0005a, 0x0005c, 0x00063, 0x000ca, 0x000da, 0x001c7, 0x001ca, 0x001e0,
+			0x003db, 0x003e8, 0x007ec, 0x001e3, 0x000d2, 0x000cb, 0x000d0, 0x000d7,
+			0x000db, 0x001c6, 0x001d5, 0x001d8, 0x003ca, 0x003da, 0x007ea, 0x007f1,
+			0x001e1, 0x000d4, 0x000cf, 0x000d6, 0x000de, 0x000e1, 0x001d0, 0x001d6,
+			0x003d1, 0x003d5, 0x003f2, 0x007ee, 0x007fb, 0x003e9, 0x001cd, 0x001c8,
+			0x001cb, 0x001d1, 0x001d7, 0x001df, 0x003cf, 0x003e0, 0x003ef, 0x007e6,
+			0x007f8, 0x00ffa, 0x003eb, 0x001dd, 0x001d3, 0x001d9, 0x001db, 0x003d2,
+			0x003cc, 0x003dc, 0x003ea, 0x007ed, 0x007f3, 0x007f9, 0x00ff9, 0x007f2,
+			0x003ce, 0x001e4, 0x003cb, 0x003d8, 0x003d6, 0x003e2, 0x003e5, 0x007e8,
+			0x007f4, 0x007f5, 0x007f7, 0x00ffb, 0x007fa, 0x003ec, 0x003df, 0x003e1,
+			0x003e4, 0x003e6, 0x003f0, 0x007e9, 0x007ef, 0x00ff8, 0x00ffe, 0x00ffc,
+			0x00fff,
+		},
+		bits: []uint8{
+			6, 5, 6, 6, 7, 8, 9, 10, 10, 10, 11, 11, 12, 5, 4, 4,
+			5, 6, 7, 7, 8, 8, 9, 10, 10, 11, 6, 4, 5, 5, 6, 6,
+			7, 8, 8, 9, 9, 10, 10, 6, 5, 5, 5, 6, 7, 7, 8, 8,
+			9, 9, 10, 10, 7, 6, 6, 6, 6, 7, 7, 8, 8, 9, 9, 10,
+			10, 8, 7, 6, 7, 7, 7, 8, 8, 8, 9, 10, 10, 11, 9, 7,
+			7, 7, 7, 8, 8, 9, 9, 9, 10, 10, 11, 9, 8, 8, 8, 8,
+			8, 9, 9, 9, 10, 10, 11, 11, 9, 8, 8, 8, 8, 8, 9, 9,
+			10, 10, 10, 11, 11, 10, 9, 9, 9, 9, 9, 9, 10, 10, 10, 11,
+			11, 12, 10, 9, 9, 9, 9, 10, 10, 10, 10, 11, 11, 11, 12, 11,
+			10, 9, 10, 10, 10, 10, 10, 11, 11, 11, 11, 12, 11, 10, 10, 10,
+			10, 10, 10, 11, 11, 12, 12, 12, 12,
+		},
+	},
+	{ // Codebook 11.
+		codes: []uint32{
+			0x00000, 0x00006, 0x00019, 0x0003d, 0x0009c, 0x000c6, 0x001a7, 0x00390,
+			0x003c2, 0x003df, 0x007e6, 0x007f3, 0x00ffb, 0x007ec, 0x00ffa, 0x00ffe,
+			0x0038e, 0x00005, 0x00001, 0x00008, 0x00014, 0x00037, 0x00042, 0x00092,
+			0x000af, 0x00191, 0x001a5, 0x001b5, 0x0039e, 0x003c0, 0x003a2, 0x003cd,
+			0x007d6, 0x000ae, 0x00017, 0x00007, 0x00009, 0x00018, 0x00039, 0x00040,
+			0x0008e, 0x000a3, 0x000b8, 0x00199, 0x001ac, 0x001c1, 0x003b1, 0x00396,
+			0x003be, 0x003ca, 0x0009d, 0x0003c, 0x00015, 0x00016, 0x0001a, 0x0003b,
+			0x00044, 0x00091, 0x000a5, 0x000be, 0x00196, 0x001ae, 0x001b9, 0x003a1,
+			0x00391, 0x003a5, 0x003d5, 0x00094, 0x0009a, 0x00036, 0x00038, 0x0003a,
+			0x00041, 0x0008c, 0x0009b, 0x000b0, 0x000c3, 0x0019e, 0x001ab, 0x001bc,
+			0x0039f, 0x0038f, 0x003a9, 0x003cf, 0x00093, 0x000bf, 0x0003e, 0x0003f,
+			0x00043, 0x00045, 0x0009e, 0x000a7, 0x000b9, 0x00194, 0x001a2, 0x001ba,
+			0x001c3, 0x003a6, 0x003a7, 0x003bb, 0x003d4, 0x0009f, 0x001a0, 0x0008f,
+			0x0008d, 0x00090, 0x00098, 0x000a6, 0x000b6, 0x000c4, 0x0019f, 0x001af,
+			0x001bf, 0x00399, 0x003bf, 0x003b4, 0x003c9, 0x003e7, 0x000a8, 0x001b6,
+			0x000ab, 0x000a4, 0x000aa, 0x000b2, 0x000c2, 0x000c5, 0x00198, 0x001a4,
+			0x001b8, 0x0038c, 0x003a4, 0x003c4, 0x003c6, 0x003dd, 0x003e8, 0x000ad,
+			0x003af, 0x00192, 0x000bd, 0x000bc, 0x0018e, 0x00197, 0x0019a, 0x001a3,
+			0x001b1, 0x0038d, 0x00398, 0x003b7, 0x003d3, 0x003d1, 0x003db, 0x007dd,
+			0x000b4, 0x003de, 0x001a9, 0x0019b, 0x0019c, 0x001a1, 0x001aa, 0x001ad,
+			0x001b3, 0x0038b, 0x003b2, 0x003b8, 0x003ce, 0x003e1, 0x003e0, 0x007d2,
+			0x007e5, 0x000b7, 0x007e3, 0x001bb, 0x001a8, 0x001a6, 0x001b0, 0x001b2,
+			0x001b7, 0x0039b, 0x0039a, 0x003ba, 0x003b5, 0x003d6, 0x007d7, 0x003e4,
+			0x007d8, 0x007ea, 0x000ba, 0x007e8, 0x003a0, 0x001bd, 0x001b4, 0x0038a,
+			0x001c4, 0x00392, 0x003aa, 0x003b0, 0x003bc, 0x003d7, 0x007d4, 0x007dc,
+			0x007db, 0x007d5, 0x007f0, 0x000c1, 0x007fb, 0x003c8, 0x003a3, 0x00395,
+			0x0039d, 0x003ac, 0x003ae, 0x003c5, 0x003d8, 0x003e2, 0x003e6, 0x007e4,
+			0x007e7, 0x007e0, 0x007e9, 0x007f7, 0x00190, 0x007f2, 0x00393, 0x001be,
+			0x001c0, 0x00394, 0x00397, 0x003ad, 0x003c3, 0x003c1, 0x003d2, 0x007da,
+			0x007d9, 0x007df, 0x007eb, 0x007f4, 0x007fa, 0x00195, 0x007f8, 0x003bd,
+			0x0039c, 0x003ab, 0x003a8, 0x003b3, 0x003b9, 0x003d0, 0x003e3, 0x003e5,
+			0x007e2, 0x007de, 0x007ed, 0x007f1, 0x007f9, 0x007fc, 0x00193, 0x00ffd,
+			0x003dc, 0x003b6, 0x003c7, 0x003cc, 0x003cb, 0x003d9, 0x003da, 0x007d3,
+			0x007e1, 0x007ee, 0x007ef, 0x007f5, 0x007f6, 0x00ffc, 0x00fff, 0x0019d,
+			0x001c2, 0x000b5, 0x000a1, 0x00096, 0x00097, 0x00095, 0x00099, 0x000a0,
+			0x000a2, 0x000ac, 0x000a9, 0x000b1, 0x000b3, 0x000bb, 0x000c0, 0x0018f,
+			0x00004,
+		},
+		bits: []uint8{
+			4, 5, 6, 7, 8, 8, 9, 10, 10, 10, 11, 11, 12, 11, 12, 12,
+			10, 5, 4, 5, 6, 7, 7, 8, 8, 9, 9, 9, 10, 10, 10, 10,
+			11, 8, 6, 5, 5, 6, 7, 7, 8, 8, 8, 9, 9, 9, 10, 10,
+			10, 10, 8, 7, 6, 6, 6, 7, 7, 8, 8, 8, 9, 9, 9, 10,
+			10, 10, 10, 8, 8, 7, 7, 7, 7, 8, 8, 8, 8, 9, 9, 9,
+			10, 10, 10, 10, 8, 8, 7, 7, 7, 7, 8, 8, 8, 9, 9, 9,
+			9, 10, 10, 10, 10, 8, 9, 8, 8, 8, 8, 8, 8, 8, 9, 9,
+			9, 10, 10, 10, 10, 10, 8, 9, 8, 8, 8, 8, 8, 8, 9, 9,
+			9, 10, 10, 10, 10, 10, 10, 8, 10, 9, 8, 8, 9, 9, 9, 9,
+			9, 10, 10, 10, 10, 10, 10, 11, 8, 10, 9, 9, 9, 9, 9, 9,
+			9, 10, 10, 10, 10, 10, 10, 11, 11, 8, 11, 9, 9, 9, 9, 9,
+			9, 10, 10, 10, 10, 10, 11, 10, 11, 11, 8, 11, 10, 9, 9, 10,
+			9, 10, 10, 10, 10, 10, 11, 11, 11, 11, 11, 8, 11, 10, 10, 10,
+			10, 10, 10, 10, 10, 10, 10, 11, 11, 11, 11, 11, 9, 11, 10, 9,
+			9, 10, 10, 10, 10, 10, 10, 11, 11, 11, 11, 11, 11, 9, 11, 10,
+			10, 10, 10, 10, 10, 10, 10, 10, 11, 11, 11, 11, 11, 11, 9, 12,
+			10, 10, 10, 10, 10, 10, 10, 11, 11, 11, 11, 11, 11, 12, 12, 9,
+			9, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 9,
+			5,
+		},
+	},
+}
+
+// The sample rates of AAC, by their index.
+var aacSampleRates = [13]int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// The offsets of the scalefactor bands of the long and short windows, by sample rate index.
+var (
+	swbOffsetsLong = [13][]int{
+		swbOffsetLong96, swbOffsetLong96, swbOffsetLong64, swbOffsetLong48, swbOffsetLong48, swbOffsetLong32, swbOffsetLong24,
+		swbOffsetLong24, swbOffsetLong16, swbOffsetLong16, swbOffsetLong16, swbOffsetLong8, swbOffsetLong8,
+	}
+	swbOffsetsShort = [13][]int{
+		swbOffsetShort96, swbOffsetShort96, swbOffsetShort96, swbOffsetShort48, swbOffsetShort48, swbOffsetShort48, swbOffsetShort24,
+		swbOffsetShort24, swbOffsetShort16, swbOffsetShort16, swbOffsetShort16, swbOffsetShort8, swbOffsetShort8,
+	}
+)
+
+var (
+	swbOffsetLong96 = []int{
+		0, 4, 8, 12, 16, 20, 24, 28, 32, 36, 40, 44, 48, 52, 56, 64, 72, 80, 88, 96, 108, 120, 132, 144, 156, 172, 188, 212,
+		240, 276, 320, 384, 448, 512, 576, 640, 704, 768, 832, 896, 960, 1024,
+	}
+	swbOffsetLong64 = []int{
+		0, 4, 8, 12, 16, 20, 24, 28, 32, 36, 40, 44, 48, 52, 56, 64, 72, 80, 88, 100, 112, 124, 140, 156, 172, 192, 216, 240,
+		268, 304, 344, 384, 424, 464, 504, 544, 584, 624, 664, 704, 744, 784, 824, 864, 904, 944, 984, 1024,
+	}
+	swbOffsetLong48 = []int{
+		0, 4, 8, 12, 16, 20, 24, 28, 32, 36, 40, 48, 56, 64, 72, 80, 88, 96, 108, 120, 132, 144, 160, 176, 196, 216, 240, 264,
+		292, 320, 352, 384, 416, 448, 480, 512, 544, 576, 608, 640, 672, 704, 736, 768, 800, 832, 864, 896, 928, 1024,
+	}
+	swbOffsetLong32 = []int{
+		0, 4, 8, 12, 16, 20, 24, 28, 32, 36, 40, 48, 56, 64, 72, 80, 88, 96, 108, 120, 132, 144, 160, 176, 196, 216, 240, 264,
+		292, 320, 352, 384, 416, 448, 480, 512, 544, 576, 608, 640, 672, 704, 736, 768, 800, 832, 864, 896, 928, 960, 992, 1024,
+	}
+	swbOffsetLong24 = []int{
+		0, 4, 8, 12, 16, 20, 24, 28, 32, 36, 40, 44, 52, 60, 68, 76, 84, 92, 100, 108, 116, 124, 136, 148, 160, 172, 188, 204,
+		220, 240, 260, 284, 308, 336, 364, 396, 432, 468, 508, 552, 600, 652, 704, 768, 832, 896, 960, 1024,
+	}
+	swbOffsetLong16 = []int{
+		0, 8, 16, 24, 32, 40, 48, 56, 64, 72, 80, 88, 100, 112, 124, 136, 148, 160, 172, 184, 196, 212, 228, 244, 260, 280,
+		300, 320, 344, 368, 396, 424, 456, 492, 532, 572, 616, 664, 716, 772, 832, 896, 960, 1024,
+	}
+	swbOffsetLong8 = []int{
+		0, 12, 24, 36, 48, 60, 72, 84, 96, 108, 120, 132, 144, 156, 172, 188, 204, 220, 236, 252, 268, 288, 308, 328, 348,
+		372, 396, 420, 448, 476, 508, 544, 580, 620, 664, 712, 764, 820, 880, 944, 1024,
+	}
+
+	swbOffsetShort96 = []int{0, 4, 8, 12, 16, 20, 24, 32, 40, 48, 64, 92, 128}
+	swbOffsetShort48 = []int{0, 4, 8, 12, 16, 20, 28, 36, 44, 56, 68, 80, 96, 112, 128}
+	swbOffsetShort24 = []int{0, 4, 8, 12, 16, 20, 24, 28, 36, 44, 52, 64, 76, 92, 108, 128}
+	swbOffsetShort16 = []int{0, 4, 8, 12, 16, 20, 24, 28, 32, 40, 48, 60, 72, 88, 108, 128}
+	swbOffsetShort8  = []int{0, 4, 8, 12, 16, 20, 24, 28, 36, 44, 52, 60, 72, 88, 108, 128}
+)
+
+// The number of scalefactor bands the temporal noise shaping of AAC-LC applies to, by sample rate index.
+var (
+	tnsMaxBandsLong  = [13]int{31, 31, 34, 40, 42, 51, 46, 46, 42, 42, 42, 39, 39}
+	tnsMaxBandsShort = [13]int{9, 9, 10, 14, 14, 14, 14, 14, 14, 14, 14, 14, 14}
+)
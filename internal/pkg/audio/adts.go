@@ -0,0 +1,118 @@
+package audio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// adtsSource decodes raw AAC, the frames of an ADTS stream as in .aac files.
+type adtsSource struct {
+	r       *bufio.Reader
+	size    int64
+	read    int64
+	config  aacConfig
+	decoder *aacDecoder
+	buf     []byte
+}
+
+func newADTSSource(r *bufio.Reader, size int64) (*adtsSource, error) {
+	header, err := r.Peek(7)
+	if err != nil {
+		return nil, fmt.Errorf("could not read header: %w", err)
+	}
+
+	config, _, _, err := parseADTSHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &adtsSource{
+		r:       r,
+		size:    size,
+		config:  config,
+		decoder: newAACDecoder(config),
+		buf:     make([]byte, maxFrameSize),
+	}, nil
+}
+
+// parseADTSHeader returns the configuration of the frame of the header, and the sizes of both.
+func parseADTSHeader(h []byte) (config aacConfig, headerSize, frameSize int, err error) {
+	if h[0] != 0xFF || h[1]&0xF6 != 0xF0 {
+		return aacConfig{}, 0, 0, errors.New("invalid ADTS sync word")
+	}
+
+	if profile := h[2] >> 6; profile != 1 {
+		return aacConfig{}, 0, 0, fmt.Errorf("%w: AAC object type %d", ErrUnsupported, profile+1)
+	}
+
+	if h[6]&0x03 != 0 {
+		return aacConfig{}, 0, 0, fmt.Errorf("%w: ADTS frames of several AAC frames", ErrUnsupported)
+	}
+
+	config.sampleRateIndex = int(h[2] >> 2 & 0x0F)
+	if config.sampleRateIndex < len(aacSampleRates) {
+		config.sampleRate = aacSampleRates[config.sampleRateIndex]
+	}
+	config.channels = int(h[2]&0x01)<<2 | int(h[3]>>6)
+
+	// The header is followed by its CRC, unless absent.
+	headerSize = 7
+	if h[1]&0x01 == 0 {
+		headerSize += 2
+	}
+
+	frameSize = int(h[3]&0x03)<<11 | int(h[4])<<3 | int(h[5])>>5
+	if frameSize < headerSize {
+		return aacConfig{}, 0, 0, fmt.Errorf("invalid ADTS frame of %d bytes", frameSize)
+	}
+	return config, headerSize, frameSize, config.validate()
+}
+
+func (s *adtsSource) sampleRate() int {
+	return s.config.sampleRate
+}
+
+func (s *adtsSource) next() ([]float64, []float64, error) {
+	for {
+		header, err := s.r.Peek(7)
+		if len(header) < 7 {
+			if err == nil || errors.Is(err, io.EOF) {
+				return nil, nil, io.EOF
+			}
+			return nil, nil, fmt.Errorf("could not read audio: %w", err)
+		}
+
+		// Frames are found again by their sync word after invalid data, like the ID3v1 tag at the end.
+		config, headerSize, frameSize, err := parseADTSHeader(header)
+		if err != nil {
+			s.r.Discard(1)
+			s.read++
+			continue
+		}
+
+		if config.sampleRate != s.config.sampleRate {
+			return nil, nil, fmt.Errorf("%w: AAC sample rate changing from %d to %d Hz", ErrUnsupported, s.config.sampleRate, config.sampleRate)
+		}
+
+		n, err := io.ReadFull(s.r, s.buf[:frameSize])
+		s.read += int64(n)
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, nil, io.EOF
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read audio: %w", err)
+		}
+		return s.decoder.decode(s.buf[headerSize:frameSize])
+	}
+}
+
+func (s *adtsSource) progress() float64 {
+	if s.size <= 0 {
+		return 0
+	}
+	return float64(s.read) / float64(s.size)
+}
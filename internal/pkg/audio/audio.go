@@ -0,0 +1,296 @@
+// Package audio extracts audio in pure Go, for hosts where ffmpeg can't be installed.
+package audio
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ErrUnsupported is returned for files that can't be decoded without ffmpeg.
+var ErrUnsupported = errors.New("unsupported audio format")
+
+// Extractor extracts audio without ffmpeg, in the format ffmpeg extracts it: 16-bit stereo PCM
+// at the requested sample rate. It decodes the common cases: WAV, MP3, and AAC, raw or in MP4
+// files. Other formats, like the Opus of WebM files, are rejected with ErrUnsupported.
+type Extractor struct{}
+
+// NewExtractor returns a new pure Go audio extractor.
+func NewExtractor() *Extractor {
+	return &Extractor{}
+}
+
+// ExtractAudio decodes the audio of the file into a WAV file next to it and returns its path.
+// Filters require ffmpeg, so none can be applied.
+// The progress function, when not nil, is called with the extracted fraction (0 to 1).
+func (e *Extractor) ExtractAudio(ctx context.Context, filePath, sampleRate string, filters []string, progress func(float64)) (string, error) {
+	if len(filters) > 0 {
+		return "", fmt.Errorf("%w: audio filters require ffmpeg", ErrUnsupported)
+	}
+
+	rate, err := strconv.Atoi(sampleRate)
+	if err != nil || rate < 1 {
+		return "", fmt.Errorf("invalid sample rate %q", sampleRate)
+	}
+
+	in, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %w", err)
+	}
+	defer in.Close()
+
+	src, err := openSource(in)
+	if err != nil {
+		return "", err
+	}
+
+	outputPath := filePath + ".wav"
+
+	if err := decode(ctx, src, rate, outputPath, progress); err != nil {
+		os.Remove(outputPath)
+
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("decoding was stopped: %w", ctx.Err())
+		}
+		return "", err
+	}
+
+	if progress != nil {
+		progress(1)
+	}
+	return outputPath, nil
+}
+
+// Duration returns the duration of the WAV or MP4 file, read from its header without decoding it.
+// The duration of raw AAC and MP3 files is only known once decoded, they are rejected with ErrUnsupported.
+func (e *Extractor) Duration(ctx context.Context, filePath string) (time.Duration, error) {
+	in, err := os.Open(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("could not open file: %w", err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("could not stat file: %w", err)
+	}
+
+	head := make([]byte, 12)
+	n, _ := in.ReadAt(head, 0)
+	head = head[:n]
+
+	switch {
+	case len(head) >= 12 && string(head[0:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		format, err := readWAVHeader(bufio.NewReader(in), info.Size())
+		if err != nil {
+			return 0, fmt.Errorf("could not read WAV: %w", err)
+		}
+
+		if format.dataSize == 0 || format.blockAlign() == 0 || format.sampleRate == 0 {
+			return 0, fmt.Errorf("%w: WAV of unknown length", ErrUnsupported)
+		}
+		return time.Duration(format.dataSize/int64(format.blockAlign())) * time.Second / time.Duration(format.sampleRate), nil
+	case len(head) >= 8 && string(head[4:8]) == "ftyp":
+		track, err := readMP4(in, info.Size())
+		if err != nil {
+			return 0, fmt.Errorf("could not read MP4: %w", err)
+		}
+
+		if track.config.sampleRate == 0 {
+			return 0, fmt.Errorf("%w: MP4 of unknown sample rate", ErrUnsupported)
+		}
+
+		// Every AAC frame holds 1024 samples.
+		frames := int64(max(len(track.samples)-track.skip, 0)) * 1024
+		return time.Duration(frames) * time.Second / time.Duration(track.config.sampleRate), nil
+	}
+	return 0, fmt.Errorf("%w: the duration is only known once decoded", ErrUnsupported)
+}
+
+// ExtractSegment copies the part of the WAV file starting at start and lasting duration
+// into a new WAV file next to it, and returns its path.
+func (e *Extractor) ExtractSegment(ctx context.Context, filePath string, start, duration time.Duration) (string, error) {
+	in, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %w", err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return "", fmt.Errorf("could not stat file: %w", err)
+	}
+
+	r := bufio.NewReaderSize(in, 64<<10)
+
+	format, err := readWAVHeader(r, info.Size())
+	if err != nil {
+		return "", fmt.Errorf("could not read WAV: %w", err)
+	}
+
+	outputPath := fmt.Sprintf("%s.%d.wav", filePath, start.Milliseconds())
+
+	skip := int64(start.Seconds()*float64(format.sampleRate)) * int64(format.blockAlign())
+	if _, err := io.CopyN(io.Discard, r, skip); err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("could not seek audio: %w", err)
+	}
+
+	frames := int64(duration.Seconds() * float64(format.sampleRate))
+
+	src := newWAVSource(io.LimitReader(r, frames*int64(format.blockAlign())), format)
+
+	if err := decode(ctx, src, format.sampleRate, outputPath, nil); err != nil {
+		os.Remove(outputPath)
+
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("decoding was stopped: %w", ctx.Err())
+		}
+		return "", err
+	}
+	return outputPath, nil
+}
+
+// source decodes the frames of an audio file into their left and right samples, between -1 and 1.
+type source interface {
+	// sampleRate returns the sample rate of the frames.
+	sampleRate() int
+
+	// next returns the next frames, or io.EOF after the last ones.
+	// The returned slices are reused by the next call.
+	next() ([]float64, []float64, error)
+
+	// progress returns the decoded fraction of the file, zero when unknown.
+	progress() float64
+}
+
+// openSource finds the format of the file by its signature, and returns the source of its frames.
+func openSource(f *os.File) (source, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("could not stat file: %w", err)
+	}
+
+	head := make([]byte, 12)
+	n, _ := f.ReadAt(head, 0)
+	head = head[:n]
+
+	switch {
+	case len(head) >= 12 && string(head[0:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		r := bufio.NewReaderSize(f, 64<<10)
+
+		format, err := readWAVHeader(r, info.Size())
+		if err != nil {
+			return nil, fmt.Errorf("could not read WAV: %w", err)
+		}
+		return newWAVSource(r, format), nil
+	case len(head) >= 8 && string(head[4:8]) == "ftyp":
+		track, err := readMP4(f, info.Size())
+		if err != nil {
+			return nil, fmt.Errorf("could not read MP4: %w", err)
+		}
+		return newMP4Source(f, track), nil
+	}
+
+	// Raw AAC and MP3 may start with the ID3 tags of their metadata.
+	var offset int64
+	if len(head) >= 10 && string(head[0:3]) == "ID3" {
+		offset = 10 + (int64(head[6]&0x7F)<<21 | int64(head[7]&0x7F)<<14 | int64(head[8]&0x7F)<<7 | int64(head[9]&0x7F))
+		if head[5]&0x10 != 0 {
+			offset += 10
+		}
+
+		n, _ := f.ReadAt(head[:2], offset)
+		head = head[:n]
+	}
+
+	switch {
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xF6 == 0xF0:
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("could not seek file: %w", err)
+		}
+
+		src, err := newADTSSource(bufio.NewReaderSize(f, 64<<10), info.Size()-offset)
+		if err != nil {
+			return nil, fmt.Errorf("could not read AAC: %w", err)
+		}
+		return src, nil
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0 && head[1]&0x06 != 0, offset > 0:
+		// The frames of MP3 may be padded away from their tags, which the decoder skips.
+		src, err := newMP3Source(f)
+		if err != nil {
+			return nil, fmt.Errorf("could not read MP3: %w", err)
+		}
+		return src, nil
+	default:
+		return nil, fmt.Errorf("%w: only WAV, MP3 and AAC audio can be decoded without ffmpeg", ErrUnsupported)
+	}
+}
+
+// decode converts the frames of the source into 16-bit stereo at the sample rate, written to the output WAV file.
+func decode(ctx context.Context, src source, sampleRate int, outputPath string, progress func(float64)) error {
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("could not create audio file: %w", err)
+	}
+	defer out.Close()
+
+	// The sizes are set once the samples are written.
+	if err := writeWAVHeader(out, sampleRate, 0); err != nil {
+		return fmt.Errorf("could not write audio file: %w", err)
+	}
+
+	w := bufio.NewWriterSize(out, 64<<10)
+	rs := resampler{w: w, from: int64(src.sampleRate()), to: int64(sampleRate)}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		left, right, err := src.next()
+
+		for i := range left {
+			if err := rs.push(left[i], right[i]); err != nil {
+				return fmt.Errorf("could not write audio file: %w", err)
+			}
+		}
+
+		if p := src.progress(); progress != nil && p > 0 {
+			progress(min(p, 1))
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := rs.flush(); err != nil {
+		return fmt.Errorf("could not write audio file: %w", err)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("could not write audio file: %w", err)
+	}
+
+	if _, err := out.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("could not write audio file: %w", err)
+	}
+
+	if err := writeWAVHeader(out, sampleRate, uint32(rs.written*4)); err != nil {
+		return fmt.Errorf("could not write audio file: %w", err)
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("could not close audio file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,416 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// bitWriter writes the bits of an AAC frame, most significant first.
+type bitWriter struct {
+	b []byte
+	n int
+}
+
+func (w *bitWriter) write(v uint32, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.b = append(w.b, 0)
+		}
+		w.b[len(w.b)-1] |= byte(v>>i&1) << (7 - w.n%8)
+		w.n++
+	}
+}
+
+func (w *bitWriter) code(c *huffmanCodebook, value int) {
+	w.write(c.codes[value], int(c.bits[value]))
+}
+
+// The sample rate index of 44100 Hz, whose first scalefactor band of the long window has 4 coefficients.
+const testSampleRateIndex = 4
+
+// aacFrame encodes a raw AAC-LC frame of a single channel, a long window whose first scalefactor
+// band holds the quantized values with codebook 1, the others being zero. Without values, the
+// frame is silent.
+func aacFrame(globalGain int, values []int) []byte {
+	var w bitWriter
+
+	w.write(elementSCE, 3)
+	w.write(0, 4) // Element tag.
+	w.write(uint32(globalGain), 8)
+
+	maxSFB := 0
+	if len(values) > 0 {
+		maxSFB = 1
+	}
+
+	// The ICS info: reserved bit, window sequence and shape, max scalefactor band, no prediction.
+	w.write(0, 1)
+	w.write(onlyLongSequence, 2)
+	w.write(0, 1)
+	w.write(uint32(maxSFB), 6)
+	w.write(0, 1)
+
+	if maxSFB > 0 {
+		// One section of codebook 1 over the band, its scalefactor equal to the global gain.
+		w.write(1, 4)
+		w.write(1, 5)
+		w.code(&aacScalefactorCodebook, 60)
+	}
+
+	w.write(0, 1) // No pulses.
+	w.write(0, 1) // No TNS.
+	w.write(0, 1) // No gain control.
+
+	if maxSFB > 0 {
+		w.code(&aacSpectralCodebooks[1], (values[0]+1)*27+(values[1]+1)*9+(values[2]+1)*3+(values[3]+1))
+	}
+
+	w.write(elementEND, 3)
+	return w.b
+}
+
+// adtsStream frames the raw AAC frames of a mono 44100 Hz stream in ADTS headers without CRC.
+func adtsStream(frames ...[]byte) []byte {
+	var out []byte
+	for _, f := range frames {
+		var w bitWriter
+		w.write(0xFFF, 12)              // Sync word.
+		w.write(0, 1)                   // MPEG-4.
+		w.write(0, 2)                   // Layer.
+		w.write(1, 1)                   // No CRC.
+		w.write(1, 2)                   // AAC-LC.
+		w.write(testSampleRateIndex, 4) // Sample rate.
+		w.write(0, 1)                   // Private bit.
+		w.write(1, 3)                   // Mono.
+		w.write(0, 4)                   // Originality, home and copyright bits.
+		w.write(uint32(7+len(f)), 13)   // Frame length, header included.
+		w.write(0x7FF, 11)              // Buffer fullness.
+		w.write(0, 2)                   // A single raw frame.
+		out = append(append(out, w.b...), f...)
+	}
+	return out
+}
+
+func mp4Box4(kind string, body ...[]byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(bytes.Join(body, nil))))
+	return append(append(b, kind...), bytes.Join(body, nil)...)
+}
+
+func u16(v int) []byte { return binary.BigEndian.AppendUint16(nil, uint16(v)) }
+func u32(v int) []byte { return binary.BigEndian.AppendUint32(nil, uint32(v)) }
+
+// mp4File puts the raw AAC frames of a mono 44100 Hz stream in the audio track of an MP4 file,
+// in a single chunk.
+func mp4File(frames ...[]byte) []byte {
+	ftyp := mp4Box4("ftyp", []byte("M4A "), u32(0), []byte("M4A mp42isom"))
+
+	// The audio specific config: AAC-LC, the sample rate index, mono, frames of 1024 samples.
+	config := []byte{2<<3 | testSampleRateIndex>>1, testSampleRateIndex&1<<7 | 1<<3}
+	decoderConfig := append([]byte{0x40, 0x15, 0, 0, 0}, make([]byte, 8)...)
+	decoderConfig = append(append(decoderConfig, 0x05, byte(len(config))), config...)
+	es := append([]byte{0, 1, 0, 0x04, byte(len(decoderConfig))}, decoderConfig...)
+	esds := mp4Box4("esds", u32(0), []byte{0x03, byte(len(es))}, es)
+
+	mp4a := mp4Box4("mp4a", make([]byte, 6), u16(1), make([]byte, 8), u16(1), u16(16), make([]byte, 4), u32(44100<<16), esds)
+	stsd := mp4Box4("stsd", u32(0), u32(1), mp4a)
+
+	sizes := [][]byte{u32(0), u32(0), u32(len(frames))}
+	for _, f := range frames {
+		sizes = append(sizes, u32(len(f)))
+	}
+	stsz := mp4Box4("stsz", sizes...)
+	stsc := mp4Box4("stsc", u32(0), u32(1), u32(1), u32(len(frames)), u32(1))
+
+	moov := func(offset int) []byte {
+		stbl := mp4Box4("stbl", stsd, stsz, stsc, mp4Box4("stco", u32(0), u32(1), u32(offset)))
+		mdhd := mp4Box4("mdhd", u32(0), u32(0), u32(0), u32(44100), u32(1024*len(frames)), u16(0), u16(0))
+		hdlr := mp4Box4("hdlr", u32(0), u32(0), []byte("soun"), make([]byte, 12), []byte{0})
+		return mp4Box4("moov", mp4Box4("trak", mp4Box4("mdia", mdhd, hdlr, mp4Box4("minf", stbl))))
+	}
+
+	offset := len(ftyp) + len(moov(0)) + 8
+	return bytes.Join([][]byte{ftyp, moov(offset), mp4Box4("mdat", frames...)}, nil)
+}
+
+// wavFile encodes the 16-bit samples of a WAV file of the channels, interleaved.
+func wavFile(sampleRate, channels int, samples []int16) []byte {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	b.Write(binary.LittleEndian.AppendUint32(nil, uint32(36+2*len(samples))))
+	b.WriteString("WAVEfmt ")
+	b.Write(binary.LittleEndian.AppendUint32(nil, 16))
+	b.Write(binary.LittleEndian.AppendUint16(nil, formatPCM))
+	b.Write(binary.LittleEndian.AppendUint16(nil, uint16(channels)))
+	b.Write(binary.LittleEndian.AppendUint32(nil, uint32(sampleRate)))
+	b.Write(binary.LittleEndian.AppendUint32(nil, uint32(sampleRate*channels*2)))
+	b.Write(binary.LittleEndian.AppendUint16(nil, uint16(channels*2)))
+	b.Write(binary.LittleEndian.AppendUint16(nil, 16))
+	b.WriteString("data")
+	b.Write(binary.LittleEndian.AppendUint32(nil, uint32(2*len(samples))))
+	for _, s := range samples {
+		b.Write(binary.LittleEndian.AppendUint16(nil, uint16(s)))
+	}
+	return b.Bytes()
+}
+
+func writeFile(t testing.TB, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// decodeAll returns the left and right samples of the file, decoded at their own sample rate.
+func decodeAll(t *testing.T, path string) (int, []float64, []float64) {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	src, err := openSource(f)
+	if err != nil {
+		t.Fatalf("could not open source: %s", err)
+	}
+
+	var left, right []float64
+	for {
+		l, r, err := src.next()
+		left, right = append(left, l...), append(right, r...)
+
+		if errors.Is(err, io.EOF) {
+			return src.sampleRate(), left, right
+		}
+		if err != nil {
+			t.Fatalf("could not decode: %s", err)
+		}
+	}
+}
+
+// goldenAAC returns the samples of the frames of aacFrame, the first one holding the values and
+// the others silent, from the definitions of ISO/IEC 14496-3 4.6.11: the inverse quantization,
+// the IMDCT of the long window, its sine window and the overlap of consecutive frames.
+func goldenAAC(globalGain int, values []int, frames int) []float64 {
+	const n = 2048
+
+	var spec [n / 2]float64
+	for k, q := range values {
+		spec[k] = math.Copysign(math.Pow(math.Abs(float64(q)), 4.0/3), float64(q)) * math.Pow(2, float64(globalGain-100)/4)
+	}
+
+	windowed := make([]float64, n)
+	for i := range windowed {
+		var x float64
+		for k, v := range spec {
+			x += v * math.Cos(2*math.Pi/n*(float64(i)+(n/2+1)/2.0)*(float64(k)+0.5))
+		}
+		windowed[i] = 2.0 / n * x * math.Sin(math.Pi/n*(float64(i)+0.5))
+	}
+
+	// The samples of the frames are scaled from 16-bit, the second half of the window
+	// is overlapped with the silent frame following it.
+	out := make([]float64, frames*n/2)
+	for i, v := range windowed {
+		if i < len(out) {
+			out[i] = v / 32768
+		}
+	}
+	return out
+}
+
+func TestDecodeAAC(t *testing.T) {
+	const globalGain = 160
+
+	values := []int{1, 0, -1, 0}
+	frames := [][]byte{aacFrame(globalGain, values), aacFrame(globalGain, nil), aacFrame(globalGain, nil)}
+	want := goldenAAC(globalGain, values, len(frames))
+
+	testCases := []struct {
+		name string
+		file string
+		data []byte
+	}{
+		{name: "ADTS", file: "audio.aac", data: adtsStream(frames...)},
+		{name: "MP4", file: "audio.m4a", data: mp4File(frames...)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rate, left, right := decodeAll(t, writeFile(t, tc.file, tc.data))
+
+			if rate != 44100 {
+				t.Errorf("sample rate = %d, want 44100", rate)
+			}
+
+			if len(left) != len(want) {
+				t.Fatalf("decoded %d samples, want %d", len(left), len(want))
+			}
+
+			for i := range want {
+				if math.Abs(left[i]-want[i]) > 1e-9 || left[i] != right[i] {
+					t.Fatalf("sample %d = %g, %g, want %g", i, left[i], right[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExtractAudioWAV(t *testing.T) {
+	samples := make([]int16, 1600)
+	for i := range samples {
+		samples[i] = int16(math.Round(math.Sin(float64(i)/10) * 12000))
+	}
+
+	path := writeFile(t, "audio.wav", wavFile(16000, 1, samples))
+
+	outputPath, err := NewExtractor().ExtractAudio(context.Background(), path, "16000", nil, nil)
+	if err != nil {
+		t.Fatalf("could not extract audio: %s", err)
+	}
+
+	out, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(out), 44+4*len(samples); got != want {
+		t.Fatalf("extracted %d bytes, want %d", got, want)
+	}
+
+	// The mono samples are copied to both sides, unchanged at the same sample rate.
+	for i, s := range samples {
+		left := int16(binary.LittleEndian.Uint16(out[44+4*i:]))
+		right := int16(binary.LittleEndian.Uint16(out[44+4*i+2:]))
+
+		if d := int(left) - int(s); left != right || d < -1 || d > 1 {
+			t.Fatalf("frame %d = %d, %d, want %d", i, left, right, s)
+		}
+	}
+}
+
+func TestExtractAudioMalformed(t *testing.T) {
+	hugeFormat := wavFile(16000, 1, make([]int16, 4))
+	binary.LittleEndian.PutUint32(hugeFormat[16:20], 0x7FFFFFF0)
+
+	manyChannels := wavFile(16000, 1, make([]int16, 4))
+	binary.LittleEndian.PutUint16(manyChannels[22:24], 0xFFFF)
+
+	hugeBox := mp4File(aacFrame(100, nil))
+	hugeBox = append(append(hugeBox[:24:24], 0, 0, 0, 1), []byte("moov\x7f\xff\xff\xff\xff\xff\xff\xff")...)
+
+	testCases := []struct {
+		name    string
+		file    string
+		data    []byte
+		wantErr bool
+	}{
+		{name: "WAV format chunk larger than the file", file: "a.wav", data: hugeFormat, wantErr: true},
+		{name: "WAV of too many channels", file: "a.wav", data: manyChannels, wantErr: true},
+		{name: "MP4 box larger than the file", file: "a.m4a", data: hugeBox, wantErr: true},
+		{name: "ADTS pulses out of the bands", file: "a.aac", data: []byte("\xff\xf1A0\x02\x1f0a0A\x00[0000")},
+		{name: "ADTS frame truncated", file: "a.aac", data: adtsStream(aacFrame(160, []int{1, 1, 1, 1}))[:12]},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeFile(t, tc.file, tc.data)
+
+			_, err := NewExtractor().ExtractAudio(context.Background(), path, "16000", nil, nil)
+			if tc.wantErr && err == nil {
+				t.Error("extracted audio of a malformed file")
+			}
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	frames := [][]byte{aacFrame(100, nil), aacFrame(100, nil), aacFrame(100, nil)}
+
+	testCases := []struct {
+		name    string
+		file    string
+		data    []byte
+		want    time.Duration
+		wantErr error
+	}{
+		{name: "WAV", file: "a.wav", data: wavFile(16000, 2, make([]int16, 2*8000)), want: 500 * time.Millisecond},
+		{name: "MP4", file: "a.m4a", data: mp4File(frames...), want: 3 * 1024 * time.Second / 44100},
+		{name: "ADTS", file: "a.aac", data: adtsStream(frames...), wantErr: ErrUnsupported},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeFile(t, tc.file, tc.data)
+
+			got, err := NewExtractor().Duration(context.Background(), path)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("got duration %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHuffmanCodebooks(t *testing.T) {
+	codebooks := map[string]*huffmanCodebook{"scalefactor": &aacScalefactorCodebook}
+	for i := 1; i < len(aacSpectralCodebooks); i++ {
+		codebooks[string(rune('0'+i/10))+string(rune('0'+i%10))] = &aacSpectralCodebooks[i]
+	}
+
+	for name, c := range codebooks {
+		t.Run(name, func(t *testing.T) {
+			// The codes of a complete prefix code fill the tree: their Kraft sum is one.
+			var sum float64
+			for value := range c.codes {
+				sum += math.Pow(2, -float64(c.bits[value]))
+
+				var w bitWriter
+				w.code(c, value)
+
+				got, err := c.decode(&bitReader{b: w.b})
+				if err != nil || got != value {
+					t.Fatalf("decoded %d, %v for value %d", got, err, value)
+				}
+			}
+
+			if sum != 1 {
+				t.Errorf("Kraft sum = %g, want 1", sum)
+			}
+		})
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	frames := [][]byte{aacFrame(160, []int{1, 0, -1, 0}), aacFrame(100, nil)}
+
+	f.Add(adtsStream(frames...))
+	f.Add(mp4File(frames...))
+	f.Add(wavFile(8000, 2, []int16{1, -1, 100, -100}))
+	f.Add([]byte("\xff\xf1A0\x02\x1f0a0A\x00[0000"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := writeFile(t, "audio", data)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		// Any input may fail, none may crash.
+		NewExtractor().ExtractAudio(ctx, path, "8000", nil, nil)
+	})
+}
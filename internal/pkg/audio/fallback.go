@@ -0,0 +1,75 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"time"
+)
+
+type extractor interface {
+	ExtractAudio(ctx context.Context, filePath, sampleRate string, filters []string, progress func(float64)) (string, error)
+	ExtractSegment(ctx context.Context, filePath string, start, duration time.Duration) (string, error)
+	Duration(ctx context.Context, filePath string) (time.Duration, error)
+}
+
+// Fallback extracts audio with ffmpeg, and in pure Go when the ffmpeg binary can't be found,
+// so that basic transcription works where ffmpeg can't be installed.
+type Fallback struct {
+	logger   *slog.Logger
+	ffmpeg   extractor
+	fallback *Extractor
+}
+
+// NewFallback returns a new audio extractor falling back to pure Go when ffmpeg is missing.
+func NewFallback(logger *slog.Logger, ffmpeg extractor) *Fallback {
+	return &Fallback{logger: logger, ffmpeg: ffmpeg, fallback: NewExtractor()}
+}
+
+// ExtractAudio extracts the audio of the file with ffmpeg, or decodes it in pure Go without the filters.
+func (f *Fallback) ExtractAudio(ctx context.Context, filePath, sampleRate string, filters []string, progress func(float64)) (_ string, err error) {
+	audioPath, err := f.ffmpeg.ExtractAudio(ctx, filePath, sampleRate, filters, progress)
+	if !errors.Is(err, exec.ErrNotFound) {
+		return audioPath, err
+	}
+
+	f.logger.Warn("Could not find ffmpeg, decoding the audio in pure Go", slog.String("filepath", filePath))
+
+	if len(filters) > 0 {
+		f.logger.Warn("Audio filters require ffmpeg, skipping them", slog.Any("filters", filters))
+	}
+
+	defer f.recoverDecoding(filePath, &err)
+	return f.fallback.ExtractAudio(ctx, filePath, sampleRate, nil, progress)
+}
+
+// ExtractSegment copies a part of the audio file with ffmpeg, or in pure Go.
+func (f *Fallback) ExtractSegment(ctx context.Context, filePath string, start, duration time.Duration) (_ string, err error) {
+	segmentPath, err := f.ffmpeg.ExtractSegment(ctx, filePath, start, duration)
+	if !errors.Is(err, exec.ErrNotFound) {
+		return segmentPath, err
+	}
+
+	defer f.recoverDecoding(filePath, &err)
+	return f.fallback.ExtractSegment(ctx, filePath, start, duration)
+}
+
+// Duration returns the duration of the file probed by ffmpeg, or read in pure Go.
+func (f *Fallback) Duration(ctx context.Context, filePath string) (time.Duration, error) {
+	d, err := f.ffmpeg.Duration(ctx, filePath)
+	if !errors.Is(err, exec.ErrNotFound) {
+		return d, err
+	}
+	return f.fallback.Duration(ctx, filePath)
+}
+
+// recoverDecoding turns a panic of the pure Go decoders into the error of the file, so that
+// a malformed upload fails its own transcription rather than the server.
+func (f *Fallback) recoverDecoding(filePath string, err *error) {
+	if r := recover(); r != nil {
+		f.logger.Error("Could not decode the audio in pure Go", slog.String("filepath", filePath), slog.Any("panic", r))
+		*err = fmt.Errorf("could not decode audio: %v", r)
+	}
+}
@@ -0,0 +1,247 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// The window sequences of AAC, from the long window to the eight short ones and back.
+const (
+	onlyLongSequence = iota
+	longStartSequence
+	eightShortSequence
+	longStopSequence
+)
+
+// imdct computes the inverse MDCT of n/2 coefficients into n samples, through a DCT-IV
+// computed with a complex FFT of n/8 points.
+type imdct struct {
+	n       int
+	twiddle []complex128 // The pre and post rotations of the DCT-IV.
+	fft     fft
+	buf     []complex128
+	dct     []float64
+}
+
+func newIMDCT(n int) *imdct {
+	m := n / 2
+
+	t := &imdct{
+		n:       n,
+		twiddle: make([]complex128, m/2),
+		fft:     newFFT(m / 2),
+		buf:     make([]complex128, m/2),
+		dct:     make([]float64, m),
+	}
+
+	for k := range t.twiddle {
+		t.twiddle[k] = cmplx.Exp(complex(0, -math.Pi*(float64(k)+0.125)/float64(m)))
+	}
+	return t
+}
+
+// transform computes the n samples of the coefficients, scaled by 2/n.
+func (t *imdct) transform(coefs, out []float64) {
+	m := t.n / 2
+
+	// The DCT-IV of the coefficients, as a complex FFT of half their length.
+	for k := range t.buf {
+		t.buf[k] = complex(coefs[2*k], coefs[m-1-2*k]) * t.twiddle[k]
+	}
+
+	t.fft.transform(t.buf)
+
+	scale := 2 / float64(t.n)
+	for k, v := range t.buf {
+		v *= t.twiddle[k]
+		t.dct[2*k] = real(v) * scale
+		t.dct[m-1-2*k] = -imag(v) * scale
+	}
+
+	// The samples are the DCT-IV extended by its symmetries.
+	for i := 0; i < m/2; i++ {
+		out[i] = t.dct[m/2+i]
+		out[m+m/2+i] = -t.dct[i]
+	}
+	for i := 0; i < m; i++ {
+		out[m/2+i] = -t.dct[m-1-i]
+	}
+}
+
+// fft is an iterative radix-2 complex FFT of a power of two points.
+type fft struct {
+	n        int
+	roots    []complex128
+	reversed []int
+}
+
+func newFFT(n int) fft {
+	f := fft{n: n, roots: make([]complex128, n/2), reversed: make([]int, n)}
+
+	for k := range f.roots {
+		f.roots[k] = cmplx.Exp(complex(0, -2*math.Pi*float64(k)/float64(n)))
+	}
+
+	bits := 0
+	for 1<<bits < n {
+		bits++
+	}
+	for i := range f.reversed {
+		r := 0
+		for b := 0; b < bits; b++ {
+			r |= (i >> b & 1) << (bits - 1 - b)
+		}
+		f.reversed[i] = r
+	}
+	return f
+}
+
+func (f fft) transform(x []complex128) {
+	for i, r := range f.reversed {
+		if i < r {
+			x[i], x[r] = x[r], x[i]
+		}
+	}
+
+	for size := 2; size <= f.n; size *= 2 {
+		step := f.n / size
+		for start := 0; start < f.n; start += size {
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], x[start+k+size/2]*f.roots[k*step]
+				x[start+k], x[start+k+size/2] = a+b, a-b
+			}
+		}
+	}
+}
+
+// The windows of AAC, by their shape: sine or Kaiser-Bessel derived.
+var (
+	longWindows  = [2][]float64{sineWindow(2048), kbdWindow(2048, 4)}
+	shortWindows = [2][]float64{sineWindow(256), kbdWindow(256, 6)}
+)
+
+func sineWindow(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = math.Sin(math.Pi / float64(n) * (float64(i) + 0.5))
+	}
+	return w
+}
+
+func kbdWindow(n int, alpha float64) []float64 {
+	kernel := make([]float64, n/2+1)
+
+	var total float64
+	for i := range kernel {
+		x := float64(i-n/4) / float64(n/4)
+		kernel[i] = besselI0(math.Pi * alpha * math.Sqrt(1-x*x))
+		total += kernel[i]
+	}
+
+	w := make([]float64, n)
+
+	var sum float64
+	for i := 0; i < n/2; i++ {
+		sum += kernel[i]
+		w[i] = math.Sqrt(sum / total)
+		w[n-1-i] = w[i]
+	}
+	return w
+}
+
+// besselI0 is the zeroth order modified Bessel function of the first kind, by its series.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > sum*1e-12; k++ {
+		term *= (x / 2 / float64(k)) * (x / 2 / float64(k))
+		sum += term
+	}
+	return sum
+}
+
+// filterbank turns the spectrum of a channel into its samples, overlapping the windows of consecutive frames.
+type filterbank struct {
+	long, short *imdct
+	overlap     []float64
+	prevShape   int
+	samples     []float64 // The windowed samples of the frame.
+	window      []float64 // The samples of a short window.
+	out         []float64
+}
+
+func newFilterbank() *filterbank {
+	return &filterbank{
+		long:    newIMDCT(2048),
+		short:   newIMDCT(256),
+		overlap: make([]float64, 1024),
+		samples: make([]float64, 2048),
+		window:  make([]float64, 256),
+		out:     make([]float64, 1024),
+	}
+}
+
+// transform returns the 1024 samples of the spectrum, between -1 and 1.
+// The returned slice is reused by the next call.
+func (f *filterbank) transform(spec []float64, sequence, shape int) []float64 {
+	long, short := longWindows, shortWindows
+	s := f.samples
+
+	switch sequence {
+	case eightShortSequence:
+		clear(s)
+
+		// The short windows overlap each other in the middle of the frame, the first one
+		// overlapping the previous frame with its shape.
+		for w := 0; w < 8; w++ {
+			f.short.transform(spec[w*128:(w+1)*128], f.window)
+
+			left := short[shape]
+			if w == 0 {
+				left = short[f.prevShape]
+			}
+
+			for i := 0; i < 128; i++ {
+				s[448+w*128+i] += f.window[i] * left[i]
+				s[448+w*128+128+i] += f.window[128+i] * short[shape][128+i]
+			}
+		}
+	default:
+		f.long.transform(spec, s)
+
+		switch sequence {
+		case longStopSequence:
+			for i := 0; i < 448; i++ {
+				s[i] = 0
+			}
+			for i := 0; i < 128; i++ {
+				s[448+i] *= short[f.prevShape][i]
+			}
+		default:
+			for i := 0; i < 1024; i++ {
+				s[i] *= long[f.prevShape][i]
+			}
+		}
+
+		switch sequence {
+		case longStartSequence:
+			for i := 0; i < 128; i++ {
+				s[1472+i] *= short[shape][128+i]
+			}
+			for i := 1600; i < 2048; i++ {
+				s[i] = 0
+			}
+		default:
+			for i := 1024; i < 2048; i++ {
+				s[i] *= long[shape][i]
+			}
+		}
+	}
+
+	for i := 0; i < 1024; i++ {
+		f.out[i] = (s[i] + f.overlap[i]) / 32768
+	}
+	copy(f.overlap, s[1024:])
+
+	f.prevShape = shape
+	return f.out
+}
@@ -0,0 +1,69 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// mp3Source decodes the frames of an MP3 file, always decoded into 16-bit stereo.
+type mp3Source struct {
+	decoder     *mp3.Decoder
+	read        int64
+	buf         []byte
+	left, right []float64
+}
+
+func newMP3Source(r io.ReadSeeker) (*mp3Source, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("could not seek file: %w", err)
+	}
+
+	decoder, err := mp3.NewDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mp3Source{
+		decoder: decoder,
+		buf:     make([]byte, 4*4096),
+		left:    make([]float64, 4096),
+		right:   make([]float64, 4096),
+	}, nil
+}
+
+func (s *mp3Source) sampleRate() int {
+	return s.decoder.SampleRate()
+}
+
+func (s *mp3Source) next() ([]float64, []float64, error) {
+	n, err := io.ReadFull(s.decoder, s.buf)
+	n -= n % 4
+	s.read += int64(n)
+
+	frames := n / 4
+	for i := 0; i < frames; i++ {
+		s.left[i] = float64(int16(binary.LittleEndian.Uint16(s.buf[4*i:]))) / (1 << 15)
+		s.right[i] = float64(int16(binary.LittleEndian.Uint16(s.buf[4*i+2:]))) / (1 << 15)
+	}
+
+	switch {
+	case errors.Is(err, io.EOF) && frames == 0:
+		return nil, nil, io.EOF
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		return s.left[:frames], s.right[:frames], nil
+	case err != nil:
+		return nil, nil, fmt.Errorf("could not decode MP3: %w", err)
+	}
+	return s.left[:frames], s.right[:frames], nil
+}
+
+func (s *mp3Source) progress() float64 {
+	if s.decoder.Length() <= 0 {
+		return 0
+	}
+	return float64(s.read) / float64(s.decoder.Length())
+}
@@ -0,0 +1,506 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxBoxSize bounds the boxes read into memory, the sample tables of hours of audio fit in a few megabytes.
+const maxBoxSize = 256 << 20
+
+// mp4Box is a box of an MP4 file, by the offset and size of its body.
+type mp4Box struct {
+	kind   string
+	offset int64
+	size   int64
+}
+
+// mp4Boxes returns the boxes between the offsets of the file.
+func mp4Boxes(r io.ReaderAt, start, end int64) ([]mp4Box, error) {
+	var (
+		boxes  []mp4Box
+		header [16]byte
+	)
+
+	for off := start; off+8 <= end; {
+		if _, err := r.ReadAt(header[:8], off); err != nil {
+			return nil, fmt.Errorf("could not read box: %w", err)
+		}
+
+		size, kind, headerSize := int64(binary.BigEndian.Uint32(header[0:4])), string(header[4:8]), int64(8)
+
+		switch size {
+		case 0:
+			size = end - off
+		case 1:
+			if _, err := r.ReadAt(header[8:16], off+8); err != nil {
+				return nil, fmt.Errorf("could not read box: %w", err)
+			}
+			size, headerSize = int64(binary.BigEndian.Uint64(header[8:16])), 16
+		}
+
+		if size < headerSize || size > end-off {
+			return nil, fmt.Errorf("invalid %q box of %d bytes", kind, size)
+		}
+
+		boxes = append(boxes, mp4Box{kind: kind, offset: off + headerSize, size: size - headerSize})
+		off += size
+	}
+	return boxes, nil
+}
+
+func findBox(boxes []mp4Box, kind string) (mp4Box, bool) {
+	for _, b := range boxes {
+		if b.kind == kind {
+			return b, true
+		}
+	}
+	return mp4Box{}, false
+}
+
+// children returns the boxes of the box at the path below it, e.g. "mdia", "minf", "stbl".
+func (b mp4Box) children(r io.ReaderAt, path ...string) ([]mp4Box, error) {
+	boxes, err := mp4Boxes(r, b.offset, b.offset+b.size)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, kind := range path {
+		box, ok := findBox(boxes, kind)
+		if !ok {
+			return nil, fmt.Errorf("missing %q box", kind)
+		}
+
+		if boxes, err = box.children(r); err != nil {
+			return nil, err
+		}
+	}
+	return boxes, nil
+}
+
+func (b mp4Box) read(r io.ReaderAt) ([]byte, error) {
+	if b.size > maxBoxSize {
+		return nil, fmt.Errorf("%q box of %d bytes is too large", b.kind, b.size)
+	}
+
+	body := make([]byte, b.size)
+	if _, err := r.ReadAt(body, b.offset); err != nil {
+		return nil, fmt.Errorf("could not read %q box: %w", b.kind, err)
+	}
+	return body, nil
+}
+
+// mp4Track is the AAC audio track of an MP4 file.
+type mp4Track struct {
+	config  aacConfig
+	samples []mp4Sample
+	skip    int // The number of frames the edit list leaves out at the start, the priming of the encoder.
+}
+
+type mp4Sample struct {
+	offset int64
+	size   int
+}
+
+// readMP4 finds the first audio track of the MP4 file, which must be AAC.
+func readMP4(r io.ReaderAt, size int64) (mp4Track, error) {
+	top, err := mp4Boxes(r, 0, size)
+	if err != nil {
+		return mp4Track{}, err
+	}
+
+	moov, ok := findBox(top, "moov")
+	if !ok {
+		return mp4Track{}, errors.New("missing \"moov\" box")
+	}
+
+	traks, err := moov.children(r)
+	if err != nil {
+		return mp4Track{}, err
+	}
+
+	for _, trak := range traks {
+		if trak.kind != "trak" {
+			continue
+		}
+
+		mdia, err := trak.children(r, "mdia")
+		if err != nil {
+			return mp4Track{}, err
+		}
+
+		hdlr, ok := findBox(mdia, "hdlr")
+		if !ok {
+			continue
+		}
+
+		body, err := hdlr.read(r)
+		if err != nil {
+			return mp4Track{}, err
+		}
+
+		if len(body) >= 12 && string(body[8:12]) == "soun" {
+			return readMP4Track(r, size, trak, mdia)
+		}
+	}
+	return mp4Track{}, errors.New("no audio track")
+}
+
+func readMP4Track(r io.ReaderAt, size int64, trak mp4Box, mdia []mp4Box) (mp4Track, error) {
+	minf, ok := findBox(mdia, "minf")
+	if !ok {
+		return mp4Track{}, errors.New("missing \"minf\" box")
+	}
+
+	stbl, err := minf.children(r, "stbl")
+	if err != nil {
+		return mp4Track{}, err
+	}
+
+	boxes := map[string][]byte{}
+	for _, kind := range []string{"stsd", "stsz", "stsc", "stco", "co64"} {
+		box, ok := findBox(stbl, kind)
+		if !ok {
+			continue
+		}
+
+		if boxes[kind], err = box.read(r); err != nil {
+			return mp4Track{}, err
+		}
+	}
+
+	var t mp4Track
+
+	if t.config, err = parseSampleDescription(boxes["stsd"]); err != nil {
+		return mp4Track{}, err
+	}
+
+	if t.samples, err = parseSampleTables(boxes["stsz"], boxes["stsc"], boxes["stco"], boxes["co64"], size); err != nil {
+		return mp4Track{}, err
+	}
+
+	if len(t.samples) == 0 {
+		return mp4Track{}, fmt.Errorf("%w: fragmented MP4", ErrUnsupported)
+	}
+
+	// The edit list skips the priming of the encoder, in the time scale of the media.
+	timescale := int64(t.config.sampleRate)
+	if mdhd, ok := findBox(mdia, "mdhd"); ok {
+		if body, err := mdhd.read(r); err == nil && len(body) >= 24 {
+			if body[0] == 1 {
+				timescale = int64(binary.BigEndian.Uint32(body[20:24]))
+			} else {
+				timescale = int64(binary.BigEndian.Uint32(body[12:16]))
+			}
+		}
+	}
+
+	if edts, err := trak.children(r, "edts"); err == nil && timescale > 0 {
+		if elst, ok := findBox(edts, "elst"); ok {
+			if body, err := elst.read(r); err == nil {
+				t.skip = int(parseEditList(body) * int64(t.config.sampleRate) / timescale)
+			}
+		}
+	}
+	return t, nil
+}
+
+// parseSampleDescription returns the AAC configuration of the first sample description.
+func parseSampleDescription(stsd []byte) (aacConfig, error) {
+	if len(stsd) < 16 {
+		return aacConfig{}, errors.New("missing sample description")
+	}
+
+	entry := stsd[8:]
+	size := int(binary.BigEndian.Uint32(entry[0:4]))
+	if size < 36 || size > len(entry) {
+		return aacConfig{}, errors.New("invalid sample description")
+	}
+
+	if kind := string(entry[4:8]); kind != "mp4a" {
+		return aacConfig{}, fmt.Errorf("%w: %q audio in MP4", ErrUnsupported, kind)
+	}
+
+	// The audio sample entry of QuickTime files grows with its version.
+	body := 36
+	switch binary.BigEndian.Uint16(entry[16:18]) {
+	case 1:
+		body += 16
+	case 2:
+		body += 36
+	}
+
+	if body+8 > size {
+		return aacConfig{}, errors.New("invalid sample description")
+	}
+
+	esds, ok := findESDS(entry[body:size], 0)
+	if !ok {
+		return aacConfig{}, errors.New("missing AAC decoder config")
+	}
+	return parseESDS(esds)
+}
+
+// findESDS finds the elementary stream descriptor in the boxes, which QuickTime nests in a "wave" box,
+// at the depth of their nesting. QuickTime never nests them twice.
+func findESDS(b []byte, depth int) ([]byte, bool) {
+	for len(b) >= 8 {
+		size, kind := int(binary.BigEndian.Uint32(b[0:4])), string(b[4:8])
+		if size < 8 || size > len(b) {
+			return nil, false
+		}
+
+		switch kind {
+		case "esds":
+			return b[8:size], true
+		case "wave":
+			if depth > 0 {
+				return nil, false
+			}
+			if esds, ok := findESDS(b[8:size], depth+1); ok {
+				return esds, true
+			}
+		}
+		b = b[size:]
+	}
+	return nil, false
+}
+
+// parseESDS returns the AAC configuration of the decoder specific info of the elementary stream descriptor.
+func parseESDS(b []byte) (aacConfig, error) {
+	if len(b) < 4 {
+		return aacConfig{}, errors.New("invalid elementary stream descriptor")
+	}
+	b = b[4:]
+
+	// The descriptors of the stream, of the decoder and of its specific info are nested in turn.
+	for _, tag := range []byte{0x03, 0x04, 0x05} {
+		body, ok := descriptor(b, tag)
+		if !ok {
+			return aacConfig{}, fmt.Errorf("missing descriptor %#x", tag)
+		}
+
+		switch tag {
+		case 0x03:
+			if len(body) < 3 {
+				return aacConfig{}, errors.New("invalid elementary stream descriptor")
+			}
+
+			flags, skip := body[2], 3
+			if flags&0x80 != 0 {
+				skip += 2
+			}
+			if flags&0x40 != 0 && len(body) > skip {
+				skip += 1 + int(body[skip])
+			}
+			if flags&0x20 != 0 {
+				skip += 2
+			}
+
+			if skip > len(body) {
+				return aacConfig{}, errors.New("invalid elementary stream descriptor")
+			}
+			b = body[skip:]
+		case 0x04:
+			if len(body) < 13 {
+				return aacConfig{}, errors.New("invalid decoder config descriptor")
+			}
+
+			// MPEG-4 audio, and the AAC profiles of MPEG-2.
+			if objectType := body[0]; objectType != 0x40 && (objectType < 0x66 || objectType > 0x68) {
+				return aacConfig{}, fmt.Errorf("%w: MP4 audio object type %#x", ErrUnsupported, objectType)
+			}
+			b = body[13:]
+		default:
+			return parseAudioSpecificConfig(body)
+		}
+	}
+	return aacConfig{}, errors.New("missing decoder specific info")
+}
+
+// descriptor returns the body of the descriptor with the tag at the start of b.
+func descriptor(b []byte, tag byte) ([]byte, bool) {
+	if len(b) < 2 || b[0] != tag {
+		return nil, false
+	}
+
+	size, i := 0, 1
+	for ; i < len(b) && i <= 4; i++ {
+		size = size<<7 | int(b[i]&0x7F)
+		if b[i]&0x80 == 0 {
+			break
+		}
+	}
+	i++
+
+	if i+size > len(b) {
+		return nil, false
+	}
+	return b[i : i+size], true
+}
+
+// parseSampleTables returns the offsets and sizes of the samples, by the chunks they are in.
+// The samples must lie within the file of the size.
+func parseSampleTables(stsz, stsc, stco, co64 []byte, size int64) ([]mp4Sample, error) {
+	if len(stsz) < 12 || len(stsc) < 8 {
+		return nil, errors.New("missing sample tables")
+	}
+
+	fixed, count := int(binary.BigEndian.Uint32(stsz[4:8])), int(binary.BigEndian.Uint32(stsz[8:12]))
+	if fixed == 0 && len(stsz) < 12+4*count || fixed > 0 && int64(fixed)*int64(count) > size {
+		return nil, errors.New("invalid sample sizes")
+	}
+
+	var chunks []int64
+	switch {
+	case len(stco) >= 8:
+		n := int(binary.BigEndian.Uint32(stco[4:8]))
+		if len(stco) < 8+4*n {
+			return nil, errors.New("invalid chunk offsets")
+		}
+		for i := 0; i < n; i++ {
+			chunks = append(chunks, int64(binary.BigEndian.Uint32(stco[8+4*i:])))
+		}
+	case len(co64) >= 8:
+		n := int(binary.BigEndian.Uint32(co64[4:8]))
+		if len(co64) < 8+8*n {
+			return nil, errors.New("invalid chunk offsets")
+		}
+		for i := 0; i < n; i++ {
+			chunks = append(chunks, int64(binary.BigEndian.Uint64(co64[8+8*i:])))
+		}
+	default:
+		return nil, errors.New("missing chunk offsets")
+	}
+
+	entries := int(binary.BigEndian.Uint32(stsc[4:8]))
+	if len(stsc) < 8+12*entries {
+		return nil, errors.New("invalid sample to chunk table")
+	}
+
+	samples := make([]mp4Sample, 0, count)
+
+	for i := 0; i < entries && len(samples) < count; i++ {
+		entry := stsc[8+12*i:]
+		first, perChunk := int(binary.BigEndian.Uint32(entry[0:4])), int(binary.BigEndian.Uint32(entry[4:8]))
+
+		last := len(chunks)
+		if i+1 < entries {
+			last = int(binary.BigEndian.Uint32(stsc[8+12*(i+1):])) - 1
+		}
+
+		if first < 1 || last > len(chunks) {
+			return nil, errors.New("invalid sample to chunk table")
+		}
+
+		for c := first; c <= last && len(samples) < count; c++ {
+			offset := chunks[c-1]
+
+			for s := 0; s < perChunk && len(samples) < count; s++ {
+				sampleSize := fixed
+				if sampleSize == 0 {
+					sampleSize = int(binary.BigEndian.Uint32(stsz[12+4*len(samples):]))
+				}
+
+				if offset < 0 || offset+int64(sampleSize) > size {
+					return nil, fmt.Errorf("invalid sample %d out of the file", len(samples))
+				}
+
+				samples = append(samples, mp4Sample{offset: offset, size: sampleSize})
+				offset += int64(sampleSize)
+			}
+		}
+	}
+
+	if len(samples) < count {
+		return nil, fmt.Errorf("invalid sample tables: %d of %d samples in chunks", len(samples), count)
+	}
+	return samples, nil
+}
+
+// parseEditList returns the start of the media in its first edit, skipping the empty ones.
+func parseEditList(elst []byte) int64 {
+	if len(elst) < 8 {
+		return 0
+	}
+
+	version, entries, b := elst[0], int(binary.BigEndian.Uint32(elst[4:8])), elst[8:]
+
+	for i := 0; i < entries; i++ {
+		var start int64
+
+		if version == 1 {
+			if len(b) < 20 {
+				return 0
+			}
+			start, b = int64(binary.BigEndian.Uint64(b[8:16])), b[20:]
+		} else {
+			if len(b) < 12 {
+				return 0
+			}
+			start, b = int64(int32(binary.BigEndian.Uint32(b[4:8]))), b[12:]
+		}
+
+		if start >= 0 {
+			return start
+		}
+	}
+	return 0
+}
+
+// mp4Source decodes the AAC samples of an MP4 track.
+type mp4Source struct {
+	r       io.ReaderAt
+	track   mp4Track
+	decoder *aacDecoder
+	sample  int // The index of the next sample.
+	skip    int
+	buf     []byte
+}
+
+func newMP4Source(r io.ReaderAt, track mp4Track) *mp4Source {
+	return &mp4Source{r: r, track: track, decoder: newAACDecoder(track.config), skip: track.skip}
+}
+
+func (s *mp4Source) sampleRate() int {
+	return s.track.config.sampleRate
+}
+
+func (s *mp4Source) next() ([]float64, []float64, error) {
+	if s.sample >= len(s.track.samples) {
+		return nil, nil, io.EOF
+	}
+
+	sample := s.track.samples[s.sample]
+	s.sample++
+
+	if sample.size > maxFrameSize {
+		return nil, nil, fmt.Errorf("invalid AAC frame of %d bytes", sample.size)
+	}
+
+	if cap(s.buf) < sample.size {
+		s.buf = make([]byte, sample.size)
+	}
+	s.buf = s.buf[:sample.size]
+
+	if _, err := s.r.ReadAt(s.buf, sample.offset); err != nil {
+		return nil, nil, fmt.Errorf("could not read audio: %w", err)
+	}
+
+	left, right, err := s.decoder.decode(s.buf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if s.skip > 0 {
+		n := min(s.skip, len(left))
+		left, right, s.skip = left[n:], right[n:], s.skip-n
+	}
+	return left, right, nil
+}
+
+func (s *mp4Source) progress() float64 {
+	return float64(s.sample) / float64(len(s.track.samples))
+}
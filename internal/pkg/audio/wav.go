@@ -0,0 +1,312 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	formatPCM        = 0x0001
+	formatFloat      = 0x0003
+	formatExtensible = 0xFFFE
+)
+
+// maxWAVChannels bounds the channels of a WAV file, whose frames are buffered by thousands.
+const maxWAVChannels = 64
+
+// wavFormat is the format of the samples of a WAV file.
+type wavFormat struct {
+	format        uint16
+	channels      int
+	sampleRate    int
+	bitsPerSample int
+	dataSize      int64 // Zero when unknown, e.g. written by a stream.
+}
+
+func (f wavFormat) blockAlign() int {
+	return f.channels * f.bitsPerSample / 8
+}
+
+// readWAVHeader reads the chunks of a WAV file of the size up to its samples, and returns their format.
+// The sizes of the chunks are checked against the rest of the file before reading them.
+func readWAVHeader(r io.Reader, size int64) (wavFormat, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return wavFormat{}, fmt.Errorf("could not read header: %w", err)
+	}
+
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return wavFormat{}, errors.New("not a WAV file")
+	}
+
+	var (
+		f       wavFormat
+		hasFmt  bool
+		chunkID [8]byte
+		offset  = int64(len(riff))
+	)
+
+	for {
+		if _, err := io.ReadFull(r, chunkID[:]); err != nil {
+			return wavFormat{}, fmt.Errorf("could not read chunk: %w", err)
+		}
+		offset += int64(len(chunkID))
+
+		id, chunkSize := string(chunkID[0:4]), int64(binary.LittleEndian.Uint32(chunkID[4:8]))
+		remaining := size - offset
+
+		switch id {
+		case "fmt ":
+			if chunkSize < 16 || chunkSize > remaining {
+				return wavFormat{}, fmt.Errorf("invalid format chunk of %d bytes", chunkSize)
+			}
+
+			// Only the fields of the extensible format are read, up to its sub format.
+			var body [26]byte
+			n := min(chunkSize, int64(len(body)))
+
+			if _, err := io.ReadFull(r, body[:n]); err != nil {
+				return wavFormat{}, fmt.Errorf("could not read format: %w", err)
+			}
+
+			if _, err := io.CopyN(io.Discard, r, min(chunkSize-n+chunkSize%2, remaining-n)); err != nil {
+				return wavFormat{}, fmt.Errorf("could not read format: %w", err)
+			}
+			offset += chunkSize + chunkSize%2
+
+			f.format = binary.LittleEndian.Uint16(body[0:2])
+			f.channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			f.sampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			f.bitsPerSample = int(binary.LittleEndian.Uint16(body[14:16]))
+
+			// The sub format of an extensible format starts with the actual format.
+			if f.format == formatExtensible && chunkSize >= 26 {
+				f.format = binary.LittleEndian.Uint16(body[24:26])
+			}
+			hasFmt = true
+		case "data":
+			if !hasFmt {
+				return wavFormat{}, errors.New("samples before their format")
+			}
+
+			// Streams leave the size unset, the samples then run to the end of the file,
+			// as they do for truncated files.
+			if chunkSize != 0 && chunkSize != math.MaxUint32 {
+				f.dataSize = min(chunkSize, remaining)
+			}
+			return f, f.validate()
+		default:
+			if chunkSize > remaining {
+				return wavFormat{}, fmt.Errorf("invalid %q chunk of %d bytes", id, chunkSize)
+			}
+
+			if _, err := io.CopyN(io.Discard, r, min(chunkSize+chunkSize%2, remaining)); err != nil {
+				return wavFormat{}, fmt.Errorf("could not skip %q chunk: %w", id, err)
+			}
+			offset += chunkSize + chunkSize%2
+		}
+	}
+}
+
+func (f wavFormat) validate() error {
+	if f.channels < 1 || f.channels > maxWAVChannels || f.sampleRate < 1 {
+		return fmt.Errorf("invalid format: %d channels at %d Hz", f.channels, f.sampleRate)
+	}
+
+	switch {
+	case f.format == formatPCM && (f.bitsPerSample == 8 || f.bitsPerSample == 16 || f.bitsPerSample == 24 || f.bitsPerSample == 32):
+	case f.format == formatFloat && (f.bitsPerSample == 32 || f.bitsPerSample == 64):
+	default:
+		return fmt.Errorf("%w: WAV format %#x with %d bits per sample", ErrUnsupported, f.format, f.bitsPerSample)
+	}
+	return nil
+}
+
+// sample decodes the sample at the start of b, between -1 and 1.
+func (f wavFormat) sample(b []byte) float64 {
+	switch {
+	case f.format == formatFloat && f.bitsPerSample == 32:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case f.format == formatFloat:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case f.bitsPerSample == 8:
+		return (float64(b[0]) - 128) / 128
+	case f.bitsPerSample == 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case f.bitsPerSample == 24:
+		return float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+}
+
+// stereo decodes a frame into its left and right samples. Mono is copied to both sides,
+// and only the front left and right channels of surround audio are kept.
+func (f wavFormat) stereo(frame []byte) (float64, float64) {
+	left := f.sample(frame)
+	if f.channels == 1 {
+		return left, left
+	}
+	return left, f.sample(frame[f.bitsPerSample/8:])
+}
+
+// wavSource decodes the samples of a WAV file.
+type wavSource struct {
+	r           io.Reader
+	format      wavFormat
+	read        int64
+	buf         []byte
+	left, right []float64
+}
+
+func newWAVSource(r io.Reader, format wavFormat) *wavSource {
+	if format.dataSize > 0 {
+		r = io.LimitReader(r, format.dataSize)
+	}
+
+	return &wavSource{
+		r:      r,
+		format: format,
+		buf:    make([]byte, format.blockAlign()*4096),
+		left:   make([]float64, 4096),
+		right:  make([]float64, 4096),
+	}
+}
+
+func (s *wavSource) sampleRate() int {
+	return s.format.sampleRate
+}
+
+func (s *wavSource) next() ([]float64, []float64, error) {
+	blockAlign := s.format.blockAlign()
+
+	n, err := io.ReadFull(s.r, s.buf)
+	n -= n % blockAlign
+
+	frames := n / blockAlign
+	for i := 0; i < frames; i++ {
+		s.left[i], s.right[i] = s.format.stereo(s.buf[i*blockAlign : (i+1)*blockAlign])
+	}
+	s.read += int64(n)
+
+	switch {
+	case errors.Is(err, io.EOF) && frames == 0:
+		return nil, nil, io.EOF
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		return s.left[:frames], s.right[:frames], nil
+	case err != nil:
+		return nil, nil, fmt.Errorf("could not read audio: %w", err)
+	}
+	return s.left[:frames], s.right[:frames], nil
+}
+
+func (s *wavSource) progress() float64 {
+	if s.format.dataSize == 0 {
+		return 0
+	}
+	return float64(s.read) / float64(s.format.dataSize)
+}
+
+// writeWAVHeader writes the header of 16-bit stereo samples at the sample rate, dataSize bytes long.
+func writeWAVHeader(w io.Writer, sampleRate int, dataSize uint32) error {
+	const (
+		channels      = 2
+		bitsPerSample = 16
+	)
+
+	header := make([]byte, 0, 44)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, 36+dataSize)
+	header = append(header, "WAVEfmt "...)
+	header = binary.LittleEndian.AppendUint32(header, 16)
+	header = binary.LittleEndian.AppendUint16(header, formatPCM)
+	header = binary.LittleEndian.AppendUint16(header, channels)
+	header = binary.LittleEndian.AppendUint32(header, uint32(sampleRate))
+	header = binary.LittleEndian.AppendUint32(header, uint32(sampleRate*channels*bitsPerSample/8))
+	header = binary.LittleEndian.AppendUint16(header, channels*bitsPerSample/8)
+	header = binary.LittleEndian.AppendUint16(header, bitsPerSample)
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, dataSize)
+
+	_, err := w.Write(header)
+	return err
+}
+
+// resampler converts stereo frames to another sample rate, averaging the frames of each output
+// frame when downsampling, which filters out most of the aliasing, and interpolating when upsampling.
+type resampler struct {
+	w        *bufio.Writer
+	from, to int64
+	written  int64 // Number of output frames.
+
+	in         int64 // Number of input frames.
+	sumL, sumR float64
+	count      int
+	prevL      float64
+	prevR      float64
+}
+
+func (r *resampler) push(left, right float64) error {
+	defer func() { r.in++ }()
+
+	switch {
+	case r.from == r.to:
+		return r.emit(left, right)
+	case r.from > r.to:
+		// The input frame n belongs to the output frame n*to/from.
+		if r.count > 0 && r.in*r.to/r.from != r.written {
+			if err := r.flush(); err != nil {
+				return err
+			}
+		}
+		r.sumL, r.sumR, r.count = r.sumL+left, r.sumR+right, r.count+1
+		return nil
+	default:
+		if r.in == 0 {
+			r.prevL, r.prevR = left, right
+		}
+
+		// Emits the output frames between the previous input frame and this one.
+		for r.written*r.from <= r.in*r.to {
+			frac := float64(r.written*r.from-(r.in-1)*r.to) / float64(r.to)
+			if r.in == 0 {
+				frac = 1
+			}
+
+			if err := r.emit(r.prevL+(left-r.prevL)*frac, r.prevR+(right-r.prevR)*frac); err != nil {
+				return err
+			}
+		}
+		r.prevL, r.prevR = left, right
+		return nil
+	}
+}
+
+// flush emits the averaged frames when downsampling.
+func (r *resampler) flush() error {
+	if r.count == 0 {
+		return nil
+	}
+
+	left, right := r.sumL/float64(r.count), r.sumR/float64(r.count)
+	r.sumL, r.sumR, r.count = 0, 0, 0
+	return r.emit(left, right)
+}
+
+func (r *resampler) emit(left, right float64) error {
+	var frame [4]byte
+	binary.LittleEndian.PutUint16(frame[0:2], uint16(pcm16(left)))
+	binary.LittleEndian.PutUint16(frame[2:4], uint16(pcm16(right)))
+
+	r.written++
+	_, err := r.w.Write(frame[:])
+	return err
+}
+
+func pcm16(v float64) int16 {
+	return int16(math.Round(max(-1, min(v, 1)) * math.MaxInt16))
+}
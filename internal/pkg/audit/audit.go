@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry represents a single audit record.
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	Action  string            `json:"action"`
+	Subject string            `json:"subject"`
+	Actor   string            `json:"actor,omitempty"`   // The authenticated user, empty for anonymous requests.
+	Address string            `json:"address,omitempty"` // The remote address of the request, e.g. of the SSO proxy.
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Log is an append-only audit log persisted as JSON lines.
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// New returns a new audit log writing to the given file path.
+func New(path string) *Log {
+	return &Log{path: path}
+}
+
+// Record appends an entry to the audit log.
+func (l *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("could not marshal audit entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not open audit log: %w", err)
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("could not write audit entry: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("could not close audit log: %w", err)
+	}
+	return nil
+}
+
+// List returns all entries in the audit log, oldest first.
+func (l *Log) List() ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("could not unmarshal audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read audit log: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrInvalidKeys is returned when the API keys can not be parsed.
+	ErrInvalidKeys = errors.New("invalid API keys")
+
+	// userNameRe matches the user names that can be used as directory names.
+	userNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+type key struct {
+	user  string
+	value string
+}
+
+// Keys identifies users by their API key. Without keys, authentication is disabled.
+type Keys struct {
+	keys []key
+}
+
+// ParseKeys parses a comma-separated list of user:key pairs, e.g. "alice:s3cr3t,bob:hunter2".
+// A user can have several keys.
+func ParseKeys(s string) (*Keys, error) {
+	var k Keys
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		user, value, ok := strings.Cut(pair, ":")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: expected user:key, got %q", ErrInvalidKeys, pair)
+		}
+
+		if !userNameRe.MatchString(user) {
+			return nil, fmt.Errorf("%w: invalid user name %q", ErrInvalidKeys, user)
+		}
+
+		if _, found := k.User(value); found {
+			return nil, fmt.Errorf("%w: duplicate key for user %q", ErrInvalidKeys, user)
+		}
+		k.keys = append(k.keys, key{user: user, value: value})
+	}
+	return &k, nil
+}
+
+// Enabled reports whether any key is configured.
+func (k *Keys) Enabled() bool {
+	return len(k.keys) > 0
+}
+
+// User returns the user identified by the API key.
+func (k *Keys) User(value string) (string, bool) {
+	var user string
+
+	// Every key is compared in constant time, so the timing doesn't reveal which keys exist.
+	for _, key := range k.keys {
+		if subtle.ConstantTimeCompare([]byte(key.value), []byte(value)) == 1 {
+			user = key.user
+		}
+	}
+	return user, user != ""
+}
@@ -0,0 +1,175 @@
+// Package callback notifies the callback URLs given with the jobs once they finish. The JSON payload
+// is signed with HMAC-SHA256, like the requests of Slack, so that receivers can verify it came from
+// the server: the X-Videoscriber-Signature header is "v0=" followed by the hex encoded HMAC of
+// "v0:<X-Videoscriber-Timestamp>:<body>" with the callback secret.
+package callback
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/ingest"
+)
+
+const (
+	// maxAttempts is how many times a callback is sent before giving up.
+	maxAttempts int = 6
+
+	// minBackoff is the delay before the first retry. It doubles with each retry, up to maxBackoff.
+	minBackoff time.Duration = 2 * time.Second
+	maxBackoff time.Duration = 2 * time.Minute
+
+	// maxResponse bounds how much of the receiver's response is read.
+	maxResponse int64 = 4 << 10
+)
+
+var (
+	// ErrInvalidURL is returned when a callback URL is not an absolute http(s) URL.
+	ErrInvalidURL = errors.New("invalid callback url")
+
+	// errPermanent marks the failures not worth retrying, e.g. a 404 from the receiver.
+	errPermanent = errors.New("permanent failure")
+)
+
+// Payload is the JSON body of a callback.
+type Payload struct {
+	JobID      string    `json:"job_id"`
+	Status     string    `json:"status"` // done or failed.
+	Files      []File    `json:"files"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// File is the outcome of a file of the job.
+type File struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	Subtitle    string `json:"subtitle,omitempty"`
+	DownloadURL string `json:"download_url,omitempty"` // Requires the API key of the job's owner.
+}
+
+// Notifier sends the callbacks. It is enabled when a secret is configured.
+type Notifier struct {
+	httpCli *http.Client
+	secret  string
+	now     func() time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+// New returns a new notifier signing the callbacks with the secret.
+func New(httpCli *http.Client, secret string) *Notifier {
+	return &Notifier{httpCli: httpCli, secret: secret, now: time.Now, sleep: sleep}
+}
+
+// Enabled reports whether callbacks can be sent, that is whether a secret is configured.
+func (n *Notifier) Enabled() bool {
+	return n.secret != ""
+}
+
+// ValidateURL checks that the callback URL is an absolute http(s) URL, whose host isn't the IP address
+// of an internal service. Host names are checked once resolved, by the transport of the notifier.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+
+	if ip := net.ParseIP(u.Hostname()); (ip != nil && !ingest.Public(ip)) || strings.EqualFold(u.Hostname(), "localhost") {
+		return fmt.Errorf("%w: %w", ErrInvalidURL, ingest.ErrForbiddenAddress)
+	}
+	return nil
+}
+
+// Notify posts the payload to the callback URL, retrying with an exponential backoff when the request
+// fails or the receiver responds with 429 or a 5xx status, until it is accepted or maxAttempts is reached.
+func (n *Notifier) Notify(ctx context.Context, callbackURL string, p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("could not marshal payload: %w", err)
+	}
+
+	backoff := minBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := n.send(ctx, callbackURL, body)
+		if err == nil {
+			return nil
+		}
+
+		if errors.Is(err, errPermanent) || attempt == maxAttempts || ctx.Err() != nil {
+			return fmt.Errorf("could not notify callback after %d attempts: %w", attempt, err)
+		}
+
+		if err := n.sleep(ctx, backoff); err != nil {
+			return fmt.Errorf("could not notify callback after %d attempts: %w", attempt, err)
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+func (n *Notifier) send(ctx context.Context, callbackURL string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: could not create request: %s", errPermanent, err)
+	}
+
+	timestamp := strconv.FormatInt(n.now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Videoscriber-Timestamp", timestamp)
+	req.Header.Set("X-Videoscriber-Signature", Sign(n.secret, timestamp, body))
+
+	resp, err := n.httpCli.Do(req)
+	if err != nil {
+		if errors.Is(err, ingest.ErrForbiddenAddress) {
+			return fmt.Errorf("%w: could not send request: %w", errPermanent, err)
+		}
+		return fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	err = fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, data)
+
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return fmt.Errorf("%w: %s", errPermanent, err)
+	}
+	return err
+}
+
+// Sign returns the signature of a callback body sent at the timestamp, in Unix seconds.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,171 @@
+// Package chaos injects faults into the pipeline, e.g. a slow provider, a failing ffmpeg or a full disk,
+// so that integration tests can exercise its failure paths deterministically.
+// Servers only inject faults in test mode.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/transcriber"
+)
+
+// Faults.
+const (
+	FaultSlowProvider  = "slow-provider"  // Delays the transcription requests, e.g. slow-provider=30s.
+	FaultFailingFFmpeg = "failing-ffmpeg" // Fails the extraction of audio and the rendering of videos.
+	FaultFullDisk      = "full-disk"      // Fails the writes of subtitles and their artifacts.
+)
+
+// ErrInjected is wrapped by the errors of injected faults.
+var ErrInjected = errors.New("injected fault")
+
+// Faults are the faults injected into the pipeline.
+type Faults struct {
+	ProviderDelay time.Duration
+	FailingFFmpeg bool
+	FullDisk      bool
+}
+
+// Parse parses a comma-separated list of faults, e.g. "slow-provider=5s,full-disk".
+func Parse(spec string) (Faults, error) {
+	var f Faults
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(item, "=")
+
+		switch name {
+		case FaultSlowProvider:
+			delay, err := time.ParseDuration(value)
+			if err != nil || delay <= 0 {
+				return Faults{}, fmt.Errorf("invalid delay of %s %q", name, value)
+			}
+			f.ProviderDelay = delay
+		case FaultFailingFFmpeg:
+			f.FailingFFmpeg = true
+		case FaultFullDisk:
+			f.FullDisk = true
+		default:
+			return Faults{}, fmt.Errorf("unknown fault %q", name)
+		}
+	}
+	return f, nil
+}
+
+// Any reports whether any fault is injected.
+func (f Faults) Any() bool {
+	return f.ProviderDelay > 0 || f.FailingFFmpeg || f.FullDisk
+}
+
+// FFmpeg runs ffmpeg to extract audio and render videos.
+type FFmpeg interface {
+	ExtractAudio(ctx context.Context, filePath, sampleRate string, filters []string, progress func(float64)) (string, error)
+	ExtractSegment(ctx context.Context, filePath string, start, duration time.Duration) (string, error)
+	Duration(ctx context.Context, filePath string) (time.Duration, error)
+	RenderClip(ctx context.Context, videoPath, subtitlePath, forceStyle string, start, duration time.Duration, width, height int, outputPath string) error
+	BurnSubtitles(ctx context.Context, videoPath, subtitlePath, forceStyle, outputPath string) error
+}
+
+// Storage stores subtitles and their artifacts.
+type Storage interface {
+	Write(owner, language, project, fileName string, data []byte) (string, error)
+	Move(owner, language, project, fileName, srcPath string) (string, error)
+}
+
+// Transcriber returns the transcriber with the provider fault injected, if any.
+func (f Faults) Transcriber(t transcriber.Transcriber) transcriber.Transcriber {
+	if f.ProviderDelay > 0 {
+		return NewSlowTranscriber(t, f.ProviderDelay)
+	}
+	return t
+}
+
+// FFmpeg returns ffmpeg with the ffmpeg fault injected, if any.
+func (f Faults) FFmpeg(e FFmpeg) FFmpeg {
+	if f.FailingFFmpeg {
+		return FailingFFmpeg{}
+	}
+	return e
+}
+
+// Storage returns the storage with the disk fault injected, if any.
+func (f Faults) Storage(s Storage) Storage {
+	if f.FullDisk {
+		return FullDisk{}
+	}
+	return s
+}
+
+// SlowTranscriber delays the requests of a transcriber, unless their context is done first.
+type SlowTranscriber struct {
+	transcriber transcriber.Transcriber
+	delay       time.Duration
+}
+
+// NewSlowTranscriber returns the transcriber delaying its requests.
+func NewSlowTranscriber(t transcriber.Transcriber, delay time.Duration) *SlowTranscriber {
+	return &SlowTranscriber{transcriber: t, delay: delay}
+}
+
+func (s *SlowTranscriber) Transcribe(ctx context.Context, req transcriber.Request) ([]byte, error) {
+	timer := time.NewTimer(s.delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("slow provider was stopped: %w", ctx.Err())
+	}
+	return s.transcriber.Transcribe(ctx, req)
+}
+
+// Describe tells the provider and model of the delayed transcriber, when it does.
+func (s *SlowTranscriber) Describe(req transcriber.Request) (string, string) {
+	if d, ok := s.transcriber.(transcriber.Describer); ok {
+		return d.Describe(req)
+	}
+	return "", ""
+}
+
+// FailingFFmpeg fails every ffmpeg run, like a broken or incompatible binary would.
+type FailingFFmpeg struct{}
+
+func (FailingFFmpeg) ExtractAudio(context.Context, string, string, []string, func(float64)) (string, error) {
+	return "", fmt.Errorf("could not run ffmpeg: %w", ErrInjected)
+}
+
+func (FailingFFmpeg) ExtractSegment(context.Context, string, time.Duration, time.Duration) (string, error) {
+	return "", fmt.Errorf("could not run ffmpeg: %w", ErrInjected)
+}
+
+func (FailingFFmpeg) Duration(context.Context, string) (time.Duration, error) {
+	return 0, fmt.Errorf("could not run ffmpeg: %w", ErrInjected)
+}
+
+func (FailingFFmpeg) RenderClip(context.Context, string, string, string, time.Duration, time.Duration, int, int, string) error {
+	return fmt.Errorf("could not run ffmpeg: %w", ErrInjected)
+}
+
+func (FailingFFmpeg) BurnSubtitles(context.Context, string, string, string, string) error {
+	return fmt.Errorf("could not run ffmpeg: %w", ErrInjected)
+}
+
+// FullDisk fails the writes of subtitles with the error of a full disk.
+type FullDisk struct{}
+
+func (FullDisk) Write(string, string, string, string, []byte) (string, error) {
+	return "", fmt.Errorf("could not write file: %w: %w", ErrInjected, syscall.ENOSPC)
+}
+
+func (FullDisk) Move(string, string, string, string, string) (string, error) {
+	return "", fmt.Errorf("could not write file: %w: %w", ErrInjected, syscall.ENOSPC)
+}
@@ -0,0 +1,139 @@
+// Package chapters splits transcripts into chapters, e.g. for the chapters of YouTube descriptions
+// or the WebVTT chapters track of a player.
+package chapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+// Chapter providers.
+const (
+	ProviderTopics string = "topics" // Detects the topic shifts of the transcript, without a model.
+	ProviderChat   string = "chat"   // A chat model of the OpenAI compatible API.
+)
+
+// ErrUnknownProvider is returned for unsupported chapter providers.
+var ErrUnknownProvider = errors.New("unknown chapters provider")
+
+// Chapter is a section of a recording. The chapters of a recording follow each other,
+// the first one starting at zero.
+type Chapter struct {
+	Start time.Duration
+	End   time.Duration
+	Title string
+}
+
+// Detector splits the cues of a transcript in the given language into chapters.
+type Detector interface {
+	Detect(ctx context.Context, cues []srt.Cue, language string) ([]Chapter, error)
+}
+
+// New returns the detector of the provider. The chat provider asks the model for the chapters.
+func New(provider string, chat chatClient, model string) (Detector, error) {
+	switch provider {
+	case ProviderTopics:
+		return NewTopics(), nil
+	case ProviderChat:
+		return NewChat(chat, model), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, provider)
+	}
+}
+
+type document struct {
+	Chapters []jsonChapter `json:"chapters"`
+}
+
+// jsonChapter is the JSON representation of a chapter. Timestamps are in seconds.
+type jsonChapter struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Title string  `json:"title"`
+}
+
+// Marshal encodes the chapters as the JSON document stored alongside the subtitles.
+func Marshal(chs []Chapter) ([]byte, error) {
+	doc := document{Chapters: make([]jsonChapter, 0, len(chs))}
+	for _, ch := range chs {
+		doc.Chapters = append(doc.Chapters, jsonChapter{Start: ch.Start.Seconds(), End: ch.End.Seconds(), Title: ch.Title})
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal chapters: %w", err)
+	}
+	return data, nil
+}
+
+// Parse decodes the chapters encoded by Marshal.
+func Parse(data []byte) ([]Chapter, error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("could not unmarshal chapters: %w", err)
+	}
+
+	chs := make([]Chapter, 0, len(doc.Chapters))
+	for _, ch := range doc.Chapters {
+		chs = append(chs, Chapter{Start: seconds(ch.Start), End: seconds(ch.End), Title: ch.Title})
+	}
+	return chs, nil
+}
+
+// YouTube formats the chapters as the timestamps of a YouTube description, e.g. "01:30 Pricing",
+// one per line. Hours are only written for recordings of an hour or more.
+func YouTube(chs []Chapter) []byte {
+	hours := len(chs) > 0 && chs[len(chs)-1].Start >= time.Hour
+
+	var b strings.Builder
+	for _, ch := range chs {
+		d := ch.Start.Truncate(time.Second)
+
+		if hours {
+			fmt.Fprintf(&b, "%d:%02d:%02d %s\n", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, ch.Title)
+			continue
+		}
+		fmt.Fprintf(&b, "%02d:%02d %s\n", int(d.Minutes()), int(d.Seconds())%60, ch.Title)
+	}
+	return []byte(b.String())
+}
+
+// VTT formats the chapters as a WebVTT chapters track, each chapter being a cue titled after it.
+func VTT(chs []Chapter) []byte {
+	cues := make([]srt.Cue, 0, len(chs))
+	for i, ch := range chs {
+		cues = append(cues, srt.Cue{Index: i + 1, Start: ch.Start, End: ch.End, Text: ch.Title})
+	}
+	return srt.FormatVTT(cues)
+}
+
+// link sets the end of each chapter to the start of the next one, the last one ending at end.
+func link(chs []Chapter, end time.Duration) []Chapter {
+	for i := range chs {
+		if i < len(chs)-1 {
+			chs[i].End = chs[i+1].Start
+			continue
+		}
+		chs[i].End = max(end, chs[i].Start)
+	}
+	return chs
+}
+
+// end returns the end of the last cue.
+func end(cues []srt.Cue) time.Duration {
+	var last time.Duration
+	for _, cue := range cues {
+		last = max(last, cue.End)
+	}
+	return last
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,127 @@
+package chapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/llm"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+// instructions ask the model for the chapters as a JSON object, in the language of the recording.
+const instructions string = `You split recordings into chapters from their transcript.
+Each line of the transcript starts with its timestamp as [HH:MM:SS].
+Respond with a JSON object with "chapters", the chapters in order, as objects with "start", the timestamp
+of the first line of the chapter, and "title", a short title of its topic in the language of the recording.
+The first chapter starts at 00:00:00. Start a chapter where the topic changes, not more often than every minute.`
+
+type chatClient interface {
+	Chat(ctx context.Context, model string, messages []llm.Message, jsonObject bool) (string, error)
+}
+
+// Chat asks a chat model for the chapters.
+type Chat struct {
+	chat  chatClient
+	model string
+}
+
+// NewChat returns a new detector using the chat model.
+func NewChat(chat chatClient, model string) *Chat {
+	return &Chat{chat: chat, model: model}
+}
+
+type chatReply struct {
+	Chapters []struct {
+		Start string `json:"start"`
+		Title string `json:"title"`
+	} `json:"chapters"`
+}
+
+// Detect asks the model for the chapters of the cues. The chapters starting after the end
+// of the transcript, or at the same time as another one, are dropped.
+func (c *Chat) Detect(ctx context.Context, cues []srt.Cue, _ string) ([]Chapter, error) {
+	if len(cues) == 0 {
+		return []Chapter{}, nil
+	}
+
+	reply, err := c.chat.Chat(ctx, c.model, []llm.Message{
+		{Role: "system", Content: instructions},
+		{Role: "user", Content: transcript(cues)},
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var r chatReply
+	if err := json.Unmarshal([]byte(reply), &r); err != nil {
+		return nil, fmt.Errorf("could not decode chapters: %w", err)
+	}
+
+	last := end(cues)
+
+	var chs []Chapter
+	for _, ch := range r.Chapters {
+		start, err := parseTimestamp(ch.Start)
+		if err != nil || start >= last || strings.TrimSpace(ch.Title) == "" {
+			continue
+		}
+		chs = append(chs, Chapter{Start: start, Title: strings.TrimSpace(ch.Title)})
+	}
+
+	if len(chs) == 0 {
+		return nil, errors.New("the model returned no chapters")
+	}
+
+	sort.SliceStable(chs, func(i, j int) bool { return chs[i].Start < chs[j].Start })
+
+	// The chapters must cover the whole recording.
+	chs[0].Start = 0
+
+	unique := chs[:1]
+	for _, ch := range chs[1:] {
+		if ch.Start > unique[len(unique)-1].Start {
+			unique = append(unique, ch)
+		}
+	}
+	return link(unique, last), nil
+}
+
+// transcript formats the cues as lines prefixed with their start, e.g. "[00:01:05] Let's start.".
+func transcript(cues []srt.Cue) string {
+	var b strings.Builder
+
+	for _, cue := range cues {
+		text := strings.Join(strings.Fields(cue.Text), " ")
+		if text == "" {
+			continue
+		}
+
+		d := cue.Start.Truncate(time.Second)
+		fmt.Fprintf(&b, "[%02d:%02d:%02d] %s\n", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, text)
+	}
+	return b.String()
+}
+
+// parseTimestamp parses a timestamp as HH:MM:SS or MM:SS.
+func parseTimestamp(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+
+	var d time.Duration
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		d = d*60 + time.Duration(n)
+	}
+	return d * time.Second, nil
+}
@@ -0,0 +1,282 @@
+package chapters
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alesr/videoscriber/internal/pkg/fulltext"
+	"github.com/alesr/videoscriber/internal/pkg/langid"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+const (
+	// blockLength is the length of the blocks of cues whose vocabularies are compared.
+	blockLength = 20 * time.Second
+
+	// window is the number of blocks on each side of a gap compared to detect a topic shift there.
+	window = 3
+
+	// minChapter is the minimum length of a chapter, keeping the chapters navigable.
+	minChapter = time.Minute
+
+	// titleWords is the number of keywords titling a chapter.
+	titleWords = 3
+
+	// minWordLength excludes short words, mostly fillers, from the vocabularies.
+	minWordLength = 3
+)
+
+// Topics detects the shifts of topic where the vocabulary of the transcript changes the most, comparing the
+// words said before and after each point, like TextTiling. The chapters are titled with their keywords.
+type Topics struct{}
+
+// NewTopics returns a new topic shift detector.
+func NewTopics() *Topics {
+	return &Topics{}
+}
+
+type block struct {
+	start time.Duration // Start of the first cue of the block.
+	terms map[string]int
+}
+
+// Detect splits the cues at the deepest drops of lexical similarity between the consecutive blocks of cues,
+// at least minChapter apart. The words are compared by stem in the language of the transcript.
+func (t *Topics) Detect(_ context.Context, cues []srt.Cue, language string) ([]Chapter, error) {
+	if len(cues) == 0 {
+		return []Chapter{}, nil
+	}
+
+	// The most frequent form of each term titles the chapters.
+	forms := map[string]map[string]int{}
+
+	var blocks []*block
+	for _, cue := range cues {
+		if len(blocks) == 0 || cue.Start >= blocks[len(blocks)-1].start+blockLength {
+			blocks = append(blocks, &block{start: cue.Start, terms: map[string]int{}})
+		}
+
+		b := blocks[len(blocks)-1]
+
+		_, text := srt.SplitSpeaker(cue.Text)
+		for _, word := range keywords(text) {
+			for _, term := range fulltext.Tokens(language, word) {
+				b.terms[term]++
+
+				if forms[term] == nil {
+					forms[term] = map[string]int{}
+				}
+				forms[term][word]++
+			}
+		}
+	}
+
+	last := end(cues)
+
+	var starts []time.Duration
+	for _, gap := range boundaries(blocks) {
+		start := blocks[gap].start
+		if start < minChapter || last-start < minChapter {
+			continue
+		}
+
+		spaced := true
+		for _, s := range starts {
+			if absDuration(start-s) < minChapter {
+				spaced = false
+				break
+			}
+		}
+
+		if spaced {
+			starts = append(starts, start)
+		}
+	}
+
+	starts = append(starts, 0)
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	chs := make([]Chapter, 0, len(starts))
+	for _, start := range starts {
+		chs = append(chs, Chapter{Start: start})
+	}
+	chs = link(chs, last)
+
+	title(chs, blocks, forms)
+	return chs, nil
+}
+
+// boundaries returns the gaps before the blocks where the topic shifts, the deepest shift first.
+// A gap is a shift when its depth, how much lower the similarity is there than at the peaks around it,
+// is larger than the mean depth minus half its standard deviation.
+func boundaries(blocks []*block) []int {
+	if len(blocks) < 2 {
+		return nil
+	}
+
+	// scores[i] is the similarity across the gap before block i+1.
+	scores := make([]float64, len(blocks)-1)
+	for i := range scores {
+		gap := i + 1
+		scores[i] = cosine(merge(blocks[max(0, gap-window):gap]), merge(blocks[gap:min(len(blocks), gap+window)]))
+	}
+
+	depths := make([]float64, len(scores))
+	for i, s := range scores {
+		left := s
+		for j := i - 1; j >= 0 && scores[j] >= left; j-- {
+			left = scores[j]
+		}
+
+		right := s
+		for j := i + 1; j < len(scores) && scores[j] >= right; j++ {
+			right = scores[j]
+		}
+		depths[i] = (left - s) + (right - s)
+	}
+
+	var mean, variance float64
+	for _, d := range depths {
+		mean += d
+	}
+	mean /= float64(len(depths))
+
+	for _, d := range depths {
+		variance += (d - mean) * (d - mean)
+	}
+	threshold := mean - math.Sqrt(variance/float64(len(depths)))/2
+
+	var gaps []int
+	for i, d := range depths {
+		if d > 0 && d > threshold {
+			gaps = append(gaps, i)
+		}
+	}
+
+	sort.SliceStable(gaps, func(i, j int) bool { return depths[gaps[i]] > depths[gaps[j]] })
+
+	for i := range gaps {
+		gaps[i]++
+	}
+	return gaps
+}
+
+// title titles each chapter with its most distinctive terms, those frequent in the chapter
+// but not in the others, in their most frequent form.
+func title(chs []Chapter, blocks []*block, forms map[string]map[string]int) {
+	counts := make([]map[string]int, len(chs))
+	for i := range chs {
+		var in []*block
+		for _, b := range blocks {
+			if b.start >= chs[i].Start && (i == len(chs)-1 || b.start < chs[i+1].Start) {
+				in = append(in, b)
+			}
+		}
+		counts[i] = merge(in)
+	}
+
+	chapters := map[string]int{} // Number of chapters with the term.
+	for _, c := range counts {
+		for term := range c {
+			chapters[term]++
+		}
+	}
+
+	for i, c := range counts {
+		type scored struct {
+			term  string
+			score float64
+		}
+
+		terms := make([]scored, 0, len(c))
+		for term, n := range c {
+			idf := math.Log(1 + float64(len(chs))/float64(chapters[term]))
+			terms = append(terms, scored{term, float64(n) * idf})
+		}
+
+		sort.Slice(terms, func(i, j int) bool {
+			if terms[i].score != terms[j].score {
+				return terms[i].score > terms[j].score
+			}
+			return terms[i].term < terms[j].term
+		})
+
+		var words []string
+		for _, t := range terms[:min(titleWords, len(terms))] {
+			words = append(words, form(forms[t.term]))
+		}
+
+		if len(words) == 0 {
+			chs[i].Title = fmt.Sprintf("Chapter %d", i+1)
+			continue
+		}
+
+		joined := strings.Join(words, ", ")
+		r, size := utf8.DecodeRuneInString(joined)
+		chs[i].Title = string(unicode.ToUpper(r)) + joined[size:]
+	}
+}
+
+// form returns the most frequent of the forms of a term, the first in alphabetical order on ties.
+func form(forms map[string]int) string {
+	var best string
+	for f, n := range forms {
+		if n > forms[best] || (n == forms[best] && f < best) {
+			best = f
+		}
+	}
+	return best
+}
+
+// keywords returns the lowercased words of the text that aren't stopwords.
+func keywords(text string) []string {
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}) {
+		if utf8.RuneCountInString(w) >= minWordLength && !langid.IsStopword(w) {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+func merge(blocks []*block) map[string]int {
+	terms := map[string]int{}
+	for _, b := range blocks {
+		for term, n := range b.terms {
+			terms[term] += n
+		}
+	}
+	return terms
+}
+
+func cosine(a, b map[string]int) float64 {
+	var dot, na, nb float64
+	for term, n := range a {
+		dot += float64(n * b[term])
+		na += float64(n * n)
+	}
+
+	for _, n := range b {
+		nb += float64(n * n)
+	}
+
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
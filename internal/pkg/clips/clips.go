@@ -0,0 +1,209 @@
+package clips
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+// Variant is the aspect of the rendered clips.
+type Variant string
+
+const (
+	VariantVertical Variant = "vertical" // 9:16, for stories and reels.
+	VariantSquare   Variant = "square"   // 1:1, for feeds.
+	VariantOriginal Variant = "original" // The aspect of the video.
+)
+
+// sizes are the dimensions of the variants. The original variant is not resized.
+var sizes = map[Variant][2]int{
+	VariantVertical: {1080, 1920},
+	VariantSquare:   {1080, 1080},
+}
+
+var (
+	// ErrInvalidRange is returned for ranges that are empty or negative.
+	ErrInvalidRange = errors.New("invalid clip range")
+
+	// ErrNotFound is returned when a rendered file does not exist.
+	ErrNotFound = errors.New("clip not found")
+
+	nameRe = regexp.MustCompile(`[^\p{L}\p{N}_-]+`)
+)
+
+// ParseVariants parses a comma-separated list of variants. Empty means vertical and square.
+func ParseVariants(s string) ([]Variant, error) {
+	if s == "" {
+		return []Variant{VariantVertical, VariantSquare}, nil
+	}
+
+	var variants []Variant
+	for _, v := range strings.Split(s, ",") {
+		variant := Variant(strings.TrimSpace(v))
+
+		if _, ok := sizes[variant]; !ok && variant != VariantOriginal {
+			return nil, fmt.Errorf("unknown variant %q", v)
+		}
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+// Range is a part of a video to turn into a clip.
+type Range struct {
+	Start time.Duration
+	End   time.Duration
+	Name  string // Optional, used for the names of the rendered files.
+}
+
+// Validate checks that the range is not empty.
+func (r Range) Validate() error {
+	if r.Start < 0 || r.End <= r.Start {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidRange, r.Start, r.End)
+	}
+	return nil
+}
+
+type renderer interface {
+	RenderClip(ctx context.Context, videoPath, subtitlePath, forceStyle string, start, duration time.Duration, width, height int, outputPath string) error
+	BurnSubtitles(ctx context.Context, videoPath, subtitlePath, forceStyle, outputPath string) error
+}
+
+// Generator cuts clips of videos with burned-in captions, in several aspects.
+type Generator struct {
+	logger   *slog.Logger
+	tmpDir   string
+	outDir   string
+	renderer renderer
+}
+
+// New returns a new generator keeping the videos in tmpDir while rendering
+// and writing the clips of each job in its own directory of outDir.
+func New(logger *slog.Logger, tmpDir, outDir string, renderer renderer) *Generator {
+	return &Generator{
+		logger:   logger,
+		tmpDir:   tmpDir,
+		outDir:   outDir,
+		renderer: renderer,
+	}
+}
+
+// Prepare copies the video to the tmp directory, since uploads are gone once the request finishes,
+// and returns the path of the copy. The caller must remove it once the clips are rendered.
+func (g *Generator) Prepare(name string, data io.Reader) (string, error) {
+	f, err := os.CreateTemp(g.tmpDir, "clip-*"+filepath.Ext(name))
+	if err != nil {
+		return "", fmt.Errorf("could not create video file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, data); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("could not write video file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// Render renders the variants of the clip of the video, with the cues of the range burned in
+// and styled with forceStyle when not empty. It returns the names of the rendered files.
+func (g *Generator) Render(ctx context.Context, jobID string, index int, videoPath string, cues []srt.Cue, r Range, variants []Variant, forceStyle string) ([]string, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(g.outDir, jobID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create clips directory: %w", err)
+	}
+
+	subPath, err := g.writeSubtitle(srt.Slice(cues, r.Start, r.End))
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(subPath)
+
+	base := fmt.Sprintf("clip%d", index+1)
+	if name := nameRe.ReplaceAllString(r.Name, "-"); strings.Trim(name, "-") != "" {
+		base = fmt.Sprintf("%s-%s", base, strings.Trim(name, "-"))
+	}
+
+	outputs := make([]string, 0, len(variants))
+
+	for _, variant := range variants {
+		size := sizes[variant]
+		name := fmt.Sprintf("%s-%s.mp4", base, variant)
+
+		if err := g.renderer.RenderClip(ctx, videoPath, subPath, forceStyle, r.Start, r.End-r.Start, size[0], size[1], filepath.Join(dir, name)); err != nil {
+			return outputs, fmt.Errorf("could not render %s clip: %w", variant, err)
+		}
+
+		g.logger.Debug("Rendered clip", slog.String("job_id", jobID), slog.String("clip", name))
+		outputs = append(outputs, name)
+	}
+	return outputs, nil
+}
+
+// Burn renders the whole video into an MP4 file in the tmp directory, with the cues burned in
+// and styled with forceStyle when not empty, and returns its path. The caller must remove it once served.
+func (g *Generator) Burn(ctx context.Context, videoPath string, cues []srt.Cue, forceStyle string) (string, error) {
+	subPath, err := g.writeSubtitle(cues)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(subPath)
+
+	out, err := os.CreateTemp(g.tmpDir, "render-*.mp4")
+	if err != nil {
+		return "", fmt.Errorf("could not create video file: %w", err)
+	}
+	out.Close()
+
+	if err := g.renderer.BurnSubtitles(ctx, videoPath, subPath, forceStyle, out.Name()); err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("could not render video: %w", err)
+	}
+	return out.Name(), nil
+}
+
+// writeSubtitle writes the cues to an SRT file in the tmp directory for ffmpeg, and returns its path.
+func (g *Generator) writeSubtitle(cues []srt.Cue) (string, error) {
+	subFile, err := os.CreateTemp(g.tmpDir, "clip-*.srt")
+	if err != nil {
+		return "", fmt.Errorf("could not create subtitle file: %w", err)
+	}
+
+	_, err = subFile.Write(srt.Format(cues))
+	if closeErr := subFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(subFile.Name())
+		return "", fmt.Errorf("could not write subtitle file: %w", err)
+	}
+	return subFile.Name(), nil
+}
+
+// Path returns the path of a file rendered for the job.
+func (g *Generator) Path(jobID, name string) (string, error) {
+	if jobID != filepath.Base(jobID) || name != filepath.Base(name) || strings.HasPrefix(jobID, ".") || strings.HasPrefix(name, ".") {
+		return "", ErrNotFound
+	}
+
+	path := filepath.Join(g.outDir, jobID, name)
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("could not stat clip: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,112 @@
+// Package degraded detects when the subtitles can't be stored, e.g. when their directory is remounted
+// read-only or the disk is full, so that the service keeps serving the stored subtitles and rejects
+// or queues the new jobs, instead of failing them one by one, until the storage accepts writes again.
+package degraded
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Monitor tracks whether the storage directory accepts writes. It probes the directory when a write
+// fails and at each interval, so that it notices both the failures and the recovery.
+type Monitor struct {
+	logger *slog.Logger
+	dir    string
+
+	mu        sync.Mutex
+	cause     error         // Why the storage doesn't accept writes. Nil when it does.
+	since     time.Time     // When the storage stopped accepting writes.
+	recovered chan struct{} // Closed once the storage accepts writes again.
+}
+
+// New returns a monitor of the storage directory, which is assumed to accept writes until probed.
+func New(logger *slog.Logger, dir string) *Monitor {
+	recovered := make(chan struct{})
+	close(recovered)
+
+	return &Monitor{logger: logger, dir: dir, recovered: recovered}
+}
+
+// Probe creates and removes a file in the directory, and records whether it succeeded.
+func (m *Monitor) Probe() error {
+	err := probe(m.dir)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case err != nil && m.cause == nil:
+		m.logger.Error("Storage is degraded, new subtitles can't be stored", slog.String("dir", m.dir), slog.String("error", err.Error()))
+		m.since, m.recovered = time.Now().UTC(), make(chan struct{})
+	case err == nil && m.cause != nil:
+		m.logger.Info("Storage recovered", slog.String("dir", m.dir), slog.Duration("after", time.Since(m.since)))
+		close(m.recovered)
+	}
+
+	m.cause = err
+	return err
+}
+
+// Degraded returns why the storage doesn't accept writes, or nil when it does, as last probed.
+func (m *Monitor) Degraded() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.cause
+}
+
+// Wait blocks until the storage accepts writes, or the context is done.
+func (m *Monitor) Wait(ctx context.Context) error {
+	m.mu.Lock()
+	recovered := m.recovered
+	m.mu.Unlock()
+
+	select {
+	case <-recovered:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Check probes the storage, for the readiness checks.
+func (m *Monitor) Check(ctx context.Context) error {
+	return m.Probe()
+}
+
+// Run probes the storage at each interval until the context is done.
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Probe()
+		}
+	}
+}
+
+func probe(dir string) error {
+	f, err := os.CreateTemp(dir, ".probe-*")
+	if err != nil {
+		return fmt.Errorf("could not create file: %w", err)
+	}
+
+	// Some full disks accept the creation of empty files only.
+	_, err = f.Write([]byte("probe"))
+	f.Close()
+	os.Remove(f.Name())
+
+	if err != nil {
+		return fmt.Errorf("could not write file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package degraded
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+)
+
+// Entry is a job waiting in the queue for the storage to accept writes again.
+type Entry struct {
+	JobID        string                  `json:"job_id"`
+	Inputs       []subtitles.QueuedInput `json:"inputs"`
+	Publications []*jobs.Publication     `json:"publications,omitempty"`
+	CallbackURL  string                  `json:"callback_url,omitempty"`
+	QueuedAt     time.Time               `json:"queued_at"`
+}
+
+// Queue persists the jobs waiting for the storage, one JSON file per job next to their input
+// files, so that they survive restarts. Its directory must not be on the degraded storage.
+// A queue in memory keeps the jobs until the program stops instead.
+type Queue struct {
+	mu      sync.Mutex
+	dir     string
+	entries map[string]Entry // By job ID, for a queue in memory.
+}
+
+// NewQueue returns a queue backed by the directory, creating it if needed.
+func NewQueue(dir string) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create queue directory: %w", err)
+	}
+	return &Queue{dir: dir}, nil
+}
+
+// NewMemoryQueue returns a queue in memory, e.g. to embed the server in other programs or test it
+// without a queue directory. The input files of its jobs stay where they were prepared.
+func NewMemoryQueue() *Queue {
+	return &Queue{entries: make(map[string]Entry)}
+}
+
+// Enabled reports whether the queue is configured.
+func (q *Queue) Enabled() bool {
+	return q != nil
+}
+
+// Dir returns the directory the input files of the queued jobs are moved to, empty in memory.
+func (q *Queue) Dir() string {
+	return q.dir
+}
+
+// Push adds the job to the queue.
+func (q *Queue) Push(e Entry) error {
+	if q.entries != nil {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+
+		q.entries[e.JobID] = e
+		return nil
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("could not marshal queue entry: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	tmp := q.path(e.JobID) + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("could not write queue entry: %w", err)
+	}
+
+	if err := os.Rename(tmp, q.path(e.JobID)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("could not write queue entry: %w", err)
+	}
+	return nil
+}
+
+// Entries returns the queued jobs, oldest first.
+func (q *Queue) Entries() ([]Entry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.entries != nil {
+		entries := make([]Entry, 0, len(q.entries))
+		for _, e := range q.entries {
+			entries = append(entries, e)
+		}
+
+		sortEntries(entries)
+		return entries, nil
+	}
+
+	files, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read queue directory: %w", err)
+	}
+
+	var entries []Entry
+
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(q.dir, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("could not read queue entry: %w", err)
+		}
+
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, fmt.Errorf("could not unmarshal queue entry %q: %w", f.Name(), err)
+		}
+		entries = append(entries, e)
+	}
+
+	sortEntries(entries)
+	return entries, nil
+}
+
+// Remove removes the job from the queue. Its input files are left to the processing.
+func (q *Queue) Remove(jobID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.entries != nil {
+		delete(q.entries, jobID)
+		return nil
+	}
+
+	if err := os.Remove(q.path(jobID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not remove queue entry: %w", err)
+	}
+	return nil
+}
+
+func (q *Queue) path(jobID string) string {
+	return filepath.Join(q.dir, jobID+".json")
+}
+
+// sortEntries sorts the entries, oldest first.
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].QueuedAt.Before(entries[j].QueuedAt)
+	})
+}
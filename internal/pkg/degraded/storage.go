@@ -0,0 +1,35 @@
+package degraded
+
+// Storage stores subtitles and their artifacts.
+type Storage interface {
+	Write(owner, language, project, fileName string, data []byte) (string, error)
+	Move(owner, language, project, fileName, srcPath string) (string, error)
+}
+
+// MonitoredStorage probes the storage directory when a write fails, so that the monitor
+// notices the storage stopped accepting writes without waiting for its next probe.
+type MonitoredStorage struct {
+	storage Storage
+	monitor *Monitor
+}
+
+// NewStorage returns the storage reporting its failed writes to the monitor.
+func NewStorage(s Storage, m *Monitor) *MonitoredStorage {
+	return &MonitoredStorage{storage: s, monitor: m}
+}
+
+func (s *MonitoredStorage) Write(owner, language, project, fileName string, data []byte) (string, error) {
+	path, err := s.storage.Write(owner, language, project, fileName, data)
+	if err != nil {
+		s.monitor.Probe()
+	}
+	return path, err
+}
+
+func (s *MonitoredStorage) Move(owner, language, project, fileName, srcPath string) (string, error) {
+	path, err := s.storage.Move(owner, language, project, fileName, srcPath)
+	if err != nil {
+		s.monitor.Probe()
+	}
+	return path, err
+}
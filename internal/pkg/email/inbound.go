@@ -0,0 +1,220 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// maxLinks bounds the number of links taken from the body of a message.
+const maxLinks int = 10
+
+// ErrNoContent is returned for SES notifications not carrying the raw message,
+// e.g. when the receipt rule stores messages in S3 instead of publishing them to SNS.
+var ErrNoContent = errors.New("notification has no message content")
+
+var link = regexp.MustCompile(`https?://[^\s<>"')\]]+`)
+
+// Attachment is a media file attached to a message.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Message is an inbound email.
+type Message struct {
+	From        string
+	Subject     string
+	Recipients  []string
+	Attachments []Attachment // Audio and video attachments only.
+	Links       []string     // Links found in the text body.
+}
+
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	Receipt          struct {
+		Recipients   []string   `json:"recipients"`
+		SpamVerdict  sesVerdict `json:"spamVerdict"`
+		VirusVerdict sesVerdict `json:"virusVerdict"`
+	} `json:"receipt"`
+	Content string `json:"content"`
+}
+
+type sesVerdict struct {
+	Status string `json:"status"`
+}
+
+// ParseSES parses the SES receipt notification published by an SNS action.
+// Messages SES flagged as spam or carrying viruses are rejected.
+func ParseSES(notification string) (*Message, error) {
+	var n sesNotification
+	if err := json.Unmarshal([]byte(notification), &n); err != nil {
+		return nil, fmt.Errorf("could not unmarshal notification: %w", err)
+	}
+
+	if n.NotificationType != "Received" {
+		return nil, fmt.Errorf("unexpected notification type %q", n.NotificationType)
+	}
+
+	if n.Receipt.SpamVerdict.Status == "FAIL" || n.Receipt.VirusVerdict.Status == "FAIL" {
+		return nil, errors.New("message flagged as spam or virus")
+	}
+
+	if n.Content == "" {
+		return nil, ErrNoContent
+	}
+
+	raw := []byte(n.Content)
+
+	// SNS actions configured with the Base64 encoding publish the content encoded.
+	if decoded, err := base64.StdEncoding.DecodeString(n.Content); err == nil {
+		raw = decoded
+	}
+
+	m, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Recipients = n.Receipt.Recipients
+	return m, nil
+}
+
+// Parse parses a raw MIME message.
+func Parse(raw []byte) (*Message, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("could not read message: %w", err)
+	}
+
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse sender: %w", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	m := Message{From: from.Address, Subject: subject}
+
+	if err := m.walk(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), "", msg.Body); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// walk collects the media attachments and links of the part and its subparts.
+func (m *Message) walk(contentType, encoding, disposition string, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("could not read part: %w", err)
+			}
+
+			if err := m.walk(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part.Header.Get("Content-Disposition"), part); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decode(body, encoding))
+	if err != nil {
+		return fmt.Errorf("could not read part: %w", err)
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"):
+		m.Attachments = append(m.Attachments, Attachment{
+			Name:        attachmentName(disposition, params, mediaType),
+			ContentType: mediaType,
+			Data:        data,
+		})
+	case mediaType == "text/plain":
+		for _, l := range link.FindAllString(string(data), -1) {
+			// Punctuation following a link usually belongs to the sentence.
+			l = strings.TrimRight(l, ".,;:!?")
+
+			if len(m.Links) < maxLinks && !contains(m.Links, l) {
+				m.Links = append(m.Links, l)
+			}
+		}
+	}
+	return nil
+}
+
+func decode(body io.Reader, encoding string) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, newlineStripper{body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+func attachmentName(disposition string, params map[string]string, mediaType string) string {
+	if _, dparams, err := mime.ParseMediaType(disposition); err == nil && dparams["filename"] != "" {
+		return dparams["filename"]
+	}
+
+	if params["name"] != "" {
+		return params["name"]
+	}
+
+	ext := ".bin"
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		ext = exts[0]
+	}
+	return "attachment" + ext
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// newlineStripper drops line breaks, which the base64 decoder doesn't accept.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (s newlineStripper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+
+	out := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[out] = b
+			out++
+		}
+	}
+	return out, err
+}
@@ -0,0 +1,91 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrSenderNotAllowed is returned for messages from senders missing from the allow list.
+var ErrSenderNotAllowed = errors.New("sender not allowed")
+
+// Inbox receives the emails sent to the configured address through
+// an SES receipt rule publishing to an SNS topic subscribed to the server.
+type Inbox struct {
+	verifier       *SNSVerifier
+	topicArn       string
+	allowedSenders []string
+}
+
+// NewInbox returns a new inbox accepting deliveries from the topic. Allowed senders
+// are addresses or domains prefixed with "@"; all senders are allowed when empty.
+func NewInbox(httpCli *http.Client, topicArn string, allowedSenders []string) *Inbox {
+	return &Inbox{
+		verifier:       NewSNSVerifier(httpCli),
+		topicArn:       topicArn,
+		allowedSenders: allowedSenders,
+	}
+}
+
+// Enabled reports whether the inbox is configured.
+func (in *Inbox) Enabled() bool {
+	return in.topicArn != ""
+}
+
+// Receive verifies and handles an SNS delivery. Subscription confirmations are
+// confirmed and return no message.
+func (in *Inbox) Receive(ctx context.Context, body []byte) (*Message, error) {
+	var m SNSMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSNSMessage, err)
+	}
+
+	if m.TopicArn != in.topicArn {
+		return nil, fmt.Errorf("%w: unexpected topic %q", ErrInvalidSNSMessage, m.TopicArn)
+	}
+
+	if err := in.verifier.Verify(ctx, m); err != nil {
+		return nil, err
+	}
+
+	switch m.Type {
+	case SNSSubscriptionConfirmation:
+		if err := in.verifier.Confirm(ctx, m); err != nil {
+			return nil, fmt.Errorf("could not confirm subscription: %w", err)
+		}
+		return nil, nil
+	case SNSNotification:
+	default:
+		return nil, nil
+	}
+
+	msg, err := ParseSES(m.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	if !in.allowed(msg.From) {
+		return nil, fmt.Errorf("%w: %s", ErrSenderNotAllowed, msg.From)
+	}
+	return msg, nil
+}
+
+func (in *Inbox) allowed(sender string) bool {
+	if len(in.allowedSenders) == 0 {
+		return true
+	}
+
+	sender = strings.ToLower(sender)
+
+	for _, a := range in.allowedSenders {
+		a = strings.ToLower(strings.TrimSpace(a))
+
+		if a == sender || strings.HasPrefix(a, "@") && strings.HasSuffix(sender, a) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package email
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Sender sends plain text emails through an SMTP server.
+type Sender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSender returns a new sender. Authentication is skipped when username is empty.
+func NewSender(addr, username, password, from string) *Sender {
+	var auth smtp.Auth
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &Sender{addr: addr, auth: auth, from: from}
+}
+
+// Enabled reports whether the sender is configured.
+func (s *Sender) Enabled() bool {
+	return s.addr != "" && s.from != ""
+}
+
+// Send sends the email to the recipient.
+func (s *Sender) Send(to, subject, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid recipient or subject")
+	}
+
+	var msg strings.Builder
+
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{to}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("could not send email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package email
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SNS message types.
+const (
+	SNSNotification             string = "Notification"
+	SNSSubscriptionConfirmation string = "SubscriptionConfirmation"
+)
+
+// ErrInvalidSNSMessage is returned for SNS messages failing verification.
+var ErrInvalidSNSMessage = errors.New("invalid sns message")
+
+// snsHost matches the hosts SNS signing certificates and subscription URLs are served from.
+var snsHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// SNSMessage is an Amazon SNS HTTP(S) delivery.
+type SNSMessage struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	SubscribeURL     string `json:"SubscribeURL"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+}
+
+// SNSVerifier verifies the signature of SNS messages.
+type SNSVerifier struct {
+	httpCli *http.Client
+
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+}
+
+// NewSNSVerifier returns a new verifier. Signing certificates are cached by URL.
+func NewSNSVerifier(httpCli *http.Client) *SNSVerifier {
+	return &SNSVerifier{
+		httpCli: httpCli,
+		certs:   make(map[string]*x509.Certificate),
+	}
+}
+
+// Verify checks that the message was signed by SNS.
+func (v *SNSVerifier) Verify(ctx context.Context, m SNSMessage) error {
+	var hash crypto.Hash
+
+	switch m.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+	case "2":
+		hash = crypto.SHA256
+	default:
+		return fmt.Errorf("%w: unsupported signature version %q", ErrInvalidSNSMessage, m.SignatureVersion)
+	}
+
+	if !trustedSNSURL(m.SigningCertURL) {
+		return fmt.Errorf("%w: untrusted signing certificate url", ErrInvalidSNSMessage)
+	}
+
+	cert, err := v.cert(ctx, m.SigningCertURL)
+	if err != nil {
+		return err
+	}
+
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: unexpected signing key type", ErrInvalidSNSMessage)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: could not decode signature", ErrInvalidSNSMessage)
+	}
+
+	var digest []byte
+	if hash == crypto.SHA1 {
+		sum := sha1.Sum([]byte(m.signedString()))
+		digest = sum[:]
+	} else {
+		sum := sha256.Sum256([]byte(m.signedString()))
+		digest = sum[:]
+	}
+
+	if err := rsa.VerifyPKCS1v15(pub, hash, digest, signature); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSNSMessage, err)
+	}
+	return nil
+}
+
+// Confirm confirms the subscription of the endpoint to the topic.
+func (v *SNSVerifier) Confirm(ctx context.Context, m SNSMessage) error {
+	if !trustedSNSURL(m.SubscribeURL) {
+		return fmt.Errorf("%w: untrusted subscribe url", ErrInvalidSNSMessage)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.SubscribeURL, nil)
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+
+	resp, err := v.httpCli.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (v *SNSVerifier) cert(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if cert, ok := v.certs[certURL]; ok {
+		return cert, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, certURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+
+	resp, err := v.httpCli.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch signing certificate: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch signing certificate: unexpected status code %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("could not read signing certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: signing certificate is not PEM encoded", ErrInvalidSNSMessage)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse signing certificate: %w", err)
+	}
+
+	v.certs[certURL] = cert
+	return cert, nil
+}
+
+// signedString builds the string SNS signs for the message type.
+func (m SNSMessage) signedString() string {
+	fields := [][2]string{{"Message", m.Message}, {"MessageId", m.MessageID}}
+
+	if m.Type == SNSNotification {
+		if m.Subject != "" {
+			fields = append(fields, [2]string{"Subject", m.Subject})
+		}
+	} else {
+		fields = append(fields, [2]string{"SubscribeURL", m.SubscribeURL})
+	}
+
+	fields = append(fields, [2]string{"Timestamp", m.Timestamp})
+
+	if m.Type != SNSNotification {
+		fields = append(fields, [2]string{"Token", m.Token})
+	}
+
+	fields = append(fields, [2]string{"TopicArn", m.TopicArn}, [2]string{"Type", m.Type})
+
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteString(f[0] + "\n" + f[1] + "\n")
+	}
+	return b.String()
+}
+
+func trustedSNSURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && u.Scheme == "https" && snsHost.MatchString(u.Host)
+}
@@ -0,0 +1,118 @@
+// Package features gates experimental subsystems behind flags, so that operators can enable them
+// per environment without separate builds.
+package features
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Flag names an experimental subsystem.
+type Flag string
+
+const (
+	FlagClips Flag = "clips" // Clip generation and burned-in rendering.
+	FlagLive  Flag = "live"  // Live transcription.
+	FlagOCR   Flag = "ocr"   // Recognition of on-screen text.
+)
+
+// defaults are the states of the flags unless configured otherwise.
+var defaults = map[Flag]bool{
+	FlagClips: true,
+	FlagLive:  false,
+	FlagOCR:   false,
+}
+
+// ErrUnknown is returned for flags that don't gate any subsystem.
+var ErrUnknown = errors.New("unknown feature flag")
+
+// State is the state of a flag.
+type State struct {
+	Name    Flag `json:"name"`
+	Enabled bool `json:"enabled"`
+	Default bool `json:"default"`
+}
+
+// Set holds the states of the flags of the deployment. It is safe for concurrent use.
+type Set struct {
+	mu      sync.RWMutex
+	enabled map[Flag]bool
+}
+
+// New returns the flags configured by the spec, a comma-separated list of flags to enable,
+// or of flag=bool pairs, e.g. "ocr,clips=false". Flags missing from the spec have their default state.
+func New(spec string) (*Set, error) {
+	enabled := make(map[Flag]bool, len(defaults))
+	for f, on := range defaults {
+		enabled[f] = on
+	}
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(item, "=")
+
+		f := Flag(strings.TrimSpace(name))
+		if _, ok := defaults[f]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknown, name)
+		}
+
+		on := true
+		if hasValue {
+			var err error
+			if on, err = strconv.ParseBool(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("invalid state of feature flag %q: %w", name, err)
+			}
+		}
+		enabled[f] = on
+	}
+	return &Set{enabled: enabled}, nil
+}
+
+// Known reports whether the flag gates a subsystem.
+func Known(f Flag) bool {
+	_, ok := defaults[f]
+	return ok
+}
+
+// Enabled reports whether the subsystem of the flag is enabled.
+func (s *Set) Enabled(f Flag) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.enabled[f]
+}
+
+// Toggle enables or disables the subsystem of the flag until the server restarts.
+func (s *Set) Toggle(f Flag, enabled bool) (State, error) {
+	if _, ok := defaults[f]; !ok {
+		return State{}, fmt.Errorf("%w: %q", ErrUnknown, f)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enabled[f] = enabled
+	return State{Name: f, Enabled: enabled, Default: defaults[f]}, nil
+}
+
+// List returns the states of all the flags, by name.
+func (s *Set) List() []State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	states := make([]State, 0, len(s.enabled))
+	for f, on := range s.enabled {
+		states = append(states, State{Name: f, Enabled: on, Default: defaults[f]})
+	}
+
+	slices.SortFunc(states, func(a, b State) int { return strings.Compare(string(a.Name), string(b.Name)) })
+	return states
+}
@@ -0,0 +1,209 @@
+package ffmpeg
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// maxArchiveSize bounds the size of a downloaded build.
+const maxArchiveSize int64 = 512 << 20 // 512MB
+
+// ErrChecksumMismatch is returned when a downloaded build doesn't match its pinned checksum.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Build is a static ffmpeg build, pinned by the checksum of its archive.
+type Build struct {
+	URL    string `json:"url"`    // Of a .zip, .tar.gz or .tgz archive, or of the binary itself.
+	SHA256 string `json:"sha256"` // Hex encoded checksum of the file at URL.
+	Binary string `json:"binary"` // Path of the binary in the archive, e.g. ffmpeg-6.1/bin/ffmpeg.
+}
+
+// Manifest lists the pinned builds by platform, e.g. "darwin/arm64" or "windows/amd64".
+type Manifest map[string]Build
+
+// LoadManifest reads a manifest from a JSON file or, when location is an HTTP(S) URL, downloads it.
+func LoadManifest(ctx context.Context, httpCli *http.Client, location string) (Manifest, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		data, err = download(ctx, httpCli, location, 1<<20)
+	} else {
+		data, err = os.ReadFile(location)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("could not unmarshal manifest: %w", err)
+	}
+	return m, nil
+}
+
+// Bootstrap installs the build of the current platform into the directory, unless already installed,
+// and returns the path of its binary. Builds are installed in a subdirectory named after their checksum,
+// so that pinning another build installs it next to the previous one.
+func Bootstrap(ctx context.Context, httpCli *http.Client, manifest Manifest, dir string) (string, error) {
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+
+	build, ok := manifest[platform]
+	if !ok {
+		return "", fmt.Errorf("no ffmpeg build pinned for %s", platform)
+	}
+
+	if len(build.SHA256) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid checksum of the %s build: %q", platform, build.SHA256)
+	}
+
+	name := "ffmpeg"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	binaryPath := filepath.Join(dir, strings.ToLower(build.SHA256), name)
+
+	if _, err := os.Stat(binaryPath); err == nil {
+		return binaryPath, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(binaryPath), 0o755); err != nil {
+		return "", fmt.Errorf("could not create directory: %w", err)
+	}
+
+	archive, err := download(ctx, httpCli, build.URL, maxArchiveSize)
+	if err != nil {
+		return "", fmt.Errorf("could not download ffmpeg: %w", err)
+	}
+
+	sum := sha256.Sum256(archive)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, build.SHA256) {
+		return "", fmt.Errorf("%w: %s downloaded from %s, %s pinned", ErrChecksumMismatch, got, build.URL, build.SHA256)
+	}
+
+	binary, err := extractBinary(build, archive)
+	if err != nil {
+		return "", fmt.Errorf("could not extract ffmpeg: %w", err)
+	}
+
+	// Written next to its final path and renamed, so that a partial binary is never used.
+	tmp := binaryPath + ".download"
+
+	if err := os.WriteFile(tmp, binary, 0o755); err != nil {
+		return "", fmt.Errorf("could not write ffmpeg: %w", err)
+	}
+
+	if err := os.Rename(tmp, binaryPath); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("could not install ffmpeg: %w", err)
+	}
+	return binaryPath, nil
+}
+
+func download(ctx context.Context, httpCli *http.Client, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+
+	resp, err := httpCli.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not read response: %w", err)
+	}
+
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("larger than %d bytes", limit)
+	}
+	return data, nil
+}
+
+// extractBinary returns the binary of the build from its archive, by the extension of its URL.
+func extractBinary(build Build, archive []byte) ([]byte, error) {
+	ext := strings.ToLower(path.Ext(strings.SplitN(build.URL, "?", 2)[0]))
+
+	switch {
+	case ext == ".zip":
+		return extractZip(archive, build.Binary)
+	case ext == ".tgz" || strings.HasSuffix(strings.ToLower(build.URL), ".tar.gz"):
+		return extractTarGz(archive, build.Binary)
+	case build.Binary == "":
+		return archive, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive %q, expected .zip, .tar.gz or .tgz", ext)
+	}
+}
+
+func extractZip(archive []byte, name string) ([]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("could not open zip: %w", err)
+	}
+
+	for _, f := range r.File {
+		if f.Name != name {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("could not open %s: %w", name, err)
+		}
+		defer rc.Close()
+
+		return io.ReadAll(io.LimitReader(rc, maxArchiveSize))
+	}
+	return nil, fmt.Errorf("%s not found in archive", name)
+}
+
+func extractTarGz(archive []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("could not open gzip: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s not found in archive", name)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("could not read tar: %w", err)
+		}
+
+		if strings.TrimPrefix(header.Name, "./") == name && header.Typeflag == tar.TypeReg {
+			return io.ReadAll(io.LimitReader(tr, maxArchiveSize))
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// placeholderSampleRate is replaced by the sample rate of the extraction in the extra arguments.
+const placeholderSampleRate string = "{sample_rate}"
+
+var durationRe = regexp.MustCompile(`Duration: (\d+):(\d+):(\d+(?:\.\d+)?)`)
+
+type audioFilter struct {
+	name  string
+	graph string
+}
+
+// filterGraphs are the audio preprocessing filters by name.
+var filterGraphs = []audioFilter{
+	{"denoise", "afftdn=nf=-25"},
+	// Only the trailing silence is trimmed, reversing the audio, so that the timestamps match the video.
+	{"trim-silence", "areverse,silenceremove=start_periods=1:start_threshold=-50dB:start_silence=0.5,areverse"},
+	{"normalize", "loudnorm=I=-16:TP=-1.5:LRA=11"},
+}
+
+// Extractor extracts the audio track of video files with ffmpeg.
+type Extractor struct {
+	binary string
+	args   []string
+}
+
+// NewExtractor returns a new audio extractor running the ffmpeg binary, e.g. the one found by Find.
+// The extra arguments are passed to the audio extraction after the default output options,
+// so they override them, e.g. "-threads 2" or "-af loudnorm". {sample_rate} is replaced by
+// the sample rate of the extraction, e.g. "-af aresample={sample_rate}:resampler=soxr".
+func NewExtractor(binary string, args []string) *Extractor {
+	return &Extractor{binary: binary, args: args}
+}
+
+// Version returns the version line of ffmpeg, e.g. "ffmpeg version 6.1.1 Copyright (c) 2000-2023 ...".
+func (e *Extractor) Version(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, e.binary, "-version").Output()
+	if err != nil {
+		return "", fmt.Errorf("could not run ffmpeg: %w", err)
+	}
+
+	version, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return version, nil
+}
+
+// Duration returns the duration of the file, read from its header without decoding it.
+func (e *Extractor) Duration(ctx context.Context, filePath string) (time.Duration, error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, e.binary, "-hide_banner", "-i", filePath)
+	cmd.Stderr = &stderr
+
+	// Without an output file, ffmpeg fails once it printed the format of the input.
+	if err := cmd.Run(); err != nil && (errors.Is(err, exec.ErrNotFound) || ctx.Err() != nil) {
+		return 0, fmt.Errorf("could not run ffmpeg: %w", err)
+	}
+
+	m := durationRe.FindSubmatch(stderr.Bytes())
+	if m == nil {
+		return 0, errors.New("could not find the duration of the file")
+	}
+
+	h, _ := strconv.Atoi(string(m[1]))
+	mins, _ := strconv.Atoi(string(m[2]))
+	sec, _ := strconv.ParseFloat(string(m[3]), 64)
+
+	return time.Duration(h)*time.Hour + time.Duration(mins)*time.Minute + time.Duration(sec*float64(time.Second)), nil
+}
+
+// ExtractAudio extracts the audio of the file into a WAV file next to it and returns its path.
+// The audio is preprocessed with the named filters, in order: "denoise" (afftdn), "trim-silence" (silenceremove)
+// and "normalize" (loudnorm). An -af option of the extra arguments replaces them.
+// The progress function, when not nil, is called with the extracted fraction (0 to 1).
+func (e *Extractor) ExtractAudio(ctx context.Context, filePath, sampleRate string, filters []string, progress func(float64)) (string, error) {
+	graph, err := filterGraph(filters)
+	if err != nil {
+		return "", err
+	}
+
+	outputPath := filePath + ".wav"
+
+	args := []string{"-y", "-i", filePath, "-vn", "-acodec", "pcm_s16le", "-ar", sampleRate, "-ac", "2", "-b:a", "32k"}
+	if graph != "" {
+		args = append(args, "-af", graph)
+	}
+
+	for _, arg := range e.args {
+		args = append(args, strings.ReplaceAll(arg, placeholderSampleRate, sampleRate))
+	}
+
+	cmd := exec.CommandContext(ctx, e.binary, append(args, "-progress", "pipe:1", "-nostats", outputPath)...)
+
+	tracker := progressTracker{report: progress}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = io.MultiWriter(&stderr, &tracker)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", fmt.Errorf("could not open ffmpeg output: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("could not start ffmpeg: %w", err)
+	}
+
+	tracker.follow(stdout)
+
+	if err := cmd.Wait(); err != nil {
+		return "", failed(ctx, err, stderr.String(), outputPath)
+	}
+
+	if progress != nil {
+		progress(1)
+	}
+	return outputPath, nil
+}
+
+// ExtractSegment copies the part of the audio file starting at start and lasting duration
+// into a new WAV file next to it, and returns its path.
+func (e *Extractor) ExtractSegment(ctx context.Context, filePath string, start, duration time.Duration) (string, error) {
+	outputPath := fmt.Sprintf("%s.%d.wav", filePath, start.Milliseconds())
+
+	cmd := exec.CommandContext(ctx,
+		e.binary, "-y", "-ss", formatSeconds(start), "-t", formatSeconds(duration), "-i", filePath,
+		"-c", "copy", outputPath,
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", failed(ctx, err, stderr.String(), outputPath)
+	}
+	return outputPath, nil
+}
+
+// RenderClip cuts the part of the video starting at start and lasting duration into an MP4 file,
+// burning in the subtitle file, styled with forceStyle when not empty. When width and height
+// are set, the video is scaled to fill that size and cropped around its center.
+func (e *Extractor) RenderClip(ctx context.Context, videoPath, subtitlePath, forceStyle string, start, duration time.Duration, width, height int, outputPath string) error {
+	var filters []string
+
+	if width > 0 && height > 0 {
+		filters = append(filters,
+			fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=increase", width, height),
+			fmt.Sprintf("crop=%d:%d", width, height),
+		)
+	}
+
+	filters = append(filters, subtitlesFilter(subtitlePath, forceStyle))
+
+	return e.render(ctx, []string{"-ss", formatSeconds(start), "-t", formatSeconds(duration), "-i", videoPath}, filters, outputPath)
+}
+
+// BurnSubtitles renders the whole video into an MP4 file with the subtitle file burned in,
+// styled with forceStyle when not empty.
+func (e *Extractor) BurnSubtitles(ctx context.Context, videoPath, subtitlePath, forceStyle, outputPath string) error {
+	return e.render(ctx, []string{"-i", videoPath}, []string{subtitlesFilter(subtitlePath, forceStyle)}, outputPath)
+}
+
+// render encodes the input into an H.264 MP4 file through the video filters.
+func (e *Extractor) render(ctx context.Context, input, filters []string, outputPath string) error {
+	args := append([]string{"-y"}, input...)
+	args = append(args,
+		"-vf", strings.Join(filters, ","),
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "23",
+		"-c:a", "aac", "-b:a", "128k", "-movflags", "+faststart", outputPath,
+	)
+
+	cmd := exec.CommandContext(ctx, e.binary, args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return failed(ctx, err, stderr.String(), outputPath)
+	}
+	return nil
+}
+
+// subtitlesFilter returns the filter burning in the subtitle file.
+func subtitlesFilter(subtitlePath, forceStyle string) string {
+	filter := "subtitles=filename=" + escapeFilterValue(subtitlePath)
+	if forceStyle != "" {
+		filter += ":force_style=" + escapeFilterValue(forceStyle)
+	}
+	return filter
+}
+
+// filterGraph returns the filter graph applying the named filters, in order.
+func filterGraph(filters []string) (string, error) {
+	graphs := make([]string, 0, len(filters))
+	for _, name := range filters {
+		i := slices.IndexFunc(filterGraphs, func(f audioFilter) bool { return f.name == name })
+		if i < 0 {
+			return "", fmt.Errorf("unknown audio filter %q", name)
+		}
+		graphs = append(graphs, filterGraphs[i].graph)
+	}
+	return strings.Join(graphs, ","), nil
+}
+
+// failed removes the output of an ffmpeg run that failed, since it is incomplete, and returns its error.
+// ffmpeg is killed when the context is done, in which case the error of the context is returned.
+func failed(ctx context.Context, err error, stderr, outputPath string) error {
+	os.Remove(outputPath)
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("ffmpeg was stopped: %w", ctx.Err())
+	}
+	return fmt.Errorf("could not run ffmpeg: %w: %s", err, lastLine(stderr))
+}
+
+// escapeFilterValue quotes a value of a filter option, so that characters special to
+// filter graphs, like the commas of force_style or the colons of paths, are taken literally.
+func escapeFilterValue(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// progressTracker learns the input duration from ffmpeg's log
+// and reports the progress printed by the -progress option.
+type progressTracker struct {
+	report   func(float64)
+	duration atomic.Int64
+	pending  []byte
+}
+
+// Write scans the ffmpeg log for the input duration.
+func (t *progressTracker) Write(p []byte) (int, error) {
+	if t.duration.Load() > 0 {
+		return len(p), nil
+	}
+
+	t.pending = append(t.pending, p...)
+
+	if m := durationRe.FindSubmatch(t.pending); m != nil {
+		h, _ := strconv.Atoi(string(m[1]))
+		mins, _ := strconv.Atoi(string(m[2]))
+		sec, _ := strconv.ParseFloat(string(m[3]), 64)
+
+		d := time.Duration(h)*time.Hour + time.Duration(mins)*time.Minute + time.Duration(sec*float64(time.Second))
+		t.duration.Store(int64(d))
+		t.pending = nil
+	}
+	return len(p), nil
+}
+
+func (t *progressTracker) follow(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || key != "out_time_us" || t.report == nil {
+			continue
+		}
+
+		duration := t.duration.Load()
+		if duration <= 0 {
+			continue
+		}
+
+		us, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		t.report(min(float64(time.Duration(us)*time.Microsecond)/float64(duration), 1))
+	}
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return lines[len(lines)-1]
+}
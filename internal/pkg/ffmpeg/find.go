@@ -0,0 +1,64 @@
+package ffmpeg
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// ErrNotFound is returned when no ffmpeg binary is found.
+var ErrNotFound = errors.New("ffmpeg not found")
+
+// Find returns the path of the ffmpeg binary: the one in the PATH, else the one next to the executable,
+// else one in the directories package managers install it to. Services get a minimal PATH,
+// e.g. launchd leaves out Homebrew, so the binary is often not in it.
+func Find() (string, error) {
+	if path, err := exec.LookPath("ffmpeg"); err == nil {
+		return path, nil
+	}
+
+	name := "ffmpeg"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	var dirs []string
+	if exe, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Dir(exe))
+	}
+
+	for _, dir := range append(dirs, knownDirs()...) {
+		path := filepath.Join(dir, name)
+
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// knownDirs returns the directories ffmpeg is usually installed to on the current OS.
+func knownDirs() []string {
+	switch runtime.GOOS {
+	case "windows":
+		var dirs []string
+
+		for _, d := range [][2]string{
+			{"ProgramFiles", `ffmpeg\bin`},
+			{"LOCALAPPDATA", `Microsoft\WinGet\Links`}, // winget.
+			{"ProgramData", `chocolatey\bin`},
+			{"USERPROFILE", `scoop\shims`},
+		} {
+			if root := os.Getenv(d[0]); root != "" {
+				dirs = append(dirs, filepath.Join(root, d[1]))
+			}
+		}
+		return append(dirs, `C:\ffmpeg\bin`)
+	case "darwin":
+		return []string{"/opt/homebrew/bin", "/usr/local/bin", "/opt/local/bin"}
+	default:
+		return []string{"/usr/local/bin", "/usr/bin", "/snap/bin"}
+	}
+}
@@ -0,0 +1,188 @@
+// Package fulltext tokenizes the transcripts for the full-text index, and the queries alike, so that
+// a word matches its other forms: case and accents are ignored, and the words of the languages with
+// a stemmer are reduced to their stem, e.g. "legendas" matches "legenda" in Portuguese.
+package fulltext
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// maxSnippet is the number of runes of text kept around the first match of a snippet.
+const maxSnippet int = 160
+
+// Tokens returns the terms of the text in the language.
+func Tokens(language, text string) []string {
+	stem := stemmers[base(language)]
+
+	var terms []string
+	for _, w := range words(text) {
+		if term := normalize(w); term != "" {
+			if stem != nil && !ideographic(w) {
+				term = stem(term)
+			}
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Snippet returns the text as HTML, with the words matching the terms in <mark> elements,
+// shortened around the first match when longer than maxSnippet.
+func Snippet(language, text string, terms []string) string {
+	match := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		match[t] = true
+	}
+
+	stem := stemmers[base(language)]
+	runes := []rune(text)
+
+	var b strings.Builder
+	from, first := 0, -1
+
+	for _, span := range spans(runes) {
+		w := string(runes[span[0]:span[1]])
+
+		term := normalize(w)
+		if stem != nil && !ideographic(w) {
+			term = stem(term)
+		}
+
+		if !match[term] {
+			continue
+		}
+
+		if first < 0 {
+			first = span[0]
+		}
+
+		b.WriteString(html.EscapeString(string(runes[from:span[0]])))
+		b.WriteString("<mark>" + html.EscapeString(w) + "</mark>")
+		from = span[1]
+	}
+	b.WriteString(html.EscapeString(string(runes[from:])))
+
+	if len(runes) <= maxSnippet || first < 0 {
+		return b.String()
+	}
+
+	// The text is shortened before highlighting, so that the marks aren't cut, and between words.
+	start := max(0, first-maxSnippet/4)
+	end := min(len(runes), start+maxSnippet)
+
+	for start > 0 && start < first && !unicode.IsSpace(runes[start-1]) {
+		start++
+	}
+
+	for end < len(runes) && end > first && !unicode.IsSpace(runes[end]) {
+		end--
+	}
+
+	snippet := Snippet(language, string(runes[start:end]), terms)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// words splits the text into words. Ideographic characters are words of their own,
+// since the languages writing them don't separate words with spaces.
+func words(text string) []string {
+	runes := []rune(text)
+
+	var ws []string
+	for _, span := range spans(runes) {
+		ws = append(ws, string(runes[span[0]:span[1]]))
+	}
+	return ws
+}
+
+func spans(runes []rune) [][2]int {
+	var (
+		spans [][2]int
+		start = -1
+	)
+
+	for i, r := range runes {
+		switch {
+		case isIdeograph(r):
+			if start >= 0 {
+				spans = append(spans, [2]int{start, i})
+				start = -1
+			}
+			spans = append(spans, [2]int{i, i + 1})
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			if start < 0 {
+				start = i
+			}
+		default:
+			if start >= 0 {
+				spans = append(spans, [2]int{start, i})
+				start = -1
+			}
+		}
+	}
+
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(runes)})
+	}
+	return spans
+}
+
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func ideographic(w string) bool {
+	for _, r := range w {
+		return isIdeograph(r)
+	}
+	return false
+}
+
+// normalize lowercases the word and removes its accents.
+func normalize(w string) string {
+	var b strings.Builder
+
+	for _, r := range strings.ToLower(w) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		if folded, ok := accents[r]; ok {
+			b.WriteString(folded)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// base returns the language of a language tag, e.g. pt of pt-BR.
+func base(language string) string {
+	language, _, _ = strings.Cut(strings.ToLower(language), "-")
+	return language
+}
+
+var accents = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'č': "c",
+	'ď': "d", 'đ': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ę': "e", 'ě': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i",
+	'ł': "l",
+	'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'ř': "r",
+	'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss",
+	'ť': "t", 'ţ': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y",
+	'ź': "z", 'ż': "z", 'ž': "z",
+	'æ': "ae", 'œ': "oe",
+}
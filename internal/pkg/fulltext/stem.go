@@ -0,0 +1,63 @@
+package fulltext
+
+import "strings"
+
+// stemmers reduce the normalized words of a language to their stem. They are light stemmers removing
+// the inflections, mostly plurals, rather than the derivations, so that unrelated words aren't conflated.
+var stemmers = map[string]func(string) string{
+	"en": stemEnglish,
+	"pt": stemPortuguese,
+	"es": stemSpanish,
+	"fr": stemFrench,
+}
+
+// minStem is the minimum number of bytes of a stem.
+const minStem int = 3
+
+type suffix struct {
+	suffix, replacement string
+}
+
+// replaceSuffix replaces the first of the suffixes ending the word, when a long enough stem is left.
+func replaceSuffix(w string, suffixes []suffix) string {
+	for _, s := range suffixes {
+		if strings.HasSuffix(w, s.suffix) && len(w)-len(s.suffix)+len(s.replacement) >= minStem {
+			return w[:len(w)-len(s.suffix)] + s.replacement
+		}
+	}
+	return w
+}
+
+var englishSuffixes = []suffix{
+	{"sses", "ss"}, {"ies", "y"}, {"ss", "ss"}, {"us", "us"}, {"is", "is"},
+	{"ing", ""}, {"ed", ""}, {"s", ""},
+}
+
+func stemEnglish(w string) string {
+	return replaceSuffix(w, englishSuffixes)
+}
+
+var portugueseSuffixes = []suffix{
+	{"oes", "ao"}, {"aes", "ao"}, {"ais", "al"}, {"eis", "el"}, {"ois", "ol"}, {"uis", "ul"},
+	{"res", "r"}, {"zes", "z"}, {"ses", "s"}, {"ns", "m"}, {"s", ""},
+}
+
+func stemPortuguese(w string) string {
+	return replaceSuffix(w, portugueseSuffixes)
+}
+
+var spanishSuffixes = []suffix{
+	{"ces", "z"}, {"iones", "ion"}, {"res", "r"}, {"les", "l"}, {"nes", "n"}, {"des", "d"}, {"s", ""},
+}
+
+func stemSpanish(w string) string {
+	return replaceSuffix(w, spanishSuffixes)
+}
+
+var frenchSuffixes = []suffix{
+	{"eaux", "eau"}, {"aux", "al"}, {"s", ""}, {"x", ""},
+}
+
+func stemFrench(w string) string {
+	return replaceSuffix(w, frenchSuffixes)
+}
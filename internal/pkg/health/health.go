@@ -0,0 +1,145 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Check is a dependency the service needs to serve requests.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+
+	// Degrades, when set, makes the service degraded rather than unready when the check fails,
+	// for the dependencies only some requests need, e.g. the storage of new subtitles.
+	Degrades bool
+}
+
+// Result is the result of a check.
+type Result struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Degrades bool   `json:"degrades,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report is the readiness of the service: ready when all the checks pass, except those degrading it.
+type Report struct {
+	Ready    bool     `json:"ready"`
+	Degraded bool     `json:"degraded,omitempty"` // Some requests fail, see the failed checks.
+	Checks   []Result `json:"checks"`
+}
+
+// Checker runs the readiness checks.
+type Checker struct {
+	timeout time.Duration
+	checks  []Check
+}
+
+// New returns a new checker running the checks, each within the timeout.
+func New(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{timeout: timeout, checks: checks}
+}
+
+// Ready runs the checks concurrently and reports their results, in the order of the checks.
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := Report{Ready: true, Checks: make([]Result, len(c.checks))}
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+
+		go func(i int, check Check) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			report.Checks[i] = Result{Name: check.Name, OK: true, Degrades: check.Degrades}
+
+			if err := check.Check(ctx); err != nil {
+				report.Checks[i] = Result{Name: check.Name, Degrades: check.Degrades, Error: err.Error()}
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, r := range report.Checks {
+		if r.Degrades {
+			report.Degraded = report.Degraded || !r.OK
+			continue
+		}
+		report.Ready = report.Ready && r.OK
+	}
+	return report
+}
+
+// Command checks that the binary runs with the arguments, e.g. ffmpeg -version.
+func Command(name, binary string, args ...string) Check {
+	return Check{Name: name, Check: func(ctx context.Context) error {
+		if _, err := exec.LookPath(binary); err != nil {
+			return fmt.Errorf("could not find %s: %w", binary, err)
+		}
+
+		if out, err := exec.CommandContext(ctx, binary, args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("could not run %s: %w: %s", binary, err, lastLine(out))
+		}
+		return nil
+	}}
+}
+
+// Writable checks that files can be created in the directory.
+func Writable(name, dir string) Check {
+	return Check{Name: name, Check: func(ctx context.Context) error {
+		f, err := os.CreateTemp(dir, ".readyz-*")
+		if err != nil {
+			return fmt.Errorf("could not create file: %w", err)
+		}
+
+		f.Close()
+
+		if err := os.Remove(f.Name()); err != nil {
+			return fmt.Errorf("could not remove file: %w", err)
+		}
+		return nil
+	}}
+}
+
+// HTTP checks that a GET of the URL succeeds. The token is sent as a bearer token when not empty.
+func HTTP(name string, httpCli *http.Client, url, token string) Check {
+	return Check{Name: name, Check: func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("could not create request: %w", err)
+		}
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := httpCli.Do(req)
+		if err != nil {
+			return fmt.Errorf("could not send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}}
+}
+
+func lastLine(out []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return lines[len(lines)-1]
+}
@@ -0,0 +1,152 @@
+package highlights
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+const (
+	// minLength and maxLength bound the length of a highlight, which is meant to become a clip.
+	minLength = 10 * time.Second
+	maxLength = 60 * time.Second
+
+	// topicPause is the silence after which the speaker is assumed to move on to another topic.
+	topicPause = 2 * time.Second
+)
+
+// emphasis are words speakers use to stress what they consider worth remembering.
+var emphasis = []string{
+	"important", "key", "remember", "secret", "never", "always", "best", "worst",
+	"mistake", "lesson", "truth", "surprising", "believe", "imagine",
+}
+
+// Highlight is a segment of a recording worth turning into a clip.
+type Highlight struct {
+	Start   time.Duration
+	End     time.Duration
+	Text    string
+	Score   float64
+	Reasons []string // Why the segment was suggested, e.g. "topic change" or "quotable line".
+}
+
+// Detect suggests up to n non-overlapping highlights of the cues, sorted by start.
+// Segments start at topic changes, detected from long pauses, and score higher with
+// quotable lines: complete, short sentences, exclamations, questions and emphasized words.
+func Detect(cues []srt.Cue, n int) []Highlight {
+	if n < 1 || len(cues) == 0 {
+		return nil
+	}
+
+	scores := make([]float64, len(cues))
+	reasons := make([][]string, len(cues))
+
+	for i, cue := range cues {
+		if i == 0 || cue.Start-cues[i-1].End >= topicPause {
+			scores[i] += 1
+			reasons[i] = append(reasons[i], "topic change")
+		}
+
+		score, quotable := quote(cue.Text)
+		scores[i] += score
+		if quotable {
+			reasons[i] = append(reasons[i], "quotable line")
+		}
+	}
+
+	var candidates []Highlight
+
+	for i := range cues {
+		h := Highlight{Start: cues[i].Start}
+		var text []string
+
+		for j := i; j < len(cues); j++ {
+			if cues[j].End-h.Start > maxLength {
+				break
+			}
+
+			if j > i && cues[j].Start-cues[j-1].End >= topicPause && h.End-h.Start >= minLength {
+				break
+			}
+
+			h.End = cues[j].End
+			h.Score += scores[j]
+			h.Reasons = merge(h.Reasons, reasons[j])
+			text = append(text, strings.TrimSpace(cues[j].Text))
+		}
+
+		if h.End <= h.Start || h.Score == 0 {
+			continue
+		}
+
+		h.Text = strings.Join(strings.Fields(strings.Join(text, " ")), " ")
+		candidates = append(candidates, h)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+	var highlights []Highlight
+	for _, c := range candidates {
+		if len(highlights) == n {
+			break
+		}
+
+		if !overlaps(highlights, c) {
+			highlights = append(highlights, c)
+		}
+	}
+
+	sort.Slice(highlights, func(i, j int) bool { return highlights[i].Start < highlights[j].Start })
+	return highlights
+}
+
+// quote scores how quotable a line is.
+func quote(text string) (float64, bool) {
+	text = strings.TrimSpace(text)
+	words := strings.Fields(strings.ToLower(text))
+
+	if len(words) == 0 {
+		return 0, false
+	}
+
+	var score float64
+
+	switch {
+	case strings.HasSuffix(text, "!"):
+		score += 0.5
+	case strings.HasSuffix(text, "?"):
+		score += 0.3
+	case strings.HasSuffix(text, "."):
+		if len(words) >= 5 && len(words) <= 20 {
+			score += 0.3
+		}
+	}
+
+	for _, word := range words {
+		if slices.Contains(emphasis, strings.Trim(word, `.,;:!?"'`)) {
+			score += 0.5
+		}
+	}
+	return score, score >= 0.5
+}
+
+func merge(reasons, more []string) []string {
+	for _, r := range more {
+		if !slices.Contains(reasons, r) {
+			reasons = append(reasons, r)
+		}
+	}
+	return reasons
+}
+
+func overlaps(highlights []Highlight, c Highlight) bool {
+	for _, h := range highlights {
+		if c.Start < h.End && h.Start < c.End {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultName is used when no file name can be derived from the response or URL.
+const defaultName string = "video"
+
+var (
+	// ErrInvalidURL is returned for URLs that are not absolute http(s) URLs.
+	ErrInvalidURL = errors.New("invalid url")
+
+	// ErrForbiddenAddress is returned when the URL resolves to a loopback, private or otherwise
+	// non-public address, so that users can't make the server fetch from internal services.
+	ErrForbiddenAddress = errors.New("forbidden address")
+
+	// ErrTooLarge is returned while reading a download exceeding the maximum size.
+	ErrTooLarge = errors.New("download exceeds the maximum size")
+
+	// ErrUnsupportedType is returned when the server responds with a content type that can't be media,
+	// e.g. the HTML page of a video instead of the video file.
+	ErrUnsupportedType = errors.New("unsupported content type")
+)
+
+// mediaTypes are the non audio or video content types media files are commonly served with.
+var mediaTypes = map[string]bool{
+	"application/octet-stream": true,
+	"binary/octet-stream":      true,
+	"application/mp4":          true,
+	"application/ogg":          true,
+	"application/x-matroska":   true,
+}
+
+// Download is a media file being fetched.
+type Download struct {
+	Name string
+	Body io.ReadCloser
+}
+
+// Source fetches media files from URLs.
+type Source interface {
+	Fetch(ctx context.Context, rawURL string) (*Download, error)
+}
+
+// Fetcher downloads media files from URLs.
+type Fetcher struct {
+	httpCli *http.Client
+	maxSize int64
+}
+
+// New returns a new fetcher for downloads of at most maxSize bytes.
+func New(maxSize int64, timeout time.Duration) *Fetcher {
+	return &Fetcher{
+		httpCli: &http.Client{Transport: PublicTransport(), Timeout: timeout},
+		maxSize: maxSize,
+	}
+}
+
+// PublicTransport returns a transport connecting only to public addresses, failing with ErrForbiddenAddress
+// otherwise, for the requests to URLs given by users. The addresses are checked once resolved, so that
+// hosts resolving to internal services are rejected too, as are redirects to them.
+func PublicTransport() *http.Transport {
+	dialer := net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); !Public(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return transport
+}
+
+// Public reports whether the IP address is a public one, not a loopback, private or link-local one.
+func Public(ip net.IP) bool {
+	return ip != nil && ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
+
+// Fetch starts downloading the file at the URL. The caller must close the body.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Download, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidURL, rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+
+	resp, err := f.httpCli.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	if resp.ContentLength > f.maxSize {
+		resp.Body.Close()
+		return nil, ErrTooLarge
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); !IsMedia(contentType) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedType, contentType)
+	}
+
+	return &Download{
+		Name: fileName(resp),
+		Body: &limitedBody{ReadCloser: resp.Body, remaining: f.maxSize},
+	}, nil
+}
+
+// fileName derives the name of the download from the Content-Disposition header,
+// or from the last segment of the URL path of the final request.
+func fileName(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := path.Base(params["filename"]); name != "." && name != "/" {
+			return name
+		}
+	}
+
+	if name := path.Base(resp.Request.URL.Path); name != "." && name != "/" {
+		return name
+	}
+	return defaultName
+}
+
+// IsMedia reports whether the content type can be an audio or video file.
+// Responses without content type are accepted.
+func IsMedia(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "video/") || strings.HasPrefix(mediaType, "audio/") || mediaTypes[mediaType]
+}
+
+// limitedBody fails reads once more than the remaining bytes were read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrTooLarge
+	}
+
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+
+	if b.remaining < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
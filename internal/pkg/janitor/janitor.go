@@ -0,0 +1,249 @@
+// Package janitor reclaims the disk space of long-running deployments: it removes orphaned tmp files,
+// expired subtitles and old job records on a schedule.
+package janitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type subtitleExpirer interface {
+	Enforce(now time.Time) (int, int64, error)
+}
+
+type jobPruner interface {
+	Prune(before time.Time) (int, error)
+}
+
+// Report is what a sweep reclaimed.
+type Report struct {
+	TmpFiles      int   `json:"tmp_files"`
+	TmpBytes      int64 `json:"tmp_bytes"`
+	Subtitles     int   `json:"subtitles"`
+	SubtitleBytes int64 `json:"subtitle_bytes"`
+	Jobs          int   `json:"jobs"`
+}
+
+// Bytes returns the disk space reclaimed.
+func (r Report) Bytes() int64 {
+	return r.TmpBytes + r.SubtitleBytes
+}
+
+func (r *Report) add(o Report) {
+	r.TmpFiles += o.TmpFiles
+	r.TmpBytes += o.TmpBytes
+	r.Subtitles += o.Subtitles
+	r.SubtitleBytes += o.SubtitleBytes
+	r.Jobs += o.Jobs
+}
+
+// Stats are the totals reclaimed since the server started.
+type Stats struct {
+	Sweeps    int        `json:"sweeps"`
+	LastSweep *time.Time `json:"last_sweep,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	Last      Report     `json:"last"`
+	Total     Report     `json:"total"`
+}
+
+// Janitor sweeps the tmp directory, the subtitles and the jobs.
+type Janitor struct {
+	logger    *slog.Logger
+	tmpDirs   []string
+	tmpTTL    time.Duration
+	subtitles subtitleExpirer
+	jobs      jobPruner
+	jobTTL    time.Duration
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// New returns a new janitor removing the files of the tmp directories older than tmpTTL, the subtitles expired by
+// their retention policies and the finished jobs older than jobTTL. A zero TTL keeps the files or the jobs.
+// Only the files at the top of the tmp directories are swept: their directories, e.g. of resumable uploads,
+// expire on their own.
+func New(logger *slog.Logger, tmpDirs []string, tmpTTL time.Duration, subtitles subtitleExpirer, jobs jobPruner, jobTTL time.Duration) *Janitor {
+	return &Janitor{
+		logger:    logger,
+		tmpDirs:   tmpDirs,
+		tmpTTL:    tmpTTL,
+		subtitles: subtitles,
+		jobs:      jobs,
+		jobTTL:    jobTTL,
+	}
+}
+
+// Sweep removes what expired at the given time and returns what it reclaimed.
+// Each step runs even when the previous one failed, and their errors are joined.
+func (j *Janitor) Sweep(now time.Time) (Report, error) {
+	var (
+		report Report
+		errs   []error
+		err    error
+	)
+
+	if j.tmpTTL > 0 {
+		if report.TmpFiles, report.TmpBytes, err = j.sweepTmp(now.Add(-j.tmpTTL)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if report.Subtitles, report.SubtitleBytes, err = j.subtitles.Enforce(now); err != nil {
+		errs = append(errs, fmt.Errorf("could not remove expired subtitles: %w", err))
+	}
+
+	if j.jobTTL > 0 {
+		if report.Jobs, err = j.jobs.Prune(now.Add(-j.jobTTL)); err != nil {
+			errs = append(errs, fmt.Errorf("could not prune jobs: %w", err))
+		}
+	}
+
+	err = errors.Join(errs...)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.stats.Sweeps++
+	j.stats.LastSweep = &now
+	j.stats.Last = report
+	j.stats.Total.add(report)
+
+	j.stats.LastError = ""
+	if err != nil {
+		j.stats.LastError = err.Error()
+	}
+	return report, err
+}
+
+// Stats returns the totals reclaimed since the janitor started.
+func (j *Janitor) Stats() Stats {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.stats
+}
+
+// Run sweeps every interval until the context is done.
+func (j *Janitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			report, err := j.Sweep(now)
+			if err != nil {
+				j.logger.Error("Could not sweep", slog.String("error", err.Error()))
+			}
+
+			if report != (Report{}) {
+				j.logger.Info("Reclaimed disk space",
+					slog.Int64("bytes", report.Bytes()),
+					slog.Int("tmp_files", report.TmpFiles),
+					slog.Int("subtitles", report.Subtitles),
+					slog.Int("jobs", report.Jobs),
+				)
+			}
+		}
+	}
+}
+
+// Leftover is a file left in a tmp directory.
+type Leftover struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Leftovers returns the files of the tmp directories that the next sweep at the given time would remove,
+// e.g. to report them. It is empty when the tmp files are kept.
+func (j *Janitor) Leftovers(now time.Time) ([]Leftover, error) {
+	leftovers := []Leftover{}
+	if j.tmpTTL <= 0 {
+		return leftovers, nil
+	}
+
+	for _, dir := range j.tmpDirs {
+		files, err := tmpFiles(dir, now.Add(-j.tmpTTL))
+		if err != nil {
+			return leftovers, err
+		}
+		leftovers = append(leftovers, files...)
+	}
+	return leftovers, nil
+}
+
+// SweepTmp removes the files of the tmp directories that expired at the given time, without waiting for
+// the next sweep, and returns how many files and bytes it removed.
+func (j *Janitor) SweepTmp(now time.Time) (int, int64, error) {
+	if j.tmpTTL <= 0 {
+		return 0, 0, nil
+	}
+	return j.sweepTmp(now.Add(-j.tmpTTL))
+}
+
+// sweepTmp removes the files of the tmp directories last modified before the given time, left behind
+// e.g. by a crash. Files of running jobs are more recent, since jobs don't last as long as the TTL.
+func (j *Janitor) sweepTmp(before time.Time) (int, int64, error) {
+	var (
+		removed int
+		size    int64
+	)
+
+	for _, dir := range j.tmpDirs {
+		files, err := tmpFiles(dir, before)
+		if err != nil {
+			return removed, size, err
+		}
+
+		for _, f := range files {
+			if err := os.Remove(f.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return removed, size, fmt.Errorf("could not remove tmp file: %w", err)
+			}
+
+			j.logger.Debug("Removed orphaned tmp file", slog.String("file", filepath.Base(f.Path)))
+
+			removed++
+			size += f.Size
+		}
+	}
+	return removed, size, nil
+}
+
+// tmpFiles returns the files at the top of the tmp directory last modified before the given time.
+func tmpFiles(dir string, before time.Time) ([]Leftover, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read tmp directory: %w", err)
+	}
+
+	var files []Leftover
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return files, fmt.Errorf("could not stat tmp file: %w", err)
+		}
+
+		if !info.ModTime().Before(before) {
+			continue
+		}
+		files = append(files, Leftover{Path: filepath.Join(dir, e.Name()), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return files, nil
+}
@@ -0,0 +1,569 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Type is the kind of processing of a job.
+type Type string
+
+const (
+	TypeTranscription Type = "transcription"
+	TypeClips         Type = "clips"
+	TypeTerminology   Type = "terminology"
+)
+
+// State is the processing state of a job or of one of its files.
+type State string
+
+const (
+	StateQueued       State = "queued"
+	StateExtracting   State = "extracting"
+	StateScheduled    State = "scheduled"
+	StateTranscribing State = "transcribing"
+	StateRendering    State = "rendering"
+	StateChecking     State = "checking"
+	StateDone         State = "done"
+	StateFailed       State = "failed"
+)
+
+// PublishState is the state of the publication of a file to a video platform.
+type PublishState string
+
+const (
+	PublishPending   PublishState = "pending"
+	PublishPublished PublishState = "published"
+	PublishFailed    PublishState = "failed"
+)
+
+// ReviewState is the state of the review of a subtitle whose quality is below the threshold.
+type ReviewState string
+
+const (
+	ReviewPending  ReviewState = "pending"
+	ReviewApproved ReviewState = "approved"
+)
+
+// ErrNotFound is returned when the job does not exist.
+var ErrNotFound = errors.New("job not found")
+
+// subscriberBuffer is the number of events buffered per subscriber.
+// Events are dropped for subscribers that don't keep up.
+const subscriberBuffer int = 64
+
+// File is the progress of a single file of a job.
+type File struct {
+	Name     string   `json:"name"`
+	State    State    `json:"state"`
+	Progress float64  `json:"progress"` // Fraction of the current state completed, when known.
+	Error    string   `json:"error,omitempty"`
+	Subtitle string   `json:"subtitle,omitempty"` // Name of the stored subtitle, once done.
+	Project  string   `json:"project,omitempty"`  // Project of the stored subtitle, once done.
+	Outputs  []string `json:"outputs,omitempty"`  // Names of the other files produced for the file, e.g. clips or artifacts.
+
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"` // When the transcription starts, once deferred.
+
+	Review ReviewState `json:"review,omitempty"` // Set when the subtitle is held for review.
+
+	Publication *Publication `json:"publication,omitempty"`
+}
+
+// Publication tracks the publication of the subtitle of a file to a video platform.
+type Publication struct {
+	Platform string       `json:"platform"`
+	VideoID  string       `json:"video_id"`
+	State    PublishState `json:"state"`
+	Ref      string       `json:"ref,omitempty"` // Identifier of the caption track on the platform.
+	Error    string       `json:"error,omitempty"`
+}
+
+// Event reports a change of a file of a job.
+type Event struct {
+	JobID    string  `json:"job_id"`
+	JobState State   `json:"job_state"`
+	Index    int     `json:"index"`
+	File     string  `json:"file"`
+	State    State   `json:"state"`
+	Progress float64 `json:"progress"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Job tracks the processing of the files of an upload.
+type Job struct {
+	ID        string    `json:"id"`
+	Type      Type      `json:"type"`
+	Owner     string    `json:"owner,omitempty"` // User who created the job, empty for anonymous callers.
+	State     State     `json:"state"`
+	Files     []File    `json:"files"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Finished reports whether the job reached a final state.
+func (j Job) Finished() bool {
+	return j.State == StateDone || j.State == StateFailed
+}
+
+// errInterrupted is recorded on the files of the jobs still running when the server stopped.
+var errInterrupted = errors.New("interrupted by a server restart")
+
+type repository interface {
+	SaveJob(job Job) error
+	Jobs() ([]Job, error)
+	DeleteJob(id string) error
+}
+
+// Manager keeps track of the jobs. Jobs are saved to the repository
+// as they change state, so their history survives restarts.
+type Manager struct {
+	repo        repository
+	mu          sync.RWMutex
+	jobs        map[string]*Job
+	subscribers map[string]map[chan Event]struct{}
+}
+
+// NewManager returns a new job manager, loading the jobs saved in the repository.
+// Jobs that were running when the server stopped are marked as failed.
+func NewManager(repo repository) (*Manager, error) {
+	m := Manager{
+		repo:        repo,
+		jobs:        make(map[string]*Job),
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+
+	saved, err := repo.Jobs()
+	if err != nil {
+		return nil, fmt.Errorf("could not load jobs: %w", err)
+	}
+
+	for i := range saved {
+		job := &saved[i]
+
+		if !job.Finished() {
+			for j := range job.Files {
+				if f := &job.Files[j]; f.State != StateDone && f.State != StateFailed {
+					f.State, f.Error = StateFailed, errInterrupted.Error()
+				}
+			}
+
+			job.State = aggregate(job.Files)
+			job.UpdatedAt = time.Now().UTC()
+
+			if err := repo.SaveJob(*job); err != nil {
+				return nil, fmt.Errorf("could not save job: %w", err)
+			}
+		}
+		m.jobs[job.ID] = job
+	}
+	return &m, nil
+}
+
+// Create registers a new queued job of the owner for the files.
+func (m *Manager) Create(jobType Type, owner string, fileNames []string) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	now := time.Now().UTC()
+
+	job := Job{
+		ID:        id,
+		Type:      jobType,
+		Owner:     owner,
+		State:     StateQueued,
+		Files:     make([]File, 0, len(fileNames)),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	for _, name := range fileNames {
+		job.Files = append(job.Files, File{Name: name, State: StateQueued})
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.repo.SaveJob(job); err != nil {
+		return Job{}, fmt.Errorf("could not save job: %w", err)
+	}
+
+	m.jobs[id] = &job
+	return job.copy(), nil
+}
+
+// Get returns a snapshot of the job.
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return job.copy(), nil
+}
+
+// List returns snapshots of the jobs created from the from time and before the to time, oldest first.
+// Zero times don't bound the range.
+func (m *Manager) List(from, to time.Time) []Job {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var list []Job
+	for _, job := range m.jobs {
+		if !from.IsZero() && job.CreatedAt.Before(from) || !to.IsZero() && !job.CreatedAt.Before(to) {
+			continue
+		}
+		list = append(list, job.copy())
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// SetFileState updates the state and progress of the file at index,
+// recomputes the state of the job and notifies the subscribers.
+func (m *Manager) SetFileState(id string, index int, state State, progress float64, fileErr error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if index < 0 || index >= len(job.Files) {
+		return fmt.Errorf("file index %d out of range", index)
+	}
+
+	file := &job.Files[index]
+	changed := file.State != state
+
+	file.State = state
+	file.Progress = progress
+
+	if fileErr != nil {
+		file.Error = fileErr.Error()
+	}
+
+	job.State = aggregate(job.Files)
+	job.UpdatedAt = time.Now().UTC()
+
+	m.publish(Event{
+		JobID:    id,
+		JobState: job.State,
+		Index:    index,
+		File:     file.Name,
+		State:    file.State,
+		Progress: file.Progress,
+		Error:    file.Error,
+	})
+
+	if job.Finished() {
+		m.closeSubscribers(id)
+	}
+
+	// Progress updates are frequent and only matter while the job runs, so only state changes are saved.
+	if changed {
+		if err := m.repo.SaveJob(*job); err != nil {
+			return fmt.Errorf("could not save job: %w", err)
+		}
+	}
+	return nil
+}
+
+// Requeue resets the unfinished files of the job to queued, e.g. for a job waiting in a queue
+// that was marked as interrupted by a restart, before processing them.
+func (m *Manager) Requeue(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	for i := range job.Files {
+		if f := &job.Files[i]; f.State != StateDone {
+			f.State, f.Progress, f.Error = StateQueued, 0, ""
+		}
+	}
+
+	job.State = aggregate(job.Files)
+	job.UpdatedAt = time.Now().UTC()
+
+	if err := m.repo.SaveJob(*job); err != nil {
+		return fmt.Errorf("could not save job: %w", err)
+	}
+	return nil
+}
+
+// SetFileSubtitle records the name and project of the stored subtitle of the file at index.
+func (m *Manager) SetFileSubtitle(id string, index int, subName, project string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if index < 0 || index >= len(job.Files) {
+		return fmt.Errorf("file index %d out of range", index)
+	}
+
+	job.Files[index].Subtitle = subName
+	job.Files[index].Project = project
+
+	if err := m.repo.SaveJob(*job); err != nil {
+		return fmt.Errorf("could not save job: %w", err)
+	}
+	return nil
+}
+
+// SetFileOutputs records the names of the files rendered for the file at index.
+func (m *Manager) SetFileOutputs(id string, index int, outputs []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if index < 0 || index >= len(job.Files) {
+		return fmt.Errorf("file index %d out of range", index)
+	}
+
+	job.Files[index].Outputs = append([]string(nil), outputs...)
+
+	if err := m.repo.SaveJob(*job); err != nil {
+		return fmt.Errorf("could not save job: %w", err)
+	}
+	return nil
+}
+
+// SetFileSchedule records when the deferred processing of the file at index starts.
+func (m *Manager) SetFileSchedule(id string, index int, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if index < 0 || index >= len(job.Files) {
+		return fmt.Errorf("file index %d out of range", index)
+	}
+
+	at = at.UTC()
+	job.Files[index].ScheduledAt = &at
+
+	if err := m.repo.SaveJob(*job); err != nil {
+		return fmt.Errorf("could not save job: %w", err)
+	}
+	return nil
+}
+
+// SetFileReview records the review state of the subtitle of the file at index.
+func (m *Manager) SetFileReview(id string, index int, state ReviewState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if index < 0 || index >= len(job.Files) {
+		return fmt.Errorf("file index %d out of range", index)
+	}
+
+	job.Files[index].Review = state
+	job.UpdatedAt = time.Now().UTC()
+
+	if err := m.repo.SaveJob(*job); err != nil {
+		return fmt.Errorf("could not save job: %w", err)
+	}
+	return nil
+}
+
+// SetPublication records the publication state of the file at index.
+func (m *Manager) SetPublication(id string, index int, p Publication) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if index < 0 || index >= len(job.Files) {
+		return fmt.Errorf("file index %d out of range", index)
+	}
+
+	job.Files[index].Publication = &p
+	job.UpdatedAt = time.Now().UTC()
+
+	if err := m.repo.SaveJob(*job); err != nil {
+		return fmt.Errorf("could not save job: %w", err)
+	}
+	return nil
+}
+
+// Subscribe returns a channel receiving the events of the job and a function to unsubscribe.
+// The channel is closed once the job finishes.
+func (m *Manager) Subscribe(id string) (<-chan Event, func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+
+	if job.Finished() {
+		close(ch)
+		return ch, func() {}, nil
+	}
+
+	if m.subscribers[id] == nil {
+		m.subscribers[id] = make(map[chan Event]struct{})
+	}
+	m.subscribers[id][ch] = struct{}{}
+
+	unsubscribe := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if _, ok := m.subscribers[id][ch]; ok {
+			delete(m.subscribers[id], ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe, nil
+}
+
+// Prune deletes the finished jobs last updated before the given time, and returns the number of deleted jobs.
+func (m *Manager) Prune(before time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pruned int
+
+	for id, job := range m.jobs {
+		if !job.Finished() || !job.UpdatedAt.Before(before) {
+			continue
+		}
+
+		if err := m.repo.DeleteJob(id); err != nil {
+			return pruned, fmt.Errorf("could not delete job: %w", err)
+		}
+
+		delete(m.jobs, id)
+		pruned++
+	}
+	return pruned, nil
+}
+
+// publish must be called with the lock held.
+func (m *Manager) publish(e Event) {
+	for ch := range m.subscribers[e.JobID] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// closeSubscribers must be called with the lock held.
+func (m *Manager) closeSubscribers(id string) {
+	for ch := range m.subscribers[id] {
+		close(ch)
+	}
+	delete(m.subscribers, id)
+}
+
+// aggregate derives the state of a job from its files. A job is done or failed
+// once all files are finished, and otherwise reports its most advanced running stage.
+func aggregate(files []File) State {
+	var (
+		finished, failed                              int
+		extracting, transcribing, rendering, checking bool
+		scheduled                                     bool
+	)
+
+	for _, f := range files {
+		switch f.State {
+		case StateDone:
+			finished++
+		case StateFailed:
+			finished++
+			failed++
+		case StateExtracting:
+			extracting = true
+		case StateTranscribing:
+			transcribing = true
+		case StateRendering:
+			rendering = true
+		case StateChecking:
+			checking = true
+		case StateScheduled:
+			scheduled = true
+		}
+	}
+
+	switch {
+	case finished == len(files) && failed > 0:
+		return StateFailed
+	case finished == len(files):
+		return StateDone
+	case rendering:
+		return StateRendering
+	case transcribing:
+		return StateTranscribing
+	case extracting:
+		return StateExtracting
+	case checking:
+		return StateChecking
+	case scheduled:
+		return StateScheduled
+	default:
+		return StateQueued
+	}
+}
+
+func (j *Job) copy() Job {
+	c := *j
+	c.Files = append([]File(nil), j.Files...)
+
+	for i, f := range c.Files {
+		c.Files[i].Outputs = append([]string(nil), f.Outputs...)
+
+		if f.Publication != nil {
+			p := *f.Publication
+			c.Files[i].Publication = &p
+		}
+	}
+	return c
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate job id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryRepository saves jobs in memory, so that the manager can be embedded in other programs,
+// or tested, without a database. Jobs are lost when the program stops.
+type MemoryRepository struct {
+	mu   sync.RWMutex
+	jobs map[string]Job
+}
+
+// NewMemoryRepository returns a new empty repository in memory.
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{jobs: make(map[string]Job)}
+}
+
+// SaveJob saves a copy of the job.
+func (r *MemoryRepository) SaveJob(job Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.jobs[job.ID] = job.copy()
+	return nil
+}
+
+// DeleteJob deletes the job.
+func (r *MemoryRepository) DeleteJob(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.jobs, id)
+	return nil
+}
+
+// Jobs returns copies of the saved jobs, the oldest first.
+func (r *MemoryRepository) Jobs() ([]Job, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	jobs := make([]Job, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		jobs = append(jobs, job.copy())
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return jobs, nil
+}
@@ -0,0 +1,91 @@
+package langid
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Unknown is returned when the language of a text cannot be identified.
+const Unknown string = "und"
+
+// stopwords holds frequent function words of the supported languages.
+// Short texts (a subtitle cue) rarely carry enough signal for n-gram models,
+// but almost always contain a few of these.
+var stopwords = map[string][]string{
+	"pt": {"o", "os", "a", "as", "um", "uma", "de", "do", "da", "dos", "das", "em", "no", "na", "que", "não", "é", "com", "para", "por", "mas", "se", "eu", "você", "ele", "ela", "isso", "isto", "muito", "também", "está", "são", "foi", "tem", "mais", "como", "quando", "onde", "porque", "então", "aqui", "agora", "nós", "vocês"},
+	"en": {"the", "a", "an", "of", "to", "in", "and", "is", "it", "that", "this", "you", "i", "he", "she", "we", "they", "was", "were", "are", "be", "have", "has", "not", "but", "for", "with", "on", "at", "what", "so", "do", "don't", "it's", "i'm", "just", "like", "there", "about", "because", "when", "where"},
+	"es": {"el", "la", "los", "las", "un", "una", "de", "del", "en", "que", "no", "es", "con", "para", "por", "pero", "si", "yo", "tú", "usted", "él", "ella", "eso", "esto", "muy", "también", "está", "son", "fue", "tiene", "más", "como", "cuando", "donde", "porque", "entonces", "aquí", "ahora", "nosotros", "y"},
+	"fr": {"le", "la", "les", "un", "une", "de", "du", "des", "en", "que", "ne", "pas", "est", "avec", "pour", "par", "mais", "si", "je", "tu", "il", "elle", "nous", "vous", "ils", "ce", "cette", "très", "aussi", "sont", "était", "a", "plus", "comme", "quand", "où", "parce", "alors", "ici", "maintenant", "et", "c'est"},
+	"de": {"der", "die", "das", "ein", "eine", "und", "ist", "nicht", "zu", "mit", "für", "von", "aber", "wenn", "ich", "du", "er", "sie", "wir", "ihr", "es", "auch", "sehr", "sind", "war", "hat", "mehr", "wie", "wo", "weil", "dann", "hier", "jetzt", "auf", "den", "dem", "im"},
+	"it": {"il", "lo", "la", "gli", "le", "un", "una", "di", "del", "della", "in", "che", "non", "è", "con", "per", "ma", "se", "io", "tu", "lui", "lei", "noi", "voi", "questo", "molto", "anche", "sono", "era", "ha", "più", "come", "quando", "dove", "perché", "allora", "qui", "adesso", "e"},
+}
+
+var index = func() map[string][]string {
+	idx := make(map[string][]string)
+	for lang, words := range stopwords {
+		for _, w := range words {
+			idx[w] = append(idx[w], lang)
+		}
+	}
+	return idx
+}()
+
+// Detect returns the most likely language of the text and a confidence between 0 and 1.
+// When no supported language scores above the others, Detect returns the fallback language.
+func Detect(text, fallback string) (string, float64) {
+	scores := make(map[string]float64)
+
+	var total int
+	for _, word := range tokenize(text) {
+		langs, ok := index[word]
+		if !ok {
+			continue
+		}
+
+		total++
+
+		// Words shared by several languages carry less signal.
+		for _, lang := range langs {
+			scores[lang] += 1 / float64(len(langs))
+		}
+	}
+
+	if total == 0 {
+		return fallback, 0
+	}
+
+	var (
+		best      string
+		bestScore float64
+		tie       bool
+	)
+
+	for lang, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = lang, score, false
+		case score == bestScore:
+			tie = true
+		}
+	}
+
+	if tie {
+		if _, ok := scores[fallback]; ok && scores[fallback] == bestScore {
+			return fallback, bestScore / float64(total)
+		}
+		return fallback, 0
+	}
+	return best, bestScore / float64(total)
+}
+
+// IsStopword reports whether the word is a frequent function word of a supported language.
+func IsStopword(word string) bool {
+	_, ok := index[strings.ToLower(word)]
+	return ok
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+}
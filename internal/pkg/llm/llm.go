@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	chatCompletionsPath string = "/chat/completions"
+	embeddingsPath      string = "/embeddings"
+)
+
+// Message is a message of a chat.
+type Message struct {
+	Role    string `json:"role"` // system, user or assistant.
+	Content string `json:"content"`
+}
+
+// Client calls the chat and embedding models of an OpenAI compatible API.
+type Client struct {
+	httpCli *http.Client
+	baseURL string
+	apiKey  string
+}
+
+// New returns a new client of the API at baseURL, e.g. https://api.openai.com/v1.
+func New(httpCli *http.Client, baseURL, apiKey string) *Client {
+	return &Client{
+		httpCli: httpCli,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+	}
+}
+
+type chatRequest struct {
+	Model          string            `json:"model"`
+	Messages       []Message         `json:"messages"`
+	ResponseFormat map[string]string `json:"response_format,omitempty"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+}
+
+// Chat returns the reply of the model to the messages. With jsonObject, the model replies with a JSON object.
+func (c *Client) Chat(ctx context.Context, model string, messages []Message, jsonObject bool) (string, error) {
+	req := chatRequest{Model: model, Messages: messages}
+	if jsonObject {
+		req.ResponseFormat = map[string]string{"type": "json_object"}
+	}
+
+	var resp chatResponse
+	if err := c.post(ctx, chatCompletionsPath, req, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", errors.New("no choices in response")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed returns the embeddings of the inputs, in order.
+func (c *Client) Embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	var resp embeddingsResponse
+	if err := c.post(ctx, embeddingsPath, embeddingsRequest{Model: model, Input: inputs}, &resp); err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(inputs))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(inputs) {
+			return nil, fmt.Errorf("unexpected embedding index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+
+	for i, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("no embedding for input %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("could not marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpCli.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("could not read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, respData)
+	}
+
+	if err := json.Unmarshal(respData, out); err != nil {
+		return fmt.Errorf("could not decode response: %w", err)
+	}
+	return nil
+}
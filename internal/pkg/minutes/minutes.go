@@ -0,0 +1,103 @@
+package minutes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/llm"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+// instructions ask the model for the minutes as a JSON object, in the language of the meeting.
+const instructions string = `You write the minutes of meetings from their transcript.
+Each line of the transcript starts with its timestamp as [HH:MM:SS], and with the speaker when known.
+Respond with a JSON object with these fields, written in the language of the meeting:
+- "summary": a short paragraph summarizing the meeting.
+- "decisions": the decisions taken, as objects with "text" and "at", the timestamp where the decision was taken.
+- "action_items": the tasks someone committed to, as objects with "text", "owner" (the person responsible,
+  empty when unclear), "due" (the deadline as said in the meeting, empty when none) and "at".
+Only include decisions and action items that were explicitly stated. Use empty arrays when there are none.`
+
+// Minutes are the structured minutes of a meeting.
+type Minutes struct {
+	Summary     string       `json:"summary"`
+	Decisions   []Decision   `json:"decisions"`
+	ActionItems []ActionItem `json:"action_items"`
+}
+
+// Decision is a decision taken in a meeting.
+type Decision struct {
+	Text string `json:"text"`
+	At   string `json:"at"` // Timestamp in the recording, as HH:MM:SS.
+}
+
+// ActionItem is a task someone committed to in a meeting.
+type ActionItem struct {
+	Text  string `json:"text"`
+	Owner string `json:"owner"`
+	Due   string `json:"due,omitempty"`
+	At    string `json:"at"` // Timestamp in the recording, as HH:MM:SS.
+}
+
+type chatClient interface {
+	Chat(ctx context.Context, model string, messages []llm.Message, jsonObject bool) (string, error)
+}
+
+// Writer writes the minutes of meetings with a chat model.
+type Writer struct {
+	chat  chatClient
+	model string
+}
+
+// NewWriter returns a new writer using the chat model.
+func NewWriter(chat chatClient, model string) *Writer {
+	return &Writer{chat: chat, model: model}
+}
+
+// Write writes the minutes of the meeting transcribed in the cues.
+func (w *Writer) Write(ctx context.Context, cues []srt.Cue) (Minutes, error) {
+	reply, err := w.chat.Chat(ctx, w.model, []llm.Message{
+		{Role: "system", Content: instructions},
+		{Role: "user", Content: transcript(cues)},
+	}, true)
+	if err != nil {
+		return Minutes{}, err
+	}
+
+	var m Minutes
+	if err := json.Unmarshal([]byte(reply), &m); err != nil {
+		return Minutes{}, fmt.Errorf("could not decode minutes: %w", err)
+	}
+
+	if m.Decisions == nil {
+		m.Decisions = []Decision{}
+	}
+
+	if m.ActionItems == nil {
+		m.ActionItems = []ActionItem{}
+	}
+	return m, nil
+}
+
+// transcript formats the cues as lines prefixed with their start, e.g. "[00:01:05] Let's start.".
+func transcript(cues []srt.Cue) string {
+	var b strings.Builder
+
+	for _, cue := range cues {
+		text := strings.Join(strings.Fields(cue.Text), " ")
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "[%s] %s\n", timestamp(cue.Start), text)
+	}
+	return b.String()
+}
+
+// timestamp formats the position in the recording as HH:MM:SS.
+func timestamp(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
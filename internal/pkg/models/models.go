@@ -0,0 +1,460 @@
+// Package models manages the model files of local transcription backends, e.g. whisper.cpp or Vosk,
+// so that offline deployments can install and pin them without shell access.
+package models
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backends the model files are for.
+const (
+	BackendWhisperCPP string = "whisper.cpp" // A single ggml file.
+	BackendVosk       string = "vosk"        // A zip archive of a model directory.
+)
+
+// manifestName is the file recording the installed models, in the models directory.
+const manifestName string = "models.json"
+
+var (
+	// ErrNotFound is returned when the model is not installed.
+	ErrNotFound = errors.New("model not found")
+
+	// ErrInvalidModel is returned when a model fails validation.
+	ErrInvalidModel = errors.New("invalid model")
+
+	// ErrPinned is returned when removing or replacing a pinned model.
+	ErrPinned = errors.New("model is pinned")
+
+	// ErrChecksumMismatch is returned when a downloaded file doesn't match its checksum.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	// ErrDownloading is returned when the model is already being downloaded.
+	ErrDownloading = errors.New("model is being downloaded")
+
+	nameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,63}$`)
+)
+
+// Model is a model file installed in the models directory, in a subdirectory named after it.
+type Model struct {
+	Name    string `json:"name"`
+	Backend string `json:"backend"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256"` // Hex encoded checksum of the file at URL.
+
+	// Pinned models can't be removed nor replaced by another file, e.g. to keep the model
+	// of a deployment from being changed by accident.
+	Pinned      bool      `json:"pinned"`
+	InstalledAt time.Time `json:"installed_at"`
+
+	// Path and Size are the directory of the model and its disk usage in bytes. They aren't recorded.
+	Path string `json:"path,omitempty"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// Validate checks the fields of a model to download.
+func (m Model) Validate() error {
+	switch {
+	case !nameRe.MatchString(m.Name):
+		return fmt.Errorf("%w: name %q", ErrInvalidModel, m.Name)
+	case m.Backend != BackendWhisperCPP && m.Backend != BackendVosk:
+		return fmt.Errorf("%w: backend must be %s or %s", ErrInvalidModel, BackendWhisperCPP, BackendVosk)
+	case !strings.HasPrefix(m.URL, "http://") && !strings.HasPrefix(m.URL, "https://"):
+		return fmt.Errorf("%w: URL must be HTTP(S)", ErrInvalidModel)
+	case len(m.SHA256) != sha256.Size*2:
+		return fmt.Errorf("%w: checksum must be a hex encoded SHA-256", ErrInvalidModel)
+	}
+
+	if _, err := hex.DecodeString(m.SHA256); err != nil {
+		return fmt.Errorf("%w: checksum must be a hex encoded SHA-256", ErrInvalidModel)
+	}
+	return nil
+}
+
+// Usage is the disk usage of the installed models.
+type Usage struct {
+	Models []Model `json:"models"`
+	Size   int64   `json:"size"` // Bytes used by all models.
+}
+
+// Store installs model files in a directory and records them in its manifest.
+type Store struct {
+	httpCli *http.Client
+	dir     string
+	maxSize int64
+
+	mu          sync.Mutex
+	models      map[string]Model
+	downloading map[string]bool
+}
+
+// NewStore returns a store of the models installed in dir. Downloads are bounded to maxSize bytes.
+func NewStore(httpCli *http.Client, dir string, maxSize int64) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create models directory: %w", err)
+	}
+
+	s := Store{
+		httpCli:     httpCli,
+		dir:         dir,
+		maxSize:     maxSize,
+		models:      make(map[string]Model),
+		downloading: make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not read models: %w", err)
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.models); err != nil {
+			return nil, fmt.Errorf("could not unmarshal models: %w", err)
+		}
+	}
+	return &s, nil
+}
+
+// List returns the installed models sorted by name, with their disk usage.
+func (s *Store) List() (Usage, error) {
+	s.mu.Lock()
+	models := make([]Model, 0, len(s.models))
+	for _, m := range s.models {
+		models = append(models, m)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].Name < models[j].Name
+	})
+
+	usage := Usage{Models: models}
+
+	for i := range models {
+		m, err := s.describe(models[i])
+		if err != nil {
+			return Usage{}, err
+		}
+
+		models[i] = m
+		usage.Size += m.Size
+	}
+	return usage, nil
+}
+
+// Get returns the installed model with the given name.
+func (s *Store) Get(name string) (Model, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.models[name]
+	if !ok {
+		return Model{}, ErrNotFound
+	}
+	return s.describe(m)
+}
+
+// Download installs the model from its URL, verifying its checksum. Vosk archives are extracted.
+// A model already installed from a file with the same checksum isn't downloaded again,
+// while a pinned one can't be replaced.
+func (s *Store) Download(ctx context.Context, m Model) (Model, error) {
+	if err := m.Validate(); err != nil {
+		return Model{}, err
+	}
+	m.SHA256 = strings.ToLower(m.SHA256)
+
+	s.mu.Lock()
+	installed, ok := s.models[m.Name]
+
+	switch {
+	case s.downloading[m.Name]:
+		s.mu.Unlock()
+		return Model{}, ErrDownloading
+	case ok && installed.SHA256 == m.SHA256 && installed.Backend == m.Backend:
+		s.mu.Unlock()
+		return s.Get(m.Name)
+	case ok && installed.Pinned:
+		s.mu.Unlock()
+		return Model{}, fmt.Errorf("%w: unpin %s to replace it", ErrPinned, m.Name)
+	}
+
+	s.downloading[m.Name] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.downloading, m.Name)
+		s.mu.Unlock()
+	}()
+
+	// Installed next to the final directory and renamed, so that a partial model is never used.
+	staging := filepath.Join(s.dir, "."+m.Name+".download")
+	os.RemoveAll(staging)
+
+	if err := s.fetch(ctx, m, staging); err != nil {
+		os.RemoveAll(staging)
+		return Model{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	modelDir := filepath.Join(s.dir, m.Name)
+
+	if err := os.RemoveAll(modelDir); err != nil {
+		os.RemoveAll(staging)
+		return Model{}, fmt.Errorf("could not remove the previous model: %w", err)
+	}
+
+	if err := os.Rename(staging, modelDir); err != nil {
+		os.RemoveAll(staging)
+		return Model{}, fmt.Errorf("could not install model: %w", err)
+	}
+
+	m.Pinned, m.InstalledAt, m.Path, m.Size = false, time.Now().UTC(), "", 0
+
+	previous, existed := s.models[m.Name]
+	s.models[m.Name] = m
+
+	if err := s.save(); err != nil {
+		if existed {
+			s.models[m.Name] = previous
+		} else {
+			delete(s.models, m.Name)
+		}
+		return Model{}, err
+	}
+	return s.describe(m)
+}
+
+// Pin pins or unpins the installed model.
+func (s *Store) Pin(name string, pinned bool) (Model, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.models[name]
+	if !ok {
+		return Model{}, ErrNotFound
+	}
+
+	previous := m
+	m.Pinned = pinned
+	s.models[name] = m
+
+	if err := s.save(); err != nil {
+		s.models[name] = previous
+		return Model{}, err
+	}
+	return s.describe(m)
+}
+
+// Remove deletes the files of the model, unless pinned.
+func (s *Store) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.models[name]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if m.Pinned {
+		return fmt.Errorf("%w: unpin %s to remove it", ErrPinned, name)
+	}
+
+	if s.downloading[name] {
+		return ErrDownloading
+	}
+
+	delete(s.models, name)
+
+	if err := s.save(); err != nil {
+		s.models[name] = m
+		return err
+	}
+
+	if err := os.RemoveAll(filepath.Join(s.dir, name)); err != nil {
+		return fmt.Errorf("could not remove model files: %w", err)
+	}
+	return nil
+}
+
+// fetch downloads the file of the model into the directory, hashing it on the way, and extracts Vosk archives.
+func (s *Store) fetch(ctx context.Context, m Model, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("could not create directory: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.URL, nil)
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+
+	resp, err := s.httpCli.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not download model: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not download model: unexpected status %d", resp.StatusCode)
+	}
+
+	fileName := path.Base(resp.Request.URL.Path)
+	if fileName == "/" || strings.HasPrefix(fileName, ".") {
+		fileName = m.Name
+	}
+
+	filePath := filepath.Join(dir, fileName)
+
+	f, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("could not create model file: %w", err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+
+	n, err := io.Copy(io.MultiWriter(f, hash), io.LimitReader(resp.Body, s.maxSize+1))
+	if err != nil {
+		return fmt.Errorf("could not download model: %w", err)
+	}
+
+	if n > s.maxSize {
+		return fmt.Errorf("model larger than %d bytes", s.maxSize)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("could not write model file: %w", err)
+	}
+
+	if got := hex.EncodeToString(hash.Sum(nil)); got != m.SHA256 {
+		return fmt.Errorf("%w: %s downloaded from %s, %s expected", ErrChecksumMismatch, got, m.URL, m.SHA256)
+	}
+
+	if m.Backend != BackendVosk {
+		return nil
+	}
+
+	if err := extractZip(filePath, dir); err != nil {
+		return fmt.Errorf("could not extract model: %w", err)
+	}
+	return os.Remove(filePath)
+}
+
+// describe sets the directory of the model and its disk usage.
+func (s *Store) describe(m Model) (Model, error) {
+	m.Path = filepath.Join(s.dir, m.Name)
+
+	size, err := diskUsage(m.Path)
+	if err != nil {
+		return Model{}, fmt.Errorf("could not measure %s: %w", m.Name, err)
+	}
+
+	m.Size = size
+	return m, nil
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.models, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal models: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(s.dir, manifestName), data, 0o644); err != nil {
+		return fmt.Errorf("could not write models: %w", err)
+	}
+	return nil
+}
+
+// extractZip extracts the archive into the directory, rejecting entries outside of it.
+func extractZip(archivePath, dir string) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("could not open zip: %w", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		target := filepath.Join(dir, filepath.FromSlash(f.Name))
+
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path %q in archive", f.Name)
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("could not create directory: %w", err)
+			}
+			continue
+		}
+
+		if err := extractFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("could not create directory: %w", err)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", f.Name, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, rc); err != nil {
+		return fmt.Errorf("could not extract %s: %w", f.Name, err)
+	}
+	return out.Close()
+}
+
+// diskUsage returns the size in bytes of the files in the directory. A missing directory uses none.
+func diskUsage(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
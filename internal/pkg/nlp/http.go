@@ -0,0 +1,65 @@
+package nlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTP analyzes text with an external NLP service. The service receives {"text": "..."}
+// and responds with an Analysis: {"sentiment": 0.4, "topics": ["pricing"]}.
+type HTTP struct {
+	httpCli *http.Client
+	url     string
+	token   string
+}
+
+// NewHTTP returns a new analyzer calling the service at url.
+// The token is sent as a bearer token when not empty.
+func NewHTTP(httpCli *http.Client, url, token string) *HTTP {
+	return &HTTP{httpCli: httpCli, url: url, token: token}
+}
+
+type analyzeRequest struct {
+	Text string `json:"text"`
+}
+
+// Analyze sends the text to the service.
+func (h *HTTP) Analyze(ctx context.Context, text string) (Analysis, error) {
+	body, err := json.Marshal(analyzeRequest{Text: text})
+	if err != nil {
+		return Analysis{}, fmt.Errorf("could not marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return Analysis{}, fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+
+	resp, err := h.httpCli.Do(req)
+	if err != nil {
+		return Analysis{}, fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Analysis{}, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, msg)
+	}
+
+	var a Analysis
+	if err := json.NewDecoder(resp.Body).Decode(&a); err != nil {
+		return Analysis{}, fmt.Errorf("could not decode response: %w", err)
+	}
+
+	a.Sentiment = max(-1, min(1, a.Sentiment))
+	return a, nil
+}
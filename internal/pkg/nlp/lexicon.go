@@ -0,0 +1,104 @@
+package nlp
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alesr/videoscriber/internal/pkg/langid"
+)
+
+const (
+	// maxTopics is the number of topics the lexicon returns for a text.
+	maxTopics int = 3
+
+	// minTopicLength excludes short words, which are rarely topics.
+	minTopicLength int = 4
+)
+
+// polarity scores words of the transcription languages, from -1 to 1.
+var polarity = map[string]float64{
+	// English.
+	"good": 1, "great": 1, "excellent": 1, "amazing": 1, "love": 1, "happy": 1, "best": 1,
+	"nice": 0.5, "like": 0.5, "thanks": 0.5, "glad": 1, "awesome": 1, "success": 1, "win": 1,
+	"bad": -1, "terrible": -1, "awful": -1, "hate": -1, "sad": -1, "worst": -1, "problem": -0.5,
+	"wrong": -0.5, "fail": -1, "failure": -1, "angry": -1, "sorry": -0.5, "difficult": -0.5, "lose": -1,
+
+	// Portuguese.
+	"bom": 1, "boa": 1, "ótimo": 1, "ótima": 1, "excelente": 1, "incrível": 1, "amo": 1, "feliz": 1,
+	"melhor": 1, "legal": 0.5, "obrigado": 0.5, "obrigada": 0.5, "sucesso": 1, "adoro": 1,
+	"ruim": -1, "péssimo": -1, "péssima": -1, "odeio": -1, "triste": -1, "pior": -1, "problema": -0.5,
+	"errado": -0.5, "erro": -0.5, "falha": -1, "fracasso": -1, "difícil": -0.5, "desculpa": -0.5,
+}
+
+// negations invert the polarity of the word that follows them.
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "don't": true, "isn't": true, "wasn't": true,
+	"não": true, "nunca": true, "nem": true,
+}
+
+// Lexicon analyzes text locally: the sentiment from a word polarity lexicon,
+// and the topics from the most frequent words that aren't stopwords.
+type Lexicon struct{}
+
+// NewLexicon returns a new lexicon analyzer.
+func NewLexicon() *Lexicon {
+	return &Lexicon{}
+}
+
+// Analyze analyzes the sentiment and topics of the text.
+func (l *Lexicon) Analyze(_ context.Context, text string) (Analysis, error) {
+	var (
+		score  float64
+		scored int
+		negate bool
+		counts = make(map[string]int)
+	)
+
+	for _, word := range words(text) {
+		if p, ok := polarity[word]; ok {
+			if negate {
+				p = -p
+			}
+			score += p
+			scored++
+		} else if !negations[word] && !langid.IsStopword(word) && utf8.RuneCountInString(word) >= minTopicLength {
+			counts[word]++
+		}
+		negate = negations[word]
+	}
+
+	a := Analysis{Topics: topics(counts)}
+	if scored > 0 {
+		a.Sentiment = score / float64(scored)
+	}
+	return a, nil
+}
+
+// topics returns the most frequent words, the most frequent first and ties in alphabetical order.
+func topics(counts map[string]int) []string {
+	words := make([]string, 0, len(counts))
+	for w := range counts {
+		words = append(words, w)
+	}
+
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+
+	if len(words) > maxTopics {
+		words = words[:maxTopics]
+	}
+	return words
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+}
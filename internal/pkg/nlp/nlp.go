@@ -0,0 +1,116 @@
+package nlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+// NLP providers.
+const (
+	ProviderLexicon string = "lexicon"
+	ProviderHTTP    string = "http"
+)
+
+// ErrUnknownProvider is returned for unsupported NLP providers.
+var ErrUnknownProvider = errors.New("unknown NLP provider")
+
+// Sentiment labels.
+const (
+	LabelPositive string = "positive"
+	LabelNeutral  string = "neutral"
+	LabelNegative string = "negative"
+)
+
+// neutralBand is the sentiment score, in absolute value, below which text is considered neutral.
+const neutralBand float64 = 0.1
+
+// Analysis is the sentiment and the topics of a text.
+type Analysis struct {
+	Sentiment float64  `json:"sentiment"` // Between -1 (negative) and 1 (positive).
+	Topics    []string `json:"topics"`
+}
+
+// Analyzer analyzes the sentiment and topics of text.
+type Analyzer interface {
+	Analyze(ctx context.Context, text string) (Analysis, error)
+}
+
+// Point is the analysis of a window of a transcript.
+type Point struct {
+	Start     float64  `json:"start"` // Seconds.
+	End       float64  `json:"end"`   // Seconds.
+	Sentiment float64  `json:"sentiment"`
+	Label     string   `json:"label"`
+	Topics    []string `json:"topics"`
+}
+
+// Timeline analyzes the cues in consecutive windows of the given length, e.g. one per minute.
+// Cues belong to the window they start in. Windows without speech are skipped.
+func Timeline(ctx context.Context, analyzer Analyzer, cues []srt.Cue, window time.Duration) ([]Point, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("invalid timeline window %s", window)
+	}
+
+	points := []Point{}
+
+	for i := 0; i < len(cues); {
+		start := cues[i].Start.Truncate(window)
+		end := start + window
+
+		var text []string
+		for ; i < len(cues) && cues[i].Start < end; i++ {
+			text = append(text, strings.TrimSpace(cues[i].Text))
+		}
+
+		analysis, err := analyzer.Analyze(ctx, strings.Join(text, "\n"))
+		if err != nil {
+			return nil, fmt.Errorf("could not analyze %s to %s: %w", start, end, err)
+		}
+
+		if analysis.Topics == nil {
+			analysis.Topics = []string{}
+		}
+
+		points = append(points, Point{
+			Start:     start.Seconds(),
+			End:       end.Seconds(),
+			Sentiment: analysis.Sentiment,
+			Label:     Label(analysis.Sentiment),
+			Topics:    analysis.Topics,
+		})
+	}
+	return points, nil
+}
+
+// Label returns the label of a sentiment score.
+func Label(sentiment float64) string {
+	switch {
+	case sentiment >= neutralBand:
+		return LabelPositive
+	case sentiment <= -neutralBand:
+		return LabelNegative
+	default:
+		return LabelNeutral
+	}
+}
+
+// New returns the analyzer of the provider. The HTTP provider requires the URL of the service.
+func New(provider string, httpCli *http.Client, url, token string) (Analyzer, error) {
+	switch provider {
+	case ProviderLexicon:
+		return NewLexicon(), nil
+	case ProviderHTTP:
+		if url == "" {
+			return nil, fmt.Errorf("the %s NLP provider requires a URL", provider)
+		}
+		return NewHTTP(httpCli, url, token), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, provider)
+	}
+}
@@ -0,0 +1,287 @@
+// Package plans binds tenants, the users of the API keys, to named rate plans, e.g. free, pro or enterprise,
+// so that one deployment can serve teams with different limits.
+package plans
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/alesr/videoscriber/internal/pkg/features"
+)
+
+var (
+	// ErrNotFound is returned when the plan does not exist.
+	ErrNotFound = errors.New("plan not found")
+
+	// ErrInvalidPlan is returned when a plan fails validation.
+	ErrInvalidPlan = errors.New("invalid plan")
+
+	// ErrInUse is returned when deleting a plan tenants are bound to.
+	ErrInUse = errors.New("plan in use")
+
+	nameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+// Plan is the limits of the tenants bound to it. Zero limits are unlimited.
+type Plan struct {
+	Name              string `json:"name"`
+	MonthlyMinutes    int    `json:"monthly_minutes"`
+	RequestsPerMinute int    `json:"requests_per_minute"`
+	MaxJobs           int    `json:"max_jobs"` // Jobs running at the same time.
+
+	// Priority is the highest priority of the files of the tenants, see CapPriority.
+	Priority string `json:"priority,omitempty"`
+
+	// Features are the experimental subsystems the tenants can use, when enabled in the deployment.
+	// All of them when nil.
+	Features []features.Flag `json:"features,omitempty"`
+}
+
+// Validate checks the plan fields.
+func (p Plan) Validate() error {
+	switch {
+	case !nameRe.MatchString(p.Name):
+		return fmt.Errorf("%w: name %q", ErrInvalidPlan, p.Name)
+	case p.MonthlyMinutes < 0 || p.RequestsPerMinute < 0 || p.MaxJobs < 0:
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalidPlan)
+	}
+
+	for _, f := range p.Features {
+		if !features.Known(f) {
+			return fmt.Errorf("%w: unknown feature %q", ErrInvalidPlan, f)
+		}
+	}
+	return nil
+}
+
+// priorities are the priorities the plans rank, lowest first.
+var priorities = []string{"low", "normal", "high"}
+
+// CapPriority returns the priority of a file of the tenants requested with the given one.
+// Requests can lower the priority of the plan, not raise it. Without a requested priority,
+// or when either isn't ranked, e.g. a priority of the routing policy, the plan's one applies.
+func (p Plan) CapPriority(requested string) string {
+	if p.Priority == "" {
+		return requested
+	}
+
+	limit, rank := slices.Index(priorities, p.Priority), slices.Index(priorities, requested)
+	if limit < 0 || rank < 0 || rank > limit {
+		return p.Priority
+	}
+	return requested
+}
+
+// Allows reports whether the tenants of the plan can use the subsystem of the flag.
+func (p Plan) Allows(f features.Flag) bool {
+	return p.Features == nil || slices.Contains(p.Features, f)
+}
+
+// state is the content of the file of the store.
+type state struct {
+	Plans   map[string]Plan   `json:"plans"`
+	Tenants map[string]string `json:"tenants"` // Names of the plans of the tenants.
+}
+
+// Store persists the plans and the tenants bound to them as JSON.
+// Tenants without a plan have the limits configured for the server.
+type Store struct {
+	mu    sync.RWMutex
+	path  string
+	state state
+}
+
+// NewStore returns a store backed by the file at path.
+func NewStore(path string) (*Store, error) {
+	s := Store{
+		path:  path,
+		state: state{Plans: make(map[string]Plan), Tenants: make(map[string]string)},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not read plans: %w", err)
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.state); err != nil {
+			return nil, fmt.Errorf("could not unmarshal plans: %w", err)
+		}
+	}
+	return &s, nil
+}
+
+// List returns the plans sorted by name.
+func (s *Store) List() []Plan {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	plans := make([]Plan, 0, len(s.state.Plans))
+	for _, p := range s.state.Plans {
+		plans = append(plans, p)
+	}
+
+	sort.Slice(plans, func(i, j int) bool {
+		return plans[i].Name < plans[j].Name
+	})
+	return plans
+}
+
+// Get returns the plan with the given name.
+func (s *Store) Get(name string) (Plan, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.state.Plans[name]
+	if !ok {
+		return Plan{}, ErrNotFound
+	}
+	return p, nil
+}
+
+// Put creates or replaces a plan. The tenants bound to it get its new limits.
+func (s *Store) Put(p Plan) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.state.Plans[p.Name]
+	s.state.Plans[p.Name] = p
+
+	if err := s.save(); err != nil {
+		if existed {
+			s.state.Plans[p.Name] = previous
+		} else {
+			delete(s.state.Plans, p.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a plan no tenant is bound to.
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.state.Plans[name]
+	if !ok {
+		return ErrNotFound
+	}
+
+	for tenant, plan := range s.state.Tenants {
+		if plan == name {
+			return fmt.Errorf("%w: tenant %q is bound to it", ErrInUse, tenant)
+		}
+	}
+
+	delete(s.state.Plans, name)
+
+	if err := s.save(); err != nil {
+		s.state.Plans[name] = previous
+		return err
+	}
+	return nil
+}
+
+// Tenants returns the names of the plans of the tenants bound to one.
+func (s *Store) Tenants() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenants := make(map[string]string, len(s.state.Tenants))
+	for tenant, plan := range s.state.Tenants {
+		tenants[tenant] = plan
+	}
+	return tenants
+}
+
+// Assign binds the tenant to the plan, replacing its previous one.
+func (s *Store) Assign(tenant, name string) (Plan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.state.Plans[name]
+	if !ok {
+		return Plan{}, ErrNotFound
+	}
+
+	previous, existed := s.state.Tenants[tenant]
+	s.state.Tenants[tenant] = name
+
+	if err := s.save(); err != nil {
+		if existed {
+			s.state.Tenants[tenant] = previous
+		} else {
+			delete(s.state.Tenants, tenant)
+		}
+		return Plan{}, err
+	}
+	return p, nil
+}
+
+// Unassign unbinds the tenant from its plan, after which it has the limits configured for the server.
+func (s *Store) Unassign(tenant string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.state.Tenants[tenant]
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(s.state.Tenants, tenant)
+
+	if err := s.save(); err != nil {
+		s.state.Tenants[tenant] = previous
+		return err
+	}
+	return nil
+}
+
+// PlanOf returns the plan of the tenant, if bound to one.
+func (s *Store) PlanOf(tenant string) (Plan, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	name, ok := s.state.Tenants[tenant]
+	if !ok {
+		return Plan{}, false
+	}
+
+	p, ok := s.state.Plans[name]
+	return p, ok
+}
+
+// RequestsPerMinute returns the rate limit of the tenant, if bound to a plan.
+func (s *Store) RequestsPerMinute(tenant string) (int, bool) {
+	p, ok := s.PlanOf(tenant)
+	return p.RequestsPerMinute, ok
+}
+
+// MonthlyMinutes returns the monthly transcription quota of the tenant, if bound to a plan.
+func (s *Store) MonthlyMinutes(tenant string) (int, bool) {
+	p, ok := s.PlanOf(tenant)
+	return p.MonthlyMinutes, ok
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal plans: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, data, 0o644); err != nil {
+		return fmt.Errorf("could not write plans: %w", err)
+	}
+	return nil
+}
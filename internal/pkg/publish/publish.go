@@ -0,0 +1,148 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Supported publishing platforms.
+const (
+	PlatformYouTube string = "youtube"
+	PlatformVimeo   string = "vimeo"
+	PlatformWistia  string = "wistia"
+	PlatformWebhook string = "webhook"
+)
+
+// ErrNoCredentials is returned when the project has no credentials for the platform.
+var ErrNoCredentials = errors.New("no credentials configured for project")
+
+// ErrInvalidCredentials is returned when credentials miss fields required by the platform.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Publisher publishes subtitles as caption tracks of videos hosted on a platform.
+type Publisher interface {
+	// SetCredentials stores the credentials used to publish on behalf of the project.
+	SetCredentials(project string, c Credentials) error
+
+	// Publish uploads the caption and returns a reference to the track on the platform.
+	Publish(ctx context.Context, project string, c Caption) (string, error)
+}
+
+// Credentials authorize and configure publishing to a platform on behalf of a project.
+// Each platform uses a subset of the fields.
+type Credentials struct {
+	// OAuth client and refresh token (YouTube).
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// Token is a personal access token (Vimeo), an API token (Wistia) or a bearer token (webhook).
+	Token string `json:"token,omitempty"`
+
+	// Endpoint, payload template and extra request headers (webhook).
+	URL      string            `json:"url,omitempty"`
+	Template string            `json:"template,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+}
+
+// Caption describes the caption track to create.
+type Caption struct {
+	VideoID  string // Optional for webhooks.
+	Language string // ISO-639-1 code.
+	Name     string
+	Data     []byte // SRT or WebVTT.
+}
+
+// credentialStore keeps the credentials of a platform per project, persisted as JSON.
+type credentialStore struct {
+	path string
+
+	mu        sync.RWMutex
+	byProject map[string]Credentials
+}
+
+func newCredentialStore(path string) (*credentialStore, error) {
+	s := credentialStore{
+		path:      path,
+		byProject: make(map[string]Credentials),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &s, nil
+		}
+		return nil, fmt.Errorf("could not read credentials file: %w", err)
+	}
+
+	if len(data) == 0 {
+		return &s, nil
+	}
+
+	if err := json.Unmarshal(data, &s.byProject); err != nil {
+		return nil, fmt.Errorf("could not unmarshal credentials: %w", err)
+	}
+	return &s, nil
+}
+
+func (s *credentialStore) get(project string) (Credentials, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.byProject[project]
+	if !ok {
+		return Credentials{}, ErrNoCredentials
+	}
+	return c, nil
+}
+
+func (s *credentialStore) set(project string, c Credentials) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.byProject[project] = c
+
+	data, err := json.MarshalIndent(s.byProject, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal credentials: %w", err)
+	}
+
+	// The file is readable by the owner only, since it holds secrets.
+	if err := os.WriteFile(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("could not write credentials file: %w", err)
+	}
+	return nil
+}
+
+// doJSON sends the request and decodes the JSON response into out, if not nil.
+func doJSON(httpCli *http.Client, req *http.Request, out any) error {
+	resp, err := httpCli.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("could not read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, data)
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("could not unmarshal response: %w", err)
+	}
+	return nil
+}
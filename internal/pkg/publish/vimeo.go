@@ -0,0 +1,115 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+const (
+	vimeoAPIURL string = "https://api.vimeo.com"
+	vimeoAccept string = "application/vnd.vimeo.*+json;version=3.4"
+)
+
+// Vimeo uploads subtitles as text tracks of Vimeo videos.
+// Projects authorize it with a personal access token granted the upload and edit scopes.
+type Vimeo struct {
+	httpCli     *http.Client
+	credentials *credentialStore
+}
+
+// NewVimeo returns a new Vimeo publisher. Credentials are persisted as JSON in the file at path.
+func NewVimeo(httpCli *http.Client, path string) (*Vimeo, error) {
+	credentials, err := newCredentialStore(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load vimeo credentials: %w", err)
+	}
+	return &Vimeo{httpCli: httpCli, credentials: credentials}, nil
+}
+
+// SetCredentials stores the credentials of the project.
+func (v *Vimeo) SetCredentials(project string, c Credentials) error {
+	if c.Token == "" {
+		return fmt.Errorf("%w: token is required", ErrInvalidCredentials)
+	}
+	return v.credentials.set(project, c)
+}
+
+// Publish creates a text track on the video, uploads the caption as WebVTT
+// and activates the track. It returns the URI of the text track.
+func (v *Vimeo) Publish(ctx context.Context, project string, c Caption) (string, error) {
+	creds, err := v.credentials.get(project)
+	if err != nil {
+		return "", err
+	}
+
+	vtt, err := toVTT(c.Data)
+	if err != nil {
+		return "", err
+	}
+
+	var track struct {
+		URI  string `json:"uri"`
+		Link string `json:"link"`
+	}
+
+	if err := v.send(ctx, creds, http.MethodPost, vimeoAPIURL+"/videos/"+url.PathEscape(c.VideoID)+"/texttracks", map[string]any{
+		"type":     "subtitles",
+		"language": c.Language,
+		"name":     c.Name,
+	}, &track); err != nil {
+		return "", fmt.Errorf("could not create text track: %w", err)
+	}
+
+	// The upload link is pre-signed, so the track file is sent without authorization.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, track.Link, bytes.NewReader(vtt))
+	if err != nil {
+		return "", fmt.Errorf("could not create upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/vtt")
+
+	if err := doJSON(v.httpCli, req, nil); err != nil {
+		return "", fmt.Errorf("could not upload text track: %w", err)
+	}
+
+	if err := v.send(ctx, creds, http.MethodPatch, vimeoAPIURL+track.URI, map[string]any{"active": true}, nil); err != nil {
+		return "", fmt.Errorf("could not activate text track: %w", err)
+	}
+	return track.URI, nil
+}
+
+func (v *Vimeo) send(ctx context.Context, creds Credentials, method, endpoint string, payload, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "bearer "+creds.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", vimeoAccept)
+
+	return doJSON(v.httpCli, req, out)
+}
+
+// toVTT converts SRT data to WebVTT, which is the only format Vimeo accepts.
+func toVTT(data []byte) ([]byte, error) {
+	if srt.IsVTT(data) {
+		return data, nil
+	}
+
+	cues, err := srt.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse subtitle: %w", err)
+	}
+	return srt.FormatVTT(cues), nil
+}
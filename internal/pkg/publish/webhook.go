@@ -0,0 +1,163 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+// maxWebhookResponse bounds how much of the endpoint's response is read.
+const maxWebhookResponse int64 = 64 << 10
+
+// templateFuncs are available to webhook payload templates.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// Payload is the data available to webhook payload templates.
+type Payload struct {
+	Project    string
+	Name       string
+	VideoID    string // Optional identifier of the target entry in the receiving system.
+	Language   string
+	Subtitle   string // The subtitle as stored (SRT or WebVTT).
+	Transcript string // The cue texts, one per line.
+	Cues       []srt.JSONCue
+}
+
+// Webhook POSTs subtitles to a project's endpoint, e.g. a CMS, with a payload
+// built from a Go template (text/template) over Payload. The template's "json"
+// function encodes a value as JSON, e.g. {"body": {{json .Transcript}}}.
+type Webhook struct {
+	httpCli     *http.Client
+	credentials *credentialStore
+}
+
+// NewWebhook returns a new webhook publisher. Endpoints are persisted as JSON in the file at path.
+func NewWebhook(httpCli *http.Client, path string) (*Webhook, error) {
+	credentials, err := newCredentialStore(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load webhook credentials: %w", err)
+	}
+	return &Webhook{httpCli: httpCli, credentials: credentials}, nil
+}
+
+// SetCredentials stores the endpoint of the project. The template and token are optional:
+// by default the payload is the JSON encoding of Payload, and the token is sent as a bearer token.
+func (wh *Webhook) SetCredentials(project string, c Credentials) error {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidCredentials)
+	}
+
+	if _, err := template.New("payload").Funcs(templateFuncs).Parse(c.Template); err != nil {
+		return fmt.Errorf("%w: could not parse template: %s", ErrInvalidCredentials, err)
+	}
+	return wh.credentials.set(project, c)
+}
+
+// Publish posts the caption to the project's endpoint. It returns the Location header
+// of the response when set, and the response status otherwise.
+func (wh *Webhook) Publish(ctx context.Context, project string, c Caption) (string, error) {
+	creds, err := wh.credentials.get(project)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := payload(creds.Template, project, c)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, creds.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("could not create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if creds.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+creds.Token)
+	}
+
+	for k, v := range creds.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := wh.httpCli.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponse))
+		return "", fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, data)
+	}
+
+	if location := resp.Header.Get("Location"); location != "" {
+		return location, nil
+	}
+	return resp.Status, nil
+}
+
+// payload renders the template for the caption. An empty template renders Payload as JSON.
+func payload(tmpl, project string, c Caption) ([]byte, error) {
+	data := c.Data
+	if srt.IsVTT(data) {
+		cues, err := srt.ParseVTT(data)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse subtitle: %w", err)
+		}
+		data = srt.Format(cues)
+	}
+
+	cues, err := srt.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse subtitle: %w", err)
+	}
+
+	p := Payload{
+		Project:    project,
+		Name:       c.Name,
+		VideoID:    c.VideoID,
+		Language:   c.Language,
+		Subtitle:   string(c.Data),
+		Transcript: strings.TrimSpace(string(srt.FormatText(cues))),
+		Cues:       make([]srt.JSONCue, 0, len(cues)),
+	}
+
+	for _, cue := range cues {
+		p.Cues = append(p.Cues, cue.ToJSON())
+	}
+
+	if tmpl == "" {
+		body, err := json.Marshal(p)
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal payload: %w", err)
+		}
+		return body, nil
+	}
+
+	t, err := template.New("payload").Funcs(templateFuncs).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, p); err != nil {
+		return nil, fmt.Errorf("could not render template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
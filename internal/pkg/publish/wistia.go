@@ -0,0 +1,122 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+const wistiaAPIURL string = "https://api.wistia.com/v1"
+
+// wistiaLanguages maps the ISO-639-1 codes supported by Whisper
+// to the ISO-639-2 codes expected by Wistia.
+var wistiaLanguages = map[string]string{
+	"af": "afr", "ar": "ara", "hy": "arm", "az": "aze", "be": "bel",
+	"bs": "bos", "bg": "bul", "ca": "cat", "zh": "chi", "hr": "hrv",
+	"cs": "cze", "da": "dan", "nl": "dut", "en": "eng", "et": "est",
+	"fi": "fin", "fr": "fre", "gl": "glg", "de": "ger", "el": "gre",
+	"he": "heb", "hi": "hin", "hu": "hun", "is": "ice", "id": "ind",
+	"it": "ita", "ja": "jpn", "kn": "kan", "kk": "kaz", "ko": "kor",
+	"lv": "lav", "lt": "lit", "mk": "mac", "ms": "may", "mr": "mar",
+	"mi": "mao", "ne": "nep", "no": "nor", "fa": "per", "pl": "pol",
+	"pt": "por", "ro": "rum", "ru": "rus", "sr": "srp", "sk": "slo",
+	"sl": "slv", "es": "spa", "sw": "swa", "sv": "swe", "tl": "tgl",
+	"ta": "tam", "th": "tha", "tr": "tur", "uk": "ukr", "ur": "urd",
+	"vi": "vie", "cy": "wel",
+}
+
+// Wistia uploads subtitles as captions of Wistia medias.
+// Projects authorize it with an API token granted read and update permissions.
+type Wistia struct {
+	httpCli     *http.Client
+	credentials *credentialStore
+}
+
+// NewWistia returns a new Wistia publisher. Credentials are persisted as JSON in the file at path.
+func NewWistia(httpCli *http.Client, path string) (*Wistia, error) {
+	credentials, err := newCredentialStore(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load wistia credentials: %w", err)
+	}
+	return &Wistia{httpCli: httpCli, credentials: credentials}, nil
+}
+
+// SetCredentials stores the credentials of the project.
+func (w *Wistia) SetCredentials(project string, c Credentials) error {
+	if c.Token == "" {
+		return fmt.Errorf("%w: token is required", ErrInvalidCredentials)
+	}
+	return w.credentials.set(project, c)
+}
+
+// Publish uploads the caption as SRT to the media identified by the video ID (the media hashed ID).
+// Wistia keeps one caption per language, so the returned reference is the media and language.
+func (w *Wistia) Publish(ctx context.Context, project string, c Caption) (string, error) {
+	creds, err := w.credentials.get(project)
+	if err != nil {
+		return "", err
+	}
+
+	language, ok := wistiaLanguages[c.Language]
+	if !ok {
+		return "", fmt.Errorf("language %q is not supported by wistia", c.Language)
+	}
+
+	data, err := toSRT(c.Data)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if err := writer.WriteField("language", language); err != nil {
+		return "", fmt.Errorf("could not write language field: %w", err)
+	}
+
+	part, err := writer.CreateFormFile("caption_file", c.Name+".srt")
+	if err != nil {
+		return "", fmt.Errorf("could not create caption file part: %w", err)
+	}
+
+	if _, err := part.Write(data); err != nil {
+		return "", fmt.Errorf("could not write caption file: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("could not close writer: %w", err)
+	}
+
+	endpoint := wistiaAPIURL + "/medias/" + url.PathEscape(c.VideoID) + "/captions.json"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return "", fmt.Errorf("could not create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+creds.Token)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	if err := doJSON(w.httpCli, req, nil); err != nil {
+		return "", fmt.Errorf("could not upload caption: %w", err)
+	}
+	return c.VideoID + "/" + language, nil
+}
+
+// toSRT converts WebVTT data to SRT, which is the format Wistia accepts.
+func toSRT(data []byte) ([]byte, error) {
+	if !srt.IsVTT(data) {
+		return data, nil
+	}
+
+	cues, err := srt.ParseVTT(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse subtitle: %w", err)
+	}
+	return srt.Format(cues), nil
+}
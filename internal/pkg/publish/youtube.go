@@ -0,0 +1,146 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+const (
+	youtubeTokenURL   string = "https://oauth2.googleapis.com/token"
+	youtubeCaptionURL string = "https://www.googleapis.com/upload/youtube/v3/captions?part=snippet&uploadType=multipart"
+)
+
+// YouTube uploads subtitles as caption tracks of YouTube videos.
+// Projects authorize it with an OAuth client and a refresh token
+// granted the youtube.force-ssl scope for their channel.
+type YouTube struct {
+	httpCli     *http.Client
+	credentials *credentialStore
+}
+
+// NewYouTube returns a new YouTube publisher. Credentials are persisted as JSON in the file at path.
+func NewYouTube(httpCli *http.Client, path string) (*YouTube, error) {
+	credentials, err := newCredentialStore(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load youtube credentials: %w", err)
+	}
+	return &YouTube{httpCli: httpCli, credentials: credentials}, nil
+}
+
+// SetCredentials stores the credentials of the project.
+func (y *YouTube) SetCredentials(project string, c Credentials) error {
+	if c.ClientID == "" || c.ClientSecret == "" || c.RefreshToken == "" {
+		return fmt.Errorf("%w: client_id, client_secret and refresh_token are required", ErrInvalidCredentials)
+	}
+	return y.credentials.set(project, c)
+}
+
+// Publish uploads the caption to the video using the credentials of the project
+// and returns the ID of the created caption track.
+func (y *YouTube) Publish(ctx context.Context, project string, c Caption) (string, error) {
+	creds, err := y.credentials.get(project)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := y.accessToken(ctx, creds)
+	if err != nil {
+		return "", err
+	}
+
+	body, contentType, err := captionBody(c)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, youtubeCaptionURL, body)
+	if err != nil {
+		return "", fmt.Errorf("could not create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", contentType)
+
+	var created struct {
+		ID string `json:"id"`
+	}
+
+	if err := doJSON(y.httpCli, req, &created); err != nil {
+		return "", fmt.Errorf("could not upload caption: %w", err)
+	}
+	return created.ID, nil
+}
+
+func (y *YouTube) accessToken(ctx context.Context, c Credentials) (string, error) {
+	form := url.Values{
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+		"refresh_token": {c.RefreshToken},
+		"grant_type":    {"refresh_token"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, youtubeTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("could not create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := doJSON(y.httpCli, req, &token); err != nil {
+		return "", fmt.Errorf("could not refresh access token: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
+// captionBody builds the multipart/related body holding the caption metadata and file.
+func captionBody(c Caption) (io.Reader, string, error) {
+	metadata, err := json.Marshal(map[string]any{
+		"snippet": map[string]any{
+			"videoId":  c.VideoID,
+			"language": c.Language,
+			"name":     c.Name,
+			"isDraft":  false,
+		},
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("could not marshal caption metadata: %w", err)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	parts := []struct {
+		contentType string
+		data        []byte
+	}{
+		{"application/json; charset=UTF-8", metadata},
+		{"application/octet-stream", c.Data},
+	}
+
+	for _, p := range parts {
+		part, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {p.contentType}})
+		if err != nil {
+			return nil, "", fmt.Errorf("could not create part: %w", err)
+		}
+
+		if _, err := part.Write(p.data); err != nil {
+			return nil, "", fmt.Errorf("could not write part: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("could not close writer: %w", err)
+	}
+	return &body, "multipart/related; boundary=" + writer.Boundary(), nil
+}
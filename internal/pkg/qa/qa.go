@@ -0,0 +1,296 @@
+package qa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/llm"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+const (
+	// maxPassageLength and maxPassageText bound the passages the transcripts are split into.
+	maxPassageLength = 45 * time.Second
+	maxPassageText   = 1000
+
+	// maxPassages is the number of passages given to the chat model to answer a question.
+	maxPassages = 8
+
+	// embedBatch is the number of passages embedded per request.
+	embedBatch = 64
+)
+
+// instructions ask the chat model to answer from the passages only, citing them.
+const instructions string = `You answer questions about recordings using only the numbered transcript passages given.
+Cite the passages supporting each statement with their numbers in brackets, e.g. [2] or [1][3].
+When the passages don't contain the answer, say so. Answer in the language of the question.`
+
+// ErrNoTranscripts is returned when asking a question without transcripts to answer from.
+var ErrNoTranscripts = errors.New("no transcripts to answer from")
+
+var citationRe = regexp.MustCompile(`\[(\d+)\]`)
+
+// Document is a stored subtitle to answer from.
+type Document struct {
+	Name string
+	Path string // Identifies the subtitle in the index.
+	Data []byte
+}
+
+// Passage is a part of a transcript and its embedding.
+type Passage struct {
+	Subtitle string
+	Start    time.Duration
+	End      time.Duration
+	Text     string
+	Vector   []float32
+}
+
+// Citation is a passage supporting an answer.
+type Citation struct {
+	Index    int     `json:"index"` // Number of the citation in the answer, e.g. 2 for [2].
+	Subtitle string  `json:"subtitle"`
+	Start    float64 `json:"start"` // Seconds.
+	End      float64 `json:"end"`   // Seconds.
+	Text     string  `json:"text"`
+}
+
+// Answer is the answer to a question with the passages it cites.
+type Answer struct {
+	Text      string     `json:"answer"`
+	Citations []Citation `json:"citations"`
+}
+
+type llmClient interface {
+	Chat(ctx context.Context, model string, messages []llm.Message, jsonObject bool) (string, error)
+	Embed(ctx context.Context, model string, inputs []string) ([][]float32, error)
+}
+
+type passageStore interface {
+	Passages(path, digest, model string) ([]Passage, bool, error)
+	SavePassages(path, digest, model string, passages []Passage) error
+}
+
+// Assistant answers questions grounded in transcripts. The transcripts are split in passages
+// whose embeddings are indexed when first asked about, and again when they change.
+type Assistant struct {
+	llm            llmClient
+	store          passageStore
+	chatModel      string
+	embeddingModel string
+}
+
+// New returns a new assistant answering with the chat model and retrieving passages with the embedding model.
+func New(llm llmClient, store passageStore, chatModel, embeddingModel string) *Assistant {
+	return &Assistant{
+		llm:            llm,
+		store:          store,
+		chatModel:      chatModel,
+		embeddingModel: embeddingModel,
+	}
+}
+
+// Ask answers the question from the passages of the documents most similar to it.
+func (a *Assistant) Ask(ctx context.Context, question string, docs []Document) (Answer, error) {
+	var passages []Passage
+
+	for _, doc := range docs {
+		docPassages, err := a.index(ctx, doc)
+		if err != nil {
+			return Answer{}, fmt.Errorf("could not index %s: %w", doc.Name, err)
+		}
+		passages = append(passages, docPassages...)
+	}
+
+	if len(passages) == 0 {
+		return Answer{}, ErrNoTranscripts
+	}
+
+	vectors, err := a.llm.Embed(ctx, a.embeddingModel, []string{question})
+	if err != nil {
+		return Answer{}, fmt.Errorf("could not embed question: %w", err)
+	}
+
+	scores := make([]float64, len(passages))
+	for i, p := range passages {
+		scores[i] = similarity(vectors[0], p.Vector)
+	}
+
+	sort.Sort(byScore{passages: passages, scores: scores})
+
+	if len(passages) > maxPassages {
+		passages = passages[:maxPassages]
+	}
+
+	var prompt strings.Builder
+	for i, p := range passages {
+		fmt.Fprintf(&prompt, "[%d] (%s, %s-%s) %s\n", i+1, p.Subtitle, timestamp(p.Start), timestamp(p.End), p.Text)
+	}
+	fmt.Fprintf(&prompt, "\nQuestion: %s", question)
+
+	reply, err := a.llm.Chat(ctx, a.chatModel, []llm.Message{
+		{Role: "system", Content: instructions},
+		{Role: "user", Content: prompt.String()},
+	}, false)
+	if err != nil {
+		return Answer{}, fmt.Errorf("could not answer question: %w", err)
+	}
+
+	answer := Answer{Text: reply, Citations: []Citation{}}
+	cited := make(map[int]bool)
+
+	for _, m := range citationRe.FindAllStringSubmatch(reply, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 || n > len(passages) || cited[n] {
+			continue
+		}
+		cited[n] = true
+
+		p := passages[n-1]
+		answer.Citations = append(answer.Citations, Citation{
+			Index:    n,
+			Subtitle: p.Subtitle,
+			Start:    p.Start.Seconds(),
+			End:      p.End.Seconds(),
+			Text:     p.Text,
+		})
+	}
+
+	sort.Slice(answer.Citations, func(i, j int) bool { return answer.Citations[i].Index < answer.Citations[j].Index })
+	return answer, nil
+}
+
+// index returns the passages of the document, embedding them unless they are already indexed.
+// The index is keyed on the content of the subtitle, so that any edit since it was indexed is embedded again.
+func (a *Assistant) index(ctx context.Context, doc Document) ([]Passage, error) {
+	sum := sha256.Sum256(doc.Data)
+	digest := hex.EncodeToString(sum[:])
+
+	passages, ok, err := a.store.Passages(doc.Path, digest, a.embeddingModel)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		for i := range passages {
+			passages[i].Subtitle = doc.Name
+		}
+		return passages, nil
+	}
+
+	var cues []srt.Cue
+	if srt.IsVTT(doc.Data) {
+		cues, err = srt.ParseVTT(doc.Data)
+	} else {
+		cues, err = srt.Parse(doc.Data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse subtitle: %w", err)
+	}
+
+	passages = split(doc.Name, cues)
+
+	for start := 0; start < len(passages); start += embedBatch {
+		batch := passages[start:min(start+embedBatch, len(passages))]
+
+		texts := make([]string, 0, len(batch))
+		for _, p := range batch {
+			texts = append(texts, p.Text)
+		}
+
+		vectors, err := a.llm.Embed(ctx, a.embeddingModel, texts)
+		if err != nil {
+			return nil, fmt.Errorf("could not embed passages: %w", err)
+		}
+
+		for i := range batch {
+			batch[i].Vector = vectors[i]
+		}
+	}
+
+	if err := a.store.SavePassages(doc.Path, digest, a.embeddingModel, passages); err != nil {
+		return nil, err
+	}
+	return passages, nil
+}
+
+// split groups consecutive cues into passages of bounded length and text.
+func split(name string, cues []srt.Cue) []Passage {
+	var (
+		passages []Passage
+		current  *Passage
+	)
+
+	for _, cue := range cues {
+		text := strings.Join(strings.Fields(cue.Text), " ")
+		if text == "" {
+			continue
+		}
+
+		if current != nil && (cue.End-current.Start > maxPassageLength || len(current.Text)+len(text) > maxPassageText) {
+			passages = append(passages, *current)
+			current = nil
+		}
+
+		if current == nil {
+			current = &Passage{Subtitle: name, Start: cue.Start, End: cue.End, Text: text}
+			continue
+		}
+
+		current.End = cue.End
+		current.Text += " " + text
+	}
+
+	if current != nil {
+		passages = append(passages, *current)
+	}
+	return passages
+}
+
+// byScore sorts passages by decreasing score.
+type byScore struct {
+	passages []Passage
+	scores   []float64
+}
+
+func (s byScore) Len() int           { return len(s.passages) }
+func (s byScore) Less(i, j int) bool { return s.scores[i] > s.scores[j] }
+
+func (s byScore) Swap(i, j int) {
+	s.passages[i], s.passages[j] = s.passages[j], s.passages[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
+
+// similarity returns the cosine similarity of the vectors.
+func similarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// timestamp formats the position in the recording as HH:MM:SS.
+func timestamp(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
@@ -0,0 +1,71 @@
+package quality
+
+// OpKind is the kind of an alignment operation.
+type OpKind string
+
+const (
+	OpEqual      OpKind = "equal"
+	OpSubstitute OpKind = "substitute"
+	OpDelete     OpKind = "delete"
+	OpInsert     OpKind = "insert"
+)
+
+// Op is an alignment operation between a reference and a hypothesis token.
+// Ref is empty for insertions and Hyp is empty for deletions.
+type Op struct {
+	Kind OpKind `json:"kind"`
+	Ref  string `json:"ref,omitempty"`
+	Hyp  string `json:"hyp,omitempty"`
+}
+
+// Align returns the minimal edit operations turning ref into hyp.
+func Align(ref, hyp []string) []Op {
+	n, m := len(ref), len(hyp)
+
+	// dist[i][j] is the edit distance between ref[:i] and hyp[:j].
+	dist := make([][]int, n+1)
+	for i := range dist {
+		dist[i] = make([]int, m+1)
+		dist[i][0] = i
+	}
+
+	for j := 0; j <= m; j++ {
+		dist[0][j] = j
+	}
+
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			cost := 1
+			if ref[i-1] == hyp[j-1] {
+				cost = 0
+			}
+			dist[i][j] = min(dist[i-1][j-1]+cost, dist[i-1][j]+1, dist[i][j-1]+1)
+		}
+	}
+
+	// Walk back from the end to recover the operations.
+	ops := make([]Op, 0, max(n, m))
+
+	i, j := n, m
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && ref[i-1] == hyp[j-1] && dist[i][j] == dist[i-1][j-1]:
+			ops = append(ops, Op{Kind: OpEqual, Ref: ref[i-1], Hyp: hyp[j-1]})
+			i, j = i-1, j-1
+		case i > 0 && j > 0 && dist[i][j] == dist[i-1][j-1]+1:
+			ops = append(ops, Op{Kind: OpSubstitute, Ref: ref[i-1], Hyp: hyp[j-1]})
+			i, j = i-1, j-1
+		case i > 0 && dist[i][j] == dist[i-1][j]+1:
+			ops = append(ops, Op{Kind: OpDelete, Ref: ref[i-1]})
+			i--
+		default:
+			ops = append(ops, Op{Kind: OpInsert, Hyp: hyp[j-1]})
+			j--
+		}
+	}
+
+	for l, r := 0, len(ops)-1; l < r; l, r = l+1, r-1 {
+		ops[l], ops[r] = ops[r], ops[l]
+	}
+	return ops
+}
@@ -0,0 +1,78 @@
+package quality
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+// maxReadingSpeed is the number of characters per second above which a cue is hard to read,
+// and usually a sign of a transcription that crammed or hallucinated text.
+const maxReadingSpeed float64 = 25
+
+// Assessment is a reference-free estimate of the quality of a subtitle.
+type Assessment struct {
+	Score  float64  `json:"score"` // Between 0 and 1, higher is better.
+	Issues []string `json:"issues,omitempty"`
+}
+
+// Assess estimates the quality of the cues of a recording of the given duration
+// from the defects transcriptions typically have: no text, repeated cues, invalid timings,
+// unreadable speeds and little speech over the recording. Duration can be zero if unknown.
+func Assess(cues []srt.Cue, duration time.Duration) Assessment {
+	if len(Words(Text(cues))) == 0 {
+		return Assessment{Score: 0, Issues: []string{"empty transcript"}}
+	}
+
+	var (
+		repeated int
+		invalid  int
+		fast     int
+		speech   time.Duration
+	)
+
+	for i, cue := range cues {
+		length := cue.End - cue.Start
+
+		if length <= 0 || (i > 0 && cue.Start < cues[i-1].End) {
+			invalid++
+		}
+
+		if i > 0 && strings.EqualFold(strings.TrimSpace(cue.Text), strings.TrimSpace(cues[i-1].Text)) {
+			repeated++
+		}
+
+		if length > 0 {
+			speech += length
+
+			if float64(utf8.RuneCountInString(cue.Text))/length.Seconds() > maxReadingSpeed {
+				fast++
+			}
+		}
+	}
+
+	a := Assessment{Score: 1}
+
+	penalize := func(count int, weight float64, issue string) {
+		if count == 0 {
+			return
+		}
+
+		a.Score -= weight * float64(count) / float64(len(cues))
+		a.Issues = append(a.Issues, issue)
+	}
+
+	penalize(repeated, 2, "repeated cues")
+	penalize(invalid, 1, "invalid or overlapping timings")
+	penalize(fast, 1, "reading speed too high")
+
+	if duration > 0 && float64(speech)/float64(duration) < 0.2 {
+		a.Score -= 0.3
+		a.Issues = append(a.Issues, "little speech detected")
+	}
+
+	a.Score = max(a.Score, 0)
+	return a
+}
@@ -0,0 +1,95 @@
+package quality
+
+import "github.com/alesr/videoscriber/internal/pkg/srt"
+
+// Evaluation scores a subtitle against a reference transcript.
+type Evaluation struct {
+	WER      Score     `json:"wer"`
+	CER      Score     `json:"cer"`
+	Segments []Segment `json:"segments"`
+}
+
+// Segment highlights the errors of a single cue.
+type Segment struct {
+	Index  int     `json:"index"`
+	Start  float64 `json:"start"`
+	End    float64 `json:"end"`
+	Text   string  `json:"text"`
+	Errors int     `json:"errors"`
+	WER    float64 `json:"wer"`
+	Ops    []Op    `json:"ops,omitempty"` // Only the operations that are errors.
+}
+
+// Evaluate computes the error rates of the cues against the reference and
+// attributes each word error to the cue it happened in.
+func Evaluate(cues []srt.Cue, reference string) Evaluation {
+	hypText := Text(cues)
+
+	eval := Evaluation{
+		WER:      WER(reference, hypText),
+		CER:      CER(reference, hypText),
+		Segments: make([]Segment, len(cues)),
+	}
+
+	// owner[k] is the cue the k-th hypothesis word belongs to.
+	var (
+		hyp   []string
+		owner []int
+	)
+
+	for i, cue := range cues {
+		eval.Segments[i] = Segment{
+			Index: cue.Index,
+			Start: cue.Start.Seconds(),
+			End:   cue.End.Seconds(),
+			Text:  cue.Text,
+		}
+
+		for _, word := range Words(cue.Text) {
+			hyp = append(hyp, word)
+			owner = append(owner, i)
+		}
+	}
+
+	if len(cues) == 0 {
+		return eval
+	}
+
+	refWords := make([]int, len(cues))
+
+	var next int // Index of the next hypothesis word.
+	for _, op := range Align(Words(reference), hyp) {
+		// Deletions belong to the cue where the missing word should have been.
+		cue := len(cues) - 1
+		if next < len(owner) {
+			cue = owner[next]
+		}
+
+		if op.Kind != OpInsert {
+			refWords[cue]++
+		}
+
+		if op.Kind != OpDelete {
+			next++
+		}
+
+		if op.Kind == OpEqual {
+			continue
+		}
+
+		eval.Segments[cue].Errors++
+		eval.Segments[cue].Ops = append(eval.Segments[cue].Ops, op)
+	}
+
+	for i := range eval.Segments {
+		seg := &eval.Segments[i]
+
+		switch {
+		case refWords[i] > 0:
+			seg.WER = float64(seg.Errors) / float64(refWords[i])
+		case seg.Errors > 0:
+			seg.WER = 1
+		}
+	}
+	return eval
+}
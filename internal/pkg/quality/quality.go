@@ -0,0 +1,128 @@
+package quality
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+// Score is an error rate between a reference and a hypothesis.
+type Score struct {
+	Rate          float64 `json:"rate"`
+	Substitutions int     `json:"substitutions"`
+	Deletions     int     `json:"deletions"`
+	Insertions    int     `json:"insertions"`
+	Length        int     `json:"length"` // Number of tokens in the reference.
+}
+
+// WER returns the word error rate of the hypothesis against the reference.
+func WER(reference, hypothesis string) Score {
+	return score(Words(reference), Words(hypothesis))
+}
+
+// CER returns the character error rate of the hypothesis against the reference.
+// Whitespace is ignored.
+func CER(reference, hypothesis string) Score {
+	return score(chars(reference), chars(hypothesis))
+}
+
+// Words returns the normalized words of the text: lowercase, without punctuation.
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+	})
+}
+
+// Text returns the text of the cues as a single string.
+func Text(cues []srt.Cue) string {
+	texts := make([]string, 0, len(cues))
+	for _, cue := range cues {
+		texts = append(texts, cue.Text)
+	}
+	return strings.Join(texts, " ")
+}
+
+// CueDiff is a time range where two transcripts disagree.
+type CueDiff struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	A     string  `json:"a"`
+	B     string  `json:"b"`
+	WER   float64 `json:"wer"`
+}
+
+// DiffCues compares each cue of a with the cues of b overlapping it in time
+// and returns the ranges where the words differ.
+func DiffCues(a, b []srt.Cue) []CueDiff {
+	var diffs []CueDiff
+
+	for _, cueA := range a {
+		var overlapping []string
+		for _, cueB := range b {
+			if overlaps(cueA.Start, cueA.End, cueB.Start, cueB.End) {
+				overlapping = append(overlapping, cueB.Text)
+			}
+		}
+
+		textB := strings.Join(overlapping, " ")
+
+		s := WER(cueA.Text, textB)
+		if s.Substitutions+s.Deletions+s.Insertions == 0 {
+			continue
+		}
+
+		diffs = append(diffs, CueDiff{
+			Start: cueA.Start.Seconds(),
+			End:   cueA.End.Seconds(),
+			A:     cueA.Text,
+			B:     textB,
+			WER:   s.Rate,
+		})
+	}
+	return diffs
+}
+
+func overlaps(startA, endA, startB, endB time.Duration) bool {
+	return startA < endB && startB < endA
+}
+
+func chars(text string) []string {
+	var out []string
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		out = append(out, string(r))
+	}
+	return out
+}
+
+// score computes the Levenshtein alignment of the token sequences.
+func score(ref, hyp []string) Score {
+	ops := Align(ref, hyp)
+
+	s := Score{Length: len(ref)}
+
+	for _, op := range ops {
+		switch op.Kind {
+		case OpSubstitute:
+			s.Substitutions++
+		case OpDelete:
+			s.Deletions++
+		case OpInsert:
+			s.Insertions++
+		}
+	}
+
+	edits := s.Substitutions + s.Deletions + s.Insertions
+
+	switch {
+	case len(ref) > 0:
+		s.Rate = float64(edits) / float64(len(ref))
+	case edits > 0:
+		s.Rate = 1
+	}
+	return s
+}
@@ -0,0 +1,73 @@
+package quota
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimits overrides the rate limit of some keys, e.g. by their rate plan.
+type rateLimits interface {
+	RequestsPerMinute(key string) (int, bool)
+}
+
+// Limiter limits the rate of requests of each key with a token bucket: a key can make
+// up to perMinute requests at once, then one more each time a minute/perMinute elapses.
+type Limiter struct {
+	perMinute int
+	limits    rateLimits
+	now       func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewLimiter returns a new limiter of perMinute requests per key, unless the limits override the one of a key.
+// Zero disables the limit.
+func NewLimiter(perMinute int, limits rateLimits) *Limiter {
+	return &Limiter{
+		perMinute: perMinute,
+		limits:    limits,
+		now:       time.Now,
+		buckets:   make(map[string]*bucket),
+	}
+}
+
+// Allow reports whether the key can make a request now and, when it can't,
+// how long until it can.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	perMinute := l.perMinute
+	if n, ok := l.limits.RequestsPerMinute(key); ok {
+		perMinute = n
+	}
+
+	if perMinute <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	rate := float64(perMinute) / time.Minute.Seconds() // Tokens per second.
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(perMinute), updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = min(float64(perMinute), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+
+	b.tokens--
+	return true, 0
+}
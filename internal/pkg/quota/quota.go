@@ -0,0 +1,88 @@
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrExceeded is returned when the monthly transcription quota is used up.
+var ErrExceeded = errors.New("monthly transcription quota exceeded")
+
+type usageStore interface {
+	AddUsage(owner, month string, d time.Duration) error
+	Usage(owner, month string) (time.Duration, error)
+}
+
+// Usage is the transcription usage of an owner in a month.
+type Usage struct {
+	Month string
+	Used  time.Duration
+	Limit time.Duration // Zero when unlimited.
+}
+
+// monthlyLimits overrides the monthly quota of some owners, e.g. by their rate plan.
+type monthlyLimits interface {
+	MonthlyMinutes(owner string) (int, bool)
+}
+
+// Accountant accounts the duration of the audio transcribed by each owner per calendar month (UTC)
+// and enforces a monthly quota.
+type Accountant struct {
+	store   usageStore
+	monthly time.Duration
+	limits  monthlyLimits
+	now     func() time.Time
+}
+
+// NewAccountant returns a new accountant enforcing the monthly quota, unless the limits override the one
+// of an owner. Zero disables the quota, but usage is still accounted.
+func NewAccountant(store usageStore, monthly time.Duration, limits monthlyLimits) *Accountant {
+	return &Accountant{store: store, monthly: monthly, limits: limits, now: time.Now}
+}
+
+// Check returns ErrExceeded when the owner used up the quota of the current month.
+// Files being transcribed are accounted once done, so the quota can be exceeded by the last files.
+func (a *Accountant) Check(owner string) error {
+	if a.limit(owner) <= 0 {
+		return nil
+	}
+
+	u, err := a.Usage(owner)
+	if err != nil {
+		return err
+	}
+
+	if u.Used >= u.Limit {
+		return fmt.Errorf("%w: %s of %s used in %s", ErrExceeded, u.Used.Round(time.Second), u.Limit, u.Month)
+	}
+	return nil
+}
+
+// Record adds the transcribed duration to the usage of the owner in the current month.
+func (a *Accountant) Record(owner string, d time.Duration) error {
+	return a.store.AddUsage(owner, a.month(), d)
+}
+
+// Usage returns the usage of the owner in the current month.
+func (a *Accountant) Usage(owner string) (Usage, error) {
+	month := a.month()
+
+	used, err := a.store.Usage(owner, month)
+	if err != nil {
+		return Usage{}, err
+	}
+	return Usage{Month: month, Used: used, Limit: a.limit(owner)}, nil
+}
+
+// limit returns the monthly quota of the owner.
+func (a *Accountant) limit(owner string) time.Duration {
+	if minutes, ok := a.limits.MonthlyMinutes(owner); ok {
+		return time.Duration(minutes) * time.Minute
+	}
+	return a.monthly
+}
+
+func (a *Accountant) month() string {
+	return a.now().UTC().Format("2006-01")
+}
@@ -0,0 +1,202 @@
+package retention
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/audit"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+)
+
+var (
+	// ErrLegalHold is returned when deleting or modifying a subtitle of a project under legal hold.
+	ErrLegalHold = errors.New("project is under legal hold")
+
+	// ErrInvalidProject is returned when the project name is not a valid path segment.
+	ErrInvalidProject = errors.New("invalid project name")
+
+	// ErrInvalidPolicy is returned when the retention policy has a negative period.
+	ErrInvalidPolicy = errors.New("invalid retention policy")
+
+	projectNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+type store interface {
+	List() ([]storage.Object, error)
+	Remove(obj storage.Object) error
+}
+
+type auditor interface {
+	Record(e audit.Entry) error
+}
+
+// Policy is the retention policy of a project.
+type Policy struct {
+	Days      int  `json:"days"` // Zero means the subtitles are kept forever.
+	LegalHold bool `json:"legal_hold"`
+}
+
+// Manager stores retention policies and enforces them on the subtitles directory.
+type Manager struct {
+	logger   *slog.Logger
+	store    store
+	path     string
+	auditor  auditor
+	mediaTTL time.Duration
+	mu       sync.RWMutex
+	policies map[string]Policy
+}
+
+// New returns a new retention manager for the stored subtitles.
+// Policies are persisted as JSON in the file at path. The audio and videos kept alongside
+// the subtitles expire after mediaTTL, unless it is zero.
+func New(logger *slog.Logger, store store, path string, auditor auditor, mediaTTL time.Duration) (*Manager, error) {
+	m := Manager{
+		logger:   logger,
+		store:    store,
+		path:     path,
+		auditor:  auditor,
+		mediaTTL: mediaTTL,
+		policies: make(map[string]Policy),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not read retention policies: %w", err)
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &m.policies); err != nil {
+			return nil, fmt.Errorf("could not unmarshal retention policies: %w", err)
+		}
+	}
+	return &m, nil
+}
+
+// ValidateProject checks whether the project name can be used as a directory name.
+func ValidateProject(project string) error {
+	if !projectNameRe.MatchString(project) {
+		return ErrInvalidProject
+	}
+	return nil
+}
+
+// Policy returns the retention policy of the project.
+func (m *Manager) Policy(project string) Policy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.policies[project]
+}
+
+// SetPolicy updates and persists the retention policy of the project.
+func (m *Manager) SetPolicy(project string, p Policy, actor string) error {
+	if err := ValidateProject(project); err != nil {
+		return err
+	}
+
+	if p.Days < 0 {
+		return ErrInvalidPolicy
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous := m.policies[project]
+	m.policies[project] = p
+
+	if err := m.save(); err != nil {
+		m.policies[project] = previous
+		return err
+	}
+
+	m.record(audit.Entry{
+		Action:  "retention.update",
+		Subject: project,
+		Actor:   actor,
+		Details: map[string]string{
+			"days":       strconv.Itoa(p.Days),
+			"legal_hold": strconv.FormatBool(p.LegalHold),
+		},
+	})
+	return nil
+}
+
+// CanModify returns ErrLegalHold if the project is under legal hold, so that its subtitles
+// can't be deleted nor rewritten, e.g. by editing their cues.
+func (m *Manager) CanModify(project string) error {
+	if m.Policy(project).LegalHold {
+		return ErrLegalHold
+	}
+	return nil
+}
+
+// Enforce deletes the subtitles older than their project's retention period, and the media kept
+// alongside them older than the media TTL, and returns the number of removed files and their size.
+// Projects under legal hold are skipped.
+func (m *Manager) Enforce(now time.Time) (int, int64, error) {
+	objects, err := m.store.List()
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not list subtitles: %w", err)
+	}
+
+	var (
+		removed int
+		size    int64
+	)
+
+	for _, obj := range objects {
+		policy := m.Policy(obj.Project)
+
+		if policy.LegalHold {
+			continue
+		}
+
+		expired := policy.Days > 0 && now.Sub(obj.ModTime) >= time.Duration(policy.Days)*24*time.Hour
+		mediaExpired := m.mediaTTL > 0 && subtitles.IsMedia(obj.Name) && now.Sub(obj.ModTime) >= m.mediaTTL
+
+		if !expired && !mediaExpired {
+			continue
+		}
+
+		if err := m.store.Remove(obj); err != nil {
+			return removed, size, fmt.Errorf("could not remove expired subtitle: %w", err)
+		}
+
+		removed++
+		size += obj.Size
+
+		m.record(audit.Entry{
+			Action:  "retention.delete",
+			Subject: obj.Name,
+			Actor:   "retention",
+			Details: map[string]string{"project": obj.Project},
+		})
+	}
+	return removed, size, nil
+}
+
+func (m *Manager) save() error {
+	data, err := json.MarshalIndent(m.policies, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal retention policies: %w", err)
+	}
+
+	if err := os.WriteFile(m.path, data, 0o644); err != nil {
+		return fmt.Errorf("could not write retention policies: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) record(e audit.Entry) {
+	if err := m.auditor.Record(e); err != nil {
+		m.logger.Error("Could not record audit entry", slog.String("action", e.Action), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,152 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	day time.Duration = 24 * time.Hour
+
+	// DefaultHorizon bounds how far in the future transcriptions are scheduled when not configured.
+	DefaultHorizon time.Duration = 30 * day
+)
+
+// DefaultDeferrable are the priorities whose transcriptions are deferred when the policy doesn't list them.
+var DefaultDeferrable = []string{"low"}
+
+// Policy bounds the transcriptions sent to the providers per hour and per day. Zero limits are unlimited.
+// Transcriptions with a deferrable priority are scheduled in the first hour and day within the limits,
+// so large batches are spread over hours or days. Other transcriptions are never deferred,
+// but count towards the limits.
+type Policy struct {
+	MaxMinutesPerHour float64  `json:"max_minutes_per_hour,omitempty"` // Audio minutes, e.g. the provider rate limit.
+	DailySpendCap     float64  `json:"daily_spend_cap,omitempty"`
+	CostPerMinute     float64  `json:"cost_per_minute,omitempty"` // Spend per audio minute, e.g. 0.006.
+	Deferrable        []string `json:"deferrable,omitempty"`      // Priorities that can be deferred. Defaults to DefaultDeferrable.
+}
+
+// LoadPolicy reads a scheduling policy from a JSON file.
+func LoadPolicy(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, fmt.Errorf("could not read scheduling policy: %w", err)
+	}
+
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Policy{}, fmt.Errorf("could not unmarshal scheduling policy: %w", err)
+	}
+
+	if p.MaxMinutesPerHour < 0 || p.DailySpendCap < 0 || p.CostPerMinute < 0 {
+		return Policy{}, fmt.Errorf("invalid scheduling policy: limits and costs can't be negative")
+	}
+	return p, nil
+}
+
+// Scheduler reserves the hourly minutes and the daily spend of the transcriptions, in UTC periods.
+// Reservations are kept in memory, so the periods start over when the server restarts.
+type Scheduler struct {
+	policy  Policy
+	horizon time.Duration
+
+	mu      sync.Mutex
+	minutes map[time.Time]float64 // Audio minutes reserved by hour.
+	spend   map[time.Time]float64 // Spend reserved by day.
+}
+
+// New returns a new scheduler enforcing the policy, deferring transcriptions up to the horizon,
+// e.g. less than the age at which the tmp files of the deferred transcriptions are removed.
+// The horizon is DefaultHorizon when zero.
+func New(policy Policy, horizon time.Duration) *Scheduler {
+	if len(policy.Deferrable) == 0 {
+		policy.Deferrable = DefaultDeferrable
+	}
+
+	if horizon <= 0 {
+		horizon = DefaultHorizon
+	}
+
+	return &Scheduler{
+		policy:  policy,
+		horizon: horizon,
+		minutes: make(map[time.Time]float64),
+		spend:   make(map[time.Time]float64),
+	}
+}
+
+// Reserve reserves the transcription of audio of duration d and returns when it can start.
+// Urgent transcriptions, and those whose priority isn't deferrable, start now.
+// Transcriptions exceeding the limits on their own start in the first period without other reservations.
+func (s *Scheduler) Reserve(priority string, urgent bool, d time.Duration) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	s.prune(now)
+
+	minutes := d.Minutes()
+	cost := minutes * s.policy.CostPerMinute
+
+	if urgent || !slices.Contains(s.policy.Deferrable, priority) {
+		s.reserve(now, minutes, cost)
+		return now
+	}
+
+	for hour := now.Truncate(time.Hour); hour.Before(now.Add(s.horizon)); hour = hour.Add(time.Hour) {
+		if !s.fits(hour, minutes, cost) {
+			continue
+		}
+
+		s.reserve(hour, minutes, cost)
+
+		if hour.Before(now) {
+			return now
+		}
+		return hour
+	}
+
+	// Past the horizon, start now rather than never.
+	s.reserve(now, minutes, cost)
+	return now
+}
+
+// fits reports whether the transcription fits in the limits of the hour and its day.
+func (s *Scheduler) fits(hour time.Time, minutes, cost float64) bool {
+	if limit := s.policy.MaxMinutesPerHour; limit > 0 {
+		if used := s.minutes[hour]; used > 0 && used+minutes > limit {
+			return false
+		}
+	}
+
+	if limit := s.policy.DailySpendCap; limit > 0 {
+		if used := s.spend[hour.Truncate(day)]; used > 0 && used+cost > limit {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Scheduler) reserve(at time.Time, minutes, cost float64) {
+	s.minutes[at.Truncate(time.Hour)] += minutes
+	s.spend[at.Truncate(day)] += cost
+}
+
+// prune forgets the reservations of past periods.
+func (s *Scheduler) prune(now time.Time) {
+	for hour := range s.minutes {
+		if hour.Before(now.Truncate(time.Hour)) {
+			delete(s.minutes, hour)
+		}
+	}
+
+	for d := range s.spend {
+		if d.Before(now.Truncate(day)) {
+			delete(s.spend, d)
+		}
+	}
+}
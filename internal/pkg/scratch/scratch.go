@@ -0,0 +1,205 @@
+// Package scratch places the temporary files of the jobs on several directories, e.g. a small fast disk
+// keeping the audio extraction fast and a larger slow one, so that large files don't fill the fast disk.
+package scratch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Dir is a directory of temporary files.
+type Dir struct {
+	Path string
+	// Capacity is the number of bytes the temporary files can take in the directory.
+	// It is ignored for the last directory, which takes the files that don't fit in the others.
+	Capacity int64
+}
+
+// ParseDirs parses comma-separated directories with their capacity in bytes, e.g. /mnt/nvme=20000000000.
+func ParseDirs(s string) ([]Dir, error) {
+	var dirs []Dir
+
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		i := strings.LastIndex(item, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("no capacity for directory %q", item)
+		}
+
+		capacity, err := strconv.ParseInt(item[i+1:], 10, 64)
+		if err != nil || capacity <= 0 {
+			return nil, fmt.Errorf("invalid capacity of directory %q", item[:i])
+		}
+		dirs = append(dirs, Dir{Path: item[:i], Capacity: capacity})
+	}
+	return dirs, nil
+}
+
+// Space creates the temporary files in the first directory with room for them, in order.
+// A file outgrowing the room left in its directory while written spills over to the next one.
+type Space struct {
+	logger *slog.Logger
+	dirs   []Dir
+
+	mu       sync.Mutex
+	reserved []int64 // Bytes reserved in each directory by the files being written.
+}
+
+// New returns the space of the directories, in order of preference, creating them if needed.
+func New(logger *slog.Logger, dirs []Dir) (*Space, error) {
+	if len(dirs) == 0 {
+		return nil, errors.New("no temporary directory")
+	}
+
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir.Path, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("could not create temporary directory: %w", err)
+		}
+	}
+
+	return &Space{logger: logger, dirs: dirs, reserved: make([]int64, len(dirs))}, nil
+}
+
+// Dirs returns the paths of the directories.
+func (s *Space) Dirs() []string {
+	paths := make([]string, 0, len(s.dirs))
+	for _, dir := range s.dirs {
+		paths = append(paths, dir.Path)
+	}
+	return paths
+}
+
+// Create writes the data to a new temporary file, named like os.CreateTemp does with the pattern, and
+// returns its path. The size of the data, when known, selects the directory; zero when unknown.
+// Files created next to it, e.g. the extracted audio, aren't accounted, so the capacities need some headroom.
+func (s *Space) Create(pattern string, size int64, data io.Reader) (string, error) {
+	i, budget := s.reserve(size)
+	return s.create(i, budget, pattern, data)
+}
+
+func (s *Space) create(i int, budget int64, pattern string, data io.Reader) (string, error) {
+	defer s.release(i, budget)
+
+	f, err := os.CreateTemp(s.dirs[i].Path, pattern)
+	if err != nil {
+		return "", fmt.Errorf("could not create temporary file: %w", err)
+	}
+
+	path := f.Name()
+
+	fail := func(err error) (string, error) {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+
+	if i == len(s.dirs)-1 {
+		if _, err := io.Copy(f, data); err != nil {
+			return fail(fmt.Errorf("could not write temporary file: %w", err))
+		}
+
+		if err := f.Close(); err != nil {
+			os.Remove(path)
+			return "", fmt.Errorf("could not close temporary file: %w", err)
+		}
+		return path, nil
+	}
+
+	// The file fits unless more than its budget arrives, e.g. exactly the size it was reserved with.
+	_, err = io.CopyN(f, data, budget+1)
+
+	switch {
+	case errors.Is(err, io.EOF):
+		if err := f.Close(); err != nil {
+			os.Remove(path)
+			return "", fmt.Errorf("could not close temporary file: %w", err)
+		}
+		return path, nil
+	case err != nil:
+		return fail(fmt.Errorf("could not write temporary file: %w", err))
+	}
+
+	// The file doesn't fit, its start is copied to the next directory before the rest of the data.
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fail(fmt.Errorf("could not rewind temporary file: %w", err))
+	}
+
+	s.logger.Debug("Temporary file spills over", slog.String("from", s.dirs[i].Path), slog.String("to", s.dirs[i+1].Path))
+
+	next, nextBudget := s.reserveIn(i+1, 0)
+
+	spilled, err := s.create(next, nextBudget, pattern, io.MultiReader(f, data))
+	if err != nil {
+		return fail(err)
+	}
+
+	f.Close()
+	os.Remove(path)
+	return spilled, nil
+}
+
+// reserve selects the first directory with room for size bytes, or any room when the size is unknown,
+// and reserves the room the file can take there.
+func (s *Space) reserve(size int64) (int, int64) {
+	return s.reserveIn(0, size)
+}
+
+func (s *Space) reserveIn(from int, size int64) (int, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := len(s.dirs) - 1
+
+	for i := from; i < last; i++ {
+		free := s.dirs[i].Capacity - s.reserved[i] - usage(s.dirs[i].Path)
+
+		if free <= 0 || size > free {
+			continue
+		}
+
+		// A file of known size only takes its size, the other files can use the rest.
+		budget := free
+		if size > 0 {
+			budget = size
+		}
+
+		s.reserved[i] += budget
+		return i, budget
+	}
+	return last, 0
+}
+
+func (s *Space) release(i int, budget int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reserved[i] -= budget
+}
+
+// usage returns the size of the files in the directory.
+func usage(dir string) int64 {
+	var size int64
+
+	filepath.WalkDir(dir, func(_ string, e fs.DirEntry, err error) error {
+		if err != nil || !e.Type().IsRegular() {
+			return nil
+		}
+
+		if info, err := e.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ErrUnsupported is returned when services can't be installed on the current OS.
+var ErrUnsupported = errors.New("services are not supported on this OS")
+
+// Config describes the service the server is installed as.
+type Config struct {
+	Name        string // Name of the Windows service, label of the launchd agent.
+	DisplayName string
+	Description string
+	Executable  string   // Absolute path of the binary.
+	Args        []string // Arguments the service runs the binary with.
+	Dir         string   // Working directory of the service.
+	LogFile     string   // File the output of the service is written to.
+}
+
+// Run calls run and closes its stop channel when the server must stop, then waits for run to return.
+// When managed, the server was started by the Windows service manager and stops when the manager
+// tells it to. Otherwise, e.g. under launchd or in a terminal, it stops on SIGINT or SIGTERM.
+func Run(name string, managed bool, run func(stop <-chan struct{}) error) error {
+	if managed {
+		return runManaged(name, run)
+	}
+	return runUntilSignal(run)
+}
+
+func runUntilSignal(run func(stop <-chan struct{}) error) error {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(c)
+
+	stop := make(chan struct{})
+
+	go func() {
+		<-c
+		close(stop)
+	}()
+	return run(stop)
+}
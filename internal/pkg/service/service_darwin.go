@@ -0,0 +1,122 @@
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// launchdPath is the PATH of the agent. launchd only sets /usr/bin:/bin:/usr/sbin:/sbin,
+// which leaves out the binaries installed by Homebrew and MacPorts, like ffmpeg.
+const launchdPath = "/opt/homebrew/bin:/usr/local/bin:/opt/local/bin:/usr/bin:/bin:/usr/sbin:/sbin"
+
+// Install writes the launchd agent of the service to the LaunchAgents of the user and loads it,
+// so that the server runs in background once the user logs in and is restarted when it exits.
+func Install(cfg Config) error {
+	path, err := plistPath(cfg.Name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("could not create launch agents directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, plist(cfg), 0o644); err != nil {
+		return fmt.Errorf("could not write launch agent: %w", err)
+	}
+
+	if out, err := exec.Command("launchctl", "load", "-w", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("could not load launch agent: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Uninstall unloads the launchd agent of the service and removes it.
+func Uninstall(name string) error {
+	path, err := plistPath(name)
+	if err != nil {
+		return err
+	}
+
+	if out, err := exec.Command("launchctl", "unload", "-w", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("could not unload launch agent: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("could not remove launch agent: %w", err)
+	}
+	return nil
+}
+
+// runManaged runs until a signal, the way launchd stops agents.
+func runManaged(_ string, run func(stop <-chan struct{}) error) error {
+	return runUntilSignal(run)
+}
+
+func plistPath(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not find home directory: %w", err)
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", name+".plist"), nil
+}
+
+// plist returns the property list of the launchd agent.
+func plist(cfg Config) []byte {
+	var b bytes.Buffer
+
+	str := func(s string) {
+		b.WriteString("\t<string>")
+		xml.EscapeText(&b, []byte(s))
+		b.WriteString("</string>\n")
+	}
+
+	key := func(k string) {
+		b.WriteString("\t<key>" + k + "</key>\n")
+	}
+
+	b.WriteString(xml.Header)
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString("<plist version=\"1.0\">\n<dict>\n")
+
+	key("Label")
+	str(cfg.Name)
+
+	key("ProgramArguments")
+	b.WriteString("\t<array>\n")
+	for _, arg := range append([]string{cfg.Executable}, cfg.Args...) {
+		b.WriteString("\t")
+		str(arg)
+	}
+	b.WriteString("\t</array>\n")
+
+	key("WorkingDirectory")
+	str(cfg.Dir)
+
+	key("EnvironmentVariables")
+	b.WriteString("\t<dict>\n\t\t<key>PATH</key>\n\t")
+	str(launchdPath)
+	b.WriteString("\t</dict>\n")
+
+	if cfg.LogFile != "" {
+		key("StandardOutPath")
+		str(cfg.LogFile)
+		key("StandardErrorPath")
+		str(cfg.LogFile)
+	}
+
+	key("RunAtLoad")
+	b.WriteString("\t<true/>\n")
+
+	// Restarts the server when it exits with an error, not when it is stopped.
+	key("KeepAlive")
+	b.WriteString("\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>\n")
+
+	b.WriteString("</dict>\n</plist>\n")
+	return b.Bytes()
+}
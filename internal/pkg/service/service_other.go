@@ -0,0 +1,17 @@
+//go:build !windows && !darwin
+
+package service
+
+// Install is not supported: run the server under the init system, e.g. a systemd unit.
+func Install(cfg Config) error {
+	return ErrUnsupported
+}
+
+// Uninstall is not supported: run the server under the init system, e.g. a systemd unit.
+func Uninstall(name string) error {
+	return ErrUnsupported
+}
+
+func runManaged(_ string, run func(stop <-chan struct{}) error) error {
+	return runUntilSignal(run)
+}
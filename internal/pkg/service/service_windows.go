@@ -0,0 +1,207 @@
+package service
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Service manager API, see https://learn.microsoft.com/en-us/windows/win32/services/service-functions.
+const (
+	serviceWin32OwnProcess = 0x10
+
+	serviceStopped      = 1
+	serviceStartPending = 2
+	serviceStopPending  = 3
+	serviceRunning      = 4
+
+	serviceAcceptStop     = 0x1
+	serviceAcceptShutdown = 0x4
+
+	serviceControlStop        = 1
+	serviceControlInterrogate = 4
+	serviceControlShutdown    = 5
+
+	errorCallNotImplemented = 120
+
+	// stopWaitHint is how long the service manager is told to wait for progress while stopping.
+	// Progress is reported at half of it until the server stopped, e.g. while jobs drain.
+	stopWaitHint = 30 * time.Second
+)
+
+var (
+	advapi32                          = syscall.NewLazyDLL("advapi32.dll")
+	procStartServiceCtrlDispatcherW   = advapi32.NewProc("StartServiceCtrlDispatcherW")
+	procRegisterServiceCtrlHandlerExW = advapi32.NewProc("RegisterServiceCtrlHandlerExW")
+	procSetServiceStatus              = advapi32.NewProc("SetServiceStatus")
+)
+
+type serviceStatus struct {
+	ServiceType             uint32
+	CurrentState            uint32
+	ControlsAccepted        uint32
+	Win32ExitCode           uint32
+	ServiceSpecificExitCode uint32
+	CheckPoint              uint32
+	WaitHint                uint32
+}
+
+type serviceTableEntry struct {
+	name *uint16
+	proc uintptr
+}
+
+// Install registers the service with the service manager, started automatically at boot.
+func Install(cfg Config) error {
+	args := []string{syscall.EscapeArg(cfg.Executable)}
+	for _, arg := range cfg.Args {
+		args = append(args, syscall.EscapeArg(arg))
+	}
+
+	if err := sc("create", cfg.Name, "binPath=", strings.Join(args, " "), "start=", "auto", "DisplayName=", cfg.DisplayName); err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	if err := sc("description", cfg.Name, cfg.Description); err != nil {
+		return fmt.Errorf("could not describe service: %w", err)
+	}
+	return nil
+}
+
+// Uninstall stops the service, when running, and removes it from the service manager.
+func Uninstall(name string) error {
+	_ = sc("stop", name)
+
+	if err := sc("delete", name); err != nil {
+		return fmt.Errorf("could not delete service: %w", err)
+	}
+	return nil
+}
+
+func sc(args ...string) error {
+	if out, err := exec.Command("sc.exe", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// managedService runs the server as a service of the service manager.
+type managedService struct {
+	name         string
+	run          func(stop <-chan struct{}) error
+	statusHandle uintptr
+
+	mu     sync.Mutex
+	status serviceStatus
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	err      error
+}
+
+// runManaged connects to the service manager, which calls the service main function on another thread,
+// and returns once the service stopped.
+func runManaged(name string, run func(stop <-chan struct{}) error) error {
+	s := managedService{
+		name:   name,
+		run:    run,
+		stop:   make(chan struct{}),
+		status: serviceStatus{ServiceType: serviceWin32OwnProcess},
+	}
+
+	serviceName, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return fmt.Errorf("invalid service name: %w", err)
+	}
+
+	table := []serviceTableEntry{
+		{name: serviceName, proc: syscall.NewCallback(s.main)},
+		{},
+	}
+
+	if ok, _, err := procStartServiceCtrlDispatcherW.Call(uintptr(unsafe.Pointer(&table[0]))); ok == 0 {
+		return fmt.Errorf("could not connect to the service manager: %w", err)
+	}
+	return s.err
+}
+
+func (s *managedService) main(argc, argv uintptr) uintptr {
+	name, _ := syscall.UTF16PtrFromString(s.name)
+
+	handle, _, err := procRegisterServiceCtrlHandlerExW.Call(uintptr(unsafe.Pointer(name)), syscall.NewCallback(s.control), 0)
+	if handle == 0 {
+		s.err = fmt.Errorf("could not register service handler: %w", err)
+		return 0
+	}
+	s.statusHandle = handle
+
+	s.setStatus(serviceStartPending, 0)
+	s.setStatus(serviceRunning, serviceAcceptStop|serviceAcceptShutdown)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.run(s.stop)
+	}()
+
+	ticker := time.NewTicker(stopWaitHint / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			s.err = err
+			s.setStatus(serviceStopped, 0)
+			return 0
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.status.CurrentState == serviceStopPending {
+				s.status.CheckPoint++
+				s.report()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *managedService) control(control, eventType, eventData, context uintptr) uintptr {
+	switch control {
+	case serviceControlStop, serviceControlShutdown:
+		s.stopOnce.Do(func() {
+			s.setStatus(serviceStopPending, 0)
+			close(s.stop)
+		})
+		return 0
+	case serviceControlInterrogate:
+		s.mu.Lock()
+		s.report()
+		s.mu.Unlock()
+		return 0
+	default:
+		return errorCallNotImplemented
+	}
+}
+
+func (s *managedService) setStatus(state, accepted uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status.CurrentState, s.status.ControlsAccepted, s.status.CheckPoint, s.status.WaitHint = state, accepted, 0, 0
+
+	if state == serviceStopPending || state == serviceStartPending {
+		s.status.WaitHint = uint32(stopWaitHint.Milliseconds())
+	}
+
+	if state == serviceStopped && s.err != nil {
+		s.status.Win32ExitCode = 1
+	}
+	s.report()
+}
+
+// report sends the status to the service manager. The caller holds the lock.
+func (s *managedService) report() {
+	procSetServiceStatus.Call(s.statusHandle, uintptr(unsafe.Pointer(&s.status)))
+}
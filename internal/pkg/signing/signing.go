@@ -0,0 +1,173 @@
+// Package signing signs the generated subtitles and artifacts with the Ed25519 key of the deployment,
+// so that downstream consumers can prove a transcript wasn't altered after it was generated. Each file
+// gets a detached signature stored alongside it, named after the file with the .sig extension, which
+// covers its name, size and SHA-256 digest.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Extension is the extension of the detached signatures, appended to the name of the signed file.
+const Extension string = ".sig"
+
+// algorithm is the only signature algorithm, recorded so that others can be introduced later.
+const algorithm string = "ed25519"
+
+var (
+	// ErrMalformedSignature is returned when a detached signature can't be parsed.
+	ErrMalformedSignature = errors.New("malformed signature")
+
+	// ErrInvalidSignature is returned when a file doesn't match its signature,
+	// or the signature wasn't made with the key of the deployment.
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Signature is the detached signature of a file.
+type Signature struct {
+	Algorithm string    `json:"algorithm"`
+	KeyID     string    `json:"key_id"`
+	Name      string    `json:"name"`
+	Size      int       `json:"size"`   // Bytes.
+	SHA256    string    `json:"sha256"` // Hex encoded.
+	SignedAt  time.Time `json:"signed_at"`
+	Signature string    `json:"signature"` // Hex encoded signature of the other fields, see message.
+}
+
+// message returns the signed representation of the signature fields.
+func (s Signature) message() []byte {
+	return []byte(strings.Join([]string{
+		"videoscriber-signature-v1",
+		s.Algorithm,
+		s.KeyID,
+		s.Name,
+		fmt.Sprint(s.Size),
+		s.SHA256,
+		s.SignedAt.UTC().Format(time.RFC3339),
+	}, "\n"))
+}
+
+// Name returns the name of the detached signature of the file.
+func Name(fileName string) string {
+	return fileName + Extension
+}
+
+// Signer signs files with the key of the deployment. It is enabled when a key is configured.
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+	now   func() time.Time
+}
+
+// New returns a new signer with the key, disabled when nil.
+func New(key ed25519.PrivateKey) *Signer {
+	s := &Signer{key: key, now: time.Now}
+	if key != nil {
+		s.keyID = KeyID(key.Public().(ed25519.PublicKey))
+	}
+	return s
+}
+
+// LoadKey reads an Ed25519 private key from a PKCS #8 PEM file, e.g. generated with
+// "openssl genpkey -algorithm ed25519".
+func LoadKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read signing key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("could not decode signing key: no PEM block")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse signing key: %w", err)
+	}
+
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key is a %T, not an Ed25519 key", key)
+	}
+	return edKey, nil
+}
+
+// KeyID returns the identifier of the public key, the hex encoded first 8 bytes of its SHA-256 digest.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Enabled reports whether files are signed, that is whether a key is configured.
+func (s *Signer) Enabled() bool {
+	return s.key != nil
+}
+
+// PublicKey returns the PEM encoded public key verifying the signatures.
+func (s *Signer) PublicKey() ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(s.key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// Sign returns the JSON encoded detached signature of the file.
+func (s *Signer) Sign(fileName string, data []byte) ([]byte, error) {
+	sum := sha256.Sum256(data)
+
+	sig := Signature{
+		Algorithm: algorithm,
+		KeyID:     s.keyID,
+		Name:      fileName,
+		Size:      len(data),
+		SHA256:    hex.EncodeToString(sum[:]),
+		SignedAt:  s.now().UTC().Truncate(time.Second),
+	}
+	sig.Signature = hex.EncodeToString(ed25519.Sign(s.key, sig.message()))
+
+	out, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal signature: %w", err)
+	}
+	return out, nil
+}
+
+// Verify checks that the file matches the JSON encoded detached signature, made with the key of the
+// signer. It returns the parsed signature, also when the file doesn't match it.
+func (s *Signer) Verify(data, signature []byte) (Signature, error) {
+	var sig Signature
+	if err := json.Unmarshal(signature, &sig); err != nil {
+		return Signature{}, fmt.Errorf("%w: %s", ErrMalformedSignature, err)
+	}
+
+	rawSig, err := hex.DecodeString(sig.Signature)
+	if err != nil || sig.Algorithm != algorithm {
+		return sig, ErrMalformedSignature
+	}
+
+	if sig.KeyID != s.keyID {
+		return sig, fmt.Errorf("%w: signed with key %s, not %s", ErrInvalidSignature, sig.KeyID, s.keyID)
+	}
+
+	if !ed25519.Verify(s.key.Public().(ed25519.PublicKey), sig.message(), rawSig) {
+		return sig, fmt.Errorf("%w: the signature was altered", ErrInvalidSignature)
+	}
+
+	sum := sha256.Sum256(data)
+	if len(data) != sig.Size || hex.EncodeToString(sum[:]) != sig.SHA256 {
+		return sig, fmt.Errorf("%w: the file was altered", ErrInvalidSignature)
+	}
+	return sig, nil
+}
@@ -0,0 +1,91 @@
+package signing
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+)
+
+// Storage stores subtitles and their artifacts.
+type Storage interface {
+	Write(owner, language, project, fileName string, data []byte) (string, error)
+	Move(owner, language, project, fileName, srcPath string) (string, error)
+}
+
+// SignedStorage stores the detached signature of each file written, alongside it. The moved files,
+// i.e. the uploaded media, aren't signed: they weren't generated.
+type SignedStorage struct {
+	storage Storage
+	signer  *Signer
+}
+
+// NewStorage returns the storage signing the files written to s with the signer, or s itself
+// when the signer is disabled.
+func NewStorage(s Storage, signer *Signer) Storage {
+	if !signer.Enabled() {
+		return s
+	}
+	return &SignedStorage{storage: s, signer: signer}
+}
+
+// Write stores the file, then its signature.
+func (s *SignedStorage) Write(owner, language, project, fileName string, data []byte) (string, error) {
+	path, err := s.storage.Write(owner, language, project, fileName, data)
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := s.signer.Sign(filepath.Base(fileName), data)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := s.storage.Write(owner, language, project, Name(fileName), sig); err != nil {
+		return "", fmt.Errorf("could not write signature: %w", err)
+	}
+	return path, nil
+}
+
+func (s *SignedStorage) Move(owner, language, project, fileName, srcPath string) (string, error) {
+	return s.storage.Move(owner, language, project, fileName, srcPath)
+}
+
+// Catalog stores subtitles and their artifacts, and replaces them once edited.
+type Catalog interface {
+	Storage
+	Replace(obj storage.Object, data []byte) error
+}
+
+// SignedCatalog signs the files written, like SignedStorage, and signs the replaced files again,
+// so that the signature of an edited subtitle matches its new content.
+type SignedCatalog struct {
+	SignedStorage
+	catalog Catalog
+}
+
+// NewCatalog returns the catalog signing the files written to c, or replaced, with the signer,
+// or c itself when the signer is disabled.
+func NewCatalog(c Catalog, signer *Signer) Catalog {
+	if !signer.Enabled() {
+		return c
+	}
+	return &SignedCatalog{SignedStorage: SignedStorage{storage: c, signer: signer}, catalog: c}
+}
+
+// Replace replaces the data of the stored file, then its signature.
+func (c *SignedCatalog) Replace(obj storage.Object, data []byte) error {
+	if err := c.catalog.Replace(obj, data); err != nil {
+		return err
+	}
+
+	sig, err := c.signer.Sign(obj.Name, data)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.catalog.Write(obj.Owner, obj.Language, obj.Project, Name(obj.Name), sig); err != nil {
+		return fmt.Errorf("could not write signature: %w", err)
+	}
+	return nil
+}
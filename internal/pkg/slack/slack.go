@@ -0,0 +1,120 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	postMessageURL string = "https://slack.com/api/chat.postMessage"
+
+	// maxRequestAge rejects replayed requests, as recommended by Slack.
+	maxRequestAge = 5 * time.Minute
+)
+
+// ErrInvalidSignature is returned for requests not signed with the signing secret.
+var ErrInvalidSignature = errors.New("invalid slack signature")
+
+// Config configures the Slack integration. It is enabled when both the signing secret and the bot token are set.
+type Config struct {
+	SigningSecret string
+	BotToken      string
+}
+
+// Client verifies requests sent by Slack and posts messages as the bot.
+type Client struct {
+	httpCli *http.Client
+	cfg     Config
+}
+
+// New returns a new Slack client.
+func New(httpCli *http.Client, cfg Config) *Client {
+	return &Client{httpCli: httpCli, cfg: cfg}
+}
+
+// Enabled reports whether the integration is configured.
+func (c *Client) Enabled() bool {
+	return c.cfg.SigningSecret != "" && c.cfg.BotToken != ""
+}
+
+// Verify checks the signature of a request sent by Slack.
+func (c *Client) Verify(header http.Header, body []byte) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+
+	if age := time.Since(time.Unix(sec, 0)); age > maxRequestAge || age < -maxRequestAge {
+		return fmt.Errorf("%w: request too old", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(c.cfg.SigningSecret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// PostMessage posts the text to the channel, in the thread of threadTS when set,
+// and returns the timestamp identifying the message.
+func (c *Client) PostMessage(ctx context.Context, channel, threadTS, text string) (string, error) {
+	body, err := json.Marshal(map[string]string{
+		"channel":   channel,
+		"thread_ts": threadTS,
+		"text":      text,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not marshal message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, postMessageURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("could not create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.cfg.BotToken)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := c.httpCli.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("could not read response body: %w", err)
+	}
+
+	// The Web API reports errors in the body, usually with a 200 status code.
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("could not unmarshal response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if !result.OK {
+		return "", fmt.Errorf("could not post message: %s", result.Error)
+	}
+	return result.TS, nil
+}
@@ -0,0 +1,112 @@
+package slug
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxBase is the maximum length in bytes of a name without its extension.
+const maxBase int = 100
+
+// transliterations are the ASCII spellings of lowercase letters of the Latin, Cyrillic and Greek scripts,
+// by groups of letters sharing the same spelling.
+var transliterations = map[string]string{
+	// Latin.
+	"àáâãäåāăą": "a", "æ": "ae", "çćĉċč": "c", "ďđð": "d", "èéêëēĕėęě": "e", "ĝğġģ": "g", "ĥħ": "h",
+	"ìíîïĩīĭįı": "i", "ĳ": "ij", "ĵ": "j", "ķ": "k", "ĺļľŀł": "l", "ñńņňŉ": "n", "òóôõöøōŏő": "o",
+	"œ": "oe", "ŕŗř": "r", "śŝşšș": "s", "ß": "ss", "ţťŧț": "t", "þ": "th", "ùúûüũūŭůűų": "u",
+	"ŵ": "w", "ýÿŷ": "y", "źżž": "z",
+	// Cyrillic, Russian and Ukrainian.
+	"а": "a", "б": "b", "в": "v", "гґ": "g", "д": "d", "е": "e", "ё": "yo", "є": "ye", "ж": "zh", "з": "z",
+	"иі": "i", "ї": "yi", "й": "y", "к": "k", "л": "l", "м": "m", "н": "n", "о": "o", "п": "p", "р": "r",
+	"с": "s", "т": "t", "уў": "u", "ф": "f", "х": "kh", "ц": "ts", "ч": "ch", "ш": "sh", "щ": "shch",
+	"ъь": "", "ы": "y", "э": "e", "ю": "yu", "я": "ya",
+	// Greek.
+	"αά": "a", "β": "v", "γ": "g", "δ": "d", "εέ": "e", "ζ": "z", "ηή": "i", "θ": "th", "ιίϊΐ": "i",
+	"κ": "k", "λ": "l", "μ": "m", "ν": "n", "ξ": "x", "οό": "o", "π": "p", "ρ": "r", "σς": "s", "τ": "t",
+	"υύϋΰ": "y", "φ": "f", "χ": "ch", "ψ": "ps", "ωώ": "o",
+}
+
+var letters = make(map[rune]string)
+
+func init() {
+	for group, ascii := range transliterations {
+		for _, r := range group {
+			letters[r] = ascii
+		}
+	}
+}
+
+// Make returns a name safe to store files under, made of ASCII letters, digits, '.', '_' and '-'.
+// Latin letters with diacritics, Cyrillic and Greek are transliterated, e.g. "Reunião Москва.mp4"
+// becomes "Reuniao-Moskva.mp4", other characters become separators. When letters can't be
+// transliterated, e.g. CJK, a short hash of the name is appended so that different names
+// don't share the same slug. The extension is kept.
+func Make(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+
+	ext, extLossy := transliterate(strings.TrimPrefix(path.Ext(name), "."))
+	if ext != "" && !extLossy {
+		ext = "." + strings.ToLower(ext)
+	} else {
+		ext = ""
+	}
+
+	base, lossy := transliterate(strings.TrimSuffix(name, path.Ext(name)))
+	lossy = lossy || extLossy
+	if len(base) > maxBase {
+		base = strings.Trim(base[:maxBase], "-._")
+	}
+
+	if base == "" {
+		base = "file"
+	}
+
+	if lossy {
+		sum := sha256.Sum256([]byte(name))
+		base += "-" + hex.EncodeToString(sum[:4])
+	}
+	return base + ext
+}
+
+// transliterate spells the text in ASCII, collapsing the characters that can't be transliterated into '-'.
+// It reports whether letters or digits were lost.
+func transliterate(text string) (string, bool) {
+	var (
+		b     strings.Builder
+		lossy bool
+	)
+
+	separate := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "-") {
+			b.WriteByte('-')
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.'):
+			b.WriteRune(r)
+		case r < utf8.RuneSelf:
+			separate()
+		default:
+			ascii, ok := letters[unicode.ToLower(r)]
+			switch {
+			case ok && unicode.IsUpper(r) && ascii != "":
+				b.WriteString(strings.ToUpper(ascii[:1]) + ascii[1:])
+			case ok:
+				b.WriteString(ascii)
+			default:
+				if unicode.IsLetter(r) || unicode.IsDigit(r) {
+					lossy = true
+				}
+				separate()
+			}
+		}
+	}
+	return strings.Trim(b.String(), "-._"), lossy
+}
@@ -0,0 +1,152 @@
+package srt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FormatVTT encodes the cues as WebVTT data.
+func FormatVTT(cues []Cue) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString("WEBVTT\n")
+
+	for _, cue := range cues {
+		fmt.Fprintf(&buf, "\n%s --> %s\n%s\n", vttTimestamp(cue.Start), vttTimestamp(cue.End), cue.Text)
+	}
+	return buf.Bytes()
+}
+
+// IsVTT reports whether the data is WebVTT rather than SRT.
+func IsVTT(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimPrefix(data, []byte("\ufeff")), []byte("WEBVTT"))
+}
+
+// ParseVTT parses WebVTT data into cues. Comments, style and region blocks are skipped,
+// and cue identifiers are replaced by the position of the cue.
+func ParseVTT(data []byte) ([]Cue, error) {
+	normalized := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	var blocks []string
+
+	for i, block := range strings.Split(normalized, "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" || i == 0 && strings.HasPrefix(strings.TrimPrefix(block, "\ufeff"), "WEBVTT") {
+			continue
+		}
+
+		lines := strings.Split(block, "\n")
+
+		timing := slices.IndexFunc(lines, func(line string) bool { return strings.Contains(line, "-->") })
+		if timing < 0 {
+			continue // NOTE, STYLE and REGION blocks.
+		}
+		blocks = append(blocks, strings.Join(lines[timing:], "\n"))
+	}
+	return Parse([]byte(strings.Join(blocks, "\n\n")))
+}
+
+// FormatText encodes the cues as a plain text transcript, one paragraph per line, separated by blank lines.
+func FormatText(cues []Cue) []byte {
+	var buf bytes.Buffer
+
+	for i, p := range Paragraphs(cues) {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(p.Text)
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+// FormatHTML encodes the cues as an HTML transcript, one paragraph element per paragraph,
+// with the timestamp of its start.
+func FormatHTML(cues []Cue, language string) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "<!DOCTYPE html>\n<html lang=\"%s\">\n<head>\n<meta charset=\"utf-8\">\n<title>Transcript</title>\n</head>\n<body>\n", html.EscapeString(language))
+
+	for _, p := range Paragraphs(cues) {
+		fmt.Fprintf(&buf, "<p data-start=\"%s\">%s</p>\n", vttTimestamp(p.Start), html.EscapeString(p.Text))
+	}
+
+	buf.WriteString("</body>\n</html>\n")
+	return buf.Bytes()
+}
+
+// FormatTSV encodes the cues as tab-separated values with
+// start and end timestamps in milliseconds, as the Whisper CLI does.
+func FormatTSV(cues []Cue) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString("start\tend\ttext\n")
+
+	for _, cue := range cues {
+		fmt.Fprintf(&buf, "%d\t%d\t%s\n", cue.Start.Milliseconds(), cue.End.Milliseconds(), singleLine(cue.Text))
+	}
+	return buf.Bytes()
+}
+
+type verboseJSON struct {
+	Task     string           `json:"task"`
+	Language string           `json:"language"`
+	Duration float64          `json:"duration"`
+	Text     string           `json:"text"`
+	Segments []verboseSegment `json:"segments"`
+}
+
+type verboseSegment struct {
+	ID    int     `json:"id"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// FormatVerboseJSON encodes the cues following the layout of Whisper's verbose JSON response.
+func FormatVerboseJSON(cues []Cue, language string) ([]byte, error) {
+	doc := verboseJSON{
+		Task:     "transcribe",
+		Language: language,
+		Segments: make([]verboseSegment, 0, len(cues)),
+	}
+
+	texts := make([]string, 0, len(cues))
+
+	for i, cue := range cues {
+		text := singleLine(cue.Text)
+
+		doc.Segments = append(doc.Segments, verboseSegment{
+			ID:    i,
+			Start: cue.Start.Seconds(),
+			End:   cue.End.Seconds(),
+			Text:  text,
+		})
+		texts = append(texts, text)
+
+		if end := cue.End.Seconds(); end > doc.Duration {
+			doc.Duration = end
+		}
+	}
+	doc.Text = strings.Join(texts, " ")
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal cues: %w", err)
+	}
+	return data, nil
+}
+
+// vttTimestamp formats the duration as a WebVTT timestamp (HH:MM:SS.mmm).
+func vttTimestamp(d time.Duration) string {
+	return strings.Replace(FormatTimestamp(d), ",", ".", 1)
+}
+
+func singleLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
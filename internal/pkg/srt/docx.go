@@ -0,0 +1,78 @@
+package srt
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// The parts of a minimal WordprocessingML package: the content types, the relationship
+// to the main document, and the document itself.
+const (
+	docxContentTypes string = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+		`</Types>`
+
+	docxRelationships string = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+		`</Relationships>`
+)
+
+// FormatDOCX encodes the cues as a Word transcript, one paragraph per paragraph,
+// with the language of the text set for spell checking.
+func FormatDOCX(cues []Cue, language string) ([]byte, error) {
+	var doc bytes.Buffer
+
+	doc.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	doc.WriteString(`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>`)
+
+	var lang bytes.Buffer
+	if language != "" {
+		lang.WriteString(`<w:rPr><w:lang w:val="`)
+		xml.EscapeText(&lang, []byte(language))
+		lang.WriteString(`"/></w:rPr>`)
+	}
+
+	for _, p := range Paragraphs(cues) {
+		doc.WriteString(`<w:p><w:r>`)
+		doc.Write(lang.Bytes())
+		doc.WriteString(`<w:t xml:space="preserve">`)
+		xml.EscapeText(&doc, []byte(p.Text))
+		doc.WriteString(`</w:t></w:r></w:p>`)
+	}
+
+	doc.WriteString(`<w:sectPr/></w:body></w:document>`)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	parts := []struct {
+		name string
+		data []byte
+	}{
+		{"[Content_Types].xml", []byte(docxContentTypes)},
+		{"_rels/.rels", []byte(docxRelationships)},
+		{"word/document.xml", doc.Bytes()},
+	}
+
+	for _, part := range parts {
+		w, err := zw.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("could not create %s: %w", part.name, err)
+		}
+
+		if _, err := w.Write(part.data); err != nil {
+			return nil, fmt.Errorf("could not write %s: %w", part.name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("could not close docx: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,127 @@
+package srt
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// paragraphPause is the silence after a sentence that starts a new paragraph.
+	paragraphPause time.Duration = 2 * time.Second
+
+	// sectionPause is the silence that starts a new paragraph even within a sentence,
+	// as the transcripts of some providers are unpunctuated.
+	sectionPause time.Duration = 5 * time.Second
+
+	// maxParagraphSentences is the number of sentences after which a paragraph ends.
+	maxParagraphSentences int = 6
+
+	// minShiftSentences is the number of sentences a paragraph has before a discourse marker starts a new one.
+	minShiftSentences int = 3
+)
+
+// shiftMarkers are the words that usually open a new topic at the start of a sentence.
+var shiftMarkers = map[string]bool{
+	// English.
+	"so": true, "now": true, "next": true, "anyway": true, "okay": true, "ok": true, "alright": true,
+	"finally": true, "first": true, "second": true, "then": true, "moving": true,
+
+	// Portuguese.
+	"então": true, "agora": true, "bom": true, "enfim": true, "primeiro": true, "segundo": true,
+	"depois": true, "finalmente": true, "próximo": true,
+}
+
+// Paragraph is a run of cues read as a block of text.
+type Paragraph struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// Paragraphs groups the cues into paragraphs. A paragraph ends at a sentence boundary followed by a pause,
+// by a discourse marker opening a new topic, or after a number of sentences, and at any long pause.
+func Paragraphs(cues []Cue) []Paragraph {
+	var (
+		paragraphs []Paragraph
+		texts      []string
+		sentences  int
+	)
+
+	for i, cue := range cues {
+		text := singleLine(cue.Text)
+		if text == "" {
+			continue
+		}
+
+		if len(texts) == 0 {
+			paragraphs = append(paragraphs, Paragraph{Start: cue.Start})
+		}
+
+		texts = append(texts, text)
+		sentences += countSentences(text)
+
+		current := &paragraphs[len(paragraphs)-1]
+		current.End = cue.End
+		current.Text = strings.Join(texts, " ")
+
+		if i == len(cues)-1 {
+			break
+		}
+
+		next := cues[i+1]
+		pause := next.Start - cue.End
+
+		var end bool
+
+		switch {
+		case pause >= sectionPause:
+			end = true
+		case !endsSentence(text):
+		case pause >= paragraphPause, sentences >= maxParagraphSentences:
+			end = true
+		case sentences >= minShiftSentences && shiftMarkers[firstWord(next.Text)]:
+			end = true
+		}
+
+		if end {
+			texts, sentences = texts[:0], 0
+		}
+	}
+	return paragraphs
+}
+
+// endsSentence reports whether the text ends with a sentence terminator, ignoring closing quotes and brackets.
+func endsSentence(text string) bool {
+	text = strings.TrimRightFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`"')]»”’`, r)
+	})
+	return strings.HasSuffix(text, ".") || strings.HasSuffix(text, "!") ||
+		strings.HasSuffix(text, "?") || strings.HasSuffix(text, "…")
+}
+
+// countSentences counts the sentence terminators of the text, a run of them counting once.
+func countSentences(text string) int {
+	var (
+		n    int
+		prev bool
+	)
+
+	for _, r := range text {
+		terminator := r == '.' || r == '!' || r == '?' || r == '…'
+		if terminator && !prev {
+			n++
+		}
+		prev = terminator
+	}
+	return n
+}
+
+// firstWord returns the first word of the text in lower case, without punctuation.
+func firstWord(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimFunc(fields[0], func(r rune) bool { return !unicode.IsLetter(r) }))
+}
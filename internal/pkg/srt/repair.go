@@ -0,0 +1,164 @@
+package srt
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Layout limits the text of the cues, e.g. to the guidelines of broadcasters. Zero values are unlimited.
+type Layout struct {
+	MaxLineLength int     // Characters per line, longer lines are wrapped at word boundaries.
+	MaxLines      int     // Lines per cue, the extra lines being split off into cues of their own.
+	MaxCPS        float64 // Characters read per second, faster cues being extended into the following pause.
+}
+
+// RepairReport counts the fixes of a repair.
+type RepairReport struct {
+	Dropped    int `json:"dropped"`    // Cues without a positive duration.
+	Overlaps   int `json:"overlaps"`   // Cues overlapping the previous one.
+	Wrapped    int `json:"wrapped"`    // Cues whose lines were too long.
+	Split      int `json:"split"`      // Cues split off cues with too many lines.
+	Extended   int `json:"extended"`   // Cues too fast to read.
+	Renumbered int `json:"renumbered"` // Cues whose number changed.
+}
+
+// Fixed reports whether cues were fixed, besides being renumbered.
+func (r RepairReport) Fixed() bool {
+	return r.Dropped > 0 || r.Overlaps > 0 || r.Wrapped > 0 || r.Split > 0 || r.Extended > 0
+}
+
+// Repair fixes the cues some providers produce: cues are put in order of their start, cues without
+// a positive duration are dropped, overlapping cues are trimmed so that each one ends when the next one
+// starts, their text is laid out within the limits of the layout and cues are numbered sequentially from first.
+func Repair(cues []Cue, first int, layout Layout) ([]Cue, RepairReport) {
+	var report RepairReport
+
+	sorted := append([]Cue(nil), cues...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	repaired := make([]Cue, 0, len(sorted))
+
+	for _, c := range sorted {
+		if c.End <= c.Start {
+			report.Dropped++
+			continue
+		}
+
+		if n := len(repaired); n > 0 && c.Start < repaired[n-1].End {
+			report.Overlaps++
+
+			prev := &repaired[n-1]
+
+			// The previous cue is cut short, unless both start together, in which case this one starts later.
+			if c.Start > prev.Start {
+				prev.End = c.Start
+			} else if c.Start = prev.End; c.End <= c.Start {
+				report.Dropped++
+				continue
+			}
+		}
+		repaired = append(repaired, c)
+	}
+
+	repaired = layout.apply(repaired, &report)
+
+	for i := range repaired {
+		if index := first + i; repaired[i].Index != index {
+			repaired[i].Index = index
+			report.Renumbered++
+		}
+	}
+	return repaired, report
+}
+
+// apply lays out the text of the ordered cues, which don't overlap.
+func (l Layout) apply(cues []Cue, report *RepairReport) []Cue {
+	laid := make([]Cue, 0, len(cues))
+
+	for _, c := range cues {
+		lines := strings.Split(c.Text, "\n")
+
+		if l.MaxLineLength > 0 && slices.ContainsFunc(lines, l.tooLong) {
+			lines = wrap(strings.Fields(c.Text), l.MaxLineLength)
+			report.Wrapped++
+		}
+
+		if l.MaxLines <= 0 || len(lines) <= l.MaxLines {
+			c.Text = strings.Join(lines, "\n")
+			laid = append(laid, c)
+			continue
+		}
+
+		// The time of the cue is shared by its parts in proportion to their text.
+		total := utf8.RuneCountInString(strings.Join(lines, ""))
+		start, span, read := c.Start, c.End-c.Start, 0
+
+		for i := 0; i < len(lines); i += l.MaxLines {
+			part := lines[i:min(i+l.MaxLines, len(lines))]
+			read += utf8.RuneCountInString(strings.Join(part, ""))
+
+			end := c.End
+			if i+l.MaxLines < len(lines) && total > 0 {
+				end = c.Start + time.Duration(float64(span)*float64(read)/float64(total))
+			}
+
+			laid = append(laid, Cue{Index: c.Index, Start: start, End: end, Text: strings.Join(part, "\n")})
+			start = end
+		}
+		report.Split += (len(lines)+l.MaxLines-1)/l.MaxLines - 1
+	}
+
+	if l.MaxCPS <= 0 {
+		return laid
+	}
+
+	// Cues too fast to read are extended up to the start of the next one, the last one as long as needed.
+	for i := range laid {
+		c := &laid[i]
+
+		chars := utf8.RuneCountInString(strings.ReplaceAll(c.Text, "\n", ""))
+		needed := c.Start + time.Duration(float64(chars)/l.MaxCPS*float64(time.Second))
+
+		if needed <= c.End {
+			continue
+		}
+
+		if i+1 < len(laid) {
+			needed = min(needed, laid[i+1].Start)
+		}
+
+		if needed > c.End {
+			c.End = needed
+			report.Extended++
+		}
+	}
+	return laid
+}
+
+func (l Layout) tooLong(line string) bool {
+	return utf8.RuneCountInString(line) > l.MaxLineLength
+}
+
+// wrap fills lines of at most length characters with the words, the words longer than that on their own line.
+func wrap(words []string, length int) []string {
+	var (
+		lines []string
+		line  string
+	)
+
+	for _, w := range words {
+		if line != "" && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(w) > length {
+			lines = append(lines, line)
+			line = ""
+		}
+
+		if line != "" {
+			line += " "
+		}
+		line += w
+	}
+	return append(lines, line)
+}
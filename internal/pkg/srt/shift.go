@@ -0,0 +1,24 @@
+package srt
+
+import "time"
+
+// Shift retimes the cues linearly, each timestamp t becoming t*scale + offset, e.g. to sync the
+// cues with a video whose intro was cut (offset) or whose timing drifts (scale). Cues ending before
+// zero are dropped and those starting before zero start at zero. The cues are renumbered.
+func Shift(cues []Cue, offset time.Duration, scale float64) []Cue {
+	shifted := make([]Cue, 0, len(cues))
+
+	for _, c := range cues {
+		c.Start = time.Duration(float64(c.Start)*scale) + offset
+		c.End = time.Duration(float64(c.End)*scale) + offset
+
+		if c.End <= 0 {
+			continue
+		}
+
+		c.Index = len(shifted) + 1
+		c.Start = max(c.Start, 0)
+		shifted = append(shifted, c)
+	}
+	return shifted
+}
@@ -0,0 +1,29 @@
+package srt
+
+import (
+	"regexp"
+	"strings"
+)
+
+// speakerRe matches diarization labels at the beginning of a cue,
+// e.g. "SPEAKER_00:", "[SPEAKER 1]" or "Speaker 2:".
+var speakerRe = regexp.MustCompile(`^(?:\[((?i:speaker)[ _]?(?:\d+|[A-Z]))\]:?|((?i:speaker)[ _]?(?:\d+|[A-Z])):)[ \t]*`)
+
+// SplitSpeaker splits the diarization label off the text of a cue. The speaker is normalized,
+// e.g. SPEAKER_1 for "[Speaker 1]", and empty when the text has no label.
+func SplitSpeaker(text string) (speaker, rest string) {
+	m := speakerRe.FindStringSubmatch(text)
+	if m == nil {
+		return "", text
+	}
+	return strings.ToUpper(strings.ReplaceAll(m[1]+m[2], " ", "_")), text[len(m[0]):]
+}
+
+// JoinSpeaker prefixes the text of a cue with the diarization label of the speaker, e.g. "[SPEAKER_1]: ",
+// like the providers do. The text is returned as is without a speaker.
+func JoinSpeaker(speaker, text string) string {
+	if speaker == "" {
+		return text
+	}
+	return "[" + speaker + "]: " + text
+}
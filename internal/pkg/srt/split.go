@@ -0,0 +1,56 @@
+package srt
+
+import (
+	"sort"
+	"time"
+)
+
+// Slice returns the cues starting within [start, end), renumbered and with timestamps
+// relative to start. Cues running past end are cut at end.
+func Slice(cues []Cue, start, end time.Duration) []Cue {
+	var part []Cue
+
+	for _, c := range cues {
+		if c.Start < start || c.Start >= end {
+			continue
+		}
+
+		c.Index = len(part) + 1
+		c.Start -= start
+		c.End = min(c.End, end) - start
+		part = append(part, c)
+	}
+	return part
+}
+
+// Chapters splits the cues into n chapters at the n-1 longest pauses between consecutive cues,
+// and returns the start of each chapter. The first chapter starts at zero.
+func Chapters(cues []Cue, n int) []time.Duration {
+	if n < 1 {
+		n = 1
+	}
+
+	type pause struct {
+		at     time.Duration // Start of the cue following the pause.
+		length time.Duration
+	}
+
+	pauses := make([]pause, 0, len(cues))
+	for i := 1; i < len(cues); i++ {
+		pauses = append(pauses, pause{at: cues[i].Start, length: cues[i].Start - cues[i-1].End})
+	}
+
+	sort.SliceStable(pauses, func(i, j int) bool { return pauses[i].length > pauses[j].length })
+
+	if len(pauses) > n-1 {
+		pauses = pauses[:n-1]
+	}
+
+	starts := []time.Duration{0}
+	for _, p := range pauses {
+		starts = append(starts, p.at)
+	}
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	return starts
+}
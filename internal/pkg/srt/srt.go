@@ -0,0 +1,156 @@
+package srt
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const timestampLayout string = "%02d:%02d:%02d,%03d"
+
+// Cue represents a single subtitle entry.
+type Cue struct {
+	Index int
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// JSONCue is the JSON representation of a cue. Timestamps are in seconds.
+type JSONCue struct {
+	Index    int     `json:"index"`
+	Start    float64 `json:"start"`
+	End      float64 `json:"end"`
+	Text     string  `json:"text"`
+	Language string  `json:"language,omitempty"`
+}
+
+// ToJSON converts the cue to its JSON representation.
+func (c Cue) ToJSON() JSONCue {
+	return JSONCue{
+		Index: c.Index,
+		Start: c.Start.Seconds(),
+		End:   c.End.Seconds(),
+		Text:  c.Text,
+	}
+}
+
+// Parse parses SRT data into cues.
+func Parse(data []byte) ([]Cue, error) {
+	normalized := strings.ReplaceAll(string(data), "\r\n", "\n")
+	normalized = strings.TrimPrefix(normalized, "\ufeff")
+
+	var cues []Cue
+
+	for _, block := range strings.Split(normalized, "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+
+		lines := strings.Split(block, "\n")
+
+		var cue Cue
+
+		if !strings.Contains(lines[0], "-->") {
+			index, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+			if err != nil {
+				return nil, fmt.Errorf("could not parse cue index %q: %w", lines[0], err)
+			}
+			cue.Index = index
+			lines = lines[1:]
+		}
+
+		if len(lines) == 0 {
+			return nil, fmt.Errorf("cue %d has no timing line", cue.Index)
+		}
+
+		start, end, err := parseTiming(lines[0])
+		if err != nil {
+			return nil, fmt.Errorf("could not parse timing of cue %d: %w", cue.Index, err)
+		}
+
+		cue.Start = start
+		cue.End = end
+		cue.Text = strings.Join(lines[1:], "\n")
+
+		if cue.Index == 0 {
+			cue.Index = len(cues) + 1
+		}
+
+		cues = append(cues, cue)
+	}
+	return cues, nil
+}
+
+// Format encodes the cues as SRT data.
+func Format(cues []Cue) []byte {
+	var buf bytes.Buffer
+
+	for i, cue := range cues {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+
+		fmt.Fprintf(&buf, "%d\n%s --> %s\n%s\n", cue.Index, FormatTimestamp(cue.Start), FormatTimestamp(cue.End), cue.Text)
+	}
+	return buf.Bytes()
+}
+
+// FormatTimestamp formats the duration as an SRT timestamp (HH:MM:SS,mmm).
+func FormatTimestamp(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	d -= s * time.Second
+	ms := d / time.Millisecond
+
+	return fmt.Sprintf(timestampLayout, h, m, s, ms)
+}
+
+// ParseTimestamp parses an SRT timestamp (HH:MM:SS,mmm). A dot is accepted as the millisecond separator.
+func ParseTimestamp(s string) (time.Duration, error) {
+	s = strings.Replace(strings.TrimSpace(s), ".", ",", 1)
+
+	var h, m, sec, ms int
+	if _, err := fmt.Sscanf(s, "%d:%d:%d,%d", &h, &m, &sec, &ms); err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q: %w", s, err)
+	}
+
+	return time.Duration(h)*time.Hour +
+		time.Duration(m)*time.Minute +
+		time.Duration(sec)*time.Second +
+		time.Duration(ms)*time.Millisecond, nil
+}
+
+func parseTiming(line string) (time.Duration, time.Duration, error) {
+	parts := strings.SplitN(line, "-->", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid timing line %q", line)
+	}
+
+	start, err := ParseTimestamp(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Drop cue settings following the end timestamp, if any.
+	endField := strings.Fields(parts[1])
+	if len(endField) == 0 {
+		return 0, 0, fmt.Errorf("invalid timing line %q", line)
+	}
+
+	end, err := ParseTimestamp(endField[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
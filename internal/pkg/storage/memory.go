@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryRoot is the root of the paths of the files stored in memory, which aren't on disk.
+const memoryRoot string = "memory:"
+
+// Memory stores files in memory, laid out like a Storage, so that the pipeline can be embedded
+// in other programs, or tested, without a subtitles directory. The paths of its objects are virtual:
+// their data is read with Read.
+type Memory struct {
+	layout *Storage
+
+	mu    sync.RWMutex
+	files map[string]memoryFile // By path.
+}
+
+type memoryFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemory returns a new empty storage in memory with the layout.
+func NewMemory(layout string) (*Memory, error) {
+	s, err := New(memoryRoot, layout)
+	if err != nil {
+		return nil, err
+	}
+	return &Memory{layout: s, files: make(map[string]memoryFile)}, nil
+}
+
+// Root returns the virtual root of the paths of the stored files.
+func (m *Memory) Root() string {
+	return memoryRoot
+}
+
+// Path returns the virtual path of the file named fileName for the given owner, language and project.
+func (m *Memory) Path(owner, language, project, fileName string) string {
+	return m.layout.Path(owner, language, project, fileName)
+}
+
+// Write stores a copy of the data and returns the path of the file.
+func (m *Memory) Write(owner, language, project, fileName string, data []byte) (string, error) {
+	filePath := m.Path(owner, language, project, fileName)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files[filePath] = memoryFile{data: slices.Clone(data), modTime: time.Now()}
+	return filePath, nil
+}
+
+// Move stores the content of the file at srcPath, e.g. a video in the tmp directory, and removes it.
+func (m *Memory) Move(owner, language, project, fileName, srcPath string) (string, error) {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file: %w", err)
+	}
+
+	filePath, err := m.Write(owner, language, project, fileName, data)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.Remove(srcPath); err != nil {
+		return "", fmt.Errorf("could not remove moved file: %w", err)
+	}
+	return filePath, nil
+}
+
+// Replace stores a copy of the data at the path of the stored file.
+func (m *Memory) Replace(obj Object, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.files[obj.Path]; !ok {
+		return fmt.Errorf("could not write file: %w", os.ErrNotExist)
+	}
+
+	m.files[obj.Path] = memoryFile{data: slices.Clone(data), modTime: time.Now()}
+	return nil
+}
+
+// Read returns a copy of the data of the stored file.
+func (m *Memory) Read(obj Object) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f, ok := m.files[obj.Path]
+	if !ok {
+		return nil, fmt.Errorf("could not read file: %w", os.ErrNotExist)
+	}
+	return slices.Clone(f.data), nil
+}
+
+// Open returns a reader of the data of the stored file, as it was when opened.
+func (m *Memory) Open(obj Object) (io.ReadSeekCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f, ok := m.files[obj.Path]
+	if !ok {
+		return nil, fmt.Errorf("could not open file: %w", os.ErrNotExist)
+	}
+
+	// The data is never modified in place, only replaced, so it is shared with the reader.
+	return nopCloser{bytes.NewReader(f.data)}, nil
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// List returns all stored files, by path.
+func (m *Memory) List() ([]Object, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	objects := make([]Object, 0, len(m.files))
+	for filePath, f := range m.files {
+		obj := m.layout.describe(filePath)
+		obj.Size = int64(len(f.data))
+		obj.ModTime = f.modTime
+
+		objects = append(objects, obj)
+	}
+
+	slices.SortFunc(objects, func(a, b Object) int { return strings.Compare(a.Path, b.Path) })
+	return objects, nil
+}
+
+// Find returns the first stored file with the given name, in any namespace.
+func (m *Memory) Find(name string) (Object, error) {
+	objects, err := m.List()
+	if err != nil {
+		return Object{}, err
+	}
+
+	for _, obj := range objects {
+		if obj.Name == name {
+			return obj, nil
+		}
+	}
+	return Object{}, ErrNotFound
+}
+
+// Remove deletes the stored file.
+func (m *Memory) Remove(obj Object) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.files[obj.Path]; !ok {
+		return fmt.Errorf("could not remove file: %w", os.ErrNotExist)
+	}
+
+	delete(m.files, obj.Path)
+	return nil
+}
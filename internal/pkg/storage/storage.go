@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLayout stores subtitles in one directory per project.
+	DefaultLayout string = "{project}/{name}"
+
+	// DefaultProject is used for subtitles uploaded without a project.
+	DefaultProject string = "default"
+
+	// UndefinedLanguage is used for subtitles without a language.
+	UndefinedLanguage string = "und"
+
+	// namespacesDir holds the subtitles of each owner, laid out like the root, e.g. .users/alice/{project}/{name}.
+	namespacesDir string = ".users"
+
+	placeholderLang    string = "{lang}"
+	placeholderProject string = "{project}"
+	placeholderName    string = "{name}"
+)
+
+var (
+	// ErrNotFound is returned when no stored file matches the requested name.
+	ErrNotFound = errors.New("file not found")
+
+	// ErrAmbiguous is returned when the requested name matches several stored files,
+	// e.g. of different projects, and their language or project is needed to tell them apart.
+	ErrAmbiguous = errors.New("name matches several files")
+
+	// ErrInvalidLayout is returned when the layout template cannot be used.
+	ErrInvalidLayout = errors.New("invalid layout")
+)
+
+// Object describes a stored file.
+type Object struct {
+	Name     string
+	Path     string
+	Owner    string // Empty for files stored outside of a namespace.
+	Language string
+	Project  string
+	Size     int64
+	ModTime  time.Time
+}
+
+// Storage resolves where subtitles live on disk according to a layout template,
+// e.g. "{lang}/{project}/{name}". The {name} placeholder must be the last segment.
+type Storage struct {
+	root     string
+	segments []string
+}
+
+// New returns a new storage rooted at dir.
+func New(dir, layout string) (*Storage, error) {
+	segments := strings.Split(strings.Trim(filepath.ToSlash(layout), "/"), "/")
+
+	if segments[len(segments)-1] != placeholderName {
+		return nil, fmt.Errorf("%w: %q must end with %s", ErrInvalidLayout, layout, placeholderName)
+	}
+
+	for _, seg := range segments[:len(segments)-1] {
+		if seg == placeholderName || seg == "" || seg == "." || seg == ".." {
+			return nil, fmt.Errorf("%w: unexpected segment %q in %q", ErrInvalidLayout, seg, layout)
+		}
+	}
+
+	return &Storage{
+		root:     dir,
+		segments: segments,
+	}, nil
+}
+
+// Root returns the root directory of the storage.
+func (s *Storage) Root() string {
+	return s.root
+}
+
+// Path returns the path of the file named fileName for the given owner, language and project.
+// Files of an owner are stored in the owner's namespace. The owner can be empty.
+func (s *Storage) Path(owner, language, project, fileName string) string {
+	if language == "" {
+		language = UndefinedLanguage
+	}
+
+	if project == "" {
+		project = DefaultProject
+	}
+
+	parts := make([]string, 0, len(s.segments)+3)
+	parts = append(parts, s.root)
+
+	if owner != "" {
+		parts = append(parts, namespacesDir, filepath.Base(owner))
+	}
+
+	for _, seg := range s.segments {
+		seg = strings.ReplaceAll(seg, placeholderLang, language)
+		seg = strings.ReplaceAll(seg, placeholderProject, project)
+		seg = strings.ReplaceAll(seg, placeholderName, filepath.Base(fileName))
+		parts = append(parts, seg)
+	}
+	return filepath.Join(parts...)
+}
+
+// Write stores the data and returns the path of the written file.
+func (s *Storage) Write(owner, language, project, fileName string, data []byte) (string, error) {
+	filePath := s.Path(owner, language, project, fileName)
+
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return "", fmt.Errorf("could not create directory: %w", err)
+	}
+
+	if err := os.WriteFile(filePath, data, 0o644); err != nil {
+		return "", fmt.Errorf("could not write file: %w", err)
+	}
+	return filePath, nil
+}
+
+// Move stores the file at srcPath, e.g. a large upload that shouldn't be read in memory,
+// and returns the path of the stored file. The source file is removed.
+func (s *Storage) Move(owner, language, project, fileName, srcPath string) (string, error) {
+	filePath := s.Path(owner, language, project, fileName)
+
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return "", fmt.Errorf("could not create directory: %w", err)
+	}
+
+	if err := os.Rename(srcPath, filePath); err == nil {
+		return filePath, nil
+	}
+
+	// Renaming fails across file systems, e.g. when tmp is a tmpfs.
+	if err := copyFile(srcPath, filePath); err != nil {
+		os.Remove(filePath)
+		return "", err
+	}
+
+	if err := os.Remove(srcPath); err != nil {
+		return "", fmt.Errorf("could not remove moved file: %w", err)
+	}
+	return filePath, nil
+}
+
+func copyFile(srcPath, dstPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("could not open file: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not create file: %w", err)
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("could not copy file: %w", err)
+	}
+
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("could not close file: %w", err)
+	}
+	return nil
+}
+
+// Replace writes the data of the stored file where it is stored.
+func (s *Storage) Replace(obj Object, data []byte) error {
+	if err := os.WriteFile(obj.Path, data, 0o644); err != nil {
+		return fmt.Errorf("could not write file: %w", err)
+	}
+	return nil
+}
+
+// Read returns the data of the stored file.
+func (s *Storage) Read(obj Object) ([]byte, error) {
+	data, err := os.ReadFile(obj.Path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %w", err)
+	}
+	return data, nil
+}
+
+// Open opens the stored file, e.g. to serve a large video without reading it in memory.
+func (s *Storage) Open(obj Object) (io.ReadSeekCloser, error) {
+	f, err := os.Open(obj.Path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %w", err)
+	}
+	return f, nil
+}
+
+// List returns all stored files.
+func (s *Storage) List() ([]Object, error) {
+	var objects []Object
+
+	if err := filepath.WalkDir(s.root, func(filePath string, file os.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("could not walk in the directory: %w", err)
+		}
+
+		if file.IsDir() {
+			return nil
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			return fmt.Errorf("could not stat file: %w", err)
+		}
+
+		obj := s.describe(filePath)
+		obj.Size = info.Size()
+		obj.ModTime = info.ModTime()
+
+		objects = append(objects, obj)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// Find returns the first stored file with the given name, in any namespace.
+func (s *Storage) Find(name string) (Object, error) {
+	objects, err := s.List()
+	if err != nil {
+		return Object{}, err
+	}
+
+	for _, obj := range objects {
+		if obj.Name == name {
+			return obj, nil
+		}
+	}
+	return Object{}, ErrNotFound
+}
+
+// Remove deletes the stored file.
+func (s *Storage) Remove(obj Object) error {
+	if err := os.Remove(obj.Path); err != nil {
+		return fmt.Errorf("could not remove file: %w", err)
+	}
+	return nil
+}
+
+// describe resolves the owner, language and project of a stored file from its path.
+// Files that don't follow the layout (e.g. stored before it changed) belong to the default project.
+func (s *Storage) describe(filePath string) Object {
+	obj := Object{
+		Name:    filepath.Base(filePath),
+		Path:    filePath,
+		Project: DefaultProject,
+	}
+
+	rel, err := filepath.Rel(s.root, filePath)
+	if err != nil {
+		return obj
+	}
+
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+
+	if len(parts) > 2 && parts[0] == namespacesDir {
+		obj.Owner, parts = parts[1], parts[2:]
+	}
+
+	if len(parts) != len(s.segments) {
+		return obj
+	}
+
+	for i, seg := range s.segments[:len(s.segments)-1] {
+		switch seg {
+		case placeholderLang:
+			obj.Language = parts[i]
+		case placeholderProject:
+			obj.Project = parts[i]
+		}
+	}
+	return obj
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/langid"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+)
+
+// minLanguageConfidence is the confidence from which the detected language of a subtitle replaces its tag.
+const minLanguageConfidence float64 = 0.5
+
+// Backfilled is the metadata recorded for a subtitle stored before the catalog recorded it.
+type Backfilled struct {
+	Subtitle         Subtitle // With the backfilled metadata.
+	PreviousLanguage string
+	Err              error // Why the subtitle couldn't be backfilled, e.g. unparsable.
+}
+
+// Backfill records the metadata of the SRT and VTT subtitles stored before the catalog recorded it, e.g. written
+// by older versions or copied to the storage directory, once synced: their duration, up to the end of their last
+// cue, their number of cues, and their language when undefined, detected from their text. With retag, the
+// language of the subtitles already tagged is detected too. With dryRun, the metadata is only reported.
+func (c *Catalog) Backfill(retag, dryRun bool) ([]Backfilled, error) {
+	if err := c.Sync(); err != nil {
+		return nil, err
+	}
+
+	subs, err := c.query(`WHERE status != ? AND job_id = '' AND duration_ms = 0 ORDER BY created_at`, StatusDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Backfilled{}
+
+	for _, sub := range subs {
+		if !indexable(sub.Name) {
+			continue
+		}
+
+		res := Backfilled{Subtitle: sub, PreviousLanguage: sub.Language}
+
+		data, err := c.objects.Read(sub.Object())
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return results, fmt.Errorf("could not read subtitle: %w", err)
+		}
+
+		var cues []srt.Cue
+		if srt.IsVTT(data) {
+			cues, err = srt.ParseVTT(data)
+		} else {
+			cues, err = srt.Parse(data)
+		}
+
+		if err != nil {
+			res.Err = fmt.Errorf("could not parse subtitle: %w", err)
+			results = append(results, res)
+			continue
+		}
+
+		res.Subtitle.Cues = len(cues)
+
+		texts := make([]string, 0, len(cues))
+		for _, cue := range cues {
+			res.Subtitle.Duration = max(res.Subtitle.Duration, cue.End)
+
+			_, text := srt.SplitSpeaker(cue.Text)
+			texts = append(texts, text)
+		}
+
+		if retag || sub.Language == storage.UndefinedLanguage {
+			language, confidence := langid.Detect(strings.Join(texts, "\n"), sub.Language)
+			if language != langid.Unknown && confidence >= minLanguageConfidence {
+				res.Subtitle.Language = language
+			}
+		}
+
+		if !dryRun {
+			if err := c.backfill(res.Subtitle, data); err != nil {
+				return results, err
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// backfill records the metadata of the subtitle, reindexing its cues in its language.
+func (c *Catalog) backfill(sub Subtitle, data []byte) error {
+	if _, err := c.store.db.Exec(`UPDATE subtitles SET language = ?, duration_ms = ? WHERE path = ?`,
+		sub.Language, sub.Duration.Milliseconds(), sub.path,
+	); err != nil {
+		return fmt.Errorf("could not backfill subtitle: %w", err)
+	}
+	return c.index(sub.path, sub.Language, data)
+}
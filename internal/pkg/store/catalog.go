@@ -0,0 +1,392 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/analytics"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+)
+
+// Subtitle statuses.
+const (
+	StatusAvailable string = "available"
+	StatusReview    string = "needs_review"
+	StatusDeleted   string = "deleted"
+)
+
+// Subtitle is the metadata of a stored subtitle.
+type Subtitle struct {
+	Name         string
+	Owner        string
+	Project      string
+	Language     string
+	Format       string
+	Size         int64
+	OriginalName string
+	Duration     time.Duration
+	Cues         int // Number of cues of the SRT and VTT subtitles.
+	JobID        string
+	Status       string
+	CreatedAt    time.Time
+	Analytics    *analytics.Analytics // Nil for subtitles generated before analytics were computed.
+
+	path string
+}
+
+// Annotation is the metadata of a subtitle known once its job file is done.
+type Annotation struct {
+	OriginalName string
+	Duration     time.Duration
+	JobID        string
+	Analytics    *analytics.Analytics
+}
+
+type objectStorage interface {
+	Path(owner, language, project, fileName string) string
+	Write(owner, language, project, fileName string, data []byte) (string, error)
+	Move(owner, language, project, fileName, srcPath string) (string, error)
+	Replace(obj storage.Object, data []byte) error
+	Read(obj storage.Object) ([]byte, error)
+	Open(obj storage.Object) (io.ReadSeekCloser, error)
+	List() ([]storage.Object, error)
+	Remove(obj storage.Object) error
+}
+
+// Catalog stores subtitles in the object storage and records their metadata in the database,
+// so that listing and finding subtitles doesn't walk the storage directory.
+type Catalog struct {
+	store   *Store
+	objects objectStorage
+}
+
+// NewCatalog returns a new catalog of the subtitles stored in objects.
+func NewCatalog(store *Store, objects objectStorage) *Catalog {
+	return &Catalog{store: store, objects: objects}
+}
+
+// Sync reconciles the catalog with the storage directory: files missing from
+// the catalog (e.g. written before it existed) are added, and the entries of
+// files removed from the directory are marked as deleted. Subtitles held for review stay held.
+func (c *Catalog) Sync() error {
+	objects, err := c.objects.List()
+	if err != nil {
+		return fmt.Errorf("could not list stored files: %w", err)
+	}
+
+	tx, err := c.store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	present := make(map[string]bool, len(objects))
+
+	for _, obj := range objects {
+		present[obj.Path] = true
+
+		language := obj.Language
+		if language == "" {
+			language = storage.UndefinedLanguage
+		}
+
+		if _, err := tx.Exec(`
+			INSERT INTO subtitles (path, name, owner, project, language, format, size, status, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (path) DO UPDATE SET owner = excluded.owner, size = excluded.size,
+				status = CASE WHEN status = ? THEN excluded.status ELSE status END, deleted_at = NULL`,
+			obj.Path, obj.Name, obj.Owner, obj.Project, language, format(obj.Name), obj.Size, StatusAvailable, obj.ModTime.UTC(), StatusDeleted,
+		); err != nil {
+			return fmt.Errorf("could not record %s: %w", obj.Path, err)
+		}
+	}
+
+	rows, err := tx.Query(`SELECT path FROM subtitles WHERE status != ?`, StatusDeleted)
+	if err != nil {
+		return fmt.Errorf("could not query subtitles: %w", err)
+	}
+
+	var missing []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return fmt.Errorf("could not scan subtitle: %w", err)
+		}
+
+		if !present[path] {
+			missing = append(missing, path)
+		}
+	}
+	rows.Close()
+
+	for _, path := range missing {
+		if _, err := tx.Exec(`UPDATE subtitles SET status = ?, deleted_at = ? WHERE path = ?`, StatusDeleted, time.Now().UTC(), path); err != nil {
+			return fmt.Errorf("could not mark %s as deleted: %w", path, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Write stores the data in the namespace of the owner and records the subtitle.
+// Rewriting a subtitle resets its metadata.
+func (c *Catalog) Write(owner, language, project, fileName string, data []byte) (string, error) {
+	path, err := c.objects.Write(owner, language, project, fileName, data)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.record(path, owner, language, project, fileName, int64(len(data))); err != nil {
+		return "", err
+	}
+
+	if indexable(fileName) {
+		if err := c.index(path, language, data); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// Exists reports whether a subtitle of the owner with the given name is stored in the language and project,
+// whatever its status, e.g. before writing a new file that mustn't overwrite it.
+func (c *Catalog) Exists(owner, language, project, fileName string) (bool, error) {
+	var n int
+	if err := c.store.db.QueryRow(
+		`SELECT COUNT(*) FROM subtitles WHERE path = ? AND status <> ?`,
+		c.objects.Path(owner, language, project, fileName), StatusDeleted,
+	).Scan(&n); err != nil {
+		return false, fmt.Errorf("could not query subtitle: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Move stores the file at srcPath in the namespace of the owner and records it, like Write.
+func (c *Catalog) Move(owner, language, project, fileName, srcPath string) (string, error) {
+	// The size is known before moving, since the object storage may not be on disk.
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("could not stat file: %w", err)
+	}
+
+	var data []byte
+	if indexable(fileName) {
+		if data, err = os.ReadFile(srcPath); err != nil {
+			return "", fmt.Errorf("could not read file: %w", err)
+		}
+	}
+
+	path, err := c.objects.Move(owner, language, project, fileName, srcPath)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.record(path, owner, language, project, fileName, info.Size()); err != nil {
+		return "", err
+	}
+
+	if data != nil {
+		if err := c.index(path, language, data); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// record records the stored file as an available subtitle, resetting its metadata.
+func (c *Catalog) record(path, owner, language, project, fileName string, size int64) error {
+	if language == "" {
+		language = storage.UndefinedLanguage
+	}
+
+	if project == "" {
+		project = storage.DefaultProject
+	}
+
+	name := filepath.Base(fileName)
+
+	if _, err := c.store.db.Exec(`
+		INSERT INTO subtitles (path, name, owner, project, language, format, size, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (path) DO UPDATE SET
+			name = excluded.name, owner = excluded.owner, project = excluded.project, language = excluded.language,
+			format = excluded.format, size = excluded.size, status = excluded.status, created_at = excluded.created_at,
+			original_name = '', duration_ms = 0, job_id = '', analytics = '', cue_count = 0, deleted_at = NULL`,
+		path, name, owner, project, language, format(name), size, StatusAvailable, time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("could not record subtitle: %w", err)
+	}
+	return nil
+}
+
+// Annotate records the metadata of the subtitle known once its job file is done.
+func (c *Catalog) Annotate(owner, language, project, fileName string, a Annotation) error {
+	var stats string
+	if a.Analytics != nil {
+		data, err := json.Marshal(a.Analytics)
+		if err != nil {
+			return fmt.Errorf("could not marshal analytics: %w", err)
+		}
+		stats = string(data)
+	}
+
+	res, err := c.store.db.Exec(`UPDATE subtitles SET original_name = ?, duration_ms = ?, job_id = ?, analytics = ? WHERE path = ?`,
+		a.OriginalName, a.Duration.Milliseconds(), a.JobID, stats, c.objects.Path(owner, language, project, fileName),
+	)
+	if err != nil {
+		return fmt.Errorf("could not annotate subtitle: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// Subtitles returns the metadata of the available subtitles of the owner, newest first.
+func (c *Catalog) Subtitles(owner string) ([]Subtitle, error) {
+	return c.query(`WHERE owner = ? AND status = ? ORDER BY created_at DESC`, owner, StatusAvailable)
+}
+
+// List returns the available subtitles of all owners.
+func (c *Catalog) List() ([]storage.Object, error) {
+	subs, err := c.query(`WHERE status = ? ORDER BY created_at DESC`, StatusAvailable)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]storage.Object, 0, len(subs))
+	for _, sub := range subs {
+		objects = append(objects, sub.Object())
+	}
+	return objects, nil
+}
+
+// Find returns the available subtitle of the owner with the given name, in the language and project unless empty.
+// It returns storage.ErrAmbiguous when several subtitles match, e.g. subtitles of the same name in two projects.
+func (c *Catalog) Find(owner, name, language, project string) (storage.Object, error) {
+	subs, err := c.query(
+		`WHERE owner = ? AND name = ? AND status = ? AND (? = '' OR language = ?) AND (? = '' OR project = ?) LIMIT 2`,
+		owner, name, StatusAvailable, language, language, project, project,
+	)
+	if err != nil {
+		return storage.Object{}, err
+	}
+
+	switch len(subs) {
+	case 0:
+		return storage.Object{}, storage.ErrNotFound
+	case 1:
+		return subs[0].Object(), nil
+	default:
+		return storage.Object{}, fmt.Errorf("%w: %q", storage.ErrAmbiguous, name)
+	}
+}
+
+// Read returns the data of the stored subtitle.
+func (c *Catalog) Read(obj storage.Object) ([]byte, error) {
+	return c.objects.Read(obj)
+}
+
+// Open opens the stored subtitle, or media kept alongside it, to stream it.
+func (c *Catalog) Open(obj storage.Object) (io.ReadSeekCloser, error) {
+	return c.objects.Open(obj)
+}
+
+// Replace replaces the data of a stored subtitle, keeping its metadata, e.g. once its cues are edited.
+// The file is written where it is stored, which may not be the path of its current language once retagged.
+func (c *Catalog) Replace(obj storage.Object, data []byte) error {
+	if err := c.objects.Replace(obj, data); err != nil {
+		return err
+	}
+
+	if _, err := c.store.db.Exec(`UPDATE subtitles SET size = ? WHERE path = ?`, len(data), obj.Path); err != nil {
+		return fmt.Errorf("could not update subtitle: %w", err)
+	}
+
+	if !indexable(obj.Name) {
+		return nil
+	}
+	return c.index(obj.Path, obj.Language, data)
+}
+
+// Remove deletes the stored file and marks the subtitle as deleted, keeping its history.
+func (c *Catalog) Remove(obj storage.Object) error {
+	if err := c.objects.Remove(obj); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if _, err := c.store.db.Exec(`UPDATE subtitles SET status = ?, deleted_at = ? WHERE path = ?`, StatusDeleted, time.Now().UTC(), obj.Path); err != nil {
+		return fmt.Errorf("could not mark subtitle as deleted: %w", err)
+	}
+	return c.unindex(obj.Path)
+}
+
+func (c *Catalog) query(where string, args ...any) ([]Subtitle, error) {
+	rows, err := c.store.db.Query(`
+		SELECT path, name, owner, project, language, format, size, original_name, duration_ms, cue_count, job_id, status, created_at, analytics
+		FROM subtitles `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query subtitles: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []Subtitle
+
+	for rows.Next() {
+		var (
+			sub        Subtitle
+			durationMS int64
+			stats      string
+		)
+
+		if err := rows.Scan(
+			&sub.path, &sub.Name, &sub.Owner, &sub.Project, &sub.Language, &sub.Format, &sub.Size,
+			&sub.OriginalName, &durationMS, &sub.Cues, &sub.JobID, &sub.Status, &sub.CreatedAt, &stats,
+		); err != nil {
+			return nil, fmt.Errorf("could not scan subtitle: %w", err)
+		}
+
+		if stats != "" {
+			sub.Analytics = &analytics.Analytics{}
+			if err := json.Unmarshal([]byte(stats), sub.Analytics); err != nil {
+				return nil, fmt.Errorf("could not unmarshal analytics: %w", err)
+			}
+		}
+
+		sub.Duration = time.Duration(durationMS) * time.Millisecond
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not iterate subtitles: %w", err)
+	}
+	return subs, nil
+}
+
+// Object returns the stored file of the subtitle.
+func (sub Subtitle) Object() storage.Object {
+	return storage.Object{
+		Name:     sub.Name,
+		Path:     sub.path,
+		Owner:    sub.Owner,
+		Language: sub.Language,
+		Project:  sub.Project,
+		Size:     sub.Size,
+		ModTime:  sub.CreatedAt,
+	}
+}
+
+func format(name string) string {
+	return strings.TrimPrefix(filepath.Ext(name), ".")
+}
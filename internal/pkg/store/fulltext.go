@@ -0,0 +1,340 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/fulltext"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+)
+
+// BM25 parameters of the ranking of the matching cues.
+const (
+	bm25K1 float64 = 1.2
+	bm25B  float64 = 0.75
+)
+
+// Match is a cue of a subtitle matching a search.
+type Match struct {
+	Cue     srt.Cue
+	Score   float64
+	Snippet string // HTML text of the cue, with the matching words in <mark> elements.
+}
+
+// SearchResult is a subtitle matching a search, with its matching cues in order.
+type SearchResult struct {
+	Subtitle Subtitle
+	Score    float64 // Sum of the scores of its matches.
+	Matches  []Match
+}
+
+// indexable reports whether the cues of the stored file can be indexed.
+func indexable(name string) bool {
+	f := format(name)
+	return f == "srt" || f == "vtt"
+}
+
+// index replaces the indexed cues of the subtitle at path with those of its data, and records their number.
+// Data that can't be parsed, e.g. edited by hand, leaves the subtitle out of the index rather than failing the write.
+func (c *Catalog) index(path, language string, data []byte) error {
+	var (
+		cues []srt.Cue
+		err  error
+	)
+
+	if srt.IsVTT(data) {
+		cues, err = srt.ParseVTT(data)
+	} else {
+		cues, err = srt.Parse(data)
+	}
+
+	tx, txErr := c.store.db.Begin()
+	if txErr != nil {
+		return fmt.Errorf("could not begin transaction: %w", txErr)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM cue_terms WHERE docid IN (SELECT id FROM indexed_cues WHERE path = ?)`, path); err != nil {
+		return fmt.Errorf("could not delete indexed terms: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM indexed_subtitles WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("could not delete indexed cues: %w", err)
+	}
+
+	if err == nil {
+		if _, err := tx.Exec(`INSERT INTO indexed_subtitles (path, size) VALUES (?, ?)`, path, len(data)); err != nil {
+			return fmt.Errorf("could not record index: %w", err)
+		}
+
+		if _, err := tx.Exec(`UPDATE subtitles SET cue_count = ? WHERE path = ?`, len(cues), path); err != nil {
+			return fmt.Errorf("could not record cue count: %w", err)
+		}
+
+		for _, cue := range cues {
+			res, err := tx.Exec(
+				`INSERT INTO indexed_cues (path, cue, start_ms, end_ms, text) VALUES (?, ?, ?, ?, ?)`,
+				path, cue.Index, cue.Start.Milliseconds(), cue.End.Milliseconds(), cue.Text,
+			)
+			if err != nil {
+				return fmt.Errorf("could not index cue: %w", err)
+			}
+
+			id, err := res.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("could not index cue: %w", err)
+			}
+
+			terms := strings.Join(fulltext.Tokens(language, cue.Text), " ")
+
+			if _, err := tx.Exec(`INSERT INTO cue_terms (docid, terms) VALUES (?, ?)`, id, terms); err != nil {
+				return fmt.Errorf("could not index terms: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit index: %w", err)
+	}
+	return nil
+}
+
+// unindex removes the cues of the subtitle at path from the index.
+func (c *Catalog) unindex(path string) error {
+	if _, err := c.store.db.Exec(`DELETE FROM cue_terms WHERE docid IN (SELECT id FROM indexed_cues WHERE path = ?)`, path); err != nil {
+		return fmt.Errorf("could not delete indexed terms: %w", err)
+	}
+
+	if _, err := c.store.db.Exec(`DELETE FROM indexed_subtitles WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("could not delete indexed cues: %w", err)
+	}
+	return nil
+}
+
+// Reindex indexes the subtitles missing from the index or changed since indexed, e.g. written before
+// the index existed or edited on disk, and returns how many were indexed.
+func (c *Catalog) Reindex() (int, error) {
+	rows, err := c.store.db.Query(`
+		SELECT s.path, s.name, s.language FROM subtitles s LEFT JOIN indexed_subtitles i ON i.path = s.path
+		WHERE s.status != ? AND (i.size IS NULL OR i.size != s.size)`, StatusDeleted)
+	if err != nil {
+		return 0, fmt.Errorf("could not query subtitles: %w", err)
+	}
+
+	type pending struct{ path, language string }
+
+	var subs []pending
+	for rows.Next() {
+		var path, name, language string
+		if err := rows.Scan(&path, &name, &language); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("could not scan subtitle: %w", err)
+		}
+
+		if indexable(name) {
+			subs = append(subs, pending{path, language})
+		}
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("could not iterate subtitles: %w", err)
+	}
+
+	var indexed int
+	for _, sub := range subs {
+		data, err := c.objects.Read(storage.Object{Path: sub.path})
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return indexed, fmt.Errorf("could not read subtitle: %w", err)
+		}
+
+		if err := c.index(sub.path, sub.language, data); err != nil {
+			return indexed, err
+		}
+		indexed++
+	}
+	return indexed, nil
+}
+
+// Search returns the available subtitles of the owner with cues containing all the words of the query,
+// ignoring their case, accents and inflections in the language of each subtitle, the most relevant first.
+// The subtitles are optionally filtered by language and project. It also reports whether more than limit match.
+func (c *Catalog) Search(owner, query, language, project string, limit int) ([]SearchResult, bool, error) {
+	where, args := `WHERE owner = ? AND status = ?`, []any{owner, StatusAvailable}
+
+	if language != "" {
+		where, args = where+` AND language = ?`, append(args, language)
+	}
+
+	if project != "" {
+		where, args = where+` AND project = ?`, append(args, project)
+	}
+
+	languages, err := c.languages(where, args)
+	if err != nil {
+		return nil, false, err
+	}
+
+	results := map[string]*SearchResult{}
+
+	// The query is tokenized like the subtitles of each language.
+	for _, lang := range languages {
+		terms := fulltext.Tokens(lang, query)
+		if len(terms) == 0 {
+			continue
+		}
+
+		if err := c.searchLanguage(results, lang, terms, where, args); err != nil {
+			return nil, false, err
+		}
+	}
+
+	ranked := make([]SearchResult, 0, len(results))
+	for _, res := range results {
+		sort.Slice(res.Matches, func(i, j int) bool {
+			return res.Matches[i].Cue.Start < res.Matches[j].Cue.Start
+		})
+		ranked = append(ranked, *res)
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Subtitle.CreatedAt.After(ranked[j].Subtitle.CreatedAt)
+	})
+
+	if len(ranked) > limit {
+		return ranked[:limit], true, nil
+	}
+	return ranked, false, nil
+}
+
+func (c *Catalog) languages(where string, args []any) ([]string, error) {
+	rows, err := c.store.db.Query(`SELECT DISTINCT language FROM subtitles `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query languages: %w", err)
+	}
+	defer rows.Close()
+
+	var languages []string
+	for rows.Next() {
+		var language string
+		if err := rows.Scan(&language); err != nil {
+			return nil, fmt.Errorf("could not scan language: %w", err)
+		}
+		languages = append(languages, language)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not iterate languages: %w", err)
+	}
+	return languages, nil
+}
+
+func (c *Catalog) searchLanguage(results map[string]*SearchResult, language string, terms []string, where string, args []any) error {
+	rows, err := c.store.db.Query(`
+		SELECT c.path, c.cue, c.start_ms, c.end_ms, c.text, matchinfo(cue_terms, 'pcnalx')
+		FROM cue_terms JOIN indexed_cues c ON c.id = cue_terms.docid
+		WHERE cue_terms MATCH ? AND c.path IN (SELECT path FROM subtitles `+where+` AND language = ?)`,
+		append(append([]any{strings.Join(terms, " ")}, args...), language)...,
+	)
+	if err != nil {
+		return fmt.Errorf("could not search cues: %w", err)
+	}
+
+	type hit struct {
+		path  string
+		match Match
+	}
+
+	var hits []hit
+	for rows.Next() {
+		var (
+			h              hit
+			startMS, endMS int64
+			info           []byte
+		)
+
+		if err := rows.Scan(&h.path, &h.match.Cue.Index, &startMS, &endMS, &h.match.Cue.Text, &info); err != nil {
+			rows.Close()
+			return fmt.Errorf("could not scan cue: %w", err)
+		}
+
+		h.match.Cue.Start = time.Duration(startMS) * time.Millisecond
+		h.match.Cue.End = time.Duration(endMS) * time.Millisecond
+		h.match.Score = bm25(info)
+		h.match.Snippet = fulltext.Snippet(language, h.match.Cue.Text, terms)
+		hits = append(hits, h)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("could not iterate cues: %w", err)
+	}
+
+	for _, h := range hits {
+		res, ok := results[h.path]
+		if !ok {
+			subs, err := c.query(`WHERE path = ?`, h.path)
+			if err != nil {
+				return err
+			}
+
+			if len(subs) == 0 {
+				continue
+			}
+
+			res = &SearchResult{Subtitle: subs[0]}
+			results[h.path] = res
+		}
+
+		res.Score += h.match.Score
+		res.Matches = append(res.Matches, h.match)
+	}
+	return nil
+}
+
+// bm25 returns the BM25 score of a matching row from its matchinfo 'pcnalx', the statistics of its
+// single column of terms: the number of phrases, columns and rows, the average and row number of
+// terms, then for each phrase, its hits in the row, in all the rows, and the number of rows it hits.
+func bm25(info []byte) float64 {
+	if len(info)%4 != 0 {
+		return 0
+	}
+
+	values := make([]uint32, len(info)/4)
+	for i := range values {
+		values[i] = binary.NativeEndian.Uint32(info[4*i:])
+	}
+
+	if len(values) < 5 {
+		return 0
+	}
+
+	phrases, rows, avgLength, length := int(values[0]), float64(values[2]), float64(values[3]), float64(values[4])
+	if avgLength == 0 || len(values) < 5+3*phrases {
+		return 0
+	}
+
+	var score float64
+	for p := 0; p < phrases; p++ {
+		x := values[5+3*p:]
+		tf, docs := float64(x[0]), float64(x[2])
+
+		// The variant of the IDF staying positive for the terms found in most of the cues.
+		idf := math.Log(1 + (rows-docs+0.5)/(docs+0.5))
+		score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*length/avgLength))
+	}
+	return score
+}
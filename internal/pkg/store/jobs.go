@@ -0,0 +1,65 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/alesr/videoscriber/internal/pkg/jobs"
+)
+
+// SaveJob inserts or updates the job.
+func (s *Store) SaveJob(job jobs.Job) error {
+	files, err := json.Marshal(job.Files)
+	if err != nil {
+		return fmt.Errorf("could not marshal job files: %w", err)
+	}
+
+	if _, err := s.db.Exec(`
+		INSERT INTO jobs (id, type, owner, state, files, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET state = excluded.state, files = excluded.files, updated_at = excluded.updated_at`,
+		job.ID, job.Type, job.Owner, job.State, files, job.CreatedAt, job.UpdatedAt,
+	); err != nil {
+		return fmt.Errorf("could not save job: %w", err)
+	}
+	return nil
+}
+
+// DeleteJob deletes the job.
+func (s *Store) DeleteJob(id string) error {
+	if _, err := s.db.Exec(`DELETE FROM jobs WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("could not delete job: %w", err)
+	}
+	return nil
+}
+
+// Jobs returns all the jobs, oldest first.
+func (s *Store) Jobs() ([]jobs.Job, error) {
+	rows, err := s.db.Query(`SELECT id, type, owner, state, files, created_at, updated_at FROM jobs ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("could not query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var result []jobs.Job
+
+	for rows.Next() {
+		var (
+			job   jobs.Job
+			files []byte
+		)
+
+		if err := rows.Scan(&job.ID, &job.Type, &job.Owner, &job.State, &files, &job.CreatedAt, &job.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("could not scan job: %w", err)
+		}
+
+		if err := json.Unmarshal(files, &job.Files); err != nil {
+			return nil, fmt.Errorf("could not unmarshal files of job %s: %w", job.ID, err)
+		}
+		result = append(result, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not iterate jobs: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+)
+
+// Orphans are the inconsistencies between the catalog and the storage directory, which Sync repairs.
+type Orphans struct {
+	Untracked []storage.Object // Stored files the catalog doesn't record, e.g. copied to the storage directory.
+	Missing   []storage.Object // Files the catalog records as stored, but removed from the storage directory.
+}
+
+// Orphans compares the catalog with the storage directory, like Sync but without repairing anything.
+func (c *Catalog) Orphans() (Orphans, error) {
+	objects, err := c.objects.List()
+	if err != nil {
+		return Orphans{}, fmt.Errorf("could not list stored files: %w", err)
+	}
+
+	subs, err := c.query(`WHERE status != ?`, StatusDeleted)
+	if err != nil {
+		return Orphans{}, err
+	}
+
+	recorded := make(map[string]bool, len(subs))
+	for _, sub := range subs {
+		recorded[sub.path] = true
+	}
+
+	present := make(map[string]bool, len(objects))
+	orphans := Orphans{Untracked: []storage.Object{}, Missing: []storage.Object{}}
+
+	for _, obj := range objects {
+		present[obj.Path] = true
+
+		if !recorded[obj.Path] {
+			orphans.Untracked = append(orphans.Untracked, obj)
+		}
+	}
+
+	for _, sub := range subs {
+		if !present[sub.path] {
+			orphans.Missing = append(orphans.Missing, sub.Object())
+		}
+	}
+	return orphans, nil
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/qa"
+)
+
+// Passages returns the indexed passages of the subtitle at path. It reports false when the subtitle
+// isn't indexed, or was indexed with another content digest or embedding model.
+func (s *Store) Passages(path, digest, model string) ([]qa.Passage, bool, error) {
+	var indexedDigest, indexedModel string
+
+	err := s.db.QueryRow(`SELECT digest, model FROM embedded_subtitles WHERE path = ?`, path).Scan(&indexedDigest, &indexedModel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("could not query index: %w", err)
+	}
+
+	if indexedDigest != digest || indexedModel != model {
+		return nil, false, nil
+	}
+
+	rows, err := s.db.Query(`SELECT start_ms, end_ms, text, vector FROM passages WHERE path = ? ORDER BY start_ms`, path)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not query passages: %w", err)
+	}
+	defer rows.Close()
+
+	passages := []qa.Passage{}
+	for rows.Next() {
+		var (
+			p              qa.Passage
+			startMS, endMS int64
+			vector         []byte
+		)
+
+		if err := rows.Scan(&startMS, &endMS, &p.Text, &vector); err != nil {
+			return nil, false, fmt.Errorf("could not scan passage: %w", err)
+		}
+
+		p.Start = time.Duration(startMS) * time.Millisecond
+		p.End = time.Duration(endMS) * time.Millisecond
+		p.Vector = decodeVector(vector)
+		passages = append(passages, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("could not iterate passages: %w", err)
+	}
+	return passages, true, nil
+}
+
+// SavePassages replaces the indexed passages of the subtitle at path.
+func (s *Store) SavePassages(path, digest, model string, passages []qa.Passage) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO embedded_subtitles (path, digest, model) VALUES (?, ?, ?)
+		ON CONFLICT (path) DO UPDATE SET digest = excluded.digest, model = excluded.model`,
+		path, digest, model,
+	); err != nil {
+		return fmt.Errorf("could not record index: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM passages WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("could not delete passages: %w", err)
+	}
+
+	for _, p := range passages {
+		if _, err := tx.Exec(
+			`INSERT INTO passages (path, start_ms, end_ms, text, vector) VALUES (?, ?, ?, ?, ?)`,
+			path, p.Start.Milliseconds(), p.End.Milliseconds(), p.Text, encodeVector(p.Vector),
+		); err != nil {
+			return fmt.Errorf("could not insert passage: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit passages: %w", err)
+	}
+	return nil
+}
+
+// encodeVector encodes the vector as little-endian float32s.
+func encodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
+
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
@@ -0,0 +1,253 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+)
+
+// ErrClaimed is returned when a subtitle under review is claimed by another reviewer.
+var ErrClaimed = errors.New("subtitle is claimed by another reviewer")
+
+// Review is a subtitle held for review because its quality is below the threshold.
+type Review struct {
+	Subtitle
+	Score     float64
+	Issues    []string
+	Reviewer  string // Empty until the review is claimed or assigned.
+	ClaimedAt time.Time
+}
+
+// Comment is a remark of a reviewer on a cue of a subtitle.
+type Comment struct {
+	ID        int64
+	Cue       int // Index of the cue, starting at 1.
+	Author    string
+	Text      string
+	CreatedAt time.Time
+}
+
+// Hold marks the subtitle as needing review, hiding it from the listings until it is approved.
+func (c *Catalog) Hold(owner, language, project, fileName string, score float64, issues []string) error {
+	issuesJSON, err := json.Marshal(issues)
+	if err != nil {
+		return fmt.Errorf("could not encode issues: %w", err)
+	}
+
+	path := c.objects.Path(owner, language, project, fileName)
+
+	tx, err := c.store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE subtitles SET status = ? WHERE path = ? AND status = ?`, StatusReview, path, StatusAvailable)
+	if err != nil {
+		return fmt.Errorf("could not hold subtitle: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return storage.ErrNotFound
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO reviews (path, score, issues, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (path) DO UPDATE SET
+			score = excluded.score, issues = excluded.issues, reviewer = '', claimed_at = NULL, created_at = excluded.created_at`,
+		path, score, string(issuesJSON), time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("could not record review: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+	return nil
+}
+
+// reviewedBy restricts the reviews to the subtitles of the owner and the reviews assigned to them.
+const reviewedBy = `AND (s.owner = ? OR (r.reviewer <> '' AND r.reviewer = ?)) `
+
+// Reviews returns the subtitles of the owner waiting for review, and the ones assigned to them, oldest first.
+func (c *Catalog) Reviews(owner string) ([]Review, error) {
+	return c.queryReviews(reviewedBy+`ORDER BY r.created_at`, owner, owner)
+}
+
+// FindReview returns the subtitle under review with the given name, of the owner or assigned to them.
+// An empty language or project matches any; it returns storage.ErrAmbiguous when several subtitles match,
+// as Find does.
+func (c *Catalog) FindReview(owner, name, language, project string) (Review, error) {
+	reviews, err := c.queryReviews(
+		reviewedBy+`AND s.name = ? AND (? = '' OR s.language = ?) AND (? = '' OR s.project = ?) LIMIT 2`,
+		owner, owner, name, language, language, project, project,
+	)
+	if err != nil {
+		return Review{}, err
+	}
+
+	switch len(reviews) {
+	case 0:
+		return Review{}, storage.ErrNotFound
+	case 1:
+		return reviews[0], nil
+	default:
+		return Review{}, fmt.Errorf("%w: %q", storage.ErrAmbiguous, name)
+	}
+}
+
+// Claim assigns the review to the reviewer.
+// Claiming a review already claimed by the same reviewer is a no-op.
+func (c *Catalog) Claim(review Review, reviewer string) (Review, error) {
+	if review.Reviewer == reviewer {
+		return review, nil
+	}
+
+	if review.Reviewer != "" {
+		return Review{}, ErrClaimed
+	}
+
+	review.Reviewer, review.ClaimedAt = reviewer, time.Now().UTC()
+
+	res, err := c.store.db.Exec(`UPDATE reviews SET reviewer = ?, claimed_at = ? WHERE path = ? AND reviewer = ''`,
+		review.Reviewer, review.ClaimedAt, review.path,
+	)
+	if err != nil {
+		return Review{}, fmt.Errorf("could not claim review: %w", err)
+	}
+
+	// Another reviewer claimed it in the meantime.
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return Review{}, ErrClaimed
+	}
+	return review, nil
+}
+
+// Assign assigns the review to the reviewer, replacing the current reviewer if any.
+func (c *Catalog) Assign(review Review, reviewer string) (Review, error) {
+	review.Reviewer, review.ClaimedAt = reviewer, time.Now().UTC()
+
+	if _, err := c.store.db.Exec(`UPDATE reviews SET reviewer = ?, claimed_at = ? WHERE path = ?`,
+		review.Reviewer, review.ClaimedAt, review.path,
+	); err != nil {
+		return Review{}, fmt.Errorf("could not assign review: %w", err)
+	}
+	return review, nil
+}
+
+// AddComment records a comment on a cue of the subtitle under review.
+// Comments are kept once the subtitle is approved.
+func (c *Catalog) AddComment(review Review, comment Comment) (Comment, error) {
+	comment.CreatedAt = time.Now().UTC()
+
+	res, err := c.store.db.Exec(`INSERT INTO review_comments (path, cue, author, text, created_at) VALUES (?, ?, ?, ?, ?)`,
+		review.path, comment.Cue, comment.Author, comment.Text, comment.CreatedAt,
+	)
+	if err != nil {
+		return Comment{}, fmt.Errorf("could not record comment: %w", err)
+	}
+
+	if comment.ID, err = res.LastInsertId(); err != nil {
+		return Comment{}, fmt.Errorf("could not get comment id: %w", err)
+	}
+	return comment, nil
+}
+
+// Comments returns the comments on the subtitle under review, oldest first.
+func (c *Catalog) Comments(review Review) ([]Comment, error) {
+	rows, err := c.store.db.Query(`SELECT id, cue, author, text, created_at FROM review_comments WHERE path = ? ORDER BY id`, review.path)
+	if err != nil {
+		return nil, fmt.Errorf("could not query comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []Comment
+
+	for rows.Next() {
+		var comment Comment
+		if err := rows.Scan(&comment.ID, &comment.Cue, &comment.Author, &comment.Text, &comment.CreatedAt); err != nil {
+			return nil, fmt.Errorf("could not scan comment: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not iterate comments: %w", err)
+	}
+	return comments, nil
+}
+
+// Revise replaces the data of a subtitle under review, keeping its metadata.
+func (c *Catalog) Revise(review Review, data []byte) error {
+	return c.Replace(review.Object(), data)
+}
+
+// Approve releases the subtitle from review, making it available in the listings.
+func (c *Catalog) Approve(review Review) error {
+	tx, err := c.store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE subtitles SET status = ? WHERE path = ? AND status = ?`, StatusAvailable, review.path, StatusReview); err != nil {
+		return fmt.Errorf("could not approve subtitle: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM reviews WHERE path = ?`, review.path); err != nil {
+		return fmt.Errorf("could not delete review: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (c *Catalog) queryReviews(where string, args ...any) ([]Review, error) {
+	rows, err := c.store.db.Query(`
+		SELECT s.path, s.name, s.owner, s.project, s.language, s.format, s.size, s.original_name, s.duration_ms, s.cue_count, s.job_id, s.status, s.created_at,
+			r.score, r.issues, r.reviewer, r.claimed_at
+		FROM reviews r JOIN subtitles s ON s.path = r.path
+		WHERE s.status = ? `+where, append([]any{StatusReview}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query reviews: %w", err)
+	}
+	defer rows.Close()
+
+	var reviews []Review
+
+	for rows.Next() {
+		var (
+			review     Review
+			durationMS int64
+			issues     string
+			claimedAt  sql.NullTime
+		)
+
+		if err := rows.Scan(
+			&review.path, &review.Name, &review.Owner, &review.Project, &review.Language, &review.Format, &review.Size,
+			&review.OriginalName, &durationMS, &review.Cues, &review.JobID, &review.Status, &review.CreatedAt,
+			&review.Score, &issues, &review.Reviewer, &claimedAt,
+		); err != nil {
+			return nil, fmt.Errorf("could not scan review: %w", err)
+		}
+
+		if err := json.Unmarshal([]byte(issues), &review.Issues); err != nil {
+			return nil, fmt.Errorf("could not decode issues: %w", err)
+		}
+
+		review.Duration = time.Duration(durationMS) * time.Millisecond
+		review.ClaimedAt = claimedAt.Time
+		reviews = append(reviews, review)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not iterate reviews: %w", err)
+	}
+	return reviews, nil
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+)
+
+// Share is a read-only link to a subtitle.
+type Share struct {
+	Token     string
+	CreatedAt time.Time
+	ExpiresAt time.Time // Zero when the share never expires.
+	VideoURL  string    // Video the subtitle is embedded with, if any.
+}
+
+// Share creates a share of the subtitle stored at obj, valid until expiresAt or forever if zero.
+// The video URL pairs the subtitle with its video for embedding, and can be empty.
+func (c *Catalog) Share(obj storage.Object, expiresAt time.Time, videoURL string) (Share, error) {
+	token, err := newToken()
+	if err != nil {
+		return Share{}, err
+	}
+
+	share := Share{Token: token, CreatedAt: time.Now().UTC(), ExpiresAt: expiresAt.UTC(), VideoURL: videoURL}
+
+	var expires sql.NullTime
+	if !expiresAt.IsZero() {
+		expires = sql.NullTime{Time: share.ExpiresAt, Valid: true}
+	}
+
+	if _, err := c.store.db.Exec(`INSERT INTO shares (token, path, created_at, expires_at, video_url) VALUES (?, ?, ?, ?, ?)`,
+		share.Token, obj.Path, share.CreatedAt, expires, share.VideoURL,
+	); err != nil {
+		return Share{}, fmt.Errorf("could not record share: %w", err)
+	}
+	return share, nil
+}
+
+// Shared returns the share and its subtitle. Expired and revoked shares,
+// and shares of subtitles that are no longer available, are not found.
+func (c *Catalog) Shared(token string) (Subtitle, Share, error) {
+	var (
+		share   Share
+		path    string
+		expires sql.NullTime
+	)
+
+	err := c.store.db.QueryRow(`
+		SELECT token, path, created_at, expires_at, video_url FROM shares
+		WHERE token = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)`,
+		token, time.Now().UTC(),
+	).Scan(&share.Token, &path, &share.CreatedAt, &expires, &share.VideoURL)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Subtitle{}, Share{}, storage.ErrNotFound
+		}
+		return Subtitle{}, Share{}, fmt.Errorf("could not query share: %w", err)
+	}
+	share.ExpiresAt = expires.Time
+
+	subs, err := c.query(`WHERE path = ? AND status = ?`, path, StatusAvailable)
+	if err != nil {
+		return Subtitle{}, Share{}, err
+	}
+
+	if len(subs) == 0 {
+		return Subtitle{}, Share{}, storage.ErrNotFound
+	}
+	return subs[0], share, nil
+}
+
+// Unshare revokes the share.
+func (c *Catalog) Unshare(token string) error {
+	res, err := c.store.db.Exec(`UPDATE shares SET revoked_at = ? WHERE token = ? AND revoked_at IS NULL`, time.Now().UTC(), token)
+	if err != nil {
+		return fmt.Errorf("could not revoke share: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate share token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
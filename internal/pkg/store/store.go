@@ -0,0 +1,185 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const schema string = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id         TEXT PRIMARY KEY,
+	state      TEXT NOT NULL,
+	files      TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS jobs_created_at ON jobs (created_at);
+
+CREATE TABLE IF NOT EXISTS subtitles (
+	path          TEXT PRIMARY KEY,
+	name          TEXT NOT NULL,
+	project       TEXT NOT NULL,
+	language      TEXT NOT NULL,
+	format        TEXT NOT NULL,
+	size          INTEGER NOT NULL,
+	original_name TEXT NOT NULL DEFAULT '',
+	duration_ms   INTEGER NOT NULL DEFAULT 0,
+	job_id        TEXT NOT NULL DEFAULT '',
+	status        TEXT NOT NULL,
+	created_at    TIMESTAMP NOT NULL,
+	deleted_at    TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS subtitles_name ON subtitles (name);
+
+CREATE TABLE IF NOT EXISTS reviews (
+	path       TEXT PRIMARY KEY REFERENCES subtitles (path) ON DELETE CASCADE,
+	score      REAL NOT NULL,
+	issues     TEXT NOT NULL,
+	reviewer   TEXT NOT NULL DEFAULT '',
+	claimed_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS review_comments (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	path       TEXT NOT NULL REFERENCES subtitles (path) ON DELETE CASCADE,
+	cue        INTEGER NOT NULL,
+	author     TEXT NOT NULL,
+	text       TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS review_comments_path ON review_comments (path);
+
+CREATE TABLE IF NOT EXISTS shares (
+	token      TEXT PRIMARY KEY,
+	path       TEXT NOT NULL REFERENCES subtitles (path) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS embedded_subtitles (
+	path   TEXT PRIMARY KEY REFERENCES subtitles (path) ON DELETE CASCADE,
+	digest TEXT NOT NULL,
+	model  TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS passages (
+	path     TEXT NOT NULL REFERENCES embedded_subtitles (path) ON DELETE CASCADE,
+	start_ms INTEGER NOT NULL,
+	end_ms   INTEGER NOT NULL,
+	text     TEXT NOT NULL,
+	vector   BLOB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS passages_path ON passages (path);
+
+-- The cues of the SRT and VTT subtitles, with their terms in a full-text table of the same rowids.
+CREATE TABLE IF NOT EXISTS indexed_subtitles (
+	path TEXT PRIMARY KEY REFERENCES subtitles (path) ON DELETE CASCADE,
+	size INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS indexed_cues (
+	id       INTEGER PRIMARY KEY,
+	path     TEXT NOT NULL REFERENCES indexed_subtitles (path) ON DELETE CASCADE,
+	cue      INTEGER NOT NULL,
+	start_ms INTEGER NOT NULL,
+	end_ms   INTEGER NOT NULL,
+	text     TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS indexed_cues_path ON indexed_cues (path);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS cue_terms USING fts4 (terms, tokenize=simple);
+
+CREATE TABLE IF NOT EXISTS usage (
+	owner       TEXT NOT NULL,
+	month       TEXT NOT NULL,
+	duration_ms INTEGER NOT NULL,
+	PRIMARY KEY (owner, month)
+);
+`
+
+// migrations alter the schema of databases created by previous versions.
+// The user_version pragma records how many of them were applied.
+var migrations = []string{
+	`ALTER TABLE shares ADD COLUMN video_url TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE jobs ADD COLUMN type TEXT NOT NULL DEFAULT 'transcription'`,
+	`ALTER TABLE subtitles ADD COLUMN owner TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE subtitles ADD COLUMN analytics TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE subtitles ADD COLUMN cue_count INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE jobs ADD COLUMN owner TEXT NOT NULL DEFAULT ''`,
+}
+
+// Store persists the metadata of jobs and subtitles in an embedded SQLite database.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens the database at path, creating it and its schema if needed.
+func Open(path string) (*Store, error) {
+	return open("file:" + path + "?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=on")
+}
+
+// OpenMemory opens a new empty database in memory, e.g. to embed the server in other programs or test it
+// without a data directory. The database is gone once closed.
+func OpenMemory() (*Store, error) {
+	// Each connection to :memory: has its own database, and the store shares a single one.
+	return open("file::memory:?_foreign_keys=on")
+}
+
+func open(dsn string) (*Store, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("could not open database: %w", err)
+	}
+
+	// SQLite allows a single writer, so sharing one connection avoids busy errors.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not create schema: %w", err)
+	}
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("could not read schema version: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		if _, err := db.Exec(migrations[i]); err != nil {
+			return fmt.Errorf("could not apply migration %d: %w", i+1, err)
+		}
+
+		if _, err := db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			return fmt.Errorf("could not update schema version: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Ping checks that the database is reachable.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AddUsage adds the transcribed duration to the usage of the owner in the month, e.g. "2024-05".
+func (s *Store) AddUsage(owner, month string, d time.Duration) error {
+	if _, err := s.db.Exec(`
+		INSERT INTO usage (owner, month, duration_ms) VALUES (?, ?, ?)
+		ON CONFLICT (owner, month) DO UPDATE SET duration_ms = duration_ms + excluded.duration_ms`,
+		owner, month, d.Milliseconds(),
+	); err != nil {
+		return fmt.Errorf("could not add usage: %w", err)
+	}
+	return nil
+}
+
+// Usage returns the duration transcribed by the owner in the month.
+func (s *Store) Usage(owner, month string) (time.Duration, error) {
+	var ms int64
+
+	err := s.db.QueryRow(`SELECT duration_ms FROM usage WHERE owner = ? AND month = ?`, owner, month).Scan(&ms)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("could not query usage: %w", err)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
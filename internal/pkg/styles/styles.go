@@ -0,0 +1,212 @@
+package styles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	PositionBottom string = "bottom"
+	PositionMiddle string = "middle"
+	PositionTop    string = "top"
+)
+
+var (
+	// ErrNotFound is returned when the profile does not exist.
+	ErrNotFound = errors.New("styling profile not found")
+
+	// ErrInvalidProfile is returned when a profile fails validation.
+	ErrInvalidProfile = errors.New("invalid styling profile")
+
+	nameRe  = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	colorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	fontRe  = regexp.MustCompile(`^[\p{L}0-9 _-]{1,64}$`)
+
+	// alignments maps positions to ASS numpad alignments (centered horizontally).
+	alignments = map[string]int{
+		PositionBottom: 2,
+		PositionMiddle: 5,
+		PositionTop:    8,
+	}
+)
+
+// Profile describes how burned-in subtitles look.
+type Profile struct {
+	Name         string  `json:"name"`
+	Font         string  `json:"font"`
+	FontSize     int     `json:"font_size"`
+	Color        string  `json:"color"`         // Text color, e.g. #FFFFFF.
+	OutlineColor string  `json:"outline_color"` // Also used as the box color.
+	Outline      float64 `json:"outline"`       // Outline width in pixels.
+	Shadow       float64 `json:"shadow"`
+	Box          bool    `json:"box"` // Opaque box behind the text instead of an outline.
+	Position     string  `json:"position"`
+	MarginV      int     `json:"margin_v"` // Vertical safe margin in pixels.
+	MarginH      int     `json:"margin_h"` // Horizontal safe margin in pixels.
+}
+
+// Validate checks the profile fields.
+func (p Profile) Validate() error {
+	switch {
+	case !nameRe.MatchString(p.Name):
+		return fmt.Errorf("%w: name %q", ErrInvalidProfile, p.Name)
+	case !fontRe.MatchString(p.Font):
+		return fmt.Errorf("%w: font %q", ErrInvalidProfile, p.Font)
+	case p.FontSize <= 0:
+		return fmt.Errorf("%w: font size must be positive", ErrInvalidProfile)
+	case !colorRe.MatchString(p.Color) || !colorRe.MatchString(p.OutlineColor):
+		return fmt.Errorf("%w: colors must be formatted as #RRGGBB", ErrInvalidProfile)
+	case p.Outline < 0 || p.Shadow < 0 || p.MarginV < 0 || p.MarginH < 0:
+		return fmt.Errorf("%w: outline, shadow and margins must not be negative", ErrInvalidProfile)
+	}
+
+	if _, ok := alignments[p.Position]; !ok {
+		return fmt.Errorf("%w: position must be one of bottom, middle or top", ErrInvalidProfile)
+	}
+	return nil
+}
+
+// ForceStyle returns the profile as the force_style option of ffmpeg's subtitles filter.
+func (p Profile) ForceStyle() string {
+	borderStyle := 1
+	if p.Box {
+		borderStyle = 3
+	}
+
+	fields := []string{
+		"FontName=" + p.Font,
+		fmt.Sprintf("FontSize=%d", p.FontSize),
+		"PrimaryColour=" + assColor(p.Color),
+		"OutlineColour=" + assColor(p.OutlineColor),
+		"BackColour=" + assColor(p.OutlineColor),
+		fmt.Sprintf("BorderStyle=%d", borderStyle),
+		fmt.Sprintf("Outline=%g", p.Outline),
+		fmt.Sprintf("Shadow=%g", p.Shadow),
+		fmt.Sprintf("Alignment=%d", alignments[p.Position]),
+		fmt.Sprintf("MarginV=%d", p.MarginV),
+		fmt.Sprintf("MarginL=%d", p.MarginH),
+		fmt.Sprintf("MarginR=%d", p.MarginH),
+	}
+	return strings.Join(fields, ",")
+}
+
+// assColor converts #RRGGBB into the &HAABBGGRR notation used by ASS styles.
+func assColor(hex string) string {
+	hex = strings.ToUpper(strings.TrimPrefix(hex, "#"))
+	return "&H00" + hex[4:6] + hex[2:4] + hex[0:2]
+}
+
+// Store persists the styling profiles as JSON.
+type Store struct {
+	mu       sync.RWMutex
+	path     string
+	profiles map[string]Profile
+}
+
+// NewStore returns a store backed by the file at path.
+func NewStore(path string) (*Store, error) {
+	s := Store{
+		path:     path,
+		profiles: make(map[string]Profile),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not read styling profiles: %w", err)
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.profiles); err != nil {
+			return nil, fmt.Errorf("could not unmarshal styling profiles: %w", err)
+		}
+	}
+	return &s, nil
+}
+
+// List returns the profiles sorted by name.
+func (s *Store) List() []Profile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	profiles := make([]Profile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		profiles = append(profiles, p)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles
+}
+
+// Get returns the profile with the given name.
+func (s *Store) Get(name string) (Profile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.profiles[name]
+	if !ok {
+		return Profile{}, ErrNotFound
+	}
+	return p, nil
+}
+
+// Put creates or replaces a profile.
+func (s *Store) Put(p Profile) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.profiles[p.Name]
+	s.profiles[p.Name] = p
+
+	if err := s.save(); err != nil {
+		if existed {
+			s.profiles[p.Name] = previous
+		} else {
+			delete(s.profiles, p.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a profile.
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.profiles[name]
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(s.profiles, name)
+
+	if err := s.save(); err != nil {
+		s.profiles[name] = previous
+		return err
+	}
+	return nil
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal styling profiles: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, data, 0o644); err != nil {
+		return fmt.Errorf("could not write styling profiles: %w", err)
+	}
+	return nil
+}
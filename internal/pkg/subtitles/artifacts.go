@@ -0,0 +1,60 @@
+package subtitles
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// artifactSuffixes are the suffixes of the artifacts stored alongside the subtitles, after the base of their name.
+var artifactSuffixes = []string{
+	".pipeline.json",
+	".source.wav",
+	".analytics.json",
+	".timeline.json",
+	".minutes.json",
+	".chapters.json",
+	".trace.json",
+	".anon.srt",
+}
+
+// ArtifactOf returns the names the subtitle the named artifact was stored alongside can have, one per format,
+// or nil when the name isn't the name of an artifact, e.g. for subtitles and their translations.
+// An artifact is orphaned once none of them is stored.
+func ArtifactOf(name string) []string {
+	base, ok := artifactBase(name)
+	if !ok || base == "" {
+		return nil
+	}
+
+	names := make([]string, 0, len(formats))
+	for f := range formats {
+		names = append(names, base+f.Extension())
+	}
+	slices.Sort(names)
+	return names
+}
+
+// IsSubtitle reports whether the named file is a subtitle or a translation, in any format,
+// and not one of the artifacts stored alongside them.
+func IsSubtitle(name string) bool {
+	_, ok := FormatOf(name)
+	return ok && ArtifactOf(name) == nil
+}
+
+func artifactBase(name string) (string, bool) {
+	for _, suffix := range artifactSuffixes {
+		if base, ok := strings.CutSuffix(name, suffix); ok {
+			return base, true
+		}
+	}
+
+	// The kept videos have the extension of the uploaded file, see VideoName, which is never
+	// the one of a subtitle, e.g. of the subtitle of an upload named clip.video.mp4.
+	if _, ok := FormatOf(name); ok {
+		return "", false
+	}
+
+	ext := path.Ext(name)
+	return strings.CutSuffix(strings.TrimSuffix(name, ext), ".video")
+}
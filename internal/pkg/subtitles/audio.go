@@ -0,0 +1,133 @@
+package subtitles
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// wavDuration returns the duration of WAV audio data, or zero if the header can't be read.
+func wavDuration(data []byte) time.Duration {
+	_, d := wavInfo(data)
+	return d
+}
+
+// wavInfo returns the byte rate and duration of WAV audio data, or zeros if the header can't be read.
+func wavInfo(data []byte) (int, time.Duration) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return 0, 0
+	}
+
+	var byteRate uint32
+
+	// Walk the chunks to find the format and the data.
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := offset + 8
+
+		switch id {
+		case "fmt ":
+			if body+12 > len(data) {
+				return 0, 0
+			}
+			byteRate = binary.LittleEndian.Uint32(data[body+8 : body+12])
+		case "data":
+			if byteRate == 0 {
+				return 0, 0
+			}
+
+			// ffmpeg may leave the size unset when streaming.
+			if size == 0 || body+size > len(data) {
+				size = len(data) - body
+			}
+			return int(byteRate), time.Duration(float64(size) / float64(byteRate) * float64(time.Second))
+		}
+
+		offset = body + size + size%2
+	}
+	return 0, 0
+}
+
+// concatWAV concatenates WAV audio data sharing the same format.
+func concatWAV(parts [][]byte) ([]byte, error) {
+	var (
+		format []byte
+		pcm    [][]byte
+		size   int
+	)
+
+	for i, data := range parts {
+		f, samples, err := wavChunks(data)
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", i, err)
+		}
+
+		if format == nil {
+			format = f
+		} else if !bytes.Equal(format, f) {
+			return nil, fmt.Errorf("part %d: audio format differs from the first part", i)
+		}
+
+		pcm = append(pcm, samples)
+		size += len(samples)
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(4+8+len(format)+len(format)%2+8+size))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(format)))
+	buf.Write(format)
+	if len(format)%2 == 1 {
+		buf.WriteByte(0)
+	}
+
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(size))
+	for _, samples := range pcm {
+		buf.Write(samples)
+	}
+	return buf.Bytes(), nil
+}
+
+// wavChunks returns the bodies of the format and data chunks of WAV audio data.
+func wavChunks(data []byte) ([]byte, []byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, nil, errors.New("not a WAV file")
+	}
+
+	var format []byte
+
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := offset + 8
+
+		switch id {
+		case "fmt ":
+			if body+size > len(data) {
+				return nil, nil, errors.New("truncated format chunk")
+			}
+			format = data[body : body+size]
+		case "data":
+			if format == nil {
+				return nil, nil, errors.New("data chunk before format chunk")
+			}
+
+			// ffmpeg may leave the size unset when streaming.
+			if size == 0 || body+size > len(data) {
+				size = len(data) - body
+			}
+			return format, data[body : body+size], nil
+		}
+
+		offset = body + size + size%2
+	}
+	return nil, nil, errors.New("no data chunk")
+}
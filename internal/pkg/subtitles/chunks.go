@@ -0,0 +1,134 @@
+package subtitles
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+const (
+	// maxAudioSize is the largest file accepted by the Whisper API.
+	maxAudioSize int = 25 << 20 // 25MB
+
+	// chunkOverlap is how much consecutive chunks overlap, so words at the boundaries aren't cut.
+	chunkOverlap time.Duration = 5 * time.Second
+
+	// chunkHeadroom keeps chunks safely under the size limit (WAV header, rounding).
+	chunkHeadroom int = 64 << 10 // 64KB
+)
+
+// chunk is a part of the audio to transcribe separately.
+type chunk struct {
+	start    time.Duration
+	duration time.Duration
+}
+
+// transcribe transcribes the audio, splitting it into overlapping chunks
+// when it is larger than what the provider accepts.
+func (s *Subtitler) transcribe(ctx context.Context, audioFilePath string, audioData []byte, in *Input) ([]byte, error) {
+	if len(audioData) <= maxAudioSize {
+		return s.transcribeAudio(ctx, audioFilePath, audioData, in)
+	}
+
+	byteRate, total := wavInfo(audioData)
+	if byteRate == 0 {
+		return nil, fmt.Errorf("audio is larger than %d bytes and its format can't be split", maxAudioSize)
+	}
+
+	chunks := splitChunks(total, time.Duration(float64(maxAudioSize-chunkHeadroom)/float64(byteRate)*float64(time.Second)))
+
+	s.logger.Debug("Transcribing audio in chunks", slog.String("filename", in.FileName), slog.Int("chunks", len(chunks)))
+
+	parts := make([][]srt.Cue, 0, len(chunks))
+
+	for _, c := range chunks {
+		cues, err := s.transcribeChunk(ctx, audioFilePath, c, in)
+		if err != nil {
+			return nil, fmt.Errorf("could not transcribe chunk at %s: %w", c.start, err)
+		}
+		parts = append(parts, cues)
+	}
+	return srt.Format(stitch(chunks, parts)), nil
+}
+
+func (s *Subtitler) transcribeChunk(ctx context.Context, audioFilePath string, c chunk, in *Input) ([]srt.Cue, error) {
+	chunkPath, err := s.audioExtractor.ExtractSegment(ctx, audioFilePath, c.start, c.duration)
+	if err != nil {
+		return nil, fmt.Errorf("could not extract chunk: %w", err)
+	}
+	defer s.removeFile(chunkPath)
+
+	chunkData, err := readFile(chunkPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read chunk: %w", err)
+	}
+
+	subData, err := s.transcribeAudio(ctx, chunkPath, chunkData, in)
+	if err != nil {
+		return nil, err
+	}
+
+	cues, err := srt.Parse(subData)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse chunk subtitle: %w", err)
+	}
+
+	for i := range cues {
+		cues[i].Start += c.start
+		cues[i].End += c.start
+	}
+	return cues, nil
+}
+
+// splitChunks splits the total duration into chunks of at most size, overlapping by chunkOverlap.
+func splitChunks(total, size time.Duration) []chunk {
+	step := size - chunkOverlap
+	if step <= 0 {
+		step = size
+	}
+
+	var chunks []chunk
+	for start := time.Duration(0); start < total; start += step {
+		chunks = append(chunks, chunk{
+			start:    start,
+			duration: min(size, total-start),
+		})
+
+		if start+size >= total {
+			break
+		}
+	}
+	return chunks
+}
+
+// stitch merges the cues of overlapping chunks. Within an overlap, cues starting before
+// its middle are taken from the earlier chunk and the others from the later one.
+func stitch(chunks []chunk, parts [][]srt.Cue) []srt.Cue {
+	var merged []srt.Cue
+
+	for i, cues := range parts {
+		lower := time.Duration(0)
+		if i > 0 {
+			lower = chunks[i].start + chunkOverlap/2
+		}
+
+		upper := time.Duration(1<<63 - 1)
+		if i < len(chunks)-1 {
+			upper = chunks[i+1].start + chunkOverlap/2
+		}
+
+		for _, cue := range cues {
+			if cue.Start >= lower && cue.Start < upper {
+				merged = append(merged, cue)
+			}
+		}
+	}
+
+	for i := range merged {
+		merged[i].Index = i + 1
+	}
+	return merged
+}
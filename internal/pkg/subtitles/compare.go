@@ -0,0 +1,85 @@
+package subtitles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TranscribeWith transcribes the input with each of the providers without storing the results, and returns
+// the duration of the audio, which each of them transcribed. The audio is extracted once and shared by all
+// providers. Results are keyed by provider name.
+func (s *Subtitler) TranscribeWith(ctx context.Context, in *Input, providers []string) (map[string][]byte, time.Duration, error) {
+	for _, name := range providers {
+		if !s.HasProvider(name) {
+			return nil, 0, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+		}
+	}
+
+	if !in.prepared() {
+		if err := s.Prepare(in); err != nil {
+			return nil, 0, err
+		}
+	}
+	defer s.removeInputFiles(in)
+
+	if in.Language == "" {
+		in.Language = DefaultLanguage
+	}
+
+	if err := s.acquire(ctx); err != nil {
+		return nil, 0, err
+	}
+	defer s.release()
+
+	audioFilePath, err := s.extractAudio(ctx, in)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not extract audio: %w", err)
+	}
+	defer s.removeFile(audioFilePath)
+
+	audioData, err := readFile(audioFilePath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not read audio file: %w", err)
+	}
+
+	duration := wavDuration(audioData)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make(map[string][]byte, len(providers))
+		errs    []error
+	)
+
+	for _, name := range providers {
+		wg.Add(1)
+
+		go func(name string) {
+			defer wg.Done()
+
+			providerIn := *in
+			providerIn.Provider = name
+
+			data, err := s.transcribe(ctx, audioFilePath, audioData, &providerIn)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("provider %s: %w", name, err))
+				return
+			}
+			results[name] = data
+		}(name)
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, 0, fmt.Errorf("could not transcribe with all providers: %w", errors.Join(errs...))
+	}
+	return results, duration, nil
+}
@@ -0,0 +1,44 @@
+package subtitles
+
+import (
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/analytics"
+)
+
+// Stage is a processing stage of an input file.
+type Stage string
+
+const (
+	StageExtracting   Stage = "extracting"
+	StageScheduled    Stage = "scheduled"
+	StageTranscribing Stage = "transcribing"
+	StageDone         Stage = "done"
+	StageFailed       Stage = "failed"
+)
+
+// Event reports the progress of an input file.
+type Event struct {
+	FileName string
+	Stage    Stage
+	Progress float64 // Fraction of the stage completed, from 0 to 1, when known.
+	Err      error
+
+	// Subtitle is the name of the stored subtitle and Duration the duration
+	// of the transcribed audio, set once the file is done.
+	Subtitle string
+	Duration time.Duration
+
+	// Usage is the duration of the audio transcribed by the providers, set once the file is done.
+	// Cues transcribed again in a second pass make it longer than Duration.
+	Usage time.Duration
+
+	// ScheduledAt is when the transcription starts, set when it is deferred.
+	ScheduledAt time.Time
+
+	// Artifacts are the names of the files stored alongside the subtitle, e.g. its timeline.
+	Artifacts []string
+
+	// Analytics are the speaking analytics of the subtitle, set once the file is done.
+	Analytics *analytics.Analytics
+}
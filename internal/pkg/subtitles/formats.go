@@ -0,0 +1,91 @@
+package subtitles
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+// Format is the output format of a subtitle.
+type Format string
+
+const (
+	FormatSRT  Format = "srt"
+	FormatVTT  Format = "vtt"
+	FormatText Format = "txt" // Paragraphs of the transcript.
+	FormatHTML Format = "html"
+	FormatDOCX Format = "docx" // Paragraphs of the transcript, as a Word document.
+	FormatTSV  Format = "tsv"
+	FormatJSON Format = "json" // Whisper's verbose JSON.
+)
+
+var formats = map[Format]string{
+	FormatSRT:  "application/x-subrip",
+	FormatVTT:  "text/vtt; charset=utf-8",
+	FormatText: "text/plain; charset=utf-8",
+	FormatHTML: "text/html; charset=utf-8",
+	FormatDOCX: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	FormatTSV:  "text/tab-separated-values; charset=utf-8",
+	FormatJSON: "application/json",
+}
+
+// ParseFormat validates the format name. An empty name is SRT.
+func ParseFormat(name string) (Format, error) {
+	if name == "" {
+		return FormatSRT, nil
+	}
+
+	f := Format(strings.ToLower(name))
+	if _, ok := formats[f]; !ok {
+		return "", fmt.Errorf("unsupported format %q", name)
+	}
+	return f, nil
+}
+
+// FormatOf returns the format of a stored subtitle file according to its extension.
+func FormatOf(fileName string) (Format, bool) {
+	f := Format(strings.TrimPrefix(path.Ext(fileName), "."))
+	_, ok := formats[f]
+	return f, ok
+}
+
+// Extension returns the file extension of the format, including the dot.
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// ContentType returns the media type used when serving files of the format.
+func (f Format) ContentType() string {
+	return formats[f]
+}
+
+// convert encodes the SRT data produced by the providers in the given format.
+func convert(subData []byte, f Format, language string) ([]byte, error) {
+	if f == FormatSRT {
+		return subData, nil
+	}
+
+	cues, err := srt.Parse(subData)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse subtitle: %w", err)
+	}
+
+	switch f {
+	case FormatVTT:
+		return srt.FormatVTT(cues), nil
+	case FormatText:
+		return srt.FormatText(cues), nil
+	case FormatHTML:
+		return srt.FormatHTML(cues, language), nil
+	case FormatDOCX:
+		return srt.FormatDOCX(cues, language)
+	case FormatTSV:
+		return srt.FormatTSV(cues), nil
+	case FormatJSON:
+		return srt.FormatVerboseJSON(cues, language)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", f)
+	}
+}
@@ -0,0 +1,98 @@
+package subtitles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Transcription is the subtitle of an input transcribed without storing it.
+type Transcription struct {
+	Subtitle []byte        // In the format of the input.
+	Duration time.Duration // Of the transcribed audio.
+	Usage    time.Duration // Of the audio transcribed by the providers, see Event.
+	Provider string
+	Model    string // When the provider tells it.
+}
+
+// Transcribe transcribes the input with the same providers, preprocessing and concurrency limit as
+// the files of GenerateFromAudioData, but returns its subtitle instead of storing it, for the tools
+// using the server as a transcription gateway. The steps following the transcription aren't run,
+// and the transcription is never deferred by the scheduler.
+func (s *Subtitler) Transcribe(ctx context.Context, in *Input) (Transcription, error) {
+	if len(in.Parts) > 0 {
+		return Transcription{}, errors.New("recordings can not be transcribed without storing them")
+	}
+
+	if in.Provider != "" && !s.HasProvider(in.Provider) {
+		return Transcription{}, fmt.Errorf("%w: %q", ErrUnknownProvider, in.Provider)
+	}
+
+	if !in.prepared() {
+		if err := s.Prepare(in); err != nil {
+			return Transcription{}, err
+		}
+	}
+	defer s.removeInputFiles(in)
+
+	if in.Language == "" {
+		in.Language = DefaultLanguage
+	}
+
+	if !SupportedLanguage(in.Language) {
+		return Transcription{}, fmt.Errorf("unsupported language %q", in.Language)
+	}
+
+	if in.Format == "" {
+		in.Format = FormatSRT
+	}
+
+	if in.Task == "" {
+		in.Task = TaskTranscribe
+	}
+	in.Urgent = true
+
+	if err := s.acquire(ctx); err != nil {
+		return Transcription{}, err
+	}
+	defer s.release()
+
+	audioFilePath, err := s.extractAudio(ctx, in)
+	if err != nil {
+		return Transcription{}, fmt.Errorf("could not extract audio: %w", err)
+	}
+	defer s.removeFile(audioFilePath)
+
+	audioData, err := readFile(audioFilePath)
+	if err != nil {
+		return Transcription{}, fmt.Errorf("could not read audio file: %w", err)
+	}
+
+	in.duration = wavDuration(audioData)
+
+	// The transcription is accounted in the limits of the scheduling policy, like the others.
+	s.scheduler.Reserve(in.Priority, in.Urgent, in.duration)
+
+	subData, err := s.transcribe(ctx, audioFilePath, audioData, in)
+	if err != nil {
+		return Transcription{}, fmt.Errorf("could not generate subtitle: %w", err)
+	}
+
+	if subData, err = s.repair(subData, in); err != nil {
+		return Transcription{}, fmt.Errorf("could not repair subtitle: %w", err)
+	}
+
+	outData, err := convert(subData, in.Format, in.OutputLanguage())
+	if err != nil {
+		return Transcription{}, fmt.Errorf("could not convert subtitle to %s: %w", in.Format, err)
+	}
+
+	return Transcription{
+		Subtitle: outData,
+		Duration: in.duration,
+		Usage:    in.usage(),
+		Provider: in.provider,
+		Model:    in.model,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package subtitles
+
+import "github.com/alesr/whisperclient"
+
+// DefaultLanguage is the transcription language used when none is given.
+const DefaultLanguage string = whisperclient.LanguagePortuguese
+
+// languages lists the ISO-639-1 codes of the languages supported by Whisper.
+var languages = map[string]string{
+	"af": "Afrikaans",
+	"ar": "Arabic",
+	"hy": "Armenian",
+	"az": "Azerbaijani",
+	"be": "Belarusian",
+	"bs": "Bosnian",
+	"bg": "Bulgarian",
+	"ca": "Catalan",
+	"zh": "Chinese",
+	"hr": "Croatian",
+	"cs": "Czech",
+	"da": "Danish",
+	"nl": "Dutch",
+	"en": "English",
+	"et": "Estonian",
+	"fi": "Finnish",
+	"fr": "French",
+	"gl": "Galician",
+	"de": "German",
+	"el": "Greek",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"hu": "Hungarian",
+	"is": "Icelandic",
+	"id": "Indonesian",
+	"it": "Italian",
+	"ja": "Japanese",
+	"kn": "Kannada",
+	"kk": "Kazakh",
+	"ko": "Korean",
+	"lv": "Latvian",
+	"lt": "Lithuanian",
+	"mk": "Macedonian",
+	"ms": "Malay",
+	"mr": "Marathi",
+	"mi": "Maori",
+	"ne": "Nepali",
+	"no": "Norwegian",
+	"fa": "Persian",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ro": "Romanian",
+	"ru": "Russian",
+	"sr": "Serbian",
+	"sk": "Slovak",
+	"sl": "Slovenian",
+	"es": "Spanish",
+	"sw": "Swahili",
+	"sv": "Swedish",
+	"tl": "Tagalog",
+	"ta": "Tamil",
+	"th": "Thai",
+	"tr": "Turkish",
+	"uk": "Ukrainian",
+	"ur": "Urdu",
+	"vi": "Vietnamese",
+	"cy": "Welsh",
+}
+
+// SupportedLanguage reports whether Whisper can transcribe the language.
+func SupportedLanguage(code string) bool {
+	_, ok := languages[code]
+	return ok
+}
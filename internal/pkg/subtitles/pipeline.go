@@ -0,0 +1,135 @@
+package subtitles
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+// Pipeline is the configuration a subtitle was generated with. It is stored alongside the subtitle
+// (.pipeline.json), with the transcribed audio (.source.wav) when kept, so that the subtitle
+// can be reprocessed with the same configuration, e.g. for audits.
+type Pipeline struct {
+	Source         string            `json:"source,omitempty"` // Name of the stored audio. Empty when not kept.
+	Video          string            `json:"video,omitempty"`  // Name of the stored video. Empty when not kept.
+	Provider       string            `json:"provider"`         // Provider that transcribed the audio, once routed.
+	Model          string            `json:"model,omitempty"`
+	Language       string            `json:"language"`
+	Format         Format            `json:"format"`
+	WordTimestamps bool              `json:"word_timestamps"`
+	Diarize        bool              `json:"diarize"`
+	Prompt         string            `json:"prompt,omitempty"`
+	Task           Task              `json:"task,omitempty"`
+	Translations   []string          `json:"translations,omitempty"`
+	Multilingual   bool              `json:"multilingual"`
+	Anonymize      bool              `json:"anonymize"`
+	Timeline       bool              `json:"timeline"`
+	Chapters       bool              `json:"chapters"`
+	Profile        Profile           `json:"profile,omitempty"`
+	SampleRate     string            `json:"sample_rate"`
+	Preprocess     []Filter          `json:"preprocess,omitempty"`  // Filters the audio was preprocessed with. The source audio is preprocessed.
+	Steps          []Step            `json:"steps,omitempty"`       // Steps of the pipeline, when configured.
+	Versions       map[string]string `json:"versions"`              // Versions of the processors, e.g. ffmpeg.
+	Repairs        *srt.RepairReport `json:"repairs,omitempty"`     // Fixes of the cues of the provider, when any.
+	SecondPass     *SecondPassReport `json:"second_pass,omitempty"` // Cues transcribed again for their low confidence, when any.
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// ParsePipeline parses the stored pipeline of a subtitle.
+func ParsePipeline(data []byte) (Pipeline, error) {
+	var p Pipeline
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Pipeline{}, fmt.Errorf("could not unmarshal pipeline: %w", err)
+	}
+	return p, nil
+}
+
+// PipelineName returns the name of the pipeline stored alongside the named subtitle, in any format.
+func PipelineName(subtitle string) string {
+	return strings.TrimSuffix(subtitle, path.Ext(subtitle)) + ".pipeline.json"
+}
+
+// SourceName returns the name of the audio stored alongside the named subtitle, in any format.
+func SourceName(subtitle string) string {
+	return strings.TrimSuffix(subtitle, path.Ext(subtitle)) + ".source.wav"
+}
+
+// VideoName returns the name of the video stored alongside the named subtitle, in any format,
+// with the extension of the uploaded file.
+func VideoName(subtitle, fileName string) string {
+	ext := strings.ToLower(path.Ext(fileName))
+	if ext == "" {
+		ext = ".mp4"
+	}
+	return strings.TrimSuffix(subtitle, path.Ext(subtitle)) + ".video" + ext
+}
+
+// writePipeline stores the pipeline of the subtitle of the input, and the transcribed audio
+// and uploaded video when kept.
+func (s *Subtitler) writePipeline(in *Input, audioData []byte) error {
+	p := Pipeline{
+		Provider:       in.provider,
+		Model:          in.model,
+		Language:       in.Language,
+		Format:         in.Format,
+		WordTimestamps: in.WordTimestamps,
+		Diarize:        in.Diarize,
+		Prompt:         in.Prompt,
+		Task:           in.Task,
+		Translations:   in.Translations,
+		Multilingual:   in.Multilingual,
+		Anonymize:      in.Anonymize,
+		Timeline:       in.Timeline,
+		Chapters:       in.Chapters,
+		Profile:        in.Profile,
+		SampleRate:     s.sampleRate,
+		Preprocess:     slices.Clone(s.filtersOf(in)),
+		Steps:          slices.Clone(s.stepsOf(in)),
+		Versions:       maps.Clone(s.versions),
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	if in.repairs.Fixed() {
+		p.Repairs = &in.repairs
+	}
+
+	if in.secondPass.Transcribed > 0 {
+		p.SecondPass = &in.secondPass
+	}
+
+	if s.retain != RetainNone {
+		p.Source = SourceName(in.output)
+
+		if _, err := s.storage.Write(in.Owner, in.OutputLanguage(), in.Project, p.Source, audioData); err != nil {
+			return fmt.Errorf("could not write source audio: %w", err)
+		}
+		in.artifacts = append(in.artifacts, p.Source)
+	}
+
+	if (in.KeepVideo || s.retain == RetainAll) && in.videoPath != "" {
+		p.Video = VideoName(in.output, in.FileName)
+
+		if _, err := s.storage.Move(in.Owner, in.OutputLanguage(), in.Project, p.Video, in.videoPath); err != nil {
+			return fmt.Errorf("could not write source video: %w", err)
+		}
+		in.videoPath = ""
+		in.artifacts = append(in.artifacts, p.Video)
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal pipeline: %w", err)
+	}
+
+	if _, err := s.storage.Write(in.Owner, in.OutputLanguage(), in.Project, PipelineName(in.output), data); err != nil {
+		return fmt.Errorf("could not write pipeline: %w", err)
+	}
+	in.artifacts = append(in.artifacts, PipelineName(in.output))
+	return nil
+}
@@ -0,0 +1,76 @@
+package subtitles
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Filter is an audio preprocessing stage, applied while extracting the audio.
+// Cleaner audio improves the accuracy of the transcription, e.g. of noisy screen recordings.
+type Filter string
+
+const (
+	FilterNormalize   Filter = "normalize"    // Normalizes the loudness.
+	FilterDenoise     Filter = "denoise"      // Reduces the background noise.
+	FilterTrimSilence Filter = "trim-silence" // Trims the trailing silence. Leading silence is kept, so that cues match the video.
+)
+
+// filterOrder is the order the filters of a list are applied in. Noise is reduced before the silence
+// is detected, and the loudness normalized last. The steps of a pipeline apply them in their own order.
+var filterOrder = []Filter{FilterDenoise, FilterTrimSilence, FilterNormalize}
+
+// filtersNone disables preprocessing, e.g. to override the default filters for a request.
+const filtersNone = "none"
+
+// ParseFilters parses a comma separated list of filters, e.g. "denoise,normalize".
+// An empty list is nil, for the default filters, while "none" is an empty, non nil list, for no filters.
+func ParseFilters(list string) ([]Filter, error) {
+	list = strings.TrimSpace(strings.ToLower(list))
+
+	switch list {
+	case "":
+		return nil, nil
+	case filtersNone:
+		return []Filter{}, nil
+	}
+
+	var filters []Filter
+	for _, name := range strings.Split(list, ",") {
+		f := Filter(strings.TrimSpace(name))
+		if !slices.Contains(filterOrder, f) {
+			return nil, fmt.Errorf("unsupported filter %q", name)
+		}
+
+		if !slices.Contains(filters, f) {
+			filters = append(filters, f)
+		}
+	}
+
+	slices.SortFunc(filters, func(a, b Filter) int {
+		return slices.Index(filterOrder, a) - slices.Index(filterOrder, b)
+	})
+	return filters, nil
+}
+
+// filtersOf returns the filters the audio of the input is preprocessed with: its own, those of the steps
+// of its pipeline when configured, or the default ones.
+func (s *Subtitler) filtersOf(in *Input) []Filter {
+	if in.Preprocess != nil {
+		return in.Preprocess
+	}
+
+	if steps := s.stepsOf(in); steps != nil {
+		return stepFilters(steps)
+	}
+	return s.filters
+}
+
+// filterNames returns the names of the filters, as the audio extractor takes them.
+func filterNames(filters []Filter) []string {
+	names := make([]string, 0, len(filters))
+	for _, f := range filters {
+		names = append(names, string(f))
+	}
+	return names
+}
@@ -0,0 +1,24 @@
+package subtitles
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Profile adapts the processing to the kind of recording.
+type Profile string
+
+const (
+	ProfileDefault Profile = ""
+	ProfileMeeting Profile = "meeting" // Also writes the minutes of the meeting, with speaker labels when supported.
+)
+
+// ParseProfile validates the profile name. An empty name is the default profile.
+func ParseProfile(name string) (Profile, error) {
+	switch p := Profile(strings.ToLower(name)); p {
+	case ProfileDefault, ProfileMeeting:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unsupported profile %q", name)
+	}
+}
@@ -0,0 +1,156 @@
+package subtitles
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotQueueable is returned when an input can't wait in a queue, e.g. a recording of several parts.
+var ErrNotQueueable = errors.New("input can not be queued")
+
+// QueuedInput is a prepared input waiting in a queue, e.g. until the storage accepts writes again.
+// It holds the options of the input, and its file moved to the directory of the queue, if any.
+type QueuedInput struct {
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+
+	FileName       string   `json:"file_name"`
+	Language       string   `json:"language,omitempty"`
+	Project        string   `json:"project,omitempty"`
+	Owner          string   `json:"owner,omitempty"`
+	Anonymize      bool     `json:"anonymize,omitempty"`
+	Provider       string   `json:"provider,omitempty"`
+	Model          string   `json:"model,omitempty"`
+	WordTimestamps bool     `json:"word_timestamps,omitempty"`
+	Diarize        bool     `json:"diarize,omitempty"`
+	Prompt         string   `json:"prompt,omitempty"`
+	Task           Task     `json:"task,omitempty"`
+	Translations   []string `json:"translations,omitempty"`
+	Priority       string   `json:"priority,omitempty"`
+	Tenant         string   `json:"tenant,omitempty"`
+	Urgent         bool     `json:"urgent,omitempty"`
+	Multilingual   bool     `json:"multilingual,omitempty"`
+	Format         Format   `json:"format,omitempty"`
+	Timeline       bool     `json:"timeline,omitempty"`
+	Chapters       bool     `json:"chapters,omitempty"`
+	Profile        Profile  `json:"profile,omitempty"`
+	Preprocess     []Filter `json:"preprocess,omitempty"`
+	KeepVideo      bool     `json:"keep_video,omitempty"`
+	Steps          []Step   `json:"steps,omitempty"`
+	Debug          bool     `json:"debug,omitempty"`
+}
+
+// Queue moves the file of the prepared input to the directory of a queue, unless empty, and returns
+// the input to store in the queue. The input can't be processed anymore, only the one returned by Input.
+func (s *Subtitler) Queue(in *Input, dir string) (QueuedInput, error) {
+	if len(in.Parts) > 0 || !in.prepared() {
+		return QueuedInput{}, ErrNotQueueable
+	}
+
+	// The file stays where it was prepared when the queue has no directory, e.g. in memory.
+	path := in.videoPath
+
+	if dir != "" {
+		path = filepath.Join(dir, filepath.Base(in.videoPath))
+
+		if err := moveFile(in.videoPath, path); err != nil {
+			return QueuedInput{}, fmt.Errorf("could not move input file to queue: %w", err)
+		}
+	}
+	in.videoPath = ""
+
+	return QueuedInput{
+		Path:           path,
+		Digest:         in.digest,
+		FileName:       in.FileName,
+		Language:       in.Language,
+		Project:        in.Project,
+		Owner:          in.Owner,
+		Anonymize:      in.Anonymize,
+		Provider:       in.Provider,
+		Model:          in.Model,
+		WordTimestamps: in.WordTimestamps,
+		Diarize:        in.Diarize,
+		Prompt:         in.Prompt,
+		Task:           in.Task,
+		Translations:   in.Translations,
+		Priority:       in.Priority,
+		Tenant:         in.Tenant,
+		Urgent:         in.Urgent,
+		Multilingual:   in.Multilingual,
+		Format:         in.Format,
+		Timeline:       in.Timeline,
+		Chapters:       in.Chapters,
+		Profile:        in.Profile,
+		Preprocess:     in.Preprocess,
+		KeepVideo:      in.KeepVideo,
+		Steps:          in.Steps,
+		Debug:          in.Debug,
+	}, nil
+}
+
+// Input returns the prepared input of the queued one. Its file is removed once processed, like
+// the files of the other inputs.
+func (q QueuedInput) Input() *Input {
+	return &Input{
+		videoPath:      q.Path,
+		digest:         q.Digest,
+		FileName:       q.FileName,
+		Language:       q.Language,
+		Project:        q.Project,
+		Owner:          q.Owner,
+		Anonymize:      q.Anonymize,
+		Provider:       q.Provider,
+		Model:          q.Model,
+		WordTimestamps: q.WordTimestamps,
+		Diarize:        q.Diarize,
+		Prompt:         q.Prompt,
+		Task:           q.Task,
+		Translations:   q.Translations,
+		Priority:       q.Priority,
+		Tenant:         q.Tenant,
+		Urgent:         q.Urgent,
+		Multilingual:   q.Multilingual,
+		Format:         q.Format,
+		Timeline:       q.Timeline,
+		Chapters:       q.Chapters,
+		Profile:        q.Profile,
+		Preprocess:     q.Preprocess,
+		KeepVideo:      q.KeepVideo,
+		Steps:          q.Steps,
+		Debug:          q.Debug,
+	}
+}
+
+// moveFile moves the file, copying it when renaming fails across file systems, e.g. when tmp is a tmpfs.
+func moveFile(srcPath, dstPath string) error {
+	if err := os.Rename(srcPath, dstPath); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dstPath)
+		return err
+	}
+
+	if err := dst.Close(); err != nil {
+		os.Remove(dstPath)
+		return err
+	}
+	return os.Remove(srcPath)
+}
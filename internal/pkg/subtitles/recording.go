@@ -0,0 +1,154 @@
+package subtitles
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/slug"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+// Part is one of the files of a recording split in several parts, e.g. chaptered camera files.
+type Part struct {
+	FileName string
+	Data     io.Reader
+
+	videoPath string
+	duration  time.Duration // Duration of the extracted audio.
+}
+
+// prepared reports whether the input files were copied to the temporary directory.
+func (in *Input) prepared() bool {
+	if len(in.Parts) == 0 {
+		return in.videoPath != ""
+	}
+
+	for _, p := range in.Parts {
+		if p.videoPath == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Recording returns an input transcribing the files of the inputs as the parts of a single recording
+// named name, in order, with the options of the first input. The copies of prepared inputs are moved to the parts.
+func Recording(name string, inputs []*Input) *Input {
+	in := *inputs[0]
+	in.FileName, in.Data, in.videoPath, in.digest = name, nil, "", ""
+	in.Parts = make([]*Part, 0, len(inputs))
+
+	for _, part := range inputs {
+		in.Parts = append(in.Parts, &Part{FileName: part.FileName, Data: part.Data, videoPath: part.videoPath})
+		part.videoPath = ""
+	}
+	return &in
+}
+
+// Discard removes the copies of the input files made by Prepare, for inputs that won't be processed.
+func (s *Subtitler) Discard(in *Input) {
+	s.removeInputFiles(in)
+}
+
+// removeInputFiles removes the copies of the input files.
+func (s *Subtitler) removeInputFiles(in *Input) {
+	if in.videoPath != "" {
+		s.removeFile(in.videoPath)
+		in.videoPath = ""
+	}
+
+	for _, p := range in.Parts {
+		if p.videoPath != "" {
+			s.removeFile(p.videoPath)
+			p.videoPath = ""
+		}
+	}
+}
+
+// extractRecordingAudio extracts the audio of each part and concatenates it in order into a single WAV file.
+func (s *Subtitler) extractRecordingAudio(ctx context.Context, in *Input) (string, error) {
+	partsData := make([][]byte, 0, len(in.Parts))
+
+	filters := s.filtersOf(in)
+
+	// Trimming the silence of a part would shift the cues of the next ones, so only the last part is trimmed.
+	partFilters := slices.DeleteFunc(slices.Clone(filters), func(f Filter) bool { return f == FilterTrimSilence })
+
+	for i, p := range in.Parts {
+		i := i
+
+		names := filterNames(partFilters)
+		if i == len(in.Parts)-1 {
+			names = filterNames(filters)
+		}
+
+		audioPath, err := s.audioExtractor.ExtractAudio(ctx, p.videoPath, s.sampleRate, names, func(progress float64) {
+			in.notify(Event{Stage: StageExtracting, Progress: (float64(i) + progress) / float64(len(in.Parts))})
+		})
+		if err != nil {
+			return "", fmt.Errorf("could not extract audio of part %q: %w", p.FileName, err)
+		}
+
+		data, err := readFile(audioPath)
+		s.removeFile(audioPath)
+
+		if err != nil {
+			return "", fmt.Errorf("could not read audio of part %q: %w", p.FileName, err)
+		}
+
+		p.duration = wavDuration(data)
+		partsData = append(partsData, data)
+	}
+
+	data, err := concatWAV(partsData)
+	if err != nil {
+		return "", fmt.Errorf("could not concatenate audio: %w", err)
+	}
+
+	// The audio is created next to the parts, like the audio extracted from a single file.
+	audioFile, err := os.CreateTemp(filepath.Dir(in.Parts[0].videoPath), slug.Make(in.FileName)+"*.wav")
+	if err != nil {
+		return "", fmt.Errorf("could not create audio file: %w", err)
+	}
+
+	if _, err := audioFile.Write(data); err != nil {
+		audioFile.Close()
+		s.removeFile(audioFile.Name())
+		return "", fmt.Errorf("could not write audio file: %w", err)
+	}
+
+	if err := audioFile.Close(); err != nil {
+		s.removeFile(audioFile.Name())
+		return "", fmt.Errorf("could not close audio file: %w", err)
+	}
+	return audioFile.Name(), nil
+}
+
+// writeParts splits the subtitle of the recording at the boundaries of its parts
+// and writes the subtitle of each part, named after the part.
+func (s *Subtitler) writeParts(in *Input, subData []byte) error {
+	cues, err := srt.Parse(subData)
+	if err != nil {
+		return fmt.Errorf("could not parse subtitle: %w", err)
+	}
+
+	var offset time.Duration
+
+	for _, p := range in.Parts {
+		partData, err := convert(srt.Format(srt.Slice(cues, offset, offset+p.duration)), in.Format, in.OutputLanguage())
+		if err != nil {
+			return fmt.Errorf("could not convert subtitle of part %q: %w", p.FileName, err)
+		}
+
+		if _, err := s.storage.Write(in.Owner, in.OutputLanguage(), in.Project, outputName(subtitleName(p.FileName), in.Format), partData); err != nil {
+			return fmt.Errorf("could not write subtitle of part %q: %w", p.FileName, err)
+		}
+		offset += p.duration
+	}
+	return nil
+}
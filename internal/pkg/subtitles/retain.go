@@ -0,0 +1,36 @@
+package subtitles
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Retain is what the Subtitler keeps of the media of a subtitle once generated, instead of deleting it.
+type Retain string
+
+const (
+	RetainNone  Retain = "keep-none"
+	RetainAudio Retain = "keep-audio" // The transcribed audio, so that the subtitle can be reprocessed or debugged.
+	RetainAll   Retain = "keep-all"   // The transcribed audio and the uploaded video.
+)
+
+// ParseRetain validates the retention of media. An empty name keeps the audio.
+func ParseRetain(name string) (Retain, error) {
+	switch r := Retain(strings.ToLower(name)); r {
+	case "":
+		return RetainAudio, nil
+	case RetainNone, RetainAudio, RetainAll:
+		return r, nil
+	default:
+		return "", fmt.Errorf("unsupported retention %q", name)
+	}
+}
+
+// IsMedia reports whether the named file is the audio or the video kept alongside a subtitle.
+func IsMedia(name string) bool {
+	if strings.HasSuffix(name, ".source.wav") {
+		return true
+	}
+	return strings.HasSuffix(strings.TrimSuffix(name, path.Ext(name)), ".video")
+}
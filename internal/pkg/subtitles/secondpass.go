@@ -0,0 +1,194 @@
+package subtitles
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/whisperclient"
+)
+
+// formatVerboseJSON is the response format of the providers with the segments and their log probabilities.
+const formatVerboseJSON string = "verbose_json"
+
+// SecondPass re-transcribes the cues transcribed with a low confidence, e.g. with a larger model,
+// and keeps the text of the pass most confident about it. Each cue transcribed again is a call to the
+// provider, so only the least confident ones are, up to a share of the cues. The providers must support
+// the verbose JSON format. Diarized transcriptions and those with word timestamps get no second pass.
+type SecondPass struct {
+	Threshold float64 // Confidence between 0 and 1 below which cues are re-transcribed, none when 0.
+	MaxShare  float64 // Share of the cues, between 0 and 1, re-transcribed at most.
+	Provider  string  // Provider of the second pass, the one of the first pass when empty.
+	Model     string  // Model of the second pass, the one of the provider when empty.
+}
+
+// SecondPassReport counts the cues of a subtitle that got a second pass.
+type SecondPassReport struct {
+	Provider    string  `json:"provider,omitempty"`
+	Model       string  `json:"model,omitempty"`
+	Transcribed int     `json:"transcribed"` // Cues below the confidence threshold.
+	Skipped     int     `json:"skipped"`     // Cues below the confidence threshold beyond the maximum share.
+	Improved    int     `json:"improved"`    // Cues whose text was replaced by the one of the second pass.
+	Seconds     float64 `json:"seconds"`     // Audio transcribed again, accounted in the usage of the owner.
+}
+
+// verboseTranscription is the verbose JSON response of the providers.
+type verboseTranscription struct {
+	Segments []struct {
+		Start      float64 `json:"start"`
+		End        float64 `json:"end"`
+		Text       string  `json:"text"`
+		AvgLogprob float64 `json:"avg_logprob"`
+	} `json:"segments"`
+}
+
+// secondPassOf reports whether the cues of the input get a second pass.
+func (s *Subtitler) secondPassOf(in *Input) bool {
+	return s.secondPass.Threshold > 0 && !in.Diarize && !in.WordTimestamps
+}
+
+// transcribeAudio transcribes the audio of the file at audioPath, small enough for the provider,
+// with a second pass over its cues of low confidence when configured.
+func (s *Subtitler) transcribeAudio(ctx context.Context, audioPath string, audioData []byte, in *Input) ([]byte, error) {
+	if !s.secondPassOf(in) {
+		return s.requestSubtitle(ctx, audioData, in, whisperclient.FormatSrt)
+	}
+
+	data, err := s.requestSubtitle(ctx, audioData, in, formatVerboseJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	cues, confidences, err := parseVerbose(data)
+	if err != nil {
+		return nil, err
+	}
+
+	retry := *in
+	if s.secondPass.Provider != "" {
+		retry.Provider = s.secondPass.Provider
+	}
+	if s.secondPass.Model != "" {
+		retry.Model = s.secondPass.Model
+	}
+
+	var low []int
+	for i := range cues {
+		if confidences[i] < s.secondPass.Threshold {
+			low = append(low, i)
+		}
+	}
+
+	// The least confident cues are transcribed again first.
+	sort.SliceStable(low, func(a, b int) bool { return confidences[low[a]] < confidences[low[b]] })
+
+	if limit := int(math.Ceil(s.secondPass.MaxShare * float64(len(cues)))); len(low) > limit {
+		in.secondPass.Skipped += len(low) - limit
+		low = low[:limit]
+	}
+
+	for _, i := range low {
+		c := cues[i]
+
+		text, confidence, err := s.retranscribe(ctx, audioPath, c, &retry)
+		if err != nil {
+			return nil, fmt.Errorf("could not transcribe cue at %s again: %w", c.Start, err)
+		}
+		in.secondPass.Transcribed++
+		in.secondPass.Seconds += (c.End - c.Start).Seconds()
+
+		if text != "" && confidence > confidences[i] {
+			cues[i].Text = text
+			in.secondPass.Improved++
+		}
+	}
+
+	if in.secondPass.Transcribed > 0 {
+		in.secondPass.Provider, in.secondPass.Model = retry.provider, retry.model
+
+		s.logger.Debug("Transcribed cues of low confidence again",
+			slog.String("filename", in.FileName),
+			slog.Int("transcribed", in.secondPass.Transcribed),
+			slog.Int("skipped", in.secondPass.Skipped),
+			slog.Int("improved", in.secondPass.Improved),
+		)
+	}
+	return srt.Format(cues), nil
+}
+
+// retranscribe transcribes the audio of the cue again, returning its text and the confidence about it.
+func (s *Subtitler) retranscribe(ctx context.Context, audioPath string, c srt.Cue, in *Input) (string, float64, error) {
+	segmentPath, err := s.audioExtractor.ExtractSegment(ctx, audioPath, c.Start, c.End-c.Start)
+	if err != nil {
+		return "", 0, fmt.Errorf("could not extract segment: %w", err)
+	}
+	defer s.removeFile(segmentPath)
+
+	segmentData, err := readFile(segmentPath)
+	if err != nil {
+		return "", 0, fmt.Errorf("could not read segment: %w", err)
+	}
+
+	data, err := s.requestSubtitle(ctx, segmentData, in, formatVerboseJSON)
+	if err != nil {
+		return "", 0, err
+	}
+
+	cues, confidences, err := parseVerbose(data)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// The confidence about the text is the one of its segments, weighted by their duration.
+	var (
+		texts          []string
+		weighted, span float64
+	)
+
+	for i, c := range cues {
+		texts = append(texts, c.Text)
+
+		d := (c.End - c.Start).Seconds()
+		weighted += confidences[i] * d
+		span += d
+	}
+
+	if span == 0 {
+		return "", 0, nil
+	}
+	return strings.Join(texts, " "), weighted / span, nil
+}
+
+// parseVerbose parses the segments of a verbose JSON transcription into cues, with the confidence
+// of the provider about each of them, the probability of its tokens on average.
+func parseVerbose(data []byte) ([]srt.Cue, []float64, error) {
+	var v verboseTranscription
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, nil, fmt.Errorf("could not unmarshal verbose transcription: %w", err)
+	}
+
+	cues := make([]srt.Cue, 0, len(v.Segments))
+	confidences := make([]float64, 0, len(v.Segments))
+
+	for _, seg := range v.Segments {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+
+		cues = append(cues, srt.Cue{
+			Index: len(cues) + 1,
+			Start: time.Duration(seg.Start * float64(time.Second)),
+			End:   time.Duration(seg.End * float64(time.Second)),
+			Text:  text,
+		})
+		confidences = append(confidences, math.Exp(seg.AvgLogprob))
+	}
+	return cues, confidences, nil
+}
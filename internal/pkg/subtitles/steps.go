@@ -0,0 +1,158 @@
+package subtitles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Step is a named step of the pipeline processing a file. The pipeline of a project can be configured
+// as an ordered list of steps, to enable, disable and order them without code changes.
+type Step string
+
+const (
+	StepExtract     Step = "extract" // Extracts the audio. Required, first.
+	StepNormalize   Step = Step(FilterNormalize)
+	StepDenoise     Step = Step(FilterDenoise)
+	StepTrimSilence Step = Step(FilterTrimSilence)
+
+	// StepVAD lets the provider skip the segments without speech, when it supports it,
+	// e.g. the vad_filter of faster-whisper. It reduces the hallucinations on long silences.
+	StepVAD Step = "vad"
+
+	StepTranscribe   Step = "transcribe" // Required.
+	StepTranslate    Step = "translate"
+	StepMultilingual Step = "multilingual"
+	StepParts        Step = "parts"
+	StepAnonymize    Step = "anonymize"
+	StepTimeline     Step = "timeline"
+	StepMinutes      Step = "minutes"
+	StepChapters     Step = "chapters"
+	StepAnalytics    Step = "analytics"
+)
+
+// AllProjects is the key of the steps of the projects without steps of their own, in ProjectSteps.
+const AllProjects string = "*"
+
+// audioSteps are the steps run between the extraction of the audio and its transcription.
+var audioSteps = []Step{StepNormalize, StepDenoise, StepTrimSilence, StepVAD}
+
+// defaultSteps are the steps of the pipeline when none is configured, in the order they run.
+// The audio is preprocessed with the filters of the Subtitler.
+var defaultSteps = []Step{
+	StepExtract,
+	StepTranscribe,
+	StepTranslate,
+	StepMultilingual,
+	StepParts,
+	StepAnonymize,
+	StepTimeline,
+	StepMinutes,
+	StepChapters,
+	StepAnalytics,
+}
+
+// ErrInvalidSteps is returned when the steps of a pipeline fail validation.
+var ErrInvalidSteps = errors.New("invalid steps")
+
+// ProjectSteps are the steps of the pipelines of the projects, by project name.
+// The steps keyed by AllProjects apply to the other projects and to the files without project.
+type ProjectSteps map[string][]Step
+
+// LoadProjectSteps reads the steps of the pipelines of the projects from a JSON file, e.g.
+//
+//	{"*": ["extract", "normalize", "transcribe", "analytics"], "podcasts": ["extract", "denoise", "vad", "transcribe"]}
+func LoadProjectSteps(path string) (ProjectSteps, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read pipeline steps: %w", err)
+	}
+
+	var steps ProjectSteps
+	if err := json.Unmarshal(data, &steps); err != nil {
+		return nil, fmt.Errorf("could not unmarshal pipeline steps: %w", err)
+	}
+
+	for project, s := range steps {
+		if err := ValidateSteps(s); err != nil {
+			return nil, fmt.Errorf("pipeline of project %s: %w", project, err)
+		}
+	}
+	return steps, nil
+}
+
+// ValidateSteps checks that the steps are known, start with the extraction of the audio, and transcribe it.
+// The audio steps must come before the transcription and the others after it.
+func ValidateSteps(steps []Step) error {
+	if len(steps) == 0 || steps[0] != StepExtract {
+		return fmt.Errorf("%w: the first step must be %s", ErrInvalidSteps, StepExtract)
+	}
+
+	transcribe := slices.Index(steps, StepTranscribe)
+	if transcribe < 0 {
+		return fmt.Errorf("%w: the %s step is required", ErrInvalidSteps, StepTranscribe)
+	}
+
+	for i, step := range steps {
+		if !slices.Contains(defaultSteps, step) && !slices.Contains(audioSteps, step) {
+			return fmt.Errorf("%w: unknown step %q", ErrInvalidSteps, step)
+		}
+
+		if slices.Index(steps, step) != i {
+			return fmt.Errorf("%w: step %s is repeated", ErrInvalidSteps, step)
+		}
+
+		if slices.Contains(audioSteps, step) && i > transcribe {
+			return fmt.Errorf("%w: step %s must come before %s", ErrInvalidSteps, step, StepTranscribe)
+		}
+
+		if !slices.Contains(audioSteps, step) && i > 0 && i < transcribe {
+			return fmt.Errorf("%w: step %s must come after %s", ErrInvalidSteps, step, StepTranscribe)
+		}
+	}
+	return nil
+}
+
+// stepsOf returns the configured steps of the pipeline of the input, or nil when none is configured.
+func (s *Subtitler) stepsOf(in *Input) []Step {
+	if in.Steps != nil {
+		return in.Steps
+	}
+
+	if steps, ok := s.projectSteps[in.Project]; ok && in.Project != "" {
+		return steps
+	}
+	return s.projectSteps[AllProjects]
+}
+
+// runs reports whether the pipeline of the input runs the step.
+func (s *Subtitler) runs(in *Input, step Step) bool {
+	steps := s.stepsOf(in)
+	if steps == nil {
+		return slices.Contains(defaultSteps, step)
+	}
+	return slices.Contains(steps, step)
+}
+
+// postSteps returns the steps of the pipeline of the input run once the subtitle is written, in order.
+func (s *Subtitler) postSteps(in *Input) []Step {
+	steps := s.stepsOf(in)
+	if steps == nil {
+		steps = defaultSteps
+	}
+	return steps[slices.Index(steps, StepTranscribe)+1:]
+}
+
+// stepFilters returns the audio filters of the steps, in order.
+func stepFilters(steps []Step) []Filter {
+	filters := []Filter{}
+	for _, step := range steps {
+		switch step {
+		case StepNormalize, StepDenoise, StepTrimSilence:
+			filters = append(filters, Filter(step))
+		}
+	}
+	return filters
+}
@@ -3,178 +3,954 @@ package subtitles
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"strings"
 	"sync"
+	"time"
 
 	"log/slog"
 
-	"github.com/alesr/audiostripper"
-	"github.com/alesr/whisperclient"
+	"github.com/alesr/videoscriber/internal/pkg/analytics"
+	"github.com/alesr/videoscriber/internal/pkg/anonymize"
+	"github.com/alesr/videoscriber/internal/pkg/chapters"
+	"github.com/alesr/videoscriber/internal/pkg/langid"
+	"github.com/alesr/videoscriber/internal/pkg/minutes"
+	"github.com/alesr/videoscriber/internal/pkg/nlp"
+	"github.com/alesr/videoscriber/internal/pkg/slug"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/videoscriber/internal/pkg/trace"
+	"github.com/alesr/videoscriber/internal/pkg/transcriber"
 )
 
-type audioStripper interface {
-	ExtractAudio(ctx context.Context, in *audiostripper.ExtractAudioInput) (*audiostripper.ExtractAudioOutput, error)
+type audioExtractor interface {
+	ExtractAudio(ctx context.Context, filePath, sampleRate string, filters []string, progress func(float64)) (string, error)
+	ExtractSegment(ctx context.Context, filePath string, start, duration time.Duration) (string, error)
+	Duration(ctx context.Context, filePath string) (time.Duration, error)
 }
 
-type whisperClient interface {
-	TranscribeAudio(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error)
+type analyzer interface {
+	Analyze(ctx context.Context, text string) (nlp.Analysis, error)
 }
 
+type minutesWriter interface {
+	Write(ctx context.Context, cues []srt.Cue) (minutes.Minutes, error)
+}
+
+type chapterDetector interface {
+	Detect(ctx context.Context, cues []srt.Cue, language string) ([]chapters.Chapter, error)
+}
+
+type scheduler interface {
+	Reserve(priority string, urgent bool, d time.Duration) time.Time
+}
+
+type storage interface {
+	Write(owner, language, project, fileName string, data []byte) (string, error)
+	Move(owner, language, project, fileName, srcPath string) (string, error)
+}
+
+type scratchSpace interface {
+	Create(pattern string, size int64, data io.Reader) (string, error)
+}
+
+// ErrUnknownProvider is returned when the requested transcription provider is not configured.
+var ErrUnknownProvider = errors.New("unknown transcription provider")
+
 // Input represents the input to the subtitle generator.
 type Input struct {
-	FileName string
-	Data     io.Reader
-	Language string // For now, we have the transcription language hardcoded to Portuguese.
+	FileName  string
+	Data      io.Reader
+	Language  string // Optional. Spoken language of the file, DefaultLanguage when empty.
+	Project   string // Optional. Subtitles are stored in a subdirectory named after the project.
+	Owner     string // Optional. Subtitles are stored in the namespace of the owner.
+	Anonymize bool   // Also writes a pseudonymized transcript (.anon.srt) alongside the raw one.
+
+	// Provider selects the transcription provider. Defaults to the Subtitler's default provider.
+	Provider string
+
+	// Model, when set, overrides the model of the provider, e.g. to reprocess with a pinned pipeline.
+	Model string
+
+	// WordTimestamps and Diarize are passed through to providers supporting them.
+	WordTimestamps bool
+	Diarize        bool
+
+	// Prompt is passed to Whisper as its initial prompt, e.g. terminology of the domain to recognize.
+	Prompt string
+
+	// Task translates the speech to English when set to TaskTranslate. Language remains the spoken language.
+	// Defaults to TaskTranscribe.
+	Task Task
+
+	// Translations are the languages the cues of the subtitle are also translated to, keeping their timestamps.
+	// Each translation is stored under its language and named after it, e.g. video.en.srt.
+	Translations []string
+
+	// Priority and Tenant are used by the routing policy to select a provider.
+	// The scheduler may defer the transcription of low priority files.
+	Priority string
+	Tenant   string
+
+	// Urgent transcriptions are never deferred by the scheduler.
+	Urgent bool
+
+	// Notify, when set, is called as the file goes through the processing stages.
+	Notify func(e Event)
+
+	// Multilingual identifies the language of each cue and writes them as JSON (.json)
+	// alongside the subtitle, for code-switched content.
+	Multilingual bool
+
+	// Format is the output format of the subtitle. Defaults to SRT.
+	// The anonymized transcript is always written as SRT.
+	Format Format
+
+	// Parts, when set, are the files of a recording split in several parts (e.g. chaptered
+	// camera files). Their audio is concatenated in order and transcribed once, as the
+	// recording named FileName. Data is ignored.
+	Parts []*Part
+
+	// SplitParts also writes the subtitle of each part of the recording, named after the part.
+	SplitParts bool
+
+	// Timeline also writes a per-minute sentiment and topic timeline (.timeline.json)
+	// alongside the subtitle, for analytics dashboards.
+	Timeline bool
+
+	// Chapters also writes the chapters of the recording (.chapters.json) alongside the subtitle.
+	Chapters bool
+
+	// Profile adapts the processing to the kind of recording. With the meeting profile,
+	// the minutes of the meeting (.minutes.json) are written alongside the subtitle.
+	Profile Profile
+
+	// Preprocess are the filters the audio is preprocessed with. Nil uses the default filters
+	// of the Subtitler, while an empty list disables preprocessing.
+	Preprocess []Filter
+
+	// Size is the size of Data in bytes when known, e.g. of resumable uploads, placing its temporary
+	// copy on the scratch directory with room for it. Zero when unknown.
+	Size int64
+
+	// KeepVideo stores the uploaded video alongside the subtitle instead of deleting it,
+	// so that the subtitle can be burned into it later. Ignored for recordings.
+	KeepVideo bool
+
+	// Steps, when set, are the steps of the pipeline processing the file, instead of those of its project.
+	Steps []Step
+
+	// Debug records the requests sent to the transcription provider and its responses, without the audio,
+	// and stores them alongside the subtitle (.trace.json), also when the transcription fails.
+	Debug bool
+
+	videoPath  string
+	digest     string        // SHA-256 of the input file, once prepared.
+	output     string        // Name of the stored subtitle.
+	artifacts  []string      // Names of the files stored alongside the subtitle.
+	duration   time.Duration // Duration of the transcribed audio.
+	analytics  *analytics.Analytics
+	worker     chan struct{} // Lane of the worker held by the file, nil while its transcription is deferred.
+	provider   string        // Provider that transcribed the audio.
+	model      string        // Model that transcribed the audio, when the provider tells it.
+	repairs    srt.RepairReport
+	secondPass SecondPassReport
+	trace      *trace.Trace
+}
+
+// usage returns the duration of the audio transcribed by the providers, including the second pass.
+func (in *Input) usage() time.Duration {
+	return in.duration + time.Duration(in.secondPass.Seconds*float64(time.Second))
+}
+
+// Digest returns the hex encoded SHA-256 of the input file once prepared, e.g. to detect duplicate submissions.
+// It is empty for recordings.
+func (in *Input) Digest() string {
+	return in.digest
+}
+
+func (in *Input) notify(e Event) {
+	if in.Notify == nil {
+		return
+	}
+
+	e.FileName = in.FileName
+	in.Notify(e)
 }
 
 // Subtitler is the subtitle generator.
 type Subtitler struct {
-	logger        *slog.Logger
-	sampleRate    string
-	outputDir     string
-	tmpDir        string
-	audioStripper audioStripper
-	whisperClient whisperClient
+	logger          *slog.Logger
+	sampleRate      string
+	filters         []Filter
+	projectSteps    ProjectSteps
+	storage         storage
+	scratch         scratchSpace
+	audioExtractor  audioExtractor
+	providers       map[string]transcriber.Transcriber
+	defaultProvider string
+	analyzer        analyzer
+	minutes         minutesWriter
+	chapters        chapterDetector
+	translator      translator
+	scheduler       scheduler
+	versions        map[string]string
+	retain          Retain
+	firstCueIndex   int
+	layout          srt.Layout
+	secondPass      SecondPass
+	workers         chan struct{}
+	fastLane        chan struct{} // Workers reserved to the short clips, nil without a fast lane.
+	shortClip       time.Duration // Duration below which the files are short clips.
+}
+
+// Config is the dependencies and the settings of the subtitle generator.
+type Config struct {
+	Logger     *slog.Logger
+	SampleRate string
+	// Scratch is the space the inputs are copied to, their audio being extracted next to them.
+	Scratch   scratchSpace
+	Storage   storage
+	Extractor audioExtractor
+	// Providers are the transcription providers by name, DefaultProvider being one of them.
+	Providers       map[string]transcriber.Transcriber
+	DefaultProvider string
+	Analyzer        analyzer
+	Minutes         minutesWriter
+	Chapters        chapterDetector
+	Translator      translator
+	Scheduler       scheduler
+
+	// Filters preprocess the audio, unless an input sets its own or its project configures
+	// the steps of its pipeline in ProjectSteps.
+	Filters      []Filter
+	ProjectSteps ProjectSteps
+	// Versions are the versions of the processors, recorded in the pipeline of each subtitle with the transcribed
+	// audio unless Retain is RetainNone, so that subtitles can be reprocessed. RetainAll also keeps the videos.
+	Versions map[string]string
+	Retain   Retain
+	// FirstCueIndex is the index of the first cue, the cues of the providers being repaired before anything
+	// is written and laid out within the limits of Layout.
+	FirstCueIndex int
+	Layout        srt.Layout
+	// SecondPass transcribes again the cues transcribed with a low confidence, disabled with a zero threshold.
+	SecondPass SecondPass
+	// MaxConcurrency is the maximum number of files processed at the same time across all requests,
+	// FastLaneWorkers of them being reserved to the clips shorter than ShortClip, so that they aren't
+	// queued behind long recordings.
+	MaxConcurrency  int
+	FastLaneWorkers int
+	ShortClip       time.Duration
 }
 
 // New returns a new subtitle generator.
-func New(
-	logger *slog.Logger,
-	sampleRate, outputDir, tmpDir string,
-	stripper audioStripper,
-	whisperCli whisperClient,
-) (*Subtitler, error) {
+func New(cfg Config) (*Subtitler, error) {
+	if _, ok := cfg.Providers[cfg.DefaultProvider]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, cfg.DefaultProvider)
+	}
+
+	if cfg.FirstCueIndex < 0 {
+		return nil, fmt.Errorf("first cue index must not be negative, got %d", cfg.FirstCueIndex)
+	}
+
+	if cfg.Layout.MaxLineLength < 0 || cfg.Layout.MaxLines < 0 || cfg.Layout.MaxCPS < 0 {
+		return nil, fmt.Errorf("cue layout limits must not be negative, got %+v", cfg.Layout)
+	}
+
+	if cfg.SecondPass.Threshold < 0 || cfg.SecondPass.Threshold >= 1 {
+		return nil, fmt.Errorf("second pass threshold must be between 0 and 1, got %g", cfg.SecondPass.Threshold)
+	}
+
+	if cfg.SecondPass.Threshold > 0 && (cfg.SecondPass.MaxShare <= 0 || cfg.SecondPass.MaxShare > 1) {
+		return nil, fmt.Errorf("second pass max share must be between 0 and 1, got %g", cfg.SecondPass.MaxShare)
+	}
+
+	if _, ok := cfg.Providers[cfg.SecondPass.Provider]; cfg.SecondPass.Provider != "" && !ok {
+		return nil, fmt.Errorf("second pass: %w: %q", ErrUnknownProvider, cfg.SecondPass.Provider)
+	}
+
+	for project, steps := range cfg.ProjectSteps {
+		if err := ValidateSteps(steps); err != nil {
+			return nil, fmt.Errorf("pipeline of project %s: %w", project, err)
+		}
+	}
+
+	if cfg.MaxConcurrency < 1 {
+		return nil, fmt.Errorf("max concurrency must be at least 1, got %d", cfg.MaxConcurrency)
+	}
+
+	if cfg.FastLaneWorkers < 0 || cfg.FastLaneWorkers >= cfg.MaxConcurrency {
+		return nil, fmt.Errorf("fast lane workers must be between 0 and max concurrency - 1, got %d", cfg.FastLaneWorkers)
+	}
+
+	var fastLane chan struct{}
+	if cfg.FastLaneWorkers > 0 {
+		if cfg.ShortClip <= 0 {
+			return nil, fmt.Errorf("short clip duration must be positive with a fast lane, got %s", cfg.ShortClip)
+		}
+		fastLane = make(chan struct{}, cfg.FastLaneWorkers)
+	}
+
 	return &Subtitler{
-		logger:        logger,
-		sampleRate:    sampleRate,
-		outputDir:     outputDir,
-		tmpDir:        tmpDir,
-		audioStripper: stripper,
-		whisperClient: whisperCli,
+		logger:          cfg.Logger,
+		sampleRate:      cfg.SampleRate,
+		filters:         cfg.Filters,
+		projectSteps:    cfg.ProjectSteps,
+		storage:         cfg.Storage,
+		scratch:         cfg.Scratch,
+		audioExtractor:  cfg.Extractor,
+		providers:       cfg.Providers,
+		defaultProvider: cfg.DefaultProvider,
+		analyzer:        cfg.Analyzer,
+		minutes:         cfg.Minutes,
+		chapters:        cfg.Chapters,
+		translator:      cfg.Translator,
+		scheduler:       cfg.Scheduler,
+		versions:        cfg.Versions,
+		retain:          cfg.Retain,
+		firstCueIndex:   cfg.FirstCueIndex,
+		layout:          cfg.Layout,
+		secondPass:      cfg.SecondPass,
+		workers:         make(chan struct{}, cfg.MaxConcurrency-cfg.FastLaneWorkers),
+		fastLane:        fastLane,
+		shortClip:       cfg.ShortClip,
 	}, nil
 }
 
-// GenerateFromAudioData generates subtitle from audio data.
-func (s *Subtitler) GenerateFromAudioData(ctx context.Context, inputs []*Input) error {
+// HasProvider reports whether the transcription provider is configured.
+func (s *Subtitler) HasProvider(name string) bool {
+	_, ok := s.providers[name]
+	return ok
+}
+
+// Result is the outcome of an input file.
+type Result struct {
+	FileName string
+	Subtitle string // Name of the stored subtitle, when it succeeded.
+	Err      error
+}
+
+// Results are the outcomes of the input files, in the order of the inputs.
+type Results []Result
+
+// Failed returns the results of the files that failed.
+func (r Results) Failed() Results {
+	var failed Results
+	for _, res := range r {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Err joins the errors of the files that failed, each prefixed by the file name. It is nil when all succeeded.
+func (r Results) Err() error {
+	var errs []error
+	for _, res := range r.Failed() {
+		errs = append(errs, fmt.Errorf("%s: %w", res.FileName, res.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// GenerateFromAudioData generates the subtitles of the input files concurrently.
+// A file failing doesn't stop the others. The returned error joins the errors of the files that failed,
+// and the results tell which files succeeded.
+func (s *Subtitler) GenerateFromAudioData(ctx context.Context, inputs []*Input) (Results, error) {
 	var (
-		wg    sync.WaitGroup
-		errCh = make(chan error, len(inputs))
+		wg      sync.WaitGroup
+		results = make(Results, len(inputs))
 	)
 
-	for _, in := range inputs {
+	for i, in := range inputs {
 		wg.Add(1)
 
-		go func(ctx context.Context, in *Input, errCh chan error) {
+		go func(i int, in *Input) {
 			defer wg.Done()
-			s.processFile(ctx, in, errCh)
-		}(ctx, in, errCh)
+
+			results[i] = Result{FileName: in.FileName, Err: s.processFile(ctx, in)}
+			if results[i].Err == nil {
+				results[i].Subtitle = in.output
+			}
+		}(i, in)
 	}
 
 	wg.Wait()
 
-	close(errCh)
+	if err := results.Err(); err != nil {
+		return results, fmt.Errorf("could not process %d of %d files: %w", len(results.Failed()), len(results), err)
+	}
+	return results, nil
+}
+
+func (s *Subtitler) processFile(ctx context.Context, in *Input) error {
+	s.probeDuration(ctx, in)
 
-	// Wrap all errors in one.
-	var err error
-	for e := range errCh {
-		err = fmt.Errorf("%w", e)
+	if err := s.acquireLane(ctx, in); err != nil {
+		s.removeInputFiles(in)
+		in.notify(Event{Stage: StageFailed, Err: err})
+		return err
 	}
 
-	if err != nil {
-		return fmt.Errorf("error while processing files: %w", err)
+	defer func() {
+		if in.worker != nil {
+			s.releaseLane(in)
+		}
+	}()
+
+	if err := s.process(ctx, in); err != nil {
+		in.notify(Event{Stage: StageFailed, Err: err, Artifacts: in.artifacts})
+		return err
 	}
+	in.notify(Event{Stage: StageDone, Progress: 1, Subtitle: in.output, Duration: in.duration, Usage: in.usage(), Artifacts: in.artifacts, Analytics: in.analytics})
 	return nil
 }
 
-func (s *Subtitler) processFile(ctx context.Context, in *Input, errCh chan error) {
-	videoPath, err := s.createVideoFile(in.FileName, in.Data)
-	if err != nil {
-		errCh <- fmt.Errorf("could not create video file: %w", err)
-		return
+func (s *Subtitler) process(ctx context.Context, in *Input) error {
+	if !in.prepared() {
+		if err := s.Prepare(in); err != nil {
+			return err
+		}
+	}
+	defer s.removeInputFiles(in)
+
+	if in.Language == "" {
+		in.Language = DefaultLanguage
+	}
+
+	if !SupportedLanguage(in.Language) {
+		return fmt.Errorf("unsupported language %q", in.Language)
+	}
+
+	if in.Format == "" {
+		in.Format = FormatSRT
+	}
+
+	// Speaker labels let the minutes attribute decisions and action items.
+	if in.Profile == ProfileMeeting {
+		in.Diarize = true
+	}
+
+	if in.Format == FormatJSON && in.Multilingual {
+		return fmt.Errorf("multilingual cues can not be combined with the %q format", in.Format)
 	}
-	defer s.removeFile(videoPath)
 
-	audioFilePath, err := s.extractAudio(ctx, videoPath, s.sampleRate)
+	if in.Task == "" {
+		in.Task = TaskTranscribe
+	}
+
+	// Translated cues are all in English.
+	if in.Task == TaskTranslate && in.Multilingual {
+		return errors.New("multilingual cues can not be combined with translation")
+	}
+
+	if in.Debug {
+		in.trace = trace.New()
+		defer s.writeTrace(in)
+	}
+
+	in.notify(Event{Stage: StageExtracting})
+
+	audioFilePath, err := s.extractAudio(ctx, in)
 	if err != nil {
-		errCh <- fmt.Errorf("could not extract audio: %w", err)
-		return
+		return fmt.Errorf("could not extract audio: %w", err)
 	}
 	defer s.removeFile(audioFilePath)
 
 	audioData, err := readFile(audioFilePath)
 	if err != nil {
-		errCh <- fmt.Errorf("could not read audio file: %w", err)
-		return
+		return fmt.Errorf("could not read audio file: %w", err)
+	}
+
+	in.duration = wavDuration(audioData)
+
+	if err := s.leaveFastLane(ctx, in); err != nil {
+		return fmt.Errorf("could not schedule transcription: %w", err)
+	}
+
+	// The audio isn't held in memory while the transcription is deferred, it is read again once it starts.
+	if at := s.scheduler.Reserve(in.Priority, in.Urgent, in.duration); time.Until(at) > 0 {
+		audioData = nil
+
+		if err := s.deferUntil(ctx, in, at); err != nil {
+			return fmt.Errorf("could not schedule transcription: %w", err)
+		}
+
+		if audioData, err = readFile(audioFilePath); err != nil {
+			return fmt.Errorf("could not read audio file: %w", err)
+		}
 	}
 
-	subData, err := s.requestSubtitle(ctx, audioData, in.FileName, s.sampleRate)
+	in.notify(Event{Stage: StageTranscribing})
+
+	subData, err := s.transcribe(ctx, audioFilePath, audioData, in)
 	if err != nil {
-		errCh <- fmt.Errorf("could not generate subtitle: %w", err)
-		return
+		return fmt.Errorf("could not generate subtitle: %w", err)
 	}
 
-	subPath := subtitlePath(s.outputDir, in.FileName)
+	if subData, err = s.repair(subData, in); err != nil {
+		return fmt.Errorf("could not repair subtitle: %w", err)
+	}
 
-	if err := writeFile(subPath, subData); err != nil {
-		errCh <- fmt.Errorf("could not write subtitle file: %w", err)
-		return
+	subName := subtitleName(in.FileName)
+
+	language := in.OutputLanguage()
+
+	outData, err := convert(subData, in.Format, language)
+	if err != nil {
+		return fmt.Errorf("could not convert subtitle to %s: %w", in.Format, err)
+	}
+
+	in.output = outputName(subName, in.Format)
+
+	if _, err := s.storage.Write(in.Owner, language, in.Project, in.output, outData); err != nil {
+		return fmt.Errorf("could not write subtitle file: %w", err)
+	}
+
+	for _, step := range s.postSteps(in) {
+		if err := s.runStep(ctx, step, in, subName, language, subData); err != nil {
+			return err
+		}
+	}
+
+	if err := s.writePipeline(in, audioData); err != nil {
+		return err
 	}
+	return nil
 }
 
-// createVideoFile creates a temporary video file and returns its path.
-// The file is deleted after when the caller finishes.
-func (s *Subtitler) createVideoFile(name string, data io.Reader) (string, error) {
-	videoFile, err := os.CreateTemp(s.tmpDir, name)
+// runStep runs a step of the pipeline following the transcription, when requested for the input.
+func (s *Subtitler) runStep(ctx context.Context, step Step, in *Input, subName, language string, subData []byte) error {
+	switch step {
+	case StepTranslate:
+		return s.writeTranslations(ctx, in, subName, subData)
+
+	case StepMultilingual:
+		if !in.Multilingual {
+			return nil
+		}
+
+		cuesData, err := multilingualCues(subData, language)
+		if err != nil {
+			return fmt.Errorf("could not identify cue languages: %w", err)
+		}
+
+		if _, err := s.storage.Write(in.Owner, language, in.Project, cuesName(subName), cuesData); err != nil {
+			return fmt.Errorf("could not write cues file: %w", err)
+		}
+		in.artifacts = append(in.artifacts, cuesName(subName))
+
+	case StepParts:
+		if in.SplitParts && len(in.Parts) > 0 {
+			return s.writeParts(in, subData)
+		}
+
+	case StepAnonymize:
+		if !in.Anonymize {
+			return nil
+		}
+
+		anonData, err := anonymize.SRT(subData)
+		if err != nil {
+			return fmt.Errorf("could not anonymize subtitle: %w", err)
+		}
+
+		if _, err := s.storage.Write(in.Owner, language, in.Project, anonymizedName(subName), anonData); err != nil {
+			return fmt.Errorf("could not write anonymized subtitle file: %w", err)
+		}
+		in.artifacts = append(in.artifacts, anonymizedName(subName))
+
+	case StepTimeline:
+		if !in.Timeline {
+			return nil
+		}
+
+		timelineData, err := s.timeline(ctx, subData)
+		if err != nil {
+			return fmt.Errorf("could not analyze transcript: %w", err)
+		}
+
+		if _, err := s.storage.Write(in.Owner, language, in.Project, timelineName(subName), timelineData); err != nil {
+			return fmt.Errorf("could not write timeline file: %w", err)
+		}
+		in.artifacts = append(in.artifacts, timelineName(subName))
+
+	case StepMinutes:
+		if in.Profile != ProfileMeeting {
+			return nil
+		}
+
+		minutesData, err := s.meetingMinutes(ctx, subData)
+		if err != nil {
+			return fmt.Errorf("could not write meeting minutes: %w", err)
+		}
+
+		if _, err := s.storage.Write(in.Owner, language, in.Project, minutesName(subName), minutesData); err != nil {
+			return fmt.Errorf("could not write minutes file: %w", err)
+		}
+		in.artifacts = append(in.artifacts, minutesName(subName))
+
+	case StepChapters:
+		if !in.Chapters {
+			return nil
+		}
+
+		chaptersData, err := s.detectChapters(ctx, subData, language)
+		if err != nil {
+			return fmt.Errorf("could not detect chapters: %w", err)
+		}
+
+		if _, err := s.storage.Write(in.Owner, language, in.Project, ChaptersName(subName), chaptersData); err != nil {
+			return fmt.Errorf("could not write chapters file: %w", err)
+		}
+		in.artifacts = append(in.artifacts, ChaptersName(subName))
+
+	case StepAnalytics:
+		stats, err := speakingAnalytics(subData, in.duration)
+		if err != nil {
+			return fmt.Errorf("could not compute analytics: %w", err)
+		}
+
+		analyticsData, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not marshal analytics: %w", err)
+		}
+
+		if _, err := s.storage.Write(in.Owner, language, in.Project, analyticsName(subName), analyticsData); err != nil {
+			return fmt.Errorf("could not write analytics file: %w", err)
+		}
+		in.artifacts = append(in.artifacts, analyticsName(subName))
+		in.analytics = &stats
+	}
+	return nil
+}
+
+// speakingAnalytics computes the speaking rate, word frequency, silences and talk time of the transcript.
+func speakingAnalytics(subData []byte, duration time.Duration) (analytics.Analytics, error) {
+	cues, err := srt.Parse(subData)
 	if err != nil {
-		return "", fmt.Errorf("could not create video file: %w", err)
+		return analytics.Analytics{}, fmt.Errorf("could not parse subtitle: %w", err)
 	}
+	return analytics.Compute(cues, duration), nil
+}
 
-	s.logger.Debug("Created video file", slog.String("filepath", videoFile.Name()))
+// meetingMinutes writes the minutes of the meeting transcribed in the subtitle.
+func (s *Subtitler) meetingMinutes(ctx context.Context, subData []byte) ([]byte, error) {
+	cues, err := srt.Parse(subData)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse subtitle: %w", err)
+	}
 
-	defer func() {
-		if err := videoFile.Close(); err != nil {
-			s.logger.Error("Could not close video file", slog.String("filepath", videoFile.Name()), slog.String("error", err.Error()))
+	m, err := s.minutes.Write(ctx, cues)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal minutes: %w", err)
+	}
+	return data, nil
+}
+
+// detectChapters splits the transcript of the subtitle into chapters.
+func (s *Subtitler) detectChapters(ctx context.Context, subData []byte, language string) ([]byte, error) {
+	cues, err := srt.Parse(subData)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse subtitle: %w", err)
+	}
+
+	chs, err := s.chapters.Detect(ctx, cues, language)
+	if err != nil {
+		return nil, err
+	}
+	return chapters.Marshal(chs)
+}
+
+// timeline analyzes the sentiment and topics of the transcript, minute by minute.
+func (s *Subtitler) timeline(ctx context.Context, subData []byte) ([]byte, error) {
+	cues, err := srt.Parse(subData)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse subtitle: %w", err)
+	}
+
+	points, err := nlp.Timeline(ctx, s.analyzer, cues, time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(timelineDocument{Window: time.Minute.Seconds(), Points: points}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal timeline: %w", err)
+	}
+	return data, nil
+}
+
+// acquire waits for a free worker slot.
+func (s *Subtitler) acquire(ctx context.Context) error {
+	select {
+	case s.workers <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("could not acquire worker: %w", ctx.Err())
+	}
+}
+
+func (s *Subtitler) release() {
+	<-s.workers
+}
+
+// short reports whether the audio of the duration is a short clip, whose transcription can take the fast lane.
+// Files of unknown duration can't, so that a long recording never holds a worker reserved to the short clips.
+func (s *Subtitler) short(d time.Duration) bool {
+	return s.fastLane != nil && d > 0 && d < s.shortClip
+}
+
+// probeDuration reads the duration of the prepared files of the input from their headers, so that
+// its lane is known before extracting them. The duration stays unknown when any of them can't be probed.
+func (s *Subtitler) probeDuration(ctx context.Context, in *Input) {
+	if s.fastLane == nil || in.duration > 0 || !in.prepared() {
+		return
+	}
+
+	paths := []string{in.videoPath}
+	if len(in.Parts) > 0 {
+		paths = paths[:0]
+		for _, p := range in.Parts {
+			paths = append(paths, p.videoPath)
 		}
-	}()
+	}
 
-	if _, err := io.Copy(videoFile, data); err != nil {
-		return "", fmt.Errorf("could not write video file: %w", err)
+	var total time.Duration
+	for _, path := range paths {
+		d, err := s.audioExtractor.Duration(ctx, path)
+		if err != nil {
+			s.logger.Debug("Could not probe duration", slog.String("file", in.FileName), slog.String("error", err.Error()))
+			return
+		}
+		total += d
 	}
-	return videoFile.Name(), nil
+	in.duration = total
+}
+
+// acquireLane waits for a free worker slot for the file, in the fast lane too for short clips,
+// preferring the other workers so that the fast lane stays free for the next clips.
+func (s *Subtitler) acquireLane(ctx context.Context, in *Input) error {
+	if !s.short(in.duration) {
+		if err := s.acquire(ctx); err != nil {
+			return err
+		}
+		in.worker = s.workers
+		return nil
+	}
+
+	select {
+	case s.workers <- struct{}{}:
+		in.worker = s.workers
+		return nil
+	default:
+	}
+
+	select {
+	case s.workers <- struct{}{}:
+		in.worker = s.workers
+	case s.fastLane <- struct{}{}:
+		in.worker = s.fastLane
+	case <-ctx.Done():
+		return fmt.Errorf("could not acquire worker: %w", ctx.Err())
+	}
+	return nil
+}
+
+func (s *Subtitler) releaseLane(in *Input) {
+	<-in.worker
+	in.worker = nil
+}
+
+// leaveFastLane moves the file out of the fast lane, where it was extracted, when its audio isn't a short clip.
+func (s *Subtitler) leaveFastLane(ctx context.Context, in *Input) error {
+	if in.worker != s.fastLane || s.short(in.duration) {
+		return nil
+	}
+
+	s.logger.Info("Leaving fast lane", slog.String("file", in.FileName), slog.Duration("duration", in.duration))
+
+	s.releaseLane(in)
+	return s.acquireLane(ctx, in)
+}
+
+// deferUntil waits until the time the scheduler reserved for the transcription of the file.
+// The worker is released while waiting, so that other files are processed meanwhile.
+func (s *Subtitler) deferUntil(ctx context.Context, in *Input, at time.Time) error {
+	s.logger.Info("Deferring transcription", slog.String("file", in.FileName), slog.Time("at", at))
+	in.notify(Event{Stage: StageScheduled, ScheduledAt: at})
+
+	s.releaseLane(in)
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return s.acquireLane(ctx, in)
+}
+
+// Prepare copies the input data into the temporary directory, so the input can be
+// processed after the reader it came from is gone (e.g. once the HTTP request finished).
+func (s *Subtitler) Prepare(in *Input) error {
+	if len(in.Parts) > 0 {
+		for _, p := range in.Parts {
+			if p.videoPath != "" {
+				continue
+			}
+
+			videoPath, _, err := s.createVideoFile(p.FileName, 0, p.Data)
+			if err != nil {
+				s.removeInputFiles(in)
+				return fmt.Errorf("could not create video file of part %q: %w", p.FileName, err)
+			}
+
+			p.videoPath = videoPath
+			p.Data = nil
+		}
+		return nil
+	}
+
+	if in.prepared() {
+		return nil
+	}
+
+	videoPath, digest, err := s.createVideoFile(in.FileName, in.Size, in.Data)
+	if err != nil {
+		return fmt.Errorf("could not create video file: %w", err)
+	}
+
+	in.videoPath, in.digest = videoPath, digest
+	in.Data = nil
+	return nil
+}
+
+// createVideoFile creates a temporary video file and returns its path and the hex encoded SHA-256 of the data.
+// The size of the data, when known, selects the directory of the file. Zero when unknown.
+// The file is deleted after when the caller finishes.
+func (s *Subtitler) createVideoFile(name string, size int64, data io.Reader) (string, string, error) {
+	hash := sha256.New()
+
+	videoPath, err := s.scratch.Create(slug.Make(name), size, io.TeeReader(data, hash))
+	if err != nil {
+		return "", "", fmt.Errorf("could not create video file: %w", err)
+	}
+
+	s.logger.Debug("Created video file", slog.String("filepath", videoPath))
+
+	return videoPath, hex.EncodeToString(hash.Sum(nil)), nil
 }
 
 // extractAudio extracts the audio from the video file.
 // The audio file (.wav) is created in the same directory as the video file (tmp).
 // The file is deleted after when the caller finishes.
-func (s *Subtitler) extractAudio(ctx context.Context, filepath, sampleRate string) (string, error) {
-	res, err := s.audioStripper.ExtractAudio(ctx, &audiostripper.ExtractAudioInput{
-		SampleRate: sampleRate,
-		FilePath:   filepath,
+func (s *Subtitler) extractAudio(ctx context.Context, in *Input) (string, error) {
+	if len(in.Parts) > 0 {
+		return s.extractRecordingAudio(ctx, in)
+	}
+
+	audioPath, err := s.audioExtractor.ExtractAudio(ctx, in.videoPath, s.sampleRate, filterNames(s.filtersOf(in)), func(progress float64) {
+		in.notify(Event{Stage: StageExtracting, Progress: progress})
 	})
 	if err != nil {
 		return "", fmt.Errorf("could not extract audio: %w", err)
 	}
-	return res.FilePath, nil
+	return audioPath, nil
 }
 
-// requestSubtitle calls the Whisper API to generate subtitles for the given audio data.
-func (s *Subtitler) requestSubtitle(ctx context.Context, audioData []byte, fileName, sampleRate string) ([]byte, error) {
-	subtitleData, err := s.whisperClient.TranscribeAudio(ctx, whisperclient.TranscribeAudioInput{
-		Name:     fileName,
-		Language: whisperclient.LanguagePortuguese, // TODO: extend support for other languages.
-		Format:   whisperclient.FormatSrt,
-		Data:     bytes.NewReader(audioData),
+// requestSubtitle calls the transcription provider to generate subtitles for the given audio data.
+func (s *Subtitler) requestSubtitle(ctx context.Context, audioData []byte, in *Input, format string) ([]byte, error) {
+	providerName := in.Provider
+	if providerName == "" {
+		providerName = s.defaultProvider
+	}
+
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, providerName)
+	}
+
+	req := transcriber.Request{
+		Model:          in.Model,
+		Duration:       wavDuration(audioData),
+		Priority:       in.Priority,
+		Tenant:         in.Tenant,
+		Name:           in.FileName,
+		Language:       in.Language,
+		Format:         format,
+		Data:           bytes.NewReader(audioData),
+		WordTimestamps: in.WordTimestamps,
+		Diarize:        in.Diarize,
+		Prompt:         in.Prompt,
+		Translate:      in.Task == TaskTranslate,
+		VAD:            s.runs(in, StepVAD),
+	}
+
+	// The provider is described before transcribing, since routing can depend on the outcome.
+	in.provider, in.model = providerName, in.Model
+	if d, ok := provider.(transcriber.Describer); ok {
+		in.provider, in.model = d.Describe(req)
+	}
+
+	ctx, call := in.trace.Start(ctx, trace.Call{
+		Provider:       in.provider,
+		Model:          in.model,
+		Language:       req.Language,
+		Format:         req.Format,
+		Prompt:         req.Prompt,
+		Translate:      req.Translate,
+		WordTimestamps: req.WordTimestamps,
+		Diarize:        req.Diarize,
+		VAD:            req.VAD,
+		AudioSize:      len(audioData),
+		AudioDuration:  req.Duration.Seconds(),
 	})
+
+	subtitleData, err := provider.Transcribe(ctx, req)
+	call.End(err)
 	if err != nil {
 		return nil, fmt.Errorf("could not generate subtitle: %w", err)
 	}
 	return subtitleData, nil
 }
 
+// writeTrace stores the trace of the provider calls alongside the subtitle. A trace failing to be written
+// doesn't fail the file, whose outcome it is meant to explain.
+func (s *Subtitler) writeTrace(in *Input) {
+	if in.trace.Empty() {
+		return
+	}
+
+	data, err := json.MarshalIndent(in.trace, "", "  ")
+	if err != nil {
+		s.logger.Error("Could not marshal trace", slog.String("filename", in.FileName), slog.String("error", err.Error()))
+		return
+	}
+
+	name := traceName(subtitleName(in.FileName))
+
+	if _, err := s.storage.Write(in.Owner, in.OutputLanguage(), in.Project, name, data); err != nil {
+		s.logger.Error("Could not write trace", slog.String("filename", in.FileName), slog.String("error", err.Error()))
+		return
+	}
+	in.artifacts = append(in.artifacts, name)
+}
+
 func (s *Subtitler) removeFile(filePath string) {
 	if err := os.Remove(filePath); err != nil {
 		s.logger.Error("Could not remove file", slog.String("filepath", filePath), slog.String("error", err.Error()))
@@ -198,22 +974,120 @@ func readFile(path string) ([]byte, error) {
 	return data, nil
 }
 
-func writeFile(path string, data []byte) error {
-	f, err := os.Create(path)
+// subtitleName returns the name the subtitle of the named file is stored under.
+// Names are slugged since uploads can be named in any script.
+func subtitleName(name string) string {
+	name = slug.Make(name)
+	return strings.TrimSuffix(name, path.Ext(name)) + ".srt"
+}
+
+// outputName returns the name of the subtitle written in the given format.
+func outputName(subName string, f Format) string {
+	return strings.TrimSuffix(subName, ".srt") + f.Extension()
+}
+
+// anonymizedName returns the name of the research-safe artifact stored alongside the subtitle.
+func anonymizedName(subName string) string {
+	return strings.TrimSuffix(subName, ".srt") + ".anon.srt"
+}
+
+// timelineName returns the name of the timeline artifact stored alongside the subtitle.
+func timelineName(subName string) string {
+	return strings.TrimSuffix(subName, ".srt") + ".timeline.json"
+}
+
+// analyticsName returns the name of the speaking analytics artifact stored alongside the subtitle.
+func analyticsName(subName string) string {
+	return strings.TrimSuffix(subName, ".srt") + ".analytics.json"
+}
+
+type timelineDocument struct {
+	Window float64     `json:"window"` // Seconds.
+	Points []nlp.Point `json:"points"`
+}
+
+// minutesName returns the name of the meeting minutes artifact stored alongside the subtitle.
+func minutesName(subName string) string {
+	return strings.TrimSuffix(subName, ".srt") + ".minutes.json"
+}
+
+// ChaptersName returns the name of the chapters stored alongside the named subtitle, in any format.
+func ChaptersName(subtitle string) string {
+	return strings.TrimSuffix(subtitle, path.Ext(subtitle)) + ".chapters.json"
+}
+
+// traceName returns the name of the provider trace artifact stored alongside the subtitle.
+func traceName(subName string) string {
+	return strings.TrimSuffix(subName, ".srt") + ".trace.json"
+}
+
+// cuesName returns the name of the JSON cues artifact stored alongside the subtitle.
+func cuesName(subName string) string {
+	return strings.TrimSuffix(subName, ".srt") + ".json"
+}
+
+type multilingualDocument struct {
+	Language  string        `json:"language"`
+	Languages []string      `json:"languages"`
+	Cues      []srt.JSONCue `json:"cues"`
+}
+
+// multilingualCues tags each cue with its identified language,
+// falling back to the transcription language when a cue is inconclusive.
+func multilingualCues(subData []byte, language string) ([]byte, error) {
+	cues, err := srt.Parse(subData)
 	if err != nil {
-		return fmt.Errorf("could not create file: %w", err)
+		return nil, fmt.Errorf("could not parse subtitle: %w", err)
 	}
 
-	if _, err := f.Write(data); err != nil {
-		return fmt.Errorf("could not write file: %w", err)
+	doc := multilingualDocument{
+		Language:  language,
+		Languages: []string{},
+		Cues:      make([]srt.JSONCue, 0, len(cues)),
 	}
 
-	if err := f.Close(); err != nil {
-		return fmt.Errorf("could not close file: %w", err)
+	seen := make(map[string]bool)
+
+	for _, cue := range cues {
+		jsonCue := cue.ToJSON()
+		jsonCue.Language, _ = langid.Detect(cue.Text, language)
+
+		if !seen[jsonCue.Language] {
+			seen[jsonCue.Language] = true
+			doc.Languages = append(doc.Languages, jsonCue.Language)
+		}
+		doc.Cues = append(doc.Cues, jsonCue)
 	}
-	return nil
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal cues: %w", err)
+	}
+	return data, nil
 }
 
-func subtitlePath(dir, name string) string {
-	return path.Join(dir, strings.Replace(name, path.Ext(name), ".srt", 1))
+// repair fixes the cues transcribed by the provider: it drops the cues without a duration, trims the
+// overlapping ones, lays out their text and numbers them sequentially, as some providers don't.
+// The fixes are recorded in the pipeline of the subtitle.
+func (s *Subtitler) repair(subData []byte, in *Input) ([]byte, error) {
+	cues, err := srt.Parse(subData)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse subtitle: %w", err)
+	}
+
+	repaired, report := srt.Repair(cues, s.firstCueIndex, s.layout)
+	in.repairs = report
+
+	if report.Fixed() {
+		s.logger.Debug("Repaired subtitle cues",
+			slog.String("filename", in.FileName),
+			slog.Int("dropped", report.Dropped),
+			slog.Int("overlaps", report.Overlaps),
+			slog.Int("wrapped", report.Wrapped),
+			slog.Int("split", report.Split),
+			slog.Int("extended", report.Extended),
+			slog.Int("renumbered", report.Renumbered),
+		)
+	}
+	return srt.Format(repaired), nil
 }
@@ -0,0 +1,38 @@
+package subtitles
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Task is what Whisper does with the speech: transcribe it in its language, or translate it.
+type Task string
+
+const (
+	TaskTranscribe Task = "transcribe"
+	TaskTranslate  Task = "translate" // Translates the speech to English, the only language Whisper translates to.
+)
+
+// TranslationLanguage is the language of translated subtitles.
+const TranslationLanguage string = "en"
+
+// ParseTask validates the task name. An empty name is the transcribe task.
+func ParseTask(name string) (Task, error) {
+	switch t := Task(strings.ToLower(name)); t {
+	case "", TaskTranscribe:
+		return TaskTranscribe, nil
+	case TaskTranslate:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unsupported task %q", name)
+	}
+}
+
+// OutputLanguage returns the language of the subtitle of the input, which is stored under it:
+// English when translating, the spoken language otherwise.
+func (in *Input) OutputLanguage() string {
+	if in.Task == TaskTranslate {
+		return TranslationLanguage
+	}
+	return in.Language
+}
@@ -0,0 +1,85 @@
+package subtitles
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/videoscriber/internal/pkg/translate"
+)
+
+// maxTranslations bounds the number of target languages of a subtitle, each translation calling the provider.
+const maxTranslations int = 10
+
+type translator interface {
+	Translate(ctx context.Context, texts []string, source, target string) ([]string, error)
+}
+
+// ParseLanguages parses a comma separated list of target languages, e.g. "en,es", skipping duplicates.
+func ParseLanguages(list string) ([]string, error) {
+	var languages []string
+
+	for _, code := range strings.Split(list, ",") {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if code == "" || slices.Contains(languages, code) {
+			continue
+		}
+
+		if !SupportedLanguage(code) {
+			return nil, fmt.Errorf("unsupported language %q", code)
+		}
+		languages = append(languages, code)
+	}
+
+	if len(languages) > maxTranslations {
+		return nil, fmt.Errorf("at most %d languages, got %d", maxTranslations, len(languages))
+	}
+	return languages, nil
+}
+
+// translationName returns the name of the translation of the subtitle to the language,
+// e.g. video.en.srt next to video.srt.
+func translationName(subName, language string, f Format) string {
+	return outputName(strings.TrimSuffix(subName, ".srt")+"."+language+".srt", f)
+}
+
+// writeTranslations translates the cues of the subtitle to each target language of the input,
+// and stores the translations in the format of the subtitle, under their language.
+func (s *Subtitler) writeTranslations(ctx context.Context, in *Input, subName string, subData []byte) error {
+	if len(in.Translations) == 0 {
+		return nil
+	}
+
+	cues, err := srt.Parse(subData)
+	if err != nil {
+		return fmt.Errorf("could not parse subtitle: %w", err)
+	}
+
+	source := in.OutputLanguage()
+
+	for _, target := range in.Translations {
+		if target == source {
+			return fmt.Errorf("the subtitle is in %q already", target)
+		}
+
+		translated, err := translate.Cues(ctx, s.translator, cues, source, target)
+		if err != nil {
+			return fmt.Errorf("could not translate to %q: %w", target, err)
+		}
+
+		data, err := convert(srt.Format(translated), in.Format, target)
+		if err != nil {
+			return fmt.Errorf("could not convert translation to %s: %w", in.Format, err)
+		}
+
+		name := translationName(subName, target, in.Format)
+
+		if _, err := s.storage.Write(in.Owner, target, in.Project, name, data); err != nil {
+			return fmt.Errorf("could not write translation file: %w", err)
+		}
+		in.artifacts = append(in.artifacts, name)
+	}
+	return nil
+}
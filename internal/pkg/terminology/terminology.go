@@ -0,0 +1,259 @@
+package terminology
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+const (
+	// maxTerms bounds the size of a glossary.
+	maxTerms int = 500
+
+	// maxTermWords bounds the number of words of a term or variant.
+	maxTermWords int = 5
+)
+
+// ErrInvalidGlossary is returned for glossaries without terms, with too many terms or with empty ones.
+var ErrInvalidGlossary = errors.New("invalid glossary")
+
+// Term is a glossary entry: the expected spelling of a term and the variants to flag.
+// Variants only need to list spellings that differ by more than case, spaces and hyphens,
+// e.g. "k8s" for "Kubernetes", since those are matched anyway.
+// A term with a language only applies to the subtitles in that language, which allows
+// a glossary to give the expected translation of a term in each language.
+type Term struct {
+	Term     string   `json:"term"`
+	Variants []string `json:"variants,omitempty"`
+	Language string   `json:"language,omitempty"`
+}
+
+// Document is a subtitle to check.
+type Document struct {
+	Name     string
+	Language string
+	Cues     []srt.Cue
+}
+
+// Spelling is a spelling of a term found in the subtitles.
+type Spelling struct {
+	Spelling string `json:"spelling"`
+	Count    int    `json:"count"`
+	Expected bool   `json:"expected"`
+}
+
+// Occurrence locates a spelling of a term that differs from the glossary.
+type Occurrence struct {
+	Subtitle string  `json:"subtitle"`
+	Cue      int     `json:"cue"`   // Position of the cue in the subtitle, starting at 1.
+	Start    float64 `json:"start"` // Seconds.
+	End      float64 `json:"end"`   // Seconds.
+	Spelling string  `json:"spelling"`
+	Text     string  `json:"text"`
+}
+
+// TermReport reports the spellings of a glossary term found in the subtitles.
+type TermReport struct {
+	Term        string       `json:"term"`
+	Language    string       `json:"language,omitempty"`
+	Consistent  bool         `json:"consistent"`
+	Spellings   []Spelling   `json:"spellings"`
+	Occurrences []Occurrence `json:"occurrences"`
+}
+
+// Report is the result of checking the terminology of subtitles against a glossary.
+// Only the terms found in the subtitles are reported, inconsistent ones first.
+type Report struct {
+	Subtitles []string     `json:"subtitles"`
+	Terms     []TermReport `json:"terms"`
+	Issues    int          `json:"issues"` // Number of occurrences that differ from the glossary.
+}
+
+// Validate checks the glossary.
+func Validate(glossary []Term) error {
+	if len(glossary) == 0 || len(glossary) > maxTerms {
+		return fmt.Errorf("%w: between 1 and %d terms are required", ErrInvalidGlossary, maxTerms)
+	}
+
+	for i, t := range glossary {
+		for _, form := range append([]string{t.Term}, t.Variants...) {
+			words := len(words(form))
+			if words == 0 || words > maxTermWords {
+				return fmt.Errorf("%w: term %d must have between 1 and %d words", ErrInvalidGlossary, i+1, maxTermWords)
+			}
+		}
+	}
+	return nil
+}
+
+// Check finds the spellings of the glossary terms in the documents and flags those that differ from the glossary.
+// Terms are matched regardless of case, spaces and hyphens, so "Video-Scriber" and "videoscriber"
+// are both spellings of "VideoScriber".
+func Check(docs []Document, glossary []Term) Report {
+	report := Report{Subtitles: make([]string, 0, len(docs)), Terms: []TermReport{}}
+
+	reports := make([]TermReport, len(glossary))
+	counts := make([]map[string]int, len(glossary))
+
+	for i, t := range glossary {
+		reports[i] = TermReport{Term: t.Term, Language: t.Language, Occurrences: []Occurrence{}}
+		counts[i] = make(map[string]int)
+	}
+
+	for _, doc := range docs {
+		report.Subtitles = append(report.Subtitles, doc.Name)
+
+		m := newMatcher(glossary, doc.Language)
+
+		for i, cue := range doc.Cues {
+			for _, match := range m.find(cue.Text) {
+				counts[match.term][match.spelling]++
+
+				if match.spelling == glossary[match.term].Term {
+					continue
+				}
+
+				reports[match.term].Occurrences = append(reports[match.term].Occurrences, Occurrence{
+					Subtitle: doc.Name,
+					Cue:      i + 1,
+					Start:    cue.Start.Seconds(),
+					End:      cue.End.Seconds(),
+					Spelling: match.spelling,
+					Text:     strings.Join(strings.Fields(cue.Text), " "),
+				})
+			}
+		}
+	}
+
+	for i, tr := range reports {
+		if len(counts[i]) == 0 {
+			continue
+		}
+
+		for spelling, count := range counts[i] {
+			tr.Spellings = append(tr.Spellings, Spelling{Spelling: spelling, Count: count, Expected: spelling == tr.Term})
+		}
+
+		sort.Slice(tr.Spellings, func(a, b int) bool {
+			if tr.Spellings[a].Count != tr.Spellings[b].Count {
+				return tr.Spellings[a].Count > tr.Spellings[b].Count
+			}
+			return tr.Spellings[a].Spelling < tr.Spellings[b].Spelling
+		})
+
+		tr.Consistent = len(tr.Occurrences) == 0
+		report.Issues += len(tr.Occurrences)
+		report.Terms = append(report.Terms, tr)
+	}
+
+	sort.SliceStable(report.Terms, func(a, b int) bool {
+		return !report.Terms[a].Consistent && report.Terms[b].Consistent
+	})
+	return report
+}
+
+// span is a word of a text and its position.
+type span struct {
+	start, end int
+	word       string
+}
+
+// words splits the text in words of letters and digits.
+func words(text string) []span {
+	var (
+		spans []span
+		start = -1
+	)
+
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			spans = append(spans, span{start: start, end: i, word: text[start:i]})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		spans = append(spans, span{start: start, end: len(text), word: text[start:]})
+	}
+	return spans
+}
+
+// key is the form of a sequence of words ignoring case and separators.
+func key(spans []span) string {
+	var b strings.Builder
+	for _, s := range spans {
+		b.WriteString(strings.ToLower(s.word))
+	}
+	return b.String()
+}
+
+// match is a spelling of a glossary term found in a text.
+type match struct {
+	term     int // Index of the term in the glossary.
+	spelling string
+}
+
+// matcher finds the glossary terms applying to a language.
+type matcher struct {
+	terms    map[string]int // Term index by the key of the term and its variants.
+	maxWords int
+}
+
+func newMatcher(glossary []Term, language string) matcher {
+	m := matcher{terms: make(map[string]int)}
+
+	for i, t := range glossary {
+		if t.Language != "" && t.Language != language {
+			continue
+		}
+
+		for _, form := range append([]string{t.Term}, t.Variants...) {
+			spans := words(form)
+			if _, ok := m.terms[key(spans)]; !ok {
+				m.terms[key(spans)] = i
+			}
+			// One more word to also match terms split in two, e.g. "video scriber".
+			m.maxWords = max(m.maxWords, len(spans)+1)
+		}
+	}
+	return m
+}
+
+// find returns the spellings of the terms in the text, preferring the longest match at each word.
+func (m matcher) find(text string) []match {
+	spans := words(text)
+
+	var matches []match
+
+	for i := 0; i < len(spans); {
+		matched := false
+
+		for n := min(m.maxWords, len(spans)-i); n > 0; n-- {
+			term, ok := m.terms[key(spans[i:i+n])]
+			if !ok {
+				continue
+			}
+
+			spelling := strings.Join(strings.Fields(text[spans[i].start:spans[i+n-1].end]), " ")
+			matches = append(matches, match{term: term, spelling: spelling})
+
+			i += n
+			matched = true
+			break
+		}
+
+		if !matched {
+			i++
+		}
+	}
+	return matches
+}
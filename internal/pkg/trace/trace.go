@@ -0,0 +1,245 @@
+// Package trace records the requests sent to the transcription providers and their responses,
+// for diagnosing the quality and the errors of a provider. Traces are opt-in per file: the HTTP
+// transport of the provider clients only records the requests whose context carries a call.
+// Credentials are redacted and the audio is never recorded, only its size.
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBody is the size of the longest response body recorded. Longer bodies are truncated.
+const maxBody int = 64 << 10
+
+// redacted replaces the values of the headers and query parameters carrying credentials.
+const redacted string = "REDACTED"
+
+// sensitive are the fragments of the names of the headers and query parameters carrying credentials.
+var sensitive = []string{"authorization", "cookie", "key", "token", "secret", "signature", "password"}
+
+// Trace is the record of the provider calls made for a file. It is safe for concurrent use.
+type Trace struct {
+	mu    sync.Mutex
+	calls []*Call
+	now   func() time.Time
+}
+
+// New returns an empty trace.
+func New() *Trace {
+	return &Trace{now: time.Now}
+}
+
+// Call is a transcription request made to a provider, with the HTTP exchanges it took,
+// e.g. several when the provider fails over to another endpoint.
+type Call struct {
+	Provider       string  `json:"provider"`
+	Model          string  `json:"model,omitempty"`
+	Language       string  `json:"language,omitempty"`
+	Format         string  `json:"format"`
+	Prompt         string  `json:"prompt,omitempty"`
+	Translate      bool    `json:"translate,omitempty"`
+	WordTimestamps bool    `json:"word_timestamps,omitempty"`
+	Diarize        bool    `json:"diarize,omitempty"`
+	VAD            bool    `json:"vad,omitempty"`
+	AudioSize      int     `json:"audio_size"`     // Bytes.
+	AudioDuration  float64 `json:"audio_duration"` // Seconds.
+
+	StartedAt time.Time  `json:"started_at"`
+	Duration  float64    `json:"duration"` // Seconds.
+	Error     string     `json:"error,omitempty"`
+	Exchanges []Exchange `json:"exchanges"`
+
+	trace *Trace
+	start time.Time
+}
+
+// Exchange is an HTTP request sent to a provider and its response. The request body is not recorded.
+type Exchange struct {
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestSize     int64             `json:"request_size"` // Bytes, -1 when unknown.
+	Status          int               `json:"status,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	Response        string            `json:"response,omitempty"`
+	Truncated       bool              `json:"truncated,omitempty"` // Whether the response was longer than recorded.
+	Duration        float64           `json:"duration"`            // Seconds.
+	Error           string            `json:"error,omitempty"`
+}
+
+type callKey struct{}
+
+// Start records the call and returns a context carrying it, so that the HTTP exchanges of the call are
+// recorded by the Transport. A nil trace records nothing and returns the context as is, with a nil call.
+func (t *Trace) Start(ctx context.Context, c Call) (context.Context, *Call) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c.trace, c.start = t, t.now()
+	c.StartedAt = c.start.UTC()
+	c.Exchanges = []Exchange{}
+
+	call := &c
+	t.calls = append(t.calls, call)
+
+	return context.WithValue(ctx, callKey{}, call), call
+}
+
+// End records the outcome of the call. It does nothing on a nil call.
+func (c *Call) End(err error) {
+	if c == nil {
+		return
+	}
+
+	c.trace.mu.Lock()
+	defer c.trace.mu.Unlock()
+
+	c.Duration = c.trace.now().Sub(c.start).Seconds()
+	if err != nil {
+		c.Error = err.Error()
+	}
+}
+
+func (c *Call) record(e Exchange) {
+	c.trace.mu.Lock()
+	defer c.trace.mu.Unlock()
+
+	c.Exchanges = append(c.Exchanges, e)
+}
+
+// Empty reports whether no call was recorded.
+func (t *Trace) Empty() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.calls) == 0
+}
+
+// MarshalJSON returns the calls of the trace.
+func (t *Trace) MarshalJSON() ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return json.Marshal(struct {
+		Calls []*Call `json:"calls"`
+	}{Calls: t.calls})
+}
+
+// Transport is an HTTP transport recording the exchanges of the requests made for a traced call.
+type Transport struct {
+	base http.RoundTripper
+}
+
+// NewTransport returns a transport sending the requests with base, http.DefaultTransport when nil.
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base}
+}
+
+// RoundTrip sends the request, recording it and its response when its context carries a call.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	call, _ := req.Context().Value(callKey{}).(*Call)
+	if call == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	e := Exchange{
+		Method:         req.Method,
+		URL:            sanitizeURL(req.URL),
+		RequestHeaders: sanitizeHeaders(req.Header),
+		RequestSize:    req.ContentLength,
+	}
+
+	start := call.trace.now()
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		e.Duration = call.trace.now().Sub(start).Seconds()
+		e.Error = err.Error()
+		call.record(e)
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// The response is handed over as read, so that the client sees the same error.
+	var rest io.Reader = bytes.NewReader(body)
+	if err != nil {
+		rest = io.MultiReader(rest, errReader{err})
+		e.Error = fmt.Sprintf("could not read response body: %s", err)
+	}
+	resp.Body = io.NopCloser(rest)
+
+	e.Duration = call.trace.now().Sub(start).Seconds()
+	e.Status = resp.StatusCode
+	e.ResponseHeaders = sanitizeHeaders(resp.Header)
+
+	if len(body) > maxBody {
+		body, e.Truncated = body[:maxBody], true
+	}
+	e.Response = string(body)
+
+	call.record(e)
+	return resp, nil
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitive {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func sanitizeHeaders(h http.Header) map[string]string {
+	headers := make(map[string]string, len(h))
+	for name, values := range h {
+		if isSensitive(name) {
+			headers[name] = redacted
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+func sanitizeURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+
+	query := c.Query()
+	for name := range query {
+		if isSensitive(name) {
+			query.Set(name, redacted)
+		}
+	}
+	c.RawQuery = query.Encode()
+
+	return c.String()
+}
@@ -0,0 +1,180 @@
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache stores the responses of the providers on disk, keyed by the audio and the options of the
+// requests, so that transcribing the same audio again doesn't call the provider until they expire.
+type Cache struct {
+	logger *slog.Logger
+	dir    string
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewCache returns a cache of the responses in dir, kept for ttl.
+func NewCache(logger *slog.Logger, dir string, ttl time.Duration) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create cache directory: %w", err)
+	}
+	return &Cache{logger: logger, dir: dir, ttl: ttl, now: time.Now}, nil
+}
+
+func (c *Cache) get(key string) ([]byte, bool) {
+	path := filepath.Join(c.dir, key)
+
+	info, err := os.Stat(path)
+	if err != nil || c.now().Sub(info.ModTime()) > c.ttl {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// put stores the response through a temporary file, so that concurrent readers never see a partial one.
+func (c *Cache) put(key string, data []byte) error {
+	f, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not create cache file: %w", err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("could not write cache file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("could not close cache file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), filepath.Join(c.dir, key)); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("could not store cache file: %w", err)
+	}
+	return nil
+}
+
+// Sweep removes the expired responses.
+func (c *Cache) Sweep() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("could not list cache directory: %w", err)
+	}
+
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || c.now().Sub(info.ModTime()) <= c.ttl {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(c.dir, e.Name())); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove cache file: %w", err)
+		}
+	}
+	return nil
+}
+
+// Run sweeps the expired responses at each interval until the context is done.
+func (c *Cache) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Sweep(); err != nil {
+				c.logger.Error("Could not sweep transcription cache", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// CachedTranscriber serves the responses of a provider from the cache, when it has them.
+type CachedTranscriber struct {
+	provider    string
+	transcriber Transcriber
+	cache       *Cache
+}
+
+// NewCachedTranscriber returns the transcriber of the provider caching its responses.
+func NewCachedTranscriber(provider string, t Transcriber, cache *Cache) *CachedTranscriber {
+	return &CachedTranscriber{provider: provider, transcriber: t, cache: cache}
+}
+
+// Transcribe returns the cached response to the request, or forwards the request and caches its response.
+func (c *CachedTranscriber) Transcribe(ctx context.Context, req Request) ([]byte, error) {
+	audio, err := io.ReadAll(req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("could not read audio: %w", err)
+	}
+
+	key := c.key(req, audio)
+
+	if data, ok := c.cache.get(key); ok {
+		return data, nil
+	}
+
+	req.Data = bytes.NewReader(audio)
+
+	data, err := c.transcriber.Transcribe(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The response is served anyway, only the next requests miss the cache.
+	if err := c.cache.put(key, data); err != nil {
+		c.cache.logger.Error("Could not cache transcription", slog.String("provider", c.provider), slog.String("error", err.Error()))
+	}
+	return data, nil
+}
+
+// Describe tells the provider and model of the transcriber, when it does.
+func (c *CachedTranscriber) Describe(req Request) (string, string) {
+	if d, ok := c.transcriber.(Describer); ok {
+		return d.Describe(req)
+	}
+	return c.provider, ""
+}
+
+// key identifies the response to the request by the audio and the options changing the response,
+// including the model serving it, so that a pinned or upgraded model isn't served another's responses.
+func (c *CachedTranscriber) key(req Request, audio []byte) string {
+	provider, model := c.Describe(req)
+
+	audioSum := sha256.Sum256(audio)
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		provider,
+		model,
+		req.Language,
+		req.Format,
+		req.Prompt,
+		strconv.FormatBool(req.Translate),
+		strconv.FormatBool(req.WordTimestamps),
+		strconv.FormatBool(req.Diarize),
+		strconv.FormatBool(req.VAD),
+		hex.EncodeToString(audioSum[:]),
+	}, "\x00")))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,259 @@
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// minCooldown and maxCooldown bound how long a failing endpoint is skipped. The cooldown doubles
+	// with each consecutive failure.
+	minCooldown time.Duration = 30 * time.Second
+	maxCooldown time.Duration = 5 * time.Minute
+
+	// latencyWeight is the weight of the last measure in the moving average of the latency of an endpoint.
+	latencyWeight float64 = 0.3
+)
+
+// EndpointConfig configures an additional endpoint of a provider, e.g. in another region.
+// Endpoints expose the OpenAI compatible transcription endpoint. When HealthURL is set,
+// it is probed periodically and the endpoint is skipped while the probe fails.
+type EndpointConfig struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Token     string `json:"token,omitempty"`
+	Model     string `json:"model,omitempty"`
+	HealthURL string `json:"health_url,omitempty"`
+}
+
+// LoadEndpoints reads the additional endpoints of the providers from a JSON file, by provider name.
+func LoadEndpoints(path string) (map[string][]EndpointConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read endpoints: %w", err)
+	}
+
+	var endpoints map[string][]EndpointConfig
+	if err := json.Unmarshal(data, &endpoints); err != nil {
+		return nil, fmt.Errorf("could not unmarshal endpoints: %w", err)
+	}
+
+	for provider, configs := range endpoints {
+		for i, c := range configs {
+			if c.Name == "" || c.URL == "" {
+				return nil, fmt.Errorf("endpoint %d of provider %s requires a name and a URL", i+1, provider)
+			}
+		}
+	}
+	return endpoints, nil
+}
+
+// Endpoint is an endpoint of a provider.
+type Endpoint struct {
+	Name        string
+	Transcriber Transcriber
+	HealthURL   string // Optional.
+}
+
+// endpointState is the health and latency of an endpoint.
+type endpointState struct {
+	Endpoint
+
+	latency  float64 // Moving average of the seconds taken per second of audio. Zero until measured.
+	failures int
+	downTill time.Time
+}
+
+// Failover is a transcriber sending requests to the fastest healthy endpoint of a provider,
+// and to the next ones when it fails.
+type Failover struct {
+	logger   *slog.Logger
+	provider string
+	httpCli  *http.Client
+
+	mu        sync.Mutex
+	endpoints []*endpointState
+}
+
+// NewFailover returns a new failover transcriber over the endpoints of the provider.
+// The HTTP client is used to probe the health URLs of the endpoints.
+func NewFailover(logger *slog.Logger, provider string, httpCli *http.Client, endpoints []Endpoint) (*Failover, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("provider %s has no endpoints", provider)
+	}
+
+	f := Failover{logger: logger, provider: provider, httpCli: httpCli}
+	for _, e := range endpoints {
+		f.endpoints = append(f.endpoints, &endpointState{Endpoint: e})
+	}
+	return &f, nil
+}
+
+// Transcribe tries the endpoints from the fastest healthy one until one succeeds.
+// Endpoints that are down are only tried when all the others failed.
+func (f *Failover) Transcribe(ctx context.Context, req Request) ([]byte, error) {
+	audio, err := io.ReadAll(req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("could not read audio: %w", err)
+	}
+
+	var errs []error
+
+	for _, e := range f.candidates() {
+		req.Data = bytes.NewReader(audio)
+
+		start := time.Now()
+
+		data, err := e.Transcriber.Transcribe(ctx, req)
+		if err == nil {
+			f.succeeded(e, time.Since(start), req.Duration)
+			return data, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		f.failed(e, err)
+		errs = append(errs, fmt.Errorf("endpoint %s: %w", e.Name, err))
+	}
+	return nil, fmt.Errorf("all endpoints of %s failed: %w", f.provider, errors.Join(errs...))
+}
+
+// Describe returns the provider and the model of the endpoint the request would be sent to first.
+func (f *Failover) Describe(req Request) (string, string) {
+	if d, ok := f.candidates()[0].Transcriber.(Describer); ok {
+		_, model := d.Describe(req)
+		return f.provider, model
+	}
+	return f.provider, ""
+}
+
+// Run probes the health URLs of the endpoints at each interval until the context is done.
+func (f *Failover) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		f.probe(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *Failover) probe(ctx context.Context) {
+	f.mu.Lock()
+	endpoints := append([]*endpointState(nil), f.endpoints...)
+	f.mu.Unlock()
+
+	for _, e := range endpoints {
+		if e.HealthURL == "" {
+			continue
+		}
+
+		if err := f.check(ctx, e.HealthURL); err != nil {
+			f.failed(e, fmt.Errorf("health check: %w", err))
+			continue
+		}
+
+		f.mu.Lock()
+		if e.failures > 0 {
+			f.logger.Info("Transcription endpoint is back up", slog.String("provider", f.provider), slog.String("endpoint", e.Name))
+		}
+		e.failures, e.downTill = 0, time.Time{}
+		f.mu.Unlock()
+	}
+}
+
+func (f *Failover) check(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+
+	resp, err := f.httpCli.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send request: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// candidates returns the endpoints in the order to try them: the healthy ones from the fastest,
+// endpoints not measured yet first, then the ones that are down, from the first to come back up.
+func (f *Failover) candidates() []*endpointState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+
+	endpoints := append([]*endpointState(nil), f.endpoints...)
+
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		a, b := endpoints[i], endpoints[j]
+
+		aDown, bDown := now.Before(a.downTill), now.Before(b.downTill)
+		switch {
+		case aDown != bDown:
+			return !aDown
+		case aDown:
+			return a.downTill.Before(b.downTill)
+		default:
+			return a.latency < b.latency
+		}
+	})
+	return endpoints
+}
+
+func (f *Failover) succeeded(e *endpointState, took, audio time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	latency := took.Seconds() / max(audio.Seconds(), 1)
+
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = latencyWeight*latency + (1-latencyWeight)*e.latency
+	}
+
+	e.failures, e.downTill = 0, time.Time{}
+}
+
+func (f *Failover) failed(e *endpointState, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cooldown := min(minCooldown<<min(e.failures, 8), maxCooldown)
+
+	e.failures++
+	e.downTill = time.Now().Add(cooldown)
+
+	f.logger.Warn("Transcription endpoint failed",
+		slog.String("provider", f.provider),
+		slog.String("endpoint", e.Name),
+		slog.Duration("cooldown", cooldown),
+		slog.String("error", err.Error()),
+	)
+}
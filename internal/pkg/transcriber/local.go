@@ -0,0 +1,129 @@
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+const (
+	localTranscriptionPath string = "/v1/audio/transcriptions"
+	localTranslationPath   string = "/v1/audio/translations"
+)
+
+// Local transcribes audio with a self-hosted faster-whisper or whisperX server
+// exposing the OpenAI compatible transcription endpoint.
+type Local struct {
+	httpCli *http.Client
+	baseURL string
+	token   string
+	model   string
+}
+
+// NewLocal returns a new transcriber for the server at baseURL.
+// The token is sent as a bearer token when not empty.
+func NewLocal(httpCli *http.Client, baseURL, token, model string) *Local {
+	return &Local{
+		httpCli: httpCli,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		model:   model,
+	}
+}
+
+// Transcribe sends the audio to the local server, to its translation endpoint when translating.
+func (l *Local) Transcribe(ctx context.Context, req Request) ([]byte, error) {
+	var body bytes.Buffer
+
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile("file", req.Name)
+	if err != nil {
+		return nil, fmt.Errorf("could not create form file: %w", err)
+	}
+
+	if _, err := io.Copy(part, req.Data); err != nil {
+		return nil, fmt.Errorf("could not copy data to form file: %w", err)
+	}
+
+	fields := [][2]string{
+		{"model", l.modelFor(req)},
+		{"response_format", req.Format},
+	}
+
+	path := localTranslationPath
+
+	// The translation endpoint detects the spoken language.
+	if !req.Translate {
+		fields = append(fields, [2]string{"language", req.Language})
+		path = localTranscriptionPath
+	}
+
+	if req.WordTimestamps {
+		fields = append(fields, [2]string{"timestamp_granularities[]", "word"})
+	}
+
+	if req.Diarize {
+		fields = append(fields, [2]string{"diarize", "true"})
+	}
+
+	if req.VAD {
+		fields = append(fields, [2]string{"vad_filter", "true"})
+	}
+
+	if req.Prompt != "" {
+		fields = append(fields, [2]string{"prompt", req.Prompt})
+	}
+
+	for _, field := range fields {
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			return nil, fmt.Errorf("could not write %s field: %w", field[0], err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("could not close writer: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, l.baseURL+path, &body)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+
+	if l.token != "" {
+		request.Header.Set("Authorization", "Bearer "+l.token)
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	response, err := l.httpCli.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request: %w", err)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read response body: %w", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d: %s", response.StatusCode, data)
+	}
+	return data, nil
+}
+
+// Describe returns the local provider and the model serving the request.
+func (l *Local) Describe(req Request) (string, string) {
+	return ProviderLocal, l.modelFor(req)
+}
+
+func (l *Local) modelFor(req Request) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return l.model
+}
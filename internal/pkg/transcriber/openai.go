@@ -0,0 +1,60 @@
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alesr/whisperclient"
+)
+
+type whisperClient interface {
+	TranscribeAudio(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error)
+}
+
+// OpenAI transcribes audio with the OpenAI Whisper API.
+type OpenAI struct {
+	client     whisperClient
+	compatible *Local
+	model      string
+}
+
+// NewOpenAI returns a new OpenAI transcriber. The model is the one the client is configured with.
+// The client doesn't support prompts nor translations, so those requests are sent through the compatible
+// transcriber of the OpenAI API, e.g. NewLocal with the API URL and key.
+func NewOpenAI(client whisperClient, compatible *Local, model string) *OpenAI {
+	return &OpenAI{client: client, compatible: compatible, model: model}
+}
+
+// Transcribe calls the Whisper API. Word timestamps, diarization and VAD are not supported and ignored.
+// The model of the client can't be overridden.
+func (o *OpenAI) Transcribe(ctx context.Context, req Request) ([]byte, error) {
+	if req.Model != "" && req.Model != o.model {
+		return nil, fmt.Errorf("%w: %q, the OpenAI provider uses %q", ErrModelUnavailable, req.Model, o.model)
+	}
+
+	if req.Prompt != "" || req.Translate {
+		if o.compatible == nil {
+			return nil, errors.New("prompts and translations are not supported by the OpenAI provider")
+		}
+
+		req.Model, req.WordTimestamps, req.Diarize, req.VAD = o.model, false, false, false
+		return o.compatible.Transcribe(ctx, req)
+	}
+
+	data, err := o.client.TranscribeAudio(ctx, whisperclient.TranscribeAudioInput{
+		Name:     req.Name,
+		Language: req.Language,
+		Format:   req.Format,
+		Data:     req.Data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not transcribe audio: %w", err)
+	}
+	return data, nil
+}
+
+// Describe returns the OpenAI provider and its model.
+func (o *OpenAI) Describe(req Request) (string, string) {
+	return ProviderOpenAI, o.model
+}
@@ -0,0 +1,165 @@
+package transcriber
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// ProviderAuto is the name of the provider routing requests according to a policy.
+const ProviderAuto string = "auto"
+
+// ErrNoRoute is returned when no rule matches the request and the policy has no fallback.
+var ErrNoRoute = errors.New("no provider matches the request")
+
+// Rule routes the requests matching all of its non-empty conditions to a provider.
+type Rule struct {
+	Name        string   `json:"name"`
+	Provider    string   `json:"provider"`
+	MinDuration Duration `json:"min_duration,omitempty"`
+	MaxDuration Duration `json:"max_duration,omitempty"`
+	Languages   []string `json:"languages,omitempty"`
+	Priorities  []string `json:"priorities,omitempty"`
+	Tenants     []string `json:"tenants,omitempty"`
+}
+
+// Policy is an ordered list of rules. The first matching rule wins.
+type Policy struct {
+	Rules    []Rule `json:"rules"`
+	Fallback string `json:"fallback"`
+}
+
+// Duration is a time.Duration encoded as a string in JSON, e.g. "10m".
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("could not unmarshal duration: %w", err)
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("could not parse duration: %w", err)
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON encodes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadPolicy reads a routing policy from a JSON file.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read routing policy: %w", err)
+	}
+
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("could not unmarshal routing policy: %w", err)
+	}
+	return &p, nil
+}
+
+// Match returns the provider of the first rule matching the request.
+func (p *Policy) Match(req Request) (string, error) {
+	for _, rule := range p.Rules {
+		if rule.matches(req) {
+			return rule.Provider, nil
+		}
+	}
+
+	if p.Fallback == "" {
+		return "", ErrNoRoute
+	}
+	return p.Fallback, nil
+}
+
+func (r Rule) matches(req Request) bool {
+	if r.MinDuration > 0 && req.Duration < time.Duration(r.MinDuration) {
+		return false
+	}
+
+	if r.MaxDuration > 0 && req.Duration > time.Duration(r.MaxDuration) {
+		return false
+	}
+
+	if len(r.Languages) > 0 && !slices.Contains(r.Languages, req.Language) {
+		return false
+	}
+
+	if len(r.Priorities) > 0 && !slices.Contains(r.Priorities, req.Priority) {
+		return false
+	}
+
+	if len(r.Tenants) > 0 && !slices.Contains(r.Tenants, req.Tenant) {
+		return false
+	}
+	return true
+}
+
+// Router is a transcriber delegating each request to the provider selected by the policy.
+type Router struct {
+	policy    *Policy
+	providers map[string]Transcriber
+}
+
+// NewRouter returns a new router. All providers referenced by the policy must be given.
+func NewRouter(policy *Policy, providers map[string]Transcriber) (*Router, error) {
+	referenced := []string{policy.Fallback}
+	for _, rule := range policy.Rules {
+		referenced = append(referenced, rule.Provider)
+	}
+
+	for _, name := range referenced {
+		if name == "" {
+			continue
+		}
+
+		if _, ok := providers[name]; !ok {
+			return nil, fmt.Errorf("routing policy references unknown provider %q", name)
+		}
+	}
+
+	return &Router{
+		policy:    policy,
+		providers: providers,
+	}, nil
+}
+
+// Transcribe forwards the request to the selected provider.
+func (r *Router) Transcribe(ctx context.Context, req Request) ([]byte, error) {
+	name, err := r.policy.Match(req)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := r.providers[name].Transcribe(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// Describe returns the provider selected by the policy, and its model when it tells it.
+func (r *Router) Describe(req Request) (string, string) {
+	name, err := r.policy.Match(req)
+	if err != nil {
+		return ProviderAuto, ""
+	}
+
+	if d, ok := r.providers[name].(Describer); ok {
+		return d.Describe(req)
+	}
+	return name, ""
+}
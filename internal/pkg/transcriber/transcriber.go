@@ -0,0 +1,59 @@
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	// ProviderOpenAI is the name of the OpenAI Whisper provider.
+	ProviderOpenAI string = "openai"
+
+	// ProviderLocal is the name of the self-hosted inference server provider.
+	ProviderLocal string = "local"
+)
+
+// Request represents a transcription request.
+type Request struct {
+	Name     string
+	Language string
+	Format   string
+	Data     io.Reader
+
+	// WordTimestamps and Diarize are passed through to providers supporting them.
+	WordTimestamps bool
+	Diarize        bool
+
+	// VAD skips the segments without speech, with the voice activity detection of the providers supporting it.
+	VAD bool
+
+	// Model, when set, overrides the model of the provider, e.g. to reproduce a previous transcription.
+	Model string
+
+	// Prompt is the initial prompt of Whisper, e.g. the names of products, people or jargon
+	// of the domain, to improve their recognition.
+	Prompt string
+
+	// Translate translates the speech to English instead of transcribing it. Language is then ignored.
+	Translate bool
+
+	// Duration, Priority and Tenant are used to route the request to a provider.
+	Duration time.Duration
+	Priority string
+	Tenant   string
+}
+
+// Transcriber transcribes audio data into the requested format.
+type Transcriber interface {
+	Transcribe(ctx context.Context, req Request) ([]byte, error)
+}
+
+// Describer is implemented by the transcribers telling the provider and the model serving a request.
+type Describer interface {
+	Describe(req Request) (provider, model string)
+}
+
+// ErrModelUnavailable is returned when the requested model can't be used by the provider.
+var ErrModelUnavailable = errors.New("model unavailable")
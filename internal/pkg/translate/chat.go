@@ -0,0 +1,62 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alesr/videoscriber/internal/pkg/llm"
+)
+
+// instructions ask the model for the translations as a JSON object, one per cue.
+const instructions string = `You translate the cues of subtitles.
+The user sends a JSON object with "source" and "target", ISO-639-1 language codes, and "cues", the texts of consecutive cues.
+Respond with a JSON object with "cues", the translation of each cue to the target language, in the same order.
+Translate each cue on its own, so that it stays in sync with the video, keeping its line breaks and speaker labels.`
+
+type chatClient interface {
+	Chat(ctx context.Context, model string, messages []llm.Message, jsonObject bool) (string, error)
+}
+
+// Chat translates with a chat model.
+type Chat struct {
+	chat  chatClient
+	model string
+}
+
+// NewChat returns a new translator using the chat model.
+func NewChat(chat chatClient, model string) *Chat {
+	return &Chat{chat: chat, model: model}
+}
+
+type chatRequest struct {
+	Source string   `json:"source"`
+	Target string   `json:"target"`
+	Cues   []string `json:"cues"`
+}
+
+type chatReply struct {
+	Cues []string `json:"cues"`
+}
+
+// Translate sends the texts to the model at once.
+func (c *Chat) Translate(ctx context.Context, texts []string, source, target string) ([]string, error) {
+	req, err := json.Marshal(chatRequest{Source: source, Target: target, Cues: texts})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal cues: %w", err)
+	}
+
+	reply, err := c.chat.Chat(ctx, c.model, []llm.Message{
+		{Role: "system", Content: instructions},
+		{Role: "user", Content: string(req)},
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var r chatReply
+	if err := json.Unmarshal([]byte(reply), &r); err != nil {
+		return nil, fmt.Errorf("could not decode translations: %w", err)
+	}
+	return r.Cues, nil
+}
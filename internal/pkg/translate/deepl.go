@@ -0,0 +1,86 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const deeplTranslatePath string = "/v2/translate"
+
+// deeplTargets are the target languages DeepL requires a regional variant of.
+var deeplTargets = map[string]string{
+	"en": "EN-US",
+	"pt": "PT-BR",
+}
+
+// DeepL translates with the DeepL API.
+type DeepL struct {
+	httpCli *http.Client
+	baseURL string
+	key     string
+}
+
+// NewDeepL returns a new translator calling the DeepL API at baseURL,
+// e.g. https://api-free.deepl.com for the free plan.
+func NewDeepL(httpCli *http.Client, baseURL, key string) *DeepL {
+	return &DeepL{httpCli: httpCli, baseURL: strings.TrimSuffix(baseURL, "/"), key: key}
+}
+
+type deeplRequest struct {
+	Text       []string `json:"text"`
+	SourceLang string   `json:"source_lang"`
+	TargetLang string   `json:"target_lang"`
+}
+
+type deeplResponse struct {
+	Translations []struct {
+		Text string `json:"text"`
+	} `json:"translations"`
+}
+
+// Translate sends the texts to the API in one request.
+func (d *DeepL) Translate(ctx context.Context, texts []string, source, target string) ([]string, error) {
+	targetLang, ok := deeplTargets[target]
+	if !ok {
+		targetLang = strings.ToUpper(target)
+	}
+
+	body, err := json.Marshal(deeplRequest{Text: texts, SourceLang: strings.ToUpper(source), TargetLang: targetLang})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.baseURL+deeplTranslatePath, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+d.key)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.httpCli.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, msg)
+	}
+
+	var r deeplResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("could not decode response: %w", err)
+	}
+
+	translations := make([]string, 0, len(r.Translations))
+	for _, t := range r.Translations {
+		translations = append(translations, t.Text)
+	}
+	return translations, nil
+}
@@ -0,0 +1,75 @@
+// Package translate translates the cues of subtitles to other languages, keeping their timestamps.
+package translate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+)
+
+// Translation providers.
+const (
+	ProviderChat  string = "chat" // A chat model of the OpenAI compatible API.
+	ProviderDeepL string = "deepl"
+)
+
+// batchSize is the number of cues translated per request, small enough for the limits of the providers
+// while giving the translation the context of the neighbouring cues.
+const batchSize int = 50
+
+// ErrUnknownProvider is returned for unsupported translation providers.
+var ErrUnknownProvider = errors.New("unknown translation provider")
+
+// Translator translates texts from the source to the target language, both ISO-639-1 codes.
+// The translations are returned in the order of the texts.
+type Translator interface {
+	Translate(ctx context.Context, texts []string, source, target string) ([]string, error)
+}
+
+// Cues translates the text of the cues, keeping their indexes and timestamps.
+func Cues(ctx context.Context, t Translator, cues []srt.Cue, source, target string) ([]srt.Cue, error) {
+	translated := make([]srt.Cue, 0, len(cues))
+
+	for start := 0; start < len(cues); start += batchSize {
+		batch := cues[start:min(start+batchSize, len(cues))]
+
+		texts := make([]string, 0, len(batch))
+		for _, cue := range batch {
+			texts = append(texts, cue.Text)
+		}
+
+		translations, err := t.Translate(ctx, texts, source, target)
+		if err != nil {
+			return nil, fmt.Errorf("could not translate cues %d to %d: %w", batch[0].Index, batch[len(batch)-1].Index, err)
+		}
+
+		if len(translations) != len(texts) {
+			return nil, fmt.Errorf("got %d translations of %d cues", len(translations), len(texts))
+		}
+
+		for i, cue := range batch {
+			cue.Text = translations[i]
+			translated = append(translated, cue)
+		}
+	}
+	return translated, nil
+}
+
+// New returns the translator of the provider. The chat provider translates with the model of the chat client,
+// while the DeepL provider requires an API key.
+func New(provider string, chat chatClient, model string, httpCli *http.Client, deeplURL, deeplKey string) (Translator, error) {
+	switch provider {
+	case ProviderChat:
+		return NewChat(chat, model), nil
+	case ProviderDeepL:
+		if deeplKey == "" {
+			return nil, fmt.Errorf("the %s translation provider requires an API key", provider)
+		}
+		return NewDeepL(httpCli, deeplURL, deeplKey), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, provider)
+	}
+}
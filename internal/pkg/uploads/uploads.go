@@ -0,0 +1,431 @@
+package uploads
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the upload does not exist.
+	ErrNotFound = errors.New("upload not found")
+
+	// ErrOffsetMismatch is returned when a chunk doesn't start where the upload stopped.
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+
+	// ErrTooLarge is returned when an upload exceeds its announced length or the maximum size.
+	ErrTooLarge = errors.New("upload exceeds its length")
+
+	// ErrCompleted is returned when writing to a completed upload.
+	ErrCompleted = errors.New("upload is completed")
+
+	// ErrBusy is returned when another chunk of the upload is being written.
+	ErrBusy = errors.New("upload is busy")
+
+	idRe = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+// Upload is a file uploaded in chunks, so that interrupted transfers can be resumed.
+type Upload struct {
+	ID        string            `json:"id"`
+	Owner     string            `json:"owner,omitempty"` // The user who created the upload, if authenticated.
+	Length    int64             `json:"length"`
+	Offset    int64             `json:"offset"`
+	Metadata  map[string]string `json:"metadata"`
+	JobID     string            `json:"job_id,omitempty"` // Set once the upload is completed and processed.
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+
+	// ExpiresAt is when the upload is removed unless resumed. Zero once complete, or when uploads don't expire.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Complete reports whether all the bytes of the upload were received.
+func (u Upload) Complete() bool {
+	return u.Offset == u.Length
+}
+
+// Expired reports whether the upload was left incomplete past its expiry.
+func (u Upload) Expired(now time.Time) bool {
+	return !u.ExpiresAt.IsZero() && now.After(u.ExpiresAt)
+}
+
+// Store keeps the uploads in a directory: the received bytes of each upload
+// in a data file, and its state next to it in a JSON file.
+type Store struct {
+	logger  *slog.Logger
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	writing map[string]bool
+}
+
+// NewStore returns a new store of uploads of at most maxSize bytes in dir.
+// Incomplete uploads expire once not resumed for ttl, unless zero.
+func NewStore(logger *slog.Logger, dir string, maxSize int64, ttl time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create uploads directory: %w", err)
+	}
+	return &Store{logger: logger, dir: dir, maxSize: maxSize, ttl: ttl, writing: make(map[string]bool)}, nil
+}
+
+// MaxSize returns the maximum size of an upload.
+func (s *Store) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Create starts an upload of length bytes on behalf of the owner, who can be empty.
+func (s *Store) Create(owner string, length int64, metadata map[string]string) (Upload, error) {
+	if length < 0 || length > s.maxSize {
+		return Upload{}, ErrTooLarge
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return Upload{}, fmt.Errorf("could not generate upload id: %w", err)
+	}
+
+	now := time.Now().UTC()
+
+	u := Upload{
+		ID:        hex.EncodeToString(b),
+		Owner:     owner,
+		Length:    length,
+		Metadata:  metadata,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	f, err := os.OpenFile(s.dataPath(u.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return Upload{}, fmt.Errorf("could not create upload file: %w", err)
+	}
+	f.Close()
+
+	if err := s.save(&u); err != nil {
+		os.Remove(s.dataPath(u.ID))
+		return Upload{}, err
+	}
+	return u, nil
+}
+
+// Get returns the upload. Expired uploads are not found.
+func (s *Store) Get(id string) (Upload, error) {
+	u, err := s.read(id)
+	if err != nil {
+		return Upload{}, err
+	}
+
+	if u.Expired(time.Now()) {
+		return Upload{}, ErrNotFound
+	}
+	return u, nil
+}
+
+// List returns the incomplete uploads of the owner, who can be empty, oldest first,
+// so that clients can resume or abandon them, e.g. after a restart.
+func (s *Store) List(owner string) ([]Upload, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var list []Upload
+	for _, id := range ids {
+		u, err := s.read(id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if u.Owner != owner || u.Complete() || u.Expired(now) {
+			continue
+		}
+		list = append(list, u)
+	}
+
+	slices.SortFunc(list, func(a, b Upload) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return list, nil
+}
+
+// Expire removes the uploads expired at now, except those being written, and returns how many were removed.
+func (s *Store) Expire(now time.Time) (int, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int
+	for _, id := range ids {
+		u, err := s.read(id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return removed, err
+		}
+
+		if !u.Expired(now) {
+			continue
+		}
+
+		if err := s.Remove(id); err != nil {
+			if errors.Is(err, ErrBusy) || errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return removed, fmt.Errorf("could not remove expired upload: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// Run removes the expired uploads every interval until the context is done.
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			removed, err := s.Expire(now)
+			if err != nil {
+				s.logger.Error("Could not remove expired uploads", slog.String("error", err.Error()))
+			}
+
+			if removed > 0 {
+				s.logger.Info("Removed expired uploads", slog.Int("count", removed))
+			}
+		}
+	}
+}
+
+// ids returns the IDs of the stored uploads.
+func (s *Store) ids() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read uploads directory: %w", err)
+	}
+
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), ".json"); ok && idRe.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// read returns the upload, expired or not.
+func (s *Store) read(id string) (Upload, error) {
+	if !idRe.MatchString(id) {
+		return Upload{}, ErrNotFound
+	}
+
+	data, err := os.ReadFile(s.infoPath(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Upload{}, ErrNotFound
+		}
+		return Upload{}, fmt.Errorf("could not read upload: %w", err)
+	}
+
+	var u Upload
+	if err := json.Unmarshal(data, &u); err != nil {
+		return Upload{}, fmt.Errorf("could not decode upload: %w", err)
+	}
+	return u, nil
+}
+
+// Append writes the chunk read from r at offset, which must be the current offset of the upload.
+// The bytes read before an error (e.g. an interrupted connection) are kept, so that the client
+// can resume from the returned offset.
+func (s *Store) Append(id string, offset int64, r io.Reader) (Upload, error) {
+	if !s.lock(id) {
+		return Upload{}, ErrBusy
+	}
+	defer s.unlock(id)
+
+	u, err := s.Get(id)
+	if err != nil {
+		return Upload{}, err
+	}
+
+	if u.Complete() {
+		return u, ErrCompleted
+	}
+
+	if offset != u.Offset {
+		return u, ErrOffsetMismatch
+	}
+
+	f, err := os.OpenFile(s.dataPath(id), os.O_WRONLY, 0o600)
+	if err != nil {
+		return u, fmt.Errorf("could not open upload file: %w", err)
+	}
+	defer f.Close()
+
+	// The file can be longer than the offset if saving the state failed after a write.
+	if err := f.Truncate(u.Offset); err != nil {
+		return u, fmt.Errorf("could not truncate upload file: %w", err)
+	}
+
+	if _, err := f.Seek(u.Offset, io.SeekStart); err != nil {
+		return u, fmt.Errorf("could not seek upload file: %w", err)
+	}
+
+	// One more byte than the remaining length is read to detect chunks exceeding it.
+	n, copyErr := io.Copy(f, io.LimitReader(r, u.Length-u.Offset+1))
+
+	if u.Offset+n > u.Length {
+		if err := f.Truncate(u.Offset); err != nil {
+			return u, fmt.Errorf("could not truncate upload file: %w", err)
+		}
+		return u, ErrTooLarge
+	}
+
+	u.Offset += n
+	u.UpdatedAt = time.Now().UTC()
+
+	if err := s.save(&u); err != nil {
+		return u, err
+	}
+
+	if copyErr != nil {
+		return u, fmt.Errorf("could not write upload file: %w", copyErr)
+	}
+	return u, nil
+}
+
+// Open opens the data of the upload for reading. The caller must close the file.
+func (s *Store) Open(id string) (*os.File, error) {
+	if !idRe.MatchString(id) {
+		return nil, ErrNotFound
+	}
+
+	f, err := os.Open(s.dataPath(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("could not open upload file: %w", err)
+	}
+	return f, nil
+}
+
+// Finish records the job processing the completed upload and removes its data, keeping its state.
+func (s *Store) Finish(id, jobID string) error {
+	u, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+
+	u.JobID = jobID
+	u.UpdatedAt = time.Now().UTC()
+
+	if err := s.save(&u); err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.dataPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not remove upload file: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the upload.
+func (s *Store) Remove(id string) error {
+	if !s.lock(id) {
+		return ErrBusy
+	}
+	defer s.unlock(id)
+
+	// Expired uploads can be removed.
+	if _, err := s.read(id); err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.dataPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not remove upload file: %w", err)
+	}
+
+	if err := os.Remove(s.infoPath(id)); err != nil {
+		return fmt.Errorf("could not remove upload: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) save(u *Upload) error {
+	// Each write postpones the expiry of an incomplete upload.
+	u.ExpiresAt = time.Time{}
+	if s.ttl > 0 && !u.Complete() {
+		u.ExpiresAt = u.UpdatedAt.Add(s.ttl)
+	}
+
+	data, err := json.Marshal(u)
+	if err != nil {
+		return fmt.Errorf("could not encode upload: %w", err)
+	}
+
+	// Written to a temporary file first so that a crash never leaves a truncated state.
+	tmp := s.infoPath(u.ID) + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("could not write upload: %w", err)
+	}
+
+	if err := os.Rename(tmp, s.infoPath(u.ID)); err != nil {
+		return fmt.Errorf("could not write upload: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) lock(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writing[id] {
+		return false
+	}
+	s.writing[id] = true
+	return true
+}
+
+func (s *Store) unlock(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.writing, id)
+}
+
+func (s *Store) dataPath(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+func (s *Store) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
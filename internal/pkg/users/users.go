@@ -0,0 +1,256 @@
+// Package users keeps track of the users seen by the server, through their API key or the SSO proxy
+// in front of it, with their role and the projects they can access, so that access control can be
+// managed without editing the configuration and restarting.
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/retention"
+)
+
+// Roles.
+const (
+	RoleMember   Role = "member"
+	RoleAdmin    Role = "admin"    // Also administers the server.
+	RoleDisabled Role = "disabled" // Can't access the server.
+)
+
+// seenInterval is how often the last time a user was seen is saved, so that requests don't all write the file.
+const seenInterval time.Duration = time.Minute
+
+// maxNameLength is the length of the longest user name, since they name directories.
+const maxNameLength int = 64
+
+var (
+	// ErrNotFound is returned when the user was never seen.
+	ErrNotFound = errors.New("user not found")
+
+	// ErrInvalidUser is returned when a role or a project of a user fails validation.
+	ErrInvalidUser = errors.New("invalid user")
+)
+
+// Role is what a user is allowed to do.
+type Role string
+
+// User is a user seen by the server.
+type User struct {
+	// Name is the namespace of the user, its API key user or derived from its SSO identity.
+	Name     string `json:"name"`
+	Identity string `json:"identity,omitempty"` // SSO identity, e.g. an email.
+	Role     Role   `json:"role"`
+
+	// Projects are the projects the user can submit files to and manage. All of them when nil.
+	Projects []string `json:"projects"`
+
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// CanAccess reports whether the user can access the project.
+func (u User) CanAccess(project string) bool {
+	return u.Projects == nil || slices.Contains(u.Projects, project)
+}
+
+// Store persists the users as JSON. It is safe for concurrent use.
+type Store struct {
+	mu    sync.Mutex
+	path  string
+	users map[string]*User
+	now   func() time.Time
+}
+
+// NewStore returns a store backed by the file at path.
+func NewStore(path string) (*Store, error) {
+	s := Store{
+		path:  path,
+		users: make(map[string]*User),
+		now:   time.Now,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not read users: %w", err)
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.users); err != nil {
+			return nil, fmt.Errorf("could not unmarshal users: %w", err)
+		}
+	}
+	return &s, nil
+}
+
+// Seen records that the user of an API key was seen and returns it, as a member when seen for the first time.
+func (s *Store) Seen(name string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[name]
+	if !ok {
+		u = &User{Name: name, Role: RoleMember}
+		s.users[name] = u
+	}
+	return s.seen(u, !ok)
+}
+
+// Resolve records that the user of an SSO identity was seen and returns it. Users seen for the first time
+// are members, named after their identity.
+func (s *Store) Resolve(identity string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Identity == identity {
+			return s.seen(u, false)
+		}
+	}
+
+	u := &User{Name: s.uniqueName(identity), Identity: identity, Role: RoleMember}
+	s.users[u.Name] = u
+
+	return s.seen(u, true)
+}
+
+// seen updates the last time the user was seen, saving it unless it was saved recently.
+func (s *Store) seen(u *User, created bool) (User, error) {
+	now := s.now()
+
+	if created {
+		u.FirstSeen = now
+	}
+
+	if !created && now.Sub(u.LastSeen) < seenInterval {
+		return u.copy(), nil
+	}
+
+	previous := u.LastSeen
+	u.LastSeen = now
+
+	if err := s.save(); err != nil {
+		if created {
+			delete(s.users, u.Name)
+		} else {
+			u.LastSeen = previous
+		}
+		return User{}, err
+	}
+	return u.copy(), nil
+}
+
+// uniqueName derives a user name usable as a directory name from the identity, e.g. alice-example-com
+// from alice@example.com, suffixed with a number when taken.
+func (s *Store) uniqueName(identity string) string {
+	var b strings.Builder
+
+	for _, r := range strings.ToLower(identity) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+
+	base := strings.Trim(b.String(), "-")
+	if len(base) > maxNameLength-4 {
+		base = strings.Trim(base[:maxNameLength-4], "-")
+	}
+
+	if base == "" {
+		base = "user"
+	}
+
+	name := base
+	for i := 2; s.users[name] != nil; i++ {
+		name = base + "-" + strconv.Itoa(i)
+	}
+	return name
+}
+
+// List returns the users sorted by name.
+func (s *Store) List() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u.copy())
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Name < users[j].Name
+	})
+	return users
+}
+
+// Get returns the user with the given name.
+func (s *Store) Get(name string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[name]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u.copy(), nil
+}
+
+// Set sets the role of a user and the projects it can access, all of them when nil.
+func (s *Store) Set(name string, role Role, projects []string) (User, error) {
+	switch role {
+	case RoleMember, RoleAdmin, RoleDisabled:
+	default:
+		return User{}, fmt.Errorf("%w: role must be one of member, admin or disabled", ErrInvalidUser)
+	}
+
+	for _, project := range projects {
+		if err := retention.ValidateProject(project); err != nil {
+			return User{}, fmt.Errorf("%w: project %q", ErrInvalidUser, project)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[name]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+
+	previous := *u
+	u.Role, u.Projects = role, slices.Clone(projects)
+
+	if err := s.save(); err != nil {
+		*u = previous
+		return User{}, err
+	}
+	return u.copy(), nil
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.users, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal users: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, data, 0o644); err != nil {
+		return fmt.Errorf("could not write users: %w", err)
+	}
+	return nil
+}
+
+func (u *User) copy() User {
+	c := *u
+	c.Projects = slices.Clone(u.Projects)
+	return c
+}
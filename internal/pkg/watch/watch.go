@@ -0,0 +1,278 @@
+// Package watch transcribes the videos dropped into an inbox directory, e.g. one shared by a NAS
+// or a media server, and moves them out of the inbox once processed.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Directories of the inbox the processed files are moved to.
+const (
+	ProcessedDir string = "processed"
+	FailedDir    string = "failed"
+)
+
+// partialExtensions are the extensions of the files still being copied by browsers and sync tools.
+var partialExtensions = map[string]bool{
+	".part": true, ".partial": true, ".tmp": true, ".crdownload": true, ".download": true,
+}
+
+type generator interface {
+	GenerateFromAudioData(ctx context.Context, inputs []*subtitles.Input) (subtitles.Results, error)
+}
+
+// snapshot is what a poll saw of a file, to tell whether it is still being written.
+type snapshot struct {
+	size    int64
+	modTime time.Time
+}
+
+// Watcher watches an inbox directory and transcribes the files dropped into it.
+type Watcher struct {
+	logger    *slog.Logger
+	generator generator
+	inbox     string
+	language  string
+	format    subtitles.Format
+	seen      map[string]snapshot
+}
+
+// New returns a new watcher of the inbox, creating its processed and failed directories.
+// The files are transcribed from the language to subtitles of the format.
+func New(logger *slog.Logger, generator generator, inbox, language string, format subtitles.Format) (*Watcher, error) {
+	for _, dir := range []string{ProcessedDir, FailedDir} {
+		if err := os.MkdirAll(filepath.Join(inbox, dir), 0o755); err != nil {
+			return nil, fmt.Errorf("could not create inbox directory: %w", err)
+		}
+	}
+
+	return &Watcher{
+		logger:    logger,
+		generator: generator,
+		inbox:     inbox,
+		language:  language,
+		format:    format,
+		seen:      make(map[string]snapshot),
+	}, nil
+}
+
+// Poll transcribes the files of the inbox that didn't change since the previous poll, so that files
+// still being copied are left for a later one. Each file is then moved to the processed directory,
+// or to the failed directory alongside a .error.txt file telling why.
+func (w *Watcher) Poll(ctx context.Context) error {
+	ready, err := w.ready()
+	if err != nil {
+		return err
+	}
+
+	if len(ready) == 0 {
+		return nil
+	}
+
+	inputs := make([]*subtitles.Input, 0, len(ready))
+
+	for _, name := range ready {
+		f, err := os.Open(filepath.Join(w.inbox, name))
+		if err != nil {
+			return fmt.Errorf("could not open file: %w", err)
+		}
+		defer f.Close()
+
+		inputs = append(inputs, &subtitles.Input{
+			FileName: name,
+			Data:     f,
+			Language: w.language,
+			Format:   w.format,
+		})
+	}
+
+	w.logger.Info("Transcribing files of the inbox", slog.Int("files", len(inputs)))
+
+	results, _ := w.generator.GenerateFromAudioData(ctx, inputs)
+
+	// The files of a stopped server are transcribed again once it restarts.
+	if ctx.Err() != nil {
+		return fmt.Errorf("could not transcribe files: %w", ctx.Err())
+	}
+
+	var errs []error
+
+	for i, res := range results {
+		name := ready[i]
+		delete(w.seen, name)
+
+		if res.Err != nil {
+			w.logger.Error("Failed to transcribe file of the inbox", slog.String("file", name), slog.String("error", res.Err.Error()))
+
+			if err := w.fail(name, res.Err); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		w.logger.Info("Transcribed file of the inbox", slog.String("file", name), slog.String("subtitle", res.Subtitle))
+
+		if _, err := w.move(name, ProcessedDir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ready returns the names of the files of the inbox that didn't change since the previous poll.
+func (w *Watcher) ready() ([]string, error) {
+	entries, err := os.ReadDir(w.inbox)
+	if err != nil {
+		return nil, fmt.Errorf("could not read inbox: %w", err)
+	}
+
+	var (
+		ready []string
+		seen  = make(map[string]snapshot, len(entries))
+	)
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasPrefix(name, ".") || partialExtensions[strings.ToLower(filepath.Ext(name))] {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed since read.
+		}
+
+		current := snapshot{size: info.Size(), modTime: info.ModTime()}
+		seen[name] = current
+
+		if previous, ok := w.seen[name]; ok && previous == current && current.size > 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	w.seen = seen
+	return ready, nil
+}
+
+// fail moves the file to the failed directory and writes its error alongside it.
+func (w *Watcher) fail(name string, fileErr error) error {
+	path, err := w.move(name, FailedDir)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path+".error.txt", []byte(fileErr.Error()+"\n"), 0o644); err != nil {
+		return fmt.Errorf("could not write error of %s: %w", name, err)
+	}
+	return nil
+}
+
+// move moves the file of the inbox to one of its directories, suffixing its name with the time
+// when a file of the same name was already moved there, and returns its new path.
+func (w *Watcher) move(name, dir string) (string, error) {
+	dst := filepath.Join(w.inbox, dir, name)
+
+	if _, err := os.Stat(dst); err == nil {
+		ext := filepath.Ext(name)
+		dst = filepath.Join(w.inbox, dir, strings.TrimSuffix(name, ext)+"-"+time.Now().Format("20060102T150405")+ext)
+	}
+
+	if err := os.Rename(filepath.Join(w.inbox, name), dst); err != nil {
+		return "", fmt.Errorf("could not move %s to %s: %w", name, dir, err)
+	}
+	return dst, nil
+}
+
+// Run processes the inbox until the context is done. It polls the inbox once its files didn't
+// change for the interval, as notified by the file system, or every interval when the inbox can't
+// be watched for changes.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	notifier, err := fsnotify.NewWatcher()
+	if err == nil {
+		if err = notifier.Add(w.inbox); err != nil {
+			notifier.Close()
+		}
+	}
+
+	if err != nil {
+		w.logger.Warn("Could not watch the inbox for changes, polling it", slog.String("error", err.Error()))
+		w.RunPolling(ctx, interval)
+		return
+	}
+	defer notifier.Close()
+
+	// The files already in the inbox are processed as if they were just dropped.
+	settled := time.NewTimer(interval)
+	defer settled.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-notifier.Events:
+			if !ok {
+				w.RunPolling(ctx, interval)
+				return
+			}
+			resetTimer(settled, interval)
+		case err, ok := <-notifier.Errors:
+			if !ok {
+				w.RunPolling(ctx, interval)
+				return
+			}
+			// Events were dropped, e.g. when the queue of the kernel overflowed, so the inbox is polled anyway.
+			w.logger.Error("Could not watch the inbox for changes", slog.String("error", err.Error()))
+			resetTimer(settled, interval)
+		case <-settled.C:
+			w.poll(ctx)
+
+			// The files seen for the first time, or still changing, are polled again once settled.
+			if len(w.seen) > 0 {
+				settled.Reset(interval)
+			}
+		}
+	}
+}
+
+// RunPolling polls the inbox every interval until the context is done, e.g. for network shares,
+// whose changes made by other hosts aren't notified.
+func (w *Watcher) RunPolling(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll(ctx)
+		}
+	}
+}
+
+func (w *Watcher) poll(ctx context.Context) {
+	if err := w.Poll(ctx); err != nil && ctx.Err() == nil {
+		w.logger.Error("Could not process the inbox", slog.String("error", err.Error()))
+	}
+}
+
+// resetTimer restarts the timer, draining it when it fired meanwhile.
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
@@ -0,0 +1,130 @@
+package ytdlp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/ingest"
+)
+
+// hosts are the video platforms whose links are fetched with yt-dlp.
+var hosts = []string{"youtube.com", "youtu.be", "vimeo.com"}
+
+// Fetcher downloads the audio of video platform links with yt-dlp, skipping the video
+// download entirely. Other links are downloaded by the fallback fetcher.
+type Fetcher struct {
+	binary   string
+	tmpDir   string
+	maxSize  int64
+	timeout  time.Duration
+	fallback ingest.Source
+}
+
+// New returns a new fetcher running the yt-dlp binary, which must be in the PATH
+// when not an absolute path. Audio files of at most maxSize bytes are downloaded to tmpDir.
+func New(binary, tmpDir string, maxSize int64, timeout time.Duration, fallback ingest.Source) (*Fetcher, error) {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("could not find yt-dlp: %w", err)
+	}
+
+	return &Fetcher{
+		binary:   path,
+		tmpDir:   tmpDir,
+		maxSize:  maxSize,
+		timeout:  timeout,
+		fallback: fallback,
+	}, nil
+}
+
+// Fetch downloads the audio of the video at the URL. The caller must close the body,
+// which removes the downloaded file.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*ingest.Download, error) {
+	if !platformURL(rawURL) {
+		return f.fallback.Fetch(ctx, rawURL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	dir, err := os.MkdirTemp(f.tmpDir, "ytdlp-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create download directory: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, f.binary,
+		"--format", "bestaudio/best",
+		"--no-playlist",
+		"--no-progress",
+		"--max-filesize", strconv.FormatInt(f.maxSize, 10),
+		"--output", filepath.Join(dir, "%(title).200B.%(ext)s"),
+		"--print", "after_move:filepath",
+		"--", rawURL,
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("could not run yt-dlp: %w: %s", err, lastLine(stderr.String()))
+	}
+
+	// yt-dlp skips files above --max-filesize without failing, so nothing is printed.
+	path := lastLine(stdout.String())
+	if path == "" {
+		os.RemoveAll(dir)
+		return nil, ingest.ErrTooLarge
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("could not open downloaded audio: %w", err)
+	}
+
+	return &ingest.Download{
+		Name: filepath.Base(path),
+		Body: &tempFile{File: file, dir: dir},
+	}, nil
+}
+
+// platformURL reports whether the URL is a link to one of the supported video platforms.
+func platformURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, h := range hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// tempFile removes its directory once closed.
+type tempFile struct {
+	*os.File
+	dir string
+}
+
+func (f *tempFile) Close() error {
+	return errors.Join(f.File.Close(), os.RemoveAll(f.dir))
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,328 @@
+// Package videoscriber embeds the subtitling pipeline of the server in Go programs, without running it:
+// the audio of the videos is extracted with ffmpeg, transcribed by Whisper, and the subtitles are written
+// to a directory.
+//
+//	s, err := videoscriber.New("subtitles", videoscriber.WithOpenAI(os.Getenv("OPENAI_API_KEY")))
+//	...
+//	results, err := s.Transcribe(ctx, videoscriber.Input{Name: "talk.mp4", Data: f, Language: "en"})
+package videoscriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/alesr/videoscriber/internal/pkg/audio"
+	"github.com/alesr/videoscriber/internal/pkg/chapters"
+	"github.com/alesr/videoscriber/internal/pkg/ffmpeg"
+	"github.com/alesr/videoscriber/internal/pkg/llm"
+	"github.com/alesr/videoscriber/internal/pkg/minutes"
+	"github.com/alesr/videoscriber/internal/pkg/nlp"
+	"github.com/alesr/videoscriber/internal/pkg/schedule"
+	"github.com/alesr/videoscriber/internal/pkg/scratch"
+	"github.com/alesr/videoscriber/internal/pkg/srt"
+	"github.com/alesr/videoscriber/internal/pkg/storage"
+	"github.com/alesr/videoscriber/internal/pkg/subtitles"
+	"github.com/alesr/videoscriber/internal/pkg/transcriber"
+	"github.com/alesr/videoscriber/internal/pkg/translate"
+	"github.com/alesr/whisperclient"
+)
+
+const (
+	sampleRate     string = "3800"
+	whisperAIModel string = "whisper-1"
+	openAIURL      string = "https://api.openai.com/v1"
+	chatModel      string = "gpt-4o-mini"
+)
+
+// Transcription providers.
+const (
+	ProviderOpenAI string = transcriber.ProviderOpenAI
+	ProviderLocal  string = transcriber.ProviderLocal // A self-hosted Whisper server.
+)
+
+// ErrNoProvider is returned when no transcription provider is configured.
+var ErrNoProvider = errors.New("no transcription provider")
+
+// Format is the format of the subtitles.
+type Format string
+
+const (
+	FormatSRT  Format = Format(subtitles.FormatSRT)
+	FormatVTT  Format = Format(subtitles.FormatVTT)
+	FormatText Format = Format(subtitles.FormatText) // Paragraphs of the transcript.
+	FormatHTML Format = Format(subtitles.FormatHTML)
+	FormatDOCX Format = Format(subtitles.FormatDOCX) // Paragraphs of the transcript, as a Word document.
+	FormatTSV  Format = Format(subtitles.FormatTSV)
+	FormatJSON Format = Format(subtitles.FormatJSON) // Whisper's verbose JSON.
+)
+
+// DefaultLanguage is the spoken language of the inputs that don't set one.
+const DefaultLanguage string = subtitles.DefaultLanguage
+
+// Input is a video or audio file to transcribe.
+type Input struct {
+	Name     string // File name, the subtitle is named after it.
+	Data     io.Reader
+	Language string // Spoken language, DefaultLanguage when empty.
+	Format   Format // FormatSRT when empty.
+	Project  string // Optional. Subtitles are stored in a subdirectory named after the project, depending on the layout.
+	Prompt   string // Optional. Terminology of the domain, e.g. product names, improves their recognition.
+	Provider string // Optional. The default provider when empty.
+}
+
+// Result is the outcome of an input.
+type Result struct {
+	Name string
+	Path string // Path of the written subtitle, when it succeeded.
+	Err  error
+}
+
+type config struct {
+	logger          *slog.Logger
+	providers       map[string]transcriber.Transcriber
+	defaultProvider string
+	openAIKey       string
+	ffmpegBinary    string
+	ffmpegArgs      []string
+	tmpDir          string
+	layout          string
+	concurrency     int
+	firstCueIndex   int
+	cueLayout       srt.Layout
+	secondPass      subtitles.SecondPass
+	keepAudio       bool
+}
+
+// Option configures a Subtitler.
+type Option func(*config)
+
+// WithOpenAI transcribes with the Whisper API of OpenAI, authenticated by the key.
+// It is the default provider unless WithLocal is given after it.
+func WithOpenAI(key string) Option {
+	return func(c *config) {
+		c.openAIKey = key
+		c.providers[ProviderOpenAI] = transcriber.NewOpenAI(
+			whisperclient.New(&http.Client{}, key, whisperAIModel),
+			transcriber.NewLocal(&http.Client{}, openAIURL, key, whisperAIModel),
+			whisperAIModel,
+		)
+		c.defaultProvider = ProviderOpenAI
+	}
+}
+
+// WithLocal transcribes with a self-hosted Whisper server serving the model at baseURL.
+// The token is sent as a bearer token when not empty.
+// It is the default provider unless WithOpenAI is given after it.
+func WithLocal(baseURL, token, model string) Option {
+	return func(c *config) {
+		c.providers[ProviderLocal] = transcriber.NewLocal(&http.Client{}, baseURL, token, model)
+		c.defaultProvider = ProviderLocal
+	}
+}
+
+// WithLogger sets the logger of the pipeline. Nothing is logged by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithFFmpeg sets the ffmpeg binary and the extra arguments of the audio extraction.
+// By default, ffmpeg is looked for in the PATH and where package managers install it.
+func WithFFmpeg(binary string, args ...string) Option {
+	return func(c *config) {
+		c.ffmpegBinary, c.ffmpegArgs = binary, args
+	}
+}
+
+// WithTmpDir sets the directory of the temporary files, the one of the system by default.
+func WithTmpDir(dir string) Option {
+	return func(c *config) {
+		c.tmpDir = dir
+	}
+}
+
+// WithLayout sets the layout of the subtitles directory, e.g. {lang}/{project}/{name}.
+// By default, the subtitles are written at the top of the directory.
+func WithLayout(layout string) Option {
+	return func(c *config) {
+		c.layout = layout
+	}
+}
+
+// WithConcurrency sets the maximum number of files transcribed at the same time, the number of CPUs by default.
+func WithConcurrency(n int) Option {
+	return func(c *config) {
+		c.concurrency = n
+	}
+}
+
+// WithFirstCueIndex sets the number of the first cue of the subtitles, 1 by default.
+func WithFirstCueIndex(index int) Option {
+	return func(c *config) {
+		c.firstCueIndex = index
+	}
+}
+
+// WithCueLayout limits the characters per line, the lines per cue and the characters per second of the cues,
+// 42, 2 and unlimited by default. Zero values are unlimited.
+func WithCueLayout(maxLineLength, maxLines int, maxCPS float64) Option {
+	return func(c *config) {
+		c.cueLayout = srt.Layout{MaxLineLength: maxLineLength, MaxLines: maxLines, MaxCPS: maxCPS}
+	}
+}
+
+// WithSecondPass transcribes again the cues transcribed with a confidence below threshold, between 0 and 1,
+// with the model of the provider, e.g. ProviderLocal with a larger model, keeping the more confident text.
+// At most maxShare of the cues, the least confident, are transcribed again. Empty provider and model are
+// those of the first pass. The providers must support the verbose_json format.
+func WithSecondPass(threshold, maxShare float64, provider, model string) Option {
+	return func(c *config) {
+		c.secondPass = subtitles.SecondPass{Threshold: threshold, MaxShare: maxShare, Provider: provider, Model: model}
+	}
+}
+
+// WithKeepAudio keeps the transcribed audio alongside each subtitle, as the server does to reprocess them.
+func WithKeepAudio() Option {
+	return func(c *config) {
+		c.keepAudio = true
+	}
+}
+
+// Subtitler generates the subtitles of video and audio files. It is safe for concurrent use.
+type Subtitler struct {
+	subtitler *subtitles.Subtitler
+	storage   *storage.Storage
+}
+
+// New returns a new subtitler writing the subtitles to outDir. At least one provider,
+// WithOpenAI or WithLocal, is required.
+func New(outDir string, opts ...Option) (*Subtitler, error) {
+	c := config{
+		logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		providers:     make(map[string]transcriber.Transcriber),
+		tmpDir:        os.TempDir(),
+		layout:        "{name}",
+		concurrency:   runtime.NumCPU(),
+		firstCueIndex: 1,
+		cueLayout:     srt.Layout{MaxLineLength: 42, MaxLines: 2},
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	if len(c.providers) == 0 {
+		return nil, ErrNoProvider
+	}
+
+	if c.ffmpegBinary == "" {
+		binary, err := ffmpeg.Find()
+		if err != nil {
+			binary = "ffmpeg" // WAV, MP3 and AAC audio are decoded in pure Go without it.
+		}
+		c.ffmpegBinary = binary
+	}
+
+	outDir, err := filepath.Abs(outDir)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve output directory: %w", err)
+	}
+
+	store, err := storage.New(outDir, c.layout)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize storage: %w", err)
+	}
+
+	// The chat models only serve the options of the server, e.g. minutes and translations.
+	llmClient := llm.New(&http.Client{Timeout: 5 * time.Minute}, openAIURL, c.openAIKey)
+
+	retain := subtitles.RetainNone
+	if c.keepAudio {
+		retain = subtitles.RetainAudio
+	}
+
+	scratchSpace, err := scratch.New(c.logger, []scratch.Dir{{Path: c.tmpDir}})
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize temporary directory: %w", err)
+	}
+
+	subtitler, err := subtitles.New(subtitles.Config{
+		Logger:          c.logger,
+		SampleRate:      sampleRate,
+		Scratch:         scratchSpace,
+		Storage:         store,
+		Extractor:       audio.NewFallback(c.logger, ffmpeg.NewExtractor(c.ffmpegBinary, c.ffmpegArgs)),
+		Providers:       c.providers,
+		DefaultProvider: c.defaultProvider,
+		Analyzer:        nlp.NewLexicon(),
+		Minutes:         minutes.NewWriter(llmClient, chatModel),
+		Chapters:        chapters.NewTopics(),
+		Translator:      translate.NewChat(llmClient, chatModel),
+		Scheduler:       schedule.New(schedule.Policy{}, 0),
+		Versions:        map[string]string{"ffmpeg": c.ffmpegBinary},
+		Retain:          retain,
+		FirstCueIndex:   c.firstCueIndex,
+		Layout:          c.cueLayout,
+		SecondPass:      c.secondPass,
+		MaxConcurrency:  c.concurrency,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Subtitler{subtitler: subtitler, storage: store}, nil
+}
+
+// Transcribe generates the subtitles of the inputs concurrently. An input failing doesn't stop the others.
+// The returned error joins the errors of the inputs that failed, and the results, in the order of the inputs,
+// tell which ones succeeded.
+func (s *Subtitler) Transcribe(ctx context.Context, inputs ...Input) ([]Result, error) {
+	in := make([]*subtitles.Input, 0, len(inputs))
+
+	for _, input := range inputs {
+		language := input.Language
+		if language == "" {
+			language = DefaultLanguage
+		}
+
+		if !subtitles.SupportedLanguage(language) {
+			return nil, fmt.Errorf("unsupported language %q of %s", language, input.Name)
+		}
+
+		format, err := subtitles.ParseFormat(string(input.Format))
+		if err != nil {
+			return nil, fmt.Errorf("invalid format of %s: %w", input.Name, err)
+		}
+
+		in = append(in, &subtitles.Input{
+			FileName: input.Name,
+			Data:     input.Data,
+			Language: language,
+			Format:   format,
+			Project:  input.Project,
+			Prompt:   input.Prompt,
+			Provider: input.Provider,
+		})
+	}
+
+	results, err := s.subtitler.GenerateFromAudioData(ctx, in)
+
+	out := make([]Result, 0, len(results))
+	for i, res := range results {
+		r := Result{Name: res.FileName, Err: res.Err}
+
+		if res.Err == nil {
+			r.Path = s.storage.Path("", in[i].OutputLanguage(), in[i].Project, res.Subtitle)
+		}
+		out = append(out, r)
+	}
+	return out, err
+}